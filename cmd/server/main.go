@@ -19,6 +19,15 @@ func main() {
 		os.Setenv("CONFIG_PATH", configPath)
 	}
 
+	// Run migrations instead of the server when asked
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrate(flag.Args()[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Migration failed: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Create and run the application
 	app, err := core.NewApp()
 	if err != nil {
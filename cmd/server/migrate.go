@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/jxlxx/civicrm/internal/config"
+	"github.com/jxlxx/civicrm/internal/database"
+	"github.com/jxlxx/civicrm/internal/database/queries"
+	"github.com/jxlxx/civicrm/internal/logger"
+	"github.com/jxlxx/civicrm/internal/migrate"
+	"github.com/jxlxx/civicrm/migrations"
+)
+
+const migrateUsage = "usage: civicrm migrate up|down|status|lint|to <version> [-skip-lint]"
+
+// runMigrate applies the embedded migrations without starting the application.
+// Pending migrations are linted before they are applied; -skip-lint applies
+// them despite findings.
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	command := args[0]
+	flags := flag.NewFlagSet("migrate "+command, flag.ContinueOnError)
+	skipLint := flags.Bool("skip-lint", false, "Apply migrations even if the linter reports unsafe operations")
+
+	// Accept flags before or after the version argument
+	positional := []string{command}
+	for remaining := args[1:]; ; {
+		if err := flags.Parse(remaining); err != nil {
+			return err
+		}
+		if flags.NArg() == 0 {
+			break
+		}
+		positional = append(positional, flags.Arg(0))
+		remaining = flags.Args()[1:]
+	}
+	args = positional
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	log, err := logger.New(&cfg.Logging)
+	if err != nil {
+		return fmt.Errorf("failed to initialize logger: %w", err)
+	}
+
+	db, err := database.New(&cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	defer db.Close()
+
+	files, err := migrate.Load(migrations.FS)
+	if err != nil {
+		return err
+	}
+	migrator := migrate.New(db.DB(), files, log)
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		if !*skipLint {
+			if err := lintPending(ctx, migrator, migrator.Latest()); err != nil {
+				return err
+			}
+		}
+		return migrator.Up(ctx)
+	case "down":
+		return migrator.Down(ctx)
+	case "to":
+		if len(args) != 2 {
+			return errors.New(migrateUsage)
+		}
+		version, err := strconv.ParseInt(args[1], 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid version %q: %w", args[1], err)
+		}
+		if !*skipLint {
+			if err := lintPending(ctx, migrator, int32(version)); err != nil {
+				return err
+			}
+		}
+		return migrator.To(ctx, int32(version))
+	case "lint":
+		return lintPending(ctx, migrator, migrator.Latest())
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "version %d of %d\n", status.Current, status.Latest)
+		for _, migration := range status.Pending {
+			fmt.Fprintf(os.Stdout, "pending  %s\n", migration.Name)
+		}
+		return nil
+	default:
+		return errors.New(migrateUsage)
+	}
+}
+
+// lintPending lints the pending migrations up to target and fails if any
+// operation may lock a busy table
+func lintPending(ctx context.Context, migrator *migrate.Migrator, target int32) error {
+	status, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	var pending []*migrate.Migration
+	for _, migration := range status.Pending {
+		if migration.Version <= target {
+			pending = append(pending, migration)
+		}
+	}
+
+	findings, err := migrate.NewLinter(queries.FS).Lint(pending)
+	if err != nil {
+		return err
+	}
+	if len(findings) == 0 {
+		return nil
+	}
+
+	for _, finding := range findings {
+		fmt.Fprintln(os.Stderr, finding)
+	}
+	return fmt.Errorf("%d unsafe operations in pending migrations; fix them, annotate them with -- lint:ignore <rule>, or rerun with -skip-lint", len(findings))
+}
@@ -0,0 +1,154 @@
+// Package addresses standardizes postal addresses: it splits street
+// addresses into number, name and unit, validates postal codes by country
+// and renders mailing labels in each country's format. The
+// parse_street_addresses job splits addresses that were not split when
+// they were written. A contact can share another contact's address, such as
+// their household's; triggers from migration 050 keep the copy identical
+// to its master.
+package addresses
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/database"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/jxlxx/civicrm/internal/logger"
+)
+
+// JobParse is the scheduled job that splits street addresses
+const JobParse = "parse_street_addresses"
+
+// parseBatchSize is the number of addresses split per transaction
+const parseBatchSize = 500
+
+var (
+	// ErrInvalidPostalCode is returned for a postal code that does not match
+	// the format of its country
+	ErrInvalidPostalCode = errors.New("invalid postal code")
+
+	// ErrNotFound is returned when an address or its contact does not exist
+	ErrNotFound = errors.New("not found")
+
+	// ErrShared is returned when changing a shared address other than
+	// through its master
+	ErrShared = errors.New("address is shared")
+
+	// ErrInvalidShare is returned when an address cannot be shared or
+	// unshared
+	ErrInvalidShare = errors.New("invalid address share")
+)
+
+// Label is the mailing label of a contact's primary address
+type Label struct {
+	ContactID uuid.UUID `json:"contact_id"`
+	Lines     []string  `json:"lines"`
+}
+
+// Service standardizes stored addresses
+type Service struct {
+	queries db.Querier
+	tx      database.Transactor
+	logger  *logger.Logger
+	now     func() time.Time
+}
+
+// New creates an address service
+func New(database *database.Database, logger *logger.Logger) *Service {
+	return &Service{
+		queries: database.ReadQuerier(),
+		tx:      database,
+		logger:  logger,
+		now:     time.Now,
+	}
+}
+
+// ParsePending splits the street addresses of a domain's contacts that have
+// not been split, a batch per transaction
+func (s *Service) ParsePending(ctx context.Context, domainID uuid.UUID) (int, error) {
+	parsed := 0
+	after := uuid.Nil
+	for {
+		rows, err := s.queries.ListAddressesToParse(ctx, db.ListAddressesToParseParams{
+			DomainID:  domainID,
+			AfterID:   after,
+			BatchSize: parseBatchSize,
+		})
+		if err != nil {
+			return parsed, fmt.Errorf("failed to list addresses to parse: %w", err)
+		}
+		if len(rows) == 0 {
+			return parsed, nil
+		}
+
+		err = s.tx.WithTx(ctx, func(q db.Querier) error {
+			for _, row := range rows {
+				street := ParseStreet(row.CountryCode.String, row.StreetAddress.String)
+				n, err := q.SetAddressStreetParts(ctx, db.SetAddressStreetPartsParams{
+					ID:            row.ID,
+					StreetAddress: row.StreetAddress,
+					StreetNumber:  nullString(street.Number),
+					StreetName:    nullString(street.Name),
+					StreetUnit:    nullString(street.Unit),
+				})
+				if err != nil {
+					return fmt.Errorf("failed to set street parts of address %s: %w", row.ID, err)
+				}
+				parsed += int(n)
+			}
+			return nil
+		})
+		if err != nil {
+			return parsed, err
+		}
+		after = rows[len(rows)-1].ID
+	}
+}
+
+// ParseJob is the handler of the parse_street_addresses job
+func (s *Service) ParseJob(ctx context.Context, job db.Job) (string, error) {
+	parsed, err := s.ParsePending(ctx, job.DomainID)
+	if err != nil {
+		return "", fmt.Errorf("parsed %d addresses: %w", parsed, err)
+	}
+	return fmt.Sprintf("parsed %d addresses", parsed), nil
+}
+
+// Labels renders the mailing labels of contacts' primary addresses, sorted
+// by name. Contacts without a primary address are left out. fromCountry is
+// the ISO code of the country the mail is sent from.
+func (s *Service) Labels(ctx context.Context, contactIDs []uuid.UUID, fromCountry string) ([]Label, error) {
+	rows, err := s.queries.ListMailingAddresses(ctx, contactIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mailing addresses: %w", err)
+	}
+
+	labels := make([]Label, len(rows))
+	for i, row := range rows {
+		labels[i] = Label{
+			ContactID: row.ContactID,
+			Lines: FormatLabel(LabelAddress{
+				Addressee:            row.DisplayName.String,
+				StreetAddress:        row.StreetAddress.String,
+				SupplementalAddress1: row.SupplementalAddress1.String,
+				City:                 row.City.String,
+				State:                row.StateProvince.String,
+				PostalCode:           row.PostalCode.String,
+				Country:              row.Country.String,
+				CountryCode:          row.CountryCode.String,
+			}, fromCountry),
+		}
+	}
+	return labels, nil
+}
+
+// nullString converts a blank string to NULL
+func nullString(s string) sql.NullString {
+	s = strings.TrimSpace(s)
+	return sql.NullString{String: s, Valid: s != ""}
+}
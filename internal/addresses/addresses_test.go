@@ -0,0 +1,83 @@
+package addresses
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/database/dbtest"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/jxlxx/civicrm/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeQuerier pages through addresses by ID and records the street parts set
+type fakeQuerier struct {
+	db.Querier
+	addresses []db.ListAddressesToParseRow
+	mailing   []db.ListMailingAddressesRow
+	set       []db.SetAddressStreetPartsParams
+}
+
+func (q *fakeQuerier) ListAddressesToParse(ctx context.Context, arg db.ListAddressesToParseParams) ([]db.ListAddressesToParseRow, error) {
+	var rows []db.ListAddressesToParseRow
+	for _, row := range q.addresses {
+		if bytes.Compare(row.ID[:], arg.AfterID[:]) > 0 && len(rows) < int(arg.BatchSize) {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+func (q *fakeQuerier) SetAddressStreetParts(ctx context.Context, arg db.SetAddressStreetPartsParams) (int64, error) {
+	q.set = append(q.set, arg)
+	return 1, nil
+}
+
+func (q *fakeQuerier) ListMailingAddresses(ctx context.Context, contactIDs []uuid.UUID) ([]db.ListMailingAddressesRow, error) {
+	return q.mailing, nil
+}
+
+func TestParsePending(t *testing.T) {
+	q := &fakeQuerier{}
+	for i := 0; i < parseBatchSize+2; i++ {
+		q.addresses = append(q.addresses, db.ListAddressesToParseRow{ID: uuid.New(), StreetAddress: dbtest.Text("Flat 3, 12 High Street"), CountryCode: dbtest.Text("GB")})
+	}
+	sort.Slice(q.addresses, func(i, j int) bool {
+		return bytes.Compare(q.addresses[i].ID[:], q.addresses[j].ID[:]) < 0
+	})
+	service := &Service{queries: q, tx: dbtest.Tx{Q: q}, logger: logger.NewNop()}
+
+	message, err := service.ParseJob(context.Background(), db.Job{DomainID: uuid.New()})
+	require.NoError(t, err)
+	assert.Equal(t, "parsed 502 addresses", message)
+	require.Len(t, q.set, parseBatchSize+2)
+	assert.Equal(t, db.SetAddressStreetPartsParams{
+		ID:            q.addresses[0].ID,
+		StreetAddress: dbtest.Text("Flat 3, 12 High Street"),
+		StreetNumber:  dbtest.Text("12"),
+		StreetName:    dbtest.Text("High Street"),
+		StreetUnit:    dbtest.Text("Flat 3"),
+	}, q.set[0])
+}
+
+func TestLabels(t *testing.T) {
+	contactID := uuid.New()
+	q := &fakeQuerier{mailing: []db.ListMailingAddressesRow{{
+		ContactID:     contactID,
+		DisplayName:   dbtest.Text("Ada Lovelace"),
+		StreetAddress: dbtest.Text("12 bis rue de la Paix"),
+		City:          dbtest.Text("Paris"),
+		PostalCode:    dbtest.Text("75002"),
+		Country:       dbtest.Text("France"),
+		CountryCode:   dbtest.Text("FR"),
+	}}}
+	service := &Service{queries: q, tx: dbtest.Tx{Q: q}, logger: logger.NewNop()}
+
+	labels, err := service.Labels(context.Background(), []uuid.UUID{contactID}, "US")
+	require.NoError(t, err)
+	assert.Equal(t, []Label{{ContactID: contactID, Lines: []string{"Ada Lovelace", "12 bis rue de la Paix", "75002 PARIS", "FRANCE"}}}, labels)
+}
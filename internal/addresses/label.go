@@ -0,0 +1,95 @@
+package addresses
+
+import (
+	"strings"
+)
+
+// LabelAddress is an address to print on a mailing label. State is the
+// state or province abbreviation; Country is the country name and
+// CountryCode its ISO code.
+type LabelAddress struct {
+	Addressee            string
+	StreetAddress        string
+	SupplementalAddress1 string
+	City                 string
+	State                string
+	PostalCode           string
+	Country              string
+	CountryCode          string
+}
+
+// localityFormats render the city, state and postal code lines of a label by
+// country ISO code, following each postal service's addressing guide
+var localityFormats = map[string]func(a LabelAddress) []string{
+	// CITY, ST 12345
+	"US": func(a LabelAddress) []string {
+		return []string{join(", ", a.City, join(" ", a.State, a.PostalCode))}
+	},
+	// CITY PROV  A1A 1A1, with two spaces before the postal code
+	"CA": func(a LabelAddress) []string {
+		return []string{join("  ", join(" ", a.City, a.State), a.PostalCode)}
+	},
+	// The post town and the postcode on lines of their own
+	"GB": func(a LabelAddress) []string {
+		return []string{strings.ToUpper(a.City), a.PostalCode}
+	},
+	// 75001 PARIS
+	"FR": func(a LabelAddress) []string {
+		return []string{join(" ", a.PostalCode, strings.ToUpper(a.City))}
+	},
+	// CITY STATE  1234
+	"AU": func(a LabelAddress) []string {
+		return []string{join("  ", strings.ToUpper(join(" ", a.City, a.State)), a.PostalCode)}
+	},
+	// 123-4567 then the prefecture and city
+	"JP": func(a LabelAddress) []string {
+		return []string{a.PostalCode, join(" ", a.State, a.City)}
+	},
+}
+
+// postalCodeFirst are the countries that write the postal code before the
+// city, as "10115 Berlin"
+var postalCodeFirst = map[string]bool{
+	"DE": true, "AT": true, "CH": true, "NL": true, "BE": true,
+	"ES": true, "IT": true, "MX": true,
+}
+
+// FormatLabel renders an address as the lines of a mailing label in the
+// format of its country. The country is printed last, in capitals, unless it
+// is fromCountry, the ISO code of the country the mail is sent from.
+func FormatLabel(a LabelAddress, fromCountry string) []string {
+	country := strings.ToUpper(a.CountryCode)
+	a.PostalCode = NormalizePostalCode(country, a.PostalCode)
+
+	lines := []string{a.Addressee, a.StreetAddress, a.SupplementalAddress1}
+	switch format, ok := localityFormats[country]; {
+	case ok:
+		lines = append(lines, format(a)...)
+	case postalCodeFirst[country]:
+		lines = append(lines, join(" ", a.PostalCode, a.City))
+	default:
+		lines = append(lines, join(" ", a.City, a.State, a.PostalCode))
+	}
+	if a.Country != "" && (country == "" || country != strings.ToUpper(fromCountry)) {
+		lines = append(lines, strings.ToUpper(a.Country))
+	}
+
+	label := lines[:0]
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			label = append(label, line)
+		}
+	}
+	return label
+}
+
+// join joins the non-empty parts with a separator
+func join(sep string, parts ...string) string {
+	var nonEmpty []string
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	return strings.Join(nonEmpty, sep)
+}
@@ -0,0 +1,57 @@
+package addresses
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatLabel(t *testing.T) {
+	tests := []struct {
+		name    string
+		address LabelAddress
+		from    string
+		want    []string
+	}{
+		{
+			name:    "US domestic",
+			address: LabelAddress{Addressee: "Ada Lovelace", StreetAddress: "123 Main St", City: "Springfield", State: "IL", PostalCode: "62701", Country: "United States", CountryCode: "US"},
+			from:    "US",
+			want:    []string{"Ada Lovelace", "123 Main St", "Springfield, IL 62701"},
+		},
+		{
+			name:    "Canada from abroad",
+			address: LabelAddress{Addressee: "Ada Lovelace", StreetAddress: "24 Sussex Dr", City: "Ottawa", State: "ON", PostalCode: "k1m1m4", Country: "Canada", CountryCode: "CA"},
+			from:    "US",
+			want:    []string{"Ada Lovelace", "24 Sussex Dr", "Ottawa ON  K1M 1M4", "CANADA"},
+		},
+		{
+			name:    "UK",
+			address: LabelAddress{Addressee: "Ada Lovelace", StreetAddress: "Flat 3, 12 High Street", City: "London", PostalCode: "sw1a1aa", Country: "United Kingdom", CountryCode: "GB"},
+			from:    "GB",
+			want:    []string{"Ada Lovelace", "Flat 3, 12 High Street", "LONDON", "SW1A 1AA"},
+		},
+		{
+			name:    "France",
+			address: LabelAddress{Addressee: "Ada Lovelace", StreetAddress: "12 bis rue de la Paix", SupplementalAddress1: "Bâtiment B", City: "Paris", PostalCode: "75002", Country: "France", CountryCode: "FR"},
+			from:    "US",
+			want:    []string{"Ada Lovelace", "12 bis rue de la Paix", "Bâtiment B", "75002 PARIS", "FRANCE"},
+		},
+		{
+			name:    "Germany",
+			address: LabelAddress{StreetAddress: "Hauptstraße 5", City: "Berlin", PostalCode: "10115", Country: "Germany", CountryCode: "DE"},
+			from:    "DE",
+			want:    []string{"Hauptstraße 5", "10115 Berlin"},
+		},
+		{
+			name:    "no country",
+			address: LabelAddress{Addressee: "Ada Lovelace", City: "Springfield", PostalCode: "62701"},
+			want:    []string{"Ada Lovelace", "Springfield 62701"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, FormatLabel(tt.address, tt.from))
+		})
+	}
+}
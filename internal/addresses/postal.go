@@ -0,0 +1,80 @@
+package addresses
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// postalCodePatterns are the postal code formats of countries by ISO code,
+// matched against codes normalized by NormalizePostalCode
+var postalCodePatterns = map[string]*regexp.Regexp{
+	"US": regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+	"CA": regexp.MustCompile(`^[ABCEGHJ-NPRSTVXY]\d[ABCEGHJ-NPRSTV-Z] \d[ABCEGHJ-NPRSTV-Z]\d$`),
+	"GB": regexp.MustCompile(`^(GIR 0AA|[A-Z]{1,2}\d[A-Z\d]? \d[A-Z]{2})$`),
+	"FR": regexp.MustCompile(`^\d{5}$`),
+	"DE": regexp.MustCompile(`^\d{5}$`),
+	"AU": regexp.MustCompile(`^\d{4}$`),
+	"JP": regexp.MustCompile(`^\d{3}-\d{4}$`),
+	"CN": regexp.MustCompile(`^\d{6}$`),
+	"IN": regexp.MustCompile(`^[1-9]\d{5}$`),
+	"BR": regexp.MustCompile(`^\d{5}-\d{3}$`),
+	"MX": regexp.MustCompile(`^\d{5}$`),
+	"ZA": regexp.MustCompile(`^\d{4}$`),
+	"NL": regexp.MustCompile(`^\d{4} [A-Z]{2}$`),
+	"BE": regexp.MustCompile(`^\d{4}$`),
+	"CH": regexp.MustCompile(`^\d{4}$`),
+	"AT": regexp.MustCompile(`^\d{4}$`),
+	"ES": regexp.MustCompile(`^\d{5}$`),
+	"IT": regexp.MustCompile(`^\d{5}$`),
+}
+
+// NormalizePostalCode upper-cases a postal code and spaces it the way the
+// country writes it: "k1a0b1" becomes "K1A 0B1" in Canada, "sw1a1aa"
+// becomes "SW1A 1AA" in the UK
+func NormalizePostalCode(country, code string) string {
+	code = strings.ToUpper(strings.Join(strings.Fields(code), " "))
+	compact := strings.ReplaceAll(code, " ", "")
+
+	switch strings.ToUpper(country) {
+	case "CA", "GB":
+		// An outward code and a three character inward code
+		if len(compact) > 3 {
+			return compact[:len(compact)-3] + " " + compact[len(compact)-3:]
+		}
+	case "NL":
+		if len(compact) == 6 {
+			return compact[:4] + " " + compact[4:]
+		}
+	case "JP":
+		if len(compact) == 7 && !strings.Contains(compact, "-") {
+			return compact[:3] + "-" + compact[3:]
+		}
+	case "BR":
+		if len(compact) == 8 && !strings.Contains(compact, "-") {
+			return compact[:5] + "-" + compact[5:]
+		}
+	case "US":
+		if len(compact) == 9 && !strings.Contains(compact, "-") {
+			return compact[:5] + "-" + compact[5:]
+		}
+	}
+	if len(compact) < len(code) && postalCodePatterns[strings.ToUpper(country)] != nil {
+		return compact
+	}
+	return code
+}
+
+// ValidatePostalCode checks a postal code against the format of the country
+// with ISO code country. Codes of countries without a known format are
+// accepted.
+func ValidatePostalCode(country, code string) error {
+	pattern, ok := postalCodePatterns[strings.ToUpper(country)]
+	if !ok || code == "" {
+		return nil
+	}
+	if !pattern.MatchString(NormalizePostalCode(country, code)) {
+		return fmt.Errorf("%w: %q is not a %s postal code", ErrInvalidPostalCode, code, strings.ToUpper(country))
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package addresses
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizePostalCode(t *testing.T) {
+	assert.Equal(t, "K1A 0B1", NormalizePostalCode("CA", "k1a0b1"))
+	assert.Equal(t, "SW1A 1AA", NormalizePostalCode("GB", " sw1a  1aa"))
+	assert.Equal(t, "12345-6789", NormalizePostalCode("US", "123456789"))
+	assert.Equal(t, "75001", NormalizePostalCode("FR", "75 001"))
+	assert.Equal(t, "1012 AB", NormalizePostalCode("NL", "1012ab"))
+	assert.Equal(t, "AB 12", NormalizePostalCode("", "ab 12"))
+}
+
+func TestValidatePostalCode(t *testing.T) {
+	valid := map[string][]string{
+		"US": {"12345", "12345-6789", "123456789"},
+		"CA": {"K1A 0B1", "m5v3l9"},
+		"GB": {"SW1A 1AA", "EC1A1BB", "M1 1AE", "GIR 0AA"},
+		"FR": {"75001"},
+		"":   {"anything"},
+		"ZZ": {"anything"},
+	}
+	for country, codes := range valid {
+		for _, code := range codes {
+			assert.NoError(t, ValidatePostalCode(country, code), "%s %s", country, code)
+		}
+	}
+
+	invalid := map[string][]string{
+		"US": {"1234", "12345-67", "ABCDE"},
+		"CA": {"D1A 0B1", "K1A 0B"},
+		"GB": {"SW1A", "12345"},
+		"FR": {"7500", "750011"},
+	}
+	for country, codes := range invalid {
+		for _, code := range codes {
+			assert.ErrorIs(t, ValidatePostalCode(country, code), ErrInvalidPostalCode, "%s %s", country, code)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package addresses
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+)
+
+// ErrInvalidAddress is returned when an address names an unknown state or
+// country, a state of another country or a malformed postal code
+var ErrInvalidAddress = errors.New("invalid address")
+
+// Fields are the fields of an address as entered. StateProvince and Country
+// are names or codes, such as "Ontario" or "ON" and "Canada" or "CA".
+type Fields struct {
+	StreetAddress        string
+	SupplementalAddress1 string
+	City                 string
+	StateProvince        string
+	PostalCode           string
+	Country              string
+}
+
+// Resolve looks up the state and country of an address, checks its postal
+// code against the country's format and splits its street address
+func Resolve(ctx context.Context, q db.Querier, address Fields) (db.CreateAddressParams, error) {
+	params := db.CreateAddressParams{
+		StreetAddress:        nullString(address.StreetAddress),
+		SupplementalAddress1: nullString(address.SupplementalAddress1),
+		City:                 nullString(address.City),
+	}
+
+	var country db.Country
+	if name := strings.TrimSpace(address.Country); name != "" {
+		var err error
+		country, err = q.GetCountryByNameOrCode(ctx, name)
+		if errors.Is(err, sql.ErrNoRows) {
+			return params, fmt.Errorf("%w: unknown country %q", ErrInvalidAddress, name)
+		}
+		if err != nil {
+			return params, fmt.Errorf("failed to get country: %w", err)
+		}
+		params.CountryID = uuid.NullUUID{UUID: country.ID, Valid: true}
+	}
+
+	if name := strings.TrimSpace(address.StateProvince); name != "" {
+		state, err := q.GetStateProvinceByName(ctx, db.GetStateProvinceByNameParams{StateProvince: name, CountryID: params.CountryID})
+		if errors.Is(err, sql.ErrNoRows) && params.CountryID.Valid {
+			// Tell a state of another country from an unknown one
+			if _, err := q.GetStateProvinceByName(ctx, db.GetStateProvinceByNameParams{StateProvince: name}); err == nil {
+				return params, fmt.Errorf("%w: %q is not a state or province of %s", ErrInvalidAddress, name, country.Name)
+			}
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			return params, fmt.Errorf("%w: unknown state or province %q", ErrInvalidAddress, name)
+		}
+		if err != nil {
+			return params, fmt.Errorf("failed to get state or province: %w", err)
+		}
+		params.StateProvinceID = uuid.NullUUID{UUID: state.ID, Valid: true}
+		if !params.CountryID.Valid && state.CountryID.Valid {
+			if country, err = q.GetCountry(ctx, state.CountryID.UUID); err != nil {
+				return params, fmt.Errorf("failed to get country: %w", err)
+			}
+			params.CountryID = state.CountryID
+		}
+	}
+
+	if code := strings.TrimSpace(address.PostalCode); code != "" {
+		if err := ValidatePostalCode(country.IsoCode.String, code); err != nil {
+			return params, fmt.Errorf("%w: %w", ErrInvalidAddress, err)
+		}
+		params.PostalCode = nullString(NormalizePostalCode(country.IsoCode.String, code))
+	}
+
+	if params.StreetAddress.Valid {
+		street := ParseStreet(country.IsoCode.String, params.StreetAddress.String)
+		params.StreetNumber = nullString(street.Number)
+		params.StreetName = nullString(street.Name)
+		params.StreetUnit = nullString(street.Unit)
+	}
+	return params, nil
+}
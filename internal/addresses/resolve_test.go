@@ -0,0 +1,78 @@
+package addresses
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/database/dbtest"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// placesQuerier serves countries and states from slices
+type placesQuerier struct {
+	db.Querier
+	countries []db.Country
+	states    []db.StateProvince
+}
+
+func (q *placesQuerier) GetCountry(ctx context.Context, id uuid.UUID) (db.Country, error) {
+	for _, country := range q.countries {
+		if country.ID == id {
+			return country, nil
+		}
+	}
+	return db.Country{}, sql.ErrNoRows
+}
+
+func (q *placesQuerier) GetCountryByNameOrCode(ctx context.Context, name string) (db.Country, error) {
+	for _, country := range q.countries {
+		if strings.EqualFold(country.Name, name) || strings.EqualFold(country.IsoCode.String, name) {
+			return country, nil
+		}
+	}
+	return db.Country{}, sql.ErrNoRows
+}
+
+func (q *placesQuerier) GetStateProvinceByName(ctx context.Context, arg db.GetStateProvinceByNameParams) (db.StateProvince, error) {
+	for _, state := range q.states {
+		if (strings.EqualFold(state.Name, arg.StateProvince) || strings.EqualFold(state.Abbreviation.String, arg.StateProvince)) &&
+			(!arg.CountryID.Valid || state.CountryID == arg.CountryID) {
+			return state, nil
+		}
+	}
+	return db.StateProvince{}, sql.ErrNoRows
+}
+
+func TestResolve(t *testing.T) {
+	us := db.Country{ID: uuid.New(), Name: "United States", IsoCode: dbtest.Text("US")}
+	ca := db.Country{ID: uuid.New(), Name: "Canada", IsoCode: dbtest.Text("CA")}
+	ontario := db.StateProvince{ID: uuid.New(), Name: "Ontario", Abbreviation: dbtest.Text("ON"), CountryID: dbtest.UUID(ca.ID)}
+	q := &placesQuerier{countries: []db.Country{us, ca}, states: []db.StateProvince{ontario}}
+	ctx := context.Background()
+
+	t.Run("splits the street and normalizes the postal code", func(t *testing.T) {
+		params, err := Resolve(ctx, q, Fields{StreetAddress: "5-123 Main St", City: "Ottawa", StateProvince: "ON", PostalCode: "k1a0b1"})
+		require.NoError(t, err)
+		assert.Equal(t, dbtest.UUID(ca.ID), params.CountryID)
+		assert.Equal(t, "K1A 0B1", params.PostalCode.String)
+		assert.Equal(t, "123", params.StreetNumber.String)
+		assert.Equal(t, "Main St", params.StreetName.String)
+		assert.Equal(t, "5", params.StreetUnit.String)
+	})
+
+	t.Run("rejects a state of another country", func(t *testing.T) {
+		_, err := Resolve(ctx, q, Fields{StateProvince: "Ontario", Country: "US"})
+		assert.ErrorIs(t, err, ErrInvalidAddress)
+		assert.ErrorContains(t, err, "not a state or province of United States")
+	})
+
+	t.Run("rejects a malformed postal code", func(t *testing.T) {
+		_, err := Resolve(ctx, q, Fields{PostalCode: "K1A 0B1", Country: "US"})
+		assert.ErrorIs(t, err, ErrInvalidAddress)
+	})
+}
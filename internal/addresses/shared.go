@@ -0,0 +1,289 @@
+package addresses
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+)
+
+// sharedRelationships name the relationship created when an individual
+// shares the address of a contact of another type
+var sharedRelationships = map[string]string{
+	"Household":    "Household Member of",
+	"Organization": "Employee of",
+}
+
+// Changes are the fields of an address to change. Nil fields keep their
+// value.
+type Changes struct {
+	StreetAddress        *string
+	SupplementalAddress1 *string
+	City                 *string
+	StateProvince        *string
+	PostalCode           *string
+	Country              *string
+}
+
+// Shared is an address shared from another contact's, with the relationship
+// created for it, if any
+type Shared struct {
+	Address      db.Address       `json:"address"`
+	Relationship *db.Relationship `json:"relationship"`
+}
+
+// Owners returns the contact of an address and, for a shared address, the
+// contact of its master
+func (s *Service) Owners(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+	address, err := s.queries.GetAddress(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("address %s %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get address: %w", err)
+	}
+	owners := []uuid.UUID{address.ContactID}
+	if !address.MasterID.Valid {
+		return owners, nil
+	}
+
+	master, err := s.queries.GetAddress(ctx, address.MasterID.UUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get master address: %w", err)
+	}
+	return append(owners, master.ContactID), nil
+}
+
+// Update changes an address and, through the trigger from migration 050,
+// every address shared from it. A shared address changes only through its
+// master.
+func (s *Service) Update(ctx context.Context, id uuid.UUID, changes Changes) (*db.Address, error) {
+	var updated db.Address
+	err := s.tx.WithTx(ctx, func(q db.Querier) error {
+		current, err := q.GetAddressForUpdate(ctx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("address %s %w", id, ErrNotFound)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get address: %w", err)
+		}
+		if current.MasterID.Valid {
+			return fmt.Errorf("%w: change address %s instead", ErrShared, current.MasterID.UUID)
+		}
+
+		fields, err := currentFields(ctx, q, current)
+		if err != nil {
+			return err
+		}
+		for _, change := range []struct {
+			value *string
+			field *string
+		}{
+			{changes.StreetAddress, &fields.StreetAddress},
+			{changes.SupplementalAddress1, &fields.SupplementalAddress1},
+			{changes.City, &fields.City},
+			{changes.StateProvince, &fields.StateProvince},
+			{changes.PostalCode, &fields.PostalCode},
+			{changes.Country, &fields.Country},
+		} {
+			if change.value != nil {
+				*change.field = *change.value
+			}
+		}
+
+		resolved, err := Resolve(ctx, q, fields)
+		if err != nil {
+			return err
+		}
+		updated, err = q.UpdateAddress(ctx, db.UpdateAddressParams{
+			ID:                   id,
+			StreetAddress:        resolved.StreetAddress,
+			StreetNumber:         resolved.StreetNumber,
+			StreetName:           resolved.StreetName,
+			StreetUnit:           resolved.StreetUnit,
+			SupplementalAddress1: resolved.SupplementalAddress1,
+			City:                 resolved.City,
+			StateProvinceID:      resolved.StateProvinceID,
+			PostalCode:           resolved.PostalCode,
+			CountryID:            resolved.CountryID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update address: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// currentFields returns the fields of a stored address, naming its state and
+// country by their codes
+func currentFields(ctx context.Context, q db.Querier, address db.Address) (Fields, error) {
+	fields := Fields{
+		StreetAddress:        address.StreetAddress.String,
+		SupplementalAddress1: address.SupplementalAddress1.String,
+		City:                 address.City.String,
+		PostalCode:           address.PostalCode.String,
+	}
+	if address.StateProvinceID.Valid {
+		state, err := q.GetStateProvince(ctx, address.StateProvinceID.UUID)
+		if err != nil {
+			return fields, fmt.Errorf("failed to get state or province: %w", err)
+		}
+		fields.StateProvince = state.Name
+	}
+	if address.CountryID.Valid {
+		country, err := q.GetCountry(ctx, address.CountryID.UUID)
+		if err != nil {
+			return fields, fmt.Errorf("failed to get country: %w", err)
+		}
+		fields.Country = country.Name
+	}
+	return fields, nil
+}
+
+// Share gives a contact a primary address shared from another contact's
+// address, which the contact then uses until it is unshared. An address
+// that is itself shared is shared from its master instead. An individual
+// sharing a household's or organization's address becomes its member or
+// employee, unless they already are.
+func (s *Service) Share(ctx context.Context, contactID, masterID uuid.UUID) (*Shared, error) {
+	var shared Shared
+	err := s.tx.WithTx(ctx, func(q db.Querier) error {
+		master, err := q.GetAddress(ctx, masterID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("address %s %w", masterID, ErrNotFound)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get address: %w", err)
+		}
+		if master.MasterID.Valid {
+			if master, err = q.GetAddress(ctx, master.MasterID.UUID); err != nil {
+				return fmt.Errorf("failed to get address: %w", err)
+			}
+		}
+		if master.ContactID == contactID {
+			return fmt.Errorf("%w: the address already belongs to the contact", ErrInvalidShare)
+		}
+
+		contact, err := getContact(ctx, q, contactID)
+		if err != nil {
+			return err
+		}
+		owner, err := getContact(ctx, q, master.ContactID)
+		if err != nil {
+			return err
+		}
+		if contact.DomainID != owner.DomainID {
+			return fmt.Errorf("%w: the contacts are in different domains", ErrInvalidShare)
+		}
+
+		shared.Address, err = q.CreateSharedAddress(ctx, db.CreateSharedAddressParams{
+			ContactID:      contactID,
+			LocationTypeID: master.LocationTypeID,
+			IsPrimary:      sql.NullBool{Bool: true, Valid: true},
+			MasterID:       uuid.NullUUID{UUID: master.ID, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create shared address: %w", err)
+		}
+
+		name, ok := sharedRelationships[owner.ContactType]
+		if !ok || contact.ContactType != "Individual" {
+			return nil
+		}
+		shared.Relationship, err = s.relate(ctx, q, name, contact.ID, owner.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &shared, nil
+}
+
+// relate creates an active relationship of the named type from contact A to
+// contact B starting today, unless there is one already or the type is
+// disabled
+func (s *Service) relate(ctx context.Context, q db.Querier, name string, contactIDA, contactIDB uuid.UUID) (*db.Relationship, error) {
+	relType, err := q.GetRelationshipTypeByName(ctx, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get relationship type: %w", err)
+	}
+	if relType.IsActive.Valid && !relType.IsActive.Bool {
+		return nil, nil
+	}
+
+	exists, err := q.HasActiveRelationship(ctx, db.HasActiveRelationshipParams{
+		ContactIDA:         contactIDA,
+		ContactIDB:         contactIDB,
+		RelationshipTypeID: relType.ID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check relationships: %w", err)
+	}
+	if exists {
+		return nil, nil
+	}
+
+	y, m, d := s.now().Date()
+	relationship, err := q.CreateRelationship(ctx, db.CreateRelationshipParams{
+		ContactIDA:         contactIDA,
+		ContactIDB:         contactIDB,
+		RelationshipTypeID: relType.ID,
+		StartDate:          sql.NullTime{Time: time.Date(y, m, d, 0, 0, 0, 0, time.UTC), Valid: true},
+		IsActive:           sql.NullBool{Bool: true, Valid: true},
+		IsPermissionAB:     sql.NullBool{Valid: true},
+		IsPermissionBA:     sql.NullBool{Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create relationship: %w", err)
+	}
+	return &relationship, nil
+}
+
+// Unshare makes a shared address independent of its master, keeping its
+// fields. Relationships created when it was shared are kept.
+func (s *Service) Unshare(ctx context.Context, id uuid.UUID) (*db.Address, error) {
+	var address db.Address
+	err := s.tx.WithTx(ctx, func(q db.Querier) error {
+		current, err := q.GetAddressForUpdate(ctx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("address %s %w", id, ErrNotFound)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get address: %w", err)
+		}
+		if !current.MasterID.Valid {
+			return fmt.Errorf("%w: the address is not shared", ErrInvalidShare)
+		}
+		if address, err = q.UnshareAddress(ctx, id); err != nil {
+			return fmt.Errorf("failed to unshare address: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
+
+// getContact returns a contact that is not deleted
+func getContact(ctx context.Context, q db.Querier, id uuid.UUID) (db.Contact, error) {
+	contact, err := q.GetContact(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && contact.IsDeleted) {
+		return contact, fmt.Errorf("contact %s %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return contact, fmt.Errorf("failed to get contact: %w", err)
+	}
+	return contact, nil
+}
@@ -0,0 +1,233 @@
+package addresses
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/database/dbtest"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/jxlxx/civicrm/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// shareQuerier serves addresses, contacts and relationship types from maps
+// and records what is written
+type shareQuerier struct {
+	placesQuerier
+	addresses     map[uuid.UUID]db.Address
+	contacts      map[uuid.UUID]db.Contact
+	types         []db.RelationshipType
+	relationships []db.Relationship
+	updated       []db.UpdateAddressParams
+}
+
+func (q *shareQuerier) GetAddress(ctx context.Context, id uuid.UUID) (db.Address, error) {
+	address, ok := q.addresses[id]
+	if !ok {
+		return db.Address{}, sql.ErrNoRows
+	}
+	return address, nil
+}
+
+func (q *shareQuerier) GetAddressForUpdate(ctx context.Context, id uuid.UUID) (db.Address, error) {
+	return q.GetAddress(ctx, id)
+}
+
+func (q *shareQuerier) GetContact(ctx context.Context, id uuid.UUID) (db.Contact, error) {
+	contact, ok := q.contacts[id]
+	if !ok {
+		return db.Contact{}, sql.ErrNoRows
+	}
+	return contact, nil
+}
+
+func (q *shareQuerier) GetStateProvince(ctx context.Context, id uuid.UUID) (db.StateProvince, error) {
+	for _, state := range q.states {
+		if state.ID == id {
+			return state, nil
+		}
+	}
+	return db.StateProvince{}, sql.ErrNoRows
+}
+
+func (q *shareQuerier) UpdateAddress(ctx context.Context, arg db.UpdateAddressParams) (db.Address, error) {
+	q.updated = append(q.updated, arg)
+	address := q.addresses[arg.ID]
+	address.StreetAddress, address.City, address.PostalCode = arg.StreetAddress, arg.City, arg.PostalCode
+	address.StateProvinceID, address.CountryID = arg.StateProvinceID, arg.CountryID
+	q.addresses[arg.ID] = address
+	return address, nil
+}
+
+// CreateSharedAddress copies the master's fields, as the trigger does
+func (q *shareQuerier) CreateSharedAddress(ctx context.Context, arg db.CreateSharedAddressParams) (db.Address, error) {
+	address := q.addresses[arg.MasterID.UUID]
+	address.ID, address.ContactID, address.LocationTypeID, address.IsPrimary, address.MasterID = uuid.New(), arg.ContactID, arg.LocationTypeID, arg.IsPrimary, arg.MasterID
+	q.addresses[address.ID] = address
+	return address, nil
+}
+
+func (q *shareQuerier) UnshareAddress(ctx context.Context, id uuid.UUID) (db.Address, error) {
+	address := q.addresses[id]
+	address.MasterID = uuid.NullUUID{}
+	q.addresses[id] = address
+	return address, nil
+}
+
+func (q *shareQuerier) GetRelationshipTypeByName(ctx context.Context, name string) (db.RelationshipType, error) {
+	for _, relType := range q.types {
+		if relType.NameAB == name {
+			return relType, nil
+		}
+	}
+	return db.RelationshipType{}, sql.ErrNoRows
+}
+
+func (q *shareQuerier) HasActiveRelationship(ctx context.Context, arg db.HasActiveRelationshipParams) (bool, error) {
+	for _, r := range q.relationships {
+		if r.ContactIDA == arg.ContactIDA && r.ContactIDB == arg.ContactIDB && r.RelationshipTypeID == arg.RelationshipTypeID && r.IsActive.Bool {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (q *shareQuerier) CreateRelationship(ctx context.Context, arg db.CreateRelationshipParams) (db.Relationship, error) {
+	relationship := db.Relationship{
+		ID:                 uuid.New(),
+		ContactIDA:         arg.ContactIDA,
+		ContactIDB:         arg.ContactIDB,
+		RelationshipTypeID: arg.RelationshipTypeID,
+		StartDate:          arg.StartDate,
+		IsActive:           arg.IsActive,
+	}
+	q.relationships = append(q.relationships, relationship)
+	return relationship, nil
+}
+
+// shareFixture is a household with an address in Ontario and an individual
+// in the same domain
+type shareFixture struct {
+	service    *Service
+	q          *shareQuerier
+	household  db.Contact
+	individual db.Contact
+	address    db.Address
+	member     db.RelationshipType
+}
+
+func newShareFixture(t *testing.T) *shareFixture {
+	t.Helper()
+	domainID := uuid.New()
+	ca := db.Country{ID: uuid.New(), Name: "Canada", IsoCode: dbtest.Text("CA")}
+	ontario := db.StateProvince{ID: uuid.New(), Name: "Ontario", Abbreviation: dbtest.Text("ON"), CountryID: dbtest.UUID(ca.ID)}
+	f := &shareFixture{
+		household:  db.Contact{ID: uuid.New(), DomainID: domainID, ContactType: "Household"},
+		individual: db.Contact{ID: uuid.New(), DomainID: domainID, ContactType: "Individual"},
+		member:     db.RelationshipType{ID: uuid.New(), NameAB: "Household Member of"},
+	}
+	f.address = db.Address{
+		ID:              uuid.New(),
+		ContactID:       f.household.ID,
+		StreetAddress:   dbtest.Text("24 Sussex Dr"),
+		City:            dbtest.Text("Ottawa"),
+		StateProvinceID: dbtest.UUID(ontario.ID),
+		PostalCode:      dbtest.Text("K1M 1M4"),
+		CountryID:       dbtest.UUID(ca.ID),
+	}
+	f.q = &shareQuerier{
+		placesQuerier: placesQuerier{countries: []db.Country{ca}, states: []db.StateProvince{ontario}},
+		addresses:     map[uuid.UUID]db.Address{f.address.ID: f.address},
+		contacts:      map[uuid.UUID]db.Contact{f.household.ID: f.household, f.individual.ID: f.individual},
+		types:         []db.RelationshipType{f.member},
+	}
+	f.service = &Service{
+		queries: f.q,
+		tx:      dbtest.Tx{Q: f.q},
+		logger:  logger.NewNop(),
+		now:     func() time.Time { return time.Date(2024, 5, 15, 12, 0, 0, 0, time.UTC) },
+	}
+	return f
+}
+
+func TestShare(t *testing.T) {
+	f := newShareFixture(t)
+	ctx := context.Background()
+
+	shared, err := f.service.Share(ctx, f.individual.ID, f.address.ID)
+	require.NoError(t, err)
+	assert.Equal(t, f.individual.ID, shared.Address.ContactID)
+	assert.Equal(t, dbtest.UUID(f.address.ID), shared.Address.MasterID)
+	assert.True(t, shared.Address.IsPrimary.Bool)
+	require.NotNil(t, shared.Relationship)
+	assert.Equal(t, f.individual.ID, shared.Relationship.ContactIDA)
+	assert.Equal(t, f.household.ID, shared.Relationship.ContactIDB)
+	assert.Equal(t, f.member.ID, shared.Relationship.RelationshipTypeID)
+	assert.Equal(t, time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC), shared.Relationship.StartDate.Time)
+
+	// Sharing the copy shares the master, and the member is related once
+	again, err := f.service.Share(ctx, f.individual.ID, shared.Address.ID)
+	require.NoError(t, err)
+	assert.Equal(t, dbtest.UUID(f.address.ID), again.Address.MasterID)
+	assert.Nil(t, again.Relationship)
+	assert.Len(t, f.q.relationships, 1)
+
+	_, err = f.service.Share(ctx, f.household.ID, f.address.ID)
+	assert.ErrorIs(t, err, ErrInvalidShare)
+	_, err = f.service.Share(ctx, uuid.New(), f.address.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestUpdate(t *testing.T) {
+	f := newShareFixture(t)
+	ctx := context.Background()
+
+	street := "7 Rideau Gate"
+	updated, err := f.service.Update(ctx, f.address.ID, Changes{StreetAddress: &street})
+	require.NoError(t, err)
+	assert.Equal(t, "7 Rideau Gate", updated.StreetAddress.String)
+	require.Len(t, f.q.updated, 1)
+	assert.Equal(t, "7", f.q.updated[0].StreetNumber.String)
+	assert.Equal(t, "Rideau Gate", f.q.updated[0].StreetName.String)
+	assert.Equal(t, "Ottawa", f.q.updated[0].City.String)
+	assert.Equal(t, f.address.StateProvinceID, f.q.updated[0].StateProvinceID)
+
+	postalCode := "90210"
+	_, err = f.service.Update(ctx, f.address.ID, Changes{PostalCode: &postalCode})
+	assert.ErrorIs(t, err, ErrInvalidAddress)
+
+	shared, err := f.service.Share(ctx, f.individual.ID, f.address.ID)
+	require.NoError(t, err)
+	_, err = f.service.Update(ctx, shared.Address.ID, Changes{StreetAddress: &street})
+	assert.ErrorIs(t, err, ErrShared)
+
+	unshared, err := f.service.Unshare(ctx, shared.Address.ID)
+	require.NoError(t, err)
+	assert.False(t, unshared.MasterID.Valid)
+	_, err = f.service.Unshare(ctx, shared.Address.ID)
+	assert.ErrorIs(t, err, ErrInvalidShare)
+	_, err = f.service.Update(ctx, shared.Address.ID, Changes{StreetAddress: &street})
+	assert.NoError(t, err)
+}
+
+func TestOwners(t *testing.T) {
+	f := newShareFixture(t)
+	ctx := context.Background()
+
+	owners, err := f.service.Owners(ctx, f.address.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{f.household.ID}, owners)
+
+	shared, err := f.service.Share(ctx, f.individual.ID, f.address.ID)
+	require.NoError(t, err)
+	owners, err = f.service.Owners(ctx, shared.Address.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{f.individual.ID, f.household.ID}, owners)
+
+	_, err = f.service.Owners(ctx, uuid.New())
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,147 @@
+package addresses
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Street is a street address split into its parts, as stored in
+// addresses.street_number, street_name and street_unit
+type Street struct {
+	Number string `json:"street_number"`
+	Name   string `json:"street_name"`
+	Unit   string `json:"street_unit"`
+}
+
+var (
+	// North American units, before or after the street: "Apt 4B", "Suite
+	// 200", "#12"
+	naUnitWords    = `apt|apartment|suite|ste|unit|room|rm|floor|fl|bldg|building`
+	naTrailingUnit = regexp.MustCompile(`(?i)(?:,\s*|\s+)((?:(?:` + naUnitWords + `)\.?\s*#?\s*|#\s*)[\w-]+)$`)
+	naLeadingUnit  = regexp.MustCompile(`(?i)^((?:` + naUnitWords + `)\.?\s*#?\s*[\w-]+|#\s*[\w-]+)\s*,?\s+`)
+	// Canadian unit before the number: "5-123 Main St"
+	caUnitNumber = regexp.MustCompile(`^(\w+)-(\d+[A-Za-z]?)\s+`)
+	// A leading number such as 123, 123A, 12-14 or 123 1/2
+	leadingNumber = regexp.MustCompile(`^(\d+[A-Za-z]?(?:-\d+[A-Za-z]?)?(?:\s+1/2)?)\s*,?\s+(.+)$`)
+
+	// British units before the street: "Flat 3, 12 High Street"
+	gbLeadingUnit = regexp.MustCompile(`(?i)^((?:flat|apartment|apt|unit|suite|room|studio)\s+[\w-]+)\s*,?\s+`)
+
+	// French units anywhere: "Appartement 12", "Bât. B", "Escalier 3"
+	frUnit = regexp.MustCompile(`(?i)(?:^|,\s*|\s+)((?:appartement|appt|apt|bâtiment|batiment|bât|bat|escalier|esc|étage|etage)\.?\s+[\w-]+)`)
+	// A French number with an optional repetition suffix: "12", "12 bis",
+	// "12B"
+	frNumber = regexp.MustCompile(`(?i)^(\d+)(?:\s*(bis|ter|quater)\b|([a-d])\b)?\s*,?\s+(.+)$`)
+
+	// A trailing number, as in Germany and the Netherlands: "Hauptstraße 5a"
+	trailingNumber = regexp.MustCompile(`^(.+?)\s+(\d+[A-Za-z]?(?:-\d+[A-Za-z]?)?)$`)
+)
+
+// numberLast are the countries that write the house number after the street
+// name
+var numberLast = map[string]bool{
+	"DE": true, "AT": true, "CH": true, "NL": true, "BE": true,
+	"ES": true, "IT": true, "SE": true, "BR": true, "MX": true,
+}
+
+// ParseStreet splits a street address into number, name and unit by the
+// conventions of the country with ISO code country. A street without a
+// number, such as "PO Box 12" or a house name, is all name.
+func ParseStreet(country, street string) Street {
+	street = strings.Join(strings.Fields(street), " ")
+	if street == "" {
+		return Street{}
+	}
+
+	switch strings.ToUpper(country) {
+	case "US", "CA", "":
+		return parseNorthAmerican(strings.ToUpper(country) == "CA", street)
+	case "GB", "UK", "IE":
+		return parseBritish(street)
+	case "FR":
+		return parseFrench(street)
+	}
+
+	if numberLast[strings.ToUpper(country)] {
+		if m := trailingNumber.FindStringSubmatch(street); m != nil {
+			return Street{Number: m[2], Name: trimName(m[1])}
+		}
+	}
+	if m := leadingNumber.FindStringSubmatch(street); m != nil {
+		return Street{Number: m[1], Name: trimName(m[2])}
+	}
+	return Street{Name: trimName(street)}
+}
+
+// parseNorthAmerican parses "123 Main St Apt 4", "Suite 200, 45 Elm Ave"
+// and, in Canada, "5-123 Main St"
+func parseNorthAmerican(canada bool, street string) Street {
+	var parsed Street
+	if m := naLeadingUnit.FindStringSubmatch(street); m != nil {
+		parsed.Unit = m[1]
+		street = street[len(m[0]):]
+	} else if m := naTrailingUnit.FindStringSubmatchIndex(street); m != nil && m[0] > 0 {
+		parsed.Unit = street[m[2]:m[3]]
+		street = street[:m[0]]
+	}
+	if canada && parsed.Unit == "" {
+		if m := caUnitNumber.FindStringSubmatch(street); m != nil {
+			parsed.Unit = m[1]
+			street = m[2] + " " + street[len(m[0]):]
+		}
+	}
+
+	if m := leadingNumber.FindStringSubmatch(street); m != nil {
+		parsed.Number, parsed.Name = m[1], trimName(m[2])
+	} else {
+		parsed.Name = trimName(street)
+	}
+	return parsed
+}
+
+// parseBritish parses "Flat 3, 12 High Street" and "12a High Street"
+func parseBritish(street string) Street {
+	var parsed Street
+	if m := gbLeadingUnit.FindStringSubmatch(street); m != nil {
+		parsed.Unit = m[1]
+		street = street[len(m[0]):]
+	}
+	if m := leadingNumber.FindStringSubmatch(street); m != nil {
+		parsed.Number, parsed.Name = m[1], trimName(m[2])
+	} else {
+		parsed.Name = trimName(street)
+	}
+	return parsed
+}
+
+// parseFrench parses "12 bis rue de la Paix, Appartement 5"
+func parseFrench(street string) Street {
+	var parsed Street
+	var units []string
+	for _, m := range frUnit.FindAllStringSubmatch(street, -1) {
+		units = append(units, m[1])
+	}
+	if len(units) > 0 {
+		parsed.Unit = strings.Join(units, ", ")
+		street = frUnit.ReplaceAllString(street, "")
+	}
+
+	if m := frNumber.FindStringSubmatch(strings.TrimSpace(street)); m != nil {
+		parsed.Number = m[1]
+		switch {
+		case m[2] != "":
+			parsed.Number += " " + strings.ToLower(m[2])
+		case m[3] != "":
+			parsed.Number += strings.ToUpper(m[3])
+		}
+		parsed.Name = trimName(m[4])
+	} else {
+		parsed.Name = trimName(street)
+	}
+	return parsed
+}
+
+// trimName removes separators left around a street name
+func trimName(name string) string {
+	return strings.Trim(name, " ,")
+}
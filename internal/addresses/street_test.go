@@ -0,0 +1,38 @@
+package addresses
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseStreet(t *testing.T) {
+	tests := []struct {
+		country, street string
+		want            Street
+	}{
+		{"US", "123 Main St", Street{Number: "123", Name: "Main St"}},
+		{"US", "123 Main St Apt 4B", Street{Number: "123", Name: "Main St", Unit: "Apt 4B"}},
+		{"US", "123 Main St, Suite 200", Street{Number: "123", Name: "Main St", Unit: "Suite 200"}},
+		{"US", "45 Elm Ave #12", Street{Number: "45", Name: "Elm Ave", Unit: "#12"}},
+		{"US", "123 1/2 Main St", Street{Number: "123 1/2", Name: "Main St"}},
+		{"US", "12-34 Queens Blvd", Street{Number: "12-34", Name: "Queens Blvd"}},
+		{"US", "PO Box 12", Street{Name: "PO Box 12"}},
+		{"CA", "5-123 Main St", Street{Number: "123", Name: "Main St", Unit: "5"}},
+		{"CA", "Unit 5, 123 Main St", Street{Number: "123", Name: "Main St", Unit: "Unit 5"}},
+		{"GB", "Flat 3, 12 High Street", Street{Number: "12", Name: "High Street", Unit: "Flat 3"}},
+		{"GB", "12a High Street", Street{Number: "12a", Name: "High Street"}},
+		{"GB", "Rose Cottage, Mill Lane", Street{Name: "Rose Cottage, Mill Lane"}},
+		{"FR", "12 bis rue de la Paix", Street{Number: "12 bis", Name: "rue de la Paix"}},
+		{"FR", "8B, avenue Foch", Street{Number: "8B", Name: "avenue Foch"}},
+		{"FR", "3 rue Oberkampf, Appartement 12", Street{Number: "3", Name: "rue Oberkampf", Unit: "Appartement 12"}},
+		{"DE", "Hauptstraße 5a", Street{Number: "5a", Name: "Hauptstraße"}},
+		{"AU", "  10   Collins   St ", Street{Number: "10", Name: "Collins St"}},
+		{"US", "", Street{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.country+" "+tt.street, func(t *testing.T) {
+			assert.Equal(t, tt.want, ParseStreet(tt.country, tt.street))
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/cache"
+	"github.com/jxlxx/civicrm/internal/config"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/jxlxx/civicrm/internal/logger"
+	"github.com/jxlxx/civicrm/internal/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAccess grants edit permission on the contacts in a set and records
+// the contacts asked about
+type fakeAccess struct {
+	editable map[uuid.UUID]bool
+	asked    []uuid.UUID
+}
+
+func (a *fakeAccess) CanUserEditContact(ctx context.Context, arg db.CanUserEditContactParams) (sql.NullBool, error) {
+	a.asked = append(a.asked, arg.ContactID)
+	return sql.NullBool{Bool: a.editable[arg.ContactID], Valid: true}, nil
+}
+
+// newAccessServer returns a server whose users may edit the given contacts
+func newAccessServer(editable ...uuid.UUID) (*Server, *fakeAccess) {
+	access := &fakeAccess{editable: make(map[uuid.UUID]bool)}
+	for _, id := range editable {
+		access.editable[id] = true
+	}
+	return &Server{access: access}, access
+}
+
+// asUser returns a context for a call by a signed in user with roles
+func asUser(roles ...string) context.Context {
+	return context.WithValue(context.Background(), userKey{}, &security.User{ID: uuid.NewString(), Roles: roles})
+}
+
+// assertStatus checks that err is an action error with status
+func assertStatus(t *testing.T, status int, err error) {
+	t.Helper()
+	var apiErr *actionError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, status, apiErr.Status)
+}
+
+func TestRequireContactEdit(t *testing.T) {
+	allowed, denied := uuid.New(), uuid.New()
+	server, access := newAccessServer(allowed)
+
+	_, err := server.requireContactEdit(context.Background(), allowed)
+	assertStatus(t, http.StatusUnauthorized, err)
+
+	badID := context.WithValue(context.Background(), userKey{}, &security.User{ID: "not-a-uuid"})
+	_, err = server.requireContactEdit(badID, allowed)
+	assertStatus(t, http.StatusUnauthorized, err)
+
+	_, err = server.requireContactEdit(asUser("user"), allowed)
+	assert.NoError(t, err)
+	_, err = server.requireContactEdit(asUser("user"), allowed, denied)
+	assertStatus(t, http.StatusForbidden, err)
+
+	access.asked = nil
+	_, err = server.requireContactEdit(asUser("admin"), denied)
+	assert.NoError(t, err)
+	assert.Empty(t, access.asked, "admins are not checked contact by contact")
+}
+
+func TestCachedAccess(t *testing.T) {
+	ctx := context.Background()
+	manager, err := cache.New(&config.CacheConfig{Driver: "memory", TTL: time.Minute, MaxEntries: 100}, logger.NewNop())
+	require.NoError(t, err)
+	defer manager.Close()
+
+	contact := uuid.New()
+	fake := &fakeAccess{editable: map[uuid.UUID]bool{contact: true}}
+	access := cachedAccess{access: fake, cache: manager}
+	arg := db.CanUserEditContactParams{UserID: uuid.New(), ContactID: contact}
+
+	for i := 0; i < 2; i++ {
+		allowed, err := access.CanUserEditContact(ctx, arg)
+		require.NoError(t, err)
+		assert.True(t, allowed.Bool)
+	}
+	assert.Len(t, fake.asked, 1, "the second check is cached")
+
+	fake.editable[contact] = false
+	require.NoError(t, manager.InvalidateTag(ctx, cache.TagACL))
+	allowed, err := access.CanUserEditContact(ctx, arg)
+	require.NoError(t, err)
+	assert.False(t, allowed.Bool, "permission changes drop cached checks")
+	assert.Len(t, fake.asked, 2)
+}
+
+func TestRelationshipWritesRequireEdit(t *testing.T) {
+	contactA, contactB := uuid.New(), uuid.New()
+	server, _ := newAccessServer(contactA)
+	params := Params{
+		"id":                   uuid.NewString(),
+		"contact_id_a":         contactA.String(),
+		"contact_id_b":         contactB.String(),
+		"relationship_type_id": uuid.NewString(),
+		"is_permission_a_b":    true,
+	}
+
+	for name, action := range map[string]Action{
+		"create": server.createRelationship,
+		"update": server.updateRelationship,
+		"delete": server.deleteRelationship,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := action(context.Background(), params)
+			assertStatus(t, http.StatusUnauthorized, err)
+		})
+	}
+
+	_, err := server.createRelationship(asUser("user"), params)
+	assertStatus(t, http.StatusForbidden, err)
+
+	existing := &db.Relationship{ID: uuid.New(), ContactIDA: contactA, ContactIDB: contactB}
+	assertStatus(t, http.StatusForbidden, server.requireRelationshipEdit(asUser("user"), existing))
+	assert.NoError(t, server.requireRelationshipEdit(asUser("admin"), existing))
+}
+
+func TestAddressWritesRequireEdit(t *testing.T) {
+	contact := uuid.New()
+	server, _ := newAccessServer()
+	params := Params{"id": uuid.NewString(), "contact_id": contact.String(), "master_id": uuid.NewString()}
+
+	for name, action := range map[string]Action{
+		"update":  server.updateAddress,
+		"share":   server.shareAddress,
+		"unshare": server.unshareAddress,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := action(context.Background(), params)
+			assertStatus(t, http.StatusUnauthorized, err)
+		})
+	}
+
+	_, err := server.shareAddress(asUser("user"), params)
+	assertStatus(t, http.StatusForbidden, err)
+}
+
+func TestContactWritesRequireUser(t *testing.T) {
+	server, _ := newAccessServer()
+	params := Params{"id": uuid.NewString(), "contact_type": "Individual", "first_name": "Ada"}
+
+	_, err := server.createContact(context.Background(), params)
+	assertStatus(t, http.StatusUnauthorized, err)
+	_, err = server.updateContact(context.Background(), params)
+	assertStatus(t, http.StatusUnauthorized, err)
+}
+
+func TestRequireRole(t *testing.T) {
+	_, err := requireRole(context.Background(), roleGroups)
+	assertStatus(t, http.StatusUnauthorized, err)
+	_, err = requireRole(asUser("user"), roleGroups)
+	assertStatus(t, http.StatusForbidden, err)
+	_, err = requireRole(asUser("user"))
+	assertStatus(t, http.StatusForbidden, err)
+
+	_, err = requireRole(asUser("user", roleGroups), roleGroups)
+	assert.NoError(t, err)
+	_, err = requireRole(asUser("admin"), roleGroups)
+	assert.NoError(t, err)
+}
+
+// assertRestricted checks that anonymous callers and users without roles
+// are turned away from registered actions before they run
+func assertRestricted(t *testing.T, server *Server, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			registered, ok := server.actions[name]
+			require.True(t, ok)
+			_, err := registered.handler(context.Background(), Params{})
+			assertStatus(t, http.StatusUnauthorized, err)
+			_, err = registered.handler(asUser("user"), Params{})
+			assertStatus(t, http.StatusForbidden, err)
+		})
+	}
+}
+
+func TestGroupWritesRequireRole(t *testing.T) {
+	server := &Server{actions: make(map[string]action)}
+	server.registerGroupActions()
+
+	assertRestricted(t, server, "Group.create", "Group.refresh",
+		"GroupNesting.create", "GroupNesting.delete",
+		"SavedSearch.create", "SavedSearch.update", "SavedSearch.delete")
+}
+
+func TestDedupeRuleGroupCreateRequiresAdmin(t *testing.T) {
+	server := &Server{actions: make(map[string]action)}
+	server.registerDedupeActions()
+
+	assertRestricted(t, server, "DedupeRuleGroup.create")
+}
+
+func TestGetDuplicatesIsLimited(t *testing.T) {
+	server, _ := newAccessServer()
+	server.scans = newLimiter(time.Minute)
+	ctx := asUser("user")
+
+	_, err := server.getDuplicates(context.Background(), Params{})
+	assertStatus(t, http.StatusUnauthorized, err)
+
+	server.scans.allow(currentUser(ctx).ID)
+	_, err = server.getDuplicates(ctx, Params{})
+	assertStatus(t, http.StatusTooManyRequests, err)
+}
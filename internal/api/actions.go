@@ -0,0 +1,363 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/security"
+)
+
+// Action implements one APIv4 entity action, such as Contact.getDuplicates.
+// It returns the records to send back as values.
+type Action func(ctx context.Context, params Params) (interface{}, error)
+
+// action is a registered action. Actions that change data are only served
+// for POST, PUT and DELETE requests.
+type action struct {
+	handler Action
+	write   bool
+}
+
+// registerActions registers the actions of every entity
+func (s *Server) registerActions() {
+	s.registerContactActions()
+	s.registerDedupeActions()
+	s.registerRelationshipActions()
+	s.registerGroupActions()
+	s.registerAddressActions()
+}
+
+// registerRead adds an action that only reads data
+func (s *Server) registerRead(entity, name string, handler Action) {
+	s.actions[entity+"."+name] = action{handler: handler}
+}
+
+// registerWrite adds an action that changes data
+func (s *Server) registerWrite(entity, name string, handler Action) {
+	s.actions[entity+"."+name] = action{handler: handler, write: true}
+}
+
+// dispatch runs the registered action for entity and name, and reports
+// whether there was one
+func (s *Server) dispatch(w http.ResponseWriter, r *http.Request, entity, name string) bool {
+	registered, exists := s.actions[entity+"."+name]
+	if !exists {
+		return false
+	}
+
+	if registered.write && r.Method == http.MethodGet {
+		writeError(w, &actionError{Status: http.StatusMethodNotAllowed, Code: "method_not_allowed", Message: fmt.Sprintf("%s.%s changes data and must be called with POST", entity, name)})
+		return true
+	}
+
+	params, err := readParams(r)
+	if err != nil {
+		writeError(w, err)
+		return true
+	}
+
+	ctx := r.Context()
+	if user := s.bearerUser(r); user != nil {
+		ctx = context.WithValue(ctx, userKey{}, user)
+	}
+
+	values, err := registered.handler(ctx, params)
+	if err != nil {
+		var apiErr *actionError
+		if !errors.As(err, &apiErr) && !errors.Is(err, sql.ErrNoRows) {
+			s.logger.Error("API action failed", "entity", entity, "action", name, "error", err)
+		}
+		writeError(w, err)
+		return true
+	}
+
+	if file, ok := values.(*fileResponse); ok {
+		writeFile(w, file)
+		return true
+	}
+
+	count := 0
+	if v := reflect.ValueOf(values); v.Kind() == reflect.Slice {
+		count = v.Len()
+		if v.IsNil() {
+			values = []interface{}{}
+		}
+	} else if values != nil {
+		values = []interface{}{values}
+		count = 1
+	} else {
+		values = []interface{}{}
+	}
+
+	writeJSON(w, http.StatusOK, actionResponse{Values: values, Count: count})
+	return true
+}
+
+// fileResponse is a file an action sends back as a download instead of
+// values
+type fileResponse struct {
+	Name        string
+	ContentType string
+	Body        []byte
+}
+
+// writeFile sends a file as an attachment
+func writeFile(w http.ResponseWriter, file *fileResponse) {
+	w.Header().Set("Content-Type", file.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.Name}))
+	w.Header().Set("Content-Length", strconv.Itoa(len(file.Body)))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(file.Body)
+}
+
+// userKey is the context key of the user calling an action
+type userKey struct{}
+
+// bearerUser returns the user of a valid bearer token, if any
+func (s *Server) bearerUser(r *http.Request) *security.User {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || token == "" {
+		return nil
+	}
+	user, err := s.security.ValidateToken(token)
+	if err != nil {
+		return nil
+	}
+	return user
+}
+
+// currentUser returns the user calling an action, or nil for anonymous calls
+func currentUser(ctx context.Context) *security.User {
+	user, _ := ctx.Value(userKey{}).(*security.User)
+	return user
+}
+
+// currentUserID returns the ID of the user calling an action, if known
+func currentUserID(ctx context.Context) uuid.NullUUID {
+	user := currentUser(ctx)
+	if user == nil {
+		return uuid.NullUUID{}
+	}
+	id, err := uuid.Parse(user.ID)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: id, Valid: true}
+}
+
+// actionResponse is APIResponse with values of any record type
+type actionResponse struct {
+	Values  interface{} `json:"values"`
+	Count   int         `json:"count"`
+	IsError bool        `json:"is_error"`
+}
+
+// actionError is an action error with the HTTP status and code to report
+type actionError struct {
+	Status  int
+	Code    string
+	Message string
+	Data    map[string]interface{}
+}
+
+func (e *actionError) Error() string {
+	return e.Message
+}
+
+// badRequest returns an error for invalid parameters
+func badRequest(format string, args ...interface{}) *actionError {
+	return &actionError{Status: http.StatusBadRequest, Code: "invalid_params", Message: fmt.Sprintf(format, args...)}
+}
+
+// unauthorized returns an error for an action that needs a signed in user
+func unauthorized() *actionError {
+	return &actionError{Status: http.StatusUnauthorized, Code: "unauthorized", Message: "Authentication required"}
+}
+
+// forbidden returns an error for an action the user may not perform
+func forbidden(format string, args ...interface{}) *actionError {
+	return &actionError{Status: http.StatusForbidden, Code: "forbidden", Message: fmt.Sprintf(format, args...)}
+}
+
+// tooManyRequests returns an error for an action called again too soon
+func tooManyRequests(format string, args ...interface{}) *actionError {
+	return &actionError{Status: http.StatusTooManyRequests, Code: "too_many_requests", Message: fmt.Sprintf(format, args...)}
+}
+
+// notFound returns an error for a missing record
+func notFound(format string, args ...interface{}) *actionError {
+	return &actionError{Status: http.StatusNotFound, Code: "not_found", Message: fmt.Sprintf(format, args...)}
+}
+
+// writeError writes err as an ErrorResponse. Errors other than *actionError are
+// reported as internal errors without their details.
+func writeError(w http.ResponseWriter, err error) {
+	var apiErr *actionError
+	switch {
+	case errors.As(err, &apiErr):
+	case errors.Is(err, sql.ErrNoRows):
+		apiErr = notFound("Record not found")
+	default:
+		apiErr = &actionError{Status: http.StatusInternalServerError, Code: "internal_error", Message: "Internal error"}
+	}
+
+	response := ErrorResponse{
+		IsError:      ptr(true),
+		ErrorCode:    ptr(apiErr.Code),
+		ErrorMessage: ptr(apiErr.Message),
+	}
+	if apiErr.Data != nil {
+		response.ErrorData = &apiErr.Data
+	}
+	writeJSON(w, apiErr.Status, response)
+}
+
+// Params holds the parameters of an action: query string values, the
+// APIv4 style "params" query parameter holding JSON, and the JSON body
+type Params map[string]interface{}
+
+// readParams collects the parameters of a request. Body values take
+// precedence over query values.
+func readParams(r *http.Request) (Params, error) {
+	params := make(Params)
+	for key, values := range r.URL.Query() {
+		if key != "params" && len(values) > 0 {
+			params[key] = values[0]
+		}
+	}
+
+	if raw := r.URL.Query().Get("params"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &params); err != nil {
+			return nil, badRequest("params must be a JSON object")
+		}
+	}
+
+	if r.Body != nil && r.Method != http.MethodGet {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, badRequest("Failed to read request body")
+		}
+		if len(body) > 0 {
+			if err := json.Unmarshal(body, &params); err != nil {
+				return nil, badRequest("Request body must be a JSON object")
+			}
+		}
+	}
+
+	return params, nil
+}
+
+// String returns a string parameter, or "" when it is not set
+func (p Params) String(name string) string {
+	switch value := p[name].(type) {
+	case string:
+		return value
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// Int returns an integer parameter, or fallback when it is not set
+func (p Params) Int(name string, fallback int) (int, error) {
+	switch value := p[name].(type) {
+	case nil:
+		return fallback, nil
+	case float64:
+		if value != float64(int(value)) {
+			return 0, badRequest("%s must be an integer", name)
+		}
+		return int(value), nil
+	case string:
+		if value == "" {
+			return fallback, nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, badRequest("%s must be an integer", name)
+		}
+		return n, nil
+	default:
+		return 0, badRequest("%s must be an integer", name)
+	}
+}
+
+// Float returns a number parameter and whether it was set
+func (p Params) Float(name string) (float64, bool, error) {
+	switch value := p[name].(type) {
+	case nil:
+		return 0, false, nil
+	case float64:
+		return value, true, nil
+	case string:
+		if value == "" {
+			return 0, false, nil
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return 0, false, badRequest("%s must be a number", name)
+		}
+		return f, true, nil
+	default:
+		return 0, false, badRequest("%s must be a number", name)
+	}
+}
+
+// Bool returns a boolean parameter, or fallback when it is not set
+func (p Params) Bool(name string, fallback bool) (bool, error) {
+	switch value := p[name].(type) {
+	case nil:
+		return fallback, nil
+	case bool:
+		return value, nil
+	case string:
+		if value == "" {
+			return fallback, nil
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return false, badRequest("%s must be true or false", name)
+		}
+		return b, nil
+	default:
+		return false, badRequest("%s must be true or false", name)
+	}
+}
+
+// UUID returns a UUID parameter and whether it was set
+func (p Params) UUID(name string) (uuid.UUID, bool, error) {
+	value := p.String(name)
+	if value == "" {
+		return uuid.Nil, false, nil
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, false, badRequest("%s must be a UUID", name)
+	}
+	return id, true, nil
+}
+
+// Decode converts the parameters into v through JSON, so v can use the same
+// field tags as the request body
+func (p Params) Decode(v interface{}) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return badRequest("Invalid parameters: %v", err)
+	}
+	return nil
+}
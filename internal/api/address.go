@@ -0,0 +1,178 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/addresses"
+)
+
+// registerAddressActions registers the Address actions
+func (s *Server) registerAddressActions() {
+	s.registerRead("Address", "parse", s.parseAddress)
+	s.registerRead("Address", "getMailingLabels", s.getMailingLabels)
+	s.registerWrite("Address", "update", s.updateAddress)
+	s.registerWrite("Address", "share", s.shareAddress)
+	s.registerWrite("Address", "unshare", s.unshareAddress)
+}
+
+// addressUpdate is the body of Address.update
+type addressUpdate struct {
+	StreetAddress        *string `json:"street_address"`
+	SupplementalAddress1 *string `json:"supplemental_address_1"`
+	City                 *string `json:"city"`
+	StateProvince        *string `json:"state_province"`
+	PostalCode           *string `json:"postal_code"`
+	Country              *string `json:"country"`
+}
+
+// addressError maps address service errors to API errors
+func addressError(err error) error {
+	switch {
+	case errors.Is(err, addresses.ErrNotFound):
+		return notFound("%v", err)
+	case errors.Is(err, addresses.ErrInvalidAddress), errors.Is(err, addresses.ErrShared), errors.Is(err, addresses.ErrInvalidShare):
+		return badRequest("%v", err)
+	}
+	return err
+}
+
+// parseAddress splits street_address into number, name and unit by the
+// conventions of country, an ISO code, and checks postal_code against the
+// country's format
+func (s *Server) parseAddress(ctx context.Context, params Params) (interface{}, error) {
+	country := params.String("country")
+	result := struct {
+		addresses.Street
+		PostalCode string `json:"postal_code,omitempty"`
+	}{Street: addresses.ParseStreet(country, params.String("street_address"))}
+
+	if code := params.String("postal_code"); code != "" {
+		if err := addresses.ValidatePostalCode(country, code); err != nil {
+			return nil, badRequest("%v", err)
+		}
+		result.PostalCode = addresses.NormalizePostalCode(country, code)
+	}
+	return result, nil
+}
+
+// getMailingLabels renders the mailing labels of the primary addresses of
+// contact_ids, or of contact_id. The country is printed on labels for
+// addresses outside from_country, an ISO code.
+func (s *Server) getMailingLabels(ctx context.Context, params Params) (interface{}, error) {
+	var input struct {
+		ContactIDs  []uuid.UUID `json:"contact_ids"`
+		FromCountry string      `json:"from_country"`
+	}
+	if err := params.Decode(&input); err != nil {
+		return nil, err
+	}
+	id, ok, err := params.UUID("contact_id")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		input.ContactIDs = append(input.ContactIDs, id)
+	}
+	if len(input.ContactIDs) == 0 {
+		return nil, badRequest("contact_id or contact_ids is required")
+	}
+	return s.services.Addresses.Labels(ctx, input.ContactIDs, input.FromCountry)
+}
+
+// updateAddress changes the address id; addresses shared from it change
+// with it. The caller must be able to edit the address's contact.
+func (s *Server) updateAddress(ctx context.Context, params Params) (interface{}, error) {
+	if _, err := requireUser(ctx); err != nil {
+		return nil, err
+	}
+	id, ok, err := params.UUID("id")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, badRequest("id is required")
+	}
+	if err := s.requireAddressEdit(ctx, id); err != nil {
+		return nil, err
+	}
+	var input addressUpdate
+	if err := params.Decode(&input); err != nil {
+		return nil, err
+	}
+
+	address, err := s.services.Addresses.Update(ctx, id, addresses.Changes(input))
+	if err != nil {
+		return nil, addressError(err)
+	}
+	return address, nil
+}
+
+// shareAddress gives contact_id a primary address shared from master_id.
+// The caller must be able to edit contact_id and the contact whose address
+// is shared.
+func (s *Server) shareAddress(ctx context.Context, params Params) (interface{}, error) {
+	if _, err := requireUser(ctx); err != nil {
+		return nil, err
+	}
+	contactID, hasContact, err := params.UUID("contact_id")
+	if err != nil {
+		return nil, err
+	}
+	masterID, hasMaster, err := params.UUID("master_id")
+	if err != nil {
+		return nil, err
+	}
+	if !hasContact || !hasMaster {
+		return nil, badRequest("contact_id and master_id are required")
+	}
+	if _, err := s.requireContactEdit(ctx, contactID); err != nil {
+		return nil, err
+	}
+	if err := s.requireAddressEdit(ctx, masterID); err != nil {
+		return nil, err
+	}
+
+	shared, err := s.services.Addresses.Share(ctx, contactID, masterID)
+	if err != nil {
+		return nil, addressError(err)
+	}
+	return shared, nil
+}
+
+// unshareAddress makes the shared address id independent of its master.
+// The caller must be able to edit the sharing contact and the contact of
+// the master.
+func (s *Server) unshareAddress(ctx context.Context, params Params) (interface{}, error) {
+	if _, err := requireUser(ctx); err != nil {
+		return nil, err
+	}
+	id, ok, err := params.UUID("id")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, badRequest("id is required")
+	}
+	if err := s.requireAddressEdit(ctx, id); err != nil {
+		return nil, err
+	}
+
+	address, err := s.services.Addresses.Unshare(ctx, id)
+	if err != nil {
+		return nil, addressError(err)
+	}
+	return address, nil
+}
+
+// requireAddressEdit checks that the caller may edit the contact of the
+// address id and, for a shared address, the contact of its master
+func (s *Server) requireAddressEdit(ctx context.Context, id uuid.UUID) error {
+	owners, err := s.services.Addresses.Owners(ctx, id)
+	if err != nil {
+		return addressError(err)
+	}
+	_, err = s.requireContactEdit(ctx, owners...)
+	return err
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jxlxx/civicrm/internal/security"
+)
+
+// defaultKeyLimit caps the number of keys returned by the cache keys endpoint
+const defaultKeyLimit = 1000
+
+// registerAdminRoutes adds the administrative endpoints to mux. They are not
+// part of the public API specification and require an admin token.
+func (s *Server) registerAdminRoutes(mux *http.ServeMux) {
+	mux.Handle("GET /admin/cache/stats", s.requireAdmin(http.HandlerFunc(s.CacheStats)))
+	mux.Handle("GET /admin/cache/keys", s.requireAdmin(http.HandlerFunc(s.CacheKeys)))
+	mux.Handle("POST /admin/cache/flush", s.requireAdmin(http.HandlerFunc(s.CacheFlush)))
+}
+
+// requireAdmin rejects requests that do not carry a valid bearer token for a
+// user with the admin role
+func (s *Server) requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || token == "" {
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+
+		user, err := s.security.ValidateToken(token)
+		if err != nil {
+			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		if !isAdmin(user) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isAdmin reports whether user has the admin role
+func isAdmin(user *security.User) bool {
+	for _, role := range user.Roles {
+		if role == "admin" {
+			return true
+		}
+	}
+	return false
+}
+
+// CacheStats returns hit, miss and eviction counters for each cache tier
+func (s *Server) CacheStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.cache.Stats())
+}
+
+// CacheKeys lists cached keys starting with the prefix query parameter
+func (s *Server) CacheKeys(w http.ResponseWriter, r *http.Request) {
+	limit := defaultKeyLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	keys, err := s.cache.Keys(r.Context(), r.URL.Query().Get("prefix"), limit)
+	if err != nil {
+		s.logger.Error("Failed to list cache keys", "error", err)
+		http.Error(w, "Failed to list cache keys", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys":  keys,
+		"count": len(keys),
+	})
+}
+
+// CacheFlush invalidates every entry with the tag query parameter, or the
+// whole cache when no tag is given
+func (s *Server) CacheFlush(w http.ResponseWriter, r *http.Request) {
+	tag := r.URL.Query().Get("tag")
+
+	var err error
+	if tag != "" {
+		err = s.cache.InvalidateTag(r.Context(), tag)
+	} else {
+		err = s.cache.Clear(r.Context())
+	}
+	if err != nil {
+		s.logger.Error("Failed to flush cache", "tag", tag, "error", err)
+		http.Error(w, "Failed to flush cache", http.StatusInternalServerError)
+		return
+	}
+
+	s.logger.Info("Cache flushed", "tag", tag)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"flushed": true,
+		"tag":     tag,
+	})
+}
+
+// writeJSON encodes v as the response body
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
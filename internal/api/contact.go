@@ -0,0 +1,563 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jxlxx/civicrm/internal/contacts"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/jxlxx/civicrm/internal/dedupe"
+)
+
+// registerContactActions registers the Contact actions
+func (s *Server) registerContactActions() {
+	s.registerRead("Contact", "get", s.getContact)
+	s.registerWrite("Contact", "create", s.createContact)
+	s.registerWrite("Contact", "update", s.updateContact)
+	s.registerRead("Contact", "getDuplicates", s.getDuplicates)
+	s.registerWrite("Contact", "merge", s.mergeContacts)
+	s.registerWrite("Contact", "exportPersonalData", s.exportPersonalData)
+	s.registerWrite("Contact", "anonymize", s.anonymizeContact)
+	s.registerRead("PersonalDataRequest", "get", s.getPersonalDataRequests)
+	s.registerRead("ContactType", "get", s.getContactTypes)
+}
+
+// nearLimit is the default and maxNearLimit the largest number of contacts
+// a proximity search returns
+const (
+	nearLimit    = 25
+	maxNearLimit = 500
+)
+
+// getContact returns a contact by id. A contact deleted by a merge returns
+// the contact it was merged into. Without an id, lat, lng and radius find
+// contacts near a point.
+func (s *Server) getContact(ctx context.Context, params Params) (interface{}, error) {
+	id, ok, err := params.UUID("id")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if params["lat"] != nil || params["lng"] != nil || params["radius"] != nil {
+			return s.getContactsNear(ctx, params)
+		}
+		return nil, badRequest("id or lat, lng and radius are required")
+	}
+
+	contact, err := s.services.Contacts.Get(ctx, id)
+	if errors.Is(err, contacts.ErrNotFound) {
+		return nil, notFound("Contact not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []*contacts.Contact{contact}, nil
+}
+
+// getContactsNear returns up to limit contacts whose geocoded primary
+// address lies within radius km of lat and lng, nearest first. The caller
+// must be signed in.
+func (s *Server) getContactsNear(ctx context.Context, params Params) (interface{}, error) {
+	if _, err := requireUser(ctx); err != nil {
+		return nil, err
+	}
+	lat, hasLat, err := params.Float("lat")
+	if err != nil {
+		return nil, err
+	}
+	lng, hasLng, err := params.Float("lng")
+	if err != nil {
+		return nil, err
+	}
+	radius, hasRadius, err := params.Float("radius")
+	if err != nil {
+		return nil, err
+	}
+	if !hasLat || !hasLng || !hasRadius {
+		return nil, badRequest("lat, lng and radius are required")
+	}
+	if lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		return nil, badRequest("lat must be within ±90 and lng within ±180")
+	}
+	if radius <= 0 {
+		return nil, badRequest("radius must be a positive number of km")
+	}
+	limit, err := params.Int("limit", nearLimit)
+	if err != nil {
+		return nil, err
+	}
+	if limit < 1 || limit > maxNearLimit {
+		return nil, badRequest("limit must be between 1 and %d", maxNearLimit)
+	}
+
+	return s.services.Contacts.Near(ctx, lat, lng, radius, limit)
+}
+
+// contactInput is the body of Contact.create. The email, phone and address
+// become the contact's primary location records.
+type contactInput struct {
+	ContactType          string   `json:"contact_type"`
+	ContactSubType       []string `json:"contact_sub_type"`
+	Prefix               string   `json:"prefix"`
+	FirstName            string   `json:"first_name"`
+	LastName             string   `json:"last_name"`
+	Suffix               string   `json:"suffix"`
+	NickName             string   `json:"nick_name"`
+	OrganizationName     string   `json:"organization_name"`
+	HouseholdName        string   `json:"household_name"`
+	Email                string   `json:"email"`
+	Phone                string   `json:"phone"`
+	StreetAddress        string   `json:"street_address"`
+	SupplementalAddress1 string   `json:"supplemental_address_1"`
+	City                 string   `json:"city"`
+	StateProvince        string   `json:"state_province"`
+	PostalCode           string   `json:"postal_code"`
+	Country              string   `json:"country"`
+}
+
+func (c contactInput) newContact() contacts.NewContact {
+	contact := contacts.NewContact{
+		CreateContactParams: db.CreateContactParams{
+			ContactType:      c.ContactType,
+			ContactSubType:   c.ContactSubType,
+			Prefix:           nullString(c.Prefix),
+			FirstName:        nullString(c.FirstName),
+			LastName:         nullString(c.LastName),
+			Suffix:           nullString(c.Suffix),
+			NickName:         nullString(c.NickName),
+			OrganizationName: nullString(c.OrganizationName),
+			HouseholdName:    nullString(c.HouseholdName),
+		},
+		Email: c.Email,
+		Phone: c.Phone,
+	}
+
+	address := contacts.NewAddress{
+		StreetAddress:        c.StreetAddress,
+		SupplementalAddress1: c.SupplementalAddress1,
+		City:                 c.City,
+		StateProvince:        c.StateProvince,
+		PostalCode:           c.PostalCode,
+		Country:              c.Country,
+	}
+	if address != (contacts.NewAddress{}) {
+		contact.Address = &address
+	}
+	return contact
+}
+
+// createContact creates a contact unless it duplicates an existing one under
+// the contact type's Unsupervised rule group. Pass dedupe_check=false to
+// create it anyway. The caller must be signed in.
+func (s *Server) createContact(ctx context.Context, params Params) (interface{}, error) {
+	if _, err := requireUser(ctx); err != nil {
+		return nil, err
+	}
+	var input contactInput
+	if err := params.Decode(&input); err != nil {
+		return nil, err
+	}
+	if !isContactType(input.ContactType) {
+		return nil, badRequest("contact_type must be Individual, Organization or Household")
+	}
+
+	check, err := params.Bool("dedupe_check", true)
+	if err != nil {
+		return nil, err
+	}
+
+	create := input.newContact()
+	if check {
+		if err := s.checkDuplicates(ctx, create); err != nil {
+			return nil, err
+		}
+	}
+
+	contact, err := s.services.Contacts.Create(ctx, create)
+	if errors.Is(err, contacts.ErrInvalidAddress) || errors.Is(err, contacts.ErrInvalidSubType) {
+		return nil, badRequest("%v", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []*contacts.Contact{contact}, nil
+}
+
+// contactUpdate is the body of Contact.update. Omitted fields keep their
+// value; an empty string clears one.
+type contactUpdate struct {
+	ContactType      *string   `json:"contact_type"`
+	ContactSubType   *[]string `json:"contact_sub_type"`
+	Prefix           *string   `json:"prefix"`
+	FirstName        *string   `json:"first_name"`
+	LastName         *string   `json:"last_name"`
+	Suffix           *string   `json:"suffix"`
+	NickName         *string   `json:"nick_name"`
+	OrganizationName *string   `json:"organization_name"`
+	HouseholdName    *string   `json:"household_name"`
+}
+
+// updateContact updates the type, subtypes and names of the contact id. Its
+// display and sort names are recomputed. The caller must be able to edit
+// the contact.
+func (s *Server) updateContact(ctx context.Context, params Params) (interface{}, error) {
+	if _, err := requireUser(ctx); err != nil {
+		return nil, err
+	}
+	id, ok, err := params.UUID("id")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, badRequest("id is required")
+	}
+
+	var input contactUpdate
+	if err := params.Decode(&input); err != nil {
+		return nil, err
+	}
+
+	current, err := s.services.Contacts.Get(ctx, id)
+	if errors.Is(err, contacts.ErrNotFound) {
+		return nil, notFound("Contact not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Get follows merge redirects, so this may update the surviving contact,
+	// which is the one the caller must be able to edit
+	if _, err := s.requireContactEdit(ctx, current.ID); err != nil {
+		return nil, err
+	}
+	update := db.UpdateContactParams{
+		ID:               current.ID,
+		ContactType:      current.ContactType,
+		ContactSubType:   current.ContactSubType,
+		Prefix:           current.Prefix,
+		FirstName:        current.FirstName,
+		LastName:         current.LastName,
+		Suffix:           current.Suffix,
+		NickName:         current.NickName,
+		OrganizationName: current.OrganizationName,
+		HouseholdName:    current.HouseholdName,
+	}
+	if input.ContactType != nil {
+		if !isContactType(*input.ContactType) {
+			return nil, badRequest("contact_type must be Individual, Organization or Household")
+		}
+		update.ContactType = *input.ContactType
+	}
+	if input.ContactSubType != nil {
+		update.ContactSubType = *input.ContactSubType
+	}
+	for _, field := range []struct {
+		input *string
+		value *sql.NullString
+	}{
+		{input.Prefix, &update.Prefix},
+		{input.FirstName, &update.FirstName},
+		{input.LastName, &update.LastName},
+		{input.Suffix, &update.Suffix},
+		{input.NickName, &update.NickName},
+		{input.OrganizationName, &update.OrganizationName},
+		{input.HouseholdName, &update.HouseholdName},
+	} {
+		if field.input != nil {
+			*field.value = nullString(*field.input)
+		}
+	}
+
+	contact, err := s.services.Contacts.Update(ctx, update)
+	switch {
+	case errors.Is(err, contacts.ErrNotFound):
+		return nil, notFound("Contact not found")
+	case errors.Is(err, contacts.ErrInvalidSubType):
+		return nil, badRequest("%v", err)
+	case err != nil:
+		return nil, err
+	}
+	return []*contacts.Contact{contact}, nil
+}
+
+// getContactTypes returns the active contact subtypes, optionally only those
+// of the contact type parent
+func (s *Server) getContactTypes(ctx context.Context, params Params) (interface{}, error) {
+	subTypes, err := s.services.Contacts.SubTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	parent := params.String("parent")
+	if parent == "" {
+		return subTypes, nil
+	}
+	filtered := make([]db.ContactSubType, 0, len(subTypes))
+	for _, subType := range subTypes {
+		if subType.Parent == parent {
+			filtered = append(filtered, subType)
+		}
+	}
+	return filtered, nil
+}
+
+// checkDuplicates returns a conflict error listing the existing contacts that
+// the new contact matches
+func (s *Server) checkDuplicates(ctx context.Context, create contacts.NewContact) error {
+	group, err := s.services.Dedupe.DefaultRuleGroup(ctx, create.ContactType, dedupe.UsedUnsupervised)
+	if errors.Is(err, dedupe.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	candidate := &dedupe.Candidate{Contact: db.Contact{
+		ContactType:      create.ContactType,
+		ContactSubType:   create.ContactSubType,
+		Prefix:           create.Prefix,
+		FirstName:        create.FirstName,
+		LastName:         create.LastName,
+		Suffix:           create.Suffix,
+		NickName:         create.NickName,
+		OrganizationName: create.OrganizationName,
+		HouseholdName:    create.HouseholdName,
+	}}
+	if create.Email != "" {
+		candidate.Emails = []db.Email{{Email: create.Email}}
+	}
+	if create.Phone != "" {
+		candidate.Phones = []db.Phone{{Phone: create.Phone}}
+	}
+	if address := create.Address; address != nil {
+		candidate.Addresses = []db.Address{{
+			StreetAddress: nullString(address.StreetAddress),
+			City:          nullString(address.City),
+			PostalCode:    nullString(address.PostalCode),
+		}}
+	}
+
+	matches, err := s.services.Dedupe.Match(ctx, group, candidate)
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+
+	return &actionError{
+		Status:  http.StatusConflict,
+		Code:    "duplicate",
+		Message: "Contact matches existing contacts",
+		Data: map[string]interface{}{
+			"rule_group": group.Name,
+			"duplicates": matches,
+		},
+	}
+}
+
+// getDuplicates returns scored duplicate pairs. The rule group is chosen by
+// rule_group_id or rule_group, otherwise the Supervised group of
+// contact_type (default Individual) is used. A scan reads every contact of
+// the type, so callers must be signed in and may start one scan per
+// duplicate scan interval.
+func (s *Server) getDuplicates(ctx context.Context, params Params) (interface{}, error) {
+	user, err := requireUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !s.scans.allow(user.ID) {
+		return nil, tooManyRequests("A duplicate scan was started less than %s ago", s.scans.interval)
+	}
+	group, err := s.ruleGroup(ctx, params, dedupe.UsedSupervised)
+	if err != nil {
+		return nil, err
+	}
+
+	batchSize, err := params.Int("batch_size", dedupe.DefaultBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	limit, err := params.Int("limit", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.services.Dedupe.FindDuplicates(ctx, group, dedupe.FindOptions{
+		BatchSize: batchSize,
+		Limit:     limit,
+	})
+}
+
+// mergeContacts merges the contact other_id into main_id. fields chooses
+// per contact field whether the "left" (main) or "right" (other) value is
+// kept. The caller must be signed in and allowed to edit both contacts.
+func (s *Server) mergeContacts(ctx context.Context, params Params) (interface{}, error) {
+	mainID, hasMain, err := params.UUID("main_id")
+	if err != nil {
+		return nil, err
+	}
+	otherID, hasOther, err := params.UUID("other_id")
+	if err != nil {
+		return nil, err
+	}
+	if !hasMain || !hasOther {
+		return nil, badRequest("main_id and other_id are required")
+	}
+	if _, err := s.requireContactEdit(ctx, mainID, otherID); err != nil {
+		return nil, err
+	}
+
+	var input struct {
+		Fields map[string]string `json:"fields"`
+	}
+	if err := params.Decode(&input); err != nil {
+		return nil, err
+	}
+
+	result, err := s.services.Contacts.Merge(ctx, mainID, otherID, contacts.MergeOptions{
+		Fields:   input.Fields,
+		MergedBy: currentUserID(ctx),
+	})
+	switch {
+	case errors.Is(err, contacts.ErrNotFound):
+		return nil, notFound("Contact not found")
+	case errors.Is(err, contacts.ErrCannotMerge):
+		return nil, badRequest("%v", err)
+	case err != nil:
+		return nil, err
+	}
+	return result, nil
+}
+
+// exportPersonalData returns everything stored about the contact id, as
+// JSON values or, with format zip, as a ZIP download. Each export is
+// recorded as a personal data request by the admin or gdpr user calling it.
+func (s *Server) exportPersonalData(ctx context.Context, params Params) (interface{}, error) {
+	if _, err := requirePersonalData(ctx); err != nil {
+		return nil, err
+	}
+	id, ok, err := params.UUID("id")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, badRequest("id is required")
+	}
+	format := params.String("format")
+	if format == "" {
+		format = contacts.FormatJSON
+	}
+
+	data, err := s.services.Contacts.ExportPersonalData(ctx, id, format, currentUserID(ctx))
+	switch {
+	case errors.Is(err, contacts.ErrNotFound):
+		return nil, notFound("Contact not found")
+	case errors.Is(err, contacts.ErrInvalidFormat):
+		return nil, badRequest("format must be json or zip")
+	case errors.Is(err, contacts.ErrNoActor):
+		return nil, unauthorized()
+	case err != nil:
+		return nil, err
+	}
+	if format == contacts.FormatJSON {
+		return data, nil
+	}
+
+	archive, err := data.Zip()
+	if err != nil {
+		return nil, err
+	}
+	return &fileResponse{
+		Name:        fmt.Sprintf("contact-%s-%s.zip", data.ContactID, data.ExportedAt.UTC().Format("20060102T150405Z")),
+		ContentType: "application/zip",
+		Body:        archive,
+	}, nil
+}
+
+// anonymizeContact scrubs the personal data of the contact id, keeping its
+// financial records, and records the erasure with its reason and the admin
+// or gdpr user calling it
+func (s *Server) anonymizeContact(ctx context.Context, params Params) (interface{}, error) {
+	if _, err := requirePersonalData(ctx); err != nil {
+		return nil, err
+	}
+	id, ok, err := params.UUID("id")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, badRequest("id is required")
+	}
+
+	result, err := s.services.Contacts.Anonymize(ctx, id, params.String("reason"), currentUserID(ctx))
+	if errors.Is(err, contacts.ErrNotFound) {
+		return nil, notFound("Contact not found")
+	}
+	if errors.Is(err, contacts.ErrNoActor) {
+		return nil, unauthorized()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// getPersonalDataRequests lists the exports and erasures of contact_id,
+// newest first, for admin and gdpr users
+func (s *Server) getPersonalDataRequests(ctx context.Context, params Params) (interface{}, error) {
+	if _, err := requirePersonalData(ctx); err != nil {
+		return nil, err
+	}
+	contactID, ok, err := params.UUID("contact_id")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, badRequest("contact_id is required")
+	}
+	return s.services.Contacts.PersonalDataRequests(ctx, contactID)
+}
+
+// ruleGroup returns the rule group selected by the parameters
+func (s *Server) ruleGroup(ctx context.Context, params Params, used string) (*dedupe.RuleGroup, error) {
+	id, hasID, err := params.UUID("rule_group_id")
+	if err != nil {
+		return nil, err
+	}
+
+	var group *dedupe.RuleGroup
+	switch name := params.String("rule_group"); {
+	case hasID:
+		group, err = s.services.Dedupe.RuleGroup(ctx, id)
+	case name != "":
+		group, err = s.services.Dedupe.RuleGroupByName(ctx, name)
+	default:
+		contactType := params.String("contact_type")
+		if contactType == "" {
+			contactType = "Individual"
+		}
+		if !isContactType(contactType) {
+			return nil, badRequest("contact_type must be Individual, Organization or Household")
+		}
+		group, err = s.services.Dedupe.DefaultRuleGroup(ctx, contactType, used)
+	}
+
+	if errors.Is(err, dedupe.ErrNotFound) {
+		return nil, notFound("Dedupe rule group not found")
+	}
+	return group, err
+}
+
+// isContactType reports whether t is a contact type
+func isContactType(t string) bool {
+	return t == "Individual" || t == "Organization" || t == "Household"
+}
+
+// nullString converts an empty string to NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParamsFloat(t *testing.T) {
+	params := Params{"number": 51.5, "text": "-0.12", "blank": "", "word": "north", "flag": true}
+
+	value, ok, err := params.Float("number")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 51.5, value)
+
+	value, ok, err = params.Float("text")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, -0.12, value)
+
+	for _, name := range []string{"blank", "missing"} {
+		_, ok, err = params.Float(name)
+		require.NoError(t, err)
+		assert.False(t, ok, name)
+	}
+	for _, name := range []string{"word", "flag"} {
+		_, _, err = params.Float(name)
+		assertStatus(t, http.StatusBadRequest, err)
+	}
+}
+
+func TestGetContactsNearChecksParams(t *testing.T) {
+	server, _ := newAccessServer()
+
+	_, err := server.getContact(context.Background(), Params{"lat": 51.5, "lng": -0.12, "radius": 10})
+	assertStatus(t, http.StatusUnauthorized, err)
+
+	for name, params := range map[string]Params{
+		"no point":        {},
+		"no radius":       {"lat": 51.5, "lng": -0.12},
+		"no lng":          {"lat": 51.5, "radius": 10.0},
+		"bad lat":         {"lat": 91.0, "lng": -0.12, "radius": 10.0},
+		"bad lng":         {"lat": 51.5, "lng": "west", "radius": 10.0},
+		"negative radius": {"lat": 51.5, "lng": -0.12, "radius": -1.0},
+		"large limit":     {"lat": 51.5, "lng": -0.12, "radius": 10.0, "limit": 10000.0},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := server.getContact(asUser("user"), params)
+			assertStatus(t, http.StatusBadRequest, err)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/jxlxx/civicrm/internal/dedupe"
+)
+
+// registerDedupeActions registers the DedupeRuleGroup actions
+func (s *Server) registerDedupeActions() {
+	s.registerRead("DedupeRuleGroup", "get", s.getRuleGroups)
+	s.registerWrite("DedupeRuleGroup", "create", restricted(s.createRuleGroup))
+}
+
+// ruleGroupInput is the body of DedupeRuleGroup.create
+type ruleGroupInput struct {
+	Name        string `json:"name"`
+	Title       string `json:"title"`
+	ContactType string `json:"contact_type"`
+	Used        string `json:"used"`
+	Threshold   int32  `json:"threshold"`
+	Rules       []struct {
+		RuleTable  string `json:"rule_table"`
+		RuleField  string `json:"rule_field"`
+		RuleLength *int32 `json:"rule_length"`
+		RuleWeight int32  `json:"rule_weight"`
+	} `json:"rules"`
+}
+
+// getRuleGroups returns every rule group with its rules
+func (s *Server) getRuleGroups(ctx context.Context, params Params) (interface{}, error) {
+	return s.services.Dedupe.RuleGroups(ctx)
+}
+
+// createRuleGroup creates a rule group with its rules. Rule groups decide
+// which new contacts are rejected as duplicates, so only admins may create
+// them.
+func (s *Server) createRuleGroup(ctx context.Context, params Params) (interface{}, error) {
+	var input ruleGroupInput
+	if err := params.Decode(&input); err != nil {
+		return nil, err
+	}
+
+	title := input.Title
+	if title == "" {
+		title = input.Name
+	}
+	used := input.Used
+	if used == "" {
+		used = dedupe.UsedGeneral
+	}
+
+	rules := make([]db.CreateDedupeRuleParams, len(input.Rules))
+	for i, rule := range input.Rules {
+		rules[i] = db.CreateDedupeRuleParams{
+			RuleTable:  rule.RuleTable,
+			RuleField:  rule.RuleField,
+			RuleWeight: rule.RuleWeight,
+		}
+		if rule.RuleLength != nil {
+			rules[i].RuleLength = sql.NullInt32{Int32: *rule.RuleLength, Valid: true}
+		}
+	}
+
+	group, err := s.services.Dedupe.CreateRuleGroup(ctx, db.CreateDedupeRuleGroupParams{
+		Name:        input.Name,
+		Title:       title,
+		ContactType: input.ContactType,
+		Used:        used,
+		Threshold:   input.Threshold,
+		IsReserved:  sql.NullBool{Bool: false, Valid: true},
+	}, rules)
+	if errors.Is(err, dedupe.ErrInvalidRuleGroup) {
+		return nil, badRequest("%v", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
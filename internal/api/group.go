@@ -0,0 +1,413 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/jxlxx/civicrm/internal/groups"
+)
+
+// registerGroupActions registers the Group, GroupContact, GroupNesting,
+// SubscriptionHistory and SavedSearch actions. Creating and refreshing
+// groups, nesting them and changing saved searches, which all decide group
+// members, is limited to admins and users with the groups role.
+func (s *Server) registerGroupActions() {
+	s.registerRead("Group", "get", s.getGroups)
+	s.registerWrite("Group", "create", restricted(s.createGroup, roleGroups))
+	s.registerWrite("Group", "refresh", restricted(s.refreshGroup, roleGroups))
+	s.registerRead("Group", "getTree", s.getGroupTree)
+	s.registerWrite("GroupNesting", "create", restricted(s.createGroupNesting, roleGroups))
+	s.registerWrite("GroupNesting", "delete", restricted(s.deleteGroupNesting, roleGroups))
+	s.registerRead("GroupContact", "get", s.getGroupContacts)
+	s.registerWrite("GroupContact", "create", s.createGroupContact)
+	s.registerWrite("GroupContact", "confirm", s.confirmGroupContact)
+	s.registerRead("SubscriptionHistory", "get", s.getSubscriptionHistory)
+	s.registerRead("SavedSearch", "get", s.getSavedSearches)
+	s.registerWrite("SavedSearch", "create", restricted(s.createSavedSearch, roleGroups))
+	s.registerWrite("SavedSearch", "update", restricted(s.updateSavedSearch, roleGroups))
+	s.registerWrite("SavedSearch", "delete", restricted(s.deleteSavedSearch, roleGroups))
+}
+
+// groupInput is the body of Group.create. A group with saved_search_id is a
+// smart group.
+type groupInput struct {
+	Name          string     `json:"name"`
+	Title         string     `json:"title"`
+	Description   string     `json:"description"`
+	SavedSearchID *uuid.UUID `json:"saved_search_id"`
+	GroupType     string     `json:"group_type"`
+	Visibility    string     `json:"visibility"`
+}
+
+// groupContactInput is the body of GroupContact.create
+type groupContactInput struct {
+	GroupID   uuid.UUID `json:"group_id"`
+	ContactID uuid.UUID `json:"contact_id"`
+	Status    string    `json:"status"`
+	Tracking  string    `json:"tracking"`
+}
+
+// savedSearchInput is the body of SavedSearch.create and SavedSearch.update.
+// api_params is an APIv4 query such as {"where": [["contact_type", "=",
+// "Individual"]]}.
+type savedSearchInput struct {
+	Name        string          `json:"name"`
+	Label       *string         `json:"label"`
+	Description *string         `json:"description"`
+	APIEntity   string          `json:"api_entity"`
+	APIParams   json.RawMessage `json:"api_params"`
+}
+
+// getGroups returns the group id, or every active group
+func (s *Server) getGroups(ctx context.Context, params Params) (interface{}, error) {
+	id, ok, err := params.UUID("id")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return s.services.Groups.Groups(ctx)
+	}
+	group, err := s.services.Groups.Group(ctx, id)
+	if err := groupError(err); err != nil {
+		return nil, err
+	}
+	return []*db.Group{group}, nil
+}
+
+// createGroup creates a static group, or a smart group when
+// saved_search_id is given
+func (s *Server) createGroup(ctx context.Context, params Params) (interface{}, error) {
+	var input groupInput
+	if err := params.Decode(&input); err != nil {
+		return nil, err
+	}
+	if input.Name == "" {
+		return nil, badRequest("name is required")
+	}
+
+	create := db.CreateGroupParams{
+		Name:        input.Name,
+		Title:       sql.NullString{String: input.Title, Valid: input.Title != ""},
+		Description: sql.NullString{String: input.Description, Valid: input.Description != ""},
+		GroupType:   sql.NullString{String: input.GroupType, Valid: input.GroupType != ""},
+		Visibility:  sql.NullString{String: input.Visibility, Valid: input.Visibility != ""},
+		IsActive:    sql.NullBool{Bool: true, Valid: true},
+	}
+	if create.Visibility.String == "" {
+		create.Visibility = sql.NullString{String: "User and User Admin Only", Valid: true}
+	}
+	if input.SavedSearchID != nil {
+		create.SavedSearchID = uuid.NullUUID{UUID: *input.SavedSearchID, Valid: true}
+	}
+
+	group, err := s.services.Groups.CreateGroup(ctx, create)
+	if err := groupError(err); err != nil {
+		return nil, err
+	}
+	return []*db.Group{group}, nil
+}
+
+// refreshGroup rebuilds the cached members of the smart group id now
+func (s *Server) refreshGroup(ctx context.Context, params Params) (interface{}, error) {
+	id, ok, err := params.UUID("id")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, badRequest("id is required")
+	}
+
+	count, err := s.services.Groups.Refresh(ctx, id)
+	if err := groupError(err); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"id": id, "contact_count": count}, nil
+}
+
+// getGroupTree returns the active groups as trees of nested groups, or only
+// the tree under the group id
+func (s *Server) getGroupTree(ctx context.Context, params Params) (interface{}, error) {
+	id, ok, err := params.UUID("id")
+	if err != nil {
+		return nil, err
+	}
+
+	trees, err := s.services.Groups.Tree(ctx, uuid.NullUUID{UUID: id, Valid: ok})
+	if err := groupError(err); err != nil {
+		return nil, err
+	}
+	return trees, nil
+}
+
+// nestingParams reads parent_group_id and child_group_id
+func nestingParams(params Params) (uuid.UUID, uuid.UUID, error) {
+	parentID, ok, err := params.UUID("parent_group_id")
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	if !ok {
+		return uuid.Nil, uuid.Nil, badRequest("parent_group_id is required")
+	}
+	childID, ok, err := params.UUID("child_group_id")
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	if !ok {
+		return uuid.Nil, uuid.Nil, badRequest("child_group_id is required")
+	}
+	return parentID, childID, nil
+}
+
+// createGroupNesting nests child_group_id under parent_group_id
+func (s *Server) createGroupNesting(ctx context.Context, params Params) (interface{}, error) {
+	parentID, childID, err := nestingParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := groupError(s.services.Groups.AddChild(ctx, parentID, childID)); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"parent_group_id": parentID, "child_group_id": childID}, nil
+}
+
+// deleteGroupNesting un-nests child_group_id from parent_group_id
+func (s *Server) deleteGroupNesting(ctx context.Context, params Params) (interface{}, error) {
+	parentID, childID, err := nestingParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := groupError(s.services.Groups.RemoveChild(ctx, parentID, childID)); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"parent_group_id": parentID, "child_group_id": childID}, nil
+}
+
+// getGroupContacts returns the members of group_id, including the members
+// of groups nested under it. Smart groups whose cache has expired are
+// refreshed first.
+func (s *Server) getGroupContacts(ctx context.Context, params Params) (interface{}, error) {
+	groupID, ok, err := params.UUID("group_id")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, badRequest("group_id is required")
+	}
+
+	members, err := s.services.Groups.Members(ctx, groupID)
+	if err := groupError(err); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// createGroupContact sets the status of contact_id in group_id and records
+// the change with a method that depends on the caller. Anonymous callers
+// subscribe from the web and may only ask for Pending, the default for
+// them; the contact is emailed a token to confirm with. Signed in users
+// need to be able to edit the contact, set any status (Added by default)
+// and are recorded as API, or Admin for admins.
+func (s *Server) createGroupContact(ctx context.Context, params Params) (interface{}, error) {
+	var input groupContactInput
+	if err := params.Decode(&input); err != nil {
+		return nil, err
+	}
+	if input.GroupID == uuid.Nil || input.ContactID == uuid.Nil {
+		return nil, badRequest("group_id and contact_id are required")
+	}
+
+	method := groups.MethodWeb
+	if currentUser(ctx) == nil {
+		if input.Status == "" {
+			input.Status = groups.StatusPending
+		}
+		if input.Status != groups.StatusPending {
+			return nil, unauthorized()
+		}
+	} else {
+		user, err := s.requireContactEdit(ctx, input.ContactID)
+		if err != nil {
+			return nil, err
+		}
+		method = groups.MethodAPI
+		if isAdmin(user) {
+			method = groups.MethodAdmin
+		}
+		if input.Status == "" {
+			input.Status = groups.StatusAdded
+		}
+	}
+
+	subscription, err := s.services.Groups.SetStatus(ctx, groups.Change{
+		GroupID:   input.GroupID,
+		ContactID: input.ContactID,
+		Status:    input.Status,
+		Method:    method,
+		ActorID:   currentUserID(ctx),
+		Tracking:  input.Tracking,
+	})
+	if err := groupError(err); err != nil {
+		return nil, err
+	}
+	return []*db.GroupContact{subscription}, nil
+}
+
+// confirmGroupContact adds the contact of a pending subscription to its
+// group with the token they were sent
+func (s *Server) confirmGroupContact(ctx context.Context, params Params) (interface{}, error) {
+	token := params.String("token")
+	if token == "" {
+		return nil, badRequest("token is required")
+	}
+
+	subscription, err := s.services.Groups.Confirm(ctx, token)
+	if err := groupError(err); err != nil {
+		return nil, err
+	}
+	return []*db.GroupContact{subscription}, nil
+}
+
+// getSubscriptionHistory returns the subscription history of contact_id,
+// group_id or both
+func (s *Server) getSubscriptionHistory(ctx context.Context, params Params) (interface{}, error) {
+	contactID, hasContact, err := params.UUID("contact_id")
+	if err != nil {
+		return nil, err
+	}
+	groupID, hasGroup, err := params.UUID("group_id")
+	if err != nil {
+		return nil, err
+	}
+	if !hasContact && !hasGroup {
+		return nil, badRequest("contact_id or group_id is required")
+	}
+
+	return s.services.Groups.History(ctx,
+		uuid.NullUUID{UUID: contactID, Valid: hasContact},
+		uuid.NullUUID{UUID: groupID, Valid: hasGroup})
+}
+
+// getSavedSearches returns the saved search id, or every saved search
+func (s *Server) getSavedSearches(ctx context.Context, params Params) (interface{}, error) {
+	id, ok, err := params.UUID("id")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return s.services.Groups.SavedSearches(ctx)
+	}
+	search, err := s.services.Groups.SavedSearch(ctx, id)
+	if err := groupError(err); err != nil {
+		return nil, err
+	}
+	return []*db.SavedSearch{search}, nil
+}
+
+// createSavedSearch stores a saved search for the calling user
+func (s *Server) createSavedSearch(ctx context.Context, params Params) (interface{}, error) {
+	var input savedSearchInput
+	if err := params.Decode(&input); err != nil {
+		return nil, err
+	}
+	if input.Name == "" {
+		return nil, badRequest("name is required")
+	}
+
+	search, err := s.services.Groups.CreateSavedSearch(ctx, db.CreateSavedSearchParams{
+		Name:        input.Name,
+		Label:       optionalString(input.Label, sql.NullString{}),
+		Description: optionalString(input.Description, sql.NullString{}),
+		ApiEntity:   input.APIEntity,
+		ApiParams:   input.APIParams,
+		CreatedBy:   currentUserID(ctx),
+	})
+	if err := groupError(err); err != nil {
+		return nil, err
+	}
+	return []*db.SavedSearch{search}, nil
+}
+
+// updateSavedSearch updates the saved search id. Omitted fields keep their
+// value; smart groups using it are refreshed when next read.
+func (s *Server) updateSavedSearch(ctx context.Context, params Params) (interface{}, error) {
+	id, ok, err := params.UUID("id")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, badRequest("id is required")
+	}
+
+	var input savedSearchInput
+	if err := params.Decode(&input); err != nil {
+		return nil, err
+	}
+
+	current, err := s.services.Groups.SavedSearch(ctx, id)
+	if err := groupError(err); err != nil {
+		return nil, err
+	}
+	update := db.UpdateSavedSearchParams{
+		ID:          id,
+		Label:       optionalString(input.Label, current.Label),
+		Description: optionalString(input.Description, current.Description),
+		ApiEntity:   current.ApiEntity,
+		ApiParams:   current.ApiParams,
+	}
+	if input.APIEntity != "" {
+		update.ApiEntity = input.APIEntity
+	}
+	if len(input.APIParams) > 0 {
+		update.ApiParams = input.APIParams
+	}
+
+	search, err := s.services.Groups.UpdateSavedSearch(ctx, update)
+	if err := groupError(err); err != nil {
+		return nil, err
+	}
+	return []*db.SavedSearch{search}, nil
+}
+
+// deleteSavedSearch deletes the saved search id
+func (s *Server) deleteSavedSearch(ctx context.Context, params Params) (interface{}, error) {
+	id, ok, err := params.UUID("id")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, badRequest("id is required")
+	}
+
+	if err := groupError(s.services.Groups.DeleteSavedSearch(ctx, id)); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"id": id}, nil
+}
+
+// optionalString returns the input when given, where an empty string clears
+// the value, and the current value otherwise
+func optionalString(input *string, current sql.NullString) sql.NullString {
+	if input == nil {
+		return current
+	}
+	return sql.NullString{String: *input, Valid: *input != ""}
+}
+
+// groupError maps group service errors to API errors
+func groupError(err error) error {
+	switch {
+	case errors.Is(err, groups.ErrNotFound):
+		return notFound("%v", err)
+	case errors.Is(err, groups.ErrInvalidSearch), errors.Is(err, groups.ErrNotSmartGroup),
+		errors.Is(err, groups.ErrCycle), errors.Is(err, groups.ErrInvalidChange),
+		errors.Is(err, groups.ErrInvalidToken):
+		return badRequest("%v", err)
+	}
+	return err
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+// limiterSweep is the number of keys a limiter keeps before it forgets the
+// ones whose interval has passed
+const limiterSweep = 1024
+
+// limiter lets each key act at most once per interval
+type limiter struct {
+	interval time.Duration
+	now      func() time.Time
+
+	mutex sync.Mutex
+	last  map[string]time.Time
+}
+
+// newLimiter creates a limiter. A zero interval allows everything.
+func newLimiter(interval time.Duration) *limiter {
+	return &limiter{interval: interval, now: time.Now, last: make(map[string]time.Time)}
+}
+
+// allow reports whether key may act now, and if so records that it did
+func (l *limiter) allow(key string) bool {
+	if l.interval <= 0 {
+		return true
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	if last, ok := l.last[key]; ok && now.Sub(last) < l.interval {
+		return false
+	}
+	if len(l.last) >= limiterSweep {
+		for k, last := range l.last {
+			if now.Sub(last) >= l.interval {
+				delete(l.last, k)
+			}
+		}
+	}
+	l.last[key] = now
+	return true
+}
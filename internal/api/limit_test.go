@@ -0,0 +1,38 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := newLimiter(time.Minute)
+	l.now = func() time.Time { return now }
+
+	assert.True(t, l.allow("ada"))
+	assert.False(t, l.allow("ada"))
+	assert.True(t, l.allow("grace"), "keys are limited separately")
+
+	now = now.Add(time.Minute)
+	assert.True(t, l.allow("ada"))
+
+	unlimited := newLimiter(0)
+	assert.True(t, unlimited.allow("ada"))
+	assert.True(t, unlimited.allow("ada"))
+}
+
+func TestLimiterForgetsExpiredKeys(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := newLimiter(time.Minute)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < limiterSweep; i++ {
+		l.allow(time.Duration(i).String())
+	}
+	now = now.Add(time.Minute)
+	assert.True(t, l.allow("ada"))
+	assert.Len(t, l.last, 1)
+}
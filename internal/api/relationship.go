@@ -0,0 +1,239 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/jxlxx/civicrm/internal/relationships"
+)
+
+// dateLayout is the format of date parameters
+const dateLayout = "2006-01-02"
+
+// registerRelationshipActions registers the Relationship and
+// RelationshipType actions
+func (s *Server) registerRelationshipActions() {
+	s.registerRead("Relationship", "get", s.getRelationships)
+	s.registerWrite("Relationship", "create", s.createRelationship)
+	s.registerWrite("Relationship", "update", s.updateRelationship)
+	s.registerWrite("Relationship", "delete", s.deleteRelationship)
+	s.registerRead("RelationshipType", "get", s.getRelationshipTypes)
+}
+
+// relationshipInput is the body of Relationship.create and
+// Relationship.update. Dates are YYYY-MM-DD; an empty string clears one.
+type relationshipInput struct {
+	ContactIDA         uuid.UUID `json:"contact_id_a"`
+	ContactIDB         uuid.UUID `json:"contact_id_b"`
+	RelationshipTypeID uuid.UUID `json:"relationship_type_id"`
+	StartDate          *string   `json:"start_date"`
+	EndDate            *string   `json:"end_date"`
+	IsActive           *bool     `json:"is_active"`
+	IsPermissionAB     *bool     `json:"is_permission_a_b"`
+	IsPermissionBA     *bool     `json:"is_permission_b_a"`
+}
+
+// relationship applies the input to a relationship
+func (in relationshipInput) relationship(r relationships.Relationship) (relationships.Relationship, error) {
+	if in.RelationshipTypeID != uuid.Nil {
+		r.RelationshipTypeID = in.RelationshipTypeID
+	}
+	for _, date := range []struct {
+		name  string
+		input *string
+		value *sql.NullTime
+	}{
+		{"start_date", in.StartDate, &r.StartDate},
+		{"end_date", in.EndDate, &r.EndDate},
+	} {
+		if date.input == nil {
+			continue
+		}
+		if *date.input == "" {
+			*date.value = sql.NullTime{}
+			continue
+		}
+		t, err := time.Parse(dateLayout, *date.input)
+		if err != nil {
+			return r, badRequest("%s must be a date such as 2024-01-31", date.name)
+		}
+		*date.value = sql.NullTime{Time: t, Valid: true}
+	}
+	if in.IsActive != nil {
+		r.IsActive = in.IsActive
+	}
+	if in.IsPermissionAB != nil {
+		r.IsPermissionAB = *in.IsPermissionAB
+	}
+	if in.IsPermissionBA != nil {
+		r.IsPermissionBA = *in.IsPermissionBA
+	}
+	return r, nil
+}
+
+// getRelationships returns the relationship id, or the relationships of
+// contact_id named from that contact's side. Inactive relationships are
+// included when include_inactive is true.
+func (s *Server) getRelationships(ctx context.Context, params Params) (interface{}, error) {
+	id, hasID, err := params.UUID("id")
+	if err != nil {
+		return nil, err
+	}
+	if hasID {
+		relationship, err := s.services.Relationships.Get(ctx, id)
+		if errors.Is(err, relationships.ErrNotFound) {
+			return nil, notFound("Relationship not found")
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []*db.Relationship{relationship}, nil
+	}
+
+	contactID, ok, err := params.UUID("contact_id")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, badRequest("id or contact_id is required")
+	}
+	includeInactive, err := params.Bool("include_inactive", false)
+	if err != nil {
+		return nil, err
+	}
+	return s.services.Relationships.ListForContact(ctx, contactID, includeInactive)
+}
+
+// createRelationship creates a relationship between contact_id_a and
+// contact_id_b. Relationships can grant edit permission, so the caller must
+// be able to edit both contacts.
+func (s *Server) createRelationship(ctx context.Context, params Params) (interface{}, error) {
+	var input relationshipInput
+	if err := params.Decode(&input); err != nil {
+		return nil, err
+	}
+	if input.ContactIDA == uuid.Nil || input.ContactIDB == uuid.Nil || input.RelationshipTypeID == uuid.Nil {
+		return nil, badRequest("contact_id_a, contact_id_b and relationship_type_id are required")
+	}
+	if _, err := s.requireContactEdit(ctx, input.ContactIDA, input.ContactIDB); err != nil {
+		return nil, err
+	}
+
+	relationship, err := input.relationship(relationships.Relationship{
+		ContactIDA: input.ContactIDA,
+		ContactIDB: input.ContactIDB,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.services.Relationships.Create(ctx, relationship)
+	if err := relationshipError(err); err != nil {
+		return nil, err
+	}
+	return []*db.Relationship{created}, nil
+}
+
+// updateRelationship updates the relationship id. Omitted fields keep their
+// value. The caller must be able to edit both of its contacts.
+func (s *Server) updateRelationship(ctx context.Context, params Params) (interface{}, error) {
+	if _, err := requireUser(ctx); err != nil {
+		return nil, err
+	}
+	id, ok, err := params.UUID("id")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, badRequest("id is required")
+	}
+
+	var input relationshipInput
+	if err := params.Decode(&input); err != nil {
+		return nil, err
+	}
+
+	current, err := s.services.Relationships.Get(ctx, id)
+	if err := relationshipError(err); err != nil {
+		return nil, err
+	}
+	if err := s.requireRelationshipEdit(ctx, current); err != nil {
+		return nil, err
+	}
+	relationship, err := input.relationship(relationships.Relationship{
+		RelationshipTypeID: current.RelationshipTypeID,
+		StartDate:          current.StartDate,
+		EndDate:            current.EndDate,
+		IsActive:           &current.IsActive.Bool,
+		IsPermissionAB:     current.IsPermissionAB.Bool,
+		IsPermissionBA:     current.IsPermissionBA.Bool,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// When the dates change and is_active is not given, the status follows
+	// the new dates
+	if input.IsActive == nil && (input.StartDate != nil || input.EndDate != nil) {
+		relationship.IsActive = nil
+	}
+
+	updated, err := s.services.Relationships.Update(ctx, id, relationship)
+	if err := relationshipError(err); err != nil {
+		return nil, err
+	}
+	return []*db.Relationship{updated}, nil
+}
+
+// deleteRelationship deletes the relationship id. The caller must be able to
+// edit both of its contacts.
+func (s *Server) deleteRelationship(ctx context.Context, params Params) (interface{}, error) {
+	if _, err := requireUser(ctx); err != nil {
+		return nil, err
+	}
+	id, ok, err := params.UUID("id")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, badRequest("id is required")
+	}
+
+	current, err := s.services.Relationships.Get(ctx, id)
+	if err := relationshipError(err); err != nil {
+		return nil, err
+	}
+	if err := s.requireRelationshipEdit(ctx, current); err != nil {
+		return nil, err
+	}
+	if err := relationshipError(s.services.Relationships.Delete(ctx, id)); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"id": id}, nil
+}
+
+// requireRelationshipEdit checks that the caller may edit both contacts of
+// an existing relationship
+func (s *Server) requireRelationshipEdit(ctx context.Context, relationship *db.Relationship) error {
+	_, err := s.requireContactEdit(ctx, relationship.ContactIDA, relationship.ContactIDB)
+	return err
+}
+
+// getRelationshipTypes returns the active relationship types
+func (s *Server) getRelationshipTypes(ctx context.Context, params Params) (interface{}, error) {
+	return s.services.Relationships.Types(ctx)
+}
+
+// relationshipError maps relationship service errors to action errors
+func relationshipError(err error) error {
+	switch {
+	case errors.Is(err, relationships.ErrNotFound):
+		return notFound("%v", err)
+	case errors.Is(err, relationships.ErrInvalidRelationship):
+		return badRequest("%v", err)
+	}
+	return err
+}
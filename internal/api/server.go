@@ -8,14 +8,21 @@ import (
 	"io/fs"
 	"net/http"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/jxlxx/civicrm/internal/addresses"
 	"github.com/jxlxx/civicrm/internal/cache"
 	"github.com/jxlxx/civicrm/internal/config"
+	"github.com/jxlxx/civicrm/internal/contacts"
 	"github.com/jxlxx/civicrm/internal/database"
+	"github.com/jxlxx/civicrm/internal/dedupe"
 	"github.com/jxlxx/civicrm/internal/extensions"
+	"github.com/jxlxx/civicrm/internal/groups"
 	"github.com/jxlxx/civicrm/internal/logger"
+	"github.com/jxlxx/civicrm/internal/metrics"
+	"github.com/jxlxx/civicrm/internal/relationships"
 	"github.com/jxlxx/civicrm/internal/security"
 )
 
@@ -33,6 +40,15 @@ func getStaticFS() http.FileSystem {
 	return http.FS(staticFS)
 }
 
+// Services are the domain services behind the entity actions
+type Services struct {
+	Contacts      *contacts.Service
+	Dedupe        *dedupe.Service
+	Relationships *relationships.Service
+	Groups        *groups.Service
+	Addresses     *addresses.Service
+}
+
 // Server represents the API server using standard library HTTP
 type Server struct {
 	config     *config.APIConfig
@@ -41,12 +57,18 @@ type Server struct {
 	cache      *cache.Manager
 	security   *security.Manager
 	extensions *extensions.Manager
+	metrics    *metrics.Registry
+	services   Services
+	access     contactAccess
+	scans      *limiter
+	actions    map[string]action
+	requests   *metrics.CounterVec
 	server     *http.Server
 	handler    http.Handler
 }
 
 // New creates a new API server
-func New(config *config.APIConfig, logger *logger.Logger, db *database.Database, cache *cache.Manager, security *security.Manager, extensions *extensions.Manager) (*Server, error) {
+func New(config *config.APIConfig, logger *logger.Logger, db *database.Database, cache *cache.Manager, security *security.Manager, extensions *extensions.Manager, registry *metrics.Registry, services Services) (*Server, error) {
 	server := &Server{
 		config:     config,
 		logger:     logger,
@@ -54,11 +76,25 @@ func New(config *config.APIConfig, logger *logger.Logger, db *database.Database,
 		cache:      cache,
 		security:   security,
 		extensions: extensions,
+		metrics:    registry,
+		services:   services,
+		access:     cachedAccess{access: db.Querier(), cache: cache},
+		scans:      newLimiter(config.DuplicateScanInterval),
+		actions:    make(map[string]action),
+		requests:   metrics.NewCounterVec("civicrm_http_requests_total", "HTTP requests by method and status.", "method", "status"),
 	}
+	registry.Register(server.requests)
+	server.registerActions()
+
+	// Routes outside the API specification share the same mux
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", registry.Handler())
+	server.registerAdminRoutes(mux)
 
 	// Create the HTTP handler using generated code with correct base URL
 	server.handler = HandlerWithOptions(server, StdHTTPServerOptions{
-		BaseURL: "/api/v4",
+		BaseURL:    "/api/v4",
+		BaseRouter: mux,
 	})
 
 	// Add middleware
@@ -78,6 +114,9 @@ func New(config *config.APIConfig, logger *logger.Logger, db *database.Database,
 
 // addMiddleware adds standard middleware to the HTTP handler
 func (s *Server) addMiddleware(handler http.Handler) http.Handler {
+	// Tenancy middleware, innermost so preflight requests are not scoped
+	handler = s.tenancyMiddleware(handler)
+
 	// Logging middleware
 	handler = s.loggingMiddleware(handler)
 
@@ -87,9 +126,20 @@ func (s *Server) addMiddleware(handler http.Handler) http.Handler {
 	// Security middleware
 	handler = s.securityMiddleware(handler)
 
+	// Track writes so later reads in the request can avoid stale replicas
+	handler = s.readYourWritesMiddleware(handler)
+
 	return handler
 }
 
+// readYourWritesMiddleware marks each request context so the database can
+// send its reads to the primary after the request has written
+func (s *Server) readYourWritesMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(database.TrackWrites(r.Context())))
+	})
+}
+
 // loggingMiddleware adds logging to all requests
 func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -101,6 +151,7 @@ func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(wrapped, r)
 
 		duration := time.Since(start)
+		s.requests.Inc(r.Method, strconv.Itoa(wrapped.statusCode))
 		s.logger.Info("HTTP request",
 			"method", r.Method,
 			"path", r.URL.Path,
@@ -248,6 +299,10 @@ func (s *Server) HealthCheck(w http.ResponseWriter, r *http.Request) {
 
 // EntityDelete handles entity deletion
 func (s *Server) EntityDelete(w http.ResponseWriter, r *http.Request, entity string, action string, params EntityDeleteParams) {
+	if s.dispatch(w, r, entity, action) {
+		return
+	}
+
 	// TODO: Implement entity deletion logic
 	response := APIResponse{
 		IsError: ptr(false),
@@ -265,6 +320,10 @@ func (s *Server) EntityDelete(w http.ResponseWriter, r *http.Request, entity str
 
 // EntityGet handles entity retrieval
 func (s *Server) EntityGet(w http.ResponseWriter, r *http.Request, entity string, action string, params EntityGetParams) {
+	if s.dispatch(w, r, entity, action) {
+		return
+	}
+
 	// TODO: Implement entity retrieval logic
 	response := APIResponse{
 		IsError: ptr(false),
@@ -282,6 +341,10 @@ func (s *Server) EntityGet(w http.ResponseWriter, r *http.Request, entity string
 
 // EntityCreate handles entity creation
 func (s *Server) EntityCreate(w http.ResponseWriter, r *http.Request, entity string, action string) {
+	if s.dispatch(w, r, entity, action) {
+		return
+	}
+
 	// TODO: Implement entity creation logic
 	response := APIResponse{
 		IsError: ptr(false),
@@ -299,6 +362,10 @@ func (s *Server) EntityCreate(w http.ResponseWriter, r *http.Request, entity str
 
 // EntityUpdate handles entity updates
 func (s *Server) EntityUpdate(w http.ResponseWriter, r *http.Request, entity string, action string, params EntityUpdateParams) {
+	if s.dispatch(w, r, entity, action) {
+		return
+	}
+
 	// TODO: Implement entity update logic
 	response := APIResponse{
 		IsError: ptr(false),
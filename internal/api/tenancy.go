@@ -0,0 +1,154 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/cache"
+	generated "github.com/jxlxx/civicrm/internal/database/generated"
+)
+
+const (
+	// domainCacheTTL is how long domain lookups stay cached
+	domainCacheTTL = 5 * time.Minute
+
+	// defaultDomain serves every request when tenancy is disabled. It holds
+	// the rows that existed before domains were introduced.
+	defaultDomain = "default"
+)
+
+var (
+	errUnknownDomain  = errors.New("unknown domain")
+	errDomainMismatch = errors.New("token is not valid for this domain")
+)
+
+// unscopedPaths are served without a domain because they do not read
+// tenant data
+var unscopedPaths = []string{
+	"/metrics",
+	"/admin/",
+	"/api/v4/health",
+	"/api/v4/openapi.json",
+	"/api/v4/static/",
+}
+
+// tenancyMiddleware scopes each request to a domain so that row level
+// security limits its queries to that domain's data. Unscoped queries see no
+// rows, so requests are scoped to the default domain when tenancy is
+// disabled.
+func (s *Server) tenancyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, path := range unscopedPaths {
+			if strings.HasPrefix(r.URL.Path, path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		domainID, err := s.resolveDomain(r)
+		switch {
+		case errors.Is(err, errUnknownDomain):
+			http.Error(w, "Unknown domain", http.StatusNotFound)
+			return
+		case errors.Is(err, errDomainMismatch):
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		case err != nil:
+			s.logger.Error("Failed to resolve domain", "host", r.Host, "error", err)
+			http.Error(w, "Failed to resolve domain", http.StatusInternalServerError)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(s.db.WithDomain(r.Context(), domainID)))
+	})
+}
+
+// resolveDomain returns the domain for a request. A domain_id claim in a
+// valid bearer token takes precedence; otherwise the Host header is mapped
+// through the configured hosts, falling back to the default domain. A token
+// for one domain cannot be used on a host mapped to another domain.
+func (s *Server) resolveDomain(r *http.Request) (uuid.UUID, error) {
+	ctx := r.Context()
+	if !s.config.Tenancy.Enabled {
+		return s.domainByName(ctx, defaultDomain)
+	}
+
+	mapped := s.config.Tenancy.Hosts[hostname(r.Host)]
+
+	if claim := s.domainClaim(r); claim != "" {
+		id, err := uuid.Parse(claim)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("%w: invalid domain_id claim", errUnknownDomain)
+		}
+		domain, err := s.lookupDomain(ctx, "id:"+claim, func(ctx context.Context, q generated.Querier) (generated.Domain, error) {
+			return q.GetDomain(ctx, id)
+		})
+		if err != nil {
+			return uuid.Nil, err
+		}
+		if mapped != "" && mapped != domain.Name {
+			return uuid.Nil, errDomainMismatch
+		}
+		return domain.ID, nil
+	}
+
+	name := mapped
+	if name == "" {
+		name = s.config.Tenancy.DefaultDomain
+	}
+	if name == "" {
+		return uuid.Nil, errUnknownDomain
+	}
+	return s.domainByName(ctx, name)
+}
+
+// domainByName returns the ID of the active domain called name
+func (s *Server) domainByName(ctx context.Context, name string) (uuid.UUID, error) {
+	domain, err := s.lookupDomain(ctx, "name:"+name, func(ctx context.Context, q generated.Querier) (generated.Domain, error) {
+		return q.GetDomainByName(ctx, name)
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return domain.ID, nil
+}
+
+// domainClaim returns the domain_id claim of a valid bearer token, if any
+func (s *Server) domainClaim(r *http.Request) string {
+	if user := s.bearerUser(r); user != nil {
+		return user.DomainID
+	}
+	return ""
+}
+
+// lookupDomain loads an active domain through the cache
+func (s *Server) lookupDomain(ctx context.Context, key string, load func(context.Context, generated.Querier) (generated.Domain, error)) (generated.Domain, error) {
+	domain, err := cache.GetOrLoad(ctx, s.cache, "domain:"+key, domainCacheTTL, func(ctx context.Context) (generated.Domain, error) {
+		return load(ctx, s.db.Querier())
+	}, cache.TagSettings)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain, errUnknownDomain
+	}
+	if err != nil {
+		return domain, err
+	}
+	if domain.IsActive.Valid && !domain.IsActive.Bool {
+		return domain, errUnknownDomain
+	}
+	return domain, nil
+}
+
+// hostname strips the port from a Host header and lowercases it
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
@@ -2,32 +2,75 @@ package cache
 
 import (
 	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"sync"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/jxlxx/civicrm/internal/config"
 	"github.com/jxlxx/civicrm/internal/logger"
 	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
 )
 
+// ErrNotFound is returned when a key is not present in any cache layer
+var ErrNotFound = errors.New("cache: key not found")
+
 // Manager manages multiple cache layers
 type Manager struct {
-	config *config.CacheConfig
-	memory *MemoryCache
-	redis  *RedisCache
-	local  *LocalCache
-	logger *logger.Logger
+	config  *config.CacheConfig
+	memory  *MemoryCache
+	redis   *RedisCache
+	local   *LocalCache
+	logger  *logger.Logger
+	codec   Codec
+	loads   singleflight.Group
+	metrics *cacheMetrics
+	nodeID  string
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// Invalidation operations broadcast to other replicas
+const (
+	invalidateKey   = "key"
+	invalidateTag   = "tag"
+	invalidateClear = "clear"
+)
+
+// invalidation is published on the Redis invalidation channel whenever an
+// entry changes, so that other replicas can evict it from their local and
+// memory tiers
+type invalidation struct {
+	Origin string `json:"origin"`
+	Op     string `json:"op"`
+	Key    string `json:"key,omitempty"`
+	Tag    string `json:"tag,omitempty"`
 }
 
-// New creates a new cache manager
-func New(config *config.CacheConfig) (*Manager, error) {
+// New creates a new cache manager. When Redis is configured the manager also
+// subscribes to invalidations published by other replicas.
+func New(config *config.CacheConfig, logger *logger.Logger) (*Manager, error) {
 	manager := &Manager{
-		config: config,
+		config:  config,
+		logger:  logger,
+		metrics: newCacheMetrics(),
+		nodeID:  uuid.NewString(),
 	}
 
+	// Values stored in Redis are serialized with the configured codec
+	codec, err := NewCodec(config.Codec)
+	if err != nil {
+		return nil, err
+	}
+	manager.codec = codec
+
 	// Initialize memory cache
-	manager.memory = NewMemoryCache(config.MaxMemory, config.MaxEntries)
+	manager.memory = &MemoryCache{store: newLRU(config.MaxMemory, config.MaxEntries, estimateSize, manager.metrics.evicted(TierMemory))}
 
 	// Initialize Redis cache if configured
 	if config.Driver == "redis" {
@@ -39,46 +82,107 @@ func New(config *config.CacheConfig) (*Manager, error) {
 	}
 
 	// Initialize local cache
-	manager.local = NewLocalCache(config.MaxMemory/10, config.MaxEntries/10)
+	manager.local = &LocalCache{store: newLRU(config.MaxMemory/10, config.MaxEntries/10, estimateSize, manager.metrics.evicted(TierLocal))}
+
+	// Listen for invalidations from other replicas
+	if manager.redis != nil {
+		if err := manager.subscribe(); err != nil {
+			manager.redis.Close()
+			return nil, fmt.Errorf("failed to subscribe to cache invalidations: %w", err)
+		}
+	}
 
 	return manager, nil
 }
 
-// Get retrieves a value from cache
+// Get retrieves a value from cache. Values read back from Redis are decoded
+// into generic types (maps, slices, strings and numbers); use the package
+// level Get or GetOrLoad to decode into a specific type.
 func (m *Manager) Get(ctx context.Context, key string) (interface{}, error) {
+	accept := func(interface{}) bool { return true }
+	decode := func(payload []byte) (interface{}, error) {
+		var val interface{}
+		err := m.codec.Unmarshal(payload, &val)
+		return val, err
+	}
+	return m.lookup(ctx, key, accept, decode)
+}
+
+// lookup searches the cache layers from fastest to slowest and fills the
+// faster layers on a hit. accept reports whether an in-process value can be
+// returned as is, and decode turns a Redis payload into a value.
+func (m *Manager) lookup(ctx context.Context, key string, accept func(interface{}) bool, decode func([]byte) (interface{}, error)) (interface{}, error) {
 	// Try local cache first (fastest)
-	if val, found := m.local.Get(key); found {
+	if val, found := m.local.Get(key); found && accept(val) {
+		m.metrics.hit(TierLocal, key)
 		return val, nil
 	}
+	m.metrics.miss(TierLocal, key)
 
 	// Try memory cache
-	if val, found := m.memory.Get(key); found {
-		// Update local cache
-		m.local.Set(key, val, m.config.TTL)
+	if val, tags, ttl, found := m.memory.GetWithTags(key); found && accept(val) {
+		m.metrics.hit(TierMemory, key)
+		// Update local cache, expiring with the memory entry
+		m.local.SetWithTags(key, val, ttl, tags)
 		return val, nil
 	}
+	m.metrics.miss(TierMemory, key)
 
 	// Try Redis cache if available
 	if m.redis != nil {
-		if val, err := m.redis.Get(ctx, key); err == nil {
-			// Update memory and local caches
-			m.memory.Set(key, val, m.config.TTL)
-			m.local.Set(key, val, m.config.TTL)
+		if payload, tags, ttl, err := m.redis.Get(ctx, key); err == nil {
+			m.metrics.hit(TierRedis, key)
+			val, err := decode(payload)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode cached value for %s: %w", key, err)
+			}
+			// Update memory and local caches, expiring with the Redis entry
+			m.memory.SetWithTags(key, val, ttl, tags)
+			m.local.SetWithTags(key, val, ttl, tags)
 			return val, nil
 		}
+		m.metrics.miss(TierRedis, key)
 	}
 
-	return nil, fmt.Errorf("key not found: %s", key)
+	return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
 }
 
 // Set stores a value in cache
 func (m *Manager) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	return m.SetWithTags(ctx, key, value, ttl)
+}
+
+// SetWithTags stores a value in cache and associates it with the given tags,
+// so that it can later be removed with InvalidateTag
+func (m *Manager) SetWithTags(ctx context.Context, key string, value interface{}, ttl time.Duration, tags ...string) error {
 	// Set in all cache layers
-	m.local.Set(key, value, ttl)
-	m.memory.Set(key, value, ttl)
+	m.local.SetWithTags(key, value, ttl, tags)
+	m.memory.SetWithTags(key, value, ttl, tags)
+
+	if m.redis != nil {
+		payload, err := m.codec.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("failed to encode cached value for %s: %w", key, err)
+		}
+		if err := m.redis.SetWithTags(ctx, key, payload, ttl, tags); err != nil {
+			return err
+		}
+		return m.publish(ctx, invalidation{Op: invalidateKey, Key: key})
+	}
+
+	return nil
+}
+
+// InvalidateTag removes every entry stored with the given tag from all cache layers
+func (m *Manager) InvalidateTag(ctx context.Context, tag string) error {
+	m.local.InvalidateTag(tag)
+	m.memory.InvalidateTag(tag)
 
 	if m.redis != nil {
-		return m.redis.Set(ctx, key, value, ttl)
+		if err := m.redis.InvalidateTag(ctx, tag); err != nil {
+			return err
+		}
+		return m.publish(ctx, invalidation{Op: invalidateTag, Tag: tag})
 	}
 
 	return nil
@@ -90,7 +194,10 @@ func (m *Manager) Delete(ctx context.Context, key string) error {
 	m.memory.Delete(key)
 
 	if m.redis != nil {
-		return m.redis.Delete(ctx, key)
+		if err := m.redis.Delete(ctx, key); err != nil {
+			return err
+		}
+		return m.publish(ctx, invalidation{Op: invalidateKey, Key: key})
 	}
 
 	return nil
@@ -102,7 +209,10 @@ func (m *Manager) Clear(ctx context.Context) error {
 	m.memory.Clear()
 
 	if m.redis != nil {
-		return m.redis.Clear(ctx)
+		if err := m.redis.Clear(ctx); err != nil {
+			return err
+		}
+		return m.publish(ctx, invalidation{Op: invalidateClear})
 	}
 
 	return nil
@@ -110,179 +220,218 @@ func (m *Manager) Clear(ctx context.Context) error {
 
 // Close closes cache connections
 func (m *Manager) Close() error {
+	if m.cancel != nil {
+		m.cancel()
+		<-m.done
+	}
+	m.local.Close()
+	m.memory.Close()
 	if m.redis != nil {
 		return m.redis.Close()
 	}
 	return nil
 }
 
+// publish broadcasts an invalidation to the other replicas
+func (m *Manager) publish(ctx context.Context, msg invalidation) error {
+	msg.Origin = m.nodeID
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if err := m.redis.Publish(ctx, payload); err != nil {
+		return fmt.Errorf("failed to publish cache invalidation: %w", err)
+	}
+	return nil
+}
+
+// subscribe starts listening for invalidations published by other replicas
+func (m *Manager) subscribe() error {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	pubsub, err := m.redis.Subscribe(ctx)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	m.cancel = cancel
+	m.done = make(chan struct{})
+
+	go func() {
+		defer close(m.done)
+		defer pubsub.Close()
+
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+				m.applyInvalidation(message.Payload)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// applyInvalidation evicts entries from the local and memory tiers. The Redis
+// tier is shared and has already been updated by the publishing replica.
+func (m *Manager) applyInvalidation(payload string) {
+	var msg invalidation
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		if m.logger != nil {
+			m.logger.Warn("Ignoring malformed cache invalidation", "error", err)
+		}
+		return
+	}
+
+	if msg.Origin == m.nodeID {
+		return
+	}
+
+	switch msg.Op {
+	case invalidateKey:
+		m.local.Delete(msg.Key)
+		m.memory.Delete(msg.Key)
+	case invalidateTag:
+		m.local.InvalidateTag(msg.Tag)
+		m.memory.InvalidateTag(msg.Tag)
+	case invalidateClear:
+		m.local.Clear()
+		m.memory.Clear()
+	default:
+		if m.logger != nil {
+			m.logger.Warn("Ignoring unknown cache invalidation", "op", msg.Op)
+		}
+	}
+}
+
 // SetLogger sets the logger for the cache manager
 func (m *Manager) SetLogger(logger *logger.Logger) {
 	m.logger = logger
 }
 
-// MemoryCache provides in-memory caching
+// MemoryCache provides in-memory caching shared by all requests on this
+// instance. It is a sharded LRU bounded by entry count and estimated bytes.
 type MemoryCache struct {
-	data       map[string]cacheItem
-	maxMemory  int64
-	maxEntries int
-	mutex      sync.RWMutex
+	store *lru
 }
 
-type cacheItem struct {
-	value      interface{}
-	expiration time.Time
-	size       int64
-}
-
-// NewMemoryCache creates a new memory cache
+// NewMemoryCache creates a new memory cache. Values are sized by an
+// estimate of the memory they reference.
 func NewMemoryCache(maxMemory int64, maxEntries int) *MemoryCache {
-	return &MemoryCache{
-		data:       make(map[string]cacheItem),
-		maxMemory:  maxMemory,
-		maxEntries: maxEntries,
-	}
+	return &MemoryCache{store: newLRU(maxMemory, maxEntries, estimateSize, nil)}
 }
 
 // Get retrieves a value from memory cache
 func (c *MemoryCache) Get(key string) (interface{}, bool) {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
-
-	item, found := c.data[key]
-	if !found {
-		return nil, false
-	}
-
-	if time.Now().After(item.expiration) {
-		delete(c.data, key)
-		return nil, false
-	}
+	return c.store.get(key)
+}
 
-	return item.value, true
+// GetWithTags retrieves a value, the tags it was stored with and its
+// remaining time to live, 0 when it never expires
+func (c *MemoryCache) GetWithTags(key string) (interface{}, []string, time.Duration, bool) {
+	return c.store.getWithTags(key)
 }
 
-// Set stores a value in memory cache
+// Set stores a value in memory cache. A non-positive ttl never expires.
 func (c *MemoryCache) Set(key string, value interface{}, ttl time.Duration) {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-
-	// Clean expired items first
-	c.cleanup()
-
-	// Check capacity constraints
-	if len(c.data) >= c.maxEntries {
-		c.evictOldest()
-	}
-
-	item := cacheItem{
-		value:      value,
-		expiration: time.Now().Add(ttl),
-		size:       64, // Simplified size calculation
-	}
+	c.store.set(key, value, ttl, nil)
+}
 
-	c.data[key] = item
+// SetWithTags stores a tagged value in memory cache
+func (c *MemoryCache) SetWithTags(key string, value interface{}, ttl time.Duration, tags []string) {
+	c.store.set(key, value, ttl, tags)
 }
 
 // Delete removes a value from memory cache
 func (c *MemoryCache) Delete(key string) {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-	delete(c.data, key)
+	c.store.delete(key)
 }
 
-// Clear clears all memory cache
-func (c *MemoryCache) Clear() {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-	c.data = make(map[string]cacheItem)
+// InvalidateTag removes every value stored with tag from memory cache
+func (c *MemoryCache) InvalidateTag(tag string) {
+	c.store.invalidateTag(tag)
 }
 
-// cleanup removes expired items
-func (c *MemoryCache) cleanup() {
-	now := time.Now()
-	for key, item := range c.data {
-		if now.After(item.expiration) {
-			delete(c.data, key)
-		}
-	}
+// Keys returns up to limit keys starting with prefix
+func (c *MemoryCache) Keys(prefix string, limit int) []string {
+	return c.store.keys(prefix, limit)
 }
 
-// evictOldest removes the oldest entry when at capacity
-func (c *MemoryCache) evictOldest() {
-	var oldestKey string
-	var oldestTime time.Time
-
-	for key, item := range c.data {
-		if oldestKey == "" || item.expiration.Before(oldestTime) {
-			oldestKey = key
-			oldestTime = item.expiration
-		}
-	}
+// Clear clears all memory cache
+func (c *MemoryCache) Clear() {
+	c.store.clear()
+}
 
-	if oldestKey != "" {
-		delete(c.data, oldestKey)
-	}
+// Close stops background expiry
+func (c *MemoryCache) Close() {
+	c.store.close()
 }
 
-// LocalCache provides per-instance caching
+// LocalCache provides a small per-instance cache in front of MemoryCache for
+// the hottest keys. It uses the same LRU with tighter limits.
 type LocalCache struct {
-	data       map[string]interface{}
-	maxMemory  int64
-	maxEntries int
-	mutex      sync.RWMutex
+	store *lru
 }
 
-// NewLocalCache creates a new local cache
+// NewLocalCache creates a new local cache. Values are sized by an
+// estimate of the memory they reference.
 func NewLocalCache(maxMemory int64, maxEntries int) *LocalCache {
-	return &LocalCache{
-		data:       make(map[string]interface{}),
-		maxMemory:  maxMemory,
-		maxEntries: maxEntries,
-	}
+	return &LocalCache{store: newLRU(maxMemory, maxEntries, estimateSize, nil)}
 }
 
 // Get retrieves a value from local cache
 func (c *LocalCache) Get(key string) (interface{}, bool) {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
-	val, found := c.data[key]
-	return val, found
+	return c.store.get(key)
 }
 
-// Set stores a value in local cache
+// Set stores a value in local cache. A non-positive ttl never expires.
 func (c *LocalCache) Set(key string, value interface{}, ttl time.Duration) {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-
-	if len(c.data) >= c.maxEntries {
-		// Simple FIFO eviction
-		for k := range c.data {
-			delete(c.data, k)
-			break
-		}
-	}
+	c.store.set(key, value, ttl, nil)
+}
 
-	c.data[key] = value
+// SetWithTags stores a tagged value in local cache
+func (c *LocalCache) SetWithTags(key string, value interface{}, ttl time.Duration, tags []string) {
+	c.store.set(key, value, ttl, tags)
 }
 
 // Delete removes a value from local cache
 func (c *LocalCache) Delete(key string) {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-	delete(c.data, key)
+	c.store.delete(key)
+}
+
+// InvalidateTag removes every value stored with tag from local cache
+func (c *LocalCache) InvalidateTag(tag string) {
+	c.store.invalidateTag(tag)
+}
+
+// Keys returns up to limit keys starting with prefix
+func (c *LocalCache) Keys(prefix string, limit int) []string {
+	return c.store.keys(prefix, limit)
 }
 
 // Clear clears all local cache
 func (c *LocalCache) Clear() {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-	c.data = make(map[string]interface{})
+	c.store.clear()
 }
 
-// RedisCache provides Redis-based caching
+// Close stops background expiry
+func (c *LocalCache) Close() {
+	c.store.close()
+}
+
+// RedisCache provides Redis-based caching. All keys are stored under a
+// prefix so that Clear only removes CiviCRM entries, and tags are kept as
+// Redis sets of the keys that carry them.
 type RedisCache struct {
 	client *redis.Client
+	prefix string
 }
 
 // NewRedisCache creates a new Redis cache
@@ -301,31 +450,244 @@ func NewRedisCache(config *config.CacheConfig) (*RedisCache, error) {
 		return nil, err
 	}
 
-	return &RedisCache{client: client}, nil
+	prefix := config.KeyPrefix
+	if prefix == "" {
+		prefix = "civicrm"
+	}
+
+	return &RedisCache{client: client, prefix: prefix + ":"}, nil
+}
+
+// key returns the namespaced Redis key for a cache key
+func (c *RedisCache) key(key string) string {
+	return c.prefix + "key:" + key
+}
+
+// tagKey returns the Redis key of the set holding the keys for a tag
+func (c *RedisCache) tagKey(tag string) string {
+	return c.prefix + "tag:" + tag
 }
 
-// Get retrieves a value from Redis
-func (c *RedisCache) Get(ctx context.Context, key string) (interface{}, error) {
-	val, err := c.client.Get(ctx, key).Result()
+// Get retrieves an encoded value, its tags and its remaining time to live
+// from Redis. The time to live is 0 when the key never expires.
+func (c *RedisCache) Get(ctx context.Context, key string) ([]byte, []string, time.Duration, error) {
+	var get *redis.StringCmd
+	var pttl *redis.DurationCmd
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		get = pipe.Get(ctx, c.key(key))
+		pttl = pipe.PTTL(ctx, c.key(key))
+		return nil
+	})
 	if err != nil {
-		return nil, err
+		return nil, nil, 0, err
 	}
-	return val, nil
+
+	data, err := get.Bytes()
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	payload, tags, err := decodeEntry(data)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	ttl := pttl.Val()
+	if ttl < 0 {
+		ttl = 0
+	}
+	return payload, tags, ttl, nil
+}
+
+// Set stores an encoded value in Redis
+func (c *RedisCache) Set(ctx context.Context, key string, payload []byte, ttl time.Duration) error {
+	return c.SetWithTags(ctx, key, payload, ttl, nil)
 }
 
-// Set stores a value in Redis
-func (c *RedisCache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
-	return c.client.Set(ctx, key, value, ttl).Err()
+// SetWithTags stores an encoded value in Redis and adds its key to each tag
+// set, removing it from the sets of tags it no longer has. The tags are also
+// kept with the value so that replicas filling their in-process tiers from
+// Redis can still invalidate the entry by tag. Tag sets live at least as
+// long as the longest-lived key they contain.
+func (c *RedisCache) SetWithTags(ctx context.Context, key string, payload []byte, ttl time.Duration, tags []string) error {
+	return c.rewrite(ctx, key, func(pipe redis.Pipeliner, oldTags []string) {
+		for _, tag := range oldTags {
+			if !slices.Contains(tags, tag) {
+				pipe.SRem(ctx, c.tagKey(tag), key)
+			}
+		}
+		pipe.Set(ctx, c.key(key), encodeEntry(payload, tags), ttl)
+		for _, tag := range tags {
+			pipe.SAdd(ctx, c.tagKey(tag), key)
+			if ttl > 0 {
+				pipe.ExpireNX(ctx, c.tagKey(tag), ttl)
+				pipe.ExpireGT(ctx, c.tagKey(tag), ttl)
+			} else {
+				pipe.Persist(ctx, c.tagKey(tag))
+			}
+		}
+	})
 }
 
-// Delete removes a value from Redis
+// Delete removes a value from Redis and its key from its tag sets
 func (c *RedisCache) Delete(ctx context.Context, key string) error {
-	return c.client.Del(ctx, key).Err()
+	return c.rewrite(ctx, key, func(pipe redis.Pipeliner, oldTags []string) {
+		for _, tag := range oldTags {
+			pipe.SRem(ctx, c.tagKey(tag), key)
+		}
+		pipe.Del(ctx, c.key(key))
+	})
+}
+
+// maxRewriteAttempts bounds the retries of a write racing other writes to
+// the same key
+const maxRewriteAttempts = 5
+
+// rewrite runs write in a transaction with the tags of the key's current
+// entry, retrying when the key changes before the transaction commits
+func (c *RedisCache) rewrite(ctx context.Context, key string, write func(pipe redis.Pipeliner, oldTags []string)) error {
+	redisKey := c.key(key)
+	for attempt := 0; attempt < maxRewriteAttempts; attempt++ {
+		err := c.client.Watch(ctx, func(tx *redis.Tx) error {
+			var oldTags []string
+			data, err := tx.Get(ctx, redisKey).Bytes()
+			switch {
+			case err == nil:
+				// A malformed entry is overwritten without untagging
+				_, oldTags, _ = decodeEntry(data)
+			case !errors.Is(err, redis.Nil):
+				return err
+			}
+
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				write(pipe, oldTags)
+				return nil
+			})
+			return err
+		}, redisKey)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return fmt.Errorf("failed to write cache key %s: %w", key, redis.TxFailedErr)
 }
 
-// Clear clears all Redis cache
+// InvalidateTag removes every key in the tag set, then the tag set itself
+func (c *RedisCache) InvalidateTag(ctx context.Context, tag string) error {
+	keys, err := c.client.SMembers(ctx, c.tagKey(tag)).Result()
+	if err != nil {
+		return err
+	}
+
+	redisKeys := make([]string, 0, len(keys)+1)
+	for _, key := range keys {
+		redisKeys = append(redisKeys, c.key(key))
+	}
+	redisKeys = append(redisKeys, c.tagKey(tag))
+
+	return c.client.Del(ctx, redisKeys...).Err()
+}
+
+// Keys returns up to limit cache keys starting with prefix. Keys are found
+// with SCAN, so the result may miss keys written while it runs.
+func (c *RedisCache) Keys(ctx context.Context, prefix string, limit int) ([]string, error) {
+	var keys []string
+	pattern := globEscaper.Replace(c.key(prefix)) + "*"
+	iter := c.client.Scan(ctx, 0, pattern, 1000).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, strings.TrimPrefix(iter.Val(), c.key("")))
+		if limit > 0 && len(keys) >= limit {
+			break
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// globEscaper escapes the characters that are special in Redis MATCH patterns
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+// Clear removes all CiviCRM keys and tags from Redis, leaving other data in
+// the database untouched
 func (c *RedisCache) Clear(ctx context.Context) error {
-	return c.client.FlushDB(ctx).Err()
+	iter := c.client.Scan(ctx, 0, globEscaper.Replace(c.prefix)+"*", 1000).Iterator()
+	batch := make([]string, 0, 1000)
+	for iter.Next(ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == cap(batch) {
+			if err := c.client.Del(ctx, batch...).Err(); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		return c.client.Del(ctx, batch...).Err()
+	}
+	return nil
+}
+
+// encodeEntry prefixes payload with its tags: a uvarint tag count followed by
+// each tag as a uvarint length and its bytes
+func encodeEntry(payload []byte, tags []string) []byte {
+	data := binary.AppendUvarint(nil, uint64(len(tags)))
+	for _, tag := range tags {
+		data = binary.AppendUvarint(data, uint64(len(tag)))
+		data = append(data, tag...)
+	}
+	return append(data, payload...)
+}
+
+// decodeEntry splits a value written by encodeEntry into payload and tags
+func decodeEntry(data []byte) ([]byte, []string, error) {
+	count, n := binary.Uvarint(data)
+	if n <= 0 || count > uint64(len(data)) {
+		return nil, nil, errors.New("malformed cache entry")
+	}
+	data = data[n:]
+
+	tags := make([]string, 0, count)
+	for i := uint64(0); i < count; i++ {
+		length, n := binary.Uvarint(data)
+		if n <= 0 || length > uint64(len(data)-n) {
+			return nil, nil, errors.New("malformed cache entry")
+		}
+		tags = append(tags, string(data[n:n+int(length)]))
+		data = data[n+int(length):]
+	}
+
+	return data, tags, nil
+}
+
+// channel returns the pub/sub channel used for invalidations
+func (c *RedisCache) channel() string {
+	return c.prefix + "invalidate"
+}
+
+// Publish sends an invalidation message to all subscribed replicas
+func (c *RedisCache) Publish(ctx context.Context, payload []byte) error {
+	return c.client.Publish(ctx, c.channel(), payload).Err()
+}
+
+// Subscribe subscribes to the invalidation channel and waits for Redis to
+// confirm the subscription
+func (c *RedisCache) Subscribe(ctx context.Context) (*redis.PubSub, error) {
+	pubsub := c.client.Subscribe(ctx, c.channel())
+
+	msg, err := pubsub.Receive(ctx)
+	if err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+	if _, ok := msg.(*redis.Subscription); !ok {
+		pubsub.Close()
+		return nil, errors.New("unexpected reply to subscribe")
+	}
+
+	return pubsub, nil
 }
 
 // Close closes Redis connection
@@ -0,0 +1,257 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jxlxx/civicrm/internal/config"
+	"github.com/jxlxx/civicrm/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	manager, err := New(&config.CacheConfig{
+		Driver:     "memory",
+		TTL:        time.Minute,
+		MaxMemory:  10 * 1024 * 1024,
+		MaxEntries: 1000,
+	}, logger.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(func() { manager.Close() })
+	return manager
+}
+
+func TestManagerInvalidateTag(t *testing.T) {
+	ctx := context.Background()
+	manager := newTestManager(t)
+
+	require.NoError(t, manager.SetWithTags(ctx, "acl:contact:1", "allow", time.Minute, TagACL, EntityTag("contact", 1)))
+	require.NoError(t, manager.SetWithTags(ctx, "acl:contact:2", "deny", time.Minute, TagACL, EntityTag("contact", 2)))
+	require.NoError(t, manager.SetWithTags(ctx, "setting:currency", "USD", time.Minute, TagSettings))
+
+	require.NoError(t, manager.InvalidateTag(ctx, EntityTag("contact", 1)))
+	_, err := manager.Get(ctx, "acl:contact:1")
+	assert.Error(t, err)
+	val, err := manager.Get(ctx, "acl:contact:2")
+	require.NoError(t, err)
+	assert.Equal(t, "deny", val)
+
+	require.NoError(t, manager.InvalidateTag(ctx, TagACL))
+	_, err = manager.Get(ctx, "acl:contact:2")
+	assert.Error(t, err)
+
+	val, err = manager.Get(ctx, "setting:currency")
+	require.NoError(t, err)
+	assert.Equal(t, "USD", val)
+}
+
+func TestManagerSetReplacesTags(t *testing.T) {
+	ctx := context.Background()
+	manager := newTestManager(t)
+
+	require.NoError(t, manager.SetWithTags(ctx, "options:gender", []string{"F", "M"}, time.Minute, EntityTag("option_group", "gender")))
+	require.NoError(t, manager.Set(ctx, "options:gender", []string{"F", "M", "X"}, time.Minute))
+
+	// The untagged rewrite is no longer part of the tag
+	require.NoError(t, manager.InvalidateTag(ctx, EntityTag("option_group", "gender")))
+	val, err := manager.Get(ctx, "options:gender")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"F", "M", "X"}, val)
+}
+
+// TestManagerBackfillKeepsTTL tests that an entry copied into the local tier
+// expires with the entry it was read from, not after the default TTL
+func TestManagerBackfillKeepsTTL(t *testing.T) {
+	ctx := context.Background()
+	manager := newTestManager(t)
+
+	manager.memory.SetWithTags("setting:currency", "USD", 50*time.Millisecond, []string{TagSettings})
+	val, err := manager.Get(ctx, "setting:currency")
+	require.NoError(t, err)
+	assert.Equal(t, "USD", val)
+
+	time.Sleep(60 * time.Millisecond)
+	_, found := manager.local.Get("setting:currency")
+	assert.False(t, found)
+	_, err = manager.Get(ctx, "setting:currency")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestManagerApplyInvalidation(t *testing.T) {
+	ctx := context.Background()
+	manager := newTestManager(t)
+
+	require.NoError(t, manager.SetWithTags(ctx, "a", 1, time.Minute, "group"))
+	require.NoError(t, manager.Set(ctx, "b", 2, time.Minute))
+	require.NoError(t, manager.Set(ctx, "c", 3, time.Minute))
+
+	// Messages from this node are ignored
+	manager.applyInvalidation(`{"origin": "` + manager.nodeID + `", "op": "key", "key": "b"}`)
+	_, err := manager.Get(ctx, "b")
+	require.NoError(t, err)
+
+	manager.applyInvalidation(`{"origin": "other", "op": "key", "key": "b"}`)
+	_, err = manager.Get(ctx, "b")
+	assert.Error(t, err)
+
+	manager.applyInvalidation(`{"origin": "other", "op": "tag", "tag": "group"}`)
+	_, err = manager.Get(ctx, "a")
+	assert.Error(t, err)
+
+	manager.applyInvalidation(`{"origin": "other", "op": "clear"}`)
+	_, err = manager.Get(ctx, "c")
+	assert.Error(t, err)
+}
+
+type cachedContact struct {
+	ID        string
+	FirstName string
+	Groups    []string
+}
+
+func TestCodecsRoundTrip(t *testing.T) {
+	contact := cachedContact{ID: "c1", FirstName: "Ada", Groups: []string{"donors"}}
+
+	for _, name := range []string{"json", "gob", "msgpack"} {
+		t.Run(name, func(t *testing.T) {
+			codec, err := NewCodec(name)
+			require.NoError(t, err)
+
+			payload, err := codec.Marshal(contact)
+			require.NoError(t, err)
+
+			// Payloads survive the Redis entry framing together with their tags
+			stored, tags, err := decodeEntry(encodeEntry(payload, []string{"contact:c1", TagACL}))
+			require.NoError(t, err)
+			assert.Equal(t, []string{"contact:c1", TagACL}, tags)
+
+			var decoded cachedContact
+			require.NoError(t, codec.Unmarshal(stored, &decoded))
+			assert.Equal(t, contact, decoded)
+		})
+	}
+
+	_, err := NewCodec("xml")
+	assert.Error(t, err)
+}
+
+func TestGetTyped(t *testing.T) {
+	ctx := context.Background()
+	manager := newTestManager(t)
+
+	require.NoError(t, manager.Set(ctx, "contact:c1", cachedContact{ID: "c1"}, time.Minute))
+
+	contact, err := Get[cachedContact](ctx, manager, "contact:c1")
+	require.NoError(t, err)
+	assert.Equal(t, "c1", contact.ID)
+
+	// A value of another type is treated as a miss
+	_, err = Get[string](ctx, manager, "contact:c1")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestGetOrLoad(t *testing.T) {
+	ctx := context.Background()
+	manager := newTestManager(t)
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (cachedContact, error) {
+		calls.Add(1)
+		<-release
+		return cachedContact{ID: "c2", FirstName: "Grace"}, nil
+	}
+
+	// Concurrent misses share one loader call
+	var wg sync.WaitGroup
+	results := make([]cachedContact, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			val, err := GetOrLoad(ctx, manager, "contact:c2", time.Minute, loader, EntityTag("contact", "c2"))
+			assert.NoError(t, err)
+			results[i] = val
+		}(i)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	for _, val := range results {
+		assert.Equal(t, "Grace", val.FirstName)
+	}
+
+	// Later calls are served from cache until the tag is invalidated
+	_, err := GetOrLoad(ctx, manager, "contact:c2", time.Minute, loader)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), calls.Load())
+
+	require.NoError(t, manager.InvalidateTag(ctx, EntityTag("contact", "c2")))
+	_, err = GetOrLoad(ctx, manager, "contact:c2", time.Minute, loader)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+// TestGetOrLoadFirstCallerCancelled tests that cancelling the request that
+// started a load does not fail the other callers waiting on it
+func TestGetOrLoadFirstCallerCancelled(t *testing.T) {
+	manager := newTestManager(t)
+
+	started := make(chan struct{})
+	var once sync.Once
+	release := make(chan struct{})
+	loader := func(ctx context.Context) (cachedContact, error) {
+		once.Do(func() { close(started) })
+		select {
+		case <-release:
+			return cachedContact{ID: "c3", FirstName: "Hedy"}, nil
+		case <-ctx.Done():
+			return cachedContact{}, ctx.Err()
+		}
+	}
+
+	first, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := GetOrLoad(first, manager, "contact:c3", time.Minute, loader)
+		firstErr <- err
+	}()
+	<-started
+
+	second := make(chan cachedContact, 1)
+	go func() {
+		val, err := GetOrLoad(context.Background(), manager, "contact:c3", time.Minute, loader)
+		assert.NoError(t, err)
+		second <- val
+	}()
+
+	cancel()
+	assert.ErrorIs(t, <-firstErr, context.Canceled)
+	close(release)
+	assert.Equal(t, "Hedy", (<-second).FirstName)
+
+	val, err := Get[cachedContact](context.Background(), manager, "contact:c3")
+	require.NoError(t, err)
+	assert.Equal(t, "Hedy", val.FirstName)
+}
+
+func TestGetOrLoadDoesNotCacheErrors(t *testing.T) {
+	ctx := context.Background()
+	manager := newTestManager(t)
+
+	_, err := GetOrLoad(ctx, manager, "report:1", time.Minute, func(ctx context.Context) (int, error) {
+		return 0, errors.New("database unavailable")
+	})
+	assert.EqualError(t, err, "database unavailable")
+
+	_, err = manager.Get(ctx, "report:1")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,31 @@
+// Package cachetest helps test services that invalidate cached entries.
+package cachetest
+
+import (
+	"context"
+	"sync"
+)
+
+// Invalidator is a cache.Invalidator that records the tags it is asked to
+// invalidate
+type Invalidator struct {
+	mutex sync.Mutex
+	tags  []string
+}
+
+// InvalidateTag records tag
+func (i *Invalidator) InvalidateTag(ctx context.Context, tag string) error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.tags = append(i.tags, tag)
+	return nil
+}
+
+// Tags returns the tags invalidated so far and forgets them
+func (i *Invalidator) Tags() []string {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	tags := i.tags
+	i.tags = nil
+	return tags
+}
@@ -0,0 +1,352 @@
+package cache
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxShards is the number of shards used for large caches
+	maxShards = 16
+	// minShardEntries keeps small caches from being split into tiny shards
+	minShardEntries = 64
+	// entryOverhead approximates the bookkeeping cost of one entry in bytes
+	entryOverhead = 96
+	// defaultValueSize is charged for values when no measure is given
+	defaultValueSize = 64
+	// expiryInterval is how often expired entries are removed in the background
+	expiryInterval = time.Minute
+)
+
+// Sizer can be implemented by cached values that know their own size
+type Sizer interface {
+	CacheSize() int64
+}
+
+// sizeOf estimates the memory used by a cached key and value. Values other
+// than strings, byte slices and Sizers are charged what measure reports.
+func sizeOf(key string, value interface{}, measure func(value interface{}) int64) int64 {
+	size := int64(entryOverhead + len(key))
+	switch v := value.(type) {
+	case []byte:
+		size += int64(len(v))
+	case string:
+		size += int64(len(v))
+	case Sizer:
+		size += v.CacheSize()
+	default:
+		if measure != nil {
+			size += measure(v)
+		} else {
+			size += defaultValueSize
+		}
+	}
+	return size
+}
+
+// lruEntry is a node in a shard's recency list
+type lruEntry struct {
+	key        string
+	value      interface{}
+	size       int64
+	expiration int64 // unix nanoseconds, 0 means no expiry
+	prev, next *lruEntry
+}
+
+func (e *lruEntry) expired(now int64) bool {
+	return e.expiration != 0 && now > e.expiration
+}
+
+// lruShard is an independently locked part of an lru cache
+type lruShard struct {
+	mutex      sync.Mutex
+	items      map[string]*lruEntry
+	index      *tagIndex
+	head, tail *lruEntry // head is most recently used
+	bytes      int64
+	maxBytes   int64
+	maxEntries int
+	onEvict    func(key, reason string)
+}
+
+// Reasons an entry is evicted from an lru
+const (
+	evictCapacity = "capacity"
+	evictExpired  = "expired"
+)
+
+// lru is a sharded, size-bounded least-recently-used cache with TTLs
+type lru struct {
+	shards  []*lruShard
+	mask    uint32
+	measure func(value interface{}) int64
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// newLRU creates an lru holding at most maxEntries entries and maxBytes
+// bytes. Non-positive limits are treated as unbounded. measure sizes values
+// that are not strings, byte slices or Sizers. onEvict, if not nil, is
+// called with the shard locked whenever an entry is dropped because of
+// capacity or expiry.
+func newLRU(maxBytes int64, maxEntries int, measure func(value interface{}) int64, onEvict func(key, reason string)) *lru {
+	count := 1
+	for count < maxShards && (maxEntries <= 0 || maxEntries/(count*2) >= minShardEntries) {
+		count *= 2
+	}
+
+	c := &lru{
+		shards:  make([]*lruShard, count),
+		mask:    uint32(count - 1),
+		measure: measure,
+		stop:    make(chan struct{}),
+	}
+	for i := range c.shards {
+		shard := &lruShard{
+			items:   make(map[string]*lruEntry),
+			index:   newTagIndex(),
+			onEvict: onEvict,
+		}
+		if maxBytes > 0 {
+			shard.maxBytes = max(maxBytes/int64(count), 1)
+		}
+		if maxEntries > 0 {
+			shard.maxEntries = max(maxEntries/count, 1)
+		}
+		c.shards[i] = shard
+	}
+
+	go c.expireLoop(expiryInterval)
+	return c
+}
+
+// shard returns the shard owning key using FNV-1a
+func (c *lru) shard(key string) *lruShard {
+	hash := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= 16777619
+	}
+	return c.shards[hash&c.mask]
+}
+
+func (c *lru) get(key string) (interface{}, bool) {
+	value, _, _, found := c.shard(key).get(key, time.Now().UnixNano())
+	return value, found
+}
+
+// getWithTags returns a value, its tags and its remaining time to live, 0
+// when it never expires
+func (c *lru) getWithTags(key string) (interface{}, []string, time.Duration, bool) {
+	now := time.Now().UnixNano()
+	value, tags, expiration, found := c.shard(key).get(key, now)
+	var ttl time.Duration
+	if found && expiration != 0 {
+		ttl = time.Duration(expiration - now)
+	}
+	return value, tags, ttl, found
+}
+
+func (c *lru) set(key string, value interface{}, ttl time.Duration, tags []string) {
+	var expiration int64
+	if ttl > 0 {
+		expiration = time.Now().Add(ttl).UnixNano()
+	}
+	c.shard(key).set(key, value, sizeOf(key, value, c.measure), expiration, tags)
+}
+
+func (c *lru) delete(key string) {
+	shard := c.shard(key)
+	shard.mutex.Lock()
+	defer shard.mutex.Unlock()
+	if entry, found := shard.items[key]; found {
+		shard.remove(entry)
+	}
+}
+
+func (c *lru) invalidateTag(tag string) {
+	for _, shard := range c.shards {
+		shard.mutex.Lock()
+		for _, key := range shard.index.take(tag) {
+			if entry, found := shard.items[key]; found {
+				shard.remove(entry)
+			}
+		}
+		shard.mutex.Unlock()
+	}
+}
+
+func (c *lru) clear() {
+	for _, shard := range c.shards {
+		shard.mutex.Lock()
+		shard.items = make(map[string]*lruEntry)
+		shard.index.clear()
+		shard.head, shard.tail = nil, nil
+		shard.bytes = 0
+		shard.mutex.Unlock()
+	}
+}
+
+// keys returns up to limit unexpired keys starting with prefix. A
+// non-positive limit returns every matching key.
+func (c *lru) keys(prefix string, limit int) []string {
+	now := time.Now().UnixNano()
+	var keys []string
+	for _, shard := range c.shards {
+		shard.mutex.Lock()
+		for key, entry := range shard.items {
+			if limit > 0 && len(keys) >= limit {
+				break
+			}
+			if !entry.expired(now) && strings.HasPrefix(key, prefix) {
+				keys = append(keys, key)
+			}
+		}
+		shard.mutex.Unlock()
+	}
+	return keys
+}
+
+// size returns the number of entries and estimated bytes held
+func (c *lru) size() (entries int, bytes int64) {
+	for _, shard := range c.shards {
+		shard.mutex.Lock()
+		entries += len(shard.items)
+		bytes += shard.bytes
+		shard.mutex.Unlock()
+	}
+	return entries, bytes
+}
+
+// close stops background expiry
+func (c *lru) close() {
+	c.once.Do(func() { close(c.stop) })
+}
+
+// expireLoop periodically removes expired entries so that values nobody
+// reads again do not hold memory until they are evicted
+func (c *lru) expireLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			c.removeExpired()
+		}
+	}
+}
+
+func (c *lru) removeExpired() {
+	now := time.Now().UnixNano()
+	for _, shard := range c.shards {
+		shard.mutex.Lock()
+		for _, entry := range shard.items {
+			if entry.expired(now) {
+				shard.evict(entry, evictExpired)
+			}
+		}
+		shard.mutex.Unlock()
+	}
+}
+
+func (s *lruShard) get(key string, now int64) (interface{}, []string, int64, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	entry, found := s.items[key]
+	if !found {
+		return nil, nil, 0, false
+	}
+	if entry.expired(now) {
+		s.evict(entry, evictExpired)
+		return nil, nil, 0, false
+	}
+
+	s.moveToFront(entry)
+	return entry.value, s.index.tags[key], entry.expiration, true
+}
+
+func (s *lruShard) set(key string, value interface{}, size, expiration int64, tags []string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if entry, found := s.items[key]; found {
+		s.remove(entry)
+	}
+
+	// Values larger than the whole shard are not cached at all
+	if s.maxBytes > 0 && size > s.maxBytes {
+		return
+	}
+
+	entry := &lruEntry{key: key, value: value, size: size, expiration: expiration}
+	s.items[key] = entry
+	s.pushFront(entry)
+	s.bytes += size
+	s.index.add(key, tags)
+
+	for s.tail != nil && s.overLimit() {
+		s.evict(s.tail, evictCapacity)
+	}
+}
+
+func (s *lruShard) overLimit() bool {
+	return (s.maxEntries > 0 && len(s.items) > s.maxEntries) ||
+		(s.maxBytes > 0 && s.bytes > s.maxBytes)
+}
+
+// evict removes entry and reports it; callers hold the shard lock
+func (s *lruShard) evict(entry *lruEntry, reason string) {
+	s.remove(entry)
+	if s.onEvict != nil {
+		s.onEvict(entry.key, reason)
+	}
+}
+
+// remove unlinks entry from the shard; callers hold the shard lock
+func (s *lruShard) remove(entry *lruEntry) {
+	if entry.prev != nil {
+		entry.prev.next = entry.next
+	} else {
+		s.head = entry.next
+	}
+	if entry.next != nil {
+		entry.next.prev = entry.prev
+	} else {
+		s.tail = entry.prev
+	}
+	entry.prev, entry.next = nil, nil
+
+	delete(s.items, entry.key)
+	s.index.remove(entry.key)
+	s.bytes -= entry.size
+}
+
+func (s *lruShard) pushFront(entry *lruEntry) {
+	entry.next = s.head
+	if s.head != nil {
+		s.head.prev = entry
+	}
+	s.head = entry
+	if s.tail == nil {
+		s.tail = entry
+	}
+}
+
+func (s *lruShard) moveToFront(entry *lruEntry) {
+	if s.head == entry {
+		return
+	}
+	// Unlink without touching the map or byte count
+	entry.prev.next = entry.next
+	if entry.next != nil {
+		entry.next.prev = entry.prev
+	} else {
+		s.tail = entry.prev
+	}
+	entry.prev = nil
+	s.pushFront(entry)
+}
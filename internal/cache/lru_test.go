@@ -0,0 +1,294 @@
+package cache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRUEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newLRU(0, 3, nil, nil)
+	defer c.close()
+	require.Len(t, c.shards, 1)
+
+	c.set("a", 1, 0, nil)
+	c.set("b", 2, 0, nil)
+	c.set("c", 3, 0, nil)
+
+	// Touch "a" so that "b" becomes the oldest entry
+	_, found := c.get("a")
+	require.True(t, found)
+
+	c.set("d", 4, 0, nil)
+	_, found = c.get("b")
+	assert.False(t, found)
+	for _, key := range []string{"a", "c", "d"} {
+		_, found = c.get(key)
+		assert.True(t, found, key)
+	}
+}
+
+func TestLRUEnforcesByteLimit(t *testing.T) {
+	value := make([]byte, 100)
+	entrySize := sizeOf("k0", value, nil)
+	c := newLRU(entrySize*2, 10, nil, nil)
+	require.Len(t, c.shards, 1)
+	defer c.close()
+
+	c.set("k0", value, 0, nil)
+	c.set("k1", value, 0, nil)
+	c.set("k2", value, 0, nil)
+
+	_, found := c.get("k0")
+	assert.False(t, found)
+	entries, bytes := c.shards[0].items, c.shards[0].bytes
+	assert.Len(t, entries, 2)
+	assert.Equal(t, entrySize*2, bytes)
+
+	// A value larger than the cache is not stored and does not evict others
+	c.set("huge", make([]byte, 1000), 0, nil)
+	_, found = c.get("huge")
+	assert.False(t, found)
+	_, found = c.get("k2")
+	assert.True(t, found)
+}
+
+// TestLRUMeasuresStructs tests that structs are charged for the strings and
+// slices they reference, so the byte limit holds for them
+func TestLRUMeasuresStructs(t *testing.T) {
+	type record struct {
+		Name  string
+		Notes []string
+	}
+	value := record{Name: "Ada", Notes: make([]string, 100)}
+	for i := range value.Notes {
+		value.Notes[i] = "a long note about the contact"
+	}
+
+	size := sizeOf("k", value, estimateSize)
+	assert.Greater(t, size, int64(100*len(value.Notes[0])))
+
+	c := newLRU(size*2, 10, estimateSize, nil)
+	defer c.close()
+	c.set("k0", value, 0, nil)
+	c.set("k1", value, 0, nil)
+	c.set("k2", value, 0, nil)
+	entries, _ := c.size()
+	assert.Equal(t, 1, entries)
+}
+
+func TestEstimateSize(t *testing.T) {
+	type node struct {
+		Name string
+		Next *node
+	}
+	cyclic := &node{Name: "loop"}
+	cyclic.Next = cyclic
+
+	notes := make([]string, 1000)
+	for i := range notes {
+		notes[i] = "note"
+	}
+	tags := map[string][]int64{"a": make([]int64, 10), "b": make([]int64, 10)}
+
+	assert.Equal(t, int64(0), estimateSize(nil))
+	assert.Equal(t, int64(8), estimateSize(int64(7)))
+	assert.Equal(t, int64(16+len("hello")), estimateSize("hello"))
+	assert.Equal(t, int64(24+1000*(16+4)), estimateSize(notes), "elements beyond the samples are charged their average")
+	assert.Equal(t, int64(8+2*(16+1+24+80)), estimateSize(tags))
+	assert.Equal(t, estimateSize(time.Time{}), estimateSize(time.Now()), "locations are not charged")
+	assert.Positive(t, estimateSize(cyclic))
+}
+
+func TestLRUHonoursTTL(t *testing.T) {
+	c := newLRU(0, 0, nil, nil)
+	defer c.close()
+
+	c.set("short", "v", time.Millisecond, nil)
+	c.set("forever", "v", 0, nil)
+	time.Sleep(5 * time.Millisecond)
+
+	_, found := c.get("short")
+	assert.False(t, found)
+	_, found = c.get("forever")
+	assert.True(t, found)
+}
+
+func TestLRURemoveExpired(t *testing.T) {
+	c := newLRU(0, 10000, nil, nil)
+	defer c.close()
+
+	for i := 0; i < 100; i++ {
+		c.set(strconv.Itoa(i), i, time.Millisecond, []string{"numbers"})
+	}
+	c.set("kept", "v", time.Hour, []string{"numbers"})
+	time.Sleep(5 * time.Millisecond)
+
+	c.removeExpired()
+
+	var entries int
+	for _, shard := range c.shards {
+		entries += len(shard.items)
+		for tag, keys := range shard.index.keys {
+			assert.Equal(t, "numbers", tag)
+			assert.LessOrEqual(t, len(keys), 1)
+		}
+	}
+	assert.Equal(t, 1, entries)
+}
+
+func TestLRUInvalidateTagAcrossShards(t *testing.T) {
+	c := newLRU(0, 100000, nil, nil)
+	defer c.close()
+	require.Greater(t, len(c.shards), 1)
+
+	for i := 0; i < 1000; i++ {
+		tags := []string{"all"}
+		if i%2 == 0 {
+			tags = append(tags, "even")
+		}
+		c.set(strconv.Itoa(i), i, 0, tags)
+	}
+
+	c.invalidateTag("even")
+	_, found := c.get("2")
+	assert.False(t, found)
+	_, found = c.get("3")
+	assert.True(t, found)
+
+	c.invalidateTag("all")
+	for _, shard := range c.shards {
+		assert.Empty(t, shard.items)
+		assert.Zero(t, shard.bytes)
+	}
+}
+
+func benchmarkKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = "contact:" + strconv.Itoa(i)
+	}
+	return keys
+}
+
+func BenchmarkMemoryCacheGet(b *testing.B) {
+	c := NewMemoryCache(100*1024*1024, 10000)
+	defer c.Close()
+	keys := benchmarkKeys(10000)
+	for _, key := range keys {
+		c.Set(key, key, time.Hour)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Get(keys[i%len(keys)])
+	}
+}
+
+func BenchmarkMemoryCacheSet(b *testing.B) {
+	c := NewMemoryCache(100*1024*1024, 10000)
+	defer c.Close()
+	keys := benchmarkKeys(100000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		key := keys[i%len(keys)]
+		c.Set(key, key, time.Hour)
+	}
+}
+
+func BenchmarkMemoryCacheParallel(b *testing.B) {
+	c := NewMemoryCache(100*1024*1024, 10000)
+	defer c.Close()
+	keys := benchmarkKeys(20000)
+	for _, key := range keys[:10000] {
+		c.Set(key, key, time.Hour)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := keys[i%len(keys)]
+			if i%10 == 0 {
+				c.Set(key, key, time.Hour)
+			} else {
+				c.Get(key)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkLocalCacheGet(b *testing.B) {
+	c := NewLocalCache(10*1024*1024, 1000)
+	defer c.Close()
+	keys := benchmarkKeys(1000)
+	for _, key := range keys {
+		c.Set(key, key, time.Hour)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			c.Get(keys[i%len(keys)])
+			i++
+		}
+	})
+}
+
+// sizeBenchmarkValue is a contact-like value for the sizing benchmarks
+type sizeBenchmarkValue struct {
+	ID       string
+	Name     string
+	Emails   []string
+	Tags     map[string]string
+	Modified time.Time
+}
+
+func newSizeBenchmarkValue() sizeBenchmarkValue {
+	return sizeBenchmarkValue{
+		ID:       "7b0b4c9e-3c1e-4a55-9b8e-2f7a1d3c6e10",
+		Name:     "Ada Lovelace",
+		Emails:   []string{"ada@example.org", "countess@example.org"},
+		Tags:     map[string]string{"volunteer": "2024", "donor": "major"},
+		Modified: time.Now(),
+	}
+}
+
+// BenchmarkSizing compares estimating a value's size with encoding it,
+// which is how values used to be sized on every Set
+func BenchmarkSizing(b *testing.B) {
+	value := newSizeBenchmarkValue()
+	b.Run("estimate", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			estimateSize(value)
+		}
+	})
+	for _, codec := range []Codec{JSONCodec{}, MsgpackCodec{}} {
+		b.Run(codec.Name(), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, _ = codec.Marshal(value)
+			}
+		})
+	}
+}
+
+func BenchmarkMemoryCacheSetStruct(b *testing.B) {
+	c := NewMemoryCache(100*1024*1024, 10000)
+	defer c.Close()
+	keys := benchmarkKeys(100000)
+	value := newSizeBenchmarkValue()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Set(keys[i%len(keys)], value, time.Hour)
+	}
+}
@@ -0,0 +1,173 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/jxlxx/civicrm/internal/config"
+	"github.com/jxlxx/civicrm/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// redisConfig returns a cache config for a Redis server started for the test
+func redisConfig(t *testing.T) (*miniredis.Miniredis, *config.CacheConfig) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	port, err := strconv.Atoi(server.Port())
+	require.NoError(t, err)
+	return server, &config.CacheConfig{
+		Driver:     "redis",
+		Host:       server.Host(),
+		Port:       port,
+		TTL:        time.Minute,
+		MaxMemory:  10 * 1024 * 1024,
+		MaxEntries: 1000,
+	}
+}
+
+func newTestRedisCache(t *testing.T) (*miniredis.Miniredis, *RedisCache) {
+	t.Helper()
+	server, config := redisConfig(t)
+	c, err := NewRedisCache(config)
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+	return server, c
+}
+
+// members returns the keys in a tag set, or nil when the set does not exist
+func members(t *testing.T, server *miniredis.Miniredis, tag string) []string {
+	t.Helper()
+	if !server.Exists("civicrm:tag:" + tag) {
+		return nil
+	}
+	keys, err := server.Members("civicrm:tag:" + tag)
+	require.NoError(t, err)
+	return keys
+}
+
+func TestRedisSetWithTagsRewritesTags(t *testing.T) {
+	ctx := context.Background()
+	server, c := newTestRedisCache(t)
+
+	require.NoError(t, c.SetWithTags(ctx, "k", []byte("v1"), time.Minute, []string{"a", "b"}))
+	assert.Equal(t, []string{"k"}, members(t, server, "a"))
+	assert.Equal(t, []string{"k"}, members(t, server, "b"))
+
+	require.NoError(t, c.SetWithTags(ctx, "k", []byte("v2"), time.Minute, []string{"b", "c"}))
+	assert.Empty(t, members(t, server, "a"), "tags the key no longer has are untagged")
+	assert.Equal(t, []string{"k"}, members(t, server, "b"))
+	assert.Equal(t, []string{"k"}, members(t, server, "c"))
+
+	payload, tags, ttl, err := c.Get(ctx, "k")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("v2"), payload)
+	assert.Equal(t, []string{"b", "c"}, tags)
+	assert.Equal(t, time.Minute, ttl)
+
+	require.NoError(t, c.Delete(ctx, "k"))
+	assert.Empty(t, members(t, server, "b"))
+	assert.Empty(t, members(t, server, "c"))
+	_, _, _, err = c.Get(ctx, "k")
+	assert.Error(t, err)
+}
+
+func TestRedisTagSetsOutliveTheirKeys(t *testing.T) {
+	ctx := context.Background()
+	server, c := newTestRedisCache(t)
+
+	require.NoError(t, c.SetWithTags(ctx, "short", []byte("v"), time.Minute, []string{"t"}))
+	assert.Equal(t, time.Minute, server.TTL("civicrm:tag:t"))
+	require.NoError(t, c.SetWithTags(ctx, "long", []byte("v"), time.Hour, []string{"t"}))
+	assert.Equal(t, time.Hour, server.TTL("civicrm:tag:t"))
+	require.NoError(t, c.SetWithTags(ctx, "shorter", []byte("v"), time.Second, []string{"t"}))
+	assert.Equal(t, time.Hour, server.TTL("civicrm:tag:t"), "a shorter key does not shorten the set")
+	require.NoError(t, c.SetWithTags(ctx, "forever", []byte("v"), 0, []string{"t"}))
+	assert.Zero(t, server.TTL("civicrm:tag:t"))
+}
+
+func TestRedisRewriteSkipsMalformedEntries(t *testing.T) {
+	ctx := context.Background()
+	server, c := newTestRedisCache(t)
+
+	require.NoError(t, server.Set("civicrm:key:k", "\xff\xff\xff"))
+	require.NoError(t, c.SetWithTags(ctx, "k", []byte("v"), time.Minute, []string{"t"}))
+	payload, _, _, err := c.Get(ctx, "k")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("v"), payload)
+}
+
+func TestRedisInvalidateTag(t *testing.T) {
+	ctx := context.Background()
+	server, c := newTestRedisCache(t)
+
+	require.NoError(t, c.SetWithTags(ctx, "acl:1", []byte("allow"), time.Minute, []string{TagACL}))
+	require.NoError(t, c.SetWithTags(ctx, "acl:2", []byte("deny"), time.Minute, []string{TagACL, "contact:2"}))
+	require.NoError(t, c.SetWithTags(ctx, "setting", []byte("USD"), time.Minute, []string{TagSettings}))
+
+	require.NoError(t, c.InvalidateTag(ctx, TagACL))
+	assert.False(t, server.Exists("civicrm:key:acl:1"))
+	assert.False(t, server.Exists("civicrm:key:acl:2"))
+	assert.False(t, server.Exists("civicrm:tag:"+TagACL))
+	assert.True(t, server.Exists("civicrm:key:setting"))
+
+	require.NoError(t, c.InvalidateTag(ctx, "missing"))
+}
+
+func TestRedisClear(t *testing.T) {
+	ctx := context.Background()
+	server, c := newTestRedisCache(t)
+
+	for i := 0; i < 300; i++ {
+		require.NoError(t, c.SetWithTags(ctx, "k"+strconv.Itoa(i), []byte("v"), time.Minute, []string{"t"}))
+	}
+	require.NoError(t, server.Set("other:key", "kept"))
+
+	require.NoError(t, c.Clear(ctx))
+	assert.Equal(t, []string{"other:key"}, server.Keys(), "keys outside the prefix are left alone")
+
+	keys, err := c.Keys(ctx, "", 0)
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestManagerRedisInvalidation(t *testing.T) {
+	ctx := context.Background()
+	_, config := redisConfig(t)
+
+	newManager := func() *Manager {
+		manager, err := New(config, logger.NewNop())
+		require.NoError(t, err)
+		t.Cleanup(func() { manager.Close() })
+		return manager
+	}
+	writer, reader := newManager(), newManager()
+
+	require.NoError(t, writer.SetWithTags(ctx, "acl:1", "allow", time.Minute, TagACL))
+	value, err := reader.Get(ctx, "acl:1")
+	require.NoError(t, err)
+	assert.Equal(t, "allow", value)
+	_, tags, _, found := reader.memory.GetWithTags("acl:1")
+	require.True(t, found, "the reader keeps a copy in its memory tier")
+	assert.Equal(t, []string{TagACL}, tags)
+
+	require.NoError(t, writer.InvalidateTag(ctx, TagACL))
+	assert.Eventually(t, func() bool {
+		_, found := reader.memory.Get("acl:1")
+		return !found
+	}, time.Second, 10*time.Millisecond, "the reader drops its copy when told")
+	_, err = reader.Get(ctx, "acl:1")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, writer.Set(ctx, "setting", "USD", time.Minute))
+	_, err = reader.Get(ctx, "setting")
+	require.NoError(t, err)
+	require.NoError(t, writer.Clear(ctx))
+	assert.Eventually(t, func() bool {
+		_, found := reader.memory.Get("setting")
+		return !found
+	}, time.Second, 10*time.Millisecond)
+}
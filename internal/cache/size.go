@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"reflect"
+	"time"
+)
+
+const (
+	// maxSizeDepth bounds how many pointers, maps and slices estimateSize
+	// follows, so cyclic values are still sized
+	maxSizeDepth = 8
+	// sizeSamples is how many elements of a slice or map are measured; the
+	// rest are charged the average of those
+	sizeSamples = 16
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// estimateSize approximates the memory held by a value from its type and
+// the lengths of the strings, slices and maps it references. It walks the
+// value instead of encoding it, which is several times cheaper on every Set.
+func estimateSize(value interface{}) int64 {
+	if value == nil {
+		return 0
+	}
+	return estimate(reflect.ValueOf(value), maxSizeDepth)
+}
+
+// estimate returns the size of v itself plus what it references
+func estimate(v reflect.Value, depth int) int64 {
+	return int64(v.Type().Size()) + referenced(v, depth)
+}
+
+// referenced returns the bytes v references outside its own storage
+func referenced(v reflect.Value, depth int) int64 {
+	if depth == 0 {
+		return 0
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return int64(v.Len())
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		return estimate(v.Elem(), depth-1)
+	case reflect.Slice:
+		if v.IsNil() {
+			return 0
+		}
+		size := int64(v.Cap()) * int64(v.Type().Elem().Size())
+		if hasReferences(v.Type().Elem()) {
+			size += sampled(v, depth-1)
+		}
+		return size
+	case reflect.Array:
+		if !hasReferences(v.Type().Elem()) {
+			return 0
+		}
+		return sampled(v, depth-1)
+	case reflect.Map:
+		if v.IsNil() || v.Len() == 0 {
+			return 0
+		}
+		var iter reflect.MapIter
+		iter.Reset(v)
+		var size int64
+		measured := 0
+		for ; measured < sizeSamples && iter.Next(); measured++ {
+			size += estimate(iter.Key(), depth-1) + estimate(iter.Value(), depth-1)
+		}
+		return size * int64(v.Len()) / int64(measured)
+	case reflect.Struct:
+		// A time's location is shared, not held by the value
+		if v.Type() == timeType {
+			return 0
+		}
+		var size int64
+		for i := 0; i < v.NumField(); i++ {
+			size += referenced(v.Field(i), depth)
+		}
+		return size
+	}
+	return 0
+}
+
+// sampled adds up what the first sizeSamples elements of a slice or array
+// reference and scales the sum to all of its elements
+func sampled(v reflect.Value, depth int) int64 {
+	n := v.Len()
+	count := min(n, sizeSamples)
+	if count == 0 {
+		return 0
+	}
+	var size int64
+	for i := 0; i < count; i++ {
+		size += referenced(v.Index(i), depth)
+	}
+	return size * int64(n) / int64(count)
+}
+
+// hasReferences reports whether values of t can reference memory outside
+// their own storage
+func hasReferences(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map:
+		return true
+	case reflect.Array:
+		return hasReferences(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if hasReferences(t.Field(i).Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,167 @@
+package cache
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/jxlxx/civicrm/internal/metrics"
+)
+
+// Cache tiers, from fastest to slowest
+const (
+	TierLocal  = "local"
+	TierMemory = "memory"
+	TierRedis  = "redis"
+)
+
+// TierStats holds counters for one cache tier
+type TierStats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Entries   int    `json:"entries"`
+	Bytes     int64  `json:"bytes"`
+}
+
+// HitRatio returns the fraction of lookups that were hits
+func (s TierStats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// Stats is a snapshot of cache counters by tier and by key namespace
+type Stats struct {
+	Tiers      map[string]TierStats            `json:"tiers"`
+	Namespaces map[string]map[string]TierStats `json:"namespaces"`
+}
+
+// cacheMetrics counts hits, misses and evictions by tier and namespace
+type cacheMetrics struct {
+	hits      *metrics.CounterVec
+	misses    *metrics.CounterVec
+	evictions *metrics.CounterVec
+}
+
+func newCacheMetrics() *cacheMetrics {
+	return &cacheMetrics{
+		hits:      metrics.NewCounterVec("civicrm_cache_hits_total", "Cache lookups answered by a tier.", "tier", "namespace"),
+		misses:    metrics.NewCounterVec("civicrm_cache_misses_total", "Cache lookups a tier could not answer.", "tier", "namespace"),
+		evictions: metrics.NewCounterVec("civicrm_cache_evictions_total", "Entries dropped from a tier because of capacity or expiry.", "tier", "namespace", "reason"),
+	}
+}
+
+// namespace returns the part of a key before the first colon, which is how
+// keys are grouped for statistics
+func namespace(key string) string {
+	if i := strings.IndexByte(key, ':'); i > 0 {
+		return key[:i]
+	}
+	return "other"
+}
+
+func (c *cacheMetrics) hit(tier, key string) {
+	c.hits.Inc(tier, namespace(key))
+}
+
+func (c *cacheMetrics) miss(tier, key string) {
+	c.misses.Inc(tier, namespace(key))
+}
+
+// evicted returns an eviction callback for an in-process tier
+func (c *cacheMetrics) evicted(tier string) func(key, reason string) {
+	return func(key, reason string) {
+		c.evictions.Inc(tier, namespace(key), reason)
+	}
+}
+
+// Stats returns a snapshot of the cache counters
+func (m *Manager) Stats() Stats {
+	stats := Stats{
+		Tiers:      make(map[string]TierStats),
+		Namespaces: make(map[string]map[string]TierStats),
+	}
+
+	update := func(tier, ns string, fn func(*TierStats)) {
+		total := stats.Tiers[tier]
+		fn(&total)
+		stats.Tiers[tier] = total
+
+		if stats.Namespaces[ns] == nil {
+			stats.Namespaces[ns] = make(map[string]TierStats)
+		}
+		byTier := stats.Namespaces[ns][tier]
+		fn(&byTier)
+		stats.Namespaces[ns][tier] = byTier
+	}
+
+	m.metrics.hits.Each(func(labels []string, count uint64) {
+		update(labels[0], labels[1], func(s *TierStats) { s.Hits += count })
+	})
+	m.metrics.misses.Each(func(labels []string, count uint64) {
+		update(labels[0], labels[1], func(s *TierStats) { s.Misses += count })
+	})
+	m.metrics.evictions.Each(func(labels []string, count uint64) {
+		update(labels[0], labels[1], func(s *TierStats) { s.Evictions += count })
+	})
+
+	for tier, store := range map[string]*lru{TierLocal: m.local.store, TierMemory: m.memory.store} {
+		total := stats.Tiers[tier]
+		total.Entries, total.Bytes = store.size()
+		stats.Tiers[tier] = total
+	}
+
+	return stats
+}
+
+// Keys returns up to limit cached keys starting with prefix across all
+// tiers. A non-positive limit returns every matching key.
+func (m *Manager) Keys(ctx context.Context, prefix string, limit int) ([]string, error) {
+	seen := make(map[string]struct{})
+	add := func(keys []string) {
+		for _, key := range keys {
+			seen[key] = struct{}{}
+		}
+	}
+
+	add(m.local.Keys(prefix, limit))
+	add(m.memory.Keys(prefix, limit))
+
+	if m.redis != nil {
+		keys, err := m.redis.Keys(ctx, prefix, limit)
+		if err != nil {
+			return nil, err
+		}
+		add(keys)
+	}
+
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	if limit > 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+	return keys, nil
+}
+
+// Collect implements metrics.Collector so the cache can be exported with
+// the rest of the application metrics
+func (m *Manager) Collect() []metrics.Sample {
+	samples := append(m.metrics.hits.Collect(), m.metrics.misses.Collect()...)
+	samples = append(samples, m.metrics.evictions.Collect()...)
+
+	for tier, store := range map[string]*lru{TierLocal: m.local.store, TierMemory: m.memory.store} {
+		entries, bytes := store.size()
+		labels := map[string]string{"tier": tier}
+		samples = append(samples,
+			metrics.Sample{Name: "civicrm_cache_entries", Help: "Entries held by an in-process tier.", Type: metrics.GaugeType, Labels: labels, Value: float64(entries)},
+			metrics.Sample{Name: "civicrm_cache_bytes", Help: "Estimated bytes held by an in-process tier.", Type: metrics.GaugeType, Labels: labels, Value: float64(bytes)},
+		)
+	}
+
+	return samples
+}
@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jxlxx/civicrm/internal/config"
+	"github.com/jxlxx/civicrm/internal/logger"
+	"github.com/jxlxx/civicrm/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManagerStats(t *testing.T) {
+	ctx := context.Background()
+	manager := newTestManager(t)
+
+	require.NoError(t, manager.Set(ctx, "setting:currency", "USD", time.Minute))
+	_, err := manager.Get(ctx, "setting:currency")
+	require.NoError(t, err)
+	_, err = manager.Get(ctx, "acl:contact:1")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	// Served from local without reaching memory
+	manager.local.Delete("setting:currency")
+	_, err = manager.Get(ctx, "setting:currency")
+	require.NoError(t, err)
+
+	stats := manager.Stats()
+	assert.Equal(t, uint64(1), stats.Tiers[TierLocal].Hits)
+	assert.Equal(t, uint64(2), stats.Tiers[TierLocal].Misses)
+	assert.Equal(t, uint64(1), stats.Tiers[TierMemory].Hits)
+	assert.Equal(t, uint64(1), stats.Tiers[TierMemory].Misses)
+	assert.Equal(t, 1, stats.Tiers[TierMemory].Entries)
+
+	assert.Equal(t, uint64(1), stats.Namespaces["setting"][TierLocal].Hits)
+	assert.Equal(t, uint64(1), stats.Namespaces["acl"][TierMemory].Misses)
+	assert.InDelta(t, 1.0/3, stats.Tiers[TierLocal].HitRatio(), 0.001)
+}
+
+func TestManagerStatsEvictions(t *testing.T) {
+	ctx := context.Background()
+	manager, err := New(&config.CacheConfig{
+		Driver:     "memory",
+		TTL:        time.Minute,
+		MaxMemory:  10 * 1024 * 1024,
+		MaxEntries: 20,
+	}, logger.NewNop())
+	require.NoError(t, err)
+	defer manager.Close()
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, manager.Set(ctx, EntityTag("contact", i), i, time.Minute))
+	}
+
+	// The local tier holds two entries, so three were evicted for capacity
+	stats := manager.Stats()
+	assert.Equal(t, uint64(3), stats.Tiers[TierLocal].Evictions)
+	assert.Equal(t, uint64(3), stats.Namespaces["contact"][TierLocal].Evictions)
+	assert.Zero(t, stats.Tiers[TierMemory].Evictions)
+}
+
+func TestManagerKeys(t *testing.T) {
+	ctx := context.Background()
+	manager := newTestManager(t)
+
+	require.NoError(t, manager.Set(ctx, "acl:contact:2", true, time.Minute))
+	require.NoError(t, manager.Set(ctx, "acl:contact:1", true, time.Minute))
+	require.NoError(t, manager.Set(ctx, "setting:currency", "USD", time.Minute))
+
+	keys, err := manager.Keys(ctx, "acl:", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"acl:contact:1", "acl:contact:2"}, keys)
+
+	keys, err = manager.Keys(ctx, "", 1)
+	require.NoError(t, err)
+	assert.Len(t, keys, 1)
+}
+
+func TestManagerCollect(t *testing.T) {
+	ctx := context.Background()
+	manager := newTestManager(t)
+
+	require.NoError(t, manager.Set(ctx, "setting:currency", "USD", time.Minute))
+	_, err := manager.Get(ctx, "setting:currency")
+	require.NoError(t, err)
+
+	registry := metrics.NewRegistry()
+	registry.Register(manager)
+
+	var found bool
+	for _, sample := range registry.Gather() {
+		if sample.Name == "civicrm_cache_hits_total" && sample.Labels["tier"] == TierLocal {
+			assert.Equal(t, "setting", sample.Labels["namespace"])
+			assert.Equal(t, float64(1), sample.Value)
+			found = true
+		}
+	}
+	assert.True(t, found)
+}
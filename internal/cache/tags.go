@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+)
+
+// Common cache tags. Entries stored with a tag can be dropped together with
+// InvalidateTag when the rows they were built from change.
+const (
+	// TagACL marks permission checks. Relationship and group membership
+	// changes invalidate it.
+	TagACL      = "acl"
+	TagSettings = "settings"
+)
+
+// Invalidator drops cached entries by tag. Services that change the rows
+// behind tagged entries take one; *Manager implements it.
+type Invalidator interface {
+	InvalidateTag(ctx context.Context, tag string) error
+}
+
+// EntityTag returns the tag for a single entity row, e.g. "contact:123"
+func EntityTag(entity string, id interface{}) string {
+	return fmt.Sprintf("%s:%v", entity, id)
+}
+
+// tagIndex tracks which keys carry which tags in an in-process cache tier
+type tagIndex struct {
+	keys map[string]map[string]struct{}
+	tags map[string][]string
+}
+
+func newTagIndex() *tagIndex {
+	return &tagIndex{
+		keys: make(map[string]map[string]struct{}),
+		tags: make(map[string][]string),
+	}
+}
+
+// add records tags for key, replacing any tags it had before
+func (t *tagIndex) add(key string, tags []string) {
+	t.remove(key)
+	if len(tags) == 0 {
+		return
+	}
+
+	t.tags[key] = tags
+	for _, tag := range tags {
+		keys, exists := t.keys[tag]
+		if !exists {
+			keys = make(map[string]struct{})
+			t.keys[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+}
+
+// remove forgets key in every tag it belongs to
+func (t *tagIndex) remove(key string) {
+	for _, tag := range t.tags[key] {
+		if keys, exists := t.keys[tag]; exists {
+			delete(keys, key)
+			if len(keys) == 0 {
+				delete(t.keys, tag)
+			}
+		}
+	}
+	delete(t.tags, key)
+}
+
+// take returns the keys carrying tag and removes them from the index
+func (t *tagIndex) take(tag string) []string {
+	keys := make([]string, 0, len(t.keys[tag]))
+	for key := range t.keys[tag] {
+		keys = append(keys, key)
+	}
+	for _, key := range keys {
+		t.remove(key)
+	}
+	return keys
+}
+
+// clear drops every tag
+func (t *tagIndex) clear() {
+	t.keys = make(map[string]map[string]struct{})
+	t.tags = make(map[string][]string)
+}
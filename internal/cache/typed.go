@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Get retrieves a value of type T from cache. Values held in memory are
+// returned as is; values read from Redis are decoded into T with the
+// manager's codec.
+func Get[T any](ctx context.Context, m *Manager, key string) (T, error) {
+	var zero T
+
+	accept := func(val interface{}) bool {
+		_, ok := val.(T)
+		return ok
+	}
+	decode := func(payload []byte) (interface{}, error) {
+		var val T
+		err := m.codec.Unmarshal(payload, &val)
+		return val, err
+	}
+
+	val, err := m.lookup(ctx, key, accept, decode)
+	if err != nil {
+		return zero, err
+	}
+	return val.(T), nil
+}
+
+// GetOrLoad returns the cached value for key, or calls loader and caches its
+// result with the given ttl and tags. Concurrent misses for the same key on
+// this instance share a single loader call, which runs without the first
+// caller's cancellation so that one cancelled request does not fail the
+// others. Loader errors are returned to every waiting caller and nothing is
+// cached.
+func GetOrLoad[T any](ctx context.Context, m *Manager, key string, ttl time.Duration, loader func(ctx context.Context) (T, error), tags ...string) (T, error) {
+	if val, err := Get[T](ctx, m, key); err == nil {
+		return val, nil
+	}
+
+	loadCtx := context.WithoutCancel(ctx)
+	result := m.loads.DoChan(key, func() (interface{}, error) {
+		// Another caller may have filled the cache while we waited
+		if val, err := Get[T](loadCtx, m, key); err == nil {
+			return val, nil
+		}
+
+		val, err := loader(loadCtx)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := m.SetWithTags(loadCtx, key, val, ttl, tags...); err != nil && m.logger != nil {
+			m.logger.Warn("Failed to cache loaded value", "key", key, "error", err)
+		}
+		return val, nil
+	})
+
+	var zero T
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case res := <-result:
+		if res.Err != nil {
+			return zero, res.Err
+		}
+		val, ok := res.Val.(T)
+		if !ok {
+			return zero, fmt.Errorf("cache: loaded value for %s has type %T", key, res.Val)
+		}
+		return val, nil
+	}
+}
@@ -17,6 +17,11 @@ type Config struct {
 	API        APIConfig        `mapstructure:"api"`
 	Logging    LoggingConfig    `mapstructure:"logging"`
 	Extensions ExtensionsConfig `mapstructure:"extensions"`
+	Jobs       JobsConfig       `mapstructure:"jobs"`
+	Groups     GroupsConfig     `mapstructure:"groups"`
+	Geocoding  GeocodingConfig  `mapstructure:"geocoding"`
+	Tracing    TracingConfig    `mapstructure:"tracing"`
+	Mail       MailConfig       `mapstructure:"mail"`
 }
 
 // DatabaseConfig holds database connection settings
@@ -30,6 +35,25 @@ type DatabaseConfig struct {
 	SSLMode  string        `mapstructure:"ssl_mode"`
 	MaxConns int           `mapstructure:"max_connections"`
 	Timeout  time.Duration `mapstructure:"timeout"`
+
+	// Queries running at least this long are logged; zero disables logging
+	SlowQueryThreshold time.Duration `mapstructure:"slow_query_threshold"`
+
+	// Read replicas used by ReadQuery and ReadQuerier
+	Replicas             []ReplicaConfig `mapstructure:"replicas"`
+	ReplicaCheckInterval time.Duration   `mapstructure:"replica_check_interval"`
+	ReadYourWrites       bool            `mapstructure:"read_your_writes"`
+}
+
+// ReplicaConfig holds connection settings for a read replica. Empty fields
+// fall back to the primary's settings.
+type ReplicaConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	Database string `mapstructure:"database"`
+	SSLMode  string `mapstructure:"ssl_mode"`
 }
 
 // CacheConfig holds cache configuration
@@ -42,6 +66,8 @@ type CacheConfig struct {
 	TTL        time.Duration `mapstructure:"ttl"`
 	MaxMemory  int64         `mapstructure:"max_memory"`
 	MaxEntries int           `mapstructure:"max_entries"`
+	KeyPrefix  string        `mapstructure:"key_prefix"`
+	Codec      string        `mapstructure:"codec"`
 }
 
 // SecurityConfig holds security settings
@@ -62,6 +88,17 @@ type APIConfig struct {
 	WriteTimeout time.Duration `mapstructure:"write_timeout"`
 	IdleTimeout  time.Duration `mapstructure:"idle_timeout"`
 	CORS         CORSConfig    `mapstructure:"cors"`
+	Tenancy      TenancyConfig `mapstructure:"tenancy"`
+	// DuplicateScanInterval is the least time between the duplicate scans
+	// of one user, which read every contact of a type
+	DuplicateScanInterval time.Duration `mapstructure:"duplicate_scan_interval"`
+}
+
+// TenancyConfig controls how API requests are mapped to domains
+type TenancyConfig struct {
+	Enabled       bool              `mapstructure:"enabled"`
+	Hosts         map[string]string `mapstructure:"hosts"`
+	DefaultDomain string            `mapstructure:"default_domain"`
 }
 
 // CORSConfig holds CORS settings
@@ -92,6 +129,63 @@ type ExtensionsConfig struct {
 	Development bool     `mapstructure:"development"`
 }
 
+// TracingConfig holds tracing settings
+type TracingConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// SampleRatio is the share of traces recorded, from 0 to 1
+	SampleRatio float64 `mapstructure:"sample_ratio"`
+}
+
+// JobsConfig holds scheduled job settings
+type JobsConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"`
+}
+
+// GroupsConfig holds contact group settings
+type GroupsConfig struct {
+	// CacheTTL is how long a smart group's cached members are used before
+	// its saved search is run again
+	CacheTTL time.Duration `mapstructure:"cache_ttl"`
+	// ConfirmSecret signs the tokens contacts use to confirm a pending
+	// subscription. The JWT secret is used when it is empty.
+	ConfirmSecret string `mapstructure:"confirm_secret"`
+	// ConfirmTTL is how long a confirmation token is valid
+	ConfirmTTL time.Duration `mapstructure:"confirm_ttl"`
+	// ConfirmURL is the page contacts open to confirm a subscription; the
+	// token is added to it as the token query parameter
+	ConfirmURL string `mapstructure:"confirm_url"`
+}
+
+// MailConfig holds outgoing email settings. Email is not sent when Host is
+// empty.
+type MailConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password"`
+	From     string `mapstructure:"from"`
+}
+
+// GeocodingConfig holds address geocoding settings
+type GeocodingConfig struct {
+	// Providers are tried in order until one locates an address:
+	// "postcode" for the offline postal code centroids and "nominatim" for a
+	// Nominatim-compatible service. Geocoding is off when empty.
+	Providers []string `mapstructure:"providers"`
+	// PostalCodesFile is a GeoNames postal code file, or the zip it is
+	// downloaded as, used instead of the small bundled sample
+	PostalCodesFile string        `mapstructure:"postal_codes_file"`
+	NominatimURL    string        `mapstructure:"nominatim_url"`
+	UserAgent       string        `mapstructure:"user_agent"`
+	Email           string        `mapstructure:"email"`
+	Timeout         time.Duration `mapstructure:"timeout"`
+	// RequestInterval is the least time between Nominatim requests
+	RequestInterval time.Duration `mapstructure:"request_interval"`
+	// BatchSize is how many addresses a geocode job run looks up
+	BatchSize int `mapstructure:"batch_size"`
+}
+
 // Load reads configuration from environment variables and config files
 func Load() (*Config, error) {
 	config := &Config{}
@@ -108,6 +202,10 @@ func Load() (*Config, error) {
 		fmt.Printf("Warning: Could not load config file: %v\n", err)
 	}
 
+	if config.Groups.ConfirmSecret == "" {
+		config.Groups.ConfirmSecret = config.Security.JWTSecret
+	}
+
 	// Validate configuration
 	if err := validate(config); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
@@ -125,6 +223,9 @@ func setDefaults(config *Config) {
 		MaxConns: 10,
 		Timeout:  30 * time.Second,
 		SSLMode:  "disable",
+
+		SlowQueryThreshold:   500 * time.Millisecond,
+		ReplicaCheckInterval: 10 * time.Second,
 	}
 
 	config.Cache = CacheConfig{
@@ -132,6 +233,8 @@ func setDefaults(config *Config) {
 		TTL:        1 * time.Hour,
 		MaxMemory:  100 * 1024 * 1024, // 100MB
 		MaxEntries: 10000,
+		KeyPrefix:  "civicrm",
+		Codec:      "json",
 	}
 
 	config.Security = SecurityConfig{
@@ -155,6 +258,7 @@ func setDefaults(config *Config) {
 			AllowCredentials: true,
 			MaxAge:           86400,
 		},
+		DuplicateScanInterval: 30 * time.Second,
 	}
 
 	config.Logging = LoggingConfig{
@@ -172,6 +276,34 @@ func setDefaults(config *Config) {
 		AutoLoad:    true,
 		Development: false,
 	}
+
+	config.Tracing = TracingConfig{
+		Enabled:     false,
+		SampleRatio: 0.1,
+	}
+
+	config.Jobs = JobsConfig{
+		Enabled:  true,
+		Interval: time.Minute,
+	}
+
+	config.Groups = GroupsConfig{
+		CacheTTL:   5 * time.Minute,
+		ConfirmTTL: 7 * 24 * time.Hour,
+	}
+
+	config.Geocoding = GeocodingConfig{
+		Providers:       []string{"postcode"},
+		NominatimURL:    "https://nominatim.openstreetmap.org",
+		UserAgent:       "civicrm-go",
+		Timeout:         10 * time.Second,
+		RequestInterval: time.Second,
+		BatchSize:       100,
+	}
+
+	config.Mail = MailConfig{
+		Port: 587,
+	}
 }
 
 // loadFromEnv loads configuration from environment variables
@@ -213,6 +345,11 @@ func loadFromEnv(config *Config) {
 		config.Security.JWTSecret = val
 	}
 
+	// Mail
+	if val := os.Getenv("SMTP_PASSWORD"); val != "" {
+		config.Mail.Password = val
+	}
+
 	// API
 	if val := os.Getenv("API_PORT"); val != "" {
 		if port, err := strconv.Atoi(val); err == nil {
@@ -270,5 +407,28 @@ func validate(config *Config) error {
 	if config.Security.JWTSecret == "" {
 		return fmt.Errorf("JWT secret is required")
 	}
+	if config.Tracing.SampleRatio < 0 || config.Tracing.SampleRatio > 1 {
+		return fmt.Errorf("tracing sample ratio must be between 0 and 1")
+	}
+	if config.Jobs.Enabled && config.Jobs.Interval <= 0 {
+		return fmt.Errorf("jobs interval must be positive")
+	}
+	if config.Groups.CacheTTL <= 0 {
+		return fmt.Errorf("groups cache TTL must be positive")
+	}
+	if config.Groups.ConfirmTTL <= 0 {
+		return fmt.Errorf("groups confirm TTL must be positive")
+	}
+	for _, provider := range config.Geocoding.Providers {
+		if provider != "postcode" && provider != "nominatim" {
+			return fmt.Errorf("unknown geocoding provider %q", provider)
+		}
+		if provider == "nominatim" && config.Geocoding.NominatimURL == "" {
+			return fmt.Errorf("geocoding nominatim URL is required")
+		}
+	}
+	if config.Geocoding.BatchSize <= 0 {
+		return fmt.Errorf("geocoding batch size must be positive")
+	}
 	return nil
 }
@@ -0,0 +1,116 @@
+// Package contacts implements contact operations that span several tables,
+// such as merging duplicates, and computes contact names.
+package contacts
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/database"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/jxlxx/civicrm/internal/logger"
+)
+
+// earthRadius is the mean radius of the earth in km
+const earthRadius = 6371.0088
+
+// maxRedirects bounds the merge redirects followed when loading a contact.
+// Merges repoint older redirects, so one is normally enough.
+const maxRedirects = 10
+
+var (
+	// ErrNotFound is returned when a contact does not exist
+	ErrNotFound = errors.New("contact not found")
+
+	// ErrCannotMerge is returned when two contacts cannot be merged
+	ErrCannotMerge = errors.New("contacts cannot be merged")
+)
+
+// Service manages contacts
+type Service struct {
+	queries  db.Querier
+	tx       database.Transactor
+	settings SettingsReader
+	logger   *logger.Logger
+}
+
+// New creates a contact service. Reads go to replicas when configured, and
+// name formats come from settings.
+func New(database *database.Database, settings SettingsReader, logger *logger.Logger) *Service {
+	return &Service{
+		queries:  database.ReadQuerier(),
+		tx:       database,
+		settings: settings,
+		logger:   logger,
+	}
+}
+
+// Get returns a contact by ID with its primary location records. A contact
+// deleted by a merge redirects to the contact it was merged into.
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (*Contact, error) {
+	contact, err := followMerges(ctx, s.queries, id)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.WithPrimaries(ctx, []db.Contact{contact})
+	if err != nil {
+		return nil, err
+	}
+	return &result[0], nil
+}
+
+// Near returns up to limit contacts whose primary address lies within km of
+// a point, nearest first, with their primary location records. Addresses
+// that have not been geocoded are never found.
+func (s *Service) Near(ctx context.Context, lat, lon, km float64, limit int) ([]Contact, error) {
+	spread := km / earthRadius * 180 / math.Pi
+	rows, err := s.queries.ListContactsNear(ctx, db.ListContactsNearParams{
+		Lat:      lat,
+		Lon:      lon,
+		MinLat:   lat - spread,
+		MaxLat:   lat + spread,
+		RadiusKm: km,
+		RowLimit: int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list contacts near point: %w", err)
+	}
+	return s.WithPrimaries(ctx, rows)
+}
+
+// Update updates a contact's type, subtypes and names, and recomputes its
+// display and sort names
+func (s *Service) Update(ctx context.Context, params db.UpdateContactParams) (*Contact, error) {
+	var updated *Contact
+	err := s.tx.WithTx(ctx, func(q db.Querier) error {
+		if err := validateSubTypes(ctx, q, params.ContactType, params.ContactSubType); err != nil {
+			return err
+		}
+
+		row, err := q.UpdateContact(ctx, params)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update contact: %w", err)
+		}
+		if row, err = s.updateNames(ctx, q, row); err != nil {
+			return err
+		}
+
+		result, err := withPrimaries(ctx, q, []db.Contact{row})
+		if err != nil {
+			return err
+		}
+		updated = &result[0]
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
@@ -0,0 +1,32 @@
+package contacts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/database/dbtest"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNear(t *testing.T) {
+	ada := db.Contact{ID: uuid.New(), ContactType: "Individual", FirstName: dbtest.Text("Ada")}
+	service, q, _ := newFixture(t, ada)
+	q.emails = []db.Email{{ContactID: ada.ID, Email: "ada@example.org", IsPrimary: dbtest.Bool(true)}}
+
+	found, err := service.Near(context.Background(), 51.5, -0.12, 10, 25)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, ada.ID, found[0].ID)
+	require.NotNil(t, found[0].PrimaryEmail)
+
+	assert.Equal(t, 51.5, q.near.Lat)
+	assert.Equal(t, -0.12, q.near.Lon)
+	assert.Equal(t, 10.0, q.near.RadiusKm)
+	assert.Equal(t, int32(25), q.near.RowLimit)
+	// 10 km is about 0.09 degrees of latitude
+	assert.InDelta(t, 51.41, q.near.MinLat, 0.001)
+	assert.InDelta(t, 51.59, q.near.MaxLat, 0.001)
+}
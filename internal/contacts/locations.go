@@ -0,0 +1,169 @@
+package contacts
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/addresses"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+)
+
+// defaultLocationType is the location type of records created with a contact
+const defaultLocationType = "Home"
+
+// ErrInvalidAddress is returned when an address names an unknown state or
+// country, a state of another country or a malformed postal code
+var ErrInvalidAddress = addresses.ErrInvalidAddress
+
+// Contact is a contact with its primary email, phone and address. The
+// location tables hold every record; these are computed from them.
+type Contact struct {
+	db.Contact
+	PrimaryEmail   *db.Email   `json:"primary_email"`
+	PrimaryPhone   *db.Phone   `json:"primary_phone"`
+	PrimaryAddress *db.Address `json:"primary_address"`
+}
+
+// NewContact is a contact to create with its primary location records
+type NewContact struct {
+	db.CreateContactParams
+	Email   string
+	Phone   string
+	Address *NewAddress
+}
+
+// NewAddress is an address to create
+type NewAddress = addresses.Fields
+
+// Create creates a contact with its primary email, phone and address in one
+// transaction, and computes its display and sort names
+func (s *Service) Create(ctx context.Context, contact NewContact) (*Contact, error) {
+	var created *Contact
+	err := s.tx.WithTx(ctx, func(q db.Querier) error {
+		if err := validateSubTypes(ctx, q, contact.ContactType, contact.ContactSubType); err != nil {
+			return err
+		}
+
+		var address db.CreateAddressParams
+		if contact.Address != nil {
+			var err error
+			if address, err = addresses.Resolve(ctx, q, *contact.Address); err != nil {
+				return err
+			}
+		}
+
+		row, err := q.CreateContact(ctx, contact.CreateContactParams)
+		if err != nil {
+			return fmt.Errorf("failed to create contact: %w", err)
+		}
+		if row, err = s.updateNames(ctx, q, row); err != nil {
+			return err
+		}
+		created = &Contact{Contact: row}
+
+		locationType, err := q.GetLocationTypeByName(ctx, defaultLocationType)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to get location type: %w", err)
+		}
+		locationTypeID := uuid.NullUUID{UUID: locationType.ID, Valid: err == nil}
+		primary := sql.NullBool{Bool: true, Valid: true}
+
+		if email := strings.TrimSpace(contact.Email); email != "" {
+			record, err := q.CreateEmail(ctx, db.CreateEmailParams{
+				ContactID:      row.ID,
+				LocationTypeID: locationTypeID,
+				IsPrimary:      primary,
+				Email:          email,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create email: %w", err)
+			}
+			created.PrimaryEmail = &record
+		}
+
+		if phone := strings.TrimSpace(contact.Phone); phone != "" {
+			record, err := q.CreatePhone(ctx, db.CreatePhoneParams{
+				ContactID:      row.ID,
+				LocationTypeID: locationTypeID,
+				PhoneType:      sql.NullString{String: "Phone", Valid: true},
+				IsPrimary:      primary,
+				Phone:          phone,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create phone: %w", err)
+			}
+			created.PrimaryPhone = &record
+		}
+
+		if contact.Address != nil {
+			address.ContactID = row.ID
+			address.LocationTypeID = locationTypeID
+			address.IsPrimary = primary
+			record, err := q.CreateAddress(ctx, address)
+			if err != nil {
+				return fmt.Errorf("failed to create address: %w", err)
+			}
+			created.PrimaryAddress = &record
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// WithPrimaries adds the primary location records to contacts
+func (s *Service) WithPrimaries(ctx context.Context, contacts []db.Contact) ([]Contact, error) {
+	return withPrimaries(ctx, s.queries, contacts)
+}
+
+func withPrimaries(ctx context.Context, q db.Querier, contacts []db.Contact) ([]Contact, error) {
+	result := make([]Contact, len(contacts))
+	if len(contacts) == 0 {
+		return result, nil
+	}
+
+	ids := make([]uuid.UUID, len(contacts))
+	byID := make(map[uuid.UUID]*Contact, len(contacts))
+	for i, contact := range contacts {
+		ids[i] = contact.ID
+		result[i] = Contact{Contact: contact}
+		byID[contact.ID] = &result[i]
+	}
+
+	emails, err := q.ListPrimaryEmails(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list primary emails: %w", err)
+	}
+	for i := range emails {
+		byID[emails[i].ContactID].PrimaryEmail = &emails[i]
+	}
+
+	phones, err := q.ListPrimaryPhones(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list primary phones: %w", err)
+	}
+	for i := range phones {
+		byID[phones[i].ContactID].PrimaryPhone = &phones[i]
+	}
+
+	addresses, err := q.ListPrimaryAddresses(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list primary addresses: %w", err)
+	}
+	for i := range addresses {
+		byID[addresses[i].ContactID].PrimaryAddress = &addresses[i]
+	}
+	return result, nil
+}
+
+// nullString converts a blank string to NULL
+func nullString(s string) sql.NullString {
+	s = strings.TrimSpace(s)
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,356 @@
+package contacts
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+)
+
+// Field choices for a merge. Left keeps the value of the contact that is
+// kept, right takes the value of the duplicate. For "email", "phone" and
+// "address" the choice decides whose primary record stays primary; the
+// other contact's records are moved either way.
+const (
+	KeepLeft  = "left"
+	KeepRight = "right"
+)
+
+// MergeOptions controls a merge
+type MergeOptions struct {
+	// Fields maps contact fields to KeepLeft or KeepRight. A field that is
+	// not listed keeps the kept contact's value, or takes the duplicate's
+	// when the kept contact has none.
+	Fields map[string]string
+
+	// MergedBy is the user performing the merge, if known
+	MergedBy uuid.NullUUID
+}
+
+// MergeResult is the outcome of a merge
+type MergeResult struct {
+	Contact *Contact         `json:"contact"`
+	Merge   db.ContactMerge  `json:"merge"`
+	Moved   map[string]int64 `json:"moved"`
+}
+
+// mergeField is a contact field that merge choices apply to
+type mergeField struct {
+	name  string
+	value func(c *db.Contact) *sql.NullString
+}
+
+var mergeFields = []mergeField{
+	{"prefix", func(c *db.Contact) *sql.NullString { return &c.Prefix }},
+	{"first_name", func(c *db.Contact) *sql.NullString { return &c.FirstName }},
+	{"last_name", func(c *db.Contact) *sql.NullString { return &c.LastName }},
+	{"suffix", func(c *db.Contact) *sql.NullString { return &c.Suffix }},
+	{"nick_name", func(c *db.Contact) *sql.NullString { return &c.NickName }},
+	{"organization_name", func(c *db.Contact) *sql.NullString { return &c.OrganizationName }},
+	{"household_name", func(c *db.Contact) *sql.NullString { return &c.HouseholdName }},
+}
+
+// mergeLocations are the location kinds merge choices apply to. Choosing
+// right makes the duplicate's primary record the primary one.
+var mergeLocations = []struct {
+	name    string
+	primary func(c *Contact) (uuid.UUID, bool)
+	set     func(q db.Querier, ctx context.Context, id uuid.UUID) error
+}{
+	{"email", func(c *Contact) (uuid.UUID, bool) {
+		if c.PrimaryEmail == nil {
+			return uuid.Nil, false
+		}
+		return c.PrimaryEmail.ID, true
+	}, db.Querier.SetPrimaryEmail},
+	{"phone", func(c *Contact) (uuid.UUID, bool) {
+		if c.PrimaryPhone == nil {
+			return uuid.Nil, false
+		}
+		return c.PrimaryPhone.ID, true
+	}, db.Querier.SetPrimaryPhone},
+	{"address", func(c *Contact) (uuid.UUID, bool) {
+		if c.PrimaryAddress == nil {
+			return uuid.Nil, false
+		}
+		return c.PrimaryAddress.ID, true
+	}, db.Querier.SetPrimaryAddress},
+}
+
+// mergeIDs are the parameters of every move query
+type mergeIDs struct {
+	MainID  uuid.UUID
+	OtherID uuid.UUID
+}
+
+// mover moves one kind of related record and returns the rows moved
+type mover func(ctx context.Context, q db.Querier, ids mergeIDs) (int64, error)
+
+// move adapts a generated move query. Every move query takes the same
+// parameters, so its params struct converts from mergeIDs.
+func move[P ~struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}](query func(db.Querier, context.Context, P) (int64, error)) mover {
+	return func(ctx context.Context, q db.Querier, ids mergeIDs) (int64, error) {
+		return query(q, ctx, P(ids))
+	}
+}
+
+// moves lists the records moved to the kept contact, by table. Records the
+// kept contact already has, such as a membership of the same group, stay with
+// the duplicate.
+var moves = []struct {
+	table string
+	move  mover
+}{
+	{"emails", move(db.Querier.MoveMergedEmails)},
+	{"phones", move(db.Querier.MoveMergedPhones)},
+	{"addresses", move(db.Querier.MoveMergedAddresses)},
+	{"contact_location_backup", move(db.Querier.MoveMergedLocationBackup)},
+	{"websites", move(db.Querier.MoveMergedWebsites)},
+	{"ims", move(db.Querier.MoveMergedIMs)},
+	{"relationships", move(db.Querier.MoveMergedRelationshipsA)},
+	{"relationships", move(db.Querier.MoveMergedRelationshipsB)},
+	{"group_contacts", move(db.Querier.MoveMergedGroupContacts)},
+	{"subscription_history", move(db.Querier.MoveMergedSubscriptionHistory)},
+	{"activity_contacts", move(db.Querier.MoveMergedActivityContacts)},
+	{"activity_assignments", move(db.Querier.MoveMergedActivityAssignments)},
+	{"contributions", move(db.Querier.MoveMergedContributions)},
+	{"memberships", move(db.Querier.MoveMergedMemberships)},
+	{"participants", move(db.Querier.MoveMergedParticipants)},
+	{"participants", move(db.Querier.MoveMergedParticipantRegistrations)},
+	{"event_registrations", move(db.Querier.MoveMergedEventRegistrations)},
+	{"case_contacts", move(db.Querier.MoveMergedCaseContacts)},
+	{"pledges", move(db.Querier.MoveMergedPledges)},
+	{"pledge_group_members", move(db.Querier.MoveMergedPledgeGroupMembers)},
+	{"entity_tags", move(db.Querier.MoveMergedEntityTags)},
+	{"custom_values", move(db.Querier.MoveMergedCustomValues)},
+	{"mailing_list_subscriptions", move(db.Querier.MoveMergedMailingSubscriptions)},
+	{"mailing_recipients", move(db.Querier.MoveMergedMailingRecipients)},
+	{"mailing_opens", move(db.Querier.MoveMergedMailingOpens)},
+	{"mailing_url_clicks", move(db.Querier.MoveMergedMailingClicks)},
+	{"sms_messages", move(db.Querier.MoveMergedSMSMessages)},
+	{"communication_preferences", move(db.Querier.MoveMergedCommunicationPreferences)},
+	{"survey_responses", move(db.Querier.MoveMergedSurveyResponses)},
+	{"campaign_contacts", move(db.Querier.MoveMergedCampaignContacts)},
+}
+
+// Merge merges the duplicate otherID into mainID in one transaction. The
+// duplicate's related records move to the kept contact, contact fields are
+// chosen by opts.Fields, and the duplicate is soft deleted with a redirect
+// to the kept contact. A merge record keeps the duplicate's previous fields.
+func (s *Service) Merge(ctx context.Context, mainID, otherID uuid.UUID, opts MergeOptions) (*MergeResult, error) {
+	if mainID == otherID {
+		return nil, fmt.Errorf("%w: a contact cannot be merged into itself", ErrCannotMerge)
+	}
+	for name, choice := range opts.Fields {
+		if !isMergeField(name) {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrCannotMerge, name)
+		}
+		if choice != KeepLeft && choice != KeepRight {
+			return nil, fmt.Errorf("%w: %s must be %q or %q", ErrCannotMerge, name, KeepLeft, KeepRight)
+		}
+	}
+
+	var result *MergeResult
+	err := s.tx.WithTx(ctx, func(q db.Querier) error {
+		main, other, err := lockPair(ctx, q, mainID, otherID)
+		if err != nil {
+			return err
+		}
+
+		merged := chooseFields(main, other, opts.Fields)
+		ids := mergeIDs{MainID: mainID, OtherID: otherID}
+
+		otherPrimaries, err := withPrimaries(ctx, q, []db.Contact{other})
+		if err != nil {
+			return err
+		}
+
+		if err := q.MarkContactMerged(ctx, db.MarkContactMergedParams(ids)); err != nil {
+			return fmt.Errorf("failed to mark contact merged: %w", err)
+		}
+		if _, err := q.RedirectMergedContacts(ctx, db.RedirectMergedContactsParams(ids)); err != nil {
+			return fmt.Errorf("failed to redirect merged contacts: %w", err)
+		}
+
+		contact, err := q.UpdateMergedContact(ctx, db.UpdateMergedContactParams{
+			ID:               mainID,
+			ContactSubType:   merged.ContactSubType,
+			Prefix:           merged.Prefix,
+			FirstName:        merged.FirstName,
+			LastName:         merged.LastName,
+			Suffix:           merged.Suffix,
+			NickName:         merged.NickName,
+			OrganizationName: merged.OrganizationName,
+			HouseholdName:    merged.HouseholdName,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update contact: %w", err)
+		}
+		if contact, err = s.updateNames(ctx, q, contact); err != nil {
+			return err
+		}
+
+		// Opt outs are combined before the kept contact's preferences decide
+		// whether the duplicate's are moved
+		if _, err := q.MergeCommunicationPreferences(ctx, db.MergeCommunicationPreferencesParams(ids)); err != nil {
+			return fmt.Errorf("failed to merge communication preferences: %w", err)
+		}
+
+		moved := make(map[string]int64)
+		for _, m := range moves {
+			n, err := m.move(ctx, q, ids)
+			if err != nil {
+				return fmt.Errorf("failed to move %s: %w", m.table, err)
+			}
+			moved[m.table] += n
+		}
+
+		for _, location := range mergeLocations {
+			id, ok := location.primary(&otherPrimaries[0])
+			if !ok || opts.Fields[location.name] != KeepRight {
+				continue
+			}
+			if err := location.set(q, ctx, id); err != nil {
+				return fmt.Errorf("failed to set primary %s: %w", location.name, err)
+			}
+		}
+
+		if _, err := q.DeleteSelfRelationships(ctx, mainID); err != nil {
+			return fmt.Errorf("failed to delete relationships between merged contacts: %w", err)
+		}
+		if _, err := q.DeleteMergedACLCache(ctx, otherID); err != nil {
+			return fmt.Errorf("failed to clear ACL cache: %w", err)
+		}
+
+		record, err := mergeRecord(mainID, other, opts, moved)
+		if err != nil {
+			return err
+		}
+		merge, err := q.CreateContactMerge(ctx, record)
+		if err != nil {
+			return fmt.Errorf("failed to create merge record: %w", err)
+		}
+
+		withLocations, err := withPrimaries(ctx, q, []db.Contact{contact})
+		if err != nil {
+			return err
+		}
+		result = &MergeResult{Contact: &withLocations[0], Merge: merge, Moved: moved}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Merged contacts", "main_id", mainID, "other_id", otherID, "merge_id", result.Merge.ID)
+	return result, nil
+}
+
+// lockPair locks both contacts for the merge and checks they can be merged
+func lockPair(ctx context.Context, q db.Querier, mainID, otherID uuid.UUID) (main, other db.Contact, err error) {
+	locked, err := q.LockContactsForMerge(ctx, db.LockContactsForMergeParams{MainID: mainID, OtherID: otherID})
+	if err != nil {
+		return main, other, fmt.Errorf("failed to lock contacts: %w", err)
+	}
+
+	var foundMain, foundOther bool
+	for _, contact := range locked {
+		switch contact.ID {
+		case mainID:
+			main, foundMain = contact, true
+		case otherID:
+			other, foundOther = contact, true
+		}
+	}
+
+	switch {
+	case !foundMain || !foundOther:
+		return main, other, ErrNotFound
+	case main.IsDeleted || other.IsDeleted:
+		return main, other, fmt.Errorf("%w: deleted contacts cannot be merged", ErrCannotMerge)
+	case main.ContactType != other.ContactType:
+		return main, other, fmt.Errorf("%w: a %s cannot be merged into a %s", ErrCannotMerge, other.ContactType, main.ContactType)
+	case main.DomainID != other.DomainID:
+		return main, other, fmt.Errorf("%w: contacts belong to different domains", ErrCannotMerge)
+	}
+	return main, other, nil
+}
+
+// chooseFields returns the kept contact with the fields chosen from the
+// duplicate
+func chooseFields(main, other db.Contact, choices map[string]string) db.Contact {
+	merged := main
+	for _, field := range mergeFields {
+		value, otherValue := field.value(&merged), field.value(&other)
+		switch choices[field.name] {
+		case KeepLeft:
+		case KeepRight:
+			*value = *otherValue
+		default:
+			if !value.Valid || value.String == "" {
+				*value = *otherValue
+			}
+		}
+	}
+
+	// The kept contact has the subtypes of both
+	merged.ContactSubType = append([]string(nil), main.ContactSubType...)
+	for _, subType := range other.ContactSubType {
+		if !slices.Contains(merged.ContactSubType, subType) {
+			merged.ContactSubType = append(merged.ContactSubType, subType)
+		}
+	}
+	return merged
+}
+
+// mergeRecord builds the merge record for the duplicate
+func mergeRecord(mainID uuid.UUID, other db.Contact, opts MergeOptions, moved map[string]int64) (db.CreateContactMergeParams, error) {
+	choices := opts.Fields
+	if choices == nil {
+		choices = map[string]string{}
+	}
+	fieldChoices, err := json.Marshal(choices)
+	if err != nil {
+		return db.CreateContactMergeParams{}, fmt.Errorf("failed to encode field choices: %w", err)
+	}
+	movedJSON, err := json.Marshal(moved)
+	if err != nil {
+		return db.CreateContactMergeParams{}, fmt.Errorf("failed to encode moved counts: %w", err)
+	}
+	snapshot, err := json.Marshal(other)
+	if err != nil {
+		return db.CreateContactMergeParams{}, fmt.Errorf("failed to encode contact: %w", err)
+	}
+
+	return db.CreateContactMergeParams{
+		MainContactID:  mainID,
+		OtherContactID: other.ID,
+		FieldChoices:   fieldChoices,
+		Moved:          movedJSON,
+		OtherSnapshot:  snapshot,
+		MergedBy:       opts.MergedBy,
+	}, nil
+}
+
+// isMergeField reports whether name is a contact field or location kind
+// merge choices apply to
+func isMergeField(name string) bool {
+	for _, field := range mergeFields {
+		if field.name == name {
+			return true
+		}
+	}
+	for _, location := range mergeLocations {
+		if location.name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,246 @@
+package contacts
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/database/dbtest"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/jxlxx/civicrm/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingDBTX records statements and their arguments and reports one
+// affected row for each
+type recordingDBTX struct {
+	db.DBTX
+	execs []string
+	args  [][]interface{}
+}
+
+func (r *recordingDBTX) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	r.execs = append(r.execs, query)
+	r.args = append(r.args, args)
+	return driver.RowsAffected(1), nil
+}
+
+// fakeQuerier runs the move queries against a recordingDBTX and serves
+// contacts from a map
+type fakeQuerier struct {
+	*db.Queries
+	contacts map[uuid.UUID]db.Contact
+	emails   []db.Email
+	updated  db.UpdateMergedContactParams
+	merge    db.CreateContactMergeParams
+	requests []db.CreatePersonalDataRequestParams
+	near     db.ListContactsNearParams
+}
+
+func (q *fakeQuerier) ListPrimaryEmails(ctx context.Context, ids []uuid.UUID) ([]db.Email, error) {
+	var primary []db.Email
+	for _, email := range q.emails {
+		for _, id := range ids {
+			if email.ContactID == id && email.IsPrimary.Bool {
+				primary = append(primary, email)
+			}
+		}
+	}
+	return primary, nil
+}
+
+func (q *fakeQuerier) ListPrimaryPhones(ctx context.Context, ids []uuid.UUID) ([]db.Phone, error) {
+	return nil, nil
+}
+
+func (q *fakeQuerier) ListPrimaryAddresses(ctx context.Context, ids []uuid.UUID) ([]db.Address, error) {
+	return nil, nil
+}
+
+func (q *fakeQuerier) GetContact(ctx context.Context, id uuid.UUID) (db.Contact, error) {
+	contact, ok := q.contacts[id]
+	if !ok {
+		return db.Contact{}, sql.ErrNoRows
+	}
+	return contact, nil
+}
+
+func (q *fakeQuerier) ListMergedContactIDs(ctx context.Context, id uuid.UUID) ([]uuid.UUID, error) {
+	ids := []uuid.UUID{id}
+	for i := 0; i < len(ids); i++ {
+		for _, contact := range q.contacts {
+			if contact.MergedToID.Valid && contact.MergedToID.UUID == ids[i] {
+				ids = append(ids, contact.ID)
+			}
+		}
+	}
+	return ids, nil
+}
+
+func (q *fakeQuerier) ListContactsNear(ctx context.Context, arg db.ListContactsNearParams) ([]db.Contact, error) {
+	q.near = arg
+	var near []db.Contact
+	for _, contact := range q.contacts {
+		near = append(near, contact)
+	}
+	return near, nil
+}
+
+func (q *fakeQuerier) LockContactsForMerge(ctx context.Context, arg db.LockContactsForMergeParams) ([]db.Contact, error) {
+	var locked []db.Contact
+	for _, id := range []uuid.UUID{arg.MainID, arg.OtherID} {
+		if contact, ok := q.contacts[id]; ok {
+			locked = append(locked, contact)
+		}
+	}
+	return locked, nil
+}
+
+func (q *fakeQuerier) UpdateMergedContact(ctx context.Context, arg db.UpdateMergedContactParams) (db.Contact, error) {
+	q.updated = arg
+	contact := q.contacts[arg.ID]
+	contact.Prefix, contact.FirstName, contact.LastName, contact.Suffix = arg.Prefix, arg.FirstName, arg.LastName, arg.Suffix
+	contact.NickName, contact.OrganizationName, contact.HouseholdName = arg.NickName, arg.OrganizationName, arg.HouseholdName
+	contact.ContactSubType = arg.ContactSubType
+	q.contacts[arg.ID] = contact
+	return contact, nil
+}
+
+func (q *fakeQuerier) SetContactNames(ctx context.Context, arg db.SetContactNamesParams) (db.Contact, error) {
+	contact := q.contacts[arg.ID]
+	contact.DisplayName, contact.SortName = arg.DisplayName, arg.SortName
+	q.contacts[arg.ID] = contact
+	return contact, nil
+}
+
+// fakeSettings serves settings from a map
+type fakeSettings map[string]string
+
+func (f fakeSettings) GetString(ctx context.Context, domainID uuid.UUID, name, fallback string) (string, error) {
+	if value, ok := f[name]; ok {
+		return value, nil
+	}
+	return fallback, nil
+}
+
+func (q *fakeQuerier) CreateContactMerge(ctx context.Context, arg db.CreateContactMergeParams) (db.ContactMerge, error) {
+	q.merge = arg
+	return db.ContactMerge{ID: uuid.New(), MainContactID: arg.MainContactID, OtherContactID: arg.OtherContactID}, nil
+}
+
+func newFixture(t *testing.T, contacts ...db.Contact) (*Service, *fakeQuerier, *recordingDBTX) {
+	t.Helper()
+	recorder := &recordingDBTX{}
+	q := &fakeQuerier{Queries: db.New(recorder), contacts: make(map[uuid.UUID]db.Contact)}
+	for _, contact := range contacts {
+		q.contacts[contact.ID] = contact
+	}
+	return &Service{queries: q, tx: dbtest.Tx{Q: q}, settings: fakeSettings{}, logger: logger.NewNop()}, q, recorder
+}
+
+func TestMerge(t *testing.T) {
+	main := db.Contact{ID: uuid.New(), ContactType: "Individual", ContactSubType: []string{"Staff"}, FirstName: dbtest.Text("Ada"), LastName: dbtest.Text("Lovelace")}
+	other := db.Contact{ID: uuid.New(), ContactType: "Individual", ContactSubType: []string{"Parent", "Staff"}, FirstName: dbtest.Text("Augusta"), LastName: dbtest.Text("Byron"), OrganizationName: dbtest.Text("Analytical Society")}
+	service, q, recorder := newFixture(t, main, other)
+	userID := dbtest.UUID(uuid.New())
+
+	result, err := service.Merge(context.Background(), main.ID, other.ID, MergeOptions{
+		Fields:   map[string]string{"last_name": KeepRight, "first_name": KeepLeft},
+		MergedBy: userID,
+	})
+	require.NoError(t, err)
+
+	// Chosen fields come from the duplicate, and empty fields are filled in
+	assert.Equal(t, dbtest.Text("Ada"), q.updated.FirstName)
+	assert.Equal(t, dbtest.Text("Byron"), q.updated.LastName)
+	assert.Equal(t, dbtest.Text("Analytical Society"), q.updated.OrganizationName)
+	assert.Equal(t, dbtest.Text("Byron"), result.Contact.LastName)
+	assert.Equal(t, []string{"Staff", "Parent"}, q.updated.ContactSubType)
+
+	// Names are recomputed from the merged fields
+	assert.Equal(t, dbtest.Text("Ada Byron"), result.Contact.DisplayName)
+	assert.Equal(t, dbtest.Text("Byron, Ada"), result.Contact.SortName)
+
+	require.NotEmpty(t, recorder.execs)
+	assert.Equal(t, db.MarkContactMerged, recorder.execs[0])
+	assert.Contains(t, recorder.execs, db.DeleteSelfRelationships)
+	assert.Contains(t, recorder.execs, db.MergeCommunicationPreferences)
+	assert.NotContains(t, recorder.execs, db.SetPrimaryEmail)
+
+	for _, m := range moves {
+		assert.Positive(t, result.Moved[m.table], m.table)
+	}
+	assert.Equal(t, int64(2), result.Moved["relationships"])
+
+	assert.Equal(t, main.ID, q.merge.MainContactID)
+	assert.Equal(t, userID, q.merge.MergedBy)
+	var snapshot db.Contact
+	require.NoError(t, json.Unmarshal(q.merge.OtherSnapshot, &snapshot))
+	assert.Equal(t, other.LastName, snapshot.LastName)
+	assert.JSONEq(t, `{"last_name": "right", "first_name": "left"}`, string(q.merge.FieldChoices))
+}
+
+// TestMergePrimaryChoice tests that choosing right for a location kind makes
+// the duplicate's primary record the primary one
+func TestMergePrimaryChoice(t *testing.T) {
+	main := db.Contact{ID: uuid.New(), ContactType: "Individual"}
+	other := db.Contact{ID: uuid.New(), ContactType: "Individual"}
+	service, q, recorder := newFixture(t, main, other)
+	q.emails = []db.Email{
+		{ID: uuid.New(), ContactID: main.ID, Email: "old@example.org", IsPrimary: dbtest.Bool(true)},
+		{ID: uuid.New(), ContactID: other.ID, Email: "new@example.org", IsPrimary: dbtest.Bool(true)},
+	}
+
+	_, err := service.Merge(context.Background(), main.ID, other.ID, MergeOptions{
+		Fields: map[string]string{"email": KeepRight, "phone": KeepRight},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, recorder.execs, db.SetPrimaryEmail)
+	assert.NotContains(t, recorder.execs, db.SetPrimaryPhone)
+}
+
+func TestMergeRejects(t *testing.T) {
+	main := db.Contact{ID: uuid.New(), ContactType: "Individual"}
+	org := db.Contact{ID: uuid.New(), ContactType: "Organization"}
+	deleted := db.Contact{ID: uuid.New(), ContactType: "Individual", IsDeleted: true}
+	service, _, recorder := newFixture(t, main, org, deleted)
+	ctx := context.Background()
+
+	_, err := service.Merge(ctx, main.ID, main.ID, MergeOptions{})
+	assert.ErrorIs(t, err, ErrCannotMerge)
+
+	_, err = service.Merge(ctx, main.ID, org.ID, MergeOptions{})
+	assert.ErrorIs(t, err, ErrCannotMerge)
+
+	_, err = service.Merge(ctx, main.ID, deleted.ID, MergeOptions{})
+	assert.ErrorIs(t, err, ErrCannotMerge)
+
+	_, err = service.Merge(ctx, main.ID, uuid.New(), MergeOptions{})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = service.Merge(ctx, main.ID, org.ID, MergeOptions{Fields: map[string]string{"password": KeepRight}})
+	assert.ErrorIs(t, err, ErrCannotMerge)
+
+	_, err = service.Merge(ctx, main.ID, org.ID, MergeOptions{Fields: map[string]string{"email": "both"}})
+	assert.ErrorIs(t, err, ErrCannotMerge)
+
+	assert.Empty(t, recorder.execs)
+}
+
+func TestGetFollowsMergeRedirects(t *testing.T) {
+	kept := db.Contact{ID: uuid.New(), ContactType: "Individual"}
+	middle := db.Contact{ID: uuid.New(), ContactType: "Individual", IsDeleted: true, MergedToID: dbtest.UUID(kept.ID)}
+	first := db.Contact{ID: uuid.New(), ContactType: "Individual", IsDeleted: true, MergedToID: dbtest.UUID(middle.ID)}
+	service, _, _ := newFixture(t, kept, middle, first)
+
+	contact, err := service.Get(context.Background(), first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, kept.ID, contact.ID)
+
+	_, err = service.Get(context.Background(), uuid.New())
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,192 @@
+package contacts
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+)
+
+// Settings holding the name formats of individuals
+const (
+	SettingDisplayNameFormat = "display_name_format"
+	SettingSortNameFormat    = "sort_name_format"
+)
+
+// Default name formats, used when a domain has no setting
+const (
+	DefaultDisplayNameFormat = "{prefix} {first_name} {last_name} {suffix}"
+	DefaultSortNameFormat    = "{last_name}, {first_name}"
+)
+
+// ErrInvalidSubType is returned when a contact subtype does not exist or
+// belongs to another contact type
+var ErrInvalidSubType = errors.New("invalid contact subtype")
+
+// SettingsReader reads per-domain settings. *settings.Service implements it.
+type SettingsReader interface {
+	GetString(ctx context.Context, domainID uuid.UUID, name, fallback string) (string, error)
+}
+
+// NameFormats are the formats of the display and sort names of individuals.
+// Organizations and households are always named by their organization or
+// household name.
+type NameFormats struct {
+	Display string
+	Sort    string
+}
+
+// nameTokens are the contact fields name formats can use
+var nameTokens = map[string]func(c *db.Contact) sql.NullString{
+	"prefix":            func(c *db.Contact) sql.NullString { return c.Prefix },
+	"first_name":        func(c *db.Contact) sql.NullString { return c.FirstName },
+	"last_name":         func(c *db.Contact) sql.NullString { return c.LastName },
+	"suffix":            func(c *db.Contact) sql.NullString { return c.Suffix },
+	"nick_name":         func(c *db.Contact) sql.NullString { return c.NickName },
+	"organization_name": func(c *db.Contact) sql.NullString { return c.OrganizationName },
+	"household_name":    func(c *db.Contact) sql.NullString { return c.HouseholdName },
+}
+
+// ComputeNames returns the display and sort names of a contact
+func ComputeNames(contact db.Contact, formats NameFormats) (display, sort string) {
+	switch contact.ContactType {
+	case "Organization":
+		name := strings.TrimSpace(contact.OrganizationName.String)
+		return name, name
+	case "Household":
+		name := strings.TrimSpace(contact.HouseholdName.String)
+		return name, name
+	}
+	return FormatName(formats.Display, contact), FormatName(formats.Sort, contact)
+}
+
+// FormatName renders a name format such as "{last_name}, {first_name}". The
+// text after a field separates it from the next field that has a value, so
+// separators next to empty fields are dropped. Text before the first field
+// and after the last one is kept only when that field has a value. Unknown
+// fields are empty.
+func FormatName(format string, contact db.Contact) string {
+	// The format alternates text and fields: texts[i] precedes fields[i],
+	// and the last text follows the last field
+	var texts, fields []string
+	for {
+		start := strings.IndexByte(format, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(format[start:], '}')
+		if end < 0 {
+			break
+		}
+		end += start
+		texts = append(texts, format[:start])
+		fields = append(fields, format[start+1:end])
+		format = format[end+1:]
+	}
+	texts = append(texts, format)
+
+	var present []int
+	values := make([]string, len(fields))
+	for i, token := range fields {
+		if field, ok := nameTokens[token]; ok {
+			values[i] = strings.TrimSpace(field(&contact).String)
+		}
+		if values[i] != "" {
+			present = append(present, i)
+		}
+	}
+	if len(present) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	if present[0] == 0 {
+		b.WriteString(texts[0])
+	}
+	for n, i := range present {
+		b.WriteString(values[i])
+		if n < len(present)-1 || i == len(fields)-1 {
+			b.WriteString(texts[i+1])
+		}
+	}
+	return strings.Trim(strings.Join(strings.Fields(b.String()), " "), " ,")
+}
+
+// nameFormats returns the name formats of a domain
+func (s *Service) nameFormats(ctx context.Context, domainID uuid.UUID) (NameFormats, error) {
+	display, err := s.settings.GetString(ctx, domainID, SettingDisplayNameFormat, DefaultDisplayNameFormat)
+	if err != nil {
+		return NameFormats{}, fmt.Errorf("failed to get display name format: %w", err)
+	}
+	sort, err := s.settings.GetString(ctx, domainID, SettingSortNameFormat, DefaultSortNameFormat)
+	if err != nil {
+		return NameFormats{}, fmt.Errorf("failed to get sort name format: %w", err)
+	}
+	return NameFormats{Display: display, Sort: sort}, nil
+}
+
+// updateNames recomputes the display and sort names of a contact that was
+// just written, and stores them when they changed
+func (s *Service) updateNames(ctx context.Context, q db.Querier, contact db.Contact) (db.Contact, error) {
+	formats, err := s.nameFormats(ctx, contact.DomainID)
+	if err != nil {
+		return contact, err
+	}
+
+	display, sort := ComputeNames(contact, formats)
+	displayName, sortName := nullString(display), nullString(sort)
+	if displayName == contact.DisplayName && sortName == contact.SortName {
+		return contact, nil
+	}
+
+	updated, err := q.SetContactNames(ctx, db.SetContactNamesParams{
+		ID:          contact.ID,
+		DisplayName: displayName,
+		SortName:    sortName,
+	})
+	if err != nil {
+		return contact, fmt.Errorf("failed to set contact names: %w", err)
+	}
+	return updated, nil
+}
+
+// validateSubTypes checks that subtypes exist, are active and belong to the
+// contact type
+func validateSubTypes(ctx context.Context, q db.Querier, contactType string, subTypes []string) error {
+	if len(subTypes) == 0 {
+		return nil
+	}
+
+	rows, err := q.ListContactSubTypesByName(ctx, subTypes)
+	if err != nil {
+		return fmt.Errorf("failed to list contact subtypes: %w", err)
+	}
+	found := make(map[string]db.ContactSubType, len(rows))
+	for _, row := range rows {
+		found[row.Name] = row
+	}
+
+	for _, name := range subTypes {
+		subType, ok := found[name]
+		switch {
+		case !ok || !subType.IsActive:
+			return fmt.Errorf("%w: unknown subtype %q", ErrInvalidSubType, name)
+		case subType.Parent != contactType:
+			return fmt.Errorf("%w: %s is a subtype of %s", ErrInvalidSubType, name, subType.Parent)
+		}
+	}
+	return nil
+}
+
+// SubTypes returns the active contact subtypes
+func (s *Service) SubTypes(ctx context.Context) ([]db.ContactSubType, error) {
+	subTypes, err := s.queries.ListContactSubTypes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list contact subtypes: %w", err)
+	}
+	return subTypes, nil
+}
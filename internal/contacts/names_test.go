@@ -0,0 +1,108 @@
+package contacts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/database/dbtest"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatName(t *testing.T) {
+	full := db.Contact{
+		Prefix:    dbtest.Text("Dr."),
+		FirstName: dbtest.Text("Ada"),
+		LastName:  dbtest.Text("Lovelace"),
+		Suffix:    dbtest.Text("PhD"),
+		NickName:  dbtest.Text("Addie"),
+	}
+
+	tests := []struct {
+		name    string
+		format  string
+		contact db.Contact
+		want    string
+	}{
+		{"display", DefaultDisplayNameFormat, full, "Dr. Ada Lovelace PhD"},
+		{"sort", DefaultSortNameFormat, full, "Lovelace, Ada"},
+		{"display without prefix", DefaultDisplayNameFormat, db.Contact{FirstName: dbtest.Text("Ada"), LastName: dbtest.Text("Lovelace")}, "Ada Lovelace"},
+		{"display without first name", DefaultDisplayNameFormat, db.Contact{Prefix: dbtest.Text("Dr."), LastName: dbtest.Text("Lovelace")}, "Dr. Lovelace"},
+		{"sort without first name", DefaultSortNameFormat, db.Contact{LastName: dbtest.Text("Lovelace")}, "Lovelace"},
+		{"sort without last name", DefaultSortNameFormat, db.Contact{FirstName: dbtest.Text("Ada")}, "Ada"},
+		{"empty", DefaultDisplayNameFormat, db.Contact{}, ""},
+		{"surrounding text", "{first_name} ({nick_name})", full, "Ada (Addie)"},
+		{"surrounding text without field", "{first_name} ({nick_name})", db.Contact{FirstName: dbtest.Text("Ada")}, "Ada"},
+		{"leading text", "Dear {first_name}", full, "Dear Ada"},
+		{"unknown field", "{first_name} {middle_name} {last_name}", full, "Ada Lovelace"},
+		{"blank values", DefaultDisplayNameFormat, db.Contact{FirstName: dbtest.Text(" Ada "), LastName: dbtest.Text("  ")}, "Ada"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, FormatName(tt.format, tt.contact))
+		})
+	}
+}
+
+func TestComputeNames(t *testing.T) {
+	formats := NameFormats{Display: "{first_name} {last_name}", Sort: "{last_name} {first_name}"}
+
+	display, sort := ComputeNames(db.Contact{ContactType: "Individual", FirstName: dbtest.Text("Ada"), LastName: dbtest.Text("Lovelace")}, formats)
+	assert.Equal(t, "Ada Lovelace", display)
+	assert.Equal(t, "Lovelace Ada", sort)
+
+	display, sort = ComputeNames(db.Contact{ContactType: "Organization", FirstName: dbtest.Text("Ada"), OrganizationName: dbtest.Text("Analytical Society")}, formats)
+	assert.Equal(t, "Analytical Society", display)
+	assert.Equal(t, "Analytical Society", sort)
+
+	display, sort = ComputeNames(db.Contact{ContactType: "Household", HouseholdName: dbtest.Text("Lovelace Family")}, formats)
+	assert.Equal(t, "Lovelace Family", display)
+	assert.Equal(t, "Lovelace Family", sort)
+}
+
+// TestUpdateNamesUsesDomainFormats tests that names follow the formats in
+// settings and are only stored when they change
+func TestUpdateNamesUsesDomainFormats(t *testing.T) {
+	contact := db.Contact{ID: uuid.New(), ContactType: "Individual", FirstName: dbtest.Text("Ada"), LastName: dbtest.Text("Lovelace")}
+	service, q, _ := newFixture(t, contact)
+	service.settings = fakeSettings{SettingDisplayNameFormat: "{last_name} {first_name}"}
+
+	updated, err := service.updateNames(context.Background(), q, contact)
+	require.NoError(t, err)
+	assert.Equal(t, dbtest.Text("Lovelace Ada"), updated.DisplayName)
+	assert.Equal(t, dbtest.Text("Lovelace, Ada"), updated.SortName)
+
+	// Unchanged names are not written again
+	delete(q.contacts, contact.ID)
+	again, err := service.updateNames(context.Background(), q, updated)
+	require.NoError(t, err)
+	assert.Equal(t, updated, again)
+}
+
+// subTypeQuerier serves contact subtypes
+type subTypeQuerier struct {
+	db.Querier
+	subTypes []db.ContactSubType
+}
+
+func (q subTypeQuerier) ListContactSubTypesByName(ctx context.Context, names []string) ([]db.ContactSubType, error) {
+	return q.subTypes, nil
+}
+
+func TestValidateSubTypes(t *testing.T) {
+	q := subTypeQuerier{subTypes: []db.ContactSubType{
+		{Name: "Student", Parent: "Individual", IsActive: true},
+		{Name: "Foundation", Parent: "Organization", IsActive: true},
+		{Name: "Alumnus", Parent: "Individual", IsActive: false},
+	}}
+	ctx := context.Background()
+
+	require.NoError(t, validateSubTypes(ctx, q, "Individual", nil))
+	require.NoError(t, validateSubTypes(ctx, q, "Individual", []string{"Student"}))
+	require.NoError(t, validateSubTypes(ctx, q, "Organization", []string{"Foundation"}))
+	assert.ErrorIs(t, validateSubTypes(ctx, q, "Individual", []string{"Foundation"}), ErrInvalidSubType)
+	assert.ErrorIs(t, validateSubTypes(ctx, q, "Individual", []string{"Alumnus"}), ErrInvalidSubType)
+	assert.ErrorIs(t, validateSubTypes(ctx, q, "Individual", []string{"Astronaut"}), ErrInvalidSubType)
+}
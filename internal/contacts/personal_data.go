@@ -0,0 +1,334 @@
+package contacts
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+)
+
+// Formats of a personal data export
+const (
+	FormatJSON = "json"
+	FormatZip  = "zip"
+)
+
+// Types of personal data request recorded in personal_data_requests
+const (
+	RequestExport    = "Export"
+	RequestAnonymize = "Anonymize"
+)
+
+var (
+	// ErrInvalidFormat is returned for an export format other than json or
+	// zip
+	ErrInvalidFormat = errors.New("invalid export format")
+
+	// ErrNoActor is returned for an export or erasure without the user
+	// making it, which every personal data request records
+	ErrNoActor = errors.New("personal data request has no actor")
+)
+
+// PersonalData is everything stored about a contact, by section. Each
+// section is a JSON array of the rows of one kind of record.
+type PersonalData struct {
+	ContactID  uuid.UUID                  `json:"contact_id"`
+	ExportedAt time.Time                  `json:"exported_at"`
+	Sections   map[string]json.RawMessage `json:"sections"`
+}
+
+// AnonymizeResult is the outcome of anonymizing a contact
+type AnonymizeResult struct {
+	ContactID uuid.UUID              `json:"contact_id"`
+	Request   db.PersonalDataRequest `json:"request"`
+	Changed   map[string]int64       `json:"changed"`
+}
+
+// exports lists the sections of a personal data export and their queries
+var exports = []struct {
+	section string
+	export  func(db.Querier, context.Context, []uuid.UUID) (json.RawMessage, error)
+}{
+	{"contact", db.Querier.ExportContact},
+	{"emails", db.Querier.ExportEmails},
+	{"phones", db.Querier.ExportPhones},
+	{"addresses", db.Querier.ExportAddresses},
+	{"websites", db.Querier.ExportWebsites},
+	{"ims", db.Querier.ExportIMs},
+	{"contact_location_backup", db.Querier.ExportContactLocationBackup},
+	{"relationships", db.Querier.ExportRelationships},
+	{"group_contacts", db.Querier.ExportGroupContacts},
+	{"subscription_history", db.Querier.ExportSubscriptionHistory},
+	{"activities", db.Querier.ExportActivities},
+	{"contributions", db.Querier.ExportContributions},
+	{"memberships", db.Querier.ExportMemberships},
+	{"participants", db.Querier.ExportParticipants},
+	{"event_registrations", db.Querier.ExportEventRegistrations},
+	{"pledges", db.Querier.ExportPledges},
+	{"case_contacts", db.Querier.ExportCaseContacts},
+	{"campaign_contacts", db.Querier.ExportCampaignContacts},
+	{"entity_tags", db.Querier.ExportEntityTags},
+	{"custom_values", db.Querier.ExportCustomValues},
+	{"mailing_list_subscriptions", db.Querier.ExportMailingSubscriptions},
+	{"mailing_recipients", db.Querier.ExportMailingRecipients},
+	{"mailing_opens", db.Querier.ExportMailingOpens},
+	{"mailing_url_clicks", db.Querier.ExportMailingClicks},
+	{"sms_messages", db.Querier.ExportSMSMessages},
+	{"communication_preferences", db.Querier.ExportCommunicationPreferences},
+	{"survey_responses", db.Querier.ExportSurveyResponses},
+	{"contact_merges", db.Querier.ExportContactMerges},
+	{"user_accounts", db.Querier.ExportUserAccounts},
+	{"personal_data_requests", db.Querier.ExportPersonalDataRequests},
+}
+
+// scrubs lists the records anonymizing a contact deletes or clears, by
+// table, after the contacts themselves are anonymized. Contributions, pledges and payments are kept whole so financial
+// totals do not change; they only point at the anonymized contact.
+var scrubs = []struct {
+	table string
+	scrub func(db.Querier, context.Context, []uuid.UUID) (int64, error)
+}{
+	{"emails", db.Querier.DeleteContactEmails},
+	{"phones", db.Querier.DeleteContactPhones},
+	{"addresses", db.Querier.DeleteContactAddresses},
+	{"websites", db.Querier.DeleteContactWebsites},
+	{"ims", db.Querier.DeleteContactIMs},
+	{"contact_location_backup", db.Querier.DeleteContactLocationBackup},
+	{"relationships", db.Querier.DeleteContactRelationships},
+	{"entity_tags", db.Querier.DeleteContactEntityTags},
+	{"custom_values", db.Querier.DeleteContactCustomValues},
+	{"uf_match", db.Querier.DeleteContactUserAccounts},
+	{"activities", db.Querier.AnonymizeActivities},
+	{"memberships", db.Querier.AnonymizeMemberships},
+	{"participants", db.Querier.AnonymizeParticipants},
+	{"mailing_list_subscriptions", db.Querier.AnonymizeMailingSubscriptions},
+	{"mailing_recipients", db.Querier.AnonymizeMailingRecipients},
+	{"mailing_opens", db.Querier.AnonymizeMailingOpens},
+	{"mailing_url_clicks", db.Querier.AnonymizeMailingClicks},
+	{"sms_messages", db.Querier.AnonymizeSMSMessages},
+	{"survey_responses", db.Querier.AnonymizeSurveyResponses},
+	{"survey_response_answers", db.Querier.AnonymizeSurveyAnswers},
+	{"contact_merges", db.Querier.AnonymizeContactMerges},
+}
+
+// ExportPersonalData collects everything stored about a contact and the
+// duplicates merged into it, and records the export, with the number of
+// records in each section, as a personal data request. A contact deleted by
+// a merge exports the contact it was merged into. format is FormatJSON or FormatZip and is only
+// recorded; the caller encodes the result.
+func (s *Service) ExportPersonalData(ctx context.Context, id uuid.UUID, format string, actorID uuid.NullUUID) (*PersonalData, error) {
+	if format != FormatJSON && format != FormatZip {
+		return nil, fmt.Errorf("%w: %q is not %s or %s", ErrInvalidFormat, format, FormatJSON, FormatZip)
+	}
+	if !actorID.Valid {
+		return nil, ErrNoActor
+	}
+
+	var data *PersonalData
+	err := s.tx.WithTx(ctx, func(q db.Querier) error {
+		contact, err := followMerges(ctx, q, id)
+		if err != nil {
+			return err
+		}
+		ids, err := q.ListMergedContactIDs(ctx, contact.ID)
+		if err != nil {
+			return fmt.Errorf("failed to list merged contacts: %w", err)
+		}
+
+		data = &PersonalData{ContactID: contact.ID, Sections: make(map[string]json.RawMessage, len(exports))}
+		counts := make(map[string]int, len(exports))
+		for _, e := range exports {
+			records, err := e.export(q, ctx, ids)
+			if err != nil {
+				return fmt.Errorf("failed to export %s: %w", e.section, err)
+			}
+			var rows []json.RawMessage
+			if err := json.Unmarshal(records, &rows); err != nil {
+				return fmt.Errorf("failed to decode %s: %w", e.section, err)
+			}
+			data.Sections[e.section] = records
+			counts[e.section] = len(rows)
+		}
+
+		request, err := recordRequest(ctx, q, contact, RequestExport, format, counts, sql.NullString{}, actorID)
+		if err != nil {
+			return err
+		}
+		data.ExportedAt = request.CreatedAt
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Exported personal data", "contact_id", data.ContactID, "format", format)
+	return data, nil
+}
+
+// Anonymize scrubs a contact's personal data in place: names are replaced
+// by "Anonymous", location records, relationships, tags, custom values and
+// login links are deleted, and free text about the contact is cleared from
+// activities, mailings, SMS and surveys. Financial records are kept whole.
+// Contacts merged into it are anonymized with it, along with every record
+// left with them. The erasure is recorded, with
+// the rows changed in each table, as a personal data request.
+func (s *Service) Anonymize(ctx context.Context, id uuid.UUID, reason string, actorID uuid.NullUUID) (*AnonymizeResult, error) {
+	if !actorID.Valid {
+		return nil, ErrNoActor
+	}
+
+	var result *AnonymizeResult
+	err := s.tx.WithTx(ctx, func(q db.Querier) error {
+		contact, err := followMerges(ctx, q, id)
+		if err != nil {
+			return err
+		}
+		ids, err := q.ListMergedContactIDs(ctx, contact.ID)
+		if err != nil {
+			return fmt.Errorf("failed to list merged contacts: %w", err)
+		}
+
+		changed := make(map[string]int64, len(scrubs)+1)
+		n, err := q.AnonymizeContact(ctx, ids)
+		if err != nil {
+			return fmt.Errorf("failed to anonymize contact: %w", err)
+		}
+		changed["contacts"] = n
+		for _, scrub := range scrubs {
+			n, err := scrub.scrub(q, ctx, ids)
+			if err != nil {
+				return fmt.Errorf("failed to anonymize %s: %w", scrub.table, err)
+			}
+			changed[scrub.table] += n
+		}
+
+		request, err := recordRequest(ctx, q, contact, RequestAnonymize, "", changed, nullString(reason), actorID)
+		if err != nil {
+			return err
+		}
+		result = &AnonymizeResult{ContactID: contact.ID, Request: request, Changed: changed}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Anonymized contact", "contact_id", result.ContactID, "request_id", result.Request.ID)
+	return result, nil
+}
+
+// PersonalDataRequests lists the exports and erasures of a contact, newest
+// first
+func (s *Service) PersonalDataRequests(ctx context.Context, contactID uuid.UUID) ([]db.PersonalDataRequest, error) {
+	requests, err := s.queries.ListPersonalDataRequests(ctx, db.ListPersonalDataRequestsParams{
+		ContactID: uuid.NullUUID{UUID: contactID, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list personal data requests: %w", err)
+	}
+	return requests, nil
+}
+
+// Zip packs an export as a ZIP archive holding manifest.json, with the
+// contact, export time and record counts, and a JSON file per section
+func (d *PersonalData) Zip() ([]byte, error) {
+	manifest := struct {
+		ContactID  uuid.UUID      `json:"contact_id"`
+		ExportedAt time.Time      `json:"exported_at"`
+		Sections   map[string]int `json:"sections"`
+	}{d.ContactID, d.ExportedAt, make(map[string]int, len(d.Sections))}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, e := range exports {
+		records, ok := d.Sections[e.section]
+		if !ok {
+			continue
+		}
+		var rows []json.RawMessage
+		if err := json.Unmarshal(records, &rows); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", e.section, err)
+		}
+		manifest.Sections[e.section] = len(rows)
+
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, records, "", "  "); err != nil {
+			return nil, fmt.Errorf("failed to format %s: %w", e.section, err)
+		}
+		if err := writeZipFile(archive, e.section+".json", d.ExportedAt, indented.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+
+	encoded, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := writeZipFile(archive, "manifest.json", d.ExportedAt, encoded); err != nil {
+		return nil, err
+	}
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// writeZipFile adds a file to an archive
+func writeZipFile(archive *zip.Writer, name string, modified time.Time, body []byte) error {
+	w, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+	if _, err := w.Write(body); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// followMerges returns a contact, following the redirects of contacts
+// deleted by a merge to the contact they were merged into
+func followMerges(ctx context.Context, q db.Querier, id uuid.UUID) (db.Contact, error) {
+	for i := 0; i <= maxRedirects; i++ {
+		contact, err := q.GetContact(ctx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return db.Contact{}, ErrNotFound
+		}
+		if err != nil {
+			return db.Contact{}, fmt.Errorf("failed to get contact: %w", err)
+		}
+		if !contact.MergedToID.Valid {
+			return contact, nil
+		}
+		id = contact.MergedToID.UUID
+	}
+	return db.Contact{}, fmt.Errorf("failed to get contact: more than %d merge redirects", maxRedirects)
+}
+
+// recordRequest records an export or erasure of a contact's personal data
+// with its per-section counts
+func recordRequest(ctx context.Context, q db.Querier, contact db.Contact, requestType, format string, counts interface{}, reason sql.NullString, actorID uuid.NullUUID) (db.PersonalDataRequest, error) {
+	records, err := json.Marshal(counts)
+	if err != nil {
+		return db.PersonalDataRequest{}, fmt.Errorf("failed to encode record counts: %w", err)
+	}
+	request, err := q.CreatePersonalDataRequest(ctx, db.CreatePersonalDataRequestParams{
+		DomainID:    contact.DomainID,
+		ContactID:   contact.ID,
+		RequestType: requestType,
+		Format:      sql.NullString{String: format, Valid: format != ""},
+		Records:     records,
+		Reason:      reason,
+		ActorID:     actorID,
+	})
+	if err != nil {
+		return db.PersonalDataRequest{}, fmt.Errorf("failed to record personal data request: %w", err)
+	}
+	return request, nil
+}
@@ -0,0 +1,119 @@
+package contacts
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/database/dbtest"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (q *fakeQuerier) CreatePersonalDataRequest(ctx context.Context, arg db.CreatePersonalDataRequestParams) (db.PersonalDataRequest, error) {
+	q.requests = append(q.requests, arg)
+	return db.PersonalDataRequest{ID: uuid.New(), ContactID: arg.ContactID, RequestType: arg.RequestType, Records: arg.Records}, nil
+}
+
+// TestAnonymize tests that anonymizing a merged contact scrubs the contact
+// it was merged into and every duplicate merged into that, leaves financial
+// records alone and records the erasure
+func TestAnonymize(t *testing.T) {
+	kept := db.Contact{ID: uuid.New(), DomainID: uuid.New(), ContactType: "Individual", FirstName: dbtest.Text("Ada")}
+	merged := db.Contact{ID: uuid.New(), ContactType: "Individual", IsDeleted: true, MergedToID: dbtest.UUID(kept.ID)}
+	older := db.Contact{ID: uuid.New(), ContactType: "Individual", IsDeleted: true, MergedToID: dbtest.UUID(merged.ID)}
+	service, q, recorder := newFixture(t, kept, merged, older)
+	actor := dbtest.UUID(uuid.New())
+
+	result, err := service.Anonymize(context.Background(), merged.ID, "Erasure request", actor)
+	require.NoError(t, err)
+	assert.Equal(t, kept.ID, result.ContactID)
+
+	assert.Equal(t, db.AnonymizeContact, recorder.execs[0])
+	assert.Contains(t, recorder.execs, db.DeleteContactEmails)
+	assert.Contains(t, recorder.execs, db.AnonymizeSMSMessages)
+	for i, exec := range recorder.execs {
+		assert.NotContains(t, exec, "contributions")
+		assert.NotContains(t, exec, "pledge")
+		assert.Equal(t, []interface{}{pq.Array([]uuid.UUID{kept.ID, merged.ID, older.ID})}, recorder.args[i], "every scrub covers the merged duplicates")
+	}
+	for _, scrub := range scrubs {
+		assert.Positive(t, result.Changed[scrub.table], scrub.table)
+	}
+	assert.Positive(t, result.Changed["contacts"])
+
+	require.Len(t, q.requests, 1)
+	request := q.requests[0]
+	assert.Equal(t, kept.ID, request.ContactID)
+	assert.Equal(t, kept.DomainID, request.DomainID)
+	assert.Equal(t, RequestAnonymize, request.RequestType)
+	assert.False(t, request.Format.Valid)
+	assert.Equal(t, dbtest.Text("Erasure request"), request.Reason)
+	assert.Equal(t, actor, request.ActorID)
+	var changed map[string]int64
+	require.NoError(t, json.Unmarshal(request.Records, &changed))
+	assert.Equal(t, int64(1), changed["emails"])
+
+	_, err = service.Anonymize(context.Background(), uuid.New(), "", actor)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = service.Anonymize(context.Background(), kept.ID, "", uuid.NullUUID{})
+	assert.ErrorIs(t, err, ErrNoActor)
+	assert.Len(t, q.requests, 1)
+}
+
+func TestExportPersonalDataRejectsFormat(t *testing.T) {
+	contact := db.Contact{ID: uuid.New(), ContactType: "Individual"}
+	service, q, _ := newFixture(t, contact)
+
+	_, err := service.ExportPersonalData(context.Background(), contact.ID, "csv", dbtest.UUID(uuid.New()))
+	assert.ErrorIs(t, err, ErrInvalidFormat)
+	_, err = service.ExportPersonalData(context.Background(), contact.ID, FormatJSON, uuid.NullUUID{})
+	assert.ErrorIs(t, err, ErrNoActor)
+	assert.Empty(t, q.requests)
+}
+
+func TestPersonalDataZip(t *testing.T) {
+	data := &PersonalData{
+		ContactID:  uuid.New(),
+		ExportedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Sections: map[string]json.RawMessage{
+			"contact": json.RawMessage(`[{"first_name": "Ada"}]`),
+			"emails":  json.RawMessage(`[{"email": "ada@example.org"}, {"email": "ada@example.com"}]`),
+			"phones":  json.RawMessage(`[]`),
+		},
+	}
+
+	archive, err := data.Zip()
+	require.NoError(t, err)
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+
+	files := make(map[string][]byte)
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		require.NoError(t, err)
+		body, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		files[file.Name] = body
+	}
+
+	require.Len(t, files, 4)
+	assert.JSONEq(t, `[{"email": "ada@example.org"}, {"email": "ada@example.com"}]`, string(files["emails.json"]))
+	assert.JSONEq(t, `[]`, string(files["phones.json"]))
+
+	var manifest struct {
+		ContactID uuid.UUID      `json:"contact_id"`
+		Sections  map[string]int `json:"sections"`
+	}
+	require.NoError(t, json.Unmarshal(files["manifest.json"], &manifest))
+	assert.Equal(t, data.ContactID, manifest.ContactID)
+	assert.Equal(t, map[string]int{"contact": 1, "emails": 2, "phones": 0}, manifest.Sections)
+}
@@ -7,27 +7,49 @@ import (
 	"os/signal"
 	"syscall"
 
+	"github.com/jxlxx/civicrm/internal/addresses"
 	"github.com/jxlxx/civicrm/internal/api"
 	"github.com/jxlxx/civicrm/internal/cache"
 	"github.com/jxlxx/civicrm/internal/config"
+	"github.com/jxlxx/civicrm/internal/contacts"
 	"github.com/jxlxx/civicrm/internal/database"
+	"github.com/jxlxx/civicrm/internal/dedupe"
 	"github.com/jxlxx/civicrm/internal/extensions"
+	"github.com/jxlxx/civicrm/internal/geocode"
+	"github.com/jxlxx/civicrm/internal/groups"
+	"github.com/jxlxx/civicrm/internal/jobs"
 	"github.com/jxlxx/civicrm/internal/logger"
+	"github.com/jxlxx/civicrm/internal/mail"
+	"github.com/jxlxx/civicrm/internal/metrics"
+	"github.com/jxlxx/civicrm/internal/relationships"
 	"github.com/jxlxx/civicrm/internal/security"
+	"github.com/jxlxx/civicrm/internal/settings"
+	"github.com/jxlxx/civicrm/internal/tracing"
 )
 
 // App represents the main CiviCRM application
 type App struct {
-	Config     *config.Config
-	Container  *Container
-	Logger     *logger.Logger
-	DB         *database.Database
-	Cache      *cache.Manager
-	Security   *security.Manager
-	Extensions *extensions.Manager
-	API        *api.Server
-	ctx        context.Context
-	cancel     context.CancelFunc
+	Config        *config.Config
+	Container     *Container
+	Logger        *logger.Logger
+	Metrics       *metrics.Registry
+	DB            *database.Database
+	Cache         *cache.Manager
+	Security      *security.Manager
+	Settings      *settings.Service
+	Contacts      *contacts.Service
+	Dedupe        *dedupe.Service
+	Relationships *relationships.Service
+	Groups        *groups.Service
+	Geocode       *geocode.Service
+	Mail          mail.Sender
+	Addresses     *addresses.Service
+	Jobs          *jobs.Scheduler
+	Extensions    *extensions.Manager
+	API           *api.Server
+	stopTracing   func(context.Context) error
+	ctx           context.Context
+	cancel        context.CancelFunc
 }
 
 // NewApp creates a new CiviCRM application instance
@@ -61,15 +83,49 @@ func (app *App) initialize() error {
 		return fmt.Errorf("failed to initialize logger: %w", err)
 	}
 
+	// Install the tracer provider before the database emits spans
+	app.stopTracing = tracing.Setup(&app.Config.Tracing, app.Logger)
+
+	// Metrics from every component are exposed through one registry
+	app.Metrics = metrics.NewRegistry()
+
 	// Initialize database
 	if app.DB, err = database.New(&app.Config.Database); err != nil {
 		return fmt.Errorf("failed to initialize database: %w", err)
 	}
+	app.DB.SetLogger(app.Logger)
+	app.Metrics.Register(app.DB)
 
 	// Initialize cache
-	if app.Cache, err = cache.New(&app.Config.Cache); err != nil {
+	if app.Cache, err = cache.New(&app.Config.Cache, app.Logger); err != nil {
 		return fmt.Errorf("failed to initialize cache: %w", err)
 	}
+	app.Metrics.Register(app.Cache)
+
+	// Initialize settings service
+	app.Settings = settings.New(app.DB.Querier(), app.Cache)
+
+	// Initialize geocoding
+	if app.Geocode, err = geocode.New(app.DB, &app.Config.Geocoding, app.Logger); err != nil {
+		return fmt.Errorf("failed to initialize geocoding: %w", err)
+	}
+
+	// Initialize outgoing email
+	app.Mail = mail.New(&app.Config.Mail)
+
+	// Initialize contact, address, dedupe, relationship and group services
+	app.Contacts = contacts.New(app.DB, app.Settings, app.Logger)
+	app.Addresses = addresses.New(app.DB, app.Logger)
+	app.Dedupe = dedupe.New(app.DB, app.Logger)
+	app.Relationships = relationships.New(app.DB, app.Cache, app.Logger)
+	app.Groups = groups.New(app.DB, &app.Config.Groups, app.Cache, app.Geocode, app.Mail, app.Logger)
+
+	// Initialize scheduled jobs
+	app.Jobs = jobs.New(app.DB, &app.Config.Jobs, app.Logger)
+	app.Jobs.Register(relationships.JobUpdateStatus, app.Relationships.UpdateStatusJob)
+	app.Jobs.Register(groups.JobRefresh, app.Groups.RefreshJob)
+	app.Jobs.Register(geocode.JobGeocode, app.Geocode.GeocodeJob)
+	app.Jobs.Register(addresses.JobParse, app.Addresses.ParseJob)
 
 	// Initialize security manager
 	if app.Security, err = security.New(&app.Config.Security); err != nil {
@@ -82,7 +138,13 @@ func (app *App) initialize() error {
 	}
 
 	// Initialize API server
-	if app.API, err = api.New(&app.Config.API, app.Logger, app.DB, app.Cache, app.Security, app.Extensions); err != nil {
+	if app.API, err = api.New(&app.Config.API, app.Logger, app.DB, app.Cache, app.Security, app.Extensions, app.Metrics, api.Services{
+		Contacts:      app.Contacts,
+		Dedupe:        app.Dedupe,
+		Relationships: app.Relationships,
+		Groups:        app.Groups,
+		Addresses:     app.Addresses,
+	}); err != nil {
 		return fmt.Errorf("failed to initialize API: %w", err)
 	}
 
@@ -107,6 +169,9 @@ func (app *App) Start() error {
 		return fmt.Errorf("failed to start API: %w", err)
 	}
 
+	// Start scheduled jobs
+	app.Jobs.Start()
+
 	app.Logger.Info("CiviCRM application started successfully")
 	return nil
 }
@@ -115,6 +180,9 @@ func (app *App) Start() error {
 func (app *App) Stop(ctx context.Context) error {
 	app.Logger.Info("Stopping CiviCRM application")
 
+	// Stop scheduled jobs
+	app.Jobs.Stop()
+
 	// Stop API server
 	if err := app.API.Stop(ctx); err != nil {
 		app.Logger.Error("Failed to stop API server", "error", err)
@@ -135,6 +203,11 @@ func (app *App) Stop(ctx context.Context) error {
 		app.Logger.Error("Failed to close cache", "error", err)
 	}
 
+	// Flush the remaining spans
+	if err := app.stopTracing(ctx); err != nil {
+		app.Logger.Error("Failed to stop tracing", "error", err)
+	}
+
 	app.cancel()
 	app.Logger.Info("CiviCRM application stopped")
 	return nil
@@ -164,9 +237,18 @@ func (app *App) registerServices() {
 	// Register core services as singletons
 	app.Container.RegisterInstance((*config.Config)(nil), app.Config)
 	app.Container.RegisterInstance((*logger.Logger)(nil), app.Logger)
+	app.Container.RegisterInstance((*metrics.Registry)(nil), app.Metrics)
 	app.Container.RegisterInstance((*database.Database)(nil), app.DB)
 	app.Container.RegisterInstance((*cache.Manager)(nil), app.Cache)
 	app.Container.RegisterInstance((*security.Manager)(nil), app.Security)
+	app.Container.RegisterInstance((*settings.Service)(nil), app.Settings)
+	app.Container.RegisterInstance((*contacts.Service)(nil), app.Contacts)
+	app.Container.RegisterInstance((*dedupe.Service)(nil), app.Dedupe)
+	app.Container.RegisterInstance((*relationships.Service)(nil), app.Relationships)
+	app.Container.RegisterInstance((*groups.Service)(nil), app.Groups)
+	app.Container.RegisterInstance((*geocode.Service)(nil), app.Geocode)
+	app.Container.RegisterInstance((*addresses.Service)(nil), app.Addresses)
+	app.Container.RegisterInstance((*jobs.Scheduler)(nil), app.Jobs)
 	app.Container.RegisterInstance((*extensions.Manager)(nil), app.Extensions)
 	app.Container.RegisterInstance((*api.Server)(nil), app.API)
 }
@@ -3,20 +3,31 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
 	"github.com/jxlxx/civicrm/internal/config"
+	generated "github.com/jxlxx/civicrm/internal/database/generated"
 	"github.com/jxlxx/civicrm/internal/logger"
 	_ "github.com/lib/pq"
 )
 
+// ErrUnsupportedDriver is returned for database drivers the schema and
+// generated queries do not support. The migrations rely on PostgreSQL types,
+// functions and triggers, and sqlc only generates PostgreSQL queries.
+var ErrUnsupportedDriver = errors.New("unsupported database driver")
+
 // Database represents the database connection manager
 type Database struct {
-	config *config.DatabaseConfig
-	db     *sql.DB
-	logger *logger.Logger
+	config   *config.DatabaseConfig
+	db       *sql.DB
+	logger   *logger.Logger
+	replicas []*replica
+	next     atomic.Uint64
+	stop     chan struct{}
+	checked  chan struct{}
 }
 
 // New creates a new database connection
@@ -26,13 +37,13 @@ func New(config *config.DatabaseConfig) (*Database, error) {
 	}
 
 	// Build connection string
-	dsn, err := db.buildDSN()
+	dsn, err := buildDSN(config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build DSN: %w", err)
 	}
 
 	// Open database connection
-	sqlDB, err := sql.Open(config.Driver, dsn)
+	sqlDB, err := open(config.Driver, dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -51,27 +62,42 @@ func New(config *config.DatabaseConfig) (*Database, error) {
 	}
 
 	db.db = sqlDB
+
+	// Open read replicas and keep checking their health
+	if db.replicas, err = openReplicas(config); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+	if len(db.replicas) > 0 && config.ReplicaCheckInterval > 0 {
+		db.stop = make(chan struct{})
+		db.checked = make(chan struct{})
+		go db.checkReplicas(config.ReplicaCheckInterval)
+	}
+
 	return db, nil
 }
 
 // buildDSN builds the database connection string
-func (db *Database) buildDSN() (string, error) {
-	switch db.config.Driver {
+func buildDSN(config *config.DatabaseConfig) (string, error) {
+	switch config.Driver {
 	case "postgres":
 		return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-			db.config.Host, db.config.Port, db.config.Username, db.config.Password,
-			db.config.Database, db.config.SSLMode), nil
+			config.Host, config.Port, config.Username, config.Password,
+			config.Database, config.SSLMode), nil
 	case "mysql":
-		return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true&timeout=%s",
-			db.config.Username, db.config.Password, db.config.Host, db.config.Port,
-			db.config.Database, db.config.Timeout), nil
+		return "", fmt.Errorf("%w: mysql is not supported, the schema requires PostgreSQL 13 or later", ErrUnsupportedDriver)
 	default:
-		return "", fmt.Errorf("unsupported database driver: %s", db.config.Driver)
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedDriver, config.Driver)
 	}
 }
 
-// Close closes the database connection
+// Close closes the primary and replica connections
 func (db *Database) Close() error {
+	if db.stop != nil {
+		close(db.stop)
+		<-db.checked
+	}
+	closeReplicas(db.replicas)
 	if db.db != nil {
 		return db.db.Close()
 	}
@@ -83,29 +109,50 @@ func (db *Database) Ping(ctx context.Context) error {
 	return db.db.PingContext(ctx)
 }
 
-// Begin starts a new transaction
+// Begin starts a new transaction on the primary
 func (db *Database) Begin(ctx context.Context) (*sql.Tx, error) {
-	return db.db.BeginTx(ctx, nil)
+	return db.beginTx(ctx, nil)
 }
 
 // Exec executes a query without returning rows
 func (db *Database) Exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
-	return db.db.ExecContext(ctx, query, args...)
+	return db.primary().ExecContext(ctx, query, args...)
 }
 
 // Query executes a query that returns rows
 func (db *Database) Query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
-	return db.db.QueryContext(ctx, query, args...)
+	return db.primary().QueryContext(ctx, query, args...)
 }
 
 // QueryRow executes a query that returns a single row
 func (db *Database) QueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
-	return db.db.QueryRowContext(ctx, query, args...)
+	return db.primary().QueryRowContext(ctx, query, args...)
 }
 
 // Prepare creates a prepared statement
 func (db *Database) Prepare(ctx context.Context, query string) (*sql.Stmt, error) {
-	return db.db.PrepareContext(ctx, query)
+	return db.primary().PrepareContext(ctx, query)
+}
+
+// beginTx starts a transaction on the primary
+func (db *Database) beginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	markWrite(ctx)
+	return db.db.BeginTx(ctx, opts)
+}
+
+// Querier returns the generated query interface bound to the primary
+func (db *Database) Querier() generated.Querier {
+	return generated.New(db.primary())
+}
+
+// primary returns the instrumented primary pool
+func (db *Database) primary() generated.DBTX {
+	return db.instrument(primaryDBTX{db.db}, poolPrimary)
+}
+
+// DB returns the underlying connection pool
+func (db *Database) DB() *sql.DB {
+	return db.db
 }
 
 // Stats returns database statistics
@@ -118,7 +165,8 @@ func (db *Database) SetLogger(logger *logger.Logger) {
 	db.logger = logger
 }
 
-// Transaction executes a function within a transaction
+// Transaction executes a function within a transaction. Prefer WithTx,
+// which also provides the generated querier and retries.
 func (db *Database) Transaction(ctx context.Context, fn func(*sql.Tx) error) error {
 	tx, err := db.Begin(ctx)
 	if err != nil {
@@ -127,11 +175,7 @@ func (db *Database) Transaction(ctx context.Context, fn func(*sql.Tx) error) err
 
 	defer func() {
 		if p := recover(); p != nil {
-			if rollbackErr := tx.Rollback(); rollbackErr != nil {
-				// Log the rollback error but still panic with the original panic
-				// In a real implementation, you might want to log this error
-				_ = rollbackErr // Suppress unused variable warning
-			}
+			db.rollback(tx.Rollback, "transaction")
 			panic(p)
 		}
 	}()
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/config"
 	db "github.com/jxlxx/civicrm/internal/database/generated"
 	"github.com/stretchr/testify/require"
 )
@@ -18,7 +19,6 @@ func TestGeneratedTypes(t *testing.T) {
 		ContactType: "Individual",
 		FirstName:   sql.NullString{String: "John", Valid: true},
 		LastName:    sql.NullString{String: "Doe", Valid: true},
-		Email:       sql.NullString{String: "john.doe@example.com", Valid: true},
 		CreatedAt:   sql.NullTime{Time: time.Now(), Valid: true},
 		UpdatedAt:   sql.NullTime{Time: time.Now(), Valid: true},
 	}
@@ -61,7 +61,6 @@ func TestParameterStructs(t *testing.T) {
 		ContactType: "Individual",
 		FirstName:   sql.NullString{String: "Jane", Valid: true},
 		LastName:    sql.NullString{String: "Smith", Valid: true},
-		Email:       sql.NullString{String: "jane.smith@example.com", Valid: true},
 	}
 
 	require.Equal(t, "Individual", createParams.ContactType)
@@ -155,3 +154,12 @@ func TestActivitiesTable(t *testing.T) {
 	require.Equal(t, int32(60), createParams.Duration.Int32)
 	require.True(t, createParams.IsCurrentRevision.Bool)
 }
+
+// TestNewRejectsUnsupportedDrivers tests that drivers other than postgres
+// fail before any connection is attempted
+func TestNewRejectsUnsupportedDrivers(t *testing.T) {
+	for _, driver := range []string{"mysql", "sqlite"} {
+		_, err := New(&config.DatabaseConfig{Driver: driver, Host: "localhost", Database: "civicrm"})
+		require.ErrorIs(t, err, ErrUnsupportedDriver, driver)
+	}
+}
@@ -0,0 +1,44 @@
+// Package dbtest helps test services against fake queriers: a transactor
+// that runs units of work directly on the fake, and builders for the
+// nullable values of generated rows.
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+)
+
+// Tx is a database.Transactor that runs units of work directly on Q,
+// without a transaction
+type Tx struct {
+	Q db.Querier
+}
+
+// WithTx calls fn with Q
+func (t Tx) WithTx(ctx context.Context, fn func(q db.Querier) error) error {
+	return fn(t.Q)
+}
+
+// Text returns s, or NULL when s is blank
+func Text(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// UUID returns id, or NULL when id is uuid.Nil
+func UUID(id uuid.UUID) uuid.NullUUID {
+	return uuid.NullUUID{UUID: id, Valid: id != uuid.Nil}
+}
+
+// Time returns t, or NULL when t is zero
+func Time(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// Bool returns b as a set boolean
+func Bool(b bool) sql.NullBool {
+	return sql.NullBool{Bool: b, Valid: true}
+}
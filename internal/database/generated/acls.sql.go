@@ -10,6 +10,7 @@ import (
 	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const CheckUserPermission = `-- name: CheckUserPermission :one
@@ -133,7 +134,7 @@ INSERT INTO acl_contact_cache (
     user_id, contact_id, operation, domain_id
 ) VALUES (
     $1, $2, $3, $4
-) RETURNING id, user_id, contact_id, operation, domain_id, created_at
+) RETURNING id, user_id, contact_id, operation, domain_id, created_at, relationship_id
 `
 
 type CreateACLContactCacheParams struct {
@@ -158,6 +159,7 @@ func (q *Queries) CreateACLContactCache(ctx context.Context, arg CreateACLContac
 		&i.Operation,
 		&i.DomainID,
 		&i.CreatedAt,
+		&i.RelationshipID,
 	)
 	return i, err
 }
@@ -366,7 +368,7 @@ func (q *Queries) GetACLCache(ctx context.Context, contactID uuid.NullUUID) ([]A
 }
 
 const GetACLContactCache = `-- name: GetACLContactCache :many
-SELECT id, user_id, contact_id, operation, domain_id, created_at FROM acl_contact_cache
+SELECT id, user_id, contact_id, operation, domain_id, created_at, relationship_id FROM acl_contact_cache
 WHERE user_id = $1 AND operation = $2
 `
 
@@ -391,6 +393,7 @@ func (q *Queries) GetACLContactCache(ctx context.Context, arg GetACLContactCache
 			&i.Operation,
 			&i.DomainID,
 			&i.CreatedAt,
+			&i.RelationshipID,
 		); err != nil {
 			return nil, err
 		}
@@ -406,7 +409,7 @@ func (q *Queries) GetACLContactCache(ctx context.Context, arg GetACLContactCache
 }
 
 const GetACLContactCacheByContact = `-- name: GetACLContactCacheByContact :many
-SELECT id, user_id, contact_id, operation, domain_id, created_at FROM acl_contact_cache
+SELECT id, user_id, contact_id, operation, domain_id, created_at, relationship_id FROM acl_contact_cache
 WHERE contact_id = $1 AND operation = $2
 `
 
@@ -431,6 +434,7 @@ func (q *Queries) GetACLContactCacheByContact(ctx context.Context, arg GetACLCon
 			&i.Operation,
 			&i.DomainID,
 			&i.CreatedAt,
+			&i.RelationshipID,
 		); err != nil {
 			return nil, err
 		}
@@ -538,7 +542,7 @@ func (q *Queries) GetACLStats(ctx context.Context) (GetACLStatsRow, error) {
 }
 
 const GetContactsForUser = `-- name: GetContactsForUser :many
-SELECT DISTINCT c.id, c.contact_type, c.first_name, c.last_name, c.organization_name, c.email, c.phone, c.address_line_1, c.address_line_2, c.city, c.state_province, c.postal_code, c.country, c.created_at, c.updated_at FROM contacts c
+SELECT DISTINCT c.id, c.contact_type, c.first_name, c.last_name, c.organization_name, c.created_at, c.updated_at, c.domain_id, c.is_deleted, c.merged_to_id, c.prefix, c.suffix, c.nick_name, c.household_name, c.display_name, c.sort_name, c.contact_sub_type, c.employer_id, c.anonymized_at FROM contacts c
 INNER JOIN acl_contact_cache acc ON c.id = acc.contact_id
 WHERE acc.user_id = $1 
 AND acc.operation = $2
@@ -566,16 +570,20 @@ func (q *Queries) GetContactsForUser(ctx context.Context, arg GetContactsForUser
 			&i.FirstName,
 			&i.LastName,
 			&i.OrganizationName,
-			&i.Email,
-			&i.Phone,
-			&i.AddressLine1,
-			&i.AddressLine2,
-			&i.City,
-			&i.StateProvince,
-			&i.PostalCode,
-			&i.Country,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
+			&i.IsDeleted,
+			&i.MergedToID,
+			&i.Prefix,
+			&i.Suffix,
+			&i.NickName,
+			&i.HouseholdName,
+			&i.DisplayName,
+			&i.SortName,
+			pq.Array(&i.ContactSubType),
+			&i.EmployerID,
+			&i.AnonymizedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -591,7 +599,7 @@ func (q *Queries) GetContactsForUser(ctx context.Context, arg GetContactsForUser
 }
 
 const GetEventsForUser = `-- name: GetEventsForUser :many
-SELECT DISTINCT e.id, e.title, e.description, e.start_date, e.end_date, e.location, e.max_participants, e.created_at, e.updated_at FROM events e
+SELECT DISTINCT e.id, e.title, e.description, e.start_date, e.end_date, e.location, e.max_participants, e.created_at, e.updated_at, e.domain_id FROM events e
 INNER JOIN acl_contact_cache acc ON e.id = acc.contact_id
 WHERE acc.user_id = $1 
 AND acc.operation = $2
@@ -623,6 +631,7 @@ func (q *Queries) GetEventsForUser(ctx context.Context, arg GetEventsForUserPara
 			&i.MaxParticipants,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
 		); err != nil {
 			return nil, err
 		}
@@ -962,6 +971,58 @@ func (q *Queries) ListACLEntityRolesByRole(ctx context.Context, aclRoleID uuid.U
 	return items, nil
 }
 
+const ListACLGroupPermittedContacts = `-- name: ListACLGroupPermittedContacts :many
+WITH role_acls AS (
+    SELECT a.object_id, a.deny FROM acls a
+    INNER JOIN acl_entity_roles aer ON a.entity_id = aer.acl_role_id
+    WHERE aer.entity_table = 'users'
+    AND aer.entity_id = $1
+    AND a.entity_table = 'acl_roles'
+    AND a.object_table = 'groups'
+    AND a.operation = $2
+    AND a.is_active = true
+    AND aer.is_active = true
+)
+SELECT gm.contact_id FROM role_acls ra
+JOIN group_members gm ON gm.group_id = ra.object_id
+WHERE NOT ra.deny
+EXCEPT
+SELECT gm.contact_id FROM role_acls ra
+JOIN group_members gm ON gm.group_id = ra.object_id
+WHERE ra.deny
+`
+
+type ListACLGroupPermittedContactsParams struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Operation string    `json:"operation"`
+}
+
+// Contacts a user may access through their roles' ACLs on groups, including
+// members of smart and nested groups. Deny rules on a group win over allow
+// rules.
+func (q *Queries) ListACLGroupPermittedContacts(ctx context.Context, arg ListACLGroupPermittedContactsParams) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, ListACLGroupPermittedContacts, arg.UserID, arg.Operation)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []uuid.UUID{}
+	for rows.Next() {
+		var contact_id uuid.UUID
+		if err := rows.Scan(&contact_id); err != nil {
+			return nil, err
+		}
+		items = append(items, contact_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const ListACLRoles = `-- name: ListACLRoles :many
 SELECT id, name, label, description, is_active, created_at, updated_at FROM acl_roles
 WHERE is_active = true
@@ -69,7 +69,7 @@ INSERT INTO activities (
     original_id, result, is_deleted, campaign_id, engagement_level, weight, is_star
 ) VALUES (
     $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23
-) RETURNING id, activity_type_id, subject, activity_date_time, duration, location, phone_id, phone_number, details, status_id, priority_id, parent_id, is_test, medium_id, is_auto, relationship_id, is_current_revision, original_id, result, is_deleted, campaign_id, engagement_level, weight, is_star, created_date, modified_date, created_at, updated_at
+) RETURNING id, activity_type_id, subject, activity_date_time, duration, location, phone_id, phone_number, details, status_id, priority_id, parent_id, is_test, medium_id, is_auto, relationship_id, is_current_revision, original_id, result, is_deleted, campaign_id, engagement_level, weight, is_star, created_date, modified_date, created_at, updated_at, domain_id
 `
 
 type CreateActivityParams struct {
@@ -154,6 +154,7 @@ func (q *Queries) CreateActivity(ctx context.Context, arg CreateActivityParams)
 		&i.ModifiedDate,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DomainID,
 	)
 	return i, err
 }
@@ -168,7 +169,7 @@ func (q *Queries) DeleteActivity(ctx context.Context, id uuid.UUID) error {
 }
 
 const GetActivitiesByCampaign = `-- name: GetActivitiesByCampaign :many
-SELECT id, activity_type_id, subject, activity_date_time, duration, location, phone_id, phone_number, details, status_id, priority_id, parent_id, is_test, medium_id, is_auto, relationship_id, is_current_revision, original_id, result, is_deleted, campaign_id, engagement_level, weight, is_star, created_date, modified_date, created_at, updated_at FROM activities 
+SELECT id, activity_type_id, subject, activity_date_time, duration, location, phone_id, phone_number, details, status_id, priority_id, parent_id, is_test, medium_id, is_auto, relationship_id, is_current_revision, original_id, result, is_deleted, campaign_id, engagement_level, weight, is_star, created_date, modified_date, created_at, updated_at, domain_id FROM activities 
 WHERE campaign_id = $1 AND is_deleted = FALSE
 ORDER BY activity_date_time DESC
 `
@@ -211,6 +212,7 @@ func (q *Queries) GetActivitiesByCampaign(ctx context.Context, campaignID uuid.N
 			&i.ModifiedDate,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
 		); err != nil {
 			return nil, err
 		}
@@ -226,7 +228,7 @@ func (q *Queries) GetActivitiesByCampaign(ctx context.Context, campaignID uuid.N
 }
 
 const GetActivitiesByContact = `-- name: GetActivitiesByContact :many
-SELECT a.id, a.activity_type_id, a.subject, a.activity_date_time, a.duration, a.location, a.phone_id, a.phone_number, a.details, a.status_id, a.priority_id, a.parent_id, a.is_test, a.medium_id, a.is_auto, a.relationship_id, a.is_current_revision, a.original_id, a.result, a.is_deleted, a.campaign_id, a.engagement_level, a.weight, a.is_star, a.created_date, a.modified_date, a.created_at, a.updated_at FROM activities a
+SELECT a.id, a.activity_type_id, a.subject, a.activity_date_time, a.duration, a.location, a.phone_id, a.phone_number, a.details, a.status_id, a.priority_id, a.parent_id, a.is_test, a.medium_id, a.is_auto, a.relationship_id, a.is_current_revision, a.original_id, a.result, a.is_deleted, a.campaign_id, a.engagement_level, a.weight, a.is_star, a.created_date, a.modified_date, a.created_at, a.updated_at, a.domain_id FROM activities a
 JOIN activity_contacts ac ON a.id = ac.activity_id
 WHERE ac.contact_id = $1 AND a.is_deleted = FALSE
 ORDER BY a.activity_date_time DESC
@@ -270,6 +272,7 @@ func (q *Queries) GetActivitiesByContact(ctx context.Context, contactID uuid.UUI
 			&i.ModifiedDate,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
 		); err != nil {
 			return nil, err
 		}
@@ -285,7 +288,7 @@ func (q *Queries) GetActivitiesByContact(ctx context.Context, contactID uuid.UUI
 }
 
 const GetActivitiesByDateRange = `-- name: GetActivitiesByDateRange :many
-SELECT id, activity_type_id, subject, activity_date_time, duration, location, phone_id, phone_number, details, status_id, priority_id, parent_id, is_test, medium_id, is_auto, relationship_id, is_current_revision, original_id, result, is_deleted, campaign_id, engagement_level, weight, is_star, created_date, modified_date, created_at, updated_at FROM activities 
+SELECT id, activity_type_id, subject, activity_date_time, duration, location, phone_id, phone_number, details, status_id, priority_id, parent_id, is_test, medium_id, is_auto, relationship_id, is_current_revision, original_id, result, is_deleted, campaign_id, engagement_level, weight, is_star, created_date, modified_date, created_at, updated_at, domain_id FROM activities 
 WHERE activity_date_time BETWEEN $1 AND $2 AND is_deleted = FALSE
 ORDER BY activity_date_time DESC
 `
@@ -333,6 +336,7 @@ func (q *Queries) GetActivitiesByDateRange(ctx context.Context, arg GetActivitie
 			&i.ModifiedDate,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
 		); err != nil {
 			return nil, err
 		}
@@ -348,7 +352,7 @@ func (q *Queries) GetActivitiesByDateRange(ctx context.Context, arg GetActivitie
 }
 
 const GetActivitiesByPriority = `-- name: GetActivitiesByPriority :many
-SELECT id, activity_type_id, subject, activity_date_time, duration, location, phone_id, phone_number, details, status_id, priority_id, parent_id, is_test, medium_id, is_auto, relationship_id, is_current_revision, original_id, result, is_deleted, campaign_id, engagement_level, weight, is_star, created_date, modified_date, created_at, updated_at FROM activities 
+SELECT id, activity_type_id, subject, activity_date_time, duration, location, phone_id, phone_number, details, status_id, priority_id, parent_id, is_test, medium_id, is_auto, relationship_id, is_current_revision, original_id, result, is_deleted, campaign_id, engagement_level, weight, is_star, created_date, modified_date, created_at, updated_at, domain_id FROM activities 
 WHERE priority_id = $1 AND is_deleted = FALSE
 ORDER BY activity_date_time DESC
 `
@@ -391,6 +395,7 @@ func (q *Queries) GetActivitiesByPriority(ctx context.Context, priorityID uuid.N
 			&i.ModifiedDate,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
 		); err != nil {
 			return nil, err
 		}
@@ -406,7 +411,7 @@ func (q *Queries) GetActivitiesByPriority(ctx context.Context, priorityID uuid.N
 }
 
 const GetActivitiesByStatus = `-- name: GetActivitiesByStatus :many
-SELECT id, activity_type_id, subject, activity_date_time, duration, location, phone_id, phone_number, details, status_id, priority_id, parent_id, is_test, medium_id, is_auto, relationship_id, is_current_revision, original_id, result, is_deleted, campaign_id, engagement_level, weight, is_star, created_date, modified_date, created_at, updated_at FROM activities 
+SELECT id, activity_type_id, subject, activity_date_time, duration, location, phone_id, phone_number, details, status_id, priority_id, parent_id, is_test, medium_id, is_auto, relationship_id, is_current_revision, original_id, result, is_deleted, campaign_id, engagement_level, weight, is_star, created_date, modified_date, created_at, updated_at, domain_id FROM activities 
 WHERE status_id = $1 AND is_deleted = FALSE
 ORDER BY activity_date_time DESC
 `
@@ -449,6 +454,7 @@ func (q *Queries) GetActivitiesByStatus(ctx context.Context, statusID uuid.NullU
 			&i.ModifiedDate,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
 		); err != nil {
 			return nil, err
 		}
@@ -464,7 +470,7 @@ func (q *Queries) GetActivitiesByStatus(ctx context.Context, statusID uuid.NullU
 }
 
 const GetActivitiesByType = `-- name: GetActivitiesByType :many
-SELECT id, activity_type_id, subject, activity_date_time, duration, location, phone_id, phone_number, details, status_id, priority_id, parent_id, is_test, medium_id, is_auto, relationship_id, is_current_revision, original_id, result, is_deleted, campaign_id, engagement_level, weight, is_star, created_date, modified_date, created_at, updated_at FROM activities 
+SELECT id, activity_type_id, subject, activity_date_time, duration, location, phone_id, phone_number, details, status_id, priority_id, parent_id, is_test, medium_id, is_auto, relationship_id, is_current_revision, original_id, result, is_deleted, campaign_id, engagement_level, weight, is_star, created_date, modified_date, created_at, updated_at, domain_id FROM activities 
 WHERE activity_type_id = $1 AND is_deleted = FALSE
 ORDER BY activity_date_time DESC
 `
@@ -507,6 +513,7 @@ func (q *Queries) GetActivitiesByType(ctx context.Context, activityTypeID uuid.U
 			&i.ModifiedDate,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
 		); err != nil {
 			return nil, err
 		}
@@ -522,7 +529,7 @@ func (q *Queries) GetActivitiesByType(ctx context.Context, activityTypeID uuid.U
 }
 
 const GetActivity = `-- name: GetActivity :one
-SELECT id, activity_type_id, subject, activity_date_time, duration, location, phone_id, phone_number, details, status_id, priority_id, parent_id, is_test, medium_id, is_auto, relationship_id, is_current_revision, original_id, result, is_deleted, campaign_id, engagement_level, weight, is_star, created_date, modified_date, created_at, updated_at FROM activities WHERE id = $1
+SELECT id, activity_type_id, subject, activity_date_time, duration, location, phone_id, phone_number, details, status_id, priority_id, parent_id, is_test, medium_id, is_auto, relationship_id, is_current_revision, original_id, result, is_deleted, campaign_id, engagement_level, weight, is_star, created_date, modified_date, created_at, updated_at, domain_id FROM activities WHERE id = $1
 `
 
 func (q *Queries) GetActivity(ctx context.Context, id uuid.UUID) (Activity, error) {
@@ -557,12 +564,13 @@ func (q *Queries) GetActivity(ctx context.Context, id uuid.UUID) (Activity, erro
 		&i.ModifiedDate,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DomainID,
 	)
 	return i, err
 }
 
 const GetActivityBySubject = `-- name: GetActivityBySubject :one
-SELECT id, activity_type_id, subject, activity_date_time, duration, location, phone_id, phone_number, details, status_id, priority_id, parent_id, is_test, medium_id, is_auto, relationship_id, is_current_revision, original_id, result, is_deleted, campaign_id, engagement_level, weight, is_star, created_date, modified_date, created_at, updated_at FROM activities WHERE subject = $1 LIMIT 1
+SELECT id, activity_type_id, subject, activity_date_time, duration, location, phone_id, phone_number, details, status_id, priority_id, parent_id, is_test, medium_id, is_auto, relationship_id, is_current_revision, original_id, result, is_deleted, campaign_id, engagement_level, weight, is_star, created_date, modified_date, created_at, updated_at, domain_id FROM activities WHERE subject = $1 LIMIT 1
 `
 
 func (q *Queries) GetActivityBySubject(ctx context.Context, subject sql.NullString) (Activity, error) {
@@ -597,12 +605,13 @@ func (q *Queries) GetActivityBySubject(ctx context.Context, subject sql.NullStri
 		&i.ModifiedDate,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DomainID,
 	)
 	return i, err
 }
 
 const GetPastActivities = `-- name: GetPastActivities :many
-SELECT id, activity_type_id, subject, activity_date_time, duration, location, phone_id, phone_number, details, status_id, priority_id, parent_id, is_test, medium_id, is_auto, relationship_id, is_current_revision, original_id, result, is_deleted, campaign_id, engagement_level, weight, is_star, created_date, modified_date, created_at, updated_at FROM activities 
+SELECT id, activity_type_id, subject, activity_date_time, duration, location, phone_id, phone_number, details, status_id, priority_id, parent_id, is_test, medium_id, is_auto, relationship_id, is_current_revision, original_id, result, is_deleted, campaign_id, engagement_level, weight, is_star, created_date, modified_date, created_at, updated_at, domain_id FROM activities 
 WHERE activity_date_time < NOW() AND is_deleted = FALSE
 ORDER BY activity_date_time DESC
 `
@@ -645,6 +654,7 @@ func (q *Queries) GetPastActivities(ctx context.Context) ([]Activity, error) {
 			&i.ModifiedDate,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
 		); err != nil {
 			return nil, err
 		}
@@ -660,7 +670,7 @@ func (q *Queries) GetPastActivities(ctx context.Context) ([]Activity, error) {
 }
 
 const GetUpcomingActivities = `-- name: GetUpcomingActivities :many
-SELECT id, activity_type_id, subject, activity_date_time, duration, location, phone_id, phone_number, details, status_id, priority_id, parent_id, is_test, medium_id, is_auto, relationship_id, is_current_revision, original_id, result, is_deleted, campaign_id, engagement_level, weight, is_star, created_date, modified_date, created_at, updated_at FROM activities 
+SELECT id, activity_type_id, subject, activity_date_time, duration, location, phone_id, phone_number, details, status_id, priority_id, parent_id, is_test, medium_id, is_auto, relationship_id, is_current_revision, original_id, result, is_deleted, campaign_id, engagement_level, weight, is_star, created_date, modified_date, created_at, updated_at, domain_id FROM activities 
 WHERE activity_date_time > NOW() AND is_deleted = FALSE
 ORDER BY activity_date_time
 `
@@ -703,6 +713,7 @@ func (q *Queries) GetUpcomingActivities(ctx context.Context) ([]Activity, error)
 			&i.ModifiedDate,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
 		); err != nil {
 			return nil, err
 		}
@@ -727,7 +738,7 @@ func (q *Queries) HardDeleteActivity(ctx context.Context, id uuid.UUID) error {
 }
 
 const ListAllActivities = `-- name: ListAllActivities :many
-SELECT id, activity_type_id, subject, activity_date_time, duration, location, phone_id, phone_number, details, status_id, priority_id, parent_id, is_test, medium_id, is_auto, relationship_id, is_current_revision, original_id, result, is_deleted, campaign_id, engagement_level, weight, is_star, created_date, modified_date, created_at, updated_at FROM activities 
+SELECT id, activity_type_id, subject, activity_date_time, duration, location, phone_id, phone_number, details, status_id, priority_id, parent_id, is_test, medium_id, is_auto, relationship_id, is_current_revision, original_id, result, is_deleted, campaign_id, engagement_level, weight, is_star, created_date, modified_date, created_at, updated_at, domain_id FROM activities 
 WHERE is_deleted = FALSE
 ORDER BY activity_date_time DESC
 `
@@ -770,6 +781,7 @@ func (q *Queries) ListAllActivities(ctx context.Context) ([]Activity, error) {
 			&i.ModifiedDate,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
 		); err != nil {
 			return nil, err
 		}
@@ -785,7 +797,7 @@ func (q *Queries) ListAllActivities(ctx context.Context) ([]Activity, error) {
 }
 
 const SearchActivities = `-- name: SearchActivities :many
-SELECT id, activity_type_id, subject, activity_date_time, duration, location, phone_id, phone_number, details, status_id, priority_id, parent_id, is_test, medium_id, is_auto, relationship_id, is_current_revision, original_id, result, is_deleted, campaign_id, engagement_level, weight, is_star, created_date, modified_date, created_at, updated_at FROM activities 
+SELECT id, activity_type_id, subject, activity_date_time, duration, location, phone_id, phone_number, details, status_id, priority_id, parent_id, is_test, medium_id, is_auto, relationship_id, is_current_revision, original_id, result, is_deleted, campaign_id, engagement_level, weight, is_star, created_date, modified_date, created_at, updated_at, domain_id FROM activities 
 WHERE (subject ILIKE $1 OR details ILIKE $1 OR location ILIKE $1)
 AND is_deleted = FALSE
 ORDER BY activity_date_time DESC
@@ -829,6 +841,7 @@ func (q *Queries) SearchActivities(ctx context.Context, subject sql.NullString)
 			&i.ModifiedDate,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
 		); err != nil {
 			return nil, err
 		}
@@ -851,7 +864,7 @@ UPDATE activities SET
     relationship_id = $16, is_current_revision = $17, original_id = $18, result = $19,
     is_deleted = $20, campaign_id = $21, engagement_level = $22, weight = $23,
     is_star = $24, updated_at = NOW()
-WHERE id = $1 RETURNING id, activity_type_id, subject, activity_date_time, duration, location, phone_id, phone_number, details, status_id, priority_id, parent_id, is_test, medium_id, is_auto, relationship_id, is_current_revision, original_id, result, is_deleted, campaign_id, engagement_level, weight, is_star, created_date, modified_date, created_at, updated_at
+WHERE id = $1 RETURNING id, activity_type_id, subject, activity_date_time, duration, location, phone_id, phone_number, details, status_id, priority_id, parent_id, is_test, medium_id, is_auto, relationship_id, is_current_revision, original_id, result, is_deleted, campaign_id, engagement_level, weight, is_star, created_date, modified_date, created_at, updated_at, domain_id
 `
 
 type UpdateActivityParams struct {
@@ -938,6 +951,7 @@ func (q *Queries) UpdateActivity(ctx context.Context, arg UpdateActivityParams)
 		&i.ModifiedDate,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DomainID,
 	)
 	return i, err
 }
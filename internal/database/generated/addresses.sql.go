@@ -0,0 +1,396 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: addresses.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const CreateSharedAddress = `-- name: CreateSharedAddress :one
+INSERT INTO addresses (contact_id, location_type_id, is_primary, master_id)
+VALUES ($1, $2, $3, $4)
+RETURNING id, contact_id, location_type_id, is_primary, is_billing, street_address, street_number, street_name, street_unit, city, state_province_id, postal_code, country_id, geo_code_1, geo_code_2, created_at, updated_at, supplemental_address_1, geocoded_at, manual_geo_code, master_id
+`
+
+type CreateSharedAddressParams struct {
+	ContactID      uuid.UUID     `json:"contact_id"`
+	LocationTypeID uuid.NullUUID `json:"location_type_id"`
+	IsPrimary      sql.NullBool  `json:"is_primary"`
+	MasterID       uuid.NullUUID `json:"master_id"`
+}
+
+// The fields are copied from the master by trigger
+func (q *Queries) CreateSharedAddress(ctx context.Context, arg CreateSharedAddressParams) (Address, error) {
+	row := q.db.QueryRowContext(ctx, CreateSharedAddress,
+		arg.ContactID,
+		arg.LocationTypeID,
+		arg.IsPrimary,
+		arg.MasterID,
+	)
+	var i Address
+	err := row.Scan(
+		&i.ID,
+		&i.ContactID,
+		&i.LocationTypeID,
+		&i.IsPrimary,
+		&i.IsBilling,
+		&i.StreetAddress,
+		&i.StreetNumber,
+		&i.StreetName,
+		&i.StreetUnit,
+		&i.City,
+		&i.StateProvinceID,
+		&i.PostalCode,
+		&i.CountryID,
+		&i.GeoCode1,
+		&i.GeoCode2,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SupplementalAddress1,
+		&i.GeocodedAt,
+		&i.ManualGeoCode,
+		&i.MasterID,
+	)
+	return i, err
+}
+
+const GetAddress = `-- name: GetAddress :one
+SELECT id, contact_id, location_type_id, is_primary, is_billing, street_address, street_number, street_name, street_unit, city, state_province_id, postal_code, country_id, geo_code_1, geo_code_2, created_at, updated_at, supplemental_address_1, geocoded_at, manual_geo_code, master_id FROM addresses WHERE id = $1
+`
+
+func (q *Queries) GetAddress(ctx context.Context, id uuid.UUID) (Address, error) {
+	row := q.db.QueryRowContext(ctx, GetAddress, id)
+	var i Address
+	err := row.Scan(
+		&i.ID,
+		&i.ContactID,
+		&i.LocationTypeID,
+		&i.IsPrimary,
+		&i.IsBilling,
+		&i.StreetAddress,
+		&i.StreetNumber,
+		&i.StreetName,
+		&i.StreetUnit,
+		&i.City,
+		&i.StateProvinceID,
+		&i.PostalCode,
+		&i.CountryID,
+		&i.GeoCode1,
+		&i.GeoCode2,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SupplementalAddress1,
+		&i.GeocodedAt,
+		&i.ManualGeoCode,
+		&i.MasterID,
+	)
+	return i, err
+}
+
+const GetAddressForUpdate = `-- name: GetAddressForUpdate :one
+SELECT id, contact_id, location_type_id, is_primary, is_billing, street_address, street_number, street_name, street_unit, city, state_province_id, postal_code, country_id, geo_code_1, geo_code_2, created_at, updated_at, supplemental_address_1, geocoded_at, manual_geo_code, master_id FROM addresses WHERE id = $1 FOR UPDATE
+`
+
+func (q *Queries) GetAddressForUpdate(ctx context.Context, id uuid.UUID) (Address, error) {
+	row := q.db.QueryRowContext(ctx, GetAddressForUpdate, id)
+	var i Address
+	err := row.Scan(
+		&i.ID,
+		&i.ContactID,
+		&i.LocationTypeID,
+		&i.IsPrimary,
+		&i.IsBilling,
+		&i.StreetAddress,
+		&i.StreetNumber,
+		&i.StreetName,
+		&i.StreetUnit,
+		&i.City,
+		&i.StateProvinceID,
+		&i.PostalCode,
+		&i.CountryID,
+		&i.GeoCode1,
+		&i.GeoCode2,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SupplementalAddress1,
+		&i.GeocodedAt,
+		&i.ManualGeoCode,
+		&i.MasterID,
+	)
+	return i, err
+}
+
+const GetStateProvince = `-- name: GetStateProvince :one
+SELECT id, name, abbreviation, country_id, is_active, created_at, updated_at FROM state_provinces WHERE id = $1
+`
+
+func (q *Queries) GetStateProvince(ctx context.Context, id uuid.UUID) (StateProvince, error) {
+	row := q.db.QueryRowContext(ctx, GetStateProvince, id)
+	var i StateProvince
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Abbreviation,
+		&i.CountryID,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const ListAddressesToParse = `-- name: ListAddressesToParse :many
+
+SELECT a.id, a.street_address, co.iso_code AS country_code
+FROM addresses a
+JOIN contacts c ON c.id = a.contact_id
+LEFT JOIN countries co ON co.id = a.country_id
+WHERE c.domain_id = $1
+  AND a.id > $2
+  AND a.street_address IS NOT NULL
+  AND a.master_id IS NULL
+  AND a.street_number IS NULL AND a.street_name IS NULL AND a.street_unit IS NULL
+ORDER BY a.id
+LIMIT $3
+`
+
+type ListAddressesToParseParams struct {
+	DomainID  uuid.UUID `json:"domain_id"`
+	AfterID   uuid.UUID `json:"after_id"`
+	BatchSize int32     `json:"batch_size"`
+}
+
+type ListAddressesToParseRow struct {
+	ID            uuid.UUID      `json:"id"`
+	StreetAddress sql.NullString `json:"street_address"`
+	CountryCode   sql.NullString `json:"country_code"`
+}
+
+// Address queries. The trigger from migration 049 clears the street parts
+// when street_address changes; those from migration 050 keep shared
+// addresses identical to their master.
+// Addresses of a domain's contacts with a street address that has not been
+// split, in ID order after after_id. Shared addresses take their master's
+// parts.
+func (q *Queries) ListAddressesToParse(ctx context.Context, arg ListAddressesToParseParams) ([]ListAddressesToParseRow, error) {
+	rows, err := q.db.QueryContext(ctx, ListAddressesToParse, arg.DomainID, arg.AfterID, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAddressesToParseRow{}
+	for rows.Next() {
+		var i ListAddressesToParseRow
+		if err := rows.Scan(&i.ID, &i.StreetAddress, &i.CountryCode); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListMailingAddresses = `-- name: ListMailingAddresses :many
+SELECT c.id AS contact_id, c.display_name, a.street_address, a.supplemental_address_1,
+       a.city, a.postal_code, sp.abbreviation AS state_province, co.name AS country,
+       co.iso_code AS country_code
+FROM contacts c
+JOIN addresses a ON a.contact_id = c.id AND a.is_primary
+LEFT JOIN state_provinces sp ON sp.id = a.state_province_id
+LEFT JOIN countries co ON co.id = a.country_id
+WHERE c.id = ANY($1::uuid[]) AND NOT c.is_deleted
+ORDER BY c.sort_name, c.id
+`
+
+type ListMailingAddressesRow struct {
+	ContactID            uuid.UUID      `json:"contact_id"`
+	DisplayName          sql.NullString `json:"display_name"`
+	StreetAddress        sql.NullString `json:"street_address"`
+	SupplementalAddress1 sql.NullString `json:"supplemental_address_1"`
+	City                 sql.NullString `json:"city"`
+	PostalCode           sql.NullString `json:"postal_code"`
+	StateProvince        sql.NullString `json:"state_province"`
+	Country              sql.NullString `json:"country"`
+	CountryCode          sql.NullString `json:"country_code"`
+}
+
+// The primary addresses of contacts, by sort name
+func (q *Queries) ListMailingAddresses(ctx context.Context, contactIds []uuid.UUID) ([]ListMailingAddressesRow, error) {
+	rows, err := q.db.QueryContext(ctx, ListMailingAddresses, pq.Array(contactIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListMailingAddressesRow{}
+	for rows.Next() {
+		var i ListMailingAddressesRow
+		if err := rows.Scan(
+			&i.ContactID,
+			&i.DisplayName,
+			&i.StreetAddress,
+			&i.SupplementalAddress1,
+			&i.City,
+			&i.PostalCode,
+			&i.StateProvince,
+			&i.Country,
+			&i.CountryCode,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const SetAddressStreetParts = `-- name: SetAddressStreetParts :execrows
+UPDATE addresses
+SET street_number = $1, street_name = $2, street_unit = $3,
+    updated_at = NOW()
+WHERE id = $4 AND street_address = $5
+`
+
+type SetAddressStreetPartsParams struct {
+	StreetNumber  sql.NullString `json:"street_number"`
+	StreetName    sql.NullString `json:"street_name"`
+	StreetUnit    sql.NullString `json:"street_unit"`
+	ID            uuid.UUID      `json:"id"`
+	StreetAddress sql.NullString `json:"street_address"`
+}
+
+// An address whose street changed since it was listed is left for the next
+// run
+func (q *Queries) SetAddressStreetParts(ctx context.Context, arg SetAddressStreetPartsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, SetAddressStreetParts,
+		arg.StreetNumber,
+		arg.StreetName,
+		arg.StreetUnit,
+		arg.ID,
+		arg.StreetAddress,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const UnshareAddress = `-- name: UnshareAddress :one
+UPDATE addresses SET master_id = NULL, updated_at = NOW()
+WHERE id = $1
+RETURNING id, contact_id, location_type_id, is_primary, is_billing, street_address, street_number, street_name, street_unit, city, state_province_id, postal_code, country_id, geo_code_1, geo_code_2, created_at, updated_at, supplemental_address_1, geocoded_at, manual_geo_code, master_id
+`
+
+func (q *Queries) UnshareAddress(ctx context.Context, id uuid.UUID) (Address, error) {
+	row := q.db.QueryRowContext(ctx, UnshareAddress, id)
+	var i Address
+	err := row.Scan(
+		&i.ID,
+		&i.ContactID,
+		&i.LocationTypeID,
+		&i.IsPrimary,
+		&i.IsBilling,
+		&i.StreetAddress,
+		&i.StreetNumber,
+		&i.StreetName,
+		&i.StreetUnit,
+		&i.City,
+		&i.StateProvinceID,
+		&i.PostalCode,
+		&i.CountryID,
+		&i.GeoCode1,
+		&i.GeoCode2,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SupplementalAddress1,
+		&i.GeocodedAt,
+		&i.ManualGeoCode,
+		&i.MasterID,
+	)
+	return i, err
+}
+
+const UpdateAddress = `-- name: UpdateAddress :one
+UPDATE addresses
+SET street_address = $1,
+    street_number = $2,
+    street_name = $3,
+    street_unit = $4,
+    supplemental_address_1 = $5,
+    city = $6,
+    state_province_id = $7,
+    postal_code = $8,
+    country_id = $9,
+    updated_at = NOW()
+WHERE id = $10
+RETURNING id, contact_id, location_type_id, is_primary, is_billing, street_address, street_number, street_name, street_unit, city, state_province_id, postal_code, country_id, geo_code_1, geo_code_2, created_at, updated_at, supplemental_address_1, geocoded_at, manual_geo_code, master_id
+`
+
+type UpdateAddressParams struct {
+	StreetAddress        sql.NullString `json:"street_address"`
+	StreetNumber         sql.NullString `json:"street_number"`
+	StreetName           sql.NullString `json:"street_name"`
+	StreetUnit           sql.NullString `json:"street_unit"`
+	SupplementalAddress1 sql.NullString `json:"supplemental_address_1"`
+	City                 sql.NullString `json:"city"`
+	StateProvinceID      uuid.NullUUID  `json:"state_province_id"`
+	PostalCode           sql.NullString `json:"postal_code"`
+	CountryID            uuid.NullUUID  `json:"country_id"`
+	ID                   uuid.UUID      `json:"id"`
+}
+
+func (q *Queries) UpdateAddress(ctx context.Context, arg UpdateAddressParams) (Address, error) {
+	row := q.db.QueryRowContext(ctx, UpdateAddress,
+		arg.StreetAddress,
+		arg.StreetNumber,
+		arg.StreetName,
+		arg.StreetUnit,
+		arg.SupplementalAddress1,
+		arg.City,
+		arg.StateProvinceID,
+		arg.PostalCode,
+		arg.CountryID,
+		arg.ID,
+	)
+	var i Address
+	err := row.Scan(
+		&i.ID,
+		&i.ContactID,
+		&i.LocationTypeID,
+		&i.IsPrimary,
+		&i.IsBilling,
+		&i.StreetAddress,
+		&i.StreetNumber,
+		&i.StreetName,
+		&i.StreetUnit,
+		&i.City,
+		&i.StateProvinceID,
+		&i.PostalCode,
+		&i.CountryID,
+		&i.GeoCode1,
+		&i.GeoCode2,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SupplementalAddress1,
+		&i.GeocodedAt,
+		&i.ManualGeoCode,
+		&i.MasterID,
+	)
+	return i, err
+}
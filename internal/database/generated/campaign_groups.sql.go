@@ -377,6 +377,52 @@ func (q *Queries) ListCampaignGroups(ctx context.Context) ([]CampaignGroup, erro
 	return items, nil
 }
 
+const ListCampaignTargetContacts = `-- name: ListCampaignTargetContacts :many
+SELECT DISTINCT c.id, c.contact_type, c.display_name, c.sort_name
+FROM campaign_groups cg
+JOIN group_members gm ON gm.group_id = cg.group_id
+JOIN contacts c ON c.id = gm.contact_id
+WHERE cg.campaign_id = $1 AND cg.is_active AND NOT c.is_deleted
+ORDER BY c.sort_name, c.id
+`
+
+type ListCampaignTargetContactsRow struct {
+	ID          uuid.UUID      `json:"id"`
+	ContactType string         `json:"contact_type"`
+	DisplayName sql.NullString `json:"display_name"`
+	SortName    sql.NullString `json:"sort_name"`
+}
+
+// Members of the campaign's active target groups, including smart and
+// nested groups
+func (q *Queries) ListCampaignTargetContacts(ctx context.Context, campaignID uuid.UUID) ([]ListCampaignTargetContactsRow, error) {
+	rows, err := q.db.QueryContext(ctx, ListCampaignTargetContacts, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListCampaignTargetContactsRow{}
+	for rows.Next() {
+		var i ListCampaignTargetContactsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ContactType,
+			&i.DisplayName,
+			&i.SortName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const SearchCampaignGroups = `-- name: SearchCampaignGroups :many
 SELECT cg.id, cg.campaign_id, cg.group_id, cg.is_active, cg.created_at, cg.updated_at FROM campaign_groups cg
 JOIN groups g ON cg.group_id = g.id
@@ -157,8 +157,8 @@ func (q *Queries) CreateMailing(ctx context.Context, arg CreateMailingParams) (M
 
 const CreateMailingList = `-- name: CreateMailingList :one
 INSERT INTO mailing_lists (
-    name, title, description, is_public, is_hidden, is_active
-) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, name, title, description, is_public, is_hidden, is_active, created_at, updated_at
+    name, title, description, is_public, is_hidden, is_active, group_id
+) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, name, title, description, is_public, is_hidden, is_active, created_at, updated_at, group_id
 `
 
 type CreateMailingListParams struct {
@@ -168,6 +168,7 @@ type CreateMailingListParams struct {
 	IsPublic    sql.NullBool   `json:"is_public"`
 	IsHidden    sql.NullBool   `json:"is_hidden"`
 	IsActive    sql.NullBool   `json:"is_active"`
+	GroupID     uuid.NullUUID  `json:"group_id"`
 }
 
 // Mailing Lists CRUD operations
@@ -179,6 +180,7 @@ func (q *Queries) CreateMailingList(ctx context.Context, arg CreateMailingListPa
 		arg.IsPublic,
 		arg.IsHidden,
 		arg.IsActive,
+		arg.GroupID,
 	)
 	var i MailingList
 	err := row.Scan(
@@ -191,6 +193,7 @@ func (q *Queries) CreateMailingList(ctx context.Context, arg CreateMailingListPa
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.GroupID,
 	)
 	return i, err
 }
@@ -654,7 +657,7 @@ func (q *Queries) GetMailing(ctx context.Context, id uuid.UUID) (Mailing, error)
 }
 
 const GetMailingList = `-- name: GetMailingList :one
-SELECT id, name, title, description, is_public, is_hidden, is_active, created_at, updated_at FROM mailing_lists WHERE id = $1
+SELECT id, name, title, description, is_public, is_hidden, is_active, created_at, updated_at, group_id FROM mailing_lists WHERE id = $1
 `
 
 func (q *Queries) GetMailingList(ctx context.Context, id uuid.UUID) (MailingList, error) {
@@ -670,12 +673,13 @@ func (q *Queries) GetMailingList(ctx context.Context, id uuid.UUID) (MailingList
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.GroupID,
 	)
 	return i, err
 }
 
 const GetMailingListByName = `-- name: GetMailingListByName :one
-SELECT id, name, title, description, is_public, is_hidden, is_active, created_at, updated_at FROM mailing_lists WHERE name = $1
+SELECT id, name, title, description, is_public, is_hidden, is_active, created_at, updated_at, group_id FROM mailing_lists WHERE name = $1
 `
 
 func (q *Queries) GetMailingListByName(ctx context.Context, name string) (MailingList, error) {
@@ -691,6 +695,7 @@ func (q *Queries) GetMailingListByName(ctx context.Context, name string) (Mailin
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.GroupID,
 	)
 	return i, err
 }
@@ -875,10 +880,11 @@ func (q *Queries) GetMessageTemplateByName(ctx context.Context, name string) (Me
 
 const GetOptOutContacts = `-- name: GetOptOutContacts :many
 SELECT 
-    c.id, c.first_name, c.last_name, c.email,
+    c.id, c.first_name, c.last_name, pe.email,
     cp.email_opt_out, cp.sms_opt_out, cp.mail_opt_out, cp.phone_opt_out
 FROM contacts c
 INNER JOIN communication_preferences cp ON c.id = cp.contact_id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE cp.email_opt_out = $1 OR cp.sms_opt_out = $2 OR cp.mail_opt_out = $3 OR cp.phone_opt_out = $4
 ORDER BY c.last_name, c.first_name
 LIMIT $5 OFFSET $6
@@ -1087,8 +1093,59 @@ func (q *Queries) GetTrackableUrl(ctx context.Context, id uuid.UUID) (MailingTra
 	return i, err
 }
 
+const ListMailingListRecipients = `-- name: ListMailingListRecipients :many
+SELECT c.id AS contact_id, c.display_name, pe.email
+FROM contacts c
+JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
+WHERE NOT c.is_deleted
+AND c.id IN (
+    SELECT mls.contact_id FROM mailing_list_subscriptions mls
+    WHERE mls.mailing_list_id = $1 AND mls.is_active AND mls.status = 'Subscribed'
+    UNION
+    SELECT gm.contact_id FROM group_members gm
+    JOIN mailing_lists ml ON ml.group_id = gm.group_id
+    WHERE ml.id = $1
+)
+AND NOT EXISTS (
+    SELECT 1 FROM mailing_list_subscriptions mls
+    WHERE mls.mailing_list_id = $1 AND mls.contact_id = c.id AND mls.status = 'Unsubscribed'
+)
+ORDER BY c.sort_name, c.id
+`
+
+type ListMailingListRecipientsRow struct {
+	ContactID   uuid.UUID      `json:"contact_id"`
+	DisplayName sql.NullString `json:"display_name"`
+	Email       string         `json:"email"`
+}
+
+// Active subscribers and members of the list's group and the groups nested
+// under it, except contacts who unsubscribed from the list
+func (q *Queries) ListMailingListRecipients(ctx context.Context, mailingListID uuid.UUID) ([]ListMailingListRecipientsRow, error) {
+	rows, err := q.db.QueryContext(ctx, ListMailingListRecipients, mailingListID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListMailingListRecipientsRow{}
+	for rows.Next() {
+		var i ListMailingListRecipientsRow
+		if err := rows.Scan(&i.ContactID, &i.DisplayName, &i.Email); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const ListMailingLists = `-- name: ListMailingLists :many
-SELECT id, name, title, description, is_public, is_hidden, is_active, created_at, updated_at FROM mailing_lists 
+SELECT id, name, title, description, is_public, is_hidden, is_active, created_at, updated_at, group_id FROM mailing_lists 
 WHERE is_active = $1 AND is_hidden = $2
 ORDER BY title
 `
@@ -1117,6 +1174,7 @@ func (q *Queries) ListMailingLists(ctx context.Context, arg ListMailingListsPara
 			&i.IsActive,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.GroupID,
 		); err != nil {
 			return nil, err
 		}
@@ -1326,9 +1384,10 @@ func (q *Queries) ListMessageTemplatesByWorkflow(ctx context.Context, arg ListMe
 }
 
 const ListRecipientsByMailing = `-- name: ListRecipientsByMailing :many
-SELECT mr.id, mr.mailing_id, mr.contact_id, mr.email, mr.status, mr.sent_date, mr.delivered_date, mr.opened_date, mr.clicked_date, mr.bounce_reason, mr.created_at, mr.updated_at, c.first_name, c.last_name, c.email as contact_email
+SELECT mr.id, mr.mailing_id, mr.contact_id, mr.email, mr.status, mr.sent_date, mr.delivered_date, mr.opened_date, mr.clicked_date, mr.bounce_reason, mr.created_at, mr.updated_at, c.first_name, c.last_name, pe.email as contact_email
 FROM mailing_recipients mr
 INNER JOIN contacts c ON mr.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE mr.mailing_id = $1
 ORDER BY c.last_name, c.first_name
 `
@@ -1493,9 +1552,10 @@ func (q *Queries) ListSubscriptionsByContact(ctx context.Context, arg ListSubscr
 }
 
 const ListSubscriptionsByList = `-- name: ListSubscriptionsByList :many
-SELECT mls.id, mls.mailing_list_id, mls.contact_id, mls.status, mls.source, mls.is_active, mls.created_at, mls.updated_at, c.first_name, c.last_name, c.email
+SELECT mls.id, mls.mailing_list_id, mls.contact_id, mls.status, mls.source, mls.is_active, mls.created_at, mls.updated_at, c.first_name, c.last_name, pe.email
 FROM mailing_list_subscriptions mls
 INNER JOIN contacts c ON mls.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE mls.mailing_list_id = $1 AND mls.is_active = $2
 ORDER BY c.last_name, c.first_name
 `
@@ -1658,8 +1718,9 @@ UPDATE mailing_lists SET
     is_public = $4,
     is_hidden = $5,
     is_active = $6,
+    group_id = $7,
     updated_at = NOW()
-WHERE id = $1 RETURNING id, name, title, description, is_public, is_hidden, is_active, created_at, updated_at
+WHERE id = $1 RETURNING id, name, title, description, is_public, is_hidden, is_active, created_at, updated_at, group_id
 `
 
 type UpdateMailingListParams struct {
@@ -1669,6 +1730,7 @@ type UpdateMailingListParams struct {
 	IsPublic    sql.NullBool   `json:"is_public"`
 	IsHidden    sql.NullBool   `json:"is_hidden"`
 	IsActive    sql.NullBool   `json:"is_active"`
+	GroupID     uuid.NullUUID  `json:"group_id"`
 }
 
 func (q *Queries) UpdateMailingList(ctx context.Context, arg UpdateMailingListParams) (MailingList, error) {
@@ -1679,6 +1741,7 @@ func (q *Queries) UpdateMailingList(ctx context.Context, arg UpdateMailingListPa
 		arg.IsPublic,
 		arg.IsHidden,
 		arg.IsActive,
+		arg.GroupID,
 	)
 	var i MailingList
 	err := row.Scan(
@@ -1691,6 +1754,7 @@ func (q *Queries) UpdateMailingList(ctx context.Context, arg UpdateMailingListPa
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.GroupID,
 	)
 	return i, err
 }
@@ -0,0 +1,182 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: contact_sub_types.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const CreateContactSubType = `-- name: CreateContactSubType :one
+
+INSERT INTO contact_sub_types (
+    name, label, parent, description
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING id, name, label, parent, description, is_active, created_at, updated_at
+`
+
+type CreateContactSubTypeParams struct {
+	Name        string         `json:"name"`
+	Label       string         `json:"label"`
+	Parent      string         `json:"parent"`
+	Description sql.NullString `json:"description"`
+}
+
+// Contact subtype queries
+func (q *Queries) CreateContactSubType(ctx context.Context, arg CreateContactSubTypeParams) (ContactSubType, error) {
+	row := q.db.QueryRowContext(ctx, CreateContactSubType,
+		arg.Name,
+		arg.Label,
+		arg.Parent,
+		arg.Description,
+	)
+	var i ContactSubType
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Label,
+		&i.Parent,
+		&i.Description,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const GetContactSubTypeByName = `-- name: GetContactSubTypeByName :one
+SELECT id, name, label, parent, description, is_active, created_at, updated_at FROM contact_sub_types WHERE name = $1
+`
+
+func (q *Queries) GetContactSubTypeByName(ctx context.Context, name string) (ContactSubType, error) {
+	row := q.db.QueryRowContext(ctx, GetContactSubTypeByName, name)
+	var i ContactSubType
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Label,
+		&i.Parent,
+		&i.Description,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const ListContactSubTypes = `-- name: ListContactSubTypes :many
+SELECT id, name, label, parent, description, is_active, created_at, updated_at FROM contact_sub_types
+WHERE is_active
+ORDER BY parent, label
+`
+
+func (q *Queries) ListContactSubTypes(ctx context.Context) ([]ContactSubType, error) {
+	rows, err := q.db.QueryContext(ctx, ListContactSubTypes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ContactSubType{}
+	for rows.Next() {
+		var i ContactSubType
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Label,
+			&i.Parent,
+			&i.Description,
+			&i.IsActive,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListContactSubTypesByName = `-- name: ListContactSubTypesByName :many
+SELECT id, name, label, parent, description, is_active, created_at, updated_at FROM contact_sub_types
+WHERE name = ANY($1::text[])
+`
+
+func (q *Queries) ListContactSubTypesByName(ctx context.Context, names []string) ([]ContactSubType, error) {
+	rows, err := q.db.QueryContext(ctx, ListContactSubTypesByName, pq.Array(names))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ContactSubType{}
+	for rows.Next() {
+		var i ContactSubType
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Label,
+			&i.Parent,
+			&i.Description,
+			&i.IsActive,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const UpdateContactSubType = `-- name: UpdateContactSubType :one
+UPDATE contact_sub_types
+SET label = $2, description = $3, is_active = $4, updated_at = NOW()
+WHERE id = $1
+RETURNING id, name, label, parent, description, is_active, created_at, updated_at
+`
+
+type UpdateContactSubTypeParams struct {
+	ID          uuid.UUID      `json:"id"`
+	Label       string         `json:"label"`
+	Description sql.NullString `json:"description"`
+	IsActive    bool           `json:"is_active"`
+}
+
+func (q *Queries) UpdateContactSubType(ctx context.Context, arg UpdateContactSubTypeParams) (ContactSubType, error) {
+	row := q.db.QueryRowContext(ctx, UpdateContactSubType,
+		arg.ID,
+		arg.Label,
+		arg.Description,
+		arg.IsActive,
+	)
+	var i ContactSubType
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Label,
+		&i.Parent,
+		&i.Description,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
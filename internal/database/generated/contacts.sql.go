@@ -10,10 +10,11 @@ import (
 	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const CountAllContacts = `-- name: CountAllContacts :one
-SELECT COUNT(*) FROM contacts
+SELECT COUNT(*) FROM contacts WHERE NOT is_deleted
 `
 
 func (q *Queries) CountAllContacts(ctx context.Context) (int64, error) {
@@ -24,7 +25,7 @@ func (q *Queries) CountAllContacts(ctx context.Context) (int64, error) {
 }
 
 const CountContacts = `-- name: CountContacts :one
-SELECT COUNT(*) FROM contacts WHERE contact_type = $1
+SELECT COUNT(*) FROM contacts WHERE contact_type = $1 AND NOT is_deleted
 `
 
 func (q *Queries) CountContacts(ctx context.Context, contactType string) (int64, error) {
@@ -36,43 +37,36 @@ func (q *Queries) CountContacts(ctx context.Context, contactType string) (int64,
 
 const CreateContact = `-- name: CreateContact :one
 INSERT INTO contacts (
-    contact_type, first_name, last_name, organization_name, 
-    email, phone, address_line_1, address_line_2, 
-    city, state_province, postal_code, country
+    contact_type, contact_sub_type, prefix, first_name, last_name, suffix,
+    nick_name, organization_name, household_name
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
-) RETURNING id, contact_type, first_name, last_name, organization_name, email, phone, address_line_1, address_line_2, city, state_province, postal_code, country, created_at, updated_at
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
+) RETURNING id, contact_type, first_name, last_name, organization_name, created_at, updated_at, domain_id, is_deleted, merged_to_id, prefix, suffix, nick_name, household_name, display_name, sort_name, contact_sub_type, employer_id, anonymized_at
 `
 
 type CreateContactParams struct {
 	ContactType      string         `json:"contact_type"`
+	ContactSubType   []string       `json:"contact_sub_type"`
+	Prefix           sql.NullString `json:"prefix"`
 	FirstName        sql.NullString `json:"first_name"`
 	LastName         sql.NullString `json:"last_name"`
+	Suffix           sql.NullString `json:"suffix"`
+	NickName         sql.NullString `json:"nick_name"`
 	OrganizationName sql.NullString `json:"organization_name"`
-	Email            sql.NullString `json:"email"`
-	Phone            sql.NullString `json:"phone"`
-	AddressLine1     sql.NullString `json:"address_line_1"`
-	AddressLine2     sql.NullString `json:"address_line_2"`
-	City             sql.NullString `json:"city"`
-	StateProvince    sql.NullString `json:"state_province"`
-	PostalCode       sql.NullString `json:"postal_code"`
-	Country          sql.NullString `json:"country"`
+	HouseholdName    sql.NullString `json:"household_name"`
 }
 
 func (q *Queries) CreateContact(ctx context.Context, arg CreateContactParams) (Contact, error) {
 	row := q.db.QueryRowContext(ctx, CreateContact,
 		arg.ContactType,
+		pq.Array(arg.ContactSubType),
+		arg.Prefix,
 		arg.FirstName,
 		arg.LastName,
+		arg.Suffix,
+		arg.NickName,
 		arg.OrganizationName,
-		arg.Email,
-		arg.Phone,
-		arg.AddressLine1,
-		arg.AddressLine2,
-		arg.City,
-		arg.StateProvince,
-		arg.PostalCode,
-		arg.Country,
+		arg.HouseholdName,
 	)
 	var i Contact
 	err := row.Scan(
@@ -81,16 +75,20 @@ func (q *Queries) CreateContact(ctx context.Context, arg CreateContactParams) (C
 		&i.FirstName,
 		&i.LastName,
 		&i.OrganizationName,
-		&i.Email,
-		&i.Phone,
-		&i.AddressLine1,
-		&i.AddressLine2,
-		&i.City,
-		&i.StateProvince,
-		&i.PostalCode,
-		&i.Country,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DomainID,
+		&i.IsDeleted,
+		&i.MergedToID,
+		&i.Prefix,
+		&i.Suffix,
+		&i.NickName,
+		&i.HouseholdName,
+		&i.DisplayName,
+		&i.SortName,
+		pq.Array(&i.ContactSubType),
+		&i.EmployerID,
+		&i.AnonymizedAt,
 	)
 	return i, err
 }
@@ -105,7 +103,7 @@ func (q *Queries) DeleteContact(ctx context.Context, id uuid.UUID) error {
 }
 
 const GetContact = `-- name: GetContact :one
-SELECT id, contact_type, first_name, last_name, organization_name, email, phone, address_line_1, address_line_2, city, state_province, postal_code, country, created_at, updated_at FROM contacts WHERE id = $1
+SELECT id, contact_type, first_name, last_name, organization_name, created_at, updated_at, domain_id, is_deleted, merged_to_id, prefix, suffix, nick_name, household_name, display_name, sort_name, contact_sub_type, employer_id, anonymized_at FROM contacts WHERE id = $1
 `
 
 func (q *Queries) GetContact(ctx context.Context, id uuid.UUID) (Contact, error) {
@@ -117,25 +115,33 @@ func (q *Queries) GetContact(ctx context.Context, id uuid.UUID) (Contact, error)
 		&i.FirstName,
 		&i.LastName,
 		&i.OrganizationName,
-		&i.Email,
-		&i.Phone,
-		&i.AddressLine1,
-		&i.AddressLine2,
-		&i.City,
-		&i.StateProvince,
-		&i.PostalCode,
-		&i.Country,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DomainID,
+		&i.IsDeleted,
+		&i.MergedToID,
+		&i.Prefix,
+		&i.Suffix,
+		&i.NickName,
+		&i.HouseholdName,
+		&i.DisplayName,
+		&i.SortName,
+		pq.Array(&i.ContactSubType),
+		&i.EmployerID,
+		&i.AnonymizedAt,
 	)
 	return i, err
 }
 
 const GetContactByEmail = `-- name: GetContactByEmail :one
-SELECT id, contact_type, first_name, last_name, organization_name, email, phone, address_line_1, address_line_2, city, state_province, postal_code, country, created_at, updated_at FROM contacts WHERE email = $1
+SELECT c.id, c.contact_type, c.first_name, c.last_name, c.organization_name, c.created_at, c.updated_at, c.domain_id, c.is_deleted, c.merged_to_id, c.prefix, c.suffix, c.nick_name, c.household_name, c.display_name, c.sort_name, c.contact_sub_type, c.employer_id, c.anonymized_at FROM contacts c
+JOIN emails e ON e.contact_id = c.id
+WHERE lower(e.email) = lower($1) AND NOT c.is_deleted
+ORDER BY e.is_primary DESC, c.created_at
+LIMIT 1
 `
 
-func (q *Queries) GetContactByEmail(ctx context.Context, email sql.NullString) (Contact, error) {
+func (q *Queries) GetContactByEmail(ctx context.Context, email string) (Contact, error) {
 	row := q.db.QueryRowContext(ctx, GetContactByEmail, email)
 	var i Contact
 	err := row.Scan(
@@ -144,25 +150,33 @@ func (q *Queries) GetContactByEmail(ctx context.Context, email sql.NullString) (
 		&i.FirstName,
 		&i.LastName,
 		&i.OrganizationName,
-		&i.Email,
-		&i.Phone,
-		&i.AddressLine1,
-		&i.AddressLine2,
-		&i.City,
-		&i.StateProvince,
-		&i.PostalCode,
-		&i.Country,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DomainID,
+		&i.IsDeleted,
+		&i.MergedToID,
+		&i.Prefix,
+		&i.Suffix,
+		&i.NickName,
+		&i.HouseholdName,
+		&i.DisplayName,
+		&i.SortName,
+		pq.Array(&i.ContactSubType),
+		&i.EmployerID,
+		&i.AnonymizedAt,
 	)
 	return i, err
 }
 
 const GetContactByPhone = `-- name: GetContactByPhone :one
-SELECT id, contact_type, first_name, last_name, organization_name, email, phone, address_line_1, address_line_2, city, state_province, postal_code, country, created_at, updated_at FROM contacts WHERE phone = $1
+SELECT c.id, c.contact_type, c.first_name, c.last_name, c.organization_name, c.created_at, c.updated_at, c.domain_id, c.is_deleted, c.merged_to_id, c.prefix, c.suffix, c.nick_name, c.household_name, c.display_name, c.sort_name, c.contact_sub_type, c.employer_id, c.anonymized_at FROM contacts c
+JOIN phones p ON p.contact_id = c.id
+WHERE p.phone = $1 AND NOT c.is_deleted
+ORDER BY p.is_primary DESC, c.created_at
+LIMIT 1
 `
 
-func (q *Queries) GetContactByPhone(ctx context.Context, phone sql.NullString) (Contact, error) {
+func (q *Queries) GetContactByPhone(ctx context.Context, phone string) (Contact, error) {
 	row := q.db.QueryRowContext(ctx, GetContactByPhone, phone)
 	var i Contact
 	err := row.Scan(
@@ -171,29 +185,37 @@ func (q *Queries) GetContactByPhone(ctx context.Context, phone sql.NullString) (
 		&i.FirstName,
 		&i.LastName,
 		&i.OrganizationName,
-		&i.Email,
-		&i.Phone,
-		&i.AddressLine1,
-		&i.AddressLine2,
-		&i.City,
-		&i.StateProvince,
-		&i.PostalCode,
-		&i.Country,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DomainID,
+		&i.IsDeleted,
+		&i.MergedToID,
+		&i.Prefix,
+		&i.Suffix,
+		&i.NickName,
+		&i.HouseholdName,
+		&i.DisplayName,
+		&i.SortName,
+		pq.Array(&i.ContactSubType),
+		&i.EmployerID,
+		&i.AnonymizedAt,
 	)
 	return i, err
 }
 
 const GetContactsByLocation = `-- name: GetContactsByLocation :many
-SELECT id, contact_type, first_name, last_name, organization_name, email, phone, address_line_1, address_line_2, city, state_province, postal_code, country, created_at, updated_at FROM contacts 
-WHERE city = $1 AND state_province = $2 
-ORDER BY created_at DESC
+SELECT c.id, c.contact_type, c.first_name, c.last_name, c.organization_name, c.created_at, c.updated_at, c.domain_id, c.is_deleted, c.merged_to_id, c.prefix, c.suffix, c.nick_name, c.household_name, c.display_name, c.sort_name, c.contact_sub_type, c.employer_id, c.anonymized_at FROM contacts c
+JOIN addresses a ON a.contact_id = c.id AND a.is_primary
+LEFT JOIN state_provinces sp ON sp.id = a.state_province_id
+WHERE a.city = $1
+  AND (sp.name = $2 OR sp.abbreviation = $2)
+  AND NOT c.is_deleted
+ORDER BY c.created_at DESC
 `
 
 type GetContactsByLocationParams struct {
 	City          sql.NullString `json:"city"`
-	StateProvince sql.NullString `json:"state_province"`
+	StateProvince string         `json:"state_province"`
 }
 
 func (q *Queries) GetContactsByLocation(ctx context.Context, arg GetContactsByLocationParams) ([]Contact, error) {
@@ -211,16 +233,20 @@ func (q *Queries) GetContactsByLocation(ctx context.Context, arg GetContactsByLo
 			&i.FirstName,
 			&i.LastName,
 			&i.OrganizationName,
-			&i.Email,
-			&i.Phone,
-			&i.AddressLine1,
-			&i.AddressLine2,
-			&i.City,
-			&i.StateProvince,
-			&i.PostalCode,
-			&i.Country,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
+			&i.IsDeleted,
+			&i.MergedToID,
+			&i.Prefix,
+			&i.Suffix,
+			&i.NickName,
+			&i.HouseholdName,
+			&i.DisplayName,
+			&i.SortName,
+			pq.Array(&i.ContactSubType),
+			&i.EmployerID,
+			&i.AnonymizedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -236,8 +262,8 @@ func (q *Queries) GetContactsByLocation(ctx context.Context, arg GetContactsByLo
 }
 
 const GetContactsByType = `-- name: GetContactsByType :many
-SELECT id, contact_type, first_name, last_name, organization_name, email, phone, address_line_1, address_line_2, city, state_province, postal_code, country, created_at, updated_at FROM contacts 
-WHERE contact_type = $1 
+SELECT id, contact_type, first_name, last_name, organization_name, created_at, updated_at, domain_id, is_deleted, merged_to_id, prefix, suffix, nick_name, household_name, display_name, sort_name, contact_sub_type, employer_id, anonymized_at FROM contacts 
+WHERE contact_type = $1 AND NOT is_deleted
 ORDER BY created_at DESC
 `
 
@@ -256,16 +282,20 @@ func (q *Queries) GetContactsByType(ctx context.Context, contactType string) ([]
 			&i.FirstName,
 			&i.LastName,
 			&i.OrganizationName,
-			&i.Email,
-			&i.Phone,
-			&i.AddressLine1,
-			&i.AddressLine2,
-			&i.City,
-			&i.StateProvince,
-			&i.PostalCode,
-			&i.Country,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
+			&i.IsDeleted,
+			&i.MergedToID,
+			&i.Prefix,
+			&i.Suffix,
+			&i.NickName,
+			&i.HouseholdName,
+			&i.DisplayName,
+			&i.SortName,
+			pq.Array(&i.ContactSubType),
+			&i.EmployerID,
+			&i.AnonymizedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -281,7 +311,8 @@ func (q *Queries) GetContactsByType(ctx context.Context, contactType string) ([]
 }
 
 const ListAllContacts = `-- name: ListAllContacts :many
-SELECT id, contact_type, first_name, last_name, organization_name, email, phone, address_line_1, address_line_2, city, state_province, postal_code, country, created_at, updated_at FROM contacts 
+SELECT id, contact_type, first_name, last_name, organization_name, created_at, updated_at, domain_id, is_deleted, merged_to_id, prefix, suffix, nick_name, household_name, display_name, sort_name, contact_sub_type, employer_id, anonymized_at FROM contacts 
+WHERE NOT is_deleted
 ORDER BY created_at DESC 
 LIMIT $1 OFFSET $2
 `
@@ -306,16 +337,20 @@ func (q *Queries) ListAllContacts(ctx context.Context, arg ListAllContactsParams
 			&i.FirstName,
 			&i.LastName,
 			&i.OrganizationName,
-			&i.Email,
-			&i.Phone,
-			&i.AddressLine1,
-			&i.AddressLine2,
-			&i.City,
-			&i.StateProvince,
-			&i.PostalCode,
-			&i.Country,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
+			&i.IsDeleted,
+			&i.MergedToID,
+			&i.Prefix,
+			&i.Suffix,
+			&i.NickName,
+			&i.HouseholdName,
+			&i.DisplayName,
+			&i.SortName,
+			pq.Array(&i.ContactSubType),
+			&i.EmployerID,
+			&i.AnonymizedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -331,8 +366,8 @@ func (q *Queries) ListAllContacts(ctx context.Context, arg ListAllContactsParams
 }
 
 const ListContacts = `-- name: ListContacts :many
-SELECT id, contact_type, first_name, last_name, organization_name, email, phone, address_line_1, address_line_2, city, state_province, postal_code, country, created_at, updated_at FROM contacts 
-WHERE contact_type = $1 
+SELECT id, contact_type, first_name, last_name, organization_name, created_at, updated_at, domain_id, is_deleted, merged_to_id, prefix, suffix, nick_name, household_name, display_name, sort_name, contact_sub_type, employer_id, anonymized_at FROM contacts 
+WHERE contact_type = $1 AND NOT is_deleted
 ORDER BY created_at DESC 
 LIMIT $2 OFFSET $3
 `
@@ -358,16 +393,98 @@ func (q *Queries) ListContacts(ctx context.Context, arg ListContactsParams) ([]C
 			&i.FirstName,
 			&i.LastName,
 			&i.OrganizationName,
-			&i.Email,
-			&i.Phone,
-			&i.AddressLine1,
-			&i.AddressLine2,
-			&i.City,
-			&i.StateProvince,
-			&i.PostalCode,
-			&i.Country,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
+			&i.IsDeleted,
+			&i.MergedToID,
+			&i.Prefix,
+			&i.Suffix,
+			&i.NickName,
+			&i.HouseholdName,
+			&i.DisplayName,
+			&i.SortName,
+			pq.Array(&i.ContactSubType),
+			&i.EmployerID,
+			&i.AnonymizedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListContactsNear = `-- name: ListContactsNear :many
+SELECT c.id, c.contact_type, c.first_name, c.last_name, c.organization_name, c.created_at, c.updated_at, c.domain_id, c.is_deleted, c.merged_to_id, c.prefix, c.suffix, c.nick_name, c.household_name, c.display_name, c.sort_name, c.contact_sub_type, c.employer_id, c.anonymized_at FROM contacts c
+JOIN addresses a ON a.contact_id = c.id AND a.is_primary
+CROSS JOIN LATERAL (
+    SELECT 6371.0088 * 2 * asin(least(1, sqrt(
+        power(sin(radians(a.geo_code_1::float8 - $1::float8) / 2), 2)
+        + cos(radians($1::float8)) * cos(radians(a.geo_code_1::float8))
+        * power(sin(radians(a.geo_code_2::float8 - $2::float8) / 2), 2)))) AS km
+) d
+WHERE a.geo_code_1 BETWEEN $3::float8 AND $4::float8
+  AND d.km <= $5::float8
+  AND NOT c.is_deleted
+ORDER BY d.km, c.id
+LIMIT $6
+`
+
+type ListContactsNearParams struct {
+	Lat      float64 `json:"lat"`
+	Lon      float64 `json:"lon"`
+	MinLat   float64 `json:"min_lat"`
+	MaxLat   float64 `json:"max_lat"`
+	RadiusKm float64 `json:"radius_km"`
+	RowLimit int32   `json:"row_limit"`
+}
+
+// Contacts whose primary address lies within radius_km of a point, nearest
+// first. The latitude range narrows the addresses before their great-circle
+// distance is computed.
+func (q *Queries) ListContactsNear(ctx context.Context, arg ListContactsNearParams) ([]Contact, error) {
+	rows, err := q.db.QueryContext(ctx, ListContactsNear,
+		arg.Lat,
+		arg.Lon,
+		arg.MinLat,
+		arg.MaxLat,
+		arg.RadiusKm,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Contact{}
+	for rows.Next() {
+		var i Contact
+		if err := rows.Scan(
+			&i.ID,
+			&i.ContactType,
+			&i.FirstName,
+			&i.LastName,
+			&i.OrganizationName,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DomainID,
+			&i.IsDeleted,
+			&i.MergedToID,
+			&i.Prefix,
+			&i.Suffix,
+			&i.NickName,
+			&i.HouseholdName,
+			&i.DisplayName,
+			&i.SortName,
+			pq.Array(&i.ContactSubType),
+			&i.EmployerID,
+			&i.AnonymizedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -383,25 +500,28 @@ func (q *Queries) ListContacts(ctx context.Context, arg ListContactsParams) ([]C
 }
 
 const SearchContacts = `-- name: SearchContacts :many
-SELECT id, contact_type, first_name, last_name, organization_name, email, phone, address_line_1, address_line_2, city, state_province, postal_code, country, created_at, updated_at FROM contacts 
+SELECT id, contact_type, first_name, last_name, organization_name, created_at, updated_at, domain_id, is_deleted, merged_to_id, prefix, suffix, nick_name, household_name, display_name, sort_name, contact_sub_type, employer_id, anonymized_at FROM contacts 
 WHERE (
+    display_name ILIKE $1 OR
     first_name ILIKE $1 OR 
     last_name ILIKE $1 OR 
+    nick_name ILIKE $1 OR
     organization_name ILIKE $1 OR
-    email ILIKE $1
-)
+    household_name ILIKE $1 OR
+    EXISTS (SELECT 1 FROM emails e WHERE e.contact_id = contacts.id AND e.email ILIKE $1)
+) AND NOT is_deleted
 ORDER BY created_at DESC 
 LIMIT $2 OFFSET $3
 `
 
 type SearchContactsParams struct {
-	FirstName sql.NullString `json:"first_name"`
-	Limit     int32          `json:"limit"`
-	Offset    int32          `json:"offset"`
+	DisplayName sql.NullString `json:"display_name"`
+	Limit       int32          `json:"limit"`
+	Offset      int32          `json:"offset"`
 }
 
 func (q *Queries) SearchContacts(ctx context.Context, arg SearchContactsParams) ([]Contact, error) {
-	rows, err := q.db.QueryContext(ctx, SearchContacts, arg.FirstName, arg.Limit, arg.Offset)
+	rows, err := q.db.QueryContext(ctx, SearchContacts, arg.DisplayName, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
@@ -415,16 +535,20 @@ func (q *Queries) SearchContacts(ctx context.Context, arg SearchContactsParams)
 			&i.FirstName,
 			&i.LastName,
 			&i.OrganizationName,
-			&i.Email,
-			&i.Phone,
-			&i.AddressLine1,
-			&i.AddressLine2,
-			&i.City,
-			&i.StateProvince,
-			&i.PostalCode,
-			&i.Country,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
+			&i.IsDeleted,
+			&i.MergedToID,
+			&i.Prefix,
+			&i.Suffix,
+			&i.NickName,
+			&i.HouseholdName,
+			&i.DisplayName,
+			&i.SortName,
+			pq.Array(&i.ContactSubType),
+			&i.EmployerID,
+			&i.AnonymizedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -439,57 +563,89 @@ func (q *Queries) SearchContacts(ctx context.Context, arg SearchContactsParams)
 	return items, nil
 }
 
+const SetContactNames = `-- name: SetContactNames :one
+UPDATE contacts
+SET display_name = $1, sort_name = $2
+WHERE id = $3
+RETURNING id, contact_type, first_name, last_name, organization_name, created_at, updated_at, domain_id, is_deleted, merged_to_id, prefix, suffix, nick_name, household_name, display_name, sort_name, contact_sub_type, employer_id, anonymized_at
+`
+
+type SetContactNamesParams struct {
+	DisplayName sql.NullString `json:"display_name"`
+	SortName    sql.NullString `json:"sort_name"`
+	ID          uuid.UUID      `json:"id"`
+}
+
+// Stores the computed display and sort names
+func (q *Queries) SetContactNames(ctx context.Context, arg SetContactNamesParams) (Contact, error) {
+	row := q.db.QueryRowContext(ctx, SetContactNames, arg.DisplayName, arg.SortName, arg.ID)
+	var i Contact
+	err := row.Scan(
+		&i.ID,
+		&i.ContactType,
+		&i.FirstName,
+		&i.LastName,
+		&i.OrganizationName,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DomainID,
+		&i.IsDeleted,
+		&i.MergedToID,
+		&i.Prefix,
+		&i.Suffix,
+		&i.NickName,
+		&i.HouseholdName,
+		&i.DisplayName,
+		&i.SortName,
+		pq.Array(&i.ContactSubType),
+		&i.EmployerID,
+		&i.AnonymizedAt,
+	)
+	return i, err
+}
+
 const UpdateContact = `-- name: UpdateContact :one
 UPDATE contacts 
 SET 
     contact_type = $2,
-    first_name = $3,
-    last_name = $4,
-    organization_name = $5,
-    email = $6,
-    phone = $7,
-    address_line_1 = $8,
-    address_line_2 = $9,
-    city = $10,
-    state_province = $11,
-    postal_code = $12,
-    country = $13,
+    contact_sub_type = $3,
+    prefix = $4,
+    first_name = $5,
+    last_name = $6,
+    suffix = $7,
+    nick_name = $8,
+    organization_name = $9,
+    household_name = $10,
     updated_at = NOW()
 WHERE id = $1 
-RETURNING id, contact_type, first_name, last_name, organization_name, email, phone, address_line_1, address_line_2, city, state_province, postal_code, country, created_at, updated_at
+RETURNING id, contact_type, first_name, last_name, organization_name, created_at, updated_at, domain_id, is_deleted, merged_to_id, prefix, suffix, nick_name, household_name, display_name, sort_name, contact_sub_type, employer_id, anonymized_at
 `
 
 type UpdateContactParams struct {
 	ID               uuid.UUID      `json:"id"`
 	ContactType      string         `json:"contact_type"`
+	ContactSubType   []string       `json:"contact_sub_type"`
+	Prefix           sql.NullString `json:"prefix"`
 	FirstName        sql.NullString `json:"first_name"`
 	LastName         sql.NullString `json:"last_name"`
+	Suffix           sql.NullString `json:"suffix"`
+	NickName         sql.NullString `json:"nick_name"`
 	OrganizationName sql.NullString `json:"organization_name"`
-	Email            sql.NullString `json:"email"`
-	Phone            sql.NullString `json:"phone"`
-	AddressLine1     sql.NullString `json:"address_line_1"`
-	AddressLine2     sql.NullString `json:"address_line_2"`
-	City             sql.NullString `json:"city"`
-	StateProvince    sql.NullString `json:"state_province"`
-	PostalCode       sql.NullString `json:"postal_code"`
-	Country          sql.NullString `json:"country"`
+	HouseholdName    sql.NullString `json:"household_name"`
 }
 
 func (q *Queries) UpdateContact(ctx context.Context, arg UpdateContactParams) (Contact, error) {
 	row := q.db.QueryRowContext(ctx, UpdateContact,
 		arg.ID,
 		arg.ContactType,
+		pq.Array(arg.ContactSubType),
+		arg.Prefix,
 		arg.FirstName,
 		arg.LastName,
+		arg.Suffix,
+		arg.NickName,
 		arg.OrganizationName,
-		arg.Email,
-		arg.Phone,
-		arg.AddressLine1,
-		arg.AddressLine2,
-		arg.City,
-		arg.StateProvince,
-		arg.PostalCode,
-		arg.Country,
+		arg.HouseholdName,
 	)
 	var i Contact
 	err := row.Scan(
@@ -498,16 +654,20 @@ func (q *Queries) UpdateContact(ctx context.Context, arg UpdateContactParams) (C
 		&i.FirstName,
 		&i.LastName,
 		&i.OrganizationName,
-		&i.Email,
-		&i.Phone,
-		&i.AddressLine1,
-		&i.AddressLine2,
-		&i.City,
-		&i.StateProvince,
-		&i.PostalCode,
-		&i.Country,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DomainID,
+		&i.IsDeleted,
+		&i.MergedToID,
+		&i.Prefix,
+		&i.Suffix,
+		&i.NickName,
+		&i.HouseholdName,
+		&i.DisplayName,
+		&i.SortName,
+		pq.Array(&i.ContactSubType),
+		&i.EmployerID,
+		&i.AnonymizedAt,
 	)
 	return i, err
 }
@@ -61,7 +61,7 @@ INSERT INTO contributions (
     contact_id, amount, currency, contribution_type, status
 ) VALUES (
     $1, $2, $3, $4, $5
-) RETURNING id, contact_id, amount, currency, contribution_type, status, received_date, created_at, updated_at
+) RETURNING id, contact_id, amount, currency, contribution_type, status, received_date, created_at, updated_at, domain_id
 `
 
 type CreateContributionParams struct {
@@ -91,6 +91,7 @@ func (q *Queries) CreateContribution(ctx context.Context, arg CreateContribution
 		&i.ReceivedDate,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DomainID,
 	)
 	return i, err
 }
@@ -105,7 +106,7 @@ func (q *Queries) DeleteContribution(ctx context.Context, id uuid.UUID) error {
 }
 
 const GetContribution = `-- name: GetContribution :one
-SELECT id, contact_id, amount, currency, contribution_type, status, received_date, created_at, updated_at FROM contributions WHERE id = $1
+SELECT id, contact_id, amount, currency, contribution_type, status, received_date, created_at, updated_at, domain_id FROM contributions WHERE id = $1
 `
 
 func (q *Queries) GetContribution(ctx context.Context, id uuid.UUID) (Contribution, error) {
@@ -121,12 +122,13 @@ func (q *Queries) GetContribution(ctx context.Context, id uuid.UUID) (Contributi
 		&i.ReceivedDate,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DomainID,
 	)
 	return i, err
 }
 
 const GetContributionsByContact = `-- name: GetContributionsByContact :many
-SELECT id, contact_id, amount, currency, contribution_type, status, received_date, created_at, updated_at FROM contributions 
+SELECT id, contact_id, amount, currency, contribution_type, status, received_date, created_at, updated_at, domain_id FROM contributions 
 WHERE contact_id = $1 
 ORDER BY received_date DESC
 `
@@ -150,6 +152,7 @@ func (q *Queries) GetContributionsByContact(ctx context.Context, contactID uuid.
 			&i.ReceivedDate,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
 		); err != nil {
 			return nil, err
 		}
@@ -166,14 +169,15 @@ func (q *Queries) GetContributionsByContact(ctx context.Context, contactID uuid.
 
 const GetContributionsByDateRange = `-- name: GetContributionsByDateRange :many
 SELECT 
-    c.id, c.contact_id, c.amount, c.currency, c.contribution_type, c.status, c.received_date, c.created_at, c.updated_at,
+    c.id, c.contact_id, c.amount, c.currency, c.contribution_type, c.status, c.received_date, c.created_at, c.updated_at, c.domain_id,
     co.contact_type,
     co.first_name,
     co.last_name,
     co.organization_name,
-    co.email
+    pe.email
 FROM contributions c
 JOIN contacts co ON c.contact_id = co.id
+LEFT JOIN emails pe ON pe.contact_id = co.id AND pe.is_primary
 WHERE c.received_date >= $1 AND c.received_date <= $2
 ORDER BY c.received_date DESC
 `
@@ -193,6 +197,7 @@ type GetContributionsByDateRangeRow struct {
 	ReceivedDate     sql.NullTime   `json:"received_date"`
 	CreatedAt        sql.NullTime   `json:"created_at"`
 	UpdatedAt        sql.NullTime   `json:"updated_at"`
+	DomainID         uuid.UUID      `json:"domain_id"`
 	ContactType      string         `json:"contact_type"`
 	FirstName        sql.NullString `json:"first_name"`
 	LastName         sql.NullString `json:"last_name"`
@@ -219,6 +224,7 @@ func (q *Queries) GetContributionsByDateRange(ctx context.Context, arg GetContri
 			&i.ReceivedDate,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
 			&i.ContactType,
 			&i.FirstName,
 			&i.LastName,
@@ -262,14 +268,15 @@ func (q *Queries) GetTotalContributionsByContact(ctx context.Context, contactID
 
 const ListContributions = `-- name: ListContributions :many
 SELECT 
-    c.id, c.contact_id, c.amount, c.currency, c.contribution_type, c.status, c.received_date, c.created_at, c.updated_at,
+    c.id, c.contact_id, c.amount, c.currency, c.contribution_type, c.status, c.received_date, c.created_at, c.updated_at, c.domain_id,
     co.contact_type,
     co.first_name,
     co.last_name,
     co.organization_name,
-    co.email
+    pe.email
 FROM contributions c
 JOIN contacts co ON c.contact_id = co.id
+LEFT JOIN emails pe ON pe.contact_id = co.id AND pe.is_primary
 ORDER BY c.received_date DESC 
 LIMIT $1 OFFSET $2
 `
@@ -289,6 +296,7 @@ type ListContributionsRow struct {
 	ReceivedDate     sql.NullTime   `json:"received_date"`
 	CreatedAt        sql.NullTime   `json:"created_at"`
 	UpdatedAt        sql.NullTime   `json:"updated_at"`
+	DomainID         uuid.UUID      `json:"domain_id"`
 	ContactType      string         `json:"contact_type"`
 	FirstName        sql.NullString `json:"first_name"`
 	LastName         sql.NullString `json:"last_name"`
@@ -315,6 +323,7 @@ func (q *Queries) ListContributions(ctx context.Context, arg ListContributionsPa
 			&i.ReceivedDate,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
 			&i.ContactType,
 			&i.FirstName,
 			&i.LastName,
@@ -336,14 +345,15 @@ func (q *Queries) ListContributions(ctx context.Context, arg ListContributionsPa
 
 const ListContributionsByStatus = `-- name: ListContributionsByStatus :many
 SELECT 
-    c.id, c.contact_id, c.amount, c.currency, c.contribution_type, c.status, c.received_date, c.created_at, c.updated_at,
+    c.id, c.contact_id, c.amount, c.currency, c.contribution_type, c.status, c.received_date, c.created_at, c.updated_at, c.domain_id,
     co.contact_type,
     co.first_name,
     co.last_name,
     co.organization_name,
-    co.email
+    pe.email
 FROM contributions c
 JOIN contacts co ON c.contact_id = co.id
+LEFT JOIN emails pe ON pe.contact_id = co.id AND pe.is_primary
 WHERE c.status = $1
 ORDER BY c.received_date DESC 
 LIMIT $2 OFFSET $3
@@ -365,6 +375,7 @@ type ListContributionsByStatusRow struct {
 	ReceivedDate     sql.NullTime   `json:"received_date"`
 	CreatedAt        sql.NullTime   `json:"created_at"`
 	UpdatedAt        sql.NullTime   `json:"updated_at"`
+	DomainID         uuid.UUID      `json:"domain_id"`
 	ContactType      string         `json:"contact_type"`
 	FirstName        sql.NullString `json:"first_name"`
 	LastName         sql.NullString `json:"last_name"`
@@ -391,6 +402,7 @@ func (q *Queries) ListContributionsByStatus(ctx context.Context, arg ListContrib
 			&i.ReceivedDate,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
 			&i.ContactType,
 			&i.FirstName,
 			&i.LastName,
@@ -412,14 +424,15 @@ func (q *Queries) ListContributionsByStatus(ctx context.Context, arg ListContrib
 
 const ListContributionsByType = `-- name: ListContributionsByType :many
 SELECT 
-    c.id, c.contact_id, c.amount, c.currency, c.contribution_type, c.status, c.received_date, c.created_at, c.updated_at,
+    c.id, c.contact_id, c.amount, c.currency, c.contribution_type, c.status, c.received_date, c.created_at, c.updated_at, c.domain_id,
     co.contact_type,
     co.first_name,
     co.last_name,
     co.organization_name,
-    co.email
+    pe.email
 FROM contributions c
 JOIN contacts co ON c.contact_id = co.id
+LEFT JOIN emails pe ON pe.contact_id = co.id AND pe.is_primary
 WHERE c.contribution_type = $1
 ORDER BY c.received_date DESC 
 LIMIT $2 OFFSET $3
@@ -441,6 +454,7 @@ type ListContributionsByTypeRow struct {
 	ReceivedDate     sql.NullTime   `json:"received_date"`
 	CreatedAt        sql.NullTime   `json:"created_at"`
 	UpdatedAt        sql.NullTime   `json:"updated_at"`
+	DomainID         uuid.UUID      `json:"domain_id"`
 	ContactType      string         `json:"contact_type"`
 	FirstName        sql.NullString `json:"first_name"`
 	LastName         sql.NullString `json:"last_name"`
@@ -467,6 +481,7 @@ func (q *Queries) ListContributionsByType(ctx context.Context, arg ListContribut
 			&i.ReceivedDate,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
 			&i.ContactType,
 			&i.FirstName,
 			&i.LastName,
@@ -488,19 +503,20 @@ func (q *Queries) ListContributionsByType(ctx context.Context, arg ListContribut
 
 const SearchContributions = `-- name: SearchContributions :many
 SELECT 
-    c.id, c.contact_id, c.amount, c.currency, c.contribution_type, c.status, c.received_date, c.created_at, c.updated_at,
+    c.id, c.contact_id, c.amount, c.currency, c.contribution_type, c.status, c.received_date, c.created_at, c.updated_at, c.domain_id,
     co.contact_type,
     co.first_name,
     co.last_name,
     co.organization_name,
-    co.email
+    pe.email
 FROM contributions c
 JOIN contacts co ON c.contact_id = co.id
+LEFT JOIN emails pe ON pe.contact_id = co.id AND pe.is_primary
 WHERE (
     co.first_name ILIKE $1 OR 
     co.last_name ILIKE $1 OR 
     co.organization_name ILIKE $1 OR
-    co.email ILIKE $1
+    pe.email ILIKE $1
 )
 ORDER BY c.received_date DESC 
 LIMIT $2 OFFSET $3
@@ -522,6 +538,7 @@ type SearchContributionsRow struct {
 	ReceivedDate     sql.NullTime   `json:"received_date"`
 	CreatedAt        sql.NullTime   `json:"created_at"`
 	UpdatedAt        sql.NullTime   `json:"updated_at"`
+	DomainID         uuid.UUID      `json:"domain_id"`
 	ContactType      string         `json:"contact_type"`
 	FirstName        sql.NullString `json:"first_name"`
 	LastName         sql.NullString `json:"last_name"`
@@ -548,6 +565,7 @@ func (q *Queries) SearchContributions(ctx context.Context, arg SearchContributio
 			&i.ReceivedDate,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
 			&i.ContactType,
 			&i.FirstName,
 			&i.LastName,
@@ -576,7 +594,7 @@ SET
     status = $5,
     updated_at = NOW()
 WHERE id = $1 
-RETURNING id, contact_id, amount, currency, contribution_type, status, received_date, created_at, updated_at
+RETURNING id, contact_id, amount, currency, contribution_type, status, received_date, created_at, updated_at, domain_id
 `
 
 type UpdateContributionParams struct {
@@ -606,6 +624,7 @@ func (q *Queries) UpdateContribution(ctx context.Context, arg UpdateContribution
 		&i.ReceivedDate,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DomainID,
 	)
 	return i, err
 }
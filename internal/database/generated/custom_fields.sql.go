@@ -10,6 +10,7 @@ import (
 	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const CountCustomFieldsByGroup = `-- name: CountCustomFieldsByGroup :one
@@ -179,23 +180,24 @@ const CreateCustomGroup = `-- name: CreateCustomGroup :one
 
 INSERT INTO custom_groups (
     name, title, extends, table_name, is_active, is_multiple, 
-    collapse_display, help_pre, help_post, weight
+    collapse_display, help_pre, help_post, weight, extends_entity_column_value
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
-) RETURNING id, name, title, extends, table_name, is_active, is_multiple, collapse_display, help_pre, help_post, weight, created_at, updated_at
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+) RETURNING id, name, title, extends, table_name, is_active, is_multiple, collapse_display, help_pre, help_post, weight, created_at, updated_at, extends_entity_column_value
 `
 
 type CreateCustomGroupParams struct {
-	Name            string         `json:"name"`
-	Title           string         `json:"title"`
-	Extends         string         `json:"extends"`
-	TableName       sql.NullString `json:"table_name"`
-	IsActive        sql.NullBool   `json:"is_active"`
-	IsMultiple      sql.NullBool   `json:"is_multiple"`
-	CollapseDisplay sql.NullBool   `json:"collapse_display"`
-	HelpPre         sql.NullString `json:"help_pre"`
-	HelpPost        sql.NullString `json:"help_post"`
-	Weight          sql.NullInt32  `json:"weight"`
+	Name                     string         `json:"name"`
+	Title                    string         `json:"title"`
+	Extends                  string         `json:"extends"`
+	TableName                sql.NullString `json:"table_name"`
+	IsActive                 sql.NullBool   `json:"is_active"`
+	IsMultiple               sql.NullBool   `json:"is_multiple"`
+	CollapseDisplay          sql.NullBool   `json:"collapse_display"`
+	HelpPre                  sql.NullString `json:"help_pre"`
+	HelpPost                 sql.NullString `json:"help_post"`
+	Weight                   sql.NullInt32  `json:"weight"`
+	ExtendsEntityColumnValue []string       `json:"extends_entity_column_value"`
 }
 
 // Custom Fields Queries
@@ -213,6 +215,7 @@ func (q *Queries) CreateCustomGroup(ctx context.Context, arg CreateCustomGroupPa
 		arg.HelpPre,
 		arg.HelpPost,
 		arg.Weight,
+		pq.Array(arg.ExtendsEntityColumnValue),
 	)
 	var i CustomGroup
 	err := row.Scan(
@@ -229,6 +232,7 @@ func (q *Queries) CreateCustomGroup(ctx context.Context, arg CreateCustomGroupPa
 		&i.Weight,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		pq.Array(&i.ExtendsEntityColumnValue),
 	)
 	return i, err
 }
@@ -821,7 +825,7 @@ func (q *Queries) GetCustomFieldsForEvent(ctx context.Context, arg GetCustomFiel
 }
 
 const GetCustomGroup = `-- name: GetCustomGroup :one
-SELECT id, name, title, extends, table_name, is_active, is_multiple, collapse_display, help_pre, help_post, weight, created_at, updated_at FROM custom_groups WHERE id = $1
+SELECT id, name, title, extends, table_name, is_active, is_multiple, collapse_display, help_pre, help_post, weight, created_at, updated_at, extends_entity_column_value FROM custom_groups WHERE id = $1
 `
 
 func (q *Queries) GetCustomGroup(ctx context.Context, id uuid.UUID) (CustomGroup, error) {
@@ -841,12 +845,13 @@ func (q *Queries) GetCustomGroup(ctx context.Context, id uuid.UUID) (CustomGroup
 		&i.Weight,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		pq.Array(&i.ExtendsEntityColumnValue),
 	)
 	return i, err
 }
 
 const GetCustomGroupByName = `-- name: GetCustomGroupByName :one
-SELECT id, name, title, extends, table_name, is_active, is_multiple, collapse_display, help_pre, help_post, weight, created_at, updated_at FROM custom_groups WHERE name = $1
+SELECT id, name, title, extends, table_name, is_active, is_multiple, collapse_display, help_pre, help_post, weight, created_at, updated_at, extends_entity_column_value FROM custom_groups WHERE name = $1
 `
 
 func (q *Queries) GetCustomGroupByName(ctx context.Context, name string) (CustomGroup, error) {
@@ -866,6 +871,7 @@ func (q *Queries) GetCustomGroupByName(ctx context.Context, name string) (Custom
 		&i.Weight,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		pq.Array(&i.ExtendsEntityColumnValue),
 	)
 	return i, err
 }
@@ -1004,7 +1010,7 @@ func (q *Queries) GetCustomValuesByEntity(ctx context.Context, arg GetCustomValu
 }
 
 const ListAllCustomGroups = `-- name: ListAllCustomGroups :many
-SELECT id, name, title, extends, table_name, is_active, is_multiple, collapse_display, help_pre, help_post, weight, created_at, updated_at FROM custom_groups 
+SELECT id, name, title, extends, table_name, is_active, is_multiple, collapse_display, help_pre, help_post, weight, created_at, updated_at, extends_entity_column_value FROM custom_groups 
 WHERE is_active = $1
 ORDER BY extends, weight, title
 `
@@ -1032,6 +1038,7 @@ func (q *Queries) ListAllCustomGroups(ctx context.Context, isActive sql.NullBool
 			&i.Weight,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			pq.Array(&i.ExtendsEntityColumnValue),
 		); err != nil {
 			return nil, err
 		}
@@ -1244,7 +1251,7 @@ func (q *Queries) ListCustomFieldsByGroup(ctx context.Context, arg ListCustomFie
 }
 
 const ListCustomGroups = `-- name: ListCustomGroups :many
-SELECT id, name, title, extends, table_name, is_active, is_multiple, collapse_display, help_pre, help_post, weight, created_at, updated_at FROM custom_groups 
+SELECT id, name, title, extends, table_name, is_active, is_multiple, collapse_display, help_pre, help_post, weight, created_at, updated_at, extends_entity_column_value FROM custom_groups 
 WHERE extends = $1 AND is_active = $2
 ORDER BY weight, title
 `
@@ -1277,6 +1284,64 @@ func (q *Queries) ListCustomGroups(ctx context.Context, arg ListCustomGroupsPara
 			&i.Weight,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			pq.Array(&i.ExtendsEntityColumnValue),
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListCustomGroupsForContact = `-- name: ListCustomGroupsForContact :many
+SELECT id, name, title, extends, table_name, is_active, is_multiple, collapse_display, help_pre, help_post, weight, created_at, updated_at, extends_entity_column_value FROM custom_groups
+WHERE is_active
+  AND (
+    extends = 'Contact'
+    OR (extends = $1::text
+        AND (extends_entity_column_value IS NULL
+             OR extends_entity_column_value && $2::text[]))
+  )
+ORDER BY weight, title
+`
+
+type ListCustomGroupsForContactParams struct {
+	ContactType     string   `json:"contact_type"`
+	ContactSubTypes []string `json:"contact_sub_types"`
+}
+
+// Groups that apply to a contact: those extending every contact, its contact
+// type, or its contact type limited to one of its subtypes
+func (q *Queries) ListCustomGroupsForContact(ctx context.Context, arg ListCustomGroupsForContactParams) ([]CustomGroup, error) {
+	rows, err := q.db.QueryContext(ctx, ListCustomGroupsForContact, arg.ContactType, pq.Array(arg.ContactSubTypes))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []CustomGroup{}
+	for rows.Next() {
+		var i CustomGroup
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Title,
+			&i.Extends,
+			&i.TableName,
+			&i.IsActive,
+			&i.IsMultiple,
+			&i.CollapseDisplay,
+			&i.HelpPre,
+			&i.HelpPost,
+			&i.Weight,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			pq.Array(&i.ExtendsEntityColumnValue),
 		); err != nil {
 			return nil, err
 		}
@@ -1525,21 +1590,23 @@ UPDATE custom_groups SET
     help_pre = $8,
     help_post = $9,
     weight = $10,
+    extends_entity_column_value = $11,
     updated_at = NOW()
-WHERE id = $1 RETURNING id, name, title, extends, table_name, is_active, is_multiple, collapse_display, help_pre, help_post, weight, created_at, updated_at
+WHERE id = $1 RETURNING id, name, title, extends, table_name, is_active, is_multiple, collapse_display, help_pre, help_post, weight, created_at, updated_at, extends_entity_column_value
 `
 
 type UpdateCustomGroupParams struct {
-	ID              uuid.UUID      `json:"id"`
-	Title           string         `json:"title"`
-	Extends         string         `json:"extends"`
-	TableName       sql.NullString `json:"table_name"`
-	IsActive        sql.NullBool   `json:"is_active"`
-	IsMultiple      sql.NullBool   `json:"is_multiple"`
-	CollapseDisplay sql.NullBool   `json:"collapse_display"`
-	HelpPre         sql.NullString `json:"help_pre"`
-	HelpPost        sql.NullString `json:"help_post"`
-	Weight          sql.NullInt32  `json:"weight"`
+	ID                       uuid.UUID      `json:"id"`
+	Title                    string         `json:"title"`
+	Extends                  string         `json:"extends"`
+	TableName                sql.NullString `json:"table_name"`
+	IsActive                 sql.NullBool   `json:"is_active"`
+	IsMultiple               sql.NullBool   `json:"is_multiple"`
+	CollapseDisplay          sql.NullBool   `json:"collapse_display"`
+	HelpPre                  sql.NullString `json:"help_pre"`
+	HelpPost                 sql.NullString `json:"help_post"`
+	Weight                   sql.NullInt32  `json:"weight"`
+	ExtendsEntityColumnValue []string       `json:"extends_entity_column_value"`
 }
 
 func (q *Queries) UpdateCustomGroup(ctx context.Context, arg UpdateCustomGroupParams) (CustomGroup, error) {
@@ -1554,6 +1621,7 @@ func (q *Queries) UpdateCustomGroup(ctx context.Context, arg UpdateCustomGroupPa
 		arg.HelpPre,
 		arg.HelpPost,
 		arg.Weight,
+		pq.Array(arg.ExtendsEntityColumnValue),
 	)
 	var i CustomGroup
 	err := row.Scan(
@@ -1570,6 +1638,7 @@ func (q *Queries) UpdateCustomGroup(ctx context.Context, arg UpdateCustomGroupPa
 		&i.Weight,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		pq.Array(&i.ExtendsEntityColumnValue),
 	)
 	return i, err
 }
@@ -0,0 +1,485 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: dedupe.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const CreateDedupeRule = `-- name: CreateDedupeRule :one
+INSERT INTO dedupe_rules (
+    rule_group_id, rule_table, rule_field, rule_length, rule_weight
+) VALUES (
+    $1, $2, $3, $4, $5
+) RETURNING id, rule_group_id, rule_table, rule_field, rule_length, rule_weight, created_at, updated_at
+`
+
+type CreateDedupeRuleParams struct {
+	RuleGroupID uuid.UUID     `json:"rule_group_id"`
+	RuleTable   string        `json:"rule_table"`
+	RuleField   string        `json:"rule_field"`
+	RuleLength  sql.NullInt32 `json:"rule_length"`
+	RuleWeight  int32         `json:"rule_weight"`
+}
+
+func (q *Queries) CreateDedupeRule(ctx context.Context, arg CreateDedupeRuleParams) (DedupeRule, error) {
+	row := q.db.QueryRowContext(ctx, CreateDedupeRule,
+		arg.RuleGroupID,
+		arg.RuleTable,
+		arg.RuleField,
+		arg.RuleLength,
+		arg.RuleWeight,
+	)
+	var i DedupeRule
+	err := row.Scan(
+		&i.ID,
+		&i.RuleGroupID,
+		&i.RuleTable,
+		&i.RuleField,
+		&i.RuleLength,
+		&i.RuleWeight,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const CreateDedupeRuleGroup = `-- name: CreateDedupeRuleGroup :one
+INSERT INTO dedupe_rule_groups (
+    name, title, contact_type, used, threshold, is_reserved
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+) RETURNING id, name, title, contact_type, used, threshold, is_reserved, created_at, updated_at
+`
+
+type CreateDedupeRuleGroupParams struct {
+	Name        string       `json:"name"`
+	Title       string       `json:"title"`
+	ContactType string       `json:"contact_type"`
+	Used        string       `json:"used"`
+	Threshold   int32        `json:"threshold"`
+	IsReserved  sql.NullBool `json:"is_reserved"`
+}
+
+func (q *Queries) CreateDedupeRuleGroup(ctx context.Context, arg CreateDedupeRuleGroupParams) (DedupeRuleGroup, error) {
+	row := q.db.QueryRowContext(ctx, CreateDedupeRuleGroup,
+		arg.Name,
+		arg.Title,
+		arg.ContactType,
+		arg.Used,
+		arg.Threshold,
+		arg.IsReserved,
+	)
+	var i DedupeRuleGroup
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Title,
+		&i.ContactType,
+		&i.Used,
+		&i.Threshold,
+		&i.IsReserved,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const DeleteDedupeRuleGroup = `-- name: DeleteDedupeRuleGroup :exec
+DELETE FROM dedupe_rule_groups WHERE id = $1 AND is_reserved = FALSE
+`
+
+func (q *Queries) DeleteDedupeRuleGroup(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, DeleteDedupeRuleGroup, id)
+	return err
+}
+
+const GetDedupeRuleGroup = `-- name: GetDedupeRuleGroup :one
+SELECT id, name, title, contact_type, used, threshold, is_reserved, created_at, updated_at FROM dedupe_rule_groups WHERE id = $1
+`
+
+func (q *Queries) GetDedupeRuleGroup(ctx context.Context, id uuid.UUID) (DedupeRuleGroup, error) {
+	row := q.db.QueryRowContext(ctx, GetDedupeRuleGroup, id)
+	var i DedupeRuleGroup
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Title,
+		&i.ContactType,
+		&i.Used,
+		&i.Threshold,
+		&i.IsReserved,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const GetDedupeRuleGroupByName = `-- name: GetDedupeRuleGroupByName :one
+SELECT id, name, title, contact_type, used, threshold, is_reserved, created_at, updated_at FROM dedupe_rule_groups WHERE name = $1
+`
+
+func (q *Queries) GetDedupeRuleGroupByName(ctx context.Context, name string) (DedupeRuleGroup, error) {
+	row := q.db.QueryRowContext(ctx, GetDedupeRuleGroupByName, name)
+	var i DedupeRuleGroup
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Title,
+		&i.ContactType,
+		&i.Used,
+		&i.Threshold,
+		&i.IsReserved,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const GetDedupeRuleGroupByUsage = `-- name: GetDedupeRuleGroupByUsage :one
+SELECT id, name, title, contact_type, used, threshold, is_reserved, created_at, updated_at FROM dedupe_rule_groups
+WHERE contact_type = $1 AND used = $2
+ORDER BY created_at ASC
+LIMIT 1
+`
+
+type GetDedupeRuleGroupByUsageParams struct {
+	ContactType string `json:"contact_type"`
+	Used        string `json:"used"`
+}
+
+func (q *Queries) GetDedupeRuleGroupByUsage(ctx context.Context, arg GetDedupeRuleGroupByUsageParams) (DedupeRuleGroup, error) {
+	row := q.db.QueryRowContext(ctx, GetDedupeRuleGroupByUsage, arg.ContactType, arg.Used)
+	var i DedupeRuleGroup
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Title,
+		&i.ContactType,
+		&i.Used,
+		&i.Threshold,
+		&i.IsReserved,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const ListAddressesForContacts = `-- name: ListAddressesForContacts :many
+SELECT id, contact_id, location_type_id, is_primary, is_billing, street_address, street_number, street_name, street_unit, city, state_province_id, postal_code, country_id, geo_code_1, geo_code_2, created_at, updated_at, supplemental_address_1, geocoded_at, manual_geo_code, master_id FROM addresses
+WHERE contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) ListAddressesForContacts(ctx context.Context, contactIds []uuid.UUID) ([]Address, error) {
+	rows, err := q.db.QueryContext(ctx, ListAddressesForContacts, pq.Array(contactIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Address{}
+	for rows.Next() {
+		var i Address
+		if err := rows.Scan(
+			&i.ID,
+			&i.ContactID,
+			&i.LocationTypeID,
+			&i.IsPrimary,
+			&i.IsBilling,
+			&i.StreetAddress,
+			&i.StreetNumber,
+			&i.StreetName,
+			&i.StreetUnit,
+			&i.City,
+			&i.StateProvinceID,
+			&i.PostalCode,
+			&i.CountryID,
+			&i.GeoCode1,
+			&i.GeoCode2,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.SupplementalAddress1,
+			&i.GeocodedAt,
+			&i.ManualGeoCode,
+			&i.MasterID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListContactsByIDs = `-- name: ListContactsByIDs :many
+SELECT id, contact_type, first_name, last_name, organization_name, created_at, updated_at, domain_id, is_deleted, merged_to_id, prefix, suffix, nick_name, household_name, display_name, sort_name, contact_sub_type, employer_id, anonymized_at FROM contacts
+WHERE id = ANY($1::uuid[])
+ORDER BY id ASC
+`
+
+func (q *Queries) ListContactsByIDs(ctx context.Context, ids []uuid.UUID) ([]Contact, error) {
+	rows, err := q.db.QueryContext(ctx, ListContactsByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Contact{}
+	for rows.Next() {
+		var i Contact
+		if err := rows.Scan(
+			&i.ID,
+			&i.ContactType,
+			&i.FirstName,
+			&i.LastName,
+			&i.OrganizationName,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DomainID,
+			&i.IsDeleted,
+			&i.MergedToID,
+			&i.Prefix,
+			&i.Suffix,
+			&i.NickName,
+			&i.HouseholdName,
+			&i.DisplayName,
+			&i.SortName,
+			pq.Array(&i.ContactSubType),
+			&i.EmployerID,
+			&i.AnonymizedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListContactsForDedupe = `-- name: ListContactsForDedupe :many
+SELECT id, contact_type, first_name, last_name, organization_name, created_at, updated_at, domain_id, is_deleted, merged_to_id, prefix, suffix, nick_name, household_name, display_name, sort_name, contact_sub_type, employer_id, anonymized_at FROM contacts
+WHERE contact_type = $1 AND id > $2 AND NOT is_deleted
+ORDER BY id ASC
+LIMIT $3
+`
+
+type ListContactsForDedupeParams struct {
+	ContactType string    `json:"contact_type"`
+	AfterID     uuid.UUID `json:"after_id"`
+	BatchSize   int32     `json:"batch_size"`
+}
+
+func (q *Queries) ListContactsForDedupe(ctx context.Context, arg ListContactsForDedupeParams) ([]Contact, error) {
+	rows, err := q.db.QueryContext(ctx, ListContactsForDedupe, arg.ContactType, arg.AfterID, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Contact{}
+	for rows.Next() {
+		var i Contact
+		if err := rows.Scan(
+			&i.ID,
+			&i.ContactType,
+			&i.FirstName,
+			&i.LastName,
+			&i.OrganizationName,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DomainID,
+			&i.IsDeleted,
+			&i.MergedToID,
+			&i.Prefix,
+			&i.Suffix,
+			&i.NickName,
+			&i.HouseholdName,
+			&i.DisplayName,
+			&i.SortName,
+			pq.Array(&i.ContactSubType),
+			&i.EmployerID,
+			&i.AnonymizedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListDedupeRuleGroups = `-- name: ListDedupeRuleGroups :many
+SELECT id, name, title, contact_type, used, threshold, is_reserved, created_at, updated_at FROM dedupe_rule_groups
+ORDER BY contact_type ASC, used DESC, name ASC
+`
+
+func (q *Queries) ListDedupeRuleGroups(ctx context.Context) ([]DedupeRuleGroup, error) {
+	rows, err := q.db.QueryContext(ctx, ListDedupeRuleGroups)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []DedupeRuleGroup{}
+	for rows.Next() {
+		var i DedupeRuleGroup
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Title,
+			&i.ContactType,
+			&i.Used,
+			&i.Threshold,
+			&i.IsReserved,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListDedupeRules = `-- name: ListDedupeRules :many
+SELECT id, rule_group_id, rule_table, rule_field, rule_length, rule_weight, created_at, updated_at FROM dedupe_rules
+WHERE rule_group_id = $1
+ORDER BY rule_weight DESC, rule_table ASC, rule_field ASC
+`
+
+func (q *Queries) ListDedupeRules(ctx context.Context, ruleGroupID uuid.UUID) ([]DedupeRule, error) {
+	rows, err := q.db.QueryContext(ctx, ListDedupeRules, ruleGroupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []DedupeRule{}
+	for rows.Next() {
+		var i DedupeRule
+		if err := rows.Scan(
+			&i.ID,
+			&i.RuleGroupID,
+			&i.RuleTable,
+			&i.RuleField,
+			&i.RuleLength,
+			&i.RuleWeight,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListEmailsForContacts = `-- name: ListEmailsForContacts :many
+SELECT id, contact_id, location_type_id, is_primary, is_billing, email, on_hold, is_bulkmail, hold_date, reset_date, signature_text, signature_html, created_at, updated_at FROM emails
+WHERE contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) ListEmailsForContacts(ctx context.Context, contactIds []uuid.UUID) ([]Email, error) {
+	rows, err := q.db.QueryContext(ctx, ListEmailsForContacts, pq.Array(contactIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Email{}
+	for rows.Next() {
+		var i Email
+		if err := rows.Scan(
+			&i.ID,
+			&i.ContactID,
+			&i.LocationTypeID,
+			&i.IsPrimary,
+			&i.IsBilling,
+			&i.Email,
+			&i.OnHold,
+			&i.IsBulkmail,
+			&i.HoldDate,
+			&i.ResetDate,
+			&i.SignatureText,
+			&i.SignatureHtml,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListPhonesForContacts = `-- name: ListPhonesForContacts :many
+SELECT id, contact_id, location_type_id, phone_type, is_primary, phone, phone_ext, created_at, updated_at FROM phones
+WHERE contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) ListPhonesForContacts(ctx context.Context, contactIds []uuid.UUID) ([]Phone, error) {
+	rows, err := q.db.QueryContext(ctx, ListPhonesForContacts, pq.Array(contactIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Phone{}
+	for rows.Next() {
+		var i Phone
+		if err := rows.Scan(
+			&i.ID,
+			&i.ContactID,
+			&i.LocationTypeID,
+			&i.PhoneType,
+			&i.IsPrimary,
+			&i.Phone,
+			&i.PhoneExt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -149,10 +149,11 @@ SELECT
     c.first_name,
     c.last_name,
     c.organization_name,
-    c.email
+    pe.email
 FROM event_registrations er
 JOIN events e ON er.event_id = e.id
 JOIN contacts c ON er.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE e.end_date < NOW()
 ORDER BY e.start_date DESC
 `
@@ -283,10 +284,11 @@ SELECT
     c.first_name,
     c.last_name,
     c.organization_name,
-    c.email
+    pe.email
 FROM event_registrations er
 JOIN events e ON er.event_id = e.id
 JOIN contacts c ON er.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE er.event_id = $1
 ORDER BY er.registration_date ASC
 `
@@ -358,10 +360,11 @@ SELECT
     c.first_name,
     c.last_name,
     c.organization_name,
-    c.email
+    pe.email
 FROM event_registrations er
 JOIN events e ON er.event_id = e.id
 JOIN contacts c ON er.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE e.start_date > NOW()
 ORDER BY e.start_date ASC
 `
@@ -433,10 +436,11 @@ SELECT
     c.first_name,
     c.last_name,
     c.organization_name,
-    c.email
+    pe.email
 FROM event_registrations er
 JOIN events e ON er.event_id = e.id
 JOIN contacts c ON er.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 ORDER BY er.registration_date DESC 
 LIMIT $1 OFFSET $2
 `
@@ -513,10 +517,11 @@ SELECT
     c.first_name,
     c.last_name,
     c.organization_name,
-    c.email
+    pe.email
 FROM event_registrations er
 JOIN events e ON er.event_id = e.id
 JOIN contacts c ON er.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE er.status = $1
 ORDER BY er.registration_date DESC 
 LIMIT $2 OFFSET $3
@@ -41,7 +41,7 @@ INSERT INTO events (
     location, max_participants
 ) VALUES (
     $1, $2, $3, $4, $5, $6
-) RETURNING id, title, description, start_date, end_date, location, max_participants, created_at, updated_at
+) RETURNING id, title, description, start_date, end_date, location, max_participants, created_at, updated_at, domain_id
 `
 
 type CreateEventParams struct {
@@ -73,6 +73,7 @@ func (q *Queries) CreateEvent(ctx context.Context, arg CreateEventParams) (Event
 		&i.MaxParticipants,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DomainID,
 	)
 	return i, err
 }
@@ -87,7 +88,7 @@ func (q *Queries) DeleteEvent(ctx context.Context, id uuid.UUID) error {
 }
 
 const GetEvent = `-- name: GetEvent :one
-SELECT id, title, description, start_date, end_date, location, max_participants, created_at, updated_at FROM events WHERE id = $1
+SELECT id, title, description, start_date, end_date, location, max_participants, created_at, updated_at, domain_id FROM events WHERE id = $1
 `
 
 func (q *Queries) GetEvent(ctx context.Context, id uuid.UUID) (Event, error) {
@@ -103,12 +104,13 @@ func (q *Queries) GetEvent(ctx context.Context, id uuid.UUID) (Event, error) {
 		&i.MaxParticipants,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DomainID,
 	)
 	return i, err
 }
 
 const GetEventByTitle = `-- name: GetEventByTitle :one
-SELECT id, title, description, start_date, end_date, location, max_participants, created_at, updated_at FROM events WHERE title = $1
+SELECT id, title, description, start_date, end_date, location, max_participants, created_at, updated_at, domain_id FROM events WHERE title = $1
 `
 
 func (q *Queries) GetEventByTitle(ctx context.Context, title string) (Event, error) {
@@ -124,12 +126,13 @@ func (q *Queries) GetEventByTitle(ctx context.Context, title string) (Event, err
 		&i.MaxParticipants,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DomainID,
 	)
 	return i, err
 }
 
 const GetEventsByDateRange = `-- name: GetEventsByDateRange :many
-SELECT id, title, description, start_date, end_date, location, max_participants, created_at, updated_at FROM events 
+SELECT id, title, description, start_date, end_date, location, max_participants, created_at, updated_at, domain_id FROM events 
 WHERE start_date >= $1 AND end_date <= $2 
 ORDER BY start_date ASC
 `
@@ -158,6 +161,7 @@ func (q *Queries) GetEventsByDateRange(ctx context.Context, arg GetEventsByDateR
 			&i.MaxParticipants,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
 		); err != nil {
 			return nil, err
 		}
@@ -173,7 +177,7 @@ func (q *Queries) GetEventsByDateRange(ctx context.Context, arg GetEventsByDateR
 }
 
 const ListEvents = `-- name: ListEvents :many
-SELECT id, title, description, start_date, end_date, location, max_participants, created_at, updated_at FROM events 
+SELECT id, title, description, start_date, end_date, location, max_participants, created_at, updated_at, domain_id FROM events 
 ORDER BY start_date ASC 
 LIMIT $1 OFFSET $2
 `
@@ -202,6 +206,7 @@ func (q *Queries) ListEvents(ctx context.Context, arg ListEventsParams) ([]Event
 			&i.MaxParticipants,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
 		); err != nil {
 			return nil, err
 		}
@@ -217,7 +222,7 @@ func (q *Queries) ListEvents(ctx context.Context, arg ListEventsParams) ([]Event
 }
 
 const ListPastEvents = `-- name: ListPastEvents :many
-SELECT id, title, description, start_date, end_date, location, max_participants, created_at, updated_at FROM events 
+SELECT id, title, description, start_date, end_date, location, max_participants, created_at, updated_at, domain_id FROM events 
 WHERE end_date < NOW() 
 ORDER BY start_date DESC 
 LIMIT $1 OFFSET $2
@@ -247,6 +252,7 @@ func (q *Queries) ListPastEvents(ctx context.Context, arg ListPastEventsParams)
 			&i.MaxParticipants,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
 		); err != nil {
 			return nil, err
 		}
@@ -262,7 +268,7 @@ func (q *Queries) ListPastEvents(ctx context.Context, arg ListPastEventsParams)
 }
 
 const ListUpcomingEvents = `-- name: ListUpcomingEvents :many
-SELECT id, title, description, start_date, end_date, location, max_participants, created_at, updated_at FROM events 
+SELECT id, title, description, start_date, end_date, location, max_participants, created_at, updated_at, domain_id FROM events 
 WHERE start_date > NOW() 
 ORDER BY start_date ASC 
 LIMIT $1 OFFSET $2
@@ -292,6 +298,7 @@ func (q *Queries) ListUpcomingEvents(ctx context.Context, arg ListUpcomingEvents
 			&i.MaxParticipants,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
 		); err != nil {
 			return nil, err
 		}
@@ -307,7 +314,7 @@ func (q *Queries) ListUpcomingEvents(ctx context.Context, arg ListUpcomingEvents
 }
 
 const SearchEvents = `-- name: SearchEvents :many
-SELECT id, title, description, start_date, end_date, location, max_participants, created_at, updated_at FROM events 
+SELECT id, title, description, start_date, end_date, location, max_participants, created_at, updated_at, domain_id FROM events 
 WHERE (
     title ILIKE $1 OR 
     description ILIKE $1 OR 
@@ -342,6 +349,7 @@ func (q *Queries) SearchEvents(ctx context.Context, arg SearchEventsParams) ([]E
 			&i.MaxParticipants,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
 		); err != nil {
 			return nil, err
 		}
@@ -367,7 +375,7 @@ SET
     max_participants = $7,
     updated_at = NOW()
 WHERE id = $1 
-RETURNING id, title, description, start_date, end_date, location, max_participants, created_at, updated_at
+RETURNING id, title, description, start_date, end_date, location, max_participants, created_at, updated_at, domain_id
 `
 
 type UpdateEventParams struct {
@@ -401,6 +409,7 @@ func (q *Queries) UpdateEvent(ctx context.Context, arg UpdateEventParams) (Event
 		&i.MaxParticipants,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DomainID,
 	)
 	return i, err
 }
@@ -0,0 +1,112 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: geocode.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+const ListAddressesToGeocode = `-- name: ListAddressesToGeocode :many
+
+SELECT a.id, a.updated_at, a.street_address, a.city, a.postal_code,
+       sp.name AS state_province, co.iso_code AS country_code
+FROM addresses a
+JOIN contacts c ON c.id = a.contact_id
+LEFT JOIN state_provinces sp ON sp.id = a.state_province_id
+LEFT JOIN countries co ON co.id = a.country_id
+WHERE c.domain_id = $1
+  AND NOT c.is_deleted
+  AND a.geocoded_at IS NULL
+  AND a.master_id IS NULL
+  AND NOT COALESCE(a.manual_geo_code, FALSE)
+ORDER BY a.updated_at, a.id
+LIMIT $2
+`
+
+type ListAddressesToGeocodeParams struct {
+	DomainID  uuid.UUID `json:"domain_id"`
+	BatchSize int32     `json:"batch_size"`
+}
+
+type ListAddressesToGeocodeRow struct {
+	ID            uuid.UUID      `json:"id"`
+	UpdatedAt     sql.NullTime   `json:"updated_at"`
+	StreetAddress sql.NullString `json:"street_address"`
+	City          sql.NullString `json:"city"`
+	PostalCode    sql.NullString `json:"postal_code"`
+	StateProvince sql.NullString `json:"state_province"`
+	CountryCode   sql.NullString `json:"country_code"`
+}
+
+// Address geocoding queries. The trigger from migration 047 clears
+// geocoded_at when an address changes, so these only track lookups.
+// Addresses of a domain's contacts not looked up since they last changed,
+// oldest change first. Shared addresses take their master's coordinates.
+func (q *Queries) ListAddressesToGeocode(ctx context.Context, arg ListAddressesToGeocodeParams) ([]ListAddressesToGeocodeRow, error) {
+	rows, err := q.db.QueryContext(ctx, ListAddressesToGeocode, arg.DomainID, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAddressesToGeocodeRow{}
+	for rows.Next() {
+		var i ListAddressesToGeocodeRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UpdatedAt,
+			&i.StreetAddress,
+			&i.City,
+			&i.PostalCode,
+			&i.StateProvince,
+			&i.CountryCode,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const SetAddressGeoCode = `-- name: SetAddressGeoCode :execrows
+UPDATE addresses
+SET geo_code_1 = $1, geo_code_2 = $2, geocoded_at = $3
+WHERE id = $4
+  AND updated_at IS NOT DISTINCT FROM $5
+  AND geocoded_at IS NULL
+`
+
+type SetAddressGeoCodeParams struct {
+	GeoCode1   sql.NullString `json:"geo_code_1"`
+	GeoCode2   sql.NullString `json:"geo_code_2"`
+	GeocodedAt sql.NullTime   `json:"geocoded_at"`
+	ID         uuid.UUID      `json:"id"`
+	UpdatedAt  sql.NullTime   `json:"updated_at"`
+}
+
+// Records a lookup, with NULL coordinates when the address was not found.
+// An address changed since it was listed is left for the next run.
+func (q *Queries) SetAddressGeoCode(ctx context.Context, arg SetAddressGeoCodeParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, SetAddressGeoCode,
+		arg.GeoCode1,
+		arg.GeoCode2,
+		arg.GeocodedAt,
+		arg.ID,
+		arg.UpdatedAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
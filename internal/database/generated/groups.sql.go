@@ -0,0 +1,459 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: groups.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const CreateGroup = `-- name: CreateGroup :one
+
+INSERT INTO groups (
+    name, title, description, saved_search_id, group_type, visibility, is_active
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING id, name, title, description, source, saved_search_id, is_active, visibility, where_clause, select_tables, where_tables, group_type, cache_date, refresh_date, parents, children, is_hidden, is_reserved, created_at, updated_at
+`
+
+type CreateGroupParams struct {
+	Name          string         `json:"name"`
+	Title         sql.NullString `json:"title"`
+	Description   sql.NullString `json:"description"`
+	SavedSearchID uuid.NullUUID  `json:"saved_search_id"`
+	GroupType     sql.NullString `json:"group_type"`
+	Visibility    sql.NullString `json:"visibility"`
+	IsActive      sql.NullBool   `json:"is_active"`
+}
+
+// Group queries. Members of static, smart and nested groups are read
+// through the group_members view from migrations 044 and 045.
+func (q *Queries) CreateGroup(ctx context.Context, arg CreateGroupParams) (Group, error) {
+	row := q.db.QueryRowContext(ctx, CreateGroup,
+		arg.Name,
+		arg.Title,
+		arg.Description,
+		arg.SavedSearchID,
+		arg.GroupType,
+		arg.Visibility,
+		arg.IsActive,
+	)
+	var i Group
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Title,
+		&i.Description,
+		&i.Source,
+		&i.SavedSearchID,
+		&i.IsActive,
+		&i.Visibility,
+		&i.WhereClause,
+		&i.SelectTables,
+		&i.WhereTables,
+		&i.GroupType,
+		&i.CacheDate,
+		&i.RefreshDate,
+		&i.Parents,
+		&i.Children,
+		&i.IsHidden,
+		&i.IsReserved,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const CreateGroupNesting = `-- name: CreateGroupNesting :exec
+INSERT INTO group_nesting (parent_group_id, child_group_id)
+VALUES ($1, $2)
+ON CONFLICT (parent_group_id, child_group_id) DO NOTHING
+`
+
+type CreateGroupNestingParams struct {
+	ParentGroupID uuid.UUID `json:"parent_group_id"`
+	ChildGroupID  uuid.UUID `json:"child_group_id"`
+}
+
+// Nesting a group under the same parent twice does nothing. A trigger
+// rejects nesting that would create a cycle.
+func (q *Queries) CreateGroupNesting(ctx context.Context, arg CreateGroupNestingParams) error {
+	_, err := q.db.ExecContext(ctx, CreateGroupNesting, arg.ParentGroupID, arg.ChildGroupID)
+	return err
+}
+
+const DeleteGroupContactCache = `-- name: DeleteGroupContactCache :exec
+DELETE FROM group_contact_cache WHERE group_id = $1
+`
+
+func (q *Queries) DeleteGroupContactCache(ctx context.Context, groupID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, DeleteGroupContactCache, groupID)
+	return err
+}
+
+const DeleteGroupNesting = `-- name: DeleteGroupNesting :execrows
+DELETE FROM group_nesting WHERE parent_group_id = $1 AND child_group_id = $2
+`
+
+type DeleteGroupNestingParams struct {
+	ParentGroupID uuid.UUID `json:"parent_group_id"`
+	ChildGroupID  uuid.UUID `json:"child_group_id"`
+}
+
+func (q *Queries) DeleteGroupNesting(ctx context.Context, arg DeleteGroupNestingParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, DeleteGroupNesting, arg.ParentGroupID, arg.ChildGroupID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const ExpireSavedSearchGroups = `-- name: ExpireSavedSearchGroups :exec
+UPDATE groups SET refresh_date = NULL WHERE saved_search_id = $1
+`
+
+// Marks the cache of the groups using a saved search as expired
+func (q *Queries) ExpireSavedSearchGroups(ctx context.Context, savedSearchID uuid.NullUUID) error {
+	_, err := q.db.ExecContext(ctx, ExpireSavedSearchGroups, savedSearchID)
+	return err
+}
+
+const GetGroup = `-- name: GetGroup :one
+SELECT id, name, title, description, source, saved_search_id, is_active, visibility, where_clause, select_tables, where_tables, group_type, cache_date, refresh_date, parents, children, is_hidden, is_reserved, created_at, updated_at FROM groups WHERE id = $1
+`
+
+func (q *Queries) GetGroup(ctx context.Context, id uuid.UUID) (Group, error) {
+	row := q.db.QueryRowContext(ctx, GetGroup, id)
+	var i Group
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Title,
+		&i.Description,
+		&i.Source,
+		&i.SavedSearchID,
+		&i.IsActive,
+		&i.Visibility,
+		&i.WhereClause,
+		&i.SelectTables,
+		&i.WhereTables,
+		&i.GroupType,
+		&i.CacheDate,
+		&i.RefreshDate,
+		&i.Parents,
+		&i.Children,
+		&i.IsHidden,
+		&i.IsReserved,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const GetGroupForUpdate = `-- name: GetGroupForUpdate :one
+SELECT id, name, title, description, source, saved_search_id, is_active, visibility, where_clause, select_tables, where_tables, group_type, cache_date, refresh_date, parents, children, is_hidden, is_reserved, created_at, updated_at FROM groups WHERE id = $1 FOR UPDATE
+`
+
+// Serializes refreshes of a smart group's cache
+func (q *Queries) GetGroupForUpdate(ctx context.Context, id uuid.UUID) (Group, error) {
+	row := q.db.QueryRowContext(ctx, GetGroupForUpdate, id)
+	var i Group
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Title,
+		&i.Description,
+		&i.Source,
+		&i.SavedSearchID,
+		&i.IsActive,
+		&i.Visibility,
+		&i.WhereClause,
+		&i.SelectTables,
+		&i.WhereTables,
+		&i.GroupType,
+		&i.CacheDate,
+		&i.RefreshDate,
+		&i.Parents,
+		&i.Children,
+		&i.IsHidden,
+		&i.IsReserved,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const InsertGroupContactCache = `-- name: InsertGroupContactCache :execrows
+INSERT INTO group_contact_cache (group_id, contact_id)
+SELECT $1, unnest($2::uuid[])
+ON CONFLICT DO NOTHING
+`
+
+type InsertGroupContactCacheParams struct {
+	GroupID    uuid.UUID   `json:"group_id"`
+	ContactIds []uuid.UUID `json:"contact_ids"`
+}
+
+func (q *Queries) InsertGroupContactCache(ctx context.Context, arg InsertGroupContactCacheParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, InsertGroupContactCache, arg.GroupID, pq.Array(arg.ContactIds))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const ListGroupDescendants = `-- name: ListGroupDescendants :many
+SELECT g.id, g.name, g.title, g.description, g.source, g.saved_search_id, g.is_active, g.visibility, g.where_clause, g.select_tables, g.where_tables, g.group_type, g.cache_date, g.refresh_date, g.parents, g.children, g.is_hidden, g.is_reserved, g.created_at, g.updated_at FROM group_hierarchy h
+JOIN groups g ON g.id = h.descendant_id
+WHERE h.ancestor_id = $1
+ORDER BY g.title, g.name
+`
+
+// Groups nested under a group at any depth
+func (q *Queries) ListGroupDescendants(ctx context.Context, ancestorID uuid.UUID) ([]Group, error) {
+	rows, err := q.db.QueryContext(ctx, ListGroupDescendants, ancestorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Group{}
+	for rows.Next() {
+		var i Group
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Title,
+			&i.Description,
+			&i.Source,
+			&i.SavedSearchID,
+			&i.IsActive,
+			&i.Visibility,
+			&i.WhereClause,
+			&i.SelectTables,
+			&i.WhereTables,
+			&i.GroupType,
+			&i.CacheDate,
+			&i.RefreshDate,
+			&i.Parents,
+			&i.Children,
+			&i.IsHidden,
+			&i.IsReserved,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListGroupMembers = `-- name: ListGroupMembers :many
+SELECT c.id, c.contact_type, c.display_name, c.sort_name, e.email
+FROM group_members gm
+JOIN contacts c ON c.id = gm.contact_id
+LEFT JOIN emails e ON e.contact_id = c.id AND e.is_primary
+WHERE gm.group_id = $1 AND NOT c.is_deleted
+ORDER BY c.sort_name, c.id
+`
+
+type ListGroupMembersRow struct {
+	ID          uuid.UUID      `json:"id"`
+	ContactType string         `json:"contact_type"`
+	DisplayName sql.NullString `json:"display_name"`
+	SortName    sql.NullString `json:"sort_name"`
+	Email       sql.NullString `json:"email"`
+}
+
+func (q *Queries) ListGroupMembers(ctx context.Context, groupID uuid.UUID) ([]ListGroupMembersRow, error) {
+	rows, err := q.db.QueryContext(ctx, ListGroupMembers, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListGroupMembersRow{}
+	for rows.Next() {
+		var i ListGroupMembersRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ContactType,
+			&i.DisplayName,
+			&i.SortName,
+			&i.Email,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListGroupNesting = `-- name: ListGroupNesting :many
+SELECT parent_group_id, child_group_id FROM group_nesting
+ORDER BY parent_group_id, child_group_id
+`
+
+type ListGroupNestingRow struct {
+	ParentGroupID uuid.UUID `json:"parent_group_id"`
+	ChildGroupID  uuid.UUID `json:"child_group_id"`
+}
+
+func (q *Queries) ListGroupNesting(ctx context.Context) ([]ListGroupNestingRow, error) {
+	rows, err := q.db.QueryContext(ctx, ListGroupNesting)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListGroupNestingRow{}
+	for rows.Next() {
+		var i ListGroupNestingRow
+		if err := rows.Scan(&i.ParentGroupID, &i.ChildGroupID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListGroups = `-- name: ListGroups :many
+SELECT id, name, title, description, source, saved_search_id, is_active, visibility, where_clause, select_tables, where_tables, group_type, cache_date, refresh_date, parents, children, is_hidden, is_reserved, created_at, updated_at FROM groups
+WHERE is_active
+ORDER BY title, name
+`
+
+func (q *Queries) ListGroups(ctx context.Context) ([]Group, error) {
+	rows, err := q.db.QueryContext(ctx, ListGroups)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Group{}
+	for rows.Next() {
+		var i Group
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Title,
+			&i.Description,
+			&i.Source,
+			&i.SavedSearchID,
+			&i.IsActive,
+			&i.Visibility,
+			&i.WhereClause,
+			&i.SelectTables,
+			&i.WhereTables,
+			&i.GroupType,
+			&i.CacheDate,
+			&i.RefreshDate,
+			&i.Parents,
+			&i.Children,
+			&i.IsHidden,
+			&i.IsReserved,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListStaleSmartGroups = `-- name: ListStaleSmartGroups :many
+SELECT g.id, g.name, g.title, g.description, g.source, g.saved_search_id, g.is_active, g.visibility, g.where_clause, g.select_tables, g.where_tables, g.group_type, g.cache_date, g.refresh_date, g.parents, g.children, g.is_hidden, g.is_reserved, g.created_at, g.updated_at FROM groups g
+JOIN saved_searches s ON s.id = g.saved_search_id
+WHERE s.domain_id = $1
+  AND g.is_active
+  AND (g.refresh_date IS NULL OR g.refresh_date <= NOW())
+ORDER BY g.refresh_date ASC NULLS FIRST
+`
+
+// Active smart groups of a domain whose cache has expired or was never built
+func (q *Queries) ListStaleSmartGroups(ctx context.Context, domainID uuid.UUID) ([]Group, error) {
+	rows, err := q.db.QueryContext(ctx, ListStaleSmartGroups, domainID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Group{}
+	for rows.Next() {
+		var i Group
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Title,
+			&i.Description,
+			&i.Source,
+			&i.SavedSearchID,
+			&i.IsActive,
+			&i.Visibility,
+			&i.WhereClause,
+			&i.SelectTables,
+			&i.WhereTables,
+			&i.GroupType,
+			&i.CacheDate,
+			&i.RefreshDate,
+			&i.Parents,
+			&i.Children,
+			&i.IsHidden,
+			&i.IsReserved,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const SetGroupCacheDates = `-- name: SetGroupCacheDates :exec
+UPDATE groups SET cache_date = $2, refresh_date = $3 WHERE id = $1
+`
+
+type SetGroupCacheDatesParams struct {
+	ID          uuid.UUID    `json:"id"`
+	CacheDate   sql.NullTime `json:"cache_date"`
+	RefreshDate sql.NullTime `json:"refresh_date"`
+}
+
+func (q *Queries) SetGroupCacheDates(ctx context.Context, arg SetGroupCacheDatesParams) error {
+	_, err := q.db.ExecContext(ctx, SetGroupCacheDates, arg.ID, arg.CacheDate, arg.RefreshDate)
+	return err
+}
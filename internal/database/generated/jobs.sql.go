@@ -11,8 +11,56 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const ClaimDueJobs = `-- name: ClaimDueJobs :many
+SELECT id, domain_id, name, description, job_type, parameters, schedule, is_active, last_run, next_run, created_at, updated_at FROM jobs
+WHERE is_active = TRUE
+AND schedule IS NOT NULL
+AND (next_run IS NULL OR next_run <= NOW())
+AND name = ANY($1::text[])
+ORDER BY next_run ASC NULLS FIRST, name ASC
+FOR UPDATE SKIP LOCKED
+`
+
+// Locks the due jobs with a handler so only one instance runs each
+func (q *Queries) ClaimDueJobs(ctx context.Context, names []string) ([]Job, error) {
+	rows, err := q.db.QueryContext(ctx, ClaimDueJobs, pq.Array(names))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Job{}
+	for rows.Next() {
+		var i Job
+		if err := rows.Scan(
+			&i.ID,
+			&i.DomainID,
+			&i.Name,
+			&i.Description,
+			&i.JobType,
+			&i.Parameters,
+			&i.Schedule,
+			&i.IsActive,
+			&i.LastRun,
+			&i.NextRun,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const CleanupOldJobLogs = `-- name: CleanupOldJobLogs :exec
 DELETE FROM job_logs 
 WHERE job_id = $1 
@@ -103,6 +151,26 @@ func (q *Queries) CreateJob(ctx context.Context, arg CreateJobParams) (Job, erro
 	return i, err
 }
 
+const CreateJobLog = `-- name: CreateJobLog :one
+INSERT INTO job_logs (job_id, status) VALUES ($1, 'running') RETURNING id, job_id, status, message, started_at, completed_at, execution_time_ms, created_at
+`
+
+func (q *Queries) CreateJobLog(ctx context.Context, jobID uuid.UUID) (JobLog, error) {
+	row := q.db.QueryRowContext(ctx, CreateJobLog, jobID)
+	var i JobLog
+	err := row.Scan(
+		&i.ID,
+		&i.JobID,
+		&i.Status,
+		&i.Message,
+		&i.StartedAt,
+		&i.CompletedAt,
+		&i.ExecutionTimeMs,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const DeleteJob = `-- name: DeleteJob :exec
 DELETE FROM jobs WHERE id = $1 AND domain_id = $2
 `
@@ -126,6 +194,29 @@ func (q *Queries) DeleteJobsByDomain(ctx context.Context, domainID uuid.UUID) er
 	return err
 }
 
+const FinishJobLog = `-- name: FinishJobLog :exec
+UPDATE job_logs
+SET status = $2, message = $3, completed_at = NOW(), execution_time_ms = $4
+WHERE id = $1
+`
+
+type FinishJobLogParams struct {
+	ID              uuid.UUID      `json:"id"`
+	Status          string         `json:"status"`
+	Message         sql.NullString `json:"message"`
+	ExecutionTimeMs sql.NullInt32  `json:"execution_time_ms"`
+}
+
+func (q *Queries) FinishJobLog(ctx context.Context, arg FinishJobLogParams) error {
+	_, err := q.db.ExecContext(ctx, FinishJobLog,
+		arg.ID,
+		arg.Status,
+		arg.Message,
+		arg.ExecutionTimeMs,
+	)
+	return err
+}
+
 const GetJob = `-- name: GetJob :one
 SELECT id, domain_id, name, description, job_type, parameters, schedule, is_active, last_run, next_run, created_at, updated_at FROM jobs WHERE id = $1
 `
@@ -680,6 +771,27 @@ func (q *Queries) SearchJobs(ctx context.Context, arg SearchJobsParams) ([]Searc
 	return items, nil
 }
 
+const SeedDomainJobs = `-- name: SeedDomainJobs :execrows
+INSERT INTO jobs (domain_id, name, description, job_type, parameters, schedule, is_active)
+SELECT d.id, j.name, j.description, j.job_type, j.parameters, j.schedule, j.is_active
+FROM domains d
+JOIN jobs j ON j.domain_id = (SELECT id FROM domains WHERE name = 'default')
+WHERE d.is_active = TRUE
+AND j.name = ANY($1::text[])
+AND NOT EXISTS (SELECT 1 FROM jobs o WHERE o.domain_id = d.id AND o.name = j.name)
+ON CONFLICT (domain_id, name) DO NOTHING
+`
+
+// Copies the named jobs of the default domain to every active domain that
+// does not have them yet
+func (q *Queries) SeedDomainJobs(ctx context.Context, names []string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, SeedDomainJobs, pq.Array(names))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const UpdateJob = `-- name: UpdateJob :one
 UPDATE jobs 
 SET 
@@ -0,0 +1,422 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: locations.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const CreateAddress = `-- name: CreateAddress :one
+INSERT INTO addresses (
+    contact_id, location_type_id, is_primary, is_billing, street_address,
+    street_number, street_name, street_unit, supplemental_address_1, city,
+    state_province_id, postal_code, country_id
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+) RETURNING id, contact_id, location_type_id, is_primary, is_billing, street_address, street_number, street_name, street_unit, city, state_province_id, postal_code, country_id, geo_code_1, geo_code_2, created_at, updated_at, supplemental_address_1, geocoded_at, manual_geo_code, master_id
+`
+
+type CreateAddressParams struct {
+	ContactID            uuid.UUID      `json:"contact_id"`
+	LocationTypeID       uuid.NullUUID  `json:"location_type_id"`
+	IsPrimary            sql.NullBool   `json:"is_primary"`
+	IsBilling            sql.NullBool   `json:"is_billing"`
+	StreetAddress        sql.NullString `json:"street_address"`
+	StreetNumber         sql.NullString `json:"street_number"`
+	StreetName           sql.NullString `json:"street_name"`
+	StreetUnit           sql.NullString `json:"street_unit"`
+	SupplementalAddress1 sql.NullString `json:"supplemental_address_1"`
+	City                 sql.NullString `json:"city"`
+	StateProvinceID      uuid.NullUUID  `json:"state_province_id"`
+	PostalCode           sql.NullString `json:"postal_code"`
+	CountryID            uuid.NullUUID  `json:"country_id"`
+}
+
+func (q *Queries) CreateAddress(ctx context.Context, arg CreateAddressParams) (Address, error) {
+	row := q.db.QueryRowContext(ctx, CreateAddress,
+		arg.ContactID,
+		arg.LocationTypeID,
+		arg.IsPrimary,
+		arg.IsBilling,
+		arg.StreetAddress,
+		arg.StreetNumber,
+		arg.StreetName,
+		arg.StreetUnit,
+		arg.SupplementalAddress1,
+		arg.City,
+		arg.StateProvinceID,
+		arg.PostalCode,
+		arg.CountryID,
+	)
+	var i Address
+	err := row.Scan(
+		&i.ID,
+		&i.ContactID,
+		&i.LocationTypeID,
+		&i.IsPrimary,
+		&i.IsBilling,
+		&i.StreetAddress,
+		&i.StreetNumber,
+		&i.StreetName,
+		&i.StreetUnit,
+		&i.City,
+		&i.StateProvinceID,
+		&i.PostalCode,
+		&i.CountryID,
+		&i.GeoCode1,
+		&i.GeoCode2,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SupplementalAddress1,
+		&i.GeocodedAt,
+		&i.ManualGeoCode,
+		&i.MasterID,
+	)
+	return i, err
+}
+
+const CreateEmail = `-- name: CreateEmail :one
+
+INSERT INTO emails (
+    contact_id, location_type_id, is_primary, is_billing, email, on_hold, is_bulkmail
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING id, contact_id, location_type_id, is_primary, is_billing, email, on_hold, is_bulkmail, hold_date, reset_date, signature_text, signature_html, created_at, updated_at
+`
+
+type CreateEmailParams struct {
+	ContactID      uuid.UUID     `json:"contact_id"`
+	LocationTypeID uuid.NullUUID `json:"location_type_id"`
+	IsPrimary      sql.NullBool  `json:"is_primary"`
+	IsBilling      sql.NullBool  `json:"is_billing"`
+	Email          string        `json:"email"`
+	OnHold         sql.NullBool  `json:"on_hold"`
+	IsBulkmail     sql.NullBool  `json:"is_bulkmail"`
+}
+
+// Contact location queries. The triggers from migration 038 keep exactly one
+// primary record per contact and kind: creating or updating a record with
+// is_primary demotes the previous primary one.
+func (q *Queries) CreateEmail(ctx context.Context, arg CreateEmailParams) (Email, error) {
+	row := q.db.QueryRowContext(ctx, CreateEmail,
+		arg.ContactID,
+		arg.LocationTypeID,
+		arg.IsPrimary,
+		arg.IsBilling,
+		arg.Email,
+		arg.OnHold,
+		arg.IsBulkmail,
+	)
+	var i Email
+	err := row.Scan(
+		&i.ID,
+		&i.ContactID,
+		&i.LocationTypeID,
+		&i.IsPrimary,
+		&i.IsBilling,
+		&i.Email,
+		&i.OnHold,
+		&i.IsBulkmail,
+		&i.HoldDate,
+		&i.ResetDate,
+		&i.SignatureText,
+		&i.SignatureHtml,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const CreatePhone = `-- name: CreatePhone :one
+INSERT INTO phones (
+    contact_id, location_type_id, phone_type, is_primary, phone, phone_ext
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+) RETURNING id, contact_id, location_type_id, phone_type, is_primary, phone, phone_ext, created_at, updated_at
+`
+
+type CreatePhoneParams struct {
+	ContactID      uuid.UUID      `json:"contact_id"`
+	LocationTypeID uuid.NullUUID  `json:"location_type_id"`
+	PhoneType      sql.NullString `json:"phone_type"`
+	IsPrimary      sql.NullBool   `json:"is_primary"`
+	Phone          string         `json:"phone"`
+	PhoneExt       sql.NullString `json:"phone_ext"`
+}
+
+func (q *Queries) CreatePhone(ctx context.Context, arg CreatePhoneParams) (Phone, error) {
+	row := q.db.QueryRowContext(ctx, CreatePhone,
+		arg.ContactID,
+		arg.LocationTypeID,
+		arg.PhoneType,
+		arg.IsPrimary,
+		arg.Phone,
+		arg.PhoneExt,
+	)
+	var i Phone
+	err := row.Scan(
+		&i.ID,
+		&i.ContactID,
+		&i.LocationTypeID,
+		&i.PhoneType,
+		&i.IsPrimary,
+		&i.Phone,
+		&i.PhoneExt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const GetCountry = `-- name: GetCountry :one
+SELECT id, name, iso_code, numeric_code, is_active, created_at, updated_at FROM countries WHERE id = $1
+`
+
+func (q *Queries) GetCountry(ctx context.Context, id uuid.UUID) (Country, error) {
+	row := q.db.QueryRowContext(ctx, GetCountry, id)
+	var i Country
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.IsoCode,
+		&i.NumericCode,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const GetCountryByNameOrCode = `-- name: GetCountryByNameOrCode :one
+SELECT id, name, iso_code, numeric_code, is_active, created_at, updated_at FROM countries
+WHERE lower(name) = lower($1) OR lower(iso_code) = lower($1)
+LIMIT 1
+`
+
+func (q *Queries) GetCountryByNameOrCode(ctx context.Context, country string) (Country, error) {
+	row := q.db.QueryRowContext(ctx, GetCountryByNameOrCode, country)
+	var i Country
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.IsoCode,
+		&i.NumericCode,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const GetLocationTypeByName = `-- name: GetLocationTypeByName :one
+SELECT id, name, display_name, description, vcard_name, is_active, created_at, updated_at FROM location_types WHERE name = $1
+`
+
+func (q *Queries) GetLocationTypeByName(ctx context.Context, name string) (LocationType, error) {
+	row := q.db.QueryRowContext(ctx, GetLocationTypeByName, name)
+	var i LocationType
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.DisplayName,
+		&i.Description,
+		&i.VcardName,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const GetStateProvinceByName = `-- name: GetStateProvinceByName :one
+SELECT id, name, abbreviation, country_id, is_active, created_at, updated_at FROM state_provinces
+WHERE (lower(name) = lower($1) OR lower(abbreviation) = lower($1))
+  AND ($2::uuid IS NULL OR country_id = $2::uuid)
+ORDER BY name
+LIMIT 1
+`
+
+type GetStateProvinceByNameParams struct {
+	StateProvince string        `json:"state_province"`
+	CountryID     uuid.NullUUID `json:"country_id"`
+}
+
+func (q *Queries) GetStateProvinceByName(ctx context.Context, arg GetStateProvinceByNameParams) (StateProvince, error) {
+	row := q.db.QueryRowContext(ctx, GetStateProvinceByName, arg.StateProvince, arg.CountryID)
+	var i StateProvince
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Abbreviation,
+		&i.CountryID,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const ListPrimaryAddresses = `-- name: ListPrimaryAddresses :many
+SELECT id, contact_id, location_type_id, is_primary, is_billing, street_address, street_number, street_name, street_unit, city, state_province_id, postal_code, country_id, geo_code_1, geo_code_2, created_at, updated_at, supplemental_address_1, geocoded_at, manual_geo_code, master_id FROM addresses
+WHERE contact_id = ANY($1::uuid[]) AND is_primary
+`
+
+func (q *Queries) ListPrimaryAddresses(ctx context.Context, contactIds []uuid.UUID) ([]Address, error) {
+	rows, err := q.db.QueryContext(ctx, ListPrimaryAddresses, pq.Array(contactIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Address{}
+	for rows.Next() {
+		var i Address
+		if err := rows.Scan(
+			&i.ID,
+			&i.ContactID,
+			&i.LocationTypeID,
+			&i.IsPrimary,
+			&i.IsBilling,
+			&i.StreetAddress,
+			&i.StreetNumber,
+			&i.StreetName,
+			&i.StreetUnit,
+			&i.City,
+			&i.StateProvinceID,
+			&i.PostalCode,
+			&i.CountryID,
+			&i.GeoCode1,
+			&i.GeoCode2,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.SupplementalAddress1,
+			&i.GeocodedAt,
+			&i.ManualGeoCode,
+			&i.MasterID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListPrimaryEmails = `-- name: ListPrimaryEmails :many
+SELECT id, contact_id, location_type_id, is_primary, is_billing, email, on_hold, is_bulkmail, hold_date, reset_date, signature_text, signature_html, created_at, updated_at FROM emails
+WHERE contact_id = ANY($1::uuid[]) AND is_primary
+`
+
+func (q *Queries) ListPrimaryEmails(ctx context.Context, contactIds []uuid.UUID) ([]Email, error) {
+	rows, err := q.db.QueryContext(ctx, ListPrimaryEmails, pq.Array(contactIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Email{}
+	for rows.Next() {
+		var i Email
+		if err := rows.Scan(
+			&i.ID,
+			&i.ContactID,
+			&i.LocationTypeID,
+			&i.IsPrimary,
+			&i.IsBilling,
+			&i.Email,
+			&i.OnHold,
+			&i.IsBulkmail,
+			&i.HoldDate,
+			&i.ResetDate,
+			&i.SignatureText,
+			&i.SignatureHtml,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListPrimaryPhones = `-- name: ListPrimaryPhones :many
+SELECT id, contact_id, location_type_id, phone_type, is_primary, phone, phone_ext, created_at, updated_at FROM phones
+WHERE contact_id = ANY($1::uuid[]) AND is_primary
+`
+
+func (q *Queries) ListPrimaryPhones(ctx context.Context, contactIds []uuid.UUID) ([]Phone, error) {
+	rows, err := q.db.QueryContext(ctx, ListPrimaryPhones, pq.Array(contactIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Phone{}
+	for rows.Next() {
+		var i Phone
+		if err := rows.Scan(
+			&i.ID,
+			&i.ContactID,
+			&i.LocationTypeID,
+			&i.PhoneType,
+			&i.IsPrimary,
+			&i.Phone,
+			&i.PhoneExt,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const SetPrimaryAddress = `-- name: SetPrimaryAddress :exec
+UPDATE addresses SET is_primary = TRUE, updated_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) SetPrimaryAddress(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, SetPrimaryAddress, id)
+	return err
+}
+
+const SetPrimaryEmail = `-- name: SetPrimaryEmail :exec
+UPDATE emails SET is_primary = TRUE, updated_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) SetPrimaryEmail(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, SetPrimaryEmail, id)
+	return err
+}
+
+const SetPrimaryPhone = `-- name: SetPrimaryPhone :exec
+UPDATE phones SET is_primary = TRUE, updated_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) SetPrimaryPhone(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, SetPrimaryPhone, id)
+	return err
+}
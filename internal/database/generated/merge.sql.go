@@ -0,0 +1,867 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: merge.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const CreateContactMerge = `-- name: CreateContactMerge :one
+INSERT INTO contact_merges (
+    main_contact_id, other_contact_id, field_choices, moved, other_snapshot, merged_by
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+) RETURNING id, main_contact_id, other_contact_id, field_choices, moved, other_snapshot, merged_by, created_at
+`
+
+type CreateContactMergeParams struct {
+	MainContactID  uuid.UUID       `json:"main_contact_id"`
+	OtherContactID uuid.UUID       `json:"other_contact_id"`
+	FieldChoices   json.RawMessage `json:"field_choices"`
+	Moved          json.RawMessage `json:"moved"`
+	OtherSnapshot  json.RawMessage `json:"other_snapshot"`
+	MergedBy       uuid.NullUUID   `json:"merged_by"`
+}
+
+func (q *Queries) CreateContactMerge(ctx context.Context, arg CreateContactMergeParams) (ContactMerge, error) {
+	row := q.db.QueryRowContext(ctx, CreateContactMerge,
+		arg.MainContactID,
+		arg.OtherContactID,
+		arg.FieldChoices,
+		arg.Moved,
+		arg.OtherSnapshot,
+		arg.MergedBy,
+	)
+	var i ContactMerge
+	err := row.Scan(
+		&i.ID,
+		&i.MainContactID,
+		&i.OtherContactID,
+		&i.FieldChoices,
+		&i.Moved,
+		&i.OtherSnapshot,
+		&i.MergedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const DeleteMergedACLCache = `-- name: DeleteMergedACLCache :execrows
+DELETE FROM acl_contact_cache WHERE contact_id = $1 OR user_id = $1
+`
+
+// Cached permissions of the duplicate are recomputed for the kept contact
+// on the next check.
+func (q *Queries) DeleteMergedACLCache(ctx context.Context, otherID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, DeleteMergedACLCache, otherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const DeleteSelfRelationships = `-- name: DeleteSelfRelationships :execrows
+DELETE FROM relationships WHERE contact_id_a = $1 AND contact_id_b = $1
+`
+
+// Relationships between the two contacts become relationships of the kept
+// contact with itself after the moves above.
+func (q *Queries) DeleteSelfRelationships(ctx context.Context, mainID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, DeleteSelfRelationships, mainID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const ListContactMerges = `-- name: ListContactMerges :many
+SELECT id, main_contact_id, other_contact_id, field_choices, moved, other_snapshot, merged_by, created_at FROM contact_merges
+WHERE main_contact_id = $1 OR other_contact_id = $1
+ORDER BY created_at DESC
+`
+
+func (q *Queries) ListContactMerges(ctx context.Context, contactID uuid.UUID) ([]ContactMerge, error) {
+	rows, err := q.db.QueryContext(ctx, ListContactMerges, contactID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ContactMerge{}
+	for rows.Next() {
+		var i ContactMerge
+		if err := rows.Scan(
+			&i.ID,
+			&i.MainContactID,
+			&i.OtherContactID,
+			&i.FieldChoices,
+			&i.Moved,
+			&i.OtherSnapshot,
+			&i.MergedBy,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const LockContactsForMerge = `-- name: LockContactsForMerge :many
+
+SELECT id, contact_type, first_name, last_name, organization_name, created_at, updated_at, domain_id, is_deleted, merged_to_id, prefix, suffix, nick_name, household_name, display_name, sort_name, contact_sub_type, employer_id, anonymized_at FROM contacts
+WHERE id IN ($1::uuid, $2::uuid)
+ORDER BY id
+FOR UPDATE
+`
+
+type LockContactsForMergeParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+// Contact merge queries. Each move takes the kept contact as @main_id and
+// the duplicate as @other_id. Rows that would duplicate one the kept contact
+// already has stay with the deleted duplicate as history.
+func (q *Queries) LockContactsForMerge(ctx context.Context, arg LockContactsForMergeParams) ([]Contact, error) {
+	rows, err := q.db.QueryContext(ctx, LockContactsForMerge, arg.MainID, arg.OtherID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Contact{}
+	for rows.Next() {
+		var i Contact
+		if err := rows.Scan(
+			&i.ID,
+			&i.ContactType,
+			&i.FirstName,
+			&i.LastName,
+			&i.OrganizationName,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DomainID,
+			&i.IsDeleted,
+			&i.MergedToID,
+			&i.Prefix,
+			&i.Suffix,
+			&i.NickName,
+			&i.HouseholdName,
+			&i.DisplayName,
+			&i.SortName,
+			pq.Array(&i.ContactSubType),
+			&i.EmployerID,
+			&i.AnonymizedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const MarkContactMerged = `-- name: MarkContactMerged :exec
+UPDATE contacts
+SET is_deleted = TRUE, merged_to_id = $1::uuid, updated_at = NOW()
+WHERE id = $2
+`
+
+type MarkContactMergedParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+func (q *Queries) MarkContactMerged(ctx context.Context, arg MarkContactMergedParams) error {
+	_, err := q.db.ExecContext(ctx, MarkContactMerged, arg.MainID, arg.OtherID)
+	return err
+}
+
+const MergeCommunicationPreferences = `-- name: MergeCommunicationPreferences :execrows
+UPDATE communication_preferences m
+SET email_opt_out = m.email_opt_out OR o.email_opt_out,
+    sms_opt_out = m.sms_opt_out OR o.sms_opt_out,
+    mail_opt_out = m.mail_opt_out OR o.mail_opt_out,
+    phone_opt_out = m.phone_opt_out OR o.phone_opt_out,
+    do_not_email = m.do_not_email OR o.do_not_email,
+    do_not_sms = m.do_not_sms OR o.do_not_sms,
+    do_not_mail = m.do_not_mail OR o.do_not_mail,
+    do_not_phone = m.do_not_phone OR o.do_not_phone,
+    do_not_trade = m.do_not_trade OR o.do_not_trade,
+    updated_at = NOW()
+FROM communication_preferences o
+WHERE m.contact_id = $1 AND o.contact_id = $2
+`
+
+type MergeCommunicationPreferencesParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+// An opt out on either contact is kept, so merging never re-subscribes
+// anyone.
+func (q *Queries) MergeCommunicationPreferences(ctx context.Context, arg MergeCommunicationPreferencesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, MergeCommunicationPreferences, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const MoveMergedActivityAssignments = `-- name: MoveMergedActivityAssignments :execrows
+UPDATE activity_assignments aa SET assignee_contact_id = $1
+WHERE aa.assignee_contact_id = $2
+  AND NOT EXISTS (SELECT 1 FROM activity_assignments m WHERE m.assignee_contact_id = $1 AND m.activity_id = aa.activity_id)
+`
+
+type MoveMergedActivityAssignmentsParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+func (q *Queries) MoveMergedActivityAssignments(ctx context.Context, arg MoveMergedActivityAssignmentsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, MoveMergedActivityAssignments, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const MoveMergedActivityContacts = `-- name: MoveMergedActivityContacts :execrows
+UPDATE activity_contacts ac SET contact_id = $1
+WHERE ac.contact_id = $2
+  AND NOT EXISTS (
+      SELECT 1 FROM activity_contacts m
+      WHERE m.contact_id = $1 AND m.activity_id = ac.activity_id AND m.role IS NOT DISTINCT FROM ac.role
+  )
+`
+
+type MoveMergedActivityContactsParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+func (q *Queries) MoveMergedActivityContacts(ctx context.Context, arg MoveMergedActivityContactsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, MoveMergedActivityContacts, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const MoveMergedAddresses = `-- name: MoveMergedAddresses :execrows
+UPDATE addresses x
+SET contact_id = $1,
+    is_primary = x.is_primary AND NOT EXISTS (SELECT 1 FROM addresses a WHERE a.contact_id = $1 AND a.is_primary)
+WHERE x.contact_id = $2
+`
+
+type MoveMergedAddressesParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+func (q *Queries) MoveMergedAddresses(ctx context.Context, arg MoveMergedAddressesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, MoveMergedAddresses, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const MoveMergedCampaignContacts = `-- name: MoveMergedCampaignContacts :execrows
+UPDATE campaign_contacts cc SET contact_id = $1
+WHERE cc.contact_id = $2
+  AND NOT EXISTS (SELECT 1 FROM campaign_contacts m WHERE m.contact_id = $1 AND m.campaign_id = cc.campaign_id)
+`
+
+type MoveMergedCampaignContactsParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+func (q *Queries) MoveMergedCampaignContacts(ctx context.Context, arg MoveMergedCampaignContactsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, MoveMergedCampaignContacts, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const MoveMergedCaseContacts = `-- name: MoveMergedCaseContacts :execrows
+UPDATE case_contacts cc SET contact_id = $1
+WHERE cc.contact_id = $2
+  AND NOT EXISTS (
+      SELECT 1 FROM case_contacts m
+      WHERE m.contact_id = $1 AND m.case_id = cc.case_id AND m.role IS NOT DISTINCT FROM cc.role
+  )
+`
+
+type MoveMergedCaseContactsParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+func (q *Queries) MoveMergedCaseContacts(ctx context.Context, arg MoveMergedCaseContactsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, MoveMergedCaseContacts, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const MoveMergedCommunicationPreferences = `-- name: MoveMergedCommunicationPreferences :execrows
+UPDATE communication_preferences cp SET contact_id = $1
+WHERE cp.contact_id = $2
+  AND NOT EXISTS (SELECT 1 FROM communication_preferences m WHERE m.contact_id = $1)
+`
+
+type MoveMergedCommunicationPreferencesParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+func (q *Queries) MoveMergedCommunicationPreferences(ctx context.Context, arg MoveMergedCommunicationPreferencesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, MoveMergedCommunicationPreferences, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const MoveMergedContributions = `-- name: MoveMergedContributions :execrows
+UPDATE contributions SET contact_id = $1 WHERE contact_id = $2
+`
+
+type MoveMergedContributionsParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+func (q *Queries) MoveMergedContributions(ctx context.Context, arg MoveMergedContributionsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, MoveMergedContributions, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const MoveMergedCustomValues = `-- name: MoveMergedCustomValues :execrows
+UPDATE custom_values cv SET entity_id = $1
+WHERE cv.entity_table = 'contacts' AND cv.entity_id = $2
+  AND NOT EXISTS (
+      SELECT 1 FROM custom_values m
+      WHERE m.entity_table = 'contacts' AND m.entity_id = $1 AND m.custom_field_id = cv.custom_field_id
+  )
+`
+
+type MoveMergedCustomValuesParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+func (q *Queries) MoveMergedCustomValues(ctx context.Context, arg MoveMergedCustomValuesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, MoveMergedCustomValues, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const MoveMergedEmails = `-- name: MoveMergedEmails :execrows
+
+UPDATE emails x
+SET contact_id = $1,
+    is_primary = x.is_primary AND NOT EXISTS (SELECT 1 FROM emails e WHERE e.contact_id = $1 AND e.is_primary)
+WHERE x.contact_id = $2
+`
+
+type MoveMergedEmailsParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+// Location records keep their primary flag only when the kept contact has no
+// primary record of that kind.
+func (q *Queries) MoveMergedEmails(ctx context.Context, arg MoveMergedEmailsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, MoveMergedEmails, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const MoveMergedEntityTags = `-- name: MoveMergedEntityTags :execrows
+UPDATE entity_tags et SET entity_id = $1
+WHERE et.entity_table = 'contacts' AND et.entity_id = $2
+  AND NOT EXISTS (
+      SELECT 1 FROM entity_tags m
+      WHERE m.entity_table = 'contacts' AND m.entity_id = $1 AND m.tag_id = et.tag_id
+  )
+`
+
+type MoveMergedEntityTagsParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+func (q *Queries) MoveMergedEntityTags(ctx context.Context, arg MoveMergedEntityTagsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, MoveMergedEntityTags, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const MoveMergedEventRegistrations = `-- name: MoveMergedEventRegistrations :execrows
+UPDATE event_registrations SET contact_id = $1 WHERE contact_id = $2
+`
+
+type MoveMergedEventRegistrationsParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+func (q *Queries) MoveMergedEventRegistrations(ctx context.Context, arg MoveMergedEventRegistrationsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, MoveMergedEventRegistrations, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const MoveMergedGroupContacts = `-- name: MoveMergedGroupContacts :execrows
+UPDATE group_contacts gc SET contact_id = $1
+WHERE gc.contact_id = $2
+  AND NOT EXISTS (SELECT 1 FROM group_contacts m WHERE m.contact_id = $1 AND m.group_id = gc.group_id)
+`
+
+type MoveMergedGroupContactsParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+func (q *Queries) MoveMergedGroupContacts(ctx context.Context, arg MoveMergedGroupContactsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, MoveMergedGroupContacts, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const MoveMergedIMs = `-- name: MoveMergedIMs :execrows
+UPDATE ims SET contact_id = $1 WHERE contact_id = $2
+`
+
+type MoveMergedIMsParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+func (q *Queries) MoveMergedIMs(ctx context.Context, arg MoveMergedIMsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, MoveMergedIMs, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const MoveMergedLocationBackup = `-- name: MoveMergedLocationBackup :execrows
+UPDATE contact_location_backup SET contact_id = $1::uuid WHERE contact_id = $2::uuid
+`
+
+type MoveMergedLocationBackupParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+func (q *Queries) MoveMergedLocationBackup(ctx context.Context, arg MoveMergedLocationBackupParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, MoveMergedLocationBackup, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const MoveMergedMailingClicks = `-- name: MoveMergedMailingClicks :execrows
+UPDATE mailing_url_clicks SET contact_id = $1 WHERE contact_id = $2
+`
+
+type MoveMergedMailingClicksParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+func (q *Queries) MoveMergedMailingClicks(ctx context.Context, arg MoveMergedMailingClicksParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, MoveMergedMailingClicks, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const MoveMergedMailingOpens = `-- name: MoveMergedMailingOpens :execrows
+UPDATE mailing_opens SET contact_id = $1 WHERE contact_id = $2
+`
+
+type MoveMergedMailingOpensParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+func (q *Queries) MoveMergedMailingOpens(ctx context.Context, arg MoveMergedMailingOpensParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, MoveMergedMailingOpens, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const MoveMergedMailingRecipients = `-- name: MoveMergedMailingRecipients :execrows
+UPDATE mailing_recipients r SET contact_id = $1
+WHERE r.contact_id = $2
+  AND NOT EXISTS (SELECT 1 FROM mailing_recipients m WHERE m.contact_id = $1 AND m.mailing_id = r.mailing_id)
+`
+
+type MoveMergedMailingRecipientsParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+func (q *Queries) MoveMergedMailingRecipients(ctx context.Context, arg MoveMergedMailingRecipientsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, MoveMergedMailingRecipients, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const MoveMergedMailingSubscriptions = `-- name: MoveMergedMailingSubscriptions :execrows
+UPDATE mailing_list_subscriptions s SET contact_id = $1
+WHERE s.contact_id = $2
+  AND NOT EXISTS (SELECT 1 FROM mailing_list_subscriptions m WHERE m.contact_id = $1 AND m.mailing_list_id = s.mailing_list_id)
+`
+
+type MoveMergedMailingSubscriptionsParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+func (q *Queries) MoveMergedMailingSubscriptions(ctx context.Context, arg MoveMergedMailingSubscriptionsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, MoveMergedMailingSubscriptions, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const MoveMergedMemberships = `-- name: MoveMergedMemberships :execrows
+UPDATE memberships SET contact_id = $1 WHERE contact_id = $2
+`
+
+type MoveMergedMembershipsParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+func (q *Queries) MoveMergedMemberships(ctx context.Context, arg MoveMergedMembershipsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, MoveMergedMemberships, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const MoveMergedParticipantRegistrations = `-- name: MoveMergedParticipantRegistrations :execrows
+UPDATE participants SET registered_by_id = $1::uuid WHERE registered_by_id = $2::uuid
+`
+
+type MoveMergedParticipantRegistrationsParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+func (q *Queries) MoveMergedParticipantRegistrations(ctx context.Context, arg MoveMergedParticipantRegistrationsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, MoveMergedParticipantRegistrations, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const MoveMergedParticipants = `-- name: MoveMergedParticipants :execrows
+UPDATE participants SET contact_id = $1 WHERE contact_id = $2
+`
+
+type MoveMergedParticipantsParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+func (q *Queries) MoveMergedParticipants(ctx context.Context, arg MoveMergedParticipantsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, MoveMergedParticipants, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const MoveMergedPhones = `-- name: MoveMergedPhones :execrows
+UPDATE phones x
+SET contact_id = $1,
+    is_primary = x.is_primary AND NOT EXISTS (SELECT 1 FROM phones p WHERE p.contact_id = $1 AND p.is_primary)
+WHERE x.contact_id = $2
+`
+
+type MoveMergedPhonesParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+func (q *Queries) MoveMergedPhones(ctx context.Context, arg MoveMergedPhonesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, MoveMergedPhones, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const MoveMergedPledgeGroupMembers = `-- name: MoveMergedPledgeGroupMembers :execrows
+UPDATE pledge_group_members pgm SET contact_id = $1
+WHERE pgm.contact_id = $2
+  AND NOT EXISTS (SELECT 1 FROM pledge_group_members m WHERE m.contact_id = $1 AND m.pledge_group_id = pgm.pledge_group_id)
+`
+
+type MoveMergedPledgeGroupMembersParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+func (q *Queries) MoveMergedPledgeGroupMembers(ctx context.Context, arg MoveMergedPledgeGroupMembersParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, MoveMergedPledgeGroupMembers, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const MoveMergedPledges = `-- name: MoveMergedPledges :execrows
+UPDATE pledges SET contact_id = $1 WHERE contact_id = $2
+`
+
+type MoveMergedPledgesParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+func (q *Queries) MoveMergedPledges(ctx context.Context, arg MoveMergedPledgesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, MoveMergedPledges, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const MoveMergedRelationshipsA = `-- name: MoveMergedRelationshipsA :execrows
+UPDATE relationships SET contact_id_a = $1 WHERE contact_id_a = $2
+`
+
+type MoveMergedRelationshipsAParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+func (q *Queries) MoveMergedRelationshipsA(ctx context.Context, arg MoveMergedRelationshipsAParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, MoveMergedRelationshipsA, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const MoveMergedRelationshipsB = `-- name: MoveMergedRelationshipsB :execrows
+UPDATE relationships SET contact_id_b = $1 WHERE contact_id_b = $2
+`
+
+type MoveMergedRelationshipsBParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+func (q *Queries) MoveMergedRelationshipsB(ctx context.Context, arg MoveMergedRelationshipsBParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, MoveMergedRelationshipsB, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const MoveMergedSMSMessages = `-- name: MoveMergedSMSMessages :execrows
+UPDATE sms_messages SET contact_id = $1 WHERE contact_id = $2
+`
+
+type MoveMergedSMSMessagesParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+func (q *Queries) MoveMergedSMSMessages(ctx context.Context, arg MoveMergedSMSMessagesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, MoveMergedSMSMessages, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const MoveMergedSubscriptionHistory = `-- name: MoveMergedSubscriptionHistory :execrows
+UPDATE subscription_history SET contact_id = $1 WHERE contact_id = $2
+`
+
+type MoveMergedSubscriptionHistoryParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+// All of the duplicate's history moves, including that of memberships left
+// with it, so the kept contact's consent record is complete
+func (q *Queries) MoveMergedSubscriptionHistory(ctx context.Context, arg MoveMergedSubscriptionHistoryParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, MoveMergedSubscriptionHistory, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const MoveMergedSurveyResponses = `-- name: MoveMergedSurveyResponses :execrows
+UPDATE survey_responses SET contact_id = $1 WHERE contact_id = $2
+`
+
+type MoveMergedSurveyResponsesParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+func (q *Queries) MoveMergedSurveyResponses(ctx context.Context, arg MoveMergedSurveyResponsesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, MoveMergedSurveyResponses, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const MoveMergedWebsites = `-- name: MoveMergedWebsites :execrows
+UPDATE websites SET contact_id = $1 WHERE contact_id = $2
+`
+
+type MoveMergedWebsitesParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+func (q *Queries) MoveMergedWebsites(ctx context.Context, arg MoveMergedWebsitesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, MoveMergedWebsites, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const RedirectMergedContacts = `-- name: RedirectMergedContacts :execrows
+UPDATE contacts SET merged_to_id = $1::uuid WHERE merged_to_id = $2::uuid
+`
+
+type RedirectMergedContactsParams struct {
+	MainID  uuid.UUID `json:"main_id"`
+	OtherID uuid.UUID `json:"other_id"`
+}
+
+func (q *Queries) RedirectMergedContacts(ctx context.Context, arg RedirectMergedContactsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, RedirectMergedContacts, arg.MainID, arg.OtherID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const UpdateMergedContact = `-- name: UpdateMergedContact :one
+UPDATE contacts
+SET
+    contact_sub_type = $1,
+    prefix = $2,
+    first_name = $3,
+    last_name = $4,
+    suffix = $5,
+    nick_name = $6,
+    organization_name = $7,
+    household_name = $8,
+    updated_at = NOW()
+WHERE id = $9
+RETURNING id, contact_type, first_name, last_name, organization_name, created_at, updated_at, domain_id, is_deleted, merged_to_id, prefix, suffix, nick_name, household_name, display_name, sort_name, contact_sub_type, employer_id, anonymized_at
+`
+
+type UpdateMergedContactParams struct {
+	ContactSubType   []string       `json:"contact_sub_type"`
+	Prefix           sql.NullString `json:"prefix"`
+	FirstName        sql.NullString `json:"first_name"`
+	LastName         sql.NullString `json:"last_name"`
+	Suffix           sql.NullString `json:"suffix"`
+	NickName         sql.NullString `json:"nick_name"`
+	OrganizationName sql.NullString `json:"organization_name"`
+	HouseholdName    sql.NullString `json:"household_name"`
+	ID               uuid.UUID      `json:"id"`
+}
+
+func (q *Queries) UpdateMergedContact(ctx context.Context, arg UpdateMergedContactParams) (Contact, error) {
+	row := q.db.QueryRowContext(ctx, UpdateMergedContact,
+		pq.Array(arg.ContactSubType),
+		arg.Prefix,
+		arg.FirstName,
+		arg.LastName,
+		arg.Suffix,
+		arg.NickName,
+		arg.OrganizationName,
+		arg.HouseholdName,
+		arg.ID,
+	)
+	var i Contact
+	err := row.Scan(
+		&i.ID,
+		&i.ContactType,
+		&i.FirstName,
+		&i.LastName,
+		&i.OrganizationName,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DomainID,
+		&i.IsDeleted,
+		&i.MergedToID,
+		&i.Prefix,
+		&i.Suffix,
+		&i.NickName,
+		&i.HouseholdName,
+		&i.DisplayName,
+		&i.SortName,
+		pq.Array(&i.ContactSubType),
+		&i.EmployerID,
+		&i.AnonymizedAt,
+	)
+	return i, err
+}
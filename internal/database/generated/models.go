@@ -6,6 +6,7 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -37,12 +38,13 @@ type AclCache struct {
 }
 
 type AclContactCache struct {
-	ID        uuid.UUID     `json:"id"`
-	UserID    uuid.NullUUID `json:"user_id"`
-	ContactID uuid.UUID     `json:"contact_id"`
-	Operation string        `json:"operation"`
-	DomainID  uuid.UUID     `json:"domain_id"`
-	CreatedAt sql.NullTime  `json:"created_at"`
+	ID             uuid.UUID     `json:"id"`
+	UserID         uuid.NullUUID `json:"user_id"`
+	ContactID      uuid.UUID     `json:"contact_id"`
+	Operation      string        `json:"operation"`
+	DomainID       uuid.UUID     `json:"domain_id"`
+	CreatedAt      sql.NullTime  `json:"created_at"`
+	RelationshipID uuid.NullUUID `json:"relationship_id"`
 }
 
 type AclEntityRole struct {
@@ -94,6 +96,7 @@ type Activity struct {
 	ModifiedDate      sql.NullTime   `json:"modified_date"`
 	CreatedAt         sql.NullTime   `json:"created_at"`
 	UpdatedAt         sql.NullTime   `json:"updated_at"`
+	DomainID          uuid.UUID      `json:"domain_id"`
 }
 
 type ActivityAssignment struct {
@@ -165,23 +168,27 @@ type ActivityType struct {
 }
 
 type Address struct {
-	ID              uuid.UUID      `json:"id"`
-	ContactID       uuid.UUID      `json:"contact_id"`
-	LocationTypeID  uuid.NullUUID  `json:"location_type_id"`
-	IsPrimary       sql.NullBool   `json:"is_primary"`
-	IsBilling       sql.NullBool   `json:"is_billing"`
-	StreetAddress   sql.NullString `json:"street_address"`
-	StreetNumber    sql.NullString `json:"street_number"`
-	StreetName      sql.NullString `json:"street_name"`
-	StreetUnit      sql.NullString `json:"street_unit"`
-	City            sql.NullString `json:"city"`
-	StateProvinceID uuid.NullUUID  `json:"state_province_id"`
-	PostalCode      sql.NullString `json:"postal_code"`
-	CountryID       uuid.NullUUID  `json:"country_id"`
-	GeoCode1        sql.NullString `json:"geo_code_1"`
-	GeoCode2        sql.NullString `json:"geo_code_2"`
-	CreatedAt       sql.NullTime   `json:"created_at"`
-	UpdatedAt       sql.NullTime   `json:"updated_at"`
+	ID                   uuid.UUID      `json:"id"`
+	ContactID            uuid.UUID      `json:"contact_id"`
+	LocationTypeID       uuid.NullUUID  `json:"location_type_id"`
+	IsPrimary            sql.NullBool   `json:"is_primary"`
+	IsBilling            sql.NullBool   `json:"is_billing"`
+	StreetAddress        sql.NullString `json:"street_address"`
+	StreetNumber         sql.NullString `json:"street_number"`
+	StreetName           sql.NullString `json:"street_name"`
+	StreetUnit           sql.NullString `json:"street_unit"`
+	City                 sql.NullString `json:"city"`
+	StateProvinceID      uuid.NullUUID  `json:"state_province_id"`
+	PostalCode           sql.NullString `json:"postal_code"`
+	CountryID            uuid.NullUUID  `json:"country_id"`
+	GeoCode1             sql.NullString `json:"geo_code_1"`
+	GeoCode2             sql.NullString `json:"geo_code_2"`
+	CreatedAt            sql.NullTime   `json:"created_at"`
+	UpdatedAt            sql.NullTime   `json:"updated_at"`
+	SupplementalAddress1 sql.NullString `json:"supplemental_address_1"`
+	GeocodedAt           sql.NullTime   `json:"geocoded_at"`
+	ManualGeoCode        sql.NullBool   `json:"manual_geo_code"`
+	MasterID             uuid.NullUUID  `json:"master_id"`
 }
 
 type Campaign struct {
@@ -373,16 +380,54 @@ type Contact struct {
 	FirstName        sql.NullString `json:"first_name"`
 	LastName         sql.NullString `json:"last_name"`
 	OrganizationName sql.NullString `json:"organization_name"`
-	Email            sql.NullString `json:"email"`
-	Phone            sql.NullString `json:"phone"`
-	AddressLine1     sql.NullString `json:"address_line_1"`
-	AddressLine2     sql.NullString `json:"address_line_2"`
-	City             sql.NullString `json:"city"`
-	StateProvince    sql.NullString `json:"state_province"`
-	PostalCode       sql.NullString `json:"postal_code"`
-	Country          sql.NullString `json:"country"`
 	CreatedAt        sql.NullTime   `json:"created_at"`
 	UpdatedAt        sql.NullTime   `json:"updated_at"`
+	DomainID         uuid.UUID      `json:"domain_id"`
+	IsDeleted        bool           `json:"is_deleted"`
+	MergedToID       uuid.NullUUID  `json:"merged_to_id"`
+	Prefix           sql.NullString `json:"prefix"`
+	Suffix           sql.NullString `json:"suffix"`
+	NickName         sql.NullString `json:"nick_name"`
+	HouseholdName    sql.NullString `json:"household_name"`
+	DisplayName      sql.NullString `json:"display_name"`
+	SortName         sql.NullString `json:"sort_name"`
+	ContactSubType   []string       `json:"contact_sub_type"`
+	EmployerID       uuid.NullUUID  `json:"employer_id"`
+	AnonymizedAt     sql.NullTime   `json:"anonymized_at"`
+}
+
+type ContactLocationBackup struct {
+	ContactID     uuid.UUID      `json:"contact_id"`
+	Email         sql.NullString `json:"email"`
+	Phone         sql.NullString `json:"phone"`
+	AddressLine1  sql.NullString `json:"address_line_1"`
+	AddressLine2  sql.NullString `json:"address_line_2"`
+	City          sql.NullString `json:"city"`
+	StateProvince sql.NullString `json:"state_province"`
+	PostalCode    sql.NullString `json:"postal_code"`
+	Country       sql.NullString `json:"country"`
+}
+
+type ContactMerge struct {
+	ID             uuid.UUID       `json:"id"`
+	MainContactID  uuid.UUID       `json:"main_contact_id"`
+	OtherContactID uuid.UUID       `json:"other_contact_id"`
+	FieldChoices   json.RawMessage `json:"field_choices"`
+	Moved          json.RawMessage `json:"moved"`
+	OtherSnapshot  json.RawMessage `json:"other_snapshot"`
+	MergedBy       uuid.NullUUID   `json:"merged_by"`
+	CreatedAt      sql.NullTime    `json:"created_at"`
+}
+
+type ContactSubType struct {
+	ID          uuid.UUID      `json:"id"`
+	Name        string         `json:"name"`
+	Label       string         `json:"label"`
+	Parent      string         `json:"parent"`
+	Description sql.NullString `json:"description"`
+	IsActive    bool           `json:"is_active"`
+	CreatedAt   sql.NullTime   `json:"created_at"`
+	UpdatedAt   sql.NullTime   `json:"updated_at"`
 }
 
 type Contribution struct {
@@ -395,6 +440,7 @@ type Contribution struct {
 	ReceivedDate     sql.NullTime   `json:"received_date"`
 	CreatedAt        sql.NullTime   `json:"created_at"`
 	UpdatedAt        sql.NullTime   `json:"updated_at"`
+	DomainID         uuid.UUID      `json:"domain_id"`
 }
 
 type Country struct {
@@ -449,19 +495,20 @@ type CustomFieldOption struct {
 
 // Groups of custom fields that extend CiviCRM entities
 type CustomGroup struct {
-	ID              uuid.UUID      `json:"id"`
-	Name            string         `json:"name"`
-	Title           string         `json:"title"`
-	Extends         string         `json:"extends"`
-	TableName       sql.NullString `json:"table_name"`
-	IsActive        sql.NullBool   `json:"is_active"`
-	IsMultiple      sql.NullBool   `json:"is_multiple"`
-	CollapseDisplay sql.NullBool   `json:"collapse_display"`
-	HelpPre         sql.NullString `json:"help_pre"`
-	HelpPost        sql.NullString `json:"help_post"`
-	Weight          sql.NullInt32  `json:"weight"`
-	CreatedAt       sql.NullTime   `json:"created_at"`
-	UpdatedAt       sql.NullTime   `json:"updated_at"`
+	ID                       uuid.UUID      `json:"id"`
+	Name                     string         `json:"name"`
+	Title                    string         `json:"title"`
+	Extends                  string         `json:"extends"`
+	TableName                sql.NullString `json:"table_name"`
+	IsActive                 sql.NullBool   `json:"is_active"`
+	IsMultiple               sql.NullBool   `json:"is_multiple"`
+	CollapseDisplay          sql.NullBool   `json:"collapse_display"`
+	HelpPre                  sql.NullString `json:"help_pre"`
+	HelpPost                 sql.NullString `json:"help_post"`
+	Weight                   sql.NullInt32  `json:"weight"`
+	CreatedAt                sql.NullTime   `json:"created_at"`
+	UpdatedAt                sql.NullTime   `json:"updated_at"`
+	ExtendsEntityColumnValue []string       `json:"extends_entity_column_value"`
 }
 
 // Actual values stored for custom fields on entities
@@ -507,6 +554,29 @@ type DashboardWidget struct {
 	UpdatedAt     sql.NullTime          `json:"updated_at"`
 }
 
+type DedupeRule struct {
+	ID          uuid.UUID     `json:"id"`
+	RuleGroupID uuid.UUID     `json:"rule_group_id"`
+	RuleTable   string        `json:"rule_table"`
+	RuleField   string        `json:"rule_field"`
+	RuleLength  sql.NullInt32 `json:"rule_length"`
+	RuleWeight  int32         `json:"rule_weight"`
+	CreatedAt   sql.NullTime  `json:"created_at"`
+	UpdatedAt   sql.NullTime  `json:"updated_at"`
+}
+
+type DedupeRuleGroup struct {
+	ID          uuid.UUID    `json:"id"`
+	Name        string       `json:"name"`
+	Title       string       `json:"title"`
+	ContactType string       `json:"contact_type"`
+	Used        string       `json:"used"`
+	Threshold   int32        `json:"threshold"`
+	IsReserved  sql.NullBool `json:"is_reserved"`
+	CreatedAt   sql.NullTime `json:"created_at"`
+	UpdatedAt   sql.NullTime `json:"updated_at"`
+}
+
 type Discount struct {
 	ID             uuid.UUID      `json:"id"`
 	Name           string         `json:"name"`
@@ -573,6 +643,7 @@ type Event struct {
 	MaxParticipants sql.NullInt32  `json:"max_participants"`
 	CreatedAt       sql.NullTime   `json:"created_at"`
 	UpdatedAt       sql.NullTime   `json:"updated_at"`
+	DomainID        uuid.UUID      `json:"domain_id"`
 }
 
 type EventFee struct {
@@ -682,6 +753,26 @@ type GroupContact struct {
 	UpdatedAt  sql.NullTime   `json:"updated_at"`
 }
 
+type GroupContactCache struct {
+	GroupID   uuid.UUID `json:"group_id"`
+	ContactID uuid.UUID `json:"contact_id"`
+}
+
+type GroupDirectMember struct {
+	GroupID   uuid.UUID `json:"group_id"`
+	ContactID uuid.UUID `json:"contact_id"`
+}
+
+type GroupHierarchy struct {
+	AncestorID   uuid.UUID `json:"ancestor_id"`
+	DescendantID uuid.UUID `json:"descendant_id"`
+}
+
+type GroupMember struct {
+	GroupID   uuid.UUID `json:"group_id"`
+	ContactID uuid.UUID `json:"contact_id"`
+}
+
 type GroupNesting struct {
 	ID            uuid.UUID    `json:"id"`
 	ParentGroupID uuid.UUID    `json:"parent_group_id"`
@@ -791,6 +882,7 @@ type MailingList struct {
 	IsActive    sql.NullBool   `json:"is_active"`
 	CreatedAt   sql.NullTime   `json:"created_at"`
 	UpdatedAt   sql.NullTime   `json:"updated_at"`
+	GroupID     uuid.NullUUID  `json:"group_id"`
 }
 
 // Contact subscriptions to mailing lists
@@ -1032,6 +1124,18 @@ type PaymentInstrument struct {
 	UpdatedAt   sql.NullTime   `json:"updated_at"`
 }
 
+type PersonalDataRequest struct {
+	ID          uuid.UUID       `json:"id"`
+	DomainID    uuid.UUID       `json:"domain_id"`
+	ContactID   uuid.UUID       `json:"contact_id"`
+	RequestType string          `json:"request_type"`
+	Format      sql.NullString  `json:"format"`
+	Records     json.RawMessage `json:"records"`
+	Reason      sql.NullString  `json:"reason"`
+	ActorID     uuid.NullUUID   `json:"actor_id"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
 type Phone struct {
 	ID             uuid.UUID      `json:"id"`
 	ContactID      uuid.UUID      `json:"contact_id"`
@@ -1317,6 +1421,19 @@ type ReportTemplate struct {
 	UpdatedAt     sql.NullTime          `json:"updated_at"`
 }
 
+type SavedSearch struct {
+	ID          uuid.UUID       `json:"id"`
+	DomainID    uuid.UUID       `json:"domain_id"`
+	Name        string          `json:"name"`
+	Label       sql.NullString  `json:"label"`
+	Description sql.NullString  `json:"description"`
+	ApiEntity   string          `json:"api_entity"`
+	ApiParams   json.RawMessage `json:"api_params"`
+	CreatedBy   uuid.NullUUID   `json:"created_by"`
+	CreatedAt   sql.NullTime    `json:"created_at"`
+	UpdatedAt   sql.NullTime    `json:"updated_at"`
+}
+
 type Setting struct {
 	ID          uuid.UUID      `json:"id"`
 	DomainID    uuid.UUID      `json:"domain_id"`
@@ -1355,6 +1472,18 @@ type StateProvince struct {
 	UpdatedAt    sql.NullTime   `json:"updated_at"`
 }
 
+type SubscriptionHistory struct {
+	ID        uuid.UUID      `json:"id"`
+	GroupID   uuid.UUID      `json:"group_id"`
+	ContactID uuid.UUID      `json:"contact_id"`
+	Status    string         `json:"status"`
+	Method    string         `json:"method"`
+	Date      time.Time      `json:"date"`
+	ActorID   uuid.NullUUID  `json:"actor_id"`
+	Tracking  sql.NullString `json:"tracking"`
+	CreatedAt sql.NullTime   `json:"created_at"`
+}
+
 type Survey struct {
 	ID           uuid.UUID      `json:"id"`
 	Title        string         `json:"title"`
@@ -10,8 +10,49 @@ import (
 	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const CanUserEditContact = `-- name: CanUserEditContact :one
+SELECT (
+    EXISTS (
+        SELECT 1 FROM acls a
+        JOIN acl_entity_roles aer ON aer.acl_role_id = a.entity_id
+        WHERE aer.entity_table = 'users' AND aer.entity_id = $1::uuid
+          AND aer.is_active = true
+          AND a.entity_table = 'acl_roles' AND a.operation = 'Edit'
+          AND a.object_table = 'contacts' AND (a.object_id = $2::uuid OR a.object_id IS NULL)
+          AND a.is_active = true AND a.deny = false
+    ) OR EXISTS (
+        SELECT 1 FROM uf_match m
+        JOIN acl_contact_cache acc ON acc.user_id = m.contact_id
+        WHERE m.uf_id = $1::uuid AND acc.contact_id = $2::uuid AND acc.operation = 'Edit'
+    )
+) AND NOT EXISTS (
+    SELECT 1 FROM acls a
+    JOIN acl_entity_roles aer ON aer.acl_role_id = a.entity_id
+    WHERE aer.entity_table = 'users' AND aer.entity_id = $1::uuid
+      AND aer.is_active = true
+      AND a.entity_table = 'acl_roles' AND a.operation = 'Edit'
+      AND a.object_table = 'contacts' AND (a.object_id = $2::uuid OR a.object_id IS NULL)
+      AND a.is_active = true AND a.deny = true
+) AS allowed
+`
+
+type CanUserEditContactParams struct {
+	UserID    uuid.UUID `json:"user_id"`
+	ContactID uuid.UUID `json:"contact_id"`
+}
+
+// A user may edit a contact through an ACL role that is not denied, or a
+// relationship that grants the user's contact Edit on it
+func (q *Queries) CanUserEditContact(ctx context.Context, arg CanUserEditContactParams) (sql.NullBool, error) {
+	row := q.db.QueryRowContext(ctx, CanUserEditContact, arg.UserID, arg.ContactID)
+	var allowed sql.NullBool
+	err := row.Scan(&allowed)
+	return allowed, err
+}
+
 const CheckCampaignPermission = `-- name: CheckCampaignPermission :one
 SELECT COUNT(*) > 0 as has_permission FROM acls a
 INNER JOIN acl_entity_roles aer ON a.entity_id = aer.acl_role_id
@@ -529,7 +570,7 @@ func (q *Queries) GetUserAccessibleCases(ctx context.Context, arg GetUserAccessi
 }
 
 const GetUserAccessibleContacts = `-- name: GetUserAccessibleContacts :many
-SELECT DISTINCT c.id, c.contact_type, c.first_name, c.last_name, c.organization_name, c.email, c.phone, c.address_line_1, c.address_line_2, c.city, c.state_province, c.postal_code, c.country, c.created_at, c.updated_at FROM contacts c
+SELECT DISTINCT c.id, c.contact_type, c.first_name, c.last_name, c.organization_name, c.created_at, c.updated_at, c.domain_id, c.is_deleted, c.merged_to_id, c.prefix, c.suffix, c.nick_name, c.household_name, c.display_name, c.sort_name, c.contact_sub_type, c.employer_id, c.anonymized_at FROM contacts c
 INNER JOIN acl_contact_cache acc ON c.id = acc.contact_id
 WHERE acc.user_id = $1 
 AND acc.operation = $2
@@ -565,16 +606,20 @@ func (q *Queries) GetUserAccessibleContacts(ctx context.Context, arg GetUserAcce
 			&i.FirstName,
 			&i.LastName,
 			&i.OrganizationName,
-			&i.Email,
-			&i.Phone,
-			&i.AddressLine1,
-			&i.AddressLine2,
-			&i.City,
-			&i.StateProvince,
-			&i.PostalCode,
-			&i.Country,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
+			&i.IsDeleted,
+			&i.MergedToID,
+			&i.Prefix,
+			&i.Suffix,
+			&i.NickName,
+			&i.HouseholdName,
+			&i.DisplayName,
+			&i.SortName,
+			pq.Array(&i.ContactSubType),
+			&i.EmployerID,
+			&i.AnonymizedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -590,7 +635,7 @@ func (q *Queries) GetUserAccessibleContacts(ctx context.Context, arg GetUserAcce
 }
 
 const GetUserAccessibleContributions = `-- name: GetUserAccessibleContributions :many
-SELECT DISTINCT cont.id, cont.contact_id, cont.amount, cont.currency, cont.contribution_type, cont.status, cont.received_date, cont.created_at, cont.updated_at FROM contributions cont
+SELECT DISTINCT cont.id, cont.contact_id, cont.amount, cont.currency, cont.contribution_type, cont.status, cont.received_date, cont.created_at, cont.updated_at, cont.domain_id FROM contributions cont
 INNER JOIN acl_contact_cache acc ON cont.id = acc.contact_id
 WHERE acc.user_id = $1 
 AND acc.operation = $2
@@ -630,6 +675,7 @@ func (q *Queries) GetUserAccessibleContributions(ctx context.Context, arg GetUse
 			&i.ReceivedDate,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
 		); err != nil {
 			return nil, err
 		}
@@ -645,7 +691,7 @@ func (q *Queries) GetUserAccessibleContributions(ctx context.Context, arg GetUse
 }
 
 const GetUserAccessibleEvents = `-- name: GetUserAccessibleEvents :many
-SELECT DISTINCT e.id, e.title, e.description, e.start_date, e.end_date, e.location, e.max_participants, e.created_at, e.updated_at FROM events e
+SELECT DISTINCT e.id, e.title, e.description, e.start_date, e.end_date, e.location, e.max_participants, e.created_at, e.updated_at, e.domain_id FROM events e
 INNER JOIN acl_contact_cache acc ON e.id = acc.contact_id
 WHERE acc.user_id = $1 
 AND acc.operation = $2
@@ -685,6 +731,7 @@ func (q *Queries) GetUserAccessibleEvents(ctx context.Context, arg GetUserAccess
 			&i.MaxParticipants,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
 		); err != nil {
 			return nil, err
 		}
@@ -942,15 +989,15 @@ func (q *Queries) GetUserPermissionSummaryByType(ctx context.Context, entityID u
 }
 
 const SearchUserAccessibleContacts = `-- name: SearchUserAccessibleContacts :many
-SELECT DISTINCT c.id, c.contact_type, c.first_name, c.last_name, c.organization_name, c.email, c.phone, c.address_line_1, c.address_line_2, c.city, c.state_province, c.postal_code, c.country, c.created_at, c.updated_at FROM contacts c
+SELECT DISTINCT c.id, c.contact_type, c.first_name, c.last_name, c.organization_name, c.created_at, c.updated_at, c.domain_id, c.is_deleted, c.merged_to_id, c.prefix, c.suffix, c.nick_name, c.household_name, c.display_name, c.sort_name, c.contact_sub_type, c.employer_id, c.anonymized_at FROM contacts c
 INNER JOIN acl_contact_cache acc ON c.id = acc.contact_id
 WHERE acc.user_id = $1 
 AND acc.operation = $2
-AND c.is_active = true
+AND c.is_deleted = false
 AND (
     c.first_name ILIKE '%' || $3 || '%' OR
     c.last_name ILIKE '%' || $3 || '%' OR
-    c.email ILIKE '%' || $3 || '%'
+    EXISTS (SELECT 1 FROM emails e WHERE e.contact_id = c.id AND e.email ILIKE '%' || $3 || '%')
 )
 ORDER BY c.last_name, c.first_name
 LIMIT $4 OFFSET $5
@@ -985,16 +1032,20 @@ func (q *Queries) SearchUserAccessibleContacts(ctx context.Context, arg SearchUs
 			&i.FirstName,
 			&i.LastName,
 			&i.OrganizationName,
-			&i.Email,
-			&i.Phone,
-			&i.AddressLine1,
-			&i.AddressLine2,
-			&i.City,
-			&i.StateProvince,
-			&i.PostalCode,
-			&i.Country,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
+			&i.IsDeleted,
+			&i.MergedToID,
+			&i.Prefix,
+			&i.Suffix,
+			&i.NickName,
+			&i.HouseholdName,
+			&i.DisplayName,
+			&i.SortName,
+			pq.Array(&i.ContactSubType),
+			&i.EmployerID,
+			&i.AnonymizedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -1010,7 +1061,7 @@ func (q *Queries) SearchUserAccessibleContacts(ctx context.Context, arg SearchUs
 }
 
 const SearchUserAccessibleEvents = `-- name: SearchUserAccessibleEvents :many
-SELECT DISTINCT e.id, e.title, e.description, e.start_date, e.end_date, e.location, e.max_participants, e.created_at, e.updated_at FROM events e
+SELECT DISTINCT e.id, e.title, e.description, e.start_date, e.end_date, e.location, e.max_participants, e.created_at, e.updated_at, e.domain_id FROM events e
 INNER JOIN acl_contact_cache acc ON e.id = acc.contact_id
 WHERE acc.user_id = $1 
 AND acc.operation = $2
@@ -1053,6 +1104,7 @@ func (q *Queries) SearchUserAccessibleEvents(ctx context.Context, arg SearchUser
 			&i.MaxParticipants,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DomainID,
 		); err != nil {
 			return nil, err
 		}
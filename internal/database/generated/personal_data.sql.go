@@ -0,0 +1,836 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: personal_data.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const AnonymizeActivities = `-- name: AnonymizeActivities :execrows
+UPDATE activities
+SET subject = NULL, details = NULL, location = NULL, phone_id = NULL, phone_number = NULL, result = NULL,
+    updated_at = NOW()
+WHERE id IN (
+    SELECT activity_id FROM activity_contacts WHERE contact_id = ANY($1::uuid[])
+    UNION
+    SELECT activity_id FROM activity_assignments WHERE assignee_contact_id = ANY($1::uuid[])
+)
+`
+
+// Clears the text of activities the contact takes part in or is assigned
+func (q *Queries) AnonymizeActivities(ctx context.Context, contactIds []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, AnonymizeActivities, pq.Array(contactIds))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const AnonymizeContact = `-- name: AnonymizeContact :execrows
+UPDATE contacts
+SET prefix = NULL, first_name = NULL, last_name = NULL, suffix = NULL,
+    nick_name = NULL, organization_name = NULL, household_name = NULL,
+    display_name = 'Anonymous', sort_name = 'Anonymous', employer_id = NULL,
+    anonymized_at = NOW(), updated_at = NOW()
+WHERE id = ANY($1::uuid[])
+`
+
+func (q *Queries) AnonymizeContact(ctx context.Context, contactIds []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, AnonymizeContact, pq.Array(contactIds))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const AnonymizeContactMerges = `-- name: AnonymizeContactMerges :execrows
+UPDATE contact_merges SET other_snapshot = '{}'
+WHERE main_contact_id = ANY($1::uuid[]) OR other_contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) AnonymizeContactMerges(ctx context.Context, contactIds []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, AnonymizeContactMerges, pq.Array(contactIds))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const AnonymizeMailingClicks = `-- name: AnonymizeMailingClicks :execrows
+UPDATE mailing_url_clicks SET ip_address = NULL, user_agent = NULL
+WHERE contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) AnonymizeMailingClicks(ctx context.Context, contactIds []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, AnonymizeMailingClicks, pq.Array(contactIds))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const AnonymizeMailingOpens = `-- name: AnonymizeMailingOpens :execrows
+UPDATE mailing_opens SET ip_address = NULL, user_agent = NULL
+WHERE contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) AnonymizeMailingOpens(ctx context.Context, contactIds []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, AnonymizeMailingOpens, pq.Array(contactIds))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const AnonymizeMailingRecipients = `-- name: AnonymizeMailingRecipients :execrows
+UPDATE mailing_recipients SET email = '', bounce_reason = NULL, updated_at = NOW()
+WHERE contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) AnonymizeMailingRecipients(ctx context.Context, contactIds []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, AnonymizeMailingRecipients, pq.Array(contactIds))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const AnonymizeMailingSubscriptions = `-- name: AnonymizeMailingSubscriptions :execrows
+UPDATE mailing_list_subscriptions SET source = NULL, updated_at = NOW()
+WHERE contact_id = ANY($1::uuid[]) AND source IS NOT NULL
+`
+
+func (q *Queries) AnonymizeMailingSubscriptions(ctx context.Context, contactIds []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, AnonymizeMailingSubscriptions, pq.Array(contactIds))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const AnonymizeMemberships = `-- name: AnonymizeMemberships :execrows
+UPDATE memberships SET source = NULL, updated_at = NOW()
+WHERE contact_id = ANY($1::uuid[]) AND source IS NOT NULL
+`
+
+func (q *Queries) AnonymizeMemberships(ctx context.Context, contactIds []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, AnonymizeMemberships, pq.Array(contactIds))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const AnonymizeParticipants = `-- name: AnonymizeParticipants :execrows
+UPDATE participants SET source = NULL, updated_at = NOW()
+WHERE contact_id = ANY($1::uuid[]) AND source IS NOT NULL
+`
+
+func (q *Queries) AnonymizeParticipants(ctx context.Context, contactIds []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, AnonymizeParticipants, pq.Array(contactIds))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const AnonymizeSMSMessages = `-- name: AnonymizeSMSMessages :execrows
+UPDATE sms_messages SET phone_number = '', message = '', updated_at = NOW()
+WHERE contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) AnonymizeSMSMessages(ctx context.Context, contactIds []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, AnonymizeSMSMessages, pq.Array(contactIds))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const AnonymizeSurveyAnswers = `-- name: AnonymizeSurveyAnswers :execrows
+UPDATE survey_response_answers SET answer_text = NULL, updated_at = NOW()
+WHERE survey_response_id IN (SELECT id FROM survey_responses WHERE contact_id = ANY($1::uuid[]))
+  AND answer_text IS NOT NULL
+`
+
+// Free text answers are cleared; numeric, date and yes/no answers stay for
+// the survey's totals
+func (q *Queries) AnonymizeSurveyAnswers(ctx context.Context, contactIds []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, AnonymizeSurveyAnswers, pq.Array(contactIds))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const AnonymizeSurveyResponses = `-- name: AnonymizeSurveyResponses :execrows
+UPDATE survey_responses SET ip_address = NULL, user_agent = NULL, updated_at = NOW()
+WHERE contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) AnonymizeSurveyResponses(ctx context.Context, contactIds []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, AnonymizeSurveyResponses, pq.Array(contactIds))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const CreatePersonalDataRequest = `-- name: CreatePersonalDataRequest :one
+INSERT INTO personal_data_requests (
+    domain_id, contact_id, request_type, format, records, reason, actor_id
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING id, domain_id, contact_id, request_type, format, records, reason, actor_id, created_at
+`
+
+type CreatePersonalDataRequestParams struct {
+	DomainID    uuid.UUID       `json:"domain_id"`
+	ContactID   uuid.UUID       `json:"contact_id"`
+	RequestType string          `json:"request_type"`
+	Format      sql.NullString  `json:"format"`
+	Records     json.RawMessage `json:"records"`
+	Reason      sql.NullString  `json:"reason"`
+	ActorID     uuid.NullUUID   `json:"actor_id"`
+}
+
+func (q *Queries) CreatePersonalDataRequest(ctx context.Context, arg CreatePersonalDataRequestParams) (PersonalDataRequest, error) {
+	row := q.db.QueryRowContext(ctx, CreatePersonalDataRequest,
+		arg.DomainID,
+		arg.ContactID,
+		arg.RequestType,
+		arg.Format,
+		arg.Records,
+		arg.Reason,
+		arg.ActorID,
+	)
+	var i PersonalDataRequest
+	err := row.Scan(
+		&i.ID,
+		&i.DomainID,
+		&i.ContactID,
+		&i.RequestType,
+		&i.Format,
+		&i.Records,
+		&i.Reason,
+		&i.ActorID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const DeleteContactAddresses = `-- name: DeleteContactAddresses :execrows
+DELETE FROM addresses WHERE contact_id = ANY($1::uuid[])
+`
+
+// Addresses shared from these become independent of them
+func (q *Queries) DeleteContactAddresses(ctx context.Context, contactIds []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, DeleteContactAddresses, pq.Array(contactIds))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const DeleteContactCustomValues = `-- name: DeleteContactCustomValues :execrows
+DELETE FROM custom_values WHERE entity_table = 'contacts' AND entity_id = ANY($1::uuid[])
+`
+
+func (q *Queries) DeleteContactCustomValues(ctx context.Context, contactIds []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, DeleteContactCustomValues, pq.Array(contactIds))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const DeleteContactEmails = `-- name: DeleteContactEmails :execrows
+DELETE FROM emails WHERE contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) DeleteContactEmails(ctx context.Context, contactIds []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, DeleteContactEmails, pq.Array(contactIds))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const DeleteContactEntityTags = `-- name: DeleteContactEntityTags :execrows
+DELETE FROM entity_tags WHERE entity_table = 'contacts' AND entity_id = ANY($1::uuid[])
+`
+
+func (q *Queries) DeleteContactEntityTags(ctx context.Context, contactIds []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, DeleteContactEntityTags, pq.Array(contactIds))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const DeleteContactIMs = `-- name: DeleteContactIMs :execrows
+DELETE FROM ims WHERE contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) DeleteContactIMs(ctx context.Context, contactIds []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, DeleteContactIMs, pq.Array(contactIds))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const DeleteContactLocationBackup = `-- name: DeleteContactLocationBackup :execrows
+DELETE FROM contact_location_backup WHERE contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) DeleteContactLocationBackup(ctx context.Context, contactIds []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, DeleteContactLocationBackup, pq.Array(contactIds))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const DeleteContactPhones = `-- name: DeleteContactPhones :execrows
+DELETE FROM phones WHERE contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) DeleteContactPhones(ctx context.Context, contactIds []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, DeleteContactPhones, pq.Array(contactIds))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const DeleteContactRelationships = `-- name: DeleteContactRelationships :execrows
+DELETE FROM relationships WHERE contact_id_a = ANY($1::uuid[]) OR contact_id_b = ANY($1::uuid[])
+`
+
+func (q *Queries) DeleteContactRelationships(ctx context.Context, contactIds []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, DeleteContactRelationships, pq.Array(contactIds))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const DeleteContactUserAccounts = `-- name: DeleteContactUserAccounts :execrows
+DELETE FROM uf_match WHERE contact_id = ANY($1::uuid[])
+`
+
+// Unlinks logins; the user accounts themselves are left to the auth system
+func (q *Queries) DeleteContactUserAccounts(ctx context.Context, contactIds []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, DeleteContactUserAccounts, pq.Array(contactIds))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const DeleteContactWebsites = `-- name: DeleteContactWebsites :execrows
+DELETE FROM websites WHERE contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) DeleteContactWebsites(ctx context.Context, contactIds []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, DeleteContactWebsites, pq.Array(contactIds))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const ExportActivities = `-- name: ExportActivities :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) || jsonb_build_object('roles', r.roles) ORDER BY x.activity_date_time, x.id), '[]')::jsonb AS records
+FROM activities x
+JOIN (
+    SELECT p.activity_id, jsonb_agg(DISTINCT p.role) AS roles
+    FROM (
+        SELECT ac.activity_id, COALESCE(ac.role, 'Contact') AS role FROM activity_contacts ac WHERE ac.contact_id = ANY($1::uuid[])
+        UNION ALL
+        SELECT aa.activity_id, 'Assignee' FROM activity_assignments aa WHERE aa.assignee_contact_id = ANY($1::uuid[])
+    ) p
+    GROUP BY p.activity_id
+) r ON r.activity_id = x.id
+`
+
+// Activities the contact takes part in or is assigned, with their roles
+func (q *Queries) ExportActivities(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, ExportActivities, pq.Array(contactIds))
+	var records json.RawMessage
+	err := row.Scan(&records)
+	return records, err
+}
+
+const ExportAddresses = `-- name: ExportAddresses :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM addresses x WHERE x.contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) ExportAddresses(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, ExportAddresses, pq.Array(contactIds))
+	var records json.RawMessage
+	err := row.Scan(&records)
+	return records, err
+}
+
+const ExportCampaignContacts = `-- name: ExportCampaignContacts :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM campaign_contacts x WHERE x.contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) ExportCampaignContacts(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, ExportCampaignContacts, pq.Array(contactIds))
+	var records json.RawMessage
+	err := row.Scan(&records)
+	return records, err
+}
+
+const ExportCaseContacts = `-- name: ExportCaseContacts :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM case_contacts x WHERE x.contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) ExportCaseContacts(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, ExportCaseContacts, pq.Array(contactIds))
+	var records json.RawMessage
+	err := row.Scan(&records)
+	return records, err
+}
+
+const ExportCommunicationPreferences = `-- name: ExportCommunicationPreferences :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM communication_preferences x WHERE x.contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) ExportCommunicationPreferences(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, ExportCommunicationPreferences, pq.Array(contactIds))
+	var records json.RawMessage
+	err := row.Scan(&records)
+	return records, err
+}
+
+const ExportContact = `-- name: ExportContact :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.merged_to_id IS NOT NULL, x.created_at, x.id), '[]')::jsonb AS records
+FROM contacts x WHERE x.id = ANY($1::uuid[])
+`
+
+// The kept contact first, then its merged duplicates
+func (q *Queries) ExportContact(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, ExportContact, pq.Array(contactIds))
+	var records json.RawMessage
+	err := row.Scan(&records)
+	return records, err
+}
+
+const ExportContactLocationBackup = `-- name: ExportContactLocationBackup :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.contact_id), '[]')::jsonb AS records
+FROM contact_location_backup x WHERE x.contact_id = ANY($1::uuid[])
+`
+
+// Location values as they were before migrating to location records
+func (q *Queries) ExportContactLocationBackup(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, ExportContactLocationBackup, pq.Array(contactIds))
+	var records json.RawMessage
+	err := row.Scan(&records)
+	return records, err
+}
+
+const ExportContactMerges = `-- name: ExportContactMerges :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM contact_merges x
+WHERE x.main_contact_id = ANY($1::uuid[]) OR x.other_contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) ExportContactMerges(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, ExportContactMerges, pq.Array(contactIds))
+	var records json.RawMessage
+	err := row.Scan(&records)
+	return records, err
+}
+
+const ExportContributions = `-- name: ExportContributions :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM contributions x WHERE x.contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) ExportContributions(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, ExportContributions, pq.Array(contactIds))
+	var records json.RawMessage
+	err := row.Scan(&records)
+	return records, err
+}
+
+const ExportCustomValues = `-- name: ExportCustomValues :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) || jsonb_build_object('field_label', f.label) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM custom_values x
+JOIN custom_fields f ON f.id = x.custom_field_id
+WHERE x.entity_table = 'contacts' AND x.entity_id = ANY($1::uuid[])
+`
+
+func (q *Queries) ExportCustomValues(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, ExportCustomValues, pq.Array(contactIds))
+	var records json.RawMessage
+	err := row.Scan(&records)
+	return records, err
+}
+
+const ExportEmails = `-- name: ExportEmails :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM emails x WHERE x.contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) ExportEmails(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, ExportEmails, pq.Array(contactIds))
+	var records json.RawMessage
+	err := row.Scan(&records)
+	return records, err
+}
+
+const ExportEntityTags = `-- name: ExportEntityTags :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) || jsonb_build_object('tag_name', t.name) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM entity_tags x
+JOIN tags t ON t.id = x.tag_id
+WHERE x.entity_table = 'contacts' AND x.entity_id = ANY($1::uuid[])
+`
+
+func (q *Queries) ExportEntityTags(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, ExportEntityTags, pq.Array(contactIds))
+	var records json.RawMessage
+	err := row.Scan(&records)
+	return records, err
+}
+
+const ExportEventRegistrations = `-- name: ExportEventRegistrations :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM event_registrations x WHERE x.contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) ExportEventRegistrations(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, ExportEventRegistrations, pq.Array(contactIds))
+	var records json.RawMessage
+	err := row.Scan(&records)
+	return records, err
+}
+
+const ExportGroupContacts = `-- name: ExportGroupContacts :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) || jsonb_build_object('group_title', g.title) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM group_contacts x
+JOIN groups g ON g.id = x.group_id
+WHERE x.contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) ExportGroupContacts(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, ExportGroupContacts, pq.Array(contactIds))
+	var records json.RawMessage
+	err := row.Scan(&records)
+	return records, err
+}
+
+const ExportIMs = `-- name: ExportIMs :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM ims x WHERE x.contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) ExportIMs(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, ExportIMs, pq.Array(contactIds))
+	var records json.RawMessage
+	err := row.Scan(&records)
+	return records, err
+}
+
+const ExportMailingClicks = `-- name: ExportMailingClicks :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) || jsonb_build_object('url', u.url) ORDER BY x.clicked_at, x.id), '[]')::jsonb AS records
+FROM mailing_url_clicks x
+JOIN mailing_trackable_urls u ON u.id = x.trackable_url_id
+WHERE x.contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) ExportMailingClicks(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, ExportMailingClicks, pq.Array(contactIds))
+	var records json.RawMessage
+	err := row.Scan(&records)
+	return records, err
+}
+
+const ExportMailingOpens = `-- name: ExportMailingOpens :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.opened_at, x.id), '[]')::jsonb AS records
+FROM mailing_opens x WHERE x.contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) ExportMailingOpens(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, ExportMailingOpens, pq.Array(contactIds))
+	var records json.RawMessage
+	err := row.Scan(&records)
+	return records, err
+}
+
+const ExportMailingRecipients = `-- name: ExportMailingRecipients :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM mailing_recipients x WHERE x.contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) ExportMailingRecipients(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, ExportMailingRecipients, pq.Array(contactIds))
+	var records json.RawMessage
+	err := row.Scan(&records)
+	return records, err
+}
+
+const ExportMailingSubscriptions = `-- name: ExportMailingSubscriptions :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM mailing_list_subscriptions x WHERE x.contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) ExportMailingSubscriptions(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, ExportMailingSubscriptions, pq.Array(contactIds))
+	var records json.RawMessage
+	err := row.Scan(&records)
+	return records, err
+}
+
+const ExportMemberships = `-- name: ExportMemberships :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) || jsonb_build_object('logs', COALESCE(
+           (SELECT jsonb_agg(to_jsonb(l) ORDER BY l.created_at, l.id) FROM membership_logs l WHERE l.membership_id = x.id), '[]'::jsonb))
+       ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM memberships x WHERE x.contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) ExportMemberships(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, ExportMemberships, pq.Array(contactIds))
+	var records json.RawMessage
+	err := row.Scan(&records)
+	return records, err
+}
+
+const ExportParticipants = `-- name: ExportParticipants :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM participants x WHERE x.contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) ExportParticipants(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, ExportParticipants, pq.Array(contactIds))
+	var records json.RawMessage
+	err := row.Scan(&records)
+	return records, err
+}
+
+const ExportPersonalDataRequests = `-- name: ExportPersonalDataRequests :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM personal_data_requests x WHERE x.contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) ExportPersonalDataRequests(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, ExportPersonalDataRequests, pq.Array(contactIds))
+	var records json.RawMessage
+	err := row.Scan(&records)
+	return records, err
+}
+
+const ExportPhones = `-- name: ExportPhones :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM phones x WHERE x.contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) ExportPhones(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, ExportPhones, pq.Array(contactIds))
+	var records json.RawMessage
+	err := row.Scan(&records)
+	return records, err
+}
+
+const ExportPledges = `-- name: ExportPledges :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) || jsonb_build_object('payments', COALESCE(
+           (SELECT jsonb_agg(to_jsonb(p) ORDER BY p.created_at, p.id) FROM pledge_payments p WHERE p.pledge_id = x.id), '[]'::jsonb))
+       ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM pledges x WHERE x.contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) ExportPledges(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, ExportPledges, pq.Array(contactIds))
+	var records json.RawMessage
+	err := row.Scan(&records)
+	return records, err
+}
+
+const ExportRelationships = `-- name: ExportRelationships :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) || jsonb_build_object('name_a_b', t.name_a_b, 'name_b_a', t.name_b_a) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM relationships x
+JOIN relationship_types t ON t.id = x.relationship_type_id
+WHERE x.contact_id_a = ANY($1::uuid[]) OR x.contact_id_b = ANY($1::uuid[])
+`
+
+func (q *Queries) ExportRelationships(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, ExportRelationships, pq.Array(contactIds))
+	var records json.RawMessage
+	err := row.Scan(&records)
+	return records, err
+}
+
+const ExportSMSMessages = `-- name: ExportSMSMessages :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM sms_messages x WHERE x.contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) ExportSMSMessages(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, ExportSMSMessages, pq.Array(contactIds))
+	var records json.RawMessage
+	err := row.Scan(&records)
+	return records, err
+}
+
+const ExportSubscriptionHistory = `-- name: ExportSubscriptionHistory :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.date, x.id), '[]')::jsonb AS records
+FROM subscription_history x WHERE x.contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) ExportSubscriptionHistory(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, ExportSubscriptionHistory, pq.Array(contactIds))
+	var records json.RawMessage
+	err := row.Scan(&records)
+	return records, err
+}
+
+const ExportSurveyResponses = `-- name: ExportSurveyResponses :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) || jsonb_build_object('answers', COALESCE(
+           (SELECT jsonb_agg(to_jsonb(a) || jsonb_build_object('question', q.question_text) ORDER BY a.created_at, a.id)
+            FROM survey_response_answers a
+            JOIN survey_questions q ON q.id = a.survey_question_id
+            WHERE a.survey_response_id = x.id), '[]'::jsonb))
+       ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM survey_responses x WHERE x.contact_id = ANY($1::uuid[])
+`
+
+// Survey responses with their answers and the questions answered
+func (q *Queries) ExportSurveyResponses(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, ExportSurveyResponses, pq.Array(contactIds))
+	var records json.RawMessage
+	err := row.Scan(&records)
+	return records, err
+}
+
+const ExportUserAccounts = `-- name: ExportUserAccounts :one
+SELECT COALESCE(jsonb_agg(jsonb_build_object(
+           'username', u.username, 'email', u.email, 'is_active', u.is_active,
+           'last_login', u.last_login, 'created_at', u.created_at, 'language', m.language)
+       ORDER BY u.created_at, u.id), '[]')::jsonb AS records
+FROM uf_match m
+JOIN users u ON u.id = m.uf_id
+WHERE m.contact_id = ANY($1::uuid[])
+`
+
+// The logins linked to the contact, without their password hashes
+func (q *Queries) ExportUserAccounts(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, ExportUserAccounts, pq.Array(contactIds))
+	var records json.RawMessage
+	err := row.Scan(&records)
+	return records, err
+}
+
+const ExportWebsites = `-- name: ExportWebsites :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM websites x WHERE x.contact_id = ANY($1::uuid[])
+`
+
+func (q *Queries) ExportWebsites(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error) {
+	row := q.db.QueryRowContext(ctx, ExportWebsites, pq.Array(contactIds))
+	var records json.RawMessage
+	err := row.Scan(&records)
+	return records, err
+}
+
+const ListMergedContactIDs = `-- name: ListMergedContactIDs :many
+
+WITH RECURSIVE merged AS (
+    SELECT c.id FROM contacts c WHERE c.id = $1::uuid
+    UNION
+    SELECT c.id FROM contacts c JOIN merged m ON c.merged_to_id = m.id
+)
+SELECT id FROM merged
+`
+
+// Personal data queries. Each Export query returns one section of a
+// contact's data as a JSON array of rows; each Anonymize or Delete query
+// scrubs one kind of record and returns the rows changed. Financial
+// records keep their amounts. Both take the contact and every duplicate
+// merged into it as @contact_ids, since a merge leaves the rows the kept
+// contact already had, such as a group membership, with the duplicate.
+// The contact and every contact merged into it, directly or through
+// earlier merges
+func (q *Queries) ListMergedContactIDs(ctx context.Context, contactID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, ListMergedContactIDs, contactID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListPersonalDataRequests = `-- name: ListPersonalDataRequests :many
+SELECT id, domain_id, contact_id, request_type, format, records, reason, actor_id, created_at FROM personal_data_requests
+WHERE ($1::uuid IS NULL OR contact_id = $1::uuid)
+  AND ($2::uuid IS NULL OR domain_id = $2::uuid)
+ORDER BY created_at DESC, id DESC
+`
+
+type ListPersonalDataRequestsParams struct {
+	ContactID uuid.NullUUID `json:"contact_id"`
+	DomainID  uuid.NullUUID `json:"domain_id"`
+}
+
+// Requests of a contact, or of a domain, newest first
+func (q *Queries) ListPersonalDataRequests(ctx context.Context, arg ListPersonalDataRequestsParams) ([]PersonalDataRequest, error) {
+	rows, err := q.db.QueryContext(ctx, ListPersonalDataRequests, arg.ContactID, arg.DomainID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []PersonalDataRequest{}
+	for rows.Next() {
+		var i PersonalDataRequest
+		if err := rows.Scan(
+			&i.ID,
+			&i.DomainID,
+			&i.ContactID,
+			&i.RequestType,
+			&i.Format,
+			&i.Records,
+			&i.Reason,
+			&i.ActorID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
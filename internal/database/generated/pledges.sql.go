@@ -429,11 +429,12 @@ SELECT
     p.id, p.contact_id, p.campaign_id, p.pledge_block_id, p.amount, p.installments, p.frequency, p.frequency_interval, p.start_date, p.end_date, p.next_payment_date, p.status, p.currency, p.financial_type_id, p.payment_instrument_id, p.is_test, p.created_at, p.updated_at,
     c.first_name,
     c.last_name,
-    c.email,
+    pe.email,
     pp.scheduled_date as next_payment_date,
     pp.scheduled_amount as next_payment_amount
 FROM pledges p
 INNER JOIN contacts c ON p.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 INNER JOIN pledge_payments pp ON p.id = pp.pledge_id
 WHERE p.status = 'In Progress' 
   AND pp.status = 'Scheduled' 
@@ -766,10 +767,11 @@ SELECT
     p.contact_id,
     c.first_name,
     c.last_name,
-    c.email
+    pe.email
 FROM pledge_payments pp
 INNER JOIN pledges p ON pp.pledge_id = p.id
 INNER JOIN contacts c ON p.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE pp.pledge_id = $1
 ORDER BY pp.scheduled_date
 `
@@ -893,12 +895,13 @@ SELECT
     p.contact_id,
     c.first_name,
     c.last_name,
-    c.email,
+    pe.email,
     p.amount as pledge_amount,
     p.frequency
 FROM pledge_reminders pr
 INNER JOIN pledges p ON pr.pledge_id = p.id
 INNER JOIN contacts c ON p.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE pr.status = 'Scheduled' 
   AND pr.scheduled_date <= $1
   AND p.status IN ('In Progress', 'Overdue')
@@ -1091,9 +1094,10 @@ func (q *Queries) ListPledgeBlocksByCampaign(ctx context.Context, arg ListPledge
 }
 
 const ListPledgeGroupMembers = `-- name: ListPledgeGroupMembers :many
-SELECT pgm.id, pgm.pledge_group_id, pgm.contact_id, pgm.pledge_id, pgm.role, pgm.is_active, pgm.joined_date, pgm.created_at, pgm.updated_at, c.first_name, c.last_name, c.email
+SELECT pgm.id, pgm.pledge_group_id, pgm.contact_id, pgm.pledge_id, pgm.role, pgm.is_active, pgm.joined_date, pgm.created_at, pgm.updated_at, c.first_name, c.last_name, pe.email
 FROM pledge_group_members pgm
 INNER JOIN contacts c ON pgm.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE pgm.pledge_group_id = $1 AND pgm.is_active = $2
 ORDER BY pgm.role, c.last_name, c.first_name
 `
@@ -1303,10 +1307,11 @@ func (q *Queries) ListPledgePayments(ctx context.Context, pledgeID uuid.UUID) ([
 }
 
 const ListPledgePaymentsByStatus = `-- name: ListPledgePaymentsByStatus :many
-SELECT pp.id, pp.pledge_id, pp.contribution_id, pp.scheduled_amount, pp.actual_amount, pp.scheduled_date, pp.actual_date, pp.status, pp.reminder_count, pp.reminder_date, pp.created_at, pp.updated_at, p.contact_id, c.first_name, c.last_name, c.email
+SELECT pp.id, pp.pledge_id, pp.contribution_id, pp.scheduled_amount, pp.actual_amount, pp.scheduled_date, pp.actual_date, pp.status, pp.reminder_count, pp.reminder_date, pp.created_at, pp.updated_at, p.contact_id, c.first_name, c.last_name, pe.email
 FROM pledge_payments pp
 INNER JOIN pledges p ON pp.pledge_id = p.id
 INNER JOIN contacts c ON p.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE pp.status = $1
 ORDER BY pp.scheduled_date ASC
 `
@@ -1416,9 +1421,10 @@ func (q *Queries) ListPledgeReminders(ctx context.Context, arg ListPledgeReminde
 }
 
 const ListPledgesByBlock = `-- name: ListPledgesByBlock :many
-SELECT p.id, p.contact_id, p.campaign_id, p.pledge_block_id, p.amount, p.installments, p.frequency, p.frequency_interval, p.start_date, p.end_date, p.next_payment_date, p.status, p.currency, p.financial_type_id, p.payment_instrument_id, p.is_test, p.created_at, p.updated_at, c.first_name, c.last_name, c.email
+SELECT p.id, p.contact_id, p.campaign_id, p.pledge_block_id, p.amount, p.installments, p.frequency, p.frequency_interval, p.start_date, p.end_date, p.next_payment_date, p.status, p.currency, p.financial_type_id, p.payment_instrument_id, p.is_test, p.created_at, p.updated_at, c.first_name, c.last_name, pe.email
 FROM pledges p
 INNER JOIN contacts c ON p.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE p.pledge_block_id = $1 AND p.status = $2
 ORDER BY p.created_at DESC
 `
@@ -1498,9 +1504,10 @@ func (q *Queries) ListPledgesByBlock(ctx context.Context, arg ListPledgesByBlock
 }
 
 const ListPledgesByCampaign = `-- name: ListPledgesByCampaign :many
-SELECT p.id, p.contact_id, p.campaign_id, p.pledge_block_id, p.amount, p.installments, p.frequency, p.frequency_interval, p.start_date, p.end_date, p.next_payment_date, p.status, p.currency, p.financial_type_id, p.payment_instrument_id, p.is_test, p.created_at, p.updated_at, c.first_name, c.last_name, c.email
+SELECT p.id, p.contact_id, p.campaign_id, p.pledge_block_id, p.amount, p.installments, p.frequency, p.frequency_interval, p.start_date, p.end_date, p.next_payment_date, p.status, p.currency, p.financial_type_id, p.payment_instrument_id, p.is_test, p.created_at, p.updated_at, c.first_name, c.last_name, pe.email
 FROM pledges p
 INNER JOIN contacts c ON p.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE p.campaign_id = $1 AND p.status = $2
 ORDER BY p.created_at DESC
 `
@@ -1628,9 +1635,10 @@ func (q *Queries) ListPledgesByContact(ctx context.Context, contactID uuid.UUID)
 }
 
 const ListPledgesByStatus = `-- name: ListPledgesByStatus :many
-SELECT p.id, p.contact_id, p.campaign_id, p.pledge_block_id, p.amount, p.installments, p.frequency, p.frequency_interval, p.start_date, p.end_date, p.next_payment_date, p.status, p.currency, p.financial_type_id, p.payment_instrument_id, p.is_test, p.created_at, p.updated_at, c.first_name, c.last_name, c.email
+SELECT p.id, p.contact_id, p.campaign_id, p.pledge_block_id, p.amount, p.installments, p.frequency, p.frequency_interval, p.start_date, p.end_date, p.next_payment_date, p.status, p.currency, p.financial_type_id, p.payment_instrument_id, p.is_test, p.created_at, p.updated_at, c.first_name, c.last_name, pe.email
 FROM pledges p
 INNER JOIN contacts c ON p.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE p.status = $1
 ORDER BY p.next_payment_date ASC
 `
@@ -1705,10 +1713,11 @@ func (q *Queries) ListPledgesByStatus(ctx context.Context, status sql.NullString
 }
 
 const ListScheduledReminders = `-- name: ListScheduledReminders :many
-SELECT pr.id, pr.pledge_id, pr.pledge_payment_id, pr.reminder_type, pr.scheduled_date, pr.sent_date, pr.status, pr.message_template_id, pr.created_at, pr.updated_at, p.contact_id, c.first_name, c.last_name, c.email
+SELECT pr.id, pr.pledge_id, pr.pledge_payment_id, pr.reminder_type, pr.scheduled_date, pr.sent_date, pr.status, pr.message_template_id, pr.created_at, pr.updated_at, p.contact_id, c.first_name, c.last_name, pe.email
 FROM pledge_reminders pr
 INNER JOIN pledges p ON pr.pledge_id = p.id
 INNER JOIN contacts c ON p.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE pr.status = 'Scheduled' AND pr.scheduled_date <= $1
 ORDER BY pr.scheduled_date
 `
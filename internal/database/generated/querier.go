@@ -7,6 +7,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -29,14 +30,35 @@ type Querier interface {
 	ActivateReportPermission(ctx context.Context, id uuid.UUID) error
 	ActivateReportSubscription(ctx context.Context, id uuid.UUID) error
 	ActivateReportTemplate(ctx context.Context, id uuid.UUID) error
+	// Relationships last changed before their start date were inactive only
+	// because they had not started; ones deactivated by hand since are left alone
+	ActivateStartedRelationships(ctx context.Context, domainID uuid.UUID) (int64, error)
 	ActivateSurvey(ctx context.Context, id uuid.UUID) error
 	ActivateSurveyCampaign(ctx context.Context, id uuid.UUID) error
 	ActivateSurveyGroup(ctx context.Context, id uuid.UUID) error
 	ActivateSurveyQuestion(ctx context.Context, id uuid.UUID) error
 	ActivateTag(ctx context.Context, id uuid.UUID) error
 	ActivateTagSet(ctx context.Context, id uuid.UUID) error
+	// Clears the text of activities the contact takes part in or is assigned
+	AnonymizeActivities(ctx context.Context, contactIds []uuid.UUID) (int64, error)
+	AnonymizeContact(ctx context.Context, contactIds []uuid.UUID) (int64, error)
+	AnonymizeContactMerges(ctx context.Context, contactIds []uuid.UUID) (int64, error)
+	AnonymizeMailingClicks(ctx context.Context, contactIds []uuid.UUID) (int64, error)
+	AnonymizeMailingOpens(ctx context.Context, contactIds []uuid.UUID) (int64, error)
+	AnonymizeMailingRecipients(ctx context.Context, contactIds []uuid.UUID) (int64, error)
+	AnonymizeMailingSubscriptions(ctx context.Context, contactIds []uuid.UUID) (int64, error)
+	AnonymizeMemberships(ctx context.Context, contactIds []uuid.UUID) (int64, error)
+	AnonymizeParticipants(ctx context.Context, contactIds []uuid.UUID) (int64, error)
+	AnonymizeSMSMessages(ctx context.Context, contactIds []uuid.UUID) (int64, error)
+	// Free text answers are cleared; numeric, date and yes/no answers stay for
+	// the survey's totals
+	AnonymizeSurveyAnswers(ctx context.Context, contactIds []uuid.UUID) (int64, error)
+	AnonymizeSurveyResponses(ctx context.Context, contactIds []uuid.UUID) (int64, error)
 	AssignUserToRole(ctx context.Context, arg AssignUserToRoleParams) (AclEntityRole, error)
 	BulkUpdateSettings(ctx context.Context, arg BulkUpdateSettingsParams) ([]Setting, error)
+	// A user may edit a contact through an ACL role that is not denied, or a
+	// relationship that grants the user's contact Edit on it
+	CanUserEditContact(ctx context.Context, arg CanUserEditContactParams) (sql.NullBool, error)
 	CheckCampaignPermission(ctx context.Context, arg CheckCampaignPermissionParams) (bool, error)
 	CheckCasePermission(ctx context.Context, arg CheckCasePermissionParams) (bool, error)
 	CheckContactPermission(ctx context.Context, arg CheckContactPermissionParams) (bool, error)
@@ -45,6 +67,8 @@ type Querier interface {
 	CheckGroupPermission(ctx context.Context, arg CheckGroupPermissionParams) (bool, error)
 	CheckMembershipPermission(ctx context.Context, arg CheckMembershipPermissionParams) (bool, error)
 	CheckUserPermission(ctx context.Context, arg CheckUserPermissionParams) (bool, error)
+	// Locks the due jobs with a handler so only one instance runs each
+	ClaimDueJobs(ctx context.Context, names []string) ([]Job, error)
 	CleanupOldJobLogs(ctx context.Context, jobID uuid.UUID) error
 	CloseCase(ctx context.Context, arg CloseCaseParams) error
 	CountActiveActivityTypes(ctx context.Context) (int64, error)
@@ -125,6 +149,7 @@ type Querier interface {
 	CreateActivity(ctx context.Context, arg CreateActivityParams) (Activity, error)
 	CreateActivityContact(ctx context.Context, arg CreateActivityContactParams) (ActivityContact, error)
 	CreateActivityType(ctx context.Context, arg CreateActivityTypeParams) (ActivityType, error)
+	CreateAddress(ctx context.Context, arg CreateAddressParams) (Address, error)
 	CreateCampaign(ctx context.Context, arg CreateCampaignParams) (Campaign, error)
 	CreateCampaignActivity(ctx context.Context, arg CreateCampaignActivityParams) (CampaignActivity, error)
 	CreateCampaignContact(ctx context.Context, arg CreateCampaignContactParams) (CampaignContact, error)
@@ -141,6 +166,9 @@ type Querier interface {
 	// Communication Preferences CRUD operations
 	CreateCommunicationPreferences(ctx context.Context, arg CreateCommunicationPreferencesParams) (CommunicationPreference, error)
 	CreateContact(ctx context.Context, arg CreateContactParams) (Contact, error)
+	CreateContactMerge(ctx context.Context, arg CreateContactMergeParams) (ContactMerge, error)
+	// Contact subtype queries
+	CreateContactSubType(ctx context.Context, arg CreateContactSubTypeParams) (ContactSubType, error)
 	CreateContribution(ctx context.Context, arg CreateContributionParams) (Contribution, error)
 	// Custom Fields CRUD operations
 	CreateCustomField(ctx context.Context, arg CreateCustomFieldParams) (CustomField, error)
@@ -154,14 +182,27 @@ type Querier interface {
 	CreateCustomValue(ctx context.Context, arg CreateCustomValueParams) (CustomValue, error)
 	CreateDashboard(ctx context.Context, arg CreateDashboardParams) (Dashboard, error)
 	CreateDashboardWidget(ctx context.Context, arg CreateDashboardWidgetParams) (DashboardWidget, error)
+	CreateDedupeRule(ctx context.Context, arg CreateDedupeRuleParams) (DedupeRule, error)
+	CreateDedupeRuleGroup(ctx context.Context, arg CreateDedupeRuleGroupParams) (DedupeRuleGroup, error)
 	CreateDiscount(ctx context.Context, arg CreateDiscountParams) (Discount, error)
 	CreateDomain(ctx context.Context, arg CreateDomainParams) (Domain, error)
+	// Contact location queries. The triggers from migration 038 keep exactly one
+	// primary record per contact and kind: creating or updating a record with
+	// is_primary demotes the previous primary one.
+	CreateEmail(ctx context.Context, arg CreateEmailParams) (Email, error)
 	CreateEntityTag(ctx context.Context, arg CreateEntityTagParams) (EntityTag, error)
 	CreateEvent(ctx context.Context, arg CreateEventParams) (Event, error)
 	CreateEventFee(ctx context.Context, arg CreateEventFeeParams) (EventFee, error)
 	CreateEventRegistration(ctx context.Context, arg CreateEventRegistrationParams) (EventRegistration, error)
 	CreateFinancialAccount(ctx context.Context, arg CreateFinancialAccountParams) (FinancialAccount, error)
+	// Group queries. Members of static, smart and nested groups are read
+	// through the group_members view from migrations 044 and 045.
+	CreateGroup(ctx context.Context, arg CreateGroupParams) (Group, error)
+	// Nesting a group under the same parent twice does nothing. A trigger
+	// rejects nesting that would create a cycle.
+	CreateGroupNesting(ctx context.Context, arg CreateGroupNestingParams) error
 	CreateJob(ctx context.Context, arg CreateJobParams) (Job, error)
+	CreateJobLog(ctx context.Context, jobID uuid.UUID) (JobLog, error)
 	CreateLineItem(ctx context.Context, arg CreateLineItemParams) (LineItem, error)
 	// Mailings CRUD operations
 	CreateMailing(ctx context.Context, arg CreateMailingParams) (Mailing, error)
@@ -183,6 +224,8 @@ type Querier interface {
 	CreateMessageTemplate(ctx context.Context, arg CreateMessageTemplateParams) (MessageTemplate, error)
 	CreateNavigation(ctx context.Context, arg CreateNavigationParams) (Navigation, error)
 	CreateParticipant(ctx context.Context, arg CreateParticipantParams) (Participant, error)
+	CreatePersonalDataRequest(ctx context.Context, arg CreatePersonalDataRequestParams) (PersonalDataRequest, error)
+	CreatePhone(ctx context.Context, arg CreatePhoneParams) (Phone, error)
 	// Pledges CRUD operations
 	CreatePledge(ctx context.Context, arg CreatePledgeParams) (Pledge, error)
 	// Pledge System Queries
@@ -203,14 +246,22 @@ type Querier interface {
 	CreatePriceFieldValue(ctx context.Context, arg CreatePriceFieldValueParams) (PriceFieldValue, error)
 	CreatePriceSet(ctx context.Context, arg CreatePriceSetParams) (PriceSet, error)
 	CreateQueue(ctx context.Context, arg CreateQueueParams) (Queue, error)
+	CreateRelationship(ctx context.Context, arg CreateRelationshipParams) (Relationship, error)
+	// Relationship queries. Triggers from migration 042 keep the ACL cache and
+	// current employers in step with these writes.
+	CreateRelationshipType(ctx context.Context, arg CreateRelationshipTypeParams) (RelationshipType, error)
 	CreateReportInstance(ctx context.Context, arg CreateReportInstanceParams) (ReportInstance, error)
 	CreateReportPermission(ctx context.Context, arg CreateReportPermissionParams) (ReportPermission, error)
 	CreateReportResult(ctx context.Context, arg CreateReportResultParams) (ReportResult, error)
 	CreateReportSubscription(ctx context.Context, arg CreateReportSubscriptionParams) (ReportSubscription, error)
 	CreateReportTemplate(ctx context.Context, arg CreateReportTemplateParams) (ReportTemplate, error)
+	CreateSavedSearch(ctx context.Context, arg CreateSavedSearchParams) (SavedSearch, error)
 	CreateSetting(ctx context.Context, arg CreateSettingParams) (Setting, error)
+	// The fields are copied from the master by trigger
+	CreateSharedAddress(ctx context.Context, arg CreateSharedAddressParams) (Address, error)
 	// SMS Messages CRUD operations
 	CreateSmsMessage(ctx context.Context, arg CreateSmsMessageParams) (SmsMessage, error)
+	CreateSubscriptionHistory(ctx context.Context, arg CreateSubscriptionHistoryParams) (SubscriptionHistory, error)
 	CreateSurvey(ctx context.Context, arg CreateSurveyParams) (Survey, error)
 	CreateSurveyCampaign(ctx context.Context, arg CreateSurveyCampaignParams) (SurveyCampaign, error)
 	CreateSurveyGroup(ctx context.Context, arg CreateSurveyGroupParams) (SurveyGroup, error)
@@ -236,6 +287,7 @@ type Querier interface {
 	DeactivateCaseType(ctx context.Context, id uuid.UUID) error
 	DeactivateDashboard(ctx context.Context, id uuid.UUID) error
 	DeactivateDashboardWidget(ctx context.Context, id uuid.UUID) error
+	DeactivateEndedRelationships(ctx context.Context, domainID uuid.UUID) (int64, error)
 	DeactivateMembershipStatus(ctx context.Context, id uuid.UUID) error
 	DeactivateMembershipType(ctx context.Context, id uuid.UUID) error
 	DeactivateReportInstance(ctx context.Context, id uuid.UUID) error
@@ -272,6 +324,18 @@ type Querier interface {
 	DeleteCaseType(ctx context.Context, id uuid.UUID) error
 	DeleteCommunicationPreferences(ctx context.Context, contactID uuid.UUID) error
 	DeleteContact(ctx context.Context, id uuid.UUID) error
+	// Addresses shared from these become independent of them
+	DeleteContactAddresses(ctx context.Context, contactIds []uuid.UUID) (int64, error)
+	DeleteContactCustomValues(ctx context.Context, contactIds []uuid.UUID) (int64, error)
+	DeleteContactEmails(ctx context.Context, contactIds []uuid.UUID) (int64, error)
+	DeleteContactEntityTags(ctx context.Context, contactIds []uuid.UUID) (int64, error)
+	DeleteContactIMs(ctx context.Context, contactIds []uuid.UUID) (int64, error)
+	DeleteContactLocationBackup(ctx context.Context, contactIds []uuid.UUID) (int64, error)
+	DeleteContactPhones(ctx context.Context, contactIds []uuid.UUID) (int64, error)
+	DeleteContactRelationships(ctx context.Context, contactIds []uuid.UUID) (int64, error)
+	// Unlinks logins; the user accounts themselves are left to the auth system
+	DeleteContactUserAccounts(ctx context.Context, contactIds []uuid.UUID) (int64, error)
+	DeleteContactWebsites(ctx context.Context, contactIds []uuid.UUID) (int64, error)
 	DeleteContribution(ctx context.Context, id uuid.UUID) error
 	DeleteCustomField(ctx context.Context, id uuid.UUID) error
 	DeleteCustomFieldOption(ctx context.Context, id uuid.UUID) error
@@ -280,6 +344,7 @@ type Querier interface {
 	DeleteCustomValuesByEntity(ctx context.Context, arg DeleteCustomValuesByEntityParams) error
 	DeleteDashboard(ctx context.Context, id uuid.UUID) error
 	DeleteDashboardWidget(ctx context.Context, id uuid.UUID) error
+	DeleteDedupeRuleGroup(ctx context.Context, id uuid.UUID) error
 	DeleteDiscount(ctx context.Context, id uuid.UUID) error
 	DeleteDomain(ctx context.Context, id uuid.UUID) error
 	DeleteEntityTag(ctx context.Context, id uuid.UUID) error
@@ -289,6 +354,8 @@ type Querier interface {
 	DeleteEventFee(ctx context.Context, id uuid.UUID) error
 	DeleteEventRegistration(ctx context.Context, id uuid.UUID) error
 	DeleteFinancialAccount(ctx context.Context, id uuid.UUID) error
+	DeleteGroupContactCache(ctx context.Context, groupID uuid.UUID) error
+	DeleteGroupNesting(ctx context.Context, arg DeleteGroupNestingParams) (int64, error)
 	DeleteJob(ctx context.Context, arg DeleteJobParams) error
 	DeleteJobsByDomain(ctx context.Context, domainID uuid.UUID) error
 	DeleteLineItem(ctx context.Context, id uuid.UUID) error
@@ -302,6 +369,9 @@ type Querier interface {
 	DeleteMembershipPayment(ctx context.Context, id uuid.UUID) error
 	DeleteMembershipStatus(ctx context.Context, id uuid.UUID) error
 	DeleteMembershipType(ctx context.Context, id uuid.UUID) error
+	// Cached permissions of the duplicate are recomputed for the kept contact
+	// on the next check.
+	DeleteMergedACLCache(ctx context.Context, otherID uuid.UUID) (int64, error)
 	DeleteMessageTemplate(ctx context.Context, id uuid.UUID) error
 	DeleteNavigation(ctx context.Context, arg DeleteNavigationParams) error
 	DeleteNavigationByDomain(ctx context.Context, domainID uuid.UUID) error
@@ -317,12 +387,17 @@ type Querier interface {
 	DeletePriceSet(ctx context.Context, id uuid.UUID) error
 	DeleteQueue(ctx context.Context, arg DeleteQueueParams) error
 	DeleteQueuesByDomain(ctx context.Context, domainID uuid.UUID) error
+	DeleteRelationship(ctx context.Context, id uuid.UUID) (int64, error)
 	DeleteReportInstance(ctx context.Context, id uuid.UUID) error
 	DeleteReportPermission(ctx context.Context, id uuid.UUID) error
 	DeleteReportResult(ctx context.Context, id uuid.UUID) error
 	DeleteReportResultsByInstance(ctx context.Context, reportInstanceID uuid.UUID) error
 	DeleteReportSubscription(ctx context.Context, id uuid.UUID) error
 	DeleteReportTemplate(ctx context.Context, id uuid.UUID) error
+	DeleteSavedSearch(ctx context.Context, id uuid.UUID) (int64, error)
+	// Relationships between the two contacts become relationships of the kept
+	// contact with itself after the moves above.
+	DeleteSelfRelationships(ctx context.Context, mainID uuid.UUID) (int64, error)
 	DeleteSetting(ctx context.Context, arg DeleteSettingParams) error
 	DeleteSettingsByDomain(ctx context.Context, domainID uuid.UUID) error
 	DeleteSmsMessage(ctx context.Context, id uuid.UUID) error
@@ -341,7 +416,45 @@ type Querier interface {
 	DeleteUFGroupsByDomain(ctx context.Context, domainID uuid.UUID) error
 	DeleteUFMatch(ctx context.Context, id uuid.UUID) error
 	DeleteUser(ctx context.Context, id uuid.UUID) error
+	// Marks the cache of the groups using a saved search as expired
+	ExpireSavedSearchGroups(ctx context.Context, savedSearchID uuid.NullUUID) error
+	// Activities the contact takes part in or is assigned, with their roles
+	ExportActivities(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error)
+	ExportAddresses(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error)
+	ExportCampaignContacts(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error)
+	ExportCaseContacts(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error)
+	ExportCommunicationPreferences(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error)
+	// The kept contact first, then its merged duplicates
+	ExportContact(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error)
+	// Location values as they were before migrating to location records
+	ExportContactLocationBackup(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error)
+	ExportContactMerges(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error)
+	ExportContributions(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error)
+	ExportCustomValues(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error)
+	ExportEmails(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error)
+	ExportEntityTags(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error)
+	ExportEventRegistrations(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error)
+	ExportGroupContacts(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error)
+	ExportIMs(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error)
+	ExportMailingClicks(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error)
+	ExportMailingOpens(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error)
+	ExportMailingRecipients(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error)
+	ExportMailingSubscriptions(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error)
+	ExportMemberships(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error)
+	ExportParticipants(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error)
+	ExportPersonalDataRequests(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error)
+	ExportPhones(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error)
+	ExportPledges(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error)
+	ExportRelationships(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error)
+	ExportSMSMessages(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error)
+	ExportSubscriptionHistory(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error)
+	// Survey responses with their answers and the questions answered
+	ExportSurveyResponses(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error)
+	// The logins linked to the contact, without their password hashes
+	ExportUserAccounts(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error)
+	ExportWebsites(ctx context.Context, contactIds []uuid.UUID) (json.RawMessage, error)
 	ExtendMembership(ctx context.Context, arg ExtendMembershipParams) error
+	FinishJobLog(ctx context.Context, arg FinishJobLogParams) error
 	GetACL(ctx context.Context, id uuid.UUID) (Acl, error)
 	GetACLCache(ctx context.Context, contactID uuid.NullUUID) ([]AclCache, error)
 	GetACLContactCache(ctx context.Context, arg GetACLContactCacheParams) ([]AclContactCache, error)
@@ -380,6 +493,8 @@ type Querier interface {
 	GetActivityType(ctx context.Context, id uuid.UUID) (ActivityType, error)
 	GetActivityTypeByLabel(ctx context.Context, label string) (ActivityType, error)
 	GetActivityTypeByName(ctx context.Context, name string) (ActivityType, error)
+	GetAddress(ctx context.Context, id uuid.UUID) (Address, error)
+	GetAddressForUpdate(ctx context.Context, id uuid.UUID) (Address, error)
 	GetCampaign(ctx context.Context, id uuid.UUID) (Campaign, error)
 	GetCampaignActivitiesByActivity(ctx context.Context, activityID uuid.UUID) (CampaignActivity, error)
 	GetCampaignActivitiesByCampaign(ctx context.Context, campaignID uuid.UUID) ([]CampaignActivity, error)
@@ -457,15 +572,18 @@ type Querier interface {
 	GetCommunicationPreferences(ctx context.Context, contactID uuid.UUID) (CommunicationPreference, error)
 	GetCompletedSurveyResponses(ctx context.Context, surveyID uuid.UUID) ([]SurveyResponse, error)
 	GetContact(ctx context.Context, id uuid.UUID) (Contact, error)
-	GetContactByEmail(ctx context.Context, email sql.NullString) (Contact, error)
-	GetContactByPhone(ctx context.Context, phone sql.NullString) (Contact, error)
+	GetContactByEmail(ctx context.Context, email string) (Contact, error)
+	GetContactByPhone(ctx context.Context, phone string) (Contact, error)
 	GetContactCommunicationHistory(ctx context.Context, arg GetContactCommunicationHistoryParams) ([]GetContactCommunicationHistoryRow, error)
+	GetContactSubTypeByName(ctx context.Context, name string) (ContactSubType, error)
 	GetContactsByLocation(ctx context.Context, arg GetContactsByLocationParams) ([]Contact, error)
 	GetContactsByType(ctx context.Context, contactType string) ([]Contact, error)
 	GetContactsForUser(ctx context.Context, arg GetContactsForUserParams) ([]Contact, error)
 	GetContribution(ctx context.Context, id uuid.UUID) (Contribution, error)
 	GetContributionsByContact(ctx context.Context, contactID uuid.UUID) ([]Contribution, error)
 	GetContributionsByDateRange(ctx context.Context, arg GetContributionsByDateRangeParams) ([]GetContributionsByDateRangeRow, error)
+	GetCountry(ctx context.Context, id uuid.UUID) (Country, error)
+	GetCountryByNameOrCode(ctx context.Context, country string) (Country, error)
 	GetCustomField(ctx context.Context, id uuid.UUID) (CustomField, error)
 	GetCustomFieldByName(ctx context.Context, arg GetCustomFieldByNameParams) (CustomField, error)
 	GetCustomFieldOption(ctx context.Context, id uuid.UUID) (CustomFieldOption, error)
@@ -490,6 +608,9 @@ type Querier interface {
 	GetDashboardWidgetsByDashboard(ctx context.Context, arg GetDashboardWidgetsByDashboardParams) ([]DashboardWidget, error)
 	GetDashboardWidgetsByType(ctx context.Context, arg GetDashboardWidgetsByTypeParams) ([]DashboardWidget, error)
 	GetDashboardsByCreator(ctx context.Context, arg GetDashboardsByCreatorParams) ([]Dashboard, error)
+	GetDedupeRuleGroup(ctx context.Context, id uuid.UUID) (DedupeRuleGroup, error)
+	GetDedupeRuleGroupByName(ctx context.Context, name string) (DedupeRuleGroup, error)
+	GetDedupeRuleGroupByUsage(ctx context.Context, arg GetDedupeRuleGroupByUsageParams) (DedupeRuleGroup, error)
 	GetDefaultDashboard(ctx context.Context) (Dashboard, error)
 	GetDefaultEventFee(ctx context.Context, eventID uuid.UUID) (EventFee, error)
 	GetDefaultSurvey(ctx context.Context) (Survey, error)
@@ -519,6 +640,13 @@ type Querier interface {
 	GetFinancialAccount(ctx context.Context, id uuid.UUID) (FinancialAccount, error)
 	GetFinancialAccountByCode(ctx context.Context, accountCode sql.NullString) (FinancialAccount, error)
 	GetFinancialAccountByName(ctx context.Context, name string) (FinancialAccount, error)
+	GetGroup(ctx context.Context, id uuid.UUID) (Group, error)
+	// Group contact and subscription history queries. Every change to a
+	// group_contacts status is recorded in subscription_history in the same
+	// transaction.
+	GetGroupContactForUpdate(ctx context.Context, arg GetGroupContactForUpdateParams) (GroupContact, error)
+	// Serializes refreshes of a smart group's cache
+	GetGroupForUpdate(ctx context.Context, id uuid.UUID) (Group, error)
 	GetGroupsForUser(ctx context.Context, arg GetGroupsForUserParams) ([]Group, error)
 	GetJob(ctx context.Context, id uuid.UUID) (Job, error)
 	GetJobByName(ctx context.Context, arg GetJobByNameParams) (Job, error)
@@ -527,10 +655,12 @@ type Querier interface {
 	GetJobWithLogs(ctx context.Context, id uuid.UUID) ([]GetJobWithLogsRow, error)
 	GetJobsDueForExecution(ctx context.Context, domainID uuid.UUID) ([]Job, error)
 	GetLatestReportResult(ctx context.Context, reportInstanceID uuid.UUID) (ReportResult, error)
+	GetLatestSubscriptionHistory(ctx context.Context, arg GetLatestSubscriptionHistoryParams) (SubscriptionHistory, error)
 	GetLineItem(ctx context.Context, id uuid.UUID) (LineItem, error)
 	GetLineItemsByEntity(ctx context.Context, arg GetLineItemsByEntityParams) ([]LineItem, error)
 	GetLineItemsByFinancialType(ctx context.Context, financialTypeID uuid.NullUUID) ([]LineItem, error)
 	GetLineItemsByPriceField(ctx context.Context, priceFieldID uuid.NullUUID) ([]LineItem, error)
+	GetLocationTypeByName(ctx context.Context, name string) (LocationType, error)
 	GetMailing(ctx context.Context, id uuid.UUID) (Mailing, error)
 	GetMailingList(ctx context.Context, id uuid.UUID) (MailingList, error)
 	GetMailingListByName(ctx context.Context, name string) (MailingList, error)
@@ -609,6 +739,9 @@ type Querier interface {
 	GetRecipientByMailingAndContact(ctx context.Context, arg GetRecipientByMailingAndContactParams) (MailingRecipient, error)
 	GetRegistrationsByContact(ctx context.Context, contactID uuid.UUID) ([]GetRegistrationsByContactRow, error)
 	GetRegistrationsByEvent(ctx context.Context, eventID uuid.UUID) ([]GetRegistrationsByEventRow, error)
+	GetRelationship(ctx context.Context, id uuid.UUID) (Relationship, error)
+	GetRelationshipType(ctx context.Context, id uuid.UUID) (RelationshipType, error)
+	GetRelationshipTypeByName(ctx context.Context, nameAB string) (RelationshipType, error)
 	GetReportInstance(ctx context.Context, id uuid.UUID) (ReportInstance, error)
 	GetReportInstanceByName(ctx context.Context, name string) (ReportInstance, error)
 	GetReportInstanceStats(ctx context.Context, isActive sql.NullBool) ([]GetReportInstanceStatsRow, error)
@@ -644,6 +777,7 @@ type Querier interface {
 	GetRolePermissionMatrix(ctx context.Context, entityID uuid.NullUUID) ([]GetRolePermissionMatrixRow, error)
 	GetRolePermissionSummary(ctx context.Context, entityID uuid.NullUUID) ([]GetRolePermissionSummaryRow, error)
 	GetRootNavigation(ctx context.Context, domainID uuid.UUID) ([]Navigation, error)
+	GetSavedSearch(ctx context.Context, id uuid.UUID) (SavedSearch, error)
 	GetScheduledReportInstances(ctx context.Context, isActive sql.NullBool) ([]ReportInstance, error)
 	GetSetting(ctx context.Context, id uuid.UUID) (Setting, error)
 	GetSettingByName(ctx context.Context, arg GetSettingByNameParams) (Setting, error)
@@ -651,7 +785,10 @@ type Querier interface {
 	GetSettingsByDomain(ctx context.Context, domainID uuid.UUID) ([]Setting, error)
 	GetSettingsByPrefix(ctx context.Context, arg GetSettingsByPrefixParams) ([]Setting, error)
 	GetSmsMessage(ctx context.Context, id uuid.UUID) (SmsMessage, error)
+	GetStateProvince(ctx context.Context, id uuid.UUID) (StateProvince, error)
+	GetStateProvinceByName(ctx context.Context, arg GetStateProvinceByNameParams) (StateProvince, error)
 	GetSubscriptionByContactAndList(ctx context.Context, arg GetSubscriptionByContactAndListParams) (MailingListSubscription, error)
+	GetSubscriptionHistory(ctx context.Context, id uuid.UUID) (SubscriptionHistory, error)
 	GetSurvey(ctx context.Context, id uuid.UUID) (Survey, error)
 	GetSurveyAnalytics(ctx context.Context, surveyID uuid.UUID) ([]GetSurveyAnalyticsRow, error)
 	GetSurveyByTitle(ctx context.Context, title string) (Survey, error)
@@ -752,8 +889,14 @@ type Querier interface {
 	HardDeleteCampaignEvent(ctx context.Context, id uuid.UUID) error
 	HardDeleteCase(ctx context.Context, id uuid.UUID) error
 	HardDeleteCaseActivity(ctx context.Context, id uuid.UUID) error
+	HasActiveRelationship(ctx context.Context, arg HasActiveRelationshipParams) (bool, error)
+	InsertGroupContactCache(ctx context.Context, arg InsertGroupContactCacheParams) (int64, error)
 	ListACLEntityRolesByEntity(ctx context.Context, arg ListACLEntityRolesByEntityParams) ([]AclEntityRole, error)
 	ListACLEntityRolesByRole(ctx context.Context, aclRoleID uuid.UUID) ([]AclEntityRole, error)
+	// Contacts a user may access through their roles' ACLs on groups, including
+	// members of smart and nested groups. Deny rules on a group win over allow
+	// rules.
+	ListACLGroupPermittedContacts(ctx context.Context, arg ListACLGroupPermittedContactsParams) ([]uuid.UUID, error)
 	ListACLRoles(ctx context.Context) ([]AclRole, error)
 	ListACLsByEntity(ctx context.Context, arg ListACLsByEntityParams) ([]Acl, error)
 	ListACLsByOperation(ctx context.Context, operation string) ([]Acl, error)
@@ -785,6 +928,19 @@ type Querier interface {
 	ListActiveUFFieldsByGroup(ctx context.Context, ufGroupID uuid.UUID) ([]UfField, error)
 	ListActiveUFGroupsByDomain(ctx context.Context, domainID uuid.UUID) ([]UfGroup, error)
 	ListActivityTypes(ctx context.Context) ([]ActivityType, error)
+	ListAddressesForContacts(ctx context.Context, contactIds []uuid.UUID) ([]Address, error)
+	// Address geocoding queries. The trigger from migration 047 clears
+	// geocoded_at when an address changes, so these only track lookups.
+	// Addresses of a domain's contacts not looked up since they last changed,
+	// oldest change first. Shared addresses take their master's coordinates.
+	ListAddressesToGeocode(ctx context.Context, arg ListAddressesToGeocodeParams) ([]ListAddressesToGeocodeRow, error)
+	// Address queries. The trigger from migration 049 clears the street parts
+	// when street_address changes; those from migration 050 keep shared
+	// addresses identical to their master.
+	// Addresses of a domain's contacts with a street address that has not been
+	// split, in ID order after after_id. Shared addresses take their master's
+	// parts.
+	ListAddressesToParse(ctx context.Context, arg ListAddressesToParseParams) ([]ListAddressesToParseRow, error)
 	ListAdminStatuses(ctx context.Context, isActive sql.NullBool) ([]MembershipStatus, error)
 	ListAllActivities(ctx context.Context) ([]Activity, error)
 	ListAllActivityContacts(ctx context.Context) ([]ActivityContact, error)
@@ -814,6 +970,9 @@ type Querier interface {
 	ListCampaignGroups(ctx context.Context) ([]CampaignGroup, error)
 	ListCampaignStatus(ctx context.Context, isActive sql.NullBool) ([]CampaignStatus, error)
 	ListCampaignStatusByGrouping(ctx context.Context, arg ListCampaignStatusByGroupingParams) ([]CampaignStatus, error)
+	// Members of the campaign's active target groups, including smart and
+	// nested groups
+	ListCampaignTargetContacts(ctx context.Context, campaignID uuid.UUID) ([]ListCampaignTargetContactsRow, error)
 	ListCampaignTypes(ctx context.Context, isActive sql.NullBool) ([]CampaignType, error)
 	ListCampaigns(ctx context.Context) ([]Campaign, error)
 	ListCampaignsByDateRange(ctx context.Context, arg ListCampaignsByDateRangeParams) ([]Campaign, error)
@@ -839,7 +998,19 @@ type Querier interface {
 	ListClosedCaseStatus(ctx context.Context, isActive sql.NullBool) ([]CaseStatus, error)
 	ListClosedCases(ctx context.Context) ([]Case, error)
 	ListCompletedCampaignStatus(ctx context.Context, isActive sql.NullBool) ([]CampaignStatus, error)
+	ListContactMerges(ctx context.Context, contactID uuid.UUID) ([]ContactMerge, error)
+	// A contact's relationships from its side: relation is name_a_b when the
+	// contact is contact A and name_b_a when it is contact B
+	ListContactRelationships(ctx context.Context, arg ListContactRelationshipsParams) ([]ListContactRelationshipsRow, error)
+	ListContactSubTypes(ctx context.Context) ([]ContactSubType, error)
+	ListContactSubTypesByName(ctx context.Context, names []string) ([]ContactSubType, error)
 	ListContacts(ctx context.Context, arg ListContactsParams) ([]Contact, error)
+	ListContactsByIDs(ctx context.Context, ids []uuid.UUID) ([]Contact, error)
+	ListContactsForDedupe(ctx context.Context, arg ListContactsForDedupeParams) ([]Contact, error)
+	// Contacts whose primary address lies within radius_km of a point, nearest
+	// first. The latitude range narrows the addresses before their great-circle
+	// distance is computed.
+	ListContactsNear(ctx context.Context, arg ListContactsNearParams) ([]Contact, error)
 	ListContributions(ctx context.Context, arg ListContributionsParams) ([]ListContributionsRow, error)
 	ListContributionsByStatus(ctx context.Context, arg ListContributionsByStatusParams) ([]ListContributionsByStatusRow, error)
 	ListContributionsByType(ctx context.Context, arg ListContributionsByTypeParams) ([]ListContributionsByTypeRow, error)
@@ -848,13 +1019,19 @@ type Querier interface {
 	ListCustomFieldsByEntity(ctx context.Context, arg ListCustomFieldsByEntityParams) ([]ListCustomFieldsByEntityRow, error)
 	ListCustomFieldsByGroup(ctx context.Context, arg ListCustomFieldsByGroupParams) ([]CustomField, error)
 	ListCustomGroups(ctx context.Context, arg ListCustomGroupsParams) ([]CustomGroup, error)
+	// Groups that apply to a contact: those extending every contact, its contact
+	// type, or its contact type limited to one of its subtypes
+	ListCustomGroupsForContact(ctx context.Context, arg ListCustomGroupsForContactParams) ([]CustomGroup, error)
 	ListDashboardWidgets(ctx context.Context) ([]DashboardWidget, error)
 	ListDashboardWidgetsByType(ctx context.Context, arg ListDashboardWidgetsByTypeParams) ([]DashboardWidget, error)
 	ListDashboards(ctx context.Context) ([]Dashboard, error)
 	ListDashboardsByDateRange(ctx context.Context, arg ListDashboardsByDateRangeParams) ([]Dashboard, error)
+	ListDedupeRuleGroups(ctx context.Context) ([]DedupeRuleGroup, error)
+	ListDedupeRules(ctx context.Context, ruleGroupID uuid.UUID) ([]DedupeRule, error)
 	ListDefaultPriceFieldValues(ctx context.Context, isActive sql.NullBool) ([]PriceFieldValue, error)
 	ListDiscounts(ctx context.Context, isActive sql.NullBool) ([]Discount, error)
 	ListDomains(ctx context.Context) ([]Domain, error)
+	ListEmailsForContacts(ctx context.Context, contactIds []uuid.UUID) ([]Email, error)
 	ListEntityTags(ctx context.Context) ([]EntityTag, error)
 	ListEntityTagsByTable(ctx context.Context, entityTable string) ([]EntityTag, error)
 	ListEntityTagsByTagSet(ctx context.Context, tagSetID uuid.NullUUID) ([]EntityTag, error)
@@ -866,9 +1043,19 @@ type Querier interface {
 	ListFailedReportResults(ctx context.Context) ([]ReportResult, error)
 	ListFinancialAccounts(ctx context.Context, isActive sql.NullBool) ([]FinancialAccount, error)
 	ListFinancialAccountsByType(ctx context.Context, arg ListFinancialAccountsByTypeParams) ([]FinancialAccount, error)
+	// Groups nested under a group at any depth
+	ListGroupDescendants(ctx context.Context, ancestorID uuid.UUID) ([]Group, error)
+	ListGroupMembers(ctx context.Context, groupID uuid.UUID) ([]ListGroupMembersRow, error)
+	ListGroupNesting(ctx context.Context) ([]ListGroupNestingRow, error)
+	ListGroups(ctx context.Context) ([]Group, error)
 	ListHeaderAccounts(ctx context.Context, arg ListHeaderAccountsParams) ([]FinancialAccount, error)
 	ListJobsByDomain(ctx context.Context, domainID uuid.UUID) ([]Job, error)
 	ListJobsByType(ctx context.Context, arg ListJobsByTypeParams) ([]Job, error)
+	// The primary addresses of contacts, by sort name
+	ListMailingAddresses(ctx context.Context, contactIds []uuid.UUID) ([]ListMailingAddressesRow, error)
+	// Active subscribers and members of the list's group and the groups nested
+	// under it, except contacts who unsubscribed from the list
+	ListMailingListRecipients(ctx context.Context, mailingListID uuid.UUID) ([]ListMailingListRecipientsRow, error)
 	ListMailingLists(ctx context.Context, arg ListMailingListsParams) ([]MailingList, error)
 	ListMailings(ctx context.Context, status sql.NullString) ([]Mailing, error)
 	ListMailingsByCreator(ctx context.Context, createdBy uuid.NullUUID) ([]Mailing, error)
@@ -891,12 +1078,24 @@ type Querier interface {
 	ListMembershipsByDateRange(ctx context.Context, arg ListMembershipsByDateRangeParams) ([]Membership, error)
 	ListMembershipsByStatus(ctx context.Context, arg ListMembershipsByStatusParams) ([]Membership, error)
 	ListMembershipsByType(ctx context.Context, arg ListMembershipsByTypeParams) ([]Membership, error)
+	// Personal data queries. Each Export query returns one section of a
+	// contact's data as a JSON array of rows; each Anonymize or Delete query
+	// scrubs one kind of record and returns the rows changed. Financial
+	// records keep their amounts. Both take the contact and every duplicate
+	// merged into it as @contact_ids, since a merge leaves the rows the kept
+	// contact already had, such as a group membership, with the duplicate.
+	// The contact and every contact merged into it, directly or through
+	// earlier merges
+	ListMergedContactIDs(ctx context.Context, contactID uuid.UUID) ([]uuid.UUID, error)
 	ListMessageTemplates(ctx context.Context, arg ListMessageTemplatesParams) ([]MessageTemplate, error)
 	ListMessageTemplatesByWorkflow(ctx context.Context, arg ListMessageTemplatesByWorkflowParams) ([]MessageTemplate, error)
 	ListMultiRecordActivityTypes(ctx context.Context) ([]ActivityType, error)
 	ListOpenCaseStatus(ctx context.Context, isActive sql.NullBool) ([]CaseStatus, error)
 	ListOpenCases(ctx context.Context) ([]Case, error)
 	ListPastEvents(ctx context.Context, arg ListPastEventsParams) ([]Event, error)
+	// Requests of a contact, or of a domain, newest first
+	ListPersonalDataRequests(ctx context.Context, arg ListPersonalDataRequestsParams) ([]PersonalDataRequest, error)
+	ListPhonesForContacts(ctx context.Context, contactIds []uuid.UUID) ([]Phone, error)
 	ListPlannedCampaignStatus(ctx context.Context, isActive sql.NullBool) ([]CampaignStatus, error)
 	ListPledgeBlocks(ctx context.Context, isActive sql.NullBool) ([]PledgeBlock, error)
 	ListPledgeBlocksByCampaign(ctx context.Context, arg ListPledgeBlocksByCampaignParams) ([]PledgeBlock, error)
@@ -918,9 +1117,15 @@ type Querier interface {
 	ListPriceSets(ctx context.Context, isActive sql.NullBool) ([]PriceSet, error)
 	ListPriceSetsByExtends(ctx context.Context, arg ListPriceSetsByExtendsParams) ([]PriceSet, error)
 	ListPriceSetsByFinancialType(ctx context.Context, arg ListPriceSetsByFinancialTypeParams) ([]PriceSet, error)
+	ListPrimaryAddresses(ctx context.Context, contactIds []uuid.UUID) ([]Address, error)
+	ListPrimaryEmails(ctx context.Context, contactIds []uuid.UUID) ([]Email, error)
+	ListPrimaryPhones(ctx context.Context, contactIds []uuid.UUID) ([]Phone, error)
 	ListQueuesByDomain(ctx context.Context, domainID uuid.UUID) ([]Queue, error)
 	ListRecipientsByMailing(ctx context.Context, mailingID uuid.UUID) ([]ListRecipientsByMailingRow, error)
 	ListRegistrationsByStatus(ctx context.Context, arg ListRegistrationsByStatusParams) ([]ListRegistrationsByStatusRow, error)
+	// Contacts the contact may access through its relationships
+	ListRelationshipPermittedContacts(ctx context.Context, arg ListRelationshipPermittedContactsParams) ([]uuid.UUID, error)
+	ListRelationshipTypes(ctx context.Context) ([]RelationshipType, error)
 	ListReportInstances(ctx context.Context) ([]ReportInstance, error)
 	ListReportInstancesByDateRange(ctx context.Context, arg ListReportInstancesByDateRangeParams) ([]ReportInstance, error)
 	ListReportPermissions(ctx context.Context) ([]ReportPermission, error)
@@ -940,9 +1145,14 @@ type Querier interface {
 	ListReservedTagSets(ctx context.Context, isActive sql.NullBool) ([]TagSet, error)
 	ListReservedTags(ctx context.Context, isActive sql.NullBool) ([]Tag, error)
 	ListReservedUFGroupsByDomain(ctx context.Context, domainID uuid.UUID) ([]UfGroup, error)
+	ListSavedSearches(ctx context.Context) ([]SavedSearch, error)
 	ListScheduledJobs(ctx context.Context, domainID uuid.UUID) ([]Job, error)
 	ListScheduledReminders(ctx context.Context, scheduledDate time.Time) ([]ListScheduledRemindersRow, error)
 	ListSmsMessagesByContact(ctx context.Context, contactID uuid.UUID) ([]SmsMessage, error)
+	// Active smart groups of a domain whose cache has expired or was never built
+	ListStaleSmartGroups(ctx context.Context, domainID uuid.UUID) ([]Group, error)
+	// History of a contact, a group or both, oldest first
+	ListSubscriptionHistory(ctx context.Context, arg ListSubscriptionHistoryParams) ([]SubscriptionHistory, error)
 	ListSubscriptionsByContact(ctx context.Context, arg ListSubscriptionsByContactParams) ([]ListSubscriptionsByContactRow, error)
 	ListSubscriptionsByList(ctx context.Context, arg ListSubscriptionsByListParams) ([]ListSubscriptionsByListRow, error)
 	ListSurveyCampaigns(ctx context.Context) ([]SurveyCampaign, error)
@@ -953,6 +1163,8 @@ type Querier interface {
 	ListSurveyResponseAnswersByQuestionType(ctx context.Context, questionType string) ([]SurveyResponseAnswer, error)
 	ListSurveyResponses(ctx context.Context) ([]SurveyResponse, error)
 	ListSurveyResponsesByDateRange(ctx context.Context, arg ListSurveyResponsesByDateRangeParams) ([]SurveyResponse, error)
+	// Members of the survey's active groups, including smart and nested groups
+	ListSurveyTargetContacts(ctx context.Context, surveyID uuid.UUID) ([]ListSurveyTargetContactsRow, error)
 	ListSurveys(ctx context.Context) ([]Survey, error)
 	ListSurveysByDateRange(ctx context.Context, arg ListSurveysByDateRangeParams) ([]Survey, error)
 	ListTagSets(ctx context.Context, isActive sql.NullBool) ([]TagSet, error)
@@ -969,11 +1181,54 @@ type Querier interface {
 	ListUpcomingEvents(ctx context.Context, arg ListUpcomingEventsParams) ([]Event, error)
 	ListUsers(ctx context.Context) ([]User, error)
 	ListUsersByRole(ctx context.Context, name string) ([]User, error)
+	// Contact merge queries. Each move takes the kept contact as @main_id and
+	// the duplicate as @other_id. Rows that would duplicate one the kept contact
+	// already has stay with the deleted duplicate as history.
+	LockContactsForMerge(ctx context.Context, arg LockContactsForMergeParams) ([]Contact, error)
+	MarkContactMerged(ctx context.Context, arg MarkContactMergedParams) error
 	MarkReportResultCompleted(ctx context.Context, id uuid.UUID) error
 	MarkReportResultFailed(ctx context.Context, arg MarkReportResultFailedParams) error
 	MarkSurveyResponseAbandoned(ctx context.Context, id uuid.UUID) error
 	MarkSurveyResponseCompleted(ctx context.Context, id uuid.UUID) error
 	MarkSurveyResponsePartial(ctx context.Context, id uuid.UUID) error
+	// An opt out on either contact is kept, so merging never re-subscribes
+	// anyone.
+	MergeCommunicationPreferences(ctx context.Context, arg MergeCommunicationPreferencesParams) (int64, error)
+	MoveMergedActivityAssignments(ctx context.Context, arg MoveMergedActivityAssignmentsParams) (int64, error)
+	MoveMergedActivityContacts(ctx context.Context, arg MoveMergedActivityContactsParams) (int64, error)
+	MoveMergedAddresses(ctx context.Context, arg MoveMergedAddressesParams) (int64, error)
+	MoveMergedCampaignContacts(ctx context.Context, arg MoveMergedCampaignContactsParams) (int64, error)
+	MoveMergedCaseContacts(ctx context.Context, arg MoveMergedCaseContactsParams) (int64, error)
+	MoveMergedCommunicationPreferences(ctx context.Context, arg MoveMergedCommunicationPreferencesParams) (int64, error)
+	MoveMergedContributions(ctx context.Context, arg MoveMergedContributionsParams) (int64, error)
+	MoveMergedCustomValues(ctx context.Context, arg MoveMergedCustomValuesParams) (int64, error)
+	// Location records keep their primary flag only when the kept contact has no
+	// primary record of that kind.
+	MoveMergedEmails(ctx context.Context, arg MoveMergedEmailsParams) (int64, error)
+	MoveMergedEntityTags(ctx context.Context, arg MoveMergedEntityTagsParams) (int64, error)
+	MoveMergedEventRegistrations(ctx context.Context, arg MoveMergedEventRegistrationsParams) (int64, error)
+	MoveMergedGroupContacts(ctx context.Context, arg MoveMergedGroupContactsParams) (int64, error)
+	MoveMergedIMs(ctx context.Context, arg MoveMergedIMsParams) (int64, error)
+	MoveMergedLocationBackup(ctx context.Context, arg MoveMergedLocationBackupParams) (int64, error)
+	MoveMergedMailingClicks(ctx context.Context, arg MoveMergedMailingClicksParams) (int64, error)
+	MoveMergedMailingOpens(ctx context.Context, arg MoveMergedMailingOpensParams) (int64, error)
+	MoveMergedMailingRecipients(ctx context.Context, arg MoveMergedMailingRecipientsParams) (int64, error)
+	MoveMergedMailingSubscriptions(ctx context.Context, arg MoveMergedMailingSubscriptionsParams) (int64, error)
+	MoveMergedMemberships(ctx context.Context, arg MoveMergedMembershipsParams) (int64, error)
+	MoveMergedParticipantRegistrations(ctx context.Context, arg MoveMergedParticipantRegistrationsParams) (int64, error)
+	MoveMergedParticipants(ctx context.Context, arg MoveMergedParticipantsParams) (int64, error)
+	MoveMergedPhones(ctx context.Context, arg MoveMergedPhonesParams) (int64, error)
+	MoveMergedPledgeGroupMembers(ctx context.Context, arg MoveMergedPledgeGroupMembersParams) (int64, error)
+	MoveMergedPledges(ctx context.Context, arg MoveMergedPledgesParams) (int64, error)
+	MoveMergedRelationshipsA(ctx context.Context, arg MoveMergedRelationshipsAParams) (int64, error)
+	MoveMergedRelationshipsB(ctx context.Context, arg MoveMergedRelationshipsBParams) (int64, error)
+	MoveMergedSMSMessages(ctx context.Context, arg MoveMergedSMSMessagesParams) (int64, error)
+	// All of the duplicate's history moves, including that of memberships left
+	// with it, so the kept contact's consent record is complete
+	MoveMergedSubscriptionHistory(ctx context.Context, arg MoveMergedSubscriptionHistoryParams) (int64, error)
+	MoveMergedSurveyResponses(ctx context.Context, arg MoveMergedSurveyResponsesParams) (int64, error)
+	MoveMergedWebsites(ctx context.Context, arg MoveMergedWebsitesParams) (int64, error)
+	RedirectMergedContacts(ctx context.Context, arg RedirectMergedContactsParams) (int64, error)
 	RemoveUserFromRole(ctx context.Context, arg RemoveUserFromRoleParams) error
 	ReorderNavigation(ctx context.Context, arg ReorderNavigationParams) ([]Navigation, error)
 	ReorderUFFields(ctx context.Context, arg ReorderUFFieldsParams) ([]UfField, error)
@@ -1038,14 +1293,31 @@ type Querier interface {
 	SearchUserAccessibleEvents(ctx context.Context, arg SearchUserAccessibleEventsParams) ([]Event, error)
 	SearchUserAccessibleGroups(ctx context.Context, arg SearchUserAccessibleGroupsParams) ([]Group, error)
 	SearchUsers(ctx context.Context, arg SearchUsersParams) ([]User, error)
+	// Copies the named jobs of the default domain to every active domain that
+	// does not have them yet
+	SeedDomainJobs(ctx context.Context, names []string) (int64, error)
+	// Records a lookup, with NULL coordinates when the address was not found.
+	// An address changed since it was listed is left for the next run.
+	SetAddressGeoCode(ctx context.Context, arg SetAddressGeoCodeParams) (int64, error)
+	// An address whose street changed since it was listed is left for the next
+	// run
+	SetAddressStreetParts(ctx context.Context, arg SetAddressStreetPartsParams) (int64, error)
+	// Stores the computed display and sort names
+	SetContactNames(ctx context.Context, arg SetContactNamesParams) (Contact, error)
 	SetDefaultDashboard(ctx context.Context) error
 	SetDefaultSurvey(ctx context.Context) error
+	SetGroupCacheDates(ctx context.Context, arg SetGroupCacheDatesParams) error
+	SetPrimaryAddress(ctx context.Context, id uuid.UUID) error
+	SetPrimaryEmail(ctx context.Context, id uuid.UUID) error
+	SetPrimaryPhone(ctx context.Context, id uuid.UUID) error
+	UnshareAddress(ctx context.Context, id uuid.UUID) (Address, error)
 	UpdateACL(ctx context.Context, arg UpdateACLParams) (Acl, error)
 	UpdateACLEntityRole(ctx context.Context, arg UpdateACLEntityRoleParams) (AclEntityRole, error)
 	UpdateACLRole(ctx context.Context, arg UpdateACLRoleParams) (AclRole, error)
 	UpdateActivity(ctx context.Context, arg UpdateActivityParams) (Activity, error)
 	UpdateActivityContact(ctx context.Context, arg UpdateActivityContactParams) (ActivityContact, error)
 	UpdateActivityType(ctx context.Context, arg UpdateActivityTypeParams) (ActivityType, error)
+	UpdateAddress(ctx context.Context, arg UpdateAddressParams) (Address, error)
 	UpdateCampaign(ctx context.Context, arg UpdateCampaignParams) (Campaign, error)
 	UpdateCampaignActivity(ctx context.Context, arg UpdateCampaignActivityParams) (CampaignActivity, error)
 	UpdateCampaignContact(ctx context.Context, arg UpdateCampaignContactParams) (CampaignContact, error)
@@ -1072,6 +1344,7 @@ type Querier interface {
 	UpdateCaseTypeWeight(ctx context.Context, arg UpdateCaseTypeWeightParams) error
 	UpdateCommunicationPreferences(ctx context.Context, arg UpdateCommunicationPreferencesParams) (CommunicationPreference, error)
 	UpdateContact(ctx context.Context, arg UpdateContactParams) (Contact, error)
+	UpdateContactSubType(ctx context.Context, arg UpdateContactSubTypeParams) (ContactSubType, error)
 	UpdateContribution(ctx context.Context, arg UpdateContributionParams) (Contribution, error)
 	UpdateCustomField(ctx context.Context, arg UpdateCustomFieldParams) (CustomField, error)
 	UpdateCustomFieldOption(ctx context.Context, arg UpdateCustomFieldOptionParams) (CustomFieldOption, error)
@@ -1106,6 +1379,7 @@ type Querier interface {
 	UpdateMembershipStatusWeight(ctx context.Context, arg UpdateMembershipStatusWeightParams) error
 	UpdateMembershipType(ctx context.Context, arg UpdateMembershipTypeParams) (MembershipType, error)
 	UpdateMembershipTypeWeight(ctx context.Context, arg UpdateMembershipTypeWeightParams) error
+	UpdateMergedContact(ctx context.Context, arg UpdateMergedContactParams) (Contact, error)
 	UpdateMessageTemplate(ctx context.Context, arg UpdateMessageTemplateParams) (MessageTemplate, error)
 	UpdateNavigation(ctx context.Context, arg UpdateNavigationParams) (Navigation, error)
 	UpdateNavigationStatus(ctx context.Context, arg UpdateNavigationStatusParams) (Navigation, error)
@@ -1126,6 +1400,7 @@ type Querier interface {
 	UpdateQueue(ctx context.Context, arg UpdateQueueParams) (Queue, error)
 	UpdateQueueStatus(ctx context.Context, arg UpdateQueueStatusParams) (Queue, error)
 	UpdateRecipientStatus(ctx context.Context, arg UpdateRecipientStatusParams) (MailingRecipient, error)
+	UpdateRelationship(ctx context.Context, arg UpdateRelationshipParams) (Relationship, error)
 	UpdateReminderStatus(ctx context.Context, arg UpdateReminderStatusParams) (PledgeReminder, error)
 	UpdateReportInstance(ctx context.Context, arg UpdateReportInstanceParams) (ReportInstance, error)
 	UpdateReportInstanceLastRun(ctx context.Context, arg UpdateReportInstanceLastRunParams) error
@@ -1139,6 +1414,7 @@ type Querier interface {
 	UpdateReportSubscriptionDeliveryConfig(ctx context.Context, arg UpdateReportSubscriptionDeliveryConfigParams) error
 	UpdateReportSubscriptionDeliveryMethod(ctx context.Context, arg UpdateReportSubscriptionDeliveryMethodParams) error
 	UpdateReportTemplate(ctx context.Context, arg UpdateReportTemplateParams) (ReportTemplate, error)
+	UpdateSavedSearch(ctx context.Context, arg UpdateSavedSearchParams) (SavedSearch, error)
 	UpdateSetting(ctx context.Context, arg UpdateSettingParams) (Setting, error)
 	UpdateSettingValue(ctx context.Context, arg UpdateSettingValueParams) (Setting, error)
 	UpdateSmsStatus(ctx context.Context, arg UpdateSmsStatusParams) (SmsMessage, error)
@@ -1166,6 +1442,7 @@ type Querier interface {
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
 	UpdateUserLastLogin(ctx context.Context, id uuid.UUID) (User, error)
 	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error)
+	UpsertGroupContact(ctx context.Context, arg UpsertGroupContactParams) (GroupContact, error)
 	ValidateUFFieldName(ctx context.Context, arg ValidateUFFieldNameParams) (bool, error)
 }
 
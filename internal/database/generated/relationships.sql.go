@@ -0,0 +1,449 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: relationships.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+const ActivateStartedRelationships = `-- name: ActivateStartedRelationships :execrows
+UPDATE relationships r
+SET is_active = TRUE, updated_at = NOW()
+FROM contacts c
+WHERE c.id = r.contact_id_a AND c.domain_id = $1
+  AND NOT COALESCE(r.is_active, FALSE)
+  AND r.start_date <= CURRENT_DATE
+  AND r.start_date > r.updated_at::date
+  AND (r.end_date IS NULL OR r.end_date >= CURRENT_DATE)
+`
+
+// Relationships last changed before their start date were inactive only
+// because they had not started; ones deactivated by hand since are left alone
+func (q *Queries) ActivateStartedRelationships(ctx context.Context, domainID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, ActivateStartedRelationships, domainID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const CreateRelationship = `-- name: CreateRelationship :one
+INSERT INTO relationships (
+    contact_id_a, contact_id_b, relationship_type_id, start_date, end_date,
+    is_active, is_permission_a_b, is_permission_b_a
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+) RETURNING id, contact_id_a, contact_id_b, relationship_type_id, start_date, end_date, is_active, is_permission_a_b, is_permission_b_a, created_at, updated_at
+`
+
+type CreateRelationshipParams struct {
+	ContactIDA         uuid.UUID    `json:"contact_id_a"`
+	ContactIDB         uuid.UUID    `json:"contact_id_b"`
+	RelationshipTypeID uuid.UUID    `json:"relationship_type_id"`
+	StartDate          sql.NullTime `json:"start_date"`
+	EndDate            sql.NullTime `json:"end_date"`
+	IsActive           sql.NullBool `json:"is_active"`
+	IsPermissionAB     sql.NullBool `json:"is_permission_a_b"`
+	IsPermissionBA     sql.NullBool `json:"is_permission_b_a"`
+}
+
+func (q *Queries) CreateRelationship(ctx context.Context, arg CreateRelationshipParams) (Relationship, error) {
+	row := q.db.QueryRowContext(ctx, CreateRelationship,
+		arg.ContactIDA,
+		arg.ContactIDB,
+		arg.RelationshipTypeID,
+		arg.StartDate,
+		arg.EndDate,
+		arg.IsActive,
+		arg.IsPermissionAB,
+		arg.IsPermissionBA,
+	)
+	var i Relationship
+	err := row.Scan(
+		&i.ID,
+		&i.ContactIDA,
+		&i.ContactIDB,
+		&i.RelationshipTypeID,
+		&i.StartDate,
+		&i.EndDate,
+		&i.IsActive,
+		&i.IsPermissionAB,
+		&i.IsPermissionBA,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const CreateRelationshipType = `-- name: CreateRelationshipType :one
+
+INSERT INTO relationship_types (
+    name_a_b, name_b_a, description, contact_type_a, contact_type_b
+) VALUES (
+    $1, $2, $3, $4, $5
+) RETURNING id, name_a_b, name_b_a, description, contact_type_a, contact_type_b, is_active, is_reserved, created_at, updated_at
+`
+
+type CreateRelationshipTypeParams struct {
+	NameAB       string         `json:"name_a_b"`
+	NameBA       string         `json:"name_b_a"`
+	Description  sql.NullString `json:"description"`
+	ContactTypeA sql.NullString `json:"contact_type_a"`
+	ContactTypeB sql.NullString `json:"contact_type_b"`
+}
+
+// Relationship queries. Triggers from migration 042 keep the ACL cache and
+// current employers in step with these writes.
+func (q *Queries) CreateRelationshipType(ctx context.Context, arg CreateRelationshipTypeParams) (RelationshipType, error) {
+	row := q.db.QueryRowContext(ctx, CreateRelationshipType,
+		arg.NameAB,
+		arg.NameBA,
+		arg.Description,
+		arg.ContactTypeA,
+		arg.ContactTypeB,
+	)
+	var i RelationshipType
+	err := row.Scan(
+		&i.ID,
+		&i.NameAB,
+		&i.NameBA,
+		&i.Description,
+		&i.ContactTypeA,
+		&i.ContactTypeB,
+		&i.IsActive,
+		&i.IsReserved,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const DeactivateEndedRelationships = `-- name: DeactivateEndedRelationships :execrows
+UPDATE relationships r
+SET is_active = FALSE, updated_at = NOW()
+FROM contacts c
+WHERE c.id = r.contact_id_a AND c.domain_id = $1
+  AND r.is_active
+  AND r.end_date < CURRENT_DATE
+`
+
+func (q *Queries) DeactivateEndedRelationships(ctx context.Context, domainID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, DeactivateEndedRelationships, domainID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const DeleteRelationship = `-- name: DeleteRelationship :execrows
+DELETE FROM relationships WHERE id = $1
+`
+
+func (q *Queries) DeleteRelationship(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, DeleteRelationship, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const GetRelationship = `-- name: GetRelationship :one
+SELECT id, contact_id_a, contact_id_b, relationship_type_id, start_date, end_date, is_active, is_permission_a_b, is_permission_b_a, created_at, updated_at FROM relationships WHERE id = $1
+`
+
+func (q *Queries) GetRelationship(ctx context.Context, id uuid.UUID) (Relationship, error) {
+	row := q.db.QueryRowContext(ctx, GetRelationship, id)
+	var i Relationship
+	err := row.Scan(
+		&i.ID,
+		&i.ContactIDA,
+		&i.ContactIDB,
+		&i.RelationshipTypeID,
+		&i.StartDate,
+		&i.EndDate,
+		&i.IsActive,
+		&i.IsPermissionAB,
+		&i.IsPermissionBA,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const GetRelationshipType = `-- name: GetRelationshipType :one
+SELECT id, name_a_b, name_b_a, description, contact_type_a, contact_type_b, is_active, is_reserved, created_at, updated_at FROM relationship_types WHERE id = $1
+`
+
+func (q *Queries) GetRelationshipType(ctx context.Context, id uuid.UUID) (RelationshipType, error) {
+	row := q.db.QueryRowContext(ctx, GetRelationshipType, id)
+	var i RelationshipType
+	err := row.Scan(
+		&i.ID,
+		&i.NameAB,
+		&i.NameBA,
+		&i.Description,
+		&i.ContactTypeA,
+		&i.ContactTypeB,
+		&i.IsActive,
+		&i.IsReserved,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const GetRelationshipTypeByName = `-- name: GetRelationshipTypeByName :one
+SELECT id, name_a_b, name_b_a, description, contact_type_a, contact_type_b, is_active, is_reserved, created_at, updated_at FROM relationship_types
+WHERE name_a_b = $1
+ORDER BY created_at
+LIMIT 1
+`
+
+func (q *Queries) GetRelationshipTypeByName(ctx context.Context, nameAB string) (RelationshipType, error) {
+	row := q.db.QueryRowContext(ctx, GetRelationshipTypeByName, nameAB)
+	var i RelationshipType
+	err := row.Scan(
+		&i.ID,
+		&i.NameAB,
+		&i.NameBA,
+		&i.Description,
+		&i.ContactTypeA,
+		&i.ContactTypeB,
+		&i.IsActive,
+		&i.IsReserved,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const HasActiveRelationship = `-- name: HasActiveRelationship :one
+SELECT EXISTS (
+    SELECT 1 FROM relationships
+    WHERE contact_id_a = $1 AND contact_id_b = $2
+      AND relationship_type_id = $3 AND is_active
+)
+`
+
+type HasActiveRelationshipParams struct {
+	ContactIDA         uuid.UUID `json:"contact_id_a"`
+	ContactIDB         uuid.UUID `json:"contact_id_b"`
+	RelationshipTypeID uuid.UUID `json:"relationship_type_id"`
+}
+
+func (q *Queries) HasActiveRelationship(ctx context.Context, arg HasActiveRelationshipParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, HasActiveRelationship, arg.ContactIDA, arg.ContactIDB, arg.RelationshipTypeID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const ListContactRelationships = `-- name: ListContactRelationships :many
+SELECT r.id, r.relationship_type_id, r.start_date, r.end_date, r.is_active,
+       'a_b'::text AS direction, t.name_a_b AS relation,
+       r.contact_id_b AS other_contact_id, c.display_name AS other_display_name,
+       COALESCE(r.is_permission_a_b, FALSE)::boolean AS can_access_other,
+       COALESCE(r.is_permission_b_a, FALSE)::boolean AS other_can_access
+FROM relationships r
+JOIN relationship_types t ON t.id = r.relationship_type_id
+JOIN contacts c ON c.id = r.contact_id_b
+WHERE r.contact_id_a = $1 AND (r.is_active OR $2::boolean)
+UNION ALL
+SELECT r.id, r.relationship_type_id, r.start_date, r.end_date, r.is_active,
+       'b_a'::text AS direction, t.name_b_a AS relation,
+       r.contact_id_a AS other_contact_id, c.display_name AS other_display_name,
+       COALESCE(r.is_permission_b_a, FALSE)::boolean AS can_access_other,
+       COALESCE(r.is_permission_a_b, FALSE)::boolean AS other_can_access
+FROM relationships r
+JOIN relationship_types t ON t.id = r.relationship_type_id
+JOIN contacts c ON c.id = r.contact_id_a
+WHERE r.contact_id_b = $1 AND (r.is_active OR $2::boolean)
+ORDER BY relation, other_display_name
+`
+
+type ListContactRelationshipsParams struct {
+	ContactID       uuid.UUID `json:"contact_id"`
+	IncludeInactive bool      `json:"include_inactive"`
+}
+
+type ListContactRelationshipsRow struct {
+	ID                 uuid.UUID      `json:"id"`
+	RelationshipTypeID uuid.UUID      `json:"relationship_type_id"`
+	StartDate          sql.NullTime   `json:"start_date"`
+	EndDate            sql.NullTime   `json:"end_date"`
+	IsActive           sql.NullBool   `json:"is_active"`
+	Direction          string         `json:"direction"`
+	Relation           string         `json:"relation"`
+	OtherContactID     uuid.UUID      `json:"other_contact_id"`
+	OtherDisplayName   sql.NullString `json:"other_display_name"`
+	CanAccessOther     bool           `json:"can_access_other"`
+	OtherCanAccess     bool           `json:"other_can_access"`
+}
+
+// A contact's relationships from its side: relation is name_a_b when the
+// contact is contact A and name_b_a when it is contact B
+func (q *Queries) ListContactRelationships(ctx context.Context, arg ListContactRelationshipsParams) ([]ListContactRelationshipsRow, error) {
+	rows, err := q.db.QueryContext(ctx, ListContactRelationships, arg.ContactID, arg.IncludeInactive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListContactRelationshipsRow{}
+	for rows.Next() {
+		var i ListContactRelationshipsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.RelationshipTypeID,
+			&i.StartDate,
+			&i.EndDate,
+			&i.IsActive,
+			&i.Direction,
+			&i.Relation,
+			&i.OtherContactID,
+			&i.OtherDisplayName,
+			&i.CanAccessOther,
+			&i.OtherCanAccess,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListRelationshipPermittedContacts = `-- name: ListRelationshipPermittedContacts :many
+SELECT DISTINCT contact_id FROM acl_contact_cache
+WHERE user_id = $1 AND operation = $2 AND relationship_id IS NOT NULL
+`
+
+type ListRelationshipPermittedContactsParams struct {
+	ContactID uuid.NullUUID `json:"contact_id"`
+	Operation string        `json:"operation"`
+}
+
+// Contacts the contact may access through its relationships
+func (q *Queries) ListRelationshipPermittedContacts(ctx context.Context, arg ListRelationshipPermittedContactsParams) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, ListRelationshipPermittedContacts, arg.ContactID, arg.Operation)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []uuid.UUID{}
+	for rows.Next() {
+		var contact_id uuid.UUID
+		if err := rows.Scan(&contact_id); err != nil {
+			return nil, err
+		}
+		items = append(items, contact_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListRelationshipTypes = `-- name: ListRelationshipTypes :many
+SELECT id, name_a_b, name_b_a, description, contact_type_a, contact_type_b, is_active, is_reserved, created_at, updated_at FROM relationship_types
+WHERE is_active
+ORDER BY name_a_b
+`
+
+func (q *Queries) ListRelationshipTypes(ctx context.Context) ([]RelationshipType, error) {
+	rows, err := q.db.QueryContext(ctx, ListRelationshipTypes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []RelationshipType{}
+	for rows.Next() {
+		var i RelationshipType
+		if err := rows.Scan(
+			&i.ID,
+			&i.NameAB,
+			&i.NameBA,
+			&i.Description,
+			&i.ContactTypeA,
+			&i.ContactTypeB,
+			&i.IsActive,
+			&i.IsReserved,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const UpdateRelationship = `-- name: UpdateRelationship :one
+UPDATE relationships
+SET
+    relationship_type_id = $2,
+    start_date = $3,
+    end_date = $4,
+    is_active = $5,
+    is_permission_a_b = $6,
+    is_permission_b_a = $7,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, contact_id_a, contact_id_b, relationship_type_id, start_date, end_date, is_active, is_permission_a_b, is_permission_b_a, created_at, updated_at
+`
+
+type UpdateRelationshipParams struct {
+	ID                 uuid.UUID    `json:"id"`
+	RelationshipTypeID uuid.UUID    `json:"relationship_type_id"`
+	StartDate          sql.NullTime `json:"start_date"`
+	EndDate            sql.NullTime `json:"end_date"`
+	IsActive           sql.NullBool `json:"is_active"`
+	IsPermissionAB     sql.NullBool `json:"is_permission_a_b"`
+	IsPermissionBA     sql.NullBool `json:"is_permission_b_a"`
+}
+
+func (q *Queries) UpdateRelationship(ctx context.Context, arg UpdateRelationshipParams) (Relationship, error) {
+	row := q.db.QueryRowContext(ctx, UpdateRelationship,
+		arg.ID,
+		arg.RelationshipTypeID,
+		arg.StartDate,
+		arg.EndDate,
+		arg.IsActive,
+		arg.IsPermissionAB,
+		arg.IsPermissionBA,
+	)
+	var i Relationship
+	err := row.Scan(
+		&i.ID,
+		&i.ContactIDA,
+		&i.ContactIDB,
+		&i.RelationshipTypeID,
+		&i.StartDate,
+		&i.EndDate,
+		&i.IsActive,
+		&i.IsPermissionAB,
+		&i.IsPermissionBA,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
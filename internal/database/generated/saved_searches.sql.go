@@ -0,0 +1,172 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: saved_searches.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+const CreateSavedSearch = `-- name: CreateSavedSearch :one
+INSERT INTO saved_searches (
+    name, label, description, api_entity, api_params, created_by
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+) RETURNING id, domain_id, name, label, description, api_entity, api_params, created_by, created_at, updated_at
+`
+
+type CreateSavedSearchParams struct {
+	Name        string          `json:"name"`
+	Label       sql.NullString  `json:"label"`
+	Description sql.NullString  `json:"description"`
+	ApiEntity   string          `json:"api_entity"`
+	ApiParams   json.RawMessage `json:"api_params"`
+	CreatedBy   uuid.NullUUID   `json:"created_by"`
+}
+
+func (q *Queries) CreateSavedSearch(ctx context.Context, arg CreateSavedSearchParams) (SavedSearch, error) {
+	row := q.db.QueryRowContext(ctx, CreateSavedSearch,
+		arg.Name,
+		arg.Label,
+		arg.Description,
+		arg.ApiEntity,
+		arg.ApiParams,
+		arg.CreatedBy,
+	)
+	var i SavedSearch
+	err := row.Scan(
+		&i.ID,
+		&i.DomainID,
+		&i.Name,
+		&i.Label,
+		&i.Description,
+		&i.ApiEntity,
+		&i.ApiParams,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const DeleteSavedSearch = `-- name: DeleteSavedSearch :execrows
+DELETE FROM saved_searches WHERE id = $1
+`
+
+func (q *Queries) DeleteSavedSearch(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, DeleteSavedSearch, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const GetSavedSearch = `-- name: GetSavedSearch :one
+SELECT id, domain_id, name, label, description, api_entity, api_params, created_by, created_at, updated_at FROM saved_searches WHERE id = $1
+`
+
+func (q *Queries) GetSavedSearch(ctx context.Context, id uuid.UUID) (SavedSearch, error) {
+	row := q.db.QueryRowContext(ctx, GetSavedSearch, id)
+	var i SavedSearch
+	err := row.Scan(
+		&i.ID,
+		&i.DomainID,
+		&i.Name,
+		&i.Label,
+		&i.Description,
+		&i.ApiEntity,
+		&i.ApiParams,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const ListSavedSearches = `-- name: ListSavedSearches :many
+SELECT id, domain_id, name, label, description, api_entity, api_params, created_by, created_at, updated_at FROM saved_searches ORDER BY name
+`
+
+func (q *Queries) ListSavedSearches(ctx context.Context) ([]SavedSearch, error) {
+	rows, err := q.db.QueryContext(ctx, ListSavedSearches)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SavedSearch{}
+	for rows.Next() {
+		var i SavedSearch
+		if err := rows.Scan(
+			&i.ID,
+			&i.DomainID,
+			&i.Name,
+			&i.Label,
+			&i.Description,
+			&i.ApiEntity,
+			&i.ApiParams,
+			&i.CreatedBy,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const UpdateSavedSearch = `-- name: UpdateSavedSearch :one
+UPDATE saved_searches
+SET
+    label = $2,
+    description = $3,
+    api_entity = $4,
+    api_params = $5,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, domain_id, name, label, description, api_entity, api_params, created_by, created_at, updated_at
+`
+
+type UpdateSavedSearchParams struct {
+	ID          uuid.UUID       `json:"id"`
+	Label       sql.NullString  `json:"label"`
+	Description sql.NullString  `json:"description"`
+	ApiEntity   string          `json:"api_entity"`
+	ApiParams   json.RawMessage `json:"api_params"`
+}
+
+func (q *Queries) UpdateSavedSearch(ctx context.Context, arg UpdateSavedSearchParams) (SavedSearch, error) {
+	row := q.db.QueryRowContext(ctx, UpdateSavedSearch,
+		arg.ID,
+		arg.Label,
+		arg.Description,
+		arg.ApiEntity,
+		arg.ApiParams,
+	)
+	var i SavedSearch
+	err := row.Scan(
+		&i.ID,
+		&i.DomainID,
+		&i.Name,
+		&i.Label,
+		&i.Description,
+		&i.ApiEntity,
+		&i.ApiParams,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
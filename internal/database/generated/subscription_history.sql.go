@@ -0,0 +1,213 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: subscription_history.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const CreateSubscriptionHistory = `-- name: CreateSubscriptionHistory :one
+INSERT INTO subscription_history (
+    group_id, contact_id, status, method, date, actor_id, tracking
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING id, group_id, contact_id, status, method, date, actor_id, tracking, created_at
+`
+
+type CreateSubscriptionHistoryParams struct {
+	GroupID   uuid.UUID      `json:"group_id"`
+	ContactID uuid.UUID      `json:"contact_id"`
+	Status    string         `json:"status"`
+	Method    string         `json:"method"`
+	Date      time.Time      `json:"date"`
+	ActorID   uuid.NullUUID  `json:"actor_id"`
+	Tracking  sql.NullString `json:"tracking"`
+}
+
+func (q *Queries) CreateSubscriptionHistory(ctx context.Context, arg CreateSubscriptionHistoryParams) (SubscriptionHistory, error) {
+	row := q.db.QueryRowContext(ctx, CreateSubscriptionHistory,
+		arg.GroupID,
+		arg.ContactID,
+		arg.Status,
+		arg.Method,
+		arg.Date,
+		arg.ActorID,
+		arg.Tracking,
+	)
+	var i SubscriptionHistory
+	err := row.Scan(
+		&i.ID,
+		&i.GroupID,
+		&i.ContactID,
+		&i.Status,
+		&i.Method,
+		&i.Date,
+		&i.ActorID,
+		&i.Tracking,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const GetGroupContactForUpdate = `-- name: GetGroupContactForUpdate :one
+
+SELECT id, group_id, contact_id, status, location_id, email_id, phone_id, created_at, updated_at FROM group_contacts WHERE group_id = $1 AND contact_id = $2 FOR UPDATE
+`
+
+type GetGroupContactForUpdateParams struct {
+	GroupID   uuid.UUID `json:"group_id"`
+	ContactID uuid.UUID `json:"contact_id"`
+}
+
+// Group contact and subscription history queries. Every change to a
+// group_contacts status is recorded in subscription_history in the same
+// transaction.
+func (q *Queries) GetGroupContactForUpdate(ctx context.Context, arg GetGroupContactForUpdateParams) (GroupContact, error) {
+	row := q.db.QueryRowContext(ctx, GetGroupContactForUpdate, arg.GroupID, arg.ContactID)
+	var i GroupContact
+	err := row.Scan(
+		&i.ID,
+		&i.GroupID,
+		&i.ContactID,
+		&i.Status,
+		&i.LocationID,
+		&i.EmailID,
+		&i.PhoneID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const GetLatestSubscriptionHistory = `-- name: GetLatestSubscriptionHistory :one
+SELECT id, group_id, contact_id, status, method, date, actor_id, tracking, created_at FROM subscription_history
+WHERE group_id = $1 AND contact_id = $2
+ORDER BY date DESC, created_at DESC
+LIMIT 1
+`
+
+type GetLatestSubscriptionHistoryParams struct {
+	GroupID   uuid.UUID `json:"group_id"`
+	ContactID uuid.UUID `json:"contact_id"`
+}
+
+func (q *Queries) GetLatestSubscriptionHistory(ctx context.Context, arg GetLatestSubscriptionHistoryParams) (SubscriptionHistory, error) {
+	row := q.db.QueryRowContext(ctx, GetLatestSubscriptionHistory, arg.GroupID, arg.ContactID)
+	var i SubscriptionHistory
+	err := row.Scan(
+		&i.ID,
+		&i.GroupID,
+		&i.ContactID,
+		&i.Status,
+		&i.Method,
+		&i.Date,
+		&i.ActorID,
+		&i.Tracking,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const GetSubscriptionHistory = `-- name: GetSubscriptionHistory :one
+SELECT id, group_id, contact_id, status, method, date, actor_id, tracking, created_at FROM subscription_history WHERE id = $1
+`
+
+func (q *Queries) GetSubscriptionHistory(ctx context.Context, id uuid.UUID) (SubscriptionHistory, error) {
+	row := q.db.QueryRowContext(ctx, GetSubscriptionHistory, id)
+	var i SubscriptionHistory
+	err := row.Scan(
+		&i.ID,
+		&i.GroupID,
+		&i.ContactID,
+		&i.Status,
+		&i.Method,
+		&i.Date,
+		&i.ActorID,
+		&i.Tracking,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const ListSubscriptionHistory = `-- name: ListSubscriptionHistory :many
+SELECT id, group_id, contact_id, status, method, date, actor_id, tracking, created_at FROM subscription_history
+WHERE ($1::uuid IS NULL OR contact_id = $1)
+  AND ($2::uuid IS NULL OR group_id = $2)
+ORDER BY date, created_at
+`
+
+type ListSubscriptionHistoryParams struct {
+	ContactID uuid.NullUUID `json:"contact_id"`
+	GroupID   uuid.NullUUID `json:"group_id"`
+}
+
+// History of a contact, a group or both, oldest first
+func (q *Queries) ListSubscriptionHistory(ctx context.Context, arg ListSubscriptionHistoryParams) ([]SubscriptionHistory, error) {
+	rows, err := q.db.QueryContext(ctx, ListSubscriptionHistory, arg.ContactID, arg.GroupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SubscriptionHistory{}
+	for rows.Next() {
+		var i SubscriptionHistory
+		if err := rows.Scan(
+			&i.ID,
+			&i.GroupID,
+			&i.ContactID,
+			&i.Status,
+			&i.Method,
+			&i.Date,
+			&i.ActorID,
+			&i.Tracking,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const UpsertGroupContact = `-- name: UpsertGroupContact :one
+INSERT INTO group_contacts (group_id, contact_id, status)
+VALUES ($1, $2, $3)
+ON CONFLICT (group_id, contact_id) DO UPDATE SET status = EXCLUDED.status
+RETURNING id, group_id, contact_id, status, location_id, email_id, phone_id, created_at, updated_at
+`
+
+type UpsertGroupContactParams struct {
+	GroupID   uuid.UUID      `json:"group_id"`
+	ContactID uuid.UUID      `json:"contact_id"`
+	Status    sql.NullString `json:"status"`
+}
+
+func (q *Queries) UpsertGroupContact(ctx context.Context, arg UpsertGroupContactParams) (GroupContact, error) {
+	row := q.db.QueryRowContext(ctx, UpsertGroupContact, arg.GroupID, arg.ContactID, arg.Status)
+	var i GroupContact
+	err := row.Scan(
+		&i.ID,
+		&i.GroupID,
+		&i.ContactID,
+		&i.Status,
+		&i.LocationID,
+		&i.EmailID,
+		&i.PhoneID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
@@ -370,6 +370,51 @@ func (q *Queries) ListSurveyGroups(ctx context.Context) ([]SurveyGroup, error) {
 	return items, nil
 }
 
+const ListSurveyTargetContacts = `-- name: ListSurveyTargetContacts :many
+SELECT DISTINCT c.id, c.contact_type, c.display_name, c.sort_name
+FROM survey_groups sg
+JOIN group_members gm ON gm.group_id = sg.group_id
+JOIN contacts c ON c.id = gm.contact_id
+WHERE sg.survey_id = $1 AND sg.is_active AND NOT c.is_deleted
+ORDER BY c.sort_name, c.id
+`
+
+type ListSurveyTargetContactsRow struct {
+	ID          uuid.UUID      `json:"id"`
+	ContactType string         `json:"contact_type"`
+	DisplayName sql.NullString `json:"display_name"`
+	SortName    sql.NullString `json:"sort_name"`
+}
+
+// Members of the survey's active groups, including smart and nested groups
+func (q *Queries) ListSurveyTargetContacts(ctx context.Context, surveyID uuid.UUID) ([]ListSurveyTargetContactsRow, error) {
+	rows, err := q.db.QueryContext(ctx, ListSurveyTargetContacts, surveyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListSurveyTargetContactsRow{}
+	for rows.Next() {
+		var i ListSurveyTargetContactsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ContactType,
+			&i.DisplayName,
+			&i.SortName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const SearchSurveyGroups = `-- name: SearchSurveyGroups :many
 SELECT sg.id, sg.survey_id, sg.group_id, sg.is_active, sg.created_at, sg.updated_at FROM survey_groups sg
 JOIN surveys s ON sg.survey_id = s.id
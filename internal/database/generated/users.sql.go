@@ -272,11 +272,12 @@ SELECT
     c.id as contact_id,
     c.first_name,
     c.last_name,
-    c.email as contact_email,
+    pe.email as contact_email,
     c.contact_type
 FROM users u
 LEFT JOIN uf_match ufm ON u.id = ufm.uf_id
 LEFT JOIN contacts c ON ufm.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE u.email = $1 AND u.is_active = true
 `
 
@@ -347,11 +348,12 @@ SELECT
     c.id as contact_id,
     c.first_name,
     c.last_name,
-    c.email as contact_email,
+    pe.email as contact_email,
     c.contact_type
 FROM users u
 LEFT JOIN uf_match ufm ON u.id = ufm.uf_id
 LEFT JOIN contacts c ON ufm.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE u.username = $1 AND u.is_active = true
 `
 
@@ -428,11 +430,12 @@ SELECT
     c.id as contact_id,
     c.first_name,
     c.last_name,
-    c.email as contact_email,
+    pe.email as contact_email,
     c.contact_type
 FROM users u
 LEFT JOIN uf_match ufm ON u.id = ufm.uf_id
 LEFT JOIN contacts c ON ufm.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE u.id = $1 AND u.is_active = true
 `
 
@@ -536,13 +539,14 @@ SELECT
     c.id as contact_id,
     c.first_name,
     c.last_name,
-    c.email as contact_email,
+    pe.email as contact_email,
     c.contact_type
 FROM users u
 INNER JOIN acl_entity_roles aer ON u.id = aer.entity_id
 INNER JOIN acl_roles ar ON aer.acl_role_id = ar.id
 LEFT JOIN uf_match ufm ON u.id = ufm.uf_id
 LEFT JOIN contacts c ON ufm.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE aer.entity_table = 'users' 
 AND ar.name = $1 
 AND u.is_active = true
@@ -611,11 +615,12 @@ SELECT
     c.id as contact_id,
     c.first_name,
     c.last_name,
-    c.email as contact_email,
+    pe.email as contact_email,
     c.contact_type
 FROM users u
 LEFT JOIN uf_match ufm ON u.id = ufm.uf_id
 LEFT JOIN contacts c ON ufm.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE u.is_active = true
 ORDER BY u.username
 `
@@ -0,0 +1,153 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	generated "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/jxlxx/civicrm/internal/metrics"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer emits spans through the globally registered provider, so spans are
+// dropped until the application installs one
+var tracer = otel.Tracer("github.com/jxlxx/civicrm/internal/database")
+
+// Pools reported by the instrumented layer
+const (
+	poolPrimary = "primary"
+	poolRead    = "read"
+	poolTx      = "tx"
+)
+
+// instrumentedDBTX traces every statement and logs the slow ones
+type instrumentedDBTX struct {
+	db   *Database
+	next generated.DBTX
+	pool string
+}
+
+// instrument wraps next so its statements are traced and timed
+func (db *Database) instrument(next generated.DBTX, pool string) generated.DBTX {
+	return instrumentedDBTX{db: db, next: next, pool: pool}
+}
+
+func (i instrumentedDBTX) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, finish := i.db.observe(ctx, i.pool, query)
+	result, err := i.next.ExecContext(ctx, query, args...)
+	finish(err)
+	return result, err
+}
+
+func (i instrumentedDBTX) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	ctx, finish := i.db.observe(ctx, i.pool, query)
+	stmt, err := i.next.PrepareContext(ctx, query)
+	finish(err)
+	return stmt, err
+}
+
+// QueryContext ends the statement's span when its rows are closed, so the
+// time spent fetching rows is traced and counted towards the slow query
+// threshold. Drivers not opened through open return rows the span cannot
+// follow; their span ends when the query returns.
+func (i instrumentedDBTX) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, finish := i.db.observe(ctx, i.pool, query)
+	done := &rowsDone{finish: finish}
+	rows, err := i.next.QueryContext(context.WithValue(ctx, rowsDoneKey{}, done), query, args...)
+	if err != nil || !done.tracked() {
+		finish(err)
+	}
+	return rows, err
+}
+
+func (i instrumentedDBTX) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, finish := i.db.observe(ctx, i.pool, query)
+	row := i.next.QueryRowContext(ctx, query, args...)
+	finish(row.Err())
+	return row
+}
+
+// observe starts a span for a statement and returns a function that ends it
+// and logs the statement if it ran longer than the slow query threshold.
+// Query arguments are never recorded since they may contain personal data.
+func (db *Database) observe(ctx context.Context, pool, query string) (context.Context, func(error)) {
+	name := queryName(query)
+	ctx, span := tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "postgresql"),
+			attribute.String("db.operation", name),
+			attribute.String("db.statement", query),
+			attribute.String("db.pool", pool),
+		),
+	)
+	start := time.Now()
+
+	return ctx, func(err error) {
+		duration := time.Since(start)
+
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+		threshold := db.config.SlowQueryThreshold
+		if threshold > 0 && duration >= threshold && db.logger != nil {
+			db.logger.Warn("Slow query",
+				"query", name,
+				"pool", pool,
+				"duration", duration,
+				"threshold", threshold,
+			)
+		}
+	}
+}
+
+// queryName returns the sqlc query name from the "-- name:" comment, or the
+// statement's first keyword for hand-written SQL
+func queryName(query string) string {
+	query = strings.TrimSpace(query)
+	if rest, found := strings.CutPrefix(query, "-- name: "); found {
+		if name, _, found := strings.Cut(rest, " "); found {
+			return name
+		}
+	}
+
+	keyword, _, _ := strings.Cut(query, " ")
+	keyword, _, _ = strings.Cut(keyword, "\n")
+	return strings.ToUpper(keyword)
+}
+
+// Collect implements metrics.Collector with connection pool statistics for
+// the primary and each replica
+func (db *Database) Collect() []metrics.Sample {
+	samples := poolSamples(poolPrimary, db.db.Stats())
+	for _, r := range db.replicas {
+		samples = append(samples, poolSamples("replica:"+r.addr, r.db.Stats())...)
+	}
+	return samples
+}
+
+// poolSamples converts sql.DBStats into samples labelled with the pool name
+func poolSamples(pool string, stats sql.DBStats) []metrics.Sample {
+	labels := map[string]string{"pool": pool}
+	sample := func(name, help, kind string, value float64) metrics.Sample {
+		return metrics.Sample{Name: name, Help: help, Type: kind, Labels: labels, Value: value}
+	}
+
+	return []metrics.Sample{
+		sample("civicrm_db_open_connections", "Established connections, both in use and idle.", metrics.GaugeType, float64(stats.OpenConnections)),
+		sample("civicrm_db_in_use_connections", "Connections currently in use.", metrics.GaugeType, float64(stats.InUse)),
+		sample("civicrm_db_idle_connections", "Idle connections.", metrics.GaugeType, float64(stats.Idle)),
+		sample("civicrm_db_max_open_connections", "Maximum number of open connections.", metrics.GaugeType, float64(stats.MaxOpenConnections)),
+		sample("civicrm_db_wait_count_total", "Connections waited for because the pool was exhausted.", metrics.CounterType, float64(stats.WaitCount)),
+		sample("civicrm_db_wait_duration_seconds_total", "Time spent waiting for a connection.", metrics.CounterType, stats.WaitDuration.Seconds()),
+	}
+}
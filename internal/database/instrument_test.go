@@ -0,0 +1,177 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/config"
+	"github.com/jxlxx/civicrm/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// TestQueryName tests that sqlc names are preferred over statement keywords
+func TestQueryName(t *testing.T) {
+	assert.Equal(t, "GetContact", queryName("-- name: GetContact :one\nSELECT * FROM contacts WHERE id = $1"))
+	assert.Equal(t, "SELECT", queryName("  select 1"))
+	assert.Equal(t, "UPDATE", queryName("update\ncontacts set first_name = $1"))
+}
+
+// TestSlowQueryLogging tests that statements over the threshold are logged
+// with their query name
+func TestSlowQueryLogging(t *testing.T) {
+	database, _ := newRecordedDatabase(t)
+
+	var buf bytes.Buffer
+	database.SetLogger(&logger.Logger{Logger: slog.New(slog.NewTextHandler(&buf, nil))})
+
+	ctx := context.Background()
+	database.config.SlowQueryThreshold = time.Hour
+	require.NoError(t, database.Querier().DeleteContact(ctx, uuid.New()))
+	assert.Empty(t, buf.String())
+
+	database.config.SlowQueryThreshold = time.Nanosecond
+	require.NoError(t, database.Querier().DeleteContact(ctx, uuid.New()))
+	assert.Contains(t, buf.String(), "Slow query")
+	assert.Contains(t, buf.String(), "query=DeleteContact")
+	assert.Contains(t, buf.String(), "pool=primary")
+}
+
+var (
+	spanExporter    = tracetest.NewInMemoryExporter()
+	installExporter sync.Once
+)
+
+// recordSpans returns an exporter holding the spans the test emits. The
+// package tracer binds to the first global provider installed, so every
+// test shares one.
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	installExporter.Do(func() {
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(spanExporter)))
+	})
+	spanExporter.Reset()
+	t.Cleanup(spanExporter.Reset)
+	return spanExporter
+}
+
+// TestQuerySpans tests that every statement is traced, including statements
+// run inside a transaction
+func TestQuerySpans(t *testing.T) {
+	exporter := recordSpans(t)
+
+	database, _ := newRecordedDatabase(t)
+	ctx := context.Background()
+
+	require.NoError(t, database.Querier().DeleteContact(ctx, uuid.New()))
+	_, err := database.Exec(ctx, "UPDATE contacts SET first_name = $1", "Ada")
+	require.NoError(t, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "DeleteContact", spans[0].Name)
+	assert.Contains(t, spans[0].Attributes, attribute.String("db.pool", "primary"))
+	assert.Equal(t, "UPDATE", spans[1].Name)
+}
+
+// TestCollectPoolStats tests that pool statistics are exported as metrics
+func TestCollectPoolStats(t *testing.T) {
+	database, _ := newRecordedDatabase(t)
+	database.replicas = []*replica{newTestReplica(t, true)}
+
+	names := map[string][]string{}
+	for _, sample := range database.Collect() {
+		names[sample.Name] = append(names[sample.Name], sample.Labels["pool"])
+	}
+
+	for _, name := range []string{
+		"civicrm_db_open_connections",
+		"civicrm_db_in_use_connections",
+		"civicrm_db_wait_count_total",
+		"civicrm_db_wait_duration_seconds_total",
+	} {
+		assert.Equal(t, []string{"primary", "replica:replica"}, names[name], name)
+	}
+}
+
+// slowRows is a driver connector whose queries return rows that take
+// rowDelay each to fetch
+type slowRows struct {
+	rows     int
+	rowDelay time.Duration
+}
+
+func (s slowRows) Connect(context.Context) (driver.Conn, error) { return slowRowsConn{s}, nil }
+func (s slowRows) Driver() driver.Driver                        { return nil }
+
+type slowRowsConn struct{ s slowRows }
+
+func (c slowRowsConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not supported")
+}
+func (c slowRowsConn) Close() error              { return nil }
+func (c slowRowsConn) Begin() (driver.Tx, error) { return nil, errors.New("begin not supported") }
+
+func (c slowRowsConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return &slowRowsIter{s: c.s}, nil
+}
+
+type slowRowsIter struct {
+	s    slowRows
+	read int
+}
+
+func (r *slowRowsIter) Columns() []string { return []string{"n"} }
+func (r *slowRowsIter) Close() error      { return nil }
+
+func (r *slowRowsIter) Next(dest []driver.Value) error {
+	if r.read == r.s.rows {
+		return io.EOF
+	}
+	time.Sleep(r.s.rowDelay)
+	r.read++
+	dest[0] = int64(r.read)
+	return nil
+}
+
+// TestQuerySpanCoversRows tests that a query's span and slow query timer run
+// until its rows are closed, so slow row fetching is traced and logged
+func TestQuerySpanCoversRows(t *testing.T) {
+	exporter := recordSpans(t)
+
+	pool := sql.OpenDB(rowsConnector{slowRows{rows: 3, rowDelay: 20 * time.Millisecond}})
+	t.Cleanup(func() { pool.Close() })
+	var buf bytes.Buffer
+	database := &Database{config: &config.DatabaseConfig{SlowQueryThreshold: 50 * time.Millisecond}, db: pool}
+	database.SetLogger(&logger.Logger{Logger: slog.New(slog.NewTextHandler(&buf, nil))})
+
+	rows, err := database.instrument(primaryDBTX{pool}, poolPrimary).QueryContext(context.Background(), "-- name: ListNumbers :many\nSELECT n")
+	require.NoError(t, err)
+	assert.Empty(t, exporter.GetSpans(), "the span ends with the rows")
+
+	var count int
+	for rows.Next() {
+		count++
+	}
+	require.NoError(t, rows.Close())
+	assert.Equal(t, 3, count)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "ListNumbers", spans[0].Name)
+	assert.GreaterOrEqual(t, spans[0].EndTime.Sub(spans[0].StartTime), 60*time.Millisecond)
+	assert.Contains(t, buf.String(), "query=ListNumbers")
+}
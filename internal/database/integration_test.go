@@ -40,7 +40,6 @@ func TestDatabaseIntegration(t *testing.T) {
 		ContactType: "Individual",
 		FirstName:   sql.NullString{String: "Test", Valid: true},
 		LastName:    sql.NullString{String: "User", Valid: true},
-		Email:       sql.NullString{String: "test@example.com", Valid: true},
 	})
 	require.NoError(t, err)
 	require.NotNil(t, contact)
@@ -58,7 +57,6 @@ func TestDatabaseIntegration(t *testing.T) {
 		ContactType: "Individual",
 		FirstName:   sql.NullString{String: "Updated", Valid: true},
 		LastName:    sql.NullString{String: "User", Valid: true},
-		Email:       sql.NullString{String: "updated@example.com", Valid: true},
 	})
 	require.NoError(t, err)
 	require.Equal(t, "Updated", updated.FirstName.String)
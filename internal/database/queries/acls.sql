@@ -248,3 +248,26 @@ FROM acls
 WHERE is_active = true;
 
 
+
+-- name: ListACLGroupPermittedContacts :many
+-- Contacts a user may access through their roles' ACLs on groups, including
+-- members of smart and nested groups. Deny rules on a group win over allow
+-- rules.
+WITH role_acls AS (
+    SELECT a.object_id, a.deny FROM acls a
+    INNER JOIN acl_entity_roles aer ON a.entity_id = aer.acl_role_id
+    WHERE aer.entity_table = 'users'
+    AND aer.entity_id = @user_id
+    AND a.entity_table = 'acl_roles'
+    AND a.object_table = 'groups'
+    AND a.operation = @operation
+    AND a.is_active = true
+    AND aer.is_active = true
+)
+SELECT gm.contact_id FROM role_acls ra
+JOIN group_members gm ON gm.group_id = ra.object_id
+WHERE NOT ra.deny
+EXCEPT
+SELECT gm.contact_id FROM role_acls ra
+JOIN group_members gm ON gm.group_id = ra.object_id
+WHERE ra.deny;
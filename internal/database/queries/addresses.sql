@@ -0,0 +1,74 @@
+-- Address queries. The trigger from migration 049 clears the street parts
+-- when street_address changes; those from migration 050 keep shared
+-- addresses identical to their master.
+
+-- name: ListAddressesToParse :many
+-- Addresses of a domain's contacts with a street address that has not been
+-- split, in ID order after after_id. Shared addresses take their master's
+-- parts.
+SELECT a.id, a.street_address, co.iso_code AS country_code
+FROM addresses a
+JOIN contacts c ON c.id = a.contact_id
+LEFT JOIN countries co ON co.id = a.country_id
+WHERE c.domain_id = @domain_id
+  AND a.id > @after_id
+  AND a.street_address IS NOT NULL
+  AND a.master_id IS NULL
+  AND a.street_number IS NULL AND a.street_name IS NULL AND a.street_unit IS NULL
+ORDER BY a.id
+LIMIT @batch_size;
+
+-- name: SetAddressStreetParts :execrows
+-- An address whose street changed since it was listed is left for the next
+-- run
+UPDATE addresses
+SET street_number = @street_number, street_name = @street_name, street_unit = @street_unit,
+    updated_at = NOW()
+WHERE id = @id AND street_address = @street_address;
+
+-- name: ListMailingAddresses :many
+-- The primary addresses of contacts, by sort name
+SELECT c.id AS contact_id, c.display_name, a.street_address, a.supplemental_address_1,
+       a.city, a.postal_code, sp.abbreviation AS state_province, co.name AS country,
+       co.iso_code AS country_code
+FROM contacts c
+JOIN addresses a ON a.contact_id = c.id AND a.is_primary
+LEFT JOIN state_provinces sp ON sp.id = a.state_province_id
+LEFT JOIN countries co ON co.id = a.country_id
+WHERE c.id = ANY(@contact_ids::uuid[]) AND NOT c.is_deleted
+ORDER BY c.sort_name, c.id;
+
+-- name: GetAddress :one
+SELECT * FROM addresses WHERE id = $1;
+
+-- name: GetAddressForUpdate :one
+SELECT * FROM addresses WHERE id = $1 FOR UPDATE;
+
+-- name: UpdateAddress :one
+UPDATE addresses
+SET street_address = @street_address,
+    street_number = @street_number,
+    street_name = @street_name,
+    street_unit = @street_unit,
+    supplemental_address_1 = @supplemental_address_1,
+    city = @city,
+    state_province_id = @state_province_id,
+    postal_code = @postal_code,
+    country_id = @country_id,
+    updated_at = NOW()
+WHERE id = @id
+RETURNING *;
+
+-- name: CreateSharedAddress :one
+-- The fields are copied from the master by trigger
+INSERT INTO addresses (contact_id, location_type_id, is_primary, master_id)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: UnshareAddress :one
+UPDATE addresses SET master_id = NULL, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: GetStateProvince :one
+SELECT * FROM state_provinces WHERE id = $1;
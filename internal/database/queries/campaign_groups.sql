@@ -79,3 +79,13 @@ LEFT JOIN groups g ON cg.group_id = g.id
 WHERE c.is_active = $1
 GROUP BY c.id, c.name
 ORDER BY c.start_date DESC;
+
+-- name: ListCampaignTargetContacts :many
+-- Members of the campaign's active target groups, including smart and
+-- nested groups
+SELECT DISTINCT c.id, c.contact_type, c.display_name, c.sort_name
+FROM campaign_groups cg
+JOIN group_members gm ON gm.group_id = cg.group_id
+JOIN contacts c ON c.id = gm.contact_id
+WHERE cg.campaign_id = $1 AND cg.is_active AND NOT c.is_deleted
+ORDER BY c.sort_name, c.id;
@@ -45,8 +45,8 @@ DELETE FROM message_templates WHERE id = $1;
 -- Mailing Lists CRUD operations
 -- name: CreateMailingList :one
 INSERT INTO mailing_lists (
-    name, title, description, is_public, is_hidden, is_active
-) VALUES ($1, $2, $3, $4, $5, $6) RETURNING *;
+    name, title, description, is_public, is_hidden, is_active, group_id
+) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING *;
 
 -- name: GetMailingList :one
 SELECT * FROM mailing_lists WHERE id = $1;
@@ -66,6 +66,7 @@ UPDATE mailing_lists SET
     is_public = $4,
     is_hidden = $5,
     is_active = $6,
+    group_id = $7,
     updated_at = NOW()
 WHERE id = $1 RETURNING *;
 
@@ -86,12 +87,34 @@ SELECT * FROM mailing_list_subscriptions
 WHERE mailing_list_id = $1 AND contact_id = $2;
 
 -- name: ListSubscriptionsByList :many
-SELECT mls.*, c.first_name, c.last_name, c.email
+SELECT mls.*, c.first_name, c.last_name, pe.email
 FROM mailing_list_subscriptions mls
 INNER JOIN contacts c ON mls.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE mls.mailing_list_id = $1 AND mls.is_active = $2
 ORDER BY c.last_name, c.first_name;
 
+-- name: ListMailingListRecipients :many
+-- Active subscribers and members of the list's group and the groups nested
+-- under it, except contacts who unsubscribed from the list
+SELECT c.id AS contact_id, c.display_name, pe.email
+FROM contacts c
+JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
+WHERE NOT c.is_deleted
+AND c.id IN (
+    SELECT mls.contact_id FROM mailing_list_subscriptions mls
+    WHERE mls.mailing_list_id = @mailing_list_id AND mls.is_active AND mls.status = 'Subscribed'
+    UNION
+    SELECT gm.contact_id FROM group_members gm
+    JOIN mailing_lists ml ON ml.group_id = gm.group_id
+    WHERE ml.id = @mailing_list_id
+)
+AND NOT EXISTS (
+    SELECT 1 FROM mailing_list_subscriptions mls
+    WHERE mls.mailing_list_id = @mailing_list_id AND mls.contact_id = c.id AND mls.status = 'Unsubscribed'
+)
+ORDER BY c.sort_name, c.id;
+
 -- name: ListSubscriptionsByContact :many
 SELECT mls.*, ml.name as list_name, ml.title as list_title
 FROM mailing_list_subscriptions mls
@@ -165,9 +188,10 @@ SELECT * FROM mailing_recipients
 WHERE mailing_id = $1 AND contact_id = $2;
 
 -- name: ListRecipientsByMailing :many
-SELECT mr.*, c.first_name, c.last_name, c.email as contact_email
+SELECT mr.*, c.first_name, c.last_name, pe.email as contact_email
 FROM mailing_recipients mr
 INNER JOIN contacts c ON mr.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE mr.mailing_id = $1
 ORDER BY c.last_name, c.first_name;
 
@@ -327,10 +351,11 @@ LIMIT $2;
 
 -- name: GetOptOutContacts :many
 SELECT 
-    c.id, c.first_name, c.last_name, c.email,
+    c.id, c.first_name, c.last_name, pe.email,
     cp.email_opt_out, cp.sms_opt_out, cp.mail_opt_out, cp.phone_opt_out
 FROM contacts c
 INNER JOIN communication_preferences cp ON c.id = cp.contact_id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE cp.email_opt_out = $1 OR cp.sms_opt_out = $2 OR cp.mail_opt_out = $3 OR cp.phone_opt_out = $4
 ORDER BY c.last_name, c.first_name
 LIMIT $5 OFFSET $6;
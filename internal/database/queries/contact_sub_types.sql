@@ -0,0 +1,26 @@
+-- Contact subtype queries
+
+-- name: CreateContactSubType :one
+INSERT INTO contact_sub_types (
+    name, label, parent, description
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING *;
+
+-- name: GetContactSubTypeByName :one
+SELECT * FROM contact_sub_types WHERE name = $1;
+
+-- name: ListContactSubTypes :many
+SELECT * FROM contact_sub_types
+WHERE is_active
+ORDER BY parent, label;
+
+-- name: ListContactSubTypesByName :many
+SELECT * FROM contact_sub_types
+WHERE name = ANY(@names::text[]);
+
+-- name: UpdateContactSubType :one
+UPDATE contact_sub_types
+SET label = $2, description = $3, is_active = $4, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
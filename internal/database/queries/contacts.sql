@@ -1,40 +1,51 @@
 -- name: CreateContact :one
 INSERT INTO contacts (
-    contact_type, first_name, last_name, organization_name, 
-    email, phone, address_line_1, address_line_2, 
-    city, state_province, postal_code, country
+    contact_type, contact_sub_type, prefix, first_name, last_name, suffix,
+    nick_name, organization_name, household_name
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+    $1, $2, $3, $4, $5, $6, $7, $8, $9
 ) RETURNING *;
 
 -- name: GetContact :one
 SELECT * FROM contacts WHERE id = $1;
 
 -- name: GetContactByEmail :one
-SELECT * FROM contacts WHERE email = $1;
+SELECT c.* FROM contacts c
+JOIN emails e ON e.contact_id = c.id
+WHERE lower(e.email) = lower(@email) AND NOT c.is_deleted
+ORDER BY e.is_primary DESC, c.created_at
+LIMIT 1;
 
 -- name: GetContactByPhone :one
-SELECT * FROM contacts WHERE phone = $1;
+SELECT c.* FROM contacts c
+JOIN phones p ON p.contact_id = c.id
+WHERE p.phone = @phone AND NOT c.is_deleted
+ORDER BY p.is_primary DESC, c.created_at
+LIMIT 1;
 
 -- name: ListContacts :many
 SELECT * FROM contacts 
-WHERE contact_type = $1 
+WHERE contact_type = $1 AND NOT is_deleted
 ORDER BY created_at DESC 
 LIMIT $2 OFFSET $3;
 
 -- name: ListAllContacts :many
 SELECT * FROM contacts 
+WHERE NOT is_deleted
 ORDER BY created_at DESC 
 LIMIT $1 OFFSET $2;
 
 -- name: SearchContacts :many
 SELECT * FROM contacts 
 WHERE (
+    display_name ILIKE $1 OR
     first_name ILIKE $1 OR 
     last_name ILIKE $1 OR 
+    nick_name ILIKE $1 OR
     organization_name ILIKE $1 OR
-    email ILIKE $1
-)
+    household_name ILIKE $1 OR
+    EXISTS (SELECT 1 FROM emails e WHERE e.contact_id = contacts.id AND e.email ILIKE $1)
+) AND NOT is_deleted
 ORDER BY created_at DESC 
 LIMIT $2 OFFSET $3;
 
@@ -42,36 +53,62 @@ LIMIT $2 OFFSET $3;
 UPDATE contacts 
 SET 
     contact_type = $2,
-    first_name = $3,
-    last_name = $4,
-    organization_name = $5,
-    email = $6,
-    phone = $7,
-    address_line_1 = $8,
-    address_line_2 = $9,
-    city = $10,
-    state_province = $11,
-    postal_code = $12,
-    country = $13,
+    contact_sub_type = $3,
+    prefix = $4,
+    first_name = $5,
+    last_name = $6,
+    suffix = $7,
+    nick_name = $8,
+    organization_name = $9,
+    household_name = $10,
     updated_at = NOW()
 WHERE id = $1 
 RETURNING *;
 
+-- name: SetContactNames :one
+-- Stores the computed display and sort names
+UPDATE contacts
+SET display_name = @display_name, sort_name = @sort_name
+WHERE id = @id
+RETURNING *;
+
 -- name: DeleteContact :exec
 DELETE FROM contacts WHERE id = $1;
 
 -- name: CountContacts :one
-SELECT COUNT(*) FROM contacts WHERE contact_type = $1;
+SELECT COUNT(*) FROM contacts WHERE contact_type = $1 AND NOT is_deleted;
 
 -- name: CountAllContacts :one
-SELECT COUNT(*) FROM contacts;
+SELECT COUNT(*) FROM contacts WHERE NOT is_deleted;
 
 -- name: GetContactsByType :many
 SELECT * FROM contacts 
-WHERE contact_type = $1 
+WHERE contact_type = $1 AND NOT is_deleted
 ORDER BY created_at DESC;
 
 -- name: GetContactsByLocation :many
-SELECT * FROM contacts 
-WHERE city = $1 AND state_province = $2 
-ORDER BY created_at DESC;
+SELECT c.* FROM contacts c
+JOIN addresses a ON a.contact_id = c.id AND a.is_primary
+LEFT JOIN state_provinces sp ON sp.id = a.state_province_id
+WHERE a.city = @city
+  AND (sp.name = @state_province OR sp.abbreviation = @state_province)
+  AND NOT c.is_deleted
+ORDER BY c.created_at DESC;
+
+-- name: ListContactsNear :many
+-- Contacts whose primary address lies within radius_km of a point, nearest
+-- first. The latitude range narrows the addresses before their great-circle
+-- distance is computed.
+SELECT c.* FROM contacts c
+JOIN addresses a ON a.contact_id = c.id AND a.is_primary
+CROSS JOIN LATERAL (
+    SELECT 6371.0088 * 2 * asin(least(1, sqrt(
+        power(sin(radians(a.geo_code_1::float8 - @lat::float8) / 2), 2)
+        + cos(radians(@lat::float8)) * cos(radians(a.geo_code_1::float8))
+        * power(sin(radians(a.geo_code_2::float8 - @lon::float8) / 2), 2)))) AS km
+) d
+WHERE a.geo_code_1 BETWEEN @min_lat::float8 AND @max_lat::float8
+  AND d.km <= @radius_km::float8
+  AND NOT c.is_deleted
+ORDER BY d.km, c.id
+LIMIT @row_limit;
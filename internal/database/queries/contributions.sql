@@ -20,9 +20,10 @@ SELECT
     co.first_name,
     co.last_name,
     co.organization_name,
-    co.email
+    pe.email
 FROM contributions c
 JOIN contacts co ON c.contact_id = co.id
+LEFT JOIN emails pe ON pe.contact_id = co.id AND pe.is_primary
 ORDER BY c.received_date DESC 
 LIMIT $1 OFFSET $2;
 
@@ -33,9 +34,10 @@ SELECT
     co.first_name,
     co.last_name,
     co.organization_name,
-    co.email
+    pe.email
 FROM contributions c
 JOIN contacts co ON c.contact_id = co.id
+LEFT JOIN emails pe ON pe.contact_id = co.id AND pe.is_primary
 WHERE c.contribution_type = $1
 ORDER BY c.received_date DESC 
 LIMIT $2 OFFSET $3;
@@ -47,9 +49,10 @@ SELECT
     co.first_name,
     co.last_name,
     co.organization_name,
-    co.email
+    pe.email
 FROM contributions c
 JOIN contacts co ON c.contact_id = co.id
+LEFT JOIN emails pe ON pe.contact_id = co.id AND pe.is_primary
 WHERE c.status = $1
 ORDER BY c.received_date DESC 
 LIMIT $2 OFFSET $3;
@@ -61,14 +64,15 @@ SELECT
     co.first_name,
     co.last_name,
     co.organization_name,
-    co.email
+    pe.email
 FROM contributions c
 JOIN contacts co ON c.contact_id = co.id
+LEFT JOIN emails pe ON pe.contact_id = co.id AND pe.is_primary
 WHERE (
     co.first_name ILIKE $1 OR 
     co.last_name ILIKE $1 OR 
     co.organization_name ILIKE $1 OR
-    co.email ILIKE $1
+    pe.email ILIKE $1
 )
 ORDER BY c.received_date DESC 
 LIMIT $2 OFFSET $3;
@@ -112,8 +116,9 @@ SELECT
     co.first_name,
     co.last_name,
     co.organization_name,
-    co.email
+    pe.email
 FROM contributions c
 JOIN contacts co ON c.contact_id = co.id
+LEFT JOIN emails pe ON pe.contact_id = co.id AND pe.is_primary
 WHERE c.received_date >= $1 AND c.received_date <= $2
 ORDER BY c.received_date DESC;
@@ -5,9 +5,9 @@
 -- name: CreateCustomGroup :one
 INSERT INTO custom_groups (
     name, title, extends, table_name, is_active, is_multiple, 
-    collapse_display, help_pre, help_post, weight
+    collapse_display, help_pre, help_post, weight, extends_entity_column_value
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
 ) RETURNING *;
 
 -- name: GetCustomGroup :one
@@ -37,9 +37,23 @@ UPDATE custom_groups SET
     help_pre = $8,
     help_post = $9,
     weight = $10,
+    extends_entity_column_value = $11,
     updated_at = NOW()
 WHERE id = $1 RETURNING *;
 
+-- name: ListCustomGroupsForContact :many
+-- Groups that apply to a contact: those extending every contact, its contact
+-- type, or its contact type limited to one of its subtypes
+SELECT * FROM custom_groups
+WHERE is_active
+  AND (
+    extends = 'Contact'
+    OR (extends = @contact_type::text
+        AND (extends_entity_column_value IS NULL
+             OR extends_entity_column_value && @contact_sub_types::text[]))
+  )
+ORDER BY weight, title;
+
 -- name: DeleteCustomGroup :exec
 DELETE FROM custom_groups WHERE id = $1;
 
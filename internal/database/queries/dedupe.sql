@@ -0,0 +1,60 @@
+-- name: CreateDedupeRuleGroup :one
+INSERT INTO dedupe_rule_groups (
+    name, title, contact_type, used, threshold, is_reserved
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: GetDedupeRuleGroup :one
+SELECT * FROM dedupe_rule_groups WHERE id = $1;
+
+-- name: GetDedupeRuleGroupByName :one
+SELECT * FROM dedupe_rule_groups WHERE name = $1;
+
+-- name: GetDedupeRuleGroupByUsage :one
+SELECT * FROM dedupe_rule_groups
+WHERE contact_type = $1 AND used = $2
+ORDER BY created_at ASC
+LIMIT 1;
+
+-- name: ListDedupeRuleGroups :many
+SELECT * FROM dedupe_rule_groups
+ORDER BY contact_type ASC, used DESC, name ASC;
+
+-- name: DeleteDedupeRuleGroup :exec
+DELETE FROM dedupe_rule_groups WHERE id = $1 AND is_reserved = FALSE;
+
+-- name: CreateDedupeRule :one
+INSERT INTO dedupe_rules (
+    rule_group_id, rule_table, rule_field, rule_length, rule_weight
+) VALUES (
+    $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: ListDedupeRules :many
+SELECT * FROM dedupe_rules
+WHERE rule_group_id = $1
+ORDER BY rule_weight DESC, rule_table ASC, rule_field ASC;
+
+-- name: ListContactsForDedupe :many
+SELECT * FROM contacts
+WHERE contact_type = @contact_type AND id > @after_id AND NOT is_deleted
+ORDER BY id ASC
+LIMIT @batch_size;
+
+-- name: ListContactsByIDs :many
+SELECT * FROM contacts
+WHERE id = ANY(@ids::uuid[])
+ORDER BY id ASC;
+
+-- name: ListEmailsForContacts :many
+SELECT * FROM emails
+WHERE contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: ListPhonesForContacts :many
+SELECT * FROM phones
+WHERE contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: ListAddressesForContacts :many
+SELECT * FROM addresses
+WHERE contact_id = ANY(@contact_ids::uuid[]);
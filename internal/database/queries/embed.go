@@ -0,0 +1,10 @@
+// Package queries embeds the sqlc query files so tools such as the migration
+// linter can check them at runtime.
+package queries
+
+import "embed"
+
+// FS holds every query file in this directory
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,28 @@
+-- Address geocoding queries. The trigger from migration 047 clears
+-- geocoded_at when an address changes, so these only track lookups.
+
+-- name: ListAddressesToGeocode :many
+-- Addresses of a domain's contacts not looked up since they last changed,
+-- oldest change first. Shared addresses take their master's coordinates.
+SELECT a.id, a.updated_at, a.street_address, a.city, a.postal_code,
+       sp.name AS state_province, co.iso_code AS country_code
+FROM addresses a
+JOIN contacts c ON c.id = a.contact_id
+LEFT JOIN state_provinces sp ON sp.id = a.state_province_id
+LEFT JOIN countries co ON co.id = a.country_id
+WHERE c.domain_id = @domain_id
+  AND NOT c.is_deleted
+  AND a.geocoded_at IS NULL
+  AND a.master_id IS NULL
+  AND NOT COALESCE(a.manual_geo_code, FALSE)
+ORDER BY a.updated_at, a.id
+LIMIT @batch_size;
+
+-- name: SetAddressGeoCode :execrows
+-- Records a lookup, with NULL coordinates when the address was not found.
+-- An address changed since it was listed is left for the next run.
+UPDATE addresses
+SET geo_code_1 = @geo_code_1, geo_code_2 = @geo_code_2, geocoded_at = @geocoded_at
+WHERE id = @id
+  AND updated_at IS NOT DISTINCT FROM @updated_at
+  AND geocoded_at IS NULL;
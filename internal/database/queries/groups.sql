@@ -0,0 +1,74 @@
+-- Group queries. Members of static, smart and nested groups are read
+-- through the group_members view from migrations 044 and 045.
+
+-- name: CreateGroup :one
+INSERT INTO groups (
+    name, title, description, saved_search_id, group_type, visibility, is_active
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: GetGroup :one
+SELECT * FROM groups WHERE id = $1;
+
+-- name: GetGroupForUpdate :one
+-- Serializes refreshes of a smart group's cache
+SELECT * FROM groups WHERE id = $1 FOR UPDATE;
+
+-- name: ListGroups :many
+SELECT * FROM groups
+WHERE is_active
+ORDER BY title, name;
+
+-- name: ListStaleSmartGroups :many
+-- Active smart groups of a domain whose cache has expired or was never built
+SELECT g.* FROM groups g
+JOIN saved_searches s ON s.id = g.saved_search_id
+WHERE s.domain_id = @domain_id
+  AND g.is_active
+  AND (g.refresh_date IS NULL OR g.refresh_date <= NOW())
+ORDER BY g.refresh_date ASC NULLS FIRST;
+
+-- name: ExpireSavedSearchGroups :exec
+-- Marks the cache of the groups using a saved search as expired
+UPDATE groups SET refresh_date = NULL WHERE saved_search_id = $1;
+
+-- name: DeleteGroupContactCache :exec
+DELETE FROM group_contact_cache WHERE group_id = $1;
+
+-- name: InsertGroupContactCache :execrows
+INSERT INTO group_contact_cache (group_id, contact_id)
+SELECT @group_id, unnest(@contact_ids::uuid[])
+ON CONFLICT DO NOTHING;
+
+-- name: SetGroupCacheDates :exec
+UPDATE groups SET cache_date = $2, refresh_date = $3 WHERE id = $1;
+
+-- name: ListGroupMembers :many
+SELECT c.id, c.contact_type, c.display_name, c.sort_name, e.email
+FROM group_members gm
+JOIN contacts c ON c.id = gm.contact_id
+LEFT JOIN emails e ON e.contact_id = c.id AND e.is_primary
+WHERE gm.group_id = $1 AND NOT c.is_deleted
+ORDER BY c.sort_name, c.id;
+
+-- name: CreateGroupNesting :exec
+-- Nesting a group under the same parent twice does nothing. A trigger
+-- rejects nesting that would create a cycle.
+INSERT INTO group_nesting (parent_group_id, child_group_id)
+VALUES ($1, $2)
+ON CONFLICT (parent_group_id, child_group_id) DO NOTHING;
+
+-- name: DeleteGroupNesting :execrows
+DELETE FROM group_nesting WHERE parent_group_id = $1 AND child_group_id = $2;
+
+-- name: ListGroupNesting :many
+SELECT parent_group_id, child_group_id FROM group_nesting
+ORDER BY parent_group_id, child_group_id;
+
+-- name: ListGroupDescendants :many
+-- Groups nested under a group at any depth
+SELECT g.* FROM group_hierarchy h
+JOIN groups g ON g.id = h.descendant_id
+WHERE h.ancestor_id = $1
+ORDER BY g.title, g.name;
@@ -137,3 +137,33 @@ WHERE job_id = $1
 AND started_at >= NOW() - INTERVAL '30 days'
 GROUP BY DATE(started_at)
 ORDER BY execution_date DESC;
+
+-- name: SeedDomainJobs :execrows
+-- Copies the named jobs of the default domain to every active domain that
+-- does not have them yet
+INSERT INTO jobs (domain_id, name, description, job_type, parameters, schedule, is_active)
+SELECT d.id, j.name, j.description, j.job_type, j.parameters, j.schedule, j.is_active
+FROM domains d
+JOIN jobs j ON j.domain_id = (SELECT id FROM domains WHERE name = 'default')
+WHERE d.is_active = TRUE
+AND j.name = ANY(@names::text[])
+AND NOT EXISTS (SELECT 1 FROM jobs o WHERE o.domain_id = d.id AND o.name = j.name)
+ON CONFLICT (domain_id, name) DO NOTHING;
+
+-- name: ClaimDueJobs :many
+-- Locks the due jobs with a handler so only one instance runs each
+SELECT * FROM jobs
+WHERE is_active = TRUE
+AND schedule IS NOT NULL
+AND (next_run IS NULL OR next_run <= NOW())
+AND name = ANY(@names::text[])
+ORDER BY next_run ASC NULLS FIRST, name ASC
+FOR UPDATE SKIP LOCKED;
+
+-- name: CreateJobLog :one
+INSERT INTO job_logs (job_id, status) VALUES ($1, 'running') RETURNING *;
+
+-- name: FinishJobLog :exec
+UPDATE job_logs
+SET status = $2, message = $3, completed_at = NOW(), execution_time_ms = $4
+WHERE id = $1;
@@ -0,0 +1,65 @@
+-- Contact location queries. The triggers from migration 038 keep exactly one
+-- primary record per contact and kind: creating or updating a record with
+-- is_primary demotes the previous primary one.
+
+-- name: CreateEmail :one
+INSERT INTO emails (
+    contact_id, location_type_id, is_primary, is_billing, email, on_hold, is_bulkmail
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: CreatePhone :one
+INSERT INTO phones (
+    contact_id, location_type_id, phone_type, is_primary, phone, phone_ext
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: CreateAddress :one
+INSERT INTO addresses (
+    contact_id, location_type_id, is_primary, is_billing, street_address,
+    street_number, street_name, street_unit, supplemental_address_1, city,
+    state_province_id, postal_code, country_id
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+) RETURNING *;
+
+-- name: SetPrimaryEmail :exec
+UPDATE emails SET is_primary = TRUE, updated_at = NOW() WHERE id = $1;
+
+-- name: SetPrimaryPhone :exec
+UPDATE phones SET is_primary = TRUE, updated_at = NOW() WHERE id = $1;
+
+-- name: SetPrimaryAddress :exec
+UPDATE addresses SET is_primary = TRUE, updated_at = NOW() WHERE id = $1;
+
+-- name: ListPrimaryEmails :many
+SELECT * FROM emails
+WHERE contact_id = ANY(@contact_ids::uuid[]) AND is_primary;
+
+-- name: ListPrimaryPhones :many
+SELECT * FROM phones
+WHERE contact_id = ANY(@contact_ids::uuid[]) AND is_primary;
+
+-- name: ListPrimaryAddresses :many
+SELECT * FROM addresses
+WHERE contact_id = ANY(@contact_ids::uuid[]) AND is_primary;
+
+-- name: GetLocationTypeByName :one
+SELECT * FROM location_types WHERE name = $1;
+
+-- name: GetCountryByNameOrCode :one
+SELECT * FROM countries
+WHERE lower(name) = lower(@country) OR lower(iso_code) = lower(@country)
+LIMIT 1;
+
+-- name: GetStateProvinceByName :one
+SELECT * FROM state_provinces
+WHERE (lower(name) = lower(@state_province) OR lower(abbreviation) = lower(@state_province))
+  AND (sqlc.narg(country_id)::uuid IS NULL OR country_id = sqlc.narg(country_id)::uuid)
+ORDER BY name
+LIMIT 1;
+
+-- name: GetCountry :one
+SELECT * FROM countries WHERE id = $1;
@@ -0,0 +1,209 @@
+-- Contact merge queries. Each move takes the kept contact as @main_id and
+-- the duplicate as @other_id. Rows that would duplicate one the kept contact
+-- already has stay with the deleted duplicate as history.
+
+-- name: LockContactsForMerge :many
+SELECT * FROM contacts
+WHERE id IN (@main_id::uuid, @other_id::uuid)
+ORDER BY id
+FOR UPDATE;
+
+-- name: MarkContactMerged :exec
+UPDATE contacts
+SET is_deleted = TRUE, merged_to_id = @main_id::uuid, updated_at = NOW()
+WHERE id = @other_id;
+
+-- name: RedirectMergedContacts :execrows
+UPDATE contacts SET merged_to_id = @main_id::uuid WHERE merged_to_id = @other_id::uuid;
+
+-- name: UpdateMergedContact :one
+UPDATE contacts
+SET
+    contact_sub_type = @contact_sub_type,
+    prefix = @prefix,
+    first_name = @first_name,
+    last_name = @last_name,
+    suffix = @suffix,
+    nick_name = @nick_name,
+    organization_name = @organization_name,
+    household_name = @household_name,
+    updated_at = NOW()
+WHERE id = @id
+RETURNING *;
+
+-- name: CreateContactMerge :one
+INSERT INTO contact_merges (
+    main_contact_id, other_contact_id, field_choices, moved, other_snapshot, merged_by
+) VALUES (
+    @main_contact_id, @other_contact_id, @field_choices, @moved, @other_snapshot, @merged_by
+) RETURNING *;
+
+-- name: ListContactMerges :many
+SELECT * FROM contact_merges
+WHERE main_contact_id = @contact_id OR other_contact_id = @contact_id
+ORDER BY created_at DESC;
+
+-- Location records keep their primary flag only when the kept contact has no
+-- primary record of that kind.
+
+-- name: MoveMergedEmails :execrows
+UPDATE emails x
+SET contact_id = @main_id,
+    is_primary = x.is_primary AND NOT EXISTS (SELECT 1 FROM emails e WHERE e.contact_id = @main_id AND e.is_primary)
+WHERE x.contact_id = @other_id;
+
+-- name: MoveMergedPhones :execrows
+UPDATE phones x
+SET contact_id = @main_id,
+    is_primary = x.is_primary AND NOT EXISTS (SELECT 1 FROM phones p WHERE p.contact_id = @main_id AND p.is_primary)
+WHERE x.contact_id = @other_id;
+
+-- name: MoveMergedAddresses :execrows
+UPDATE addresses x
+SET contact_id = @main_id,
+    is_primary = x.is_primary AND NOT EXISTS (SELECT 1 FROM addresses a WHERE a.contact_id = @main_id AND a.is_primary)
+WHERE x.contact_id = @other_id;
+
+-- name: MoveMergedLocationBackup :execrows
+UPDATE contact_location_backup SET contact_id = @main_id::uuid WHERE contact_id = @other_id::uuid;
+
+-- name: MoveMergedWebsites :execrows
+UPDATE websites SET contact_id = @main_id WHERE contact_id = @other_id;
+
+-- name: MoveMergedIMs :execrows
+UPDATE ims SET contact_id = @main_id WHERE contact_id = @other_id;
+
+-- name: MoveMergedRelationshipsA :execrows
+UPDATE relationships SET contact_id_a = @main_id WHERE contact_id_a = @other_id;
+
+-- name: MoveMergedRelationshipsB :execrows
+UPDATE relationships SET contact_id_b = @main_id WHERE contact_id_b = @other_id;
+
+-- Relationships between the two contacts become relationships of the kept
+-- contact with itself after the moves above.
+-- name: DeleteSelfRelationships :execrows
+DELETE FROM relationships WHERE contact_id_a = @main_id AND contact_id_b = @main_id;
+
+-- name: MoveMergedGroupContacts :execrows
+UPDATE group_contacts gc SET contact_id = @main_id
+WHERE gc.contact_id = @other_id
+  AND NOT EXISTS (SELECT 1 FROM group_contacts m WHERE m.contact_id = @main_id AND m.group_id = gc.group_id);
+
+-- name: MoveMergedSubscriptionHistory :execrows
+-- All of the duplicate's history moves, including that of memberships left
+-- with it, so the kept contact's consent record is complete
+UPDATE subscription_history SET contact_id = @main_id WHERE contact_id = @other_id;
+
+-- name: MoveMergedActivityContacts :execrows
+UPDATE activity_contacts ac SET contact_id = @main_id
+WHERE ac.contact_id = @other_id
+  AND NOT EXISTS (
+      SELECT 1 FROM activity_contacts m
+      WHERE m.contact_id = @main_id AND m.activity_id = ac.activity_id AND m.role IS NOT DISTINCT FROM ac.role
+  );
+
+-- name: MoveMergedActivityAssignments :execrows
+UPDATE activity_assignments aa SET assignee_contact_id = @main_id
+WHERE aa.assignee_contact_id = @other_id
+  AND NOT EXISTS (SELECT 1 FROM activity_assignments m WHERE m.assignee_contact_id = @main_id AND m.activity_id = aa.activity_id);
+
+-- name: MoveMergedContributions :execrows
+UPDATE contributions SET contact_id = @main_id WHERE contact_id = @other_id;
+
+-- name: MoveMergedMemberships :execrows
+UPDATE memberships SET contact_id = @main_id WHERE contact_id = @other_id;
+
+-- name: MoveMergedParticipants :execrows
+UPDATE participants SET contact_id = @main_id WHERE contact_id = @other_id;
+
+-- name: MoveMergedParticipantRegistrations :execrows
+UPDATE participants SET registered_by_id = @main_id::uuid WHERE registered_by_id = @other_id::uuid;
+
+-- name: MoveMergedEventRegistrations :execrows
+UPDATE event_registrations SET contact_id = @main_id WHERE contact_id = @other_id;
+
+-- name: MoveMergedCaseContacts :execrows
+UPDATE case_contacts cc SET contact_id = @main_id
+WHERE cc.contact_id = @other_id
+  AND NOT EXISTS (
+      SELECT 1 FROM case_contacts m
+      WHERE m.contact_id = @main_id AND m.case_id = cc.case_id AND m.role IS NOT DISTINCT FROM cc.role
+  );
+
+-- name: MoveMergedPledges :execrows
+UPDATE pledges SET contact_id = @main_id WHERE contact_id = @other_id;
+
+-- name: MoveMergedPledgeGroupMembers :execrows
+UPDATE pledge_group_members pgm SET contact_id = @main_id
+WHERE pgm.contact_id = @other_id
+  AND NOT EXISTS (SELECT 1 FROM pledge_group_members m WHERE m.contact_id = @main_id AND m.pledge_group_id = pgm.pledge_group_id);
+
+-- name: MoveMergedEntityTags :execrows
+UPDATE entity_tags et SET entity_id = @main_id
+WHERE et.entity_table = 'contacts' AND et.entity_id = @other_id
+  AND NOT EXISTS (
+      SELECT 1 FROM entity_tags m
+      WHERE m.entity_table = 'contacts' AND m.entity_id = @main_id AND m.tag_id = et.tag_id
+  );
+
+-- name: MoveMergedCustomValues :execrows
+UPDATE custom_values cv SET entity_id = @main_id
+WHERE cv.entity_table = 'contacts' AND cv.entity_id = @other_id
+  AND NOT EXISTS (
+      SELECT 1 FROM custom_values m
+      WHERE m.entity_table = 'contacts' AND m.entity_id = @main_id AND m.custom_field_id = cv.custom_field_id
+  );
+
+-- name: MoveMergedMailingSubscriptions :execrows
+UPDATE mailing_list_subscriptions s SET contact_id = @main_id
+WHERE s.contact_id = @other_id
+  AND NOT EXISTS (SELECT 1 FROM mailing_list_subscriptions m WHERE m.contact_id = @main_id AND m.mailing_list_id = s.mailing_list_id);
+
+-- name: MoveMergedMailingRecipients :execrows
+UPDATE mailing_recipients r SET contact_id = @main_id
+WHERE r.contact_id = @other_id
+  AND NOT EXISTS (SELECT 1 FROM mailing_recipients m WHERE m.contact_id = @main_id AND m.mailing_id = r.mailing_id);
+
+-- name: MoveMergedMailingOpens :execrows
+UPDATE mailing_opens SET contact_id = @main_id WHERE contact_id = @other_id;
+
+-- name: MoveMergedMailingClicks :execrows
+UPDATE mailing_url_clicks SET contact_id = @main_id WHERE contact_id = @other_id;
+
+-- name: MoveMergedSMSMessages :execrows
+UPDATE sms_messages SET contact_id = @main_id WHERE contact_id = @other_id;
+
+-- An opt out on either contact is kept, so merging never re-subscribes
+-- anyone.
+-- name: MergeCommunicationPreferences :execrows
+UPDATE communication_preferences m
+SET email_opt_out = m.email_opt_out OR o.email_opt_out,
+    sms_opt_out = m.sms_opt_out OR o.sms_opt_out,
+    mail_opt_out = m.mail_opt_out OR o.mail_opt_out,
+    phone_opt_out = m.phone_opt_out OR o.phone_opt_out,
+    do_not_email = m.do_not_email OR o.do_not_email,
+    do_not_sms = m.do_not_sms OR o.do_not_sms,
+    do_not_mail = m.do_not_mail OR o.do_not_mail,
+    do_not_phone = m.do_not_phone OR o.do_not_phone,
+    do_not_trade = m.do_not_trade OR o.do_not_trade,
+    updated_at = NOW()
+FROM communication_preferences o
+WHERE m.contact_id = @main_id AND o.contact_id = @other_id;
+
+-- name: MoveMergedCommunicationPreferences :execrows
+UPDATE communication_preferences cp SET contact_id = @main_id
+WHERE cp.contact_id = @other_id
+  AND NOT EXISTS (SELECT 1 FROM communication_preferences m WHERE m.contact_id = @main_id);
+
+-- name: MoveMergedSurveyResponses :execrows
+UPDATE survey_responses SET contact_id = @main_id WHERE contact_id = @other_id;
+
+-- name: MoveMergedCampaignContacts :execrows
+UPDATE campaign_contacts cc SET contact_id = @main_id
+WHERE cc.contact_id = @other_id
+  AND NOT EXISTS (SELECT 1 FROM campaign_contacts m WHERE m.contact_id = @main_id AND m.campaign_id = cc.campaign_id);
+
+-- Cached permissions of the duplicate are recomputed for the kept contact
+-- on the next check.
+-- name: DeleteMergedACLCache :execrows
+DELETE FROM acl_contact_cache WHERE contact_id = @other_id OR user_id = @other_id;
@@ -13,6 +13,33 @@ AND aer.is_active = true
 ORDER BY a.priority ASC
 LIMIT 1;
 
+-- name: CanUserEditContact :one
+-- A user may edit a contact through an ACL role that is not denied, or a
+-- relationship that grants the user's contact Edit on it
+SELECT (
+    EXISTS (
+        SELECT 1 FROM acls a
+        JOIN acl_entity_roles aer ON aer.acl_role_id = a.entity_id
+        WHERE aer.entity_table = 'users' AND aer.entity_id = @user_id::uuid
+          AND aer.is_active = true
+          AND a.entity_table = 'acl_roles' AND a.operation = 'Edit'
+          AND a.object_table = 'contacts' AND (a.object_id = @contact_id::uuid OR a.object_id IS NULL)
+          AND a.is_active = true AND a.deny = false
+    ) OR EXISTS (
+        SELECT 1 FROM uf_match m
+        JOIN acl_contact_cache acc ON acc.user_id = m.contact_id
+        WHERE m.uf_id = @user_id::uuid AND acc.contact_id = @contact_id::uuid AND acc.operation = 'Edit'
+    )
+) AND NOT EXISTS (
+    SELECT 1 FROM acls a
+    JOIN acl_entity_roles aer ON aer.acl_role_id = a.entity_id
+    WHERE aer.entity_table = 'users' AND aer.entity_id = @user_id::uuid
+      AND aer.is_active = true
+      AND a.entity_table = 'acl_roles' AND a.operation = 'Edit'
+      AND a.object_table = 'contacts' AND (a.object_id = @contact_id::uuid OR a.object_id IS NULL)
+      AND a.is_active = true AND a.deny = true
+) AS allowed;
+
 -- name: CheckGroupPermission :one
 SELECT COUNT(*) > 0 as has_permission FROM acls a
 INNER JOIN acl_entity_roles aer ON a.entity_id = aer.acl_role_id
@@ -249,11 +276,11 @@ SELECT DISTINCT c.* FROM contacts c
 INNER JOIN acl_contact_cache acc ON c.id = acc.contact_id
 WHERE acc.user_id = $1 
 AND acc.operation = $2
-AND c.is_active = true
+AND c.is_deleted = false
 AND (
     c.first_name ILIKE '%' || $3 || '%' OR
     c.last_name ILIKE '%' || $3 || '%' OR
-    c.email ILIKE '%' || $3 || '%'
+    EXISTS (SELECT 1 FROM emails e WHERE e.contact_id = c.id AND e.email ILIKE '%' || $3 || '%')
 )
 ORDER BY c.last_name, c.first_name
 LIMIT $4 OFFSET $5;
@@ -0,0 +1,283 @@
+-- Personal data queries. Each Export query returns one section of a
+-- contact's data as a JSON array of rows; each Anonymize or Delete query
+-- scrubs one kind of record and returns the rows changed. Financial
+-- records keep their amounts. Both take the contact and every duplicate
+-- merged into it as @contact_ids, since a merge leaves the rows the kept
+-- contact already had, such as a group membership, with the duplicate.
+
+-- name: ListMergedContactIDs :many
+-- The contact and every contact merged into it, directly or through
+-- earlier merges
+WITH RECURSIVE merged AS (
+    SELECT c.id FROM contacts c WHERE c.id = @contact_id::uuid
+    UNION
+    SELECT c.id FROM contacts c JOIN merged m ON c.merged_to_id = m.id
+)
+SELECT id FROM merged;
+
+-- name: ExportContact :one
+-- The kept contact first, then its merged duplicates
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.merged_to_id IS NOT NULL, x.created_at, x.id), '[]')::jsonb AS records
+FROM contacts x WHERE x.id = ANY(@contact_ids::uuid[]);
+
+-- name: ExportEmails :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM emails x WHERE x.contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: ExportPhones :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM phones x WHERE x.contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: ExportAddresses :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM addresses x WHERE x.contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: ExportWebsites :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM websites x WHERE x.contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: ExportIMs :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM ims x WHERE x.contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: ExportRelationships :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) || jsonb_build_object('name_a_b', t.name_a_b, 'name_b_a', t.name_b_a) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM relationships x
+JOIN relationship_types t ON t.id = x.relationship_type_id
+WHERE x.contact_id_a = ANY(@contact_ids::uuid[]) OR x.contact_id_b = ANY(@contact_ids::uuid[]);
+
+-- name: ExportGroupContacts :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) || jsonb_build_object('group_title', g.title) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM group_contacts x
+JOIN groups g ON g.id = x.group_id
+WHERE x.contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: ExportSubscriptionHistory :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.date, x.id), '[]')::jsonb AS records
+FROM subscription_history x WHERE x.contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: ExportActivities :one
+-- Activities the contact takes part in or is assigned, with their roles
+SELECT COALESCE(jsonb_agg(to_jsonb(x) || jsonb_build_object('roles', r.roles) ORDER BY x.activity_date_time, x.id), '[]')::jsonb AS records
+FROM activities x
+JOIN (
+    SELECT p.activity_id, jsonb_agg(DISTINCT p.role) AS roles
+    FROM (
+        SELECT ac.activity_id, COALESCE(ac.role, 'Contact') AS role FROM activity_contacts ac WHERE ac.contact_id = ANY(@contact_ids::uuid[])
+        UNION ALL
+        SELECT aa.activity_id, 'Assignee' FROM activity_assignments aa WHERE aa.assignee_contact_id = ANY(@contact_ids::uuid[])
+    ) p
+    GROUP BY p.activity_id
+) r ON r.activity_id = x.id;
+
+-- name: ExportContributions :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM contributions x WHERE x.contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: ExportMemberships :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) || jsonb_build_object('logs', COALESCE(
+           (SELECT jsonb_agg(to_jsonb(l) ORDER BY l.created_at, l.id) FROM membership_logs l WHERE l.membership_id = x.id), '[]'::jsonb))
+       ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM memberships x WHERE x.contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: ExportParticipants :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM participants x WHERE x.contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: ExportEventRegistrations :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM event_registrations x WHERE x.contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: ExportPledges :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) || jsonb_build_object('payments', COALESCE(
+           (SELECT jsonb_agg(to_jsonb(p) ORDER BY p.created_at, p.id) FROM pledge_payments p WHERE p.pledge_id = x.id), '[]'::jsonb))
+       ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM pledges x WHERE x.contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: ExportCaseContacts :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM case_contacts x WHERE x.contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: ExportCampaignContacts :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM campaign_contacts x WHERE x.contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: ExportEntityTags :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) || jsonb_build_object('tag_name', t.name) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM entity_tags x
+JOIN tags t ON t.id = x.tag_id
+WHERE x.entity_table = 'contacts' AND x.entity_id = ANY(@contact_ids::uuid[]);
+
+-- name: ExportCustomValues :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) || jsonb_build_object('field_label', f.label) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM custom_values x
+JOIN custom_fields f ON f.id = x.custom_field_id
+WHERE x.entity_table = 'contacts' AND x.entity_id = ANY(@contact_ids::uuid[]);
+
+-- name: ExportMailingSubscriptions :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM mailing_list_subscriptions x WHERE x.contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: ExportMailingRecipients :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM mailing_recipients x WHERE x.contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: ExportMailingOpens :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.opened_at, x.id), '[]')::jsonb AS records
+FROM mailing_opens x WHERE x.contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: ExportMailingClicks :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) || jsonb_build_object('url', u.url) ORDER BY x.clicked_at, x.id), '[]')::jsonb AS records
+FROM mailing_url_clicks x
+JOIN mailing_trackable_urls u ON u.id = x.trackable_url_id
+WHERE x.contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: ExportSMSMessages :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM sms_messages x WHERE x.contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: ExportCommunicationPreferences :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM communication_preferences x WHERE x.contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: ExportSurveyResponses :one
+-- Survey responses with their answers and the questions answered
+SELECT COALESCE(jsonb_agg(to_jsonb(x) || jsonb_build_object('answers', COALESCE(
+           (SELECT jsonb_agg(to_jsonb(a) || jsonb_build_object('question', q.question_text) ORDER BY a.created_at, a.id)
+            FROM survey_response_answers a
+            JOIN survey_questions q ON q.id = a.survey_question_id
+            WHERE a.survey_response_id = x.id), '[]'::jsonb))
+       ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM survey_responses x WHERE x.contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: ExportContactMerges :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM contact_merges x
+WHERE x.main_contact_id = ANY(@contact_ids::uuid[]) OR x.other_contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: ExportUserAccounts :one
+-- The logins linked to the contact, without their password hashes
+SELECT COALESCE(jsonb_agg(jsonb_build_object(
+           'username', u.username, 'email', u.email, 'is_active', u.is_active,
+           'last_login', u.last_login, 'created_at', u.created_at, 'language', m.language)
+       ORDER BY u.created_at, u.id), '[]')::jsonb AS records
+FROM uf_match m
+JOIN users u ON u.id = m.uf_id
+WHERE m.contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: ExportContactLocationBackup :one
+-- Location values as they were before migrating to location records
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.contact_id), '[]')::jsonb AS records
+FROM contact_location_backup x WHERE x.contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: ExportPersonalDataRequests :one
+SELECT COALESCE(jsonb_agg(to_jsonb(x) ORDER BY x.created_at, x.id), '[]')::jsonb AS records
+FROM personal_data_requests x WHERE x.contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: AnonymizeContact :execrows
+UPDATE contacts
+SET prefix = NULL, first_name = NULL, last_name = NULL, suffix = NULL,
+    nick_name = NULL, organization_name = NULL, household_name = NULL,
+    display_name = 'Anonymous', sort_name = 'Anonymous', employer_id = NULL,
+    anonymized_at = NOW(), updated_at = NOW()
+WHERE id = ANY(@contact_ids::uuid[]);
+
+-- name: AnonymizeContactMerges :execrows
+UPDATE contact_merges SET other_snapshot = '{}'
+WHERE main_contact_id = ANY(@contact_ids::uuid[]) OR other_contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: DeleteContactEmails :execrows
+DELETE FROM emails WHERE contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: DeleteContactPhones :execrows
+DELETE FROM phones WHERE contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: DeleteContactAddresses :execrows
+-- Addresses shared from these become independent of them
+DELETE FROM addresses WHERE contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: DeleteContactWebsites :execrows
+DELETE FROM websites WHERE contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: DeleteContactIMs :execrows
+DELETE FROM ims WHERE contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: DeleteContactLocationBackup :execrows
+DELETE FROM contact_location_backup WHERE contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: DeleteContactRelationships :execrows
+DELETE FROM relationships WHERE contact_id_a = ANY(@contact_ids::uuid[]) OR contact_id_b = ANY(@contact_ids::uuid[]);
+
+-- name: DeleteContactEntityTags :execrows
+DELETE FROM entity_tags WHERE entity_table = 'contacts' AND entity_id = ANY(@contact_ids::uuid[]);
+
+-- name: DeleteContactCustomValues :execrows
+DELETE FROM custom_values WHERE entity_table = 'contacts' AND entity_id = ANY(@contact_ids::uuid[]);
+
+-- name: DeleteContactUserAccounts :execrows
+-- Unlinks logins; the user accounts themselves are left to the auth system
+DELETE FROM uf_match WHERE contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: AnonymizeActivities :execrows
+-- Clears the text of activities the contact takes part in or is assigned
+UPDATE activities
+SET subject = NULL, details = NULL, location = NULL, phone_id = NULL, phone_number = NULL, result = NULL,
+    updated_at = NOW()
+WHERE id IN (
+    SELECT activity_id FROM activity_contacts WHERE contact_id = ANY(@contact_ids::uuid[])
+    UNION
+    SELECT activity_id FROM activity_assignments WHERE assignee_contact_id = ANY(@contact_ids::uuid[])
+);
+
+-- name: AnonymizeMemberships :execrows
+UPDATE memberships SET source = NULL, updated_at = NOW()
+WHERE contact_id = ANY(@contact_ids::uuid[]) AND source IS NOT NULL;
+
+-- name: AnonymizeParticipants :execrows
+UPDATE participants SET source = NULL, updated_at = NOW()
+WHERE contact_id = ANY(@contact_ids::uuid[]) AND source IS NOT NULL;
+
+-- name: AnonymizeMailingSubscriptions :execrows
+UPDATE mailing_list_subscriptions SET source = NULL, updated_at = NOW()
+WHERE contact_id = ANY(@contact_ids::uuid[]) AND source IS NOT NULL;
+
+-- name: AnonymizeMailingRecipients :execrows
+UPDATE mailing_recipients SET email = '', bounce_reason = NULL, updated_at = NOW()
+WHERE contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: AnonymizeMailingOpens :execrows
+UPDATE mailing_opens SET ip_address = NULL, user_agent = NULL
+WHERE contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: AnonymizeMailingClicks :execrows
+UPDATE mailing_url_clicks SET ip_address = NULL, user_agent = NULL
+WHERE contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: AnonymizeSMSMessages :execrows
+UPDATE sms_messages SET phone_number = '', message = '', updated_at = NOW()
+WHERE contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: AnonymizeSurveyResponses :execrows
+UPDATE survey_responses SET ip_address = NULL, user_agent = NULL, updated_at = NOW()
+WHERE contact_id = ANY(@contact_ids::uuid[]);
+
+-- name: AnonymizeSurveyAnswers :execrows
+-- Free text answers are cleared; numeric, date and yes/no answers stay for
+-- the survey's totals
+UPDATE survey_response_answers SET answer_text = NULL, updated_at = NOW()
+WHERE survey_response_id IN (SELECT id FROM survey_responses WHERE contact_id = ANY(@contact_ids::uuid[]))
+  AND answer_text IS NOT NULL;
+
+-- name: CreatePersonalDataRequest :one
+INSERT INTO personal_data_requests (
+    domain_id, contact_id, request_type, format, records, reason, actor_id
+) VALUES (
+    @domain_id, @contact_id, @request_type, @format, @records, @reason, @actor_id
+) RETURNING *;
+
+-- name: ListPersonalDataRequests :many
+-- Requests of a contact, or of a domain, newest first
+SELECT * FROM personal_data_requests
+WHERE (sqlc.narg(contact_id)::uuid IS NULL OR contact_id = sqlc.narg(contact_id)::uuid)
+  AND (sqlc.narg(domain_id)::uuid IS NULL OR domain_id = sqlc.narg(domain_id)::uuid)
+ORDER BY created_at DESC, id DESC;
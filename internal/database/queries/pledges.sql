@@ -57,23 +57,26 @@ WHERE contact_id = $1
 ORDER BY created_at DESC;
 
 -- name: ListPledgesByCampaign :many
-SELECT p.*, c.first_name, c.last_name, c.email
+SELECT p.*, c.first_name, c.last_name, pe.email
 FROM pledges p
 INNER JOIN contacts c ON p.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE p.campaign_id = $1 AND p.status = $2
 ORDER BY p.created_at DESC;
 
 -- name: ListPledgesByBlock :many
-SELECT p.*, c.first_name, c.last_name, c.email
+SELECT p.*, c.first_name, c.last_name, pe.email
 FROM pledges p
 INNER JOIN contacts c ON p.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE p.pledge_block_id = $1 AND p.status = $2
 ORDER BY p.created_at DESC;
 
 -- name: ListPledgesByStatus :many
-SELECT p.*, c.first_name, c.last_name, c.email
+SELECT p.*, c.first_name, c.last_name, pe.email
 FROM pledges p
 INNER JOIN contacts c ON p.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE p.status = $1
 ORDER BY p.next_payment_date ASC;
 
@@ -119,10 +122,11 @@ WHERE pledge_id = $1
 ORDER BY scheduled_date;
 
 -- name: ListPledgePaymentsByStatus :many
-SELECT pp.*, p.contact_id, c.first_name, c.last_name, c.email
+SELECT pp.*, p.contact_id, c.first_name, c.last_name, pe.email
 FROM pledge_payments pp
 INNER JOIN pledges p ON pp.pledge_id = p.id
 INNER JOIN contacts c ON p.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE pp.status = $1
 ORDER BY pp.scheduled_date ASC;
 
@@ -182,10 +186,11 @@ WHERE pledge_id = $1 AND status = $2
 ORDER BY scheduled_date;
 
 -- name: ListScheduledReminders :many
-SELECT pr.*, p.contact_id, c.first_name, c.last_name, c.email
+SELECT pr.*, p.contact_id, c.first_name, c.last_name, pe.email
 FROM pledge_reminders pr
 INNER JOIN pledges p ON pr.pledge_id = p.id
 INNER JOIN contacts c ON p.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE pr.status = 'Scheduled' AND pr.scheduled_date <= $1
 ORDER BY pr.scheduled_date;
 
@@ -241,9 +246,10 @@ INSERT INTO pledge_group_members (
 SELECT * FROM pledge_group_members WHERE id = $1;
 
 -- name: ListPledgeGroupMembers :many
-SELECT pgm.*, c.first_name, c.last_name, c.email
+SELECT pgm.*, c.first_name, c.last_name, pe.email
 FROM pledge_group_members pgm
 INNER JOIN contacts c ON pgm.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE pgm.pledge_group_id = $1 AND pgm.is_active = $2
 ORDER BY pgm.role, c.last_name, c.first_name;
 
@@ -278,10 +284,11 @@ SELECT
     p.contact_id,
     c.first_name,
     c.last_name,
-    c.email
+    pe.email
 FROM pledge_payments pp
 INNER JOIN pledges p ON pp.pledge_id = p.id
 INNER JOIN contacts c ON p.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE pp.pledge_id = $1
 ORDER BY pp.scheduled_date;
 
@@ -290,11 +297,12 @@ SELECT
     p.*,
     c.first_name,
     c.last_name,
-    c.email,
+    pe.email,
     pp.scheduled_date as next_payment_date,
     pp.scheduled_amount as next_payment_amount
 FROM pledges p
 INNER JOIN contacts c ON p.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 INNER JOIN pledge_payments pp ON p.id = pp.pledge_id
 WHERE p.status = 'In Progress' 
   AND pp.status = 'Scheduled' 
@@ -332,12 +340,13 @@ SELECT
     p.contact_id,
     c.first_name,
     c.last_name,
-    c.email,
+    pe.email,
     p.amount as pledge_amount,
     p.frequency
 FROM pledge_reminders pr
 INNER JOIN pledges p ON pr.pledge_id = p.id
 INNER JOIN contacts c ON p.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE pr.status = 'Scheduled' 
   AND pr.scheduled_date <= $1
   AND p.status IN ('In Progress', 'Overdue')
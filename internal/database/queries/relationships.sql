@@ -0,0 +1,106 @@
+-- Relationship queries. Triggers from migration 042 keep the ACL cache and
+-- current employers in step with these writes.
+
+-- name: CreateRelationshipType :one
+INSERT INTO relationship_types (
+    name_a_b, name_b_a, description, contact_type_a, contact_type_b
+) VALUES (
+    $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: GetRelationshipType :one
+SELECT * FROM relationship_types WHERE id = $1;
+
+-- name: GetRelationshipTypeByName :one
+SELECT * FROM relationship_types
+WHERE name_a_b = $1
+ORDER BY created_at
+LIMIT 1;
+
+-- name: HasActiveRelationship :one
+SELECT EXISTS (
+    SELECT 1 FROM relationships
+    WHERE contact_id_a = @contact_id_a AND contact_id_b = @contact_id_b
+      AND relationship_type_id = @relationship_type_id AND is_active
+);
+
+-- name: ListRelationshipTypes :many
+SELECT * FROM relationship_types
+WHERE is_active
+ORDER BY name_a_b;
+
+-- name: CreateRelationship :one
+INSERT INTO relationships (
+    contact_id_a, contact_id_b, relationship_type_id, start_date, end_date,
+    is_active, is_permission_a_b, is_permission_b_a
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+) RETURNING *;
+
+-- name: GetRelationship :one
+SELECT * FROM relationships WHERE id = $1;
+
+-- name: UpdateRelationship :one
+UPDATE relationships
+SET
+    relationship_type_id = $2,
+    start_date = $3,
+    end_date = $4,
+    is_active = $5,
+    is_permission_a_b = $6,
+    is_permission_b_a = $7,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteRelationship :execrows
+DELETE FROM relationships WHERE id = $1;
+
+-- name: ListContactRelationships :many
+-- A contact's relationships from its side: relation is name_a_b when the
+-- contact is contact A and name_b_a when it is contact B
+SELECT r.id, r.relationship_type_id, r.start_date, r.end_date, r.is_active,
+       'a_b'::text AS direction, t.name_a_b AS relation,
+       r.contact_id_b AS other_contact_id, c.display_name AS other_display_name,
+       COALESCE(r.is_permission_a_b, FALSE)::boolean AS can_access_other,
+       COALESCE(r.is_permission_b_a, FALSE)::boolean AS other_can_access
+FROM relationships r
+JOIN relationship_types t ON t.id = r.relationship_type_id
+JOIN contacts c ON c.id = r.contact_id_b
+WHERE r.contact_id_a = @contact_id AND (r.is_active OR @include_inactive::boolean)
+UNION ALL
+SELECT r.id, r.relationship_type_id, r.start_date, r.end_date, r.is_active,
+       'b_a'::text AS direction, t.name_b_a AS relation,
+       r.contact_id_a AS other_contact_id, c.display_name AS other_display_name,
+       COALESCE(r.is_permission_b_a, FALSE)::boolean AS can_access_other,
+       COALESCE(r.is_permission_a_b, FALSE)::boolean AS other_can_access
+FROM relationships r
+JOIN relationship_types t ON t.id = r.relationship_type_id
+JOIN contacts c ON c.id = r.contact_id_a
+WHERE r.contact_id_b = @contact_id AND (r.is_active OR @include_inactive::boolean)
+ORDER BY relation, other_display_name;
+
+-- name: ActivateStartedRelationships :execrows
+-- Relationships last changed before their start date were inactive only
+-- because they had not started; ones deactivated by hand since are left alone
+UPDATE relationships r
+SET is_active = TRUE, updated_at = NOW()
+FROM contacts c
+WHERE c.id = r.contact_id_a AND c.domain_id = @domain_id
+  AND NOT COALESCE(r.is_active, FALSE)
+  AND r.start_date <= CURRENT_DATE
+  AND r.start_date > r.updated_at::date
+  AND (r.end_date IS NULL OR r.end_date >= CURRENT_DATE);
+
+-- name: DeactivateEndedRelationships :execrows
+UPDATE relationships r
+SET is_active = FALSE, updated_at = NOW()
+FROM contacts c
+WHERE c.id = r.contact_id_a AND c.domain_id = @domain_id
+  AND r.is_active
+  AND r.end_date < CURRENT_DATE;
+
+-- name: ListRelationshipPermittedContacts :many
+-- Contacts the contact may access through its relationships
+SELECT DISTINCT contact_id FROM acl_contact_cache
+WHERE user_id = @contact_id AND operation = @operation AND relationship_id IS NOT NULL;
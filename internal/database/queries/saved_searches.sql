@@ -0,0 +1,26 @@
+-- name: CreateSavedSearch :one
+INSERT INTO saved_searches (
+    name, label, description, api_entity, api_params, created_by
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: GetSavedSearch :one
+SELECT * FROM saved_searches WHERE id = $1;
+
+-- name: ListSavedSearches :many
+SELECT * FROM saved_searches ORDER BY name;
+
+-- name: UpdateSavedSearch :one
+UPDATE saved_searches
+SET
+    label = $2,
+    description = $3,
+    api_entity = $4,
+    api_params = $5,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteSavedSearch :execrows
+DELETE FROM saved_searches WHERE id = $1;
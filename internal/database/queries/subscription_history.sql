@@ -0,0 +1,35 @@
+-- Group contact and subscription history queries. Every change to a
+-- group_contacts status is recorded in subscription_history in the same
+-- transaction.
+
+-- name: GetGroupContactForUpdate :one
+SELECT * FROM group_contacts WHERE group_id = $1 AND contact_id = $2 FOR UPDATE;
+
+-- name: UpsertGroupContact :one
+INSERT INTO group_contacts (group_id, contact_id, status)
+VALUES ($1, $2, $3)
+ON CONFLICT (group_id, contact_id) DO UPDATE SET status = EXCLUDED.status
+RETURNING *;
+
+-- name: CreateSubscriptionHistory :one
+INSERT INTO subscription_history (
+    group_id, contact_id, status, method, date, actor_id, tracking
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: GetSubscriptionHistory :one
+SELECT * FROM subscription_history WHERE id = $1;
+
+-- name: GetLatestSubscriptionHistory :one
+SELECT * FROM subscription_history
+WHERE group_id = $1 AND contact_id = $2
+ORDER BY date DESC, created_at DESC
+LIMIT 1;
+
+-- name: ListSubscriptionHistory :many
+-- History of a contact, a group or both, oldest first
+SELECT * FROM subscription_history
+WHERE (sqlc.narg('contact_id')::uuid IS NULL OR contact_id = sqlc.narg('contact_id'))
+  AND (sqlc.narg('group_id')::uuid IS NULL OR group_id = sqlc.narg('group_id'))
+ORDER BY date, created_at;
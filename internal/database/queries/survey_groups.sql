@@ -79,3 +79,12 @@ LEFT JOIN groups g ON sg.group_id = g.id
 WHERE s.is_active = $1
 GROUP BY s.id, s.title
 ORDER BY s.created_date DESC;
+
+-- name: ListSurveyTargetContacts :many
+-- Members of the survey's active groups, including smart and nested groups
+SELECT DISTINCT c.id, c.contact_type, c.display_name, c.sort_name
+FROM survey_groups sg
+JOIN group_members gm ON gm.group_id = sg.group_id
+JOIN contacts c ON c.id = gm.contact_id
+WHERE sg.survey_id = $1 AND sg.is_active AND NOT c.is_deleted
+ORDER BY c.sort_name, c.id;
@@ -116,11 +116,12 @@ SELECT
     c.id as contact_id,
     c.first_name,
     c.last_name,
-    c.email as contact_email,
+    pe.email as contact_email,
     c.contact_type
 FROM users u
 LEFT JOIN uf_match ufm ON u.id = ufm.uf_id
 LEFT JOIN contacts c ON ufm.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE u.id = $1 AND u.is_active = true;
 
 -- name: GetUserByUsernameWithContact :one
@@ -129,11 +130,12 @@ SELECT
     c.id as contact_id,
     c.first_name,
     c.last_name,
-    c.email as contact_email,
+    pe.email as contact_email,
     c.contact_type
 FROM users u
 LEFT JOIN uf_match ufm ON u.id = ufm.uf_id
 LEFT JOIN contacts c ON ufm.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE u.username = $1 AND u.is_active = true;
 
 -- name: GetUserByEmailWithContact :one
@@ -142,11 +144,12 @@ SELECT
     c.id as contact_id,
     c.first_name,
     c.last_name,
-    c.email as contact_email,
+    pe.email as contact_email,
     c.contact_type
 FROM users u
 LEFT JOIN uf_match ufm ON u.id = ufm.uf_id
 LEFT JOIN contacts c ON ufm.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE u.email = $1 AND u.is_active = true;
 
 -- name: GetUsersWithContacts :many
@@ -155,11 +158,12 @@ SELECT
     c.id as contact_id,
     c.first_name,
     c.last_name,
-    c.email as contact_email,
+    pe.email as contact_email,
     c.contact_type
 FROM users u
 LEFT JOIN uf_match ufm ON u.id = ufm.uf_id
 LEFT JOIN contacts c ON ufm.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE u.is_active = true
 ORDER BY u.username;
 
@@ -169,13 +173,14 @@ SELECT
     c.id as contact_id,
     c.first_name,
     c.last_name,
-    c.email as contact_email,
+    pe.email as contact_email,
     c.contact_type
 FROM users u
 INNER JOIN acl_entity_roles aer ON u.id = aer.entity_id
 INNER JOIN acl_roles ar ON aer.acl_role_id = ar.id
 LEFT JOIN uf_match ufm ON u.id = ufm.uf_id
 LEFT JOIN contacts c ON ufm.contact_id = c.id
+LEFT JOIN emails pe ON pe.contact_id = c.id AND pe.is_primary
 WHERE aer.entity_table = 'users' 
 AND ar.name = $1 
 AND u.is_active = true
@@ -0,0 +1,271 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/jxlxx/civicrm/internal/config"
+	generated "github.com/jxlxx/civicrm/internal/database/generated"
+)
+
+// replica is a connection pool for a read replica
+type replica struct {
+	addr    string
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// openReplicas opens a pool for each configured replica. Replicas that cannot
+// be reached yet are kept and marked unhealthy until a health check passes.
+func openReplicas(primary *config.DatabaseConfig) ([]*replica, error) {
+	replicas := make([]*replica, 0, len(primary.Replicas))
+	for _, rc := range primary.Replicas {
+		cfg := replicaConfig(primary, rc)
+
+		dsn, err := buildDSN(cfg)
+		if err != nil {
+			closeReplicas(replicas)
+			return nil, fmt.Errorf("failed to build replica DSN: %w", err)
+		}
+
+		sqlDB, err := open(cfg.Driver, dsn)
+		if err != nil {
+			closeReplicas(replicas)
+			return nil, fmt.Errorf("failed to open replica %s:%d: %w", cfg.Host, cfg.Port, err)
+		}
+		sqlDB.SetMaxOpenConns(cfg.MaxConns)
+		sqlDB.SetMaxIdleConns(cfg.MaxConns / 2)
+		sqlDB.SetConnMaxLifetime(time.Hour)
+
+		r := &replica{addr: fmt.Sprintf("%s:%d", cfg.Host, cfg.Port), db: sqlDB}
+
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+		r.healthy.Store(sqlDB.PingContext(ctx) == nil)
+		cancel()
+
+		replicas = append(replicas, r)
+	}
+	return replicas, nil
+}
+
+// replicaConfig returns the primary's settings overridden by a replica's
+func replicaConfig(primary *config.DatabaseConfig, rc config.ReplicaConfig) *config.DatabaseConfig {
+	cfg := *primary
+	cfg.Replicas = nil
+	if rc.Host != "" {
+		cfg.Host = rc.Host
+	}
+	if rc.Port != 0 {
+		cfg.Port = rc.Port
+	}
+	if rc.Username != "" {
+		cfg.Username = rc.Username
+	}
+	if rc.Password != "" {
+		cfg.Password = rc.Password
+	}
+	if rc.Database != "" {
+		cfg.Database = rc.Database
+	}
+	if rc.SSLMode != "" {
+		cfg.SSLMode = rc.SSLMode
+	}
+	return &cfg
+}
+
+func closeReplicas(replicas []*replica) {
+	for _, r := range replicas {
+		r.db.Close()
+	}
+}
+
+// checkReplicas pings every replica on each tick until stop is closed
+func (db *Database) checkReplicas(interval time.Duration) {
+	defer close(db.checked)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-db.stop:
+			return
+		case <-ticker.C:
+			db.pingReplicas()
+		}
+	}
+}
+
+// pingReplicas updates the health of every replica, logging changes
+func (db *Database) pingReplicas() {
+	for _, r := range db.replicas {
+		ctx, cancel := context.WithTimeout(context.Background(), db.config.Timeout)
+		err := r.db.PingContext(ctx)
+		cancel()
+
+		healthy := err == nil
+		if r.healthy.Swap(healthy) == healthy || db.logger == nil {
+			continue
+		}
+		if healthy {
+			db.logger.Info("Database replica recovered", "replica", r.addr)
+		} else {
+			db.logger.Warn("Database replica unhealthy", "replica", r.addr, "error", err)
+		}
+	}
+}
+
+// readDB picks the pool for a read: the next healthy replica in round-robin
+// order, or the primary when none is healthy or the request has written and
+// read-your-writes is enabled
+func (db *Database) readDB(ctx context.Context) *sql.DB {
+	if db.config.ReadYourWrites && hasWritten(ctx) {
+		return db.db
+	}
+
+	n := uint64(len(db.replicas))
+	if n == 0 {
+		return db.db
+	}
+
+	start := db.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		if r := db.replicas[(start+i)%n]; r.healthy.Load() {
+			return r.db
+		}
+	}
+	return db.db
+}
+
+// ReadQuery executes a read-only query on a replica
+func (db *Database) ReadQuery(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return db.reader().QueryContext(ctx, query, args...)
+}
+
+// ReadQueryRow executes a read-only query that returns a single row on a replica
+func (db *Database) ReadQueryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return db.reader().QueryRowContext(ctx, query, args...)
+}
+
+// ReadQuerier returns the generated query interface routed to replicas. Use
+// it only for queries that do not write, such as reports; replicas may lag
+// behind the primary.
+func (db *Database) ReadQuerier() generated.Querier {
+	return generated.New(db.reader())
+}
+
+// reader returns the instrumented read routing layer
+func (db *Database) reader() generated.DBTX {
+	return db.instrument(readDBTX{db: db}, poolRead)
+}
+
+// readDBTX routes each generated query to a pool chosen per call
+type readDBTX struct {
+	db *Database
+}
+
+func (r readDBTX) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return r.db.readDB(ctx).ExecContext(ctx, query, args...)
+}
+
+func (r readDBTX) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return r.db.readDB(ctx).PrepareContext(ctx, query)
+}
+
+func (r readDBTX) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return r.db.readDB(ctx).QueryContext(ctx, query, args...)
+}
+
+func (r readDBTX) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return r.db.readDB(ctx).QueryRowContext(ctx, query, args...)
+}
+
+// primaryDBTX runs generated queries on the primary and records writes for
+// read-your-writes
+type primaryDBTX struct {
+	db *sql.DB
+}
+
+func (p primaryDBTX) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	markWrite(ctx)
+	return p.db.ExecContext(ctx, query, args...)
+}
+
+func (p primaryDBTX) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	if isWrite(query) {
+		markWrite(ctx)
+	}
+	return p.db.PrepareContext(ctx, query)
+}
+
+func (p primaryDBTX) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if isWrite(query) {
+		markWrite(ctx)
+	}
+	return p.db.QueryContext(ctx, query, args...)
+}
+
+func (p primaryDBTX) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if isWrite(query) {
+		markWrite(ctx)
+	}
+	return p.db.QueryRowContext(ctx, query, args...)
+}
+
+// writeTracker records whether a request has written to the primary
+type writeTracker struct {
+	written atomic.Bool
+}
+
+type writeTrackerKey struct{}
+
+// TrackWrites returns a context that remembers writes made with it, so that
+// later reads in the same request go to the primary when read-your-writes is
+// enabled. The API server calls it once per request.
+func TrackWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, writeTrackerKey{}, &writeTracker{})
+}
+
+func markWrite(ctx context.Context) {
+	if tracker, ok := ctx.Value(writeTrackerKey{}).(*writeTracker); ok {
+		tracker.written.Store(true)
+	}
+}
+
+func hasWritten(ctx context.Context) bool {
+	tracker, ok := ctx.Value(writeTrackerKey{}).(*writeTracker)
+	return ok && tracker.written.Load()
+}
+
+var modifyingKeyword = regexp.MustCompile(`(?i)\b(insert|update|delete|merge)\b`)
+
+// isWrite reports whether a statement may modify data. Statements are
+// classified by their first keyword after any leading comments, such as the
+// "-- name:" line sqlc adds.
+func isWrite(query string) bool {
+	query = strings.TrimSpace(query)
+	for strings.HasPrefix(query, "--") || strings.HasPrefix(query, "/*") {
+		if strings.HasPrefix(query, "--") {
+			_, query, _ = strings.Cut(query, "\n")
+		} else {
+			_, query, _ = strings.Cut(query, "*/")
+		}
+		query = strings.TrimSpace(query)
+	}
+
+	keyword, _, _ := strings.Cut(query, " ")
+	keyword, _, _ = strings.Cut(keyword, "\n")
+	switch strings.ToLower(keyword) {
+	case "select", "show", "values", "table", "explain":
+		return false
+	case "with":
+		return modifyingKeyword.MatchString(query)
+	default:
+		return true
+	}
+}
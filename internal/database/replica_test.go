@@ -0,0 +1,121 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/jxlxx/civicrm/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestPool returns a pool that is never connected to
+func newTestPool(t *testing.T) *sql.DB {
+	t.Helper()
+	pool, err := sql.Open("postgres", "host=invalid")
+	require.NoError(t, err)
+	t.Cleanup(func() { pool.Close() })
+	return pool
+}
+
+func newTestReplica(t *testing.T, healthy bool) *replica {
+	r := &replica{addr: "replica", db: newTestPool(t)}
+	r.healthy.Store(healthy)
+	return r
+}
+
+// TestReadDBRoundRobin tests that reads rotate over healthy replicas only
+func TestReadDBRoundRobin(t *testing.T) {
+	a, b, down := newTestReplica(t, true), newTestReplica(t, true), newTestReplica(t, false)
+	db := &Database{
+		config:   &config.DatabaseConfig{},
+		db:       newTestPool(t),
+		replicas: []*replica{a, down, b},
+	}
+
+	ctx := context.Background()
+	seen := map[*sql.DB]int{}
+	for i := 0; i < 10; i++ {
+		seen[db.readDB(ctx)]++
+	}
+
+	assert.Len(t, seen, 2)
+	assert.Positive(t, seen[a.db])
+	assert.Positive(t, seen[b.db])
+	assert.Zero(t, seen[down.db])
+}
+
+// TestReadDBFallsBackToPrimary tests that reads use the primary when no
+// replica is healthy or none are configured
+func TestReadDBFallsBackToPrimary(t *testing.T) {
+	ctx := context.Background()
+	primary := newTestPool(t)
+
+	db := &Database{config: &config.DatabaseConfig{}, db: primary}
+	assert.Same(t, primary, db.readDB(ctx))
+
+	db.replicas = []*replica{newTestReplica(t, false)}
+	assert.Same(t, primary, db.readDB(ctx))
+}
+
+// TestReadYourWrites tests that a request that wrote reads from the primary
+func TestReadYourWrites(t *testing.T) {
+	primary := newTestPool(t)
+	r := newTestReplica(t, true)
+	db := &Database{
+		config:   &config.DatabaseConfig{ReadYourWrites: true},
+		db:       primary,
+		replicas: []*replica{r},
+	}
+
+	ctx := TrackWrites(context.Background())
+	assert.Same(t, r.db, db.readDB(ctx))
+
+	markWrite(ctx)
+	assert.Same(t, primary, db.readDB(ctx))
+
+	// Other requests are unaffected
+	assert.Same(t, r.db, db.readDB(TrackWrites(context.Background())))
+
+	// Without read-your-writes, writes do not pin reads to the primary
+	db.config.ReadYourWrites = false
+	assert.Same(t, r.db, db.readDB(ctx))
+}
+
+// TestIsWrite tests statement classification
+func TestIsWrite(t *testing.T) {
+	tests := map[string]bool{
+		"-- name: GetContact :one\nSELECT * FROM contacts WHERE id = $1":                      false,
+		"-- name: CreateContact :one\nINSERT INTO contacts (first_name) VALUES ($1)":          true,
+		"/* report */ select count(*) from contributions":                                     false,
+		"UPDATE contacts SET first_name = $1":                                                 true,
+		"WITH totals AS (SELECT 1) SELECT * FROM totals":                                      false,
+		"WITH moved AS (DELETE FROM queue RETURNING *) SELECT * FROM moved":                   true,
+		"-- name: DeleteContact :exec\nDELETE FROM contacts WHERE id = $1":                    true,
+		"-- name: CountContacts :one\n-- Counts every contact\nSELECT count(*) FROM contacts": false,
+	}
+
+	for query, want := range tests {
+		assert.Equal(t, want, isWrite(query), query)
+	}
+}
+
+// TestReplicaConfig tests that replicas inherit unset fields from the primary
+func TestReplicaConfig(t *testing.T) {
+	primary := &config.DatabaseConfig{
+		Driver:   "postgres",
+		Host:     "primary",
+		Port:     5432,
+		Username: "civicrm",
+		Password: "secret",
+		Database: "civicrm",
+		Replicas: []config.ReplicaConfig{{Host: "replica"}},
+	}
+
+	cfg := replicaConfig(primary, primary.Replicas[0])
+	assert.Equal(t, "replica", cfg.Host)
+	assert.Equal(t, 5432, cfg.Port)
+	assert.Equal(t, "civicrm", cfg.Username)
+	assert.Nil(t, cfg.Replicas)
+}
@@ -0,0 +1,273 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+)
+
+// rowsDoneKey is the context key of the rowsDone of a query
+type rowsDoneKey struct{}
+
+// rowsDone ends a query's span once the driver's rows are closed. The driver
+// claims it when it returns rows; unclaimed, the query ends on return.
+type rowsDone struct {
+	mutex   sync.Mutex
+	claimed bool
+	finish  func(error)
+}
+
+// tracked reports whether the driver's rows end the span
+func (d *rowsDone) tracked() bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.claimed
+}
+
+// open opens a database whose connections report when query rows are closed
+func open(driverName, dsn string) (*sql.DB, error) {
+	probe, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	drv := probe.Driver()
+	probe.Close()
+
+	var connector driver.Connector = dsnConnector{dsn: dsn, driver: drv}
+	if ctxDriver, ok := drv.(driver.DriverContext); ok {
+		if connector, err = ctxDriver.OpenConnector(dsn); err != nil {
+			return nil, err
+		}
+	}
+	return sql.OpenDB(rowsConnector{connector}), nil
+}
+
+// dsnConnector connects drivers that do not implement driver.DriverContext
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) { return c.driver.Open(c.dsn) }
+func (c dsnConnector) Driver() driver.Driver                        { return c.driver }
+
+// rowsConnector wraps the connections of a connector in rowsConn
+type rowsConnector struct {
+	next driver.Connector
+}
+
+func (c rowsConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.next.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &rowsConn{Conn: conn}, nil
+}
+
+func (c rowsConnector) Driver() driver.Driver { return c.next.Driver() }
+
+// rowsConn passes everything through to the driver's connection, setting
+// the session's domain before each statement and wrapping the rows of
+// queries carrying a rowsDone. Optional interfaces the driver lacks report
+// driver.ErrSkip, so database/sql falls back as it would without the
+// wrapper.
+type rowsConn struct {
+	driver.Conn
+
+	// domain is the app.domain_id the session has, inTx whether a
+	// transaction is open and broken whether setting the domain failed
+	domain string
+	inTx   bool
+	broken bool
+}
+
+func (c *rowsConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	if err := c.scope(ctx); err != nil {
+		return nil, err
+	}
+	rows, err := queryer.QueryContext(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	done, _ := ctx.Value(rowsDoneKey{}).(*rowsDone)
+	if done == nil {
+		return rows, nil
+	}
+	done.mutex.Lock()
+	defer done.mutex.Unlock()
+	if done.claimed {
+		return rows, nil
+	}
+	done.claimed = true
+	return &trackedRows{Rows: rows, finish: done.finish}, nil
+}
+
+func (c *rowsConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	if err := c.scope(ctx); err != nil {
+		return nil, err
+	}
+	return execer.ExecContext(ctx, query, args)
+}
+
+func (c *rowsConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := c.prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &scopedStmt{Stmt: stmt, conn: c}, nil
+}
+
+// prepare prepares a statement on the driver's connection
+func (c *rowsConn) prepare(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *rowsConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if err := c.scope(ctx); err != nil {
+		return nil, err
+	}
+	tx, err := c.begin(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	c.inTx = true
+	return &scopedTx{Tx: tx, conn: c}, nil
+}
+
+// begin starts a transaction on the driver's connection
+func (c *rowsConn) begin(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	if opts.ReadOnly || opts.Isolation != driver.IsolationLevel(sql.LevelDefault) {
+		return nil, errors.New("database driver does not support transaction options")
+	}
+	return c.Conn.Begin()
+}
+
+func (c *rowsConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *rowsConn) ResetSession(ctx context.Context) error {
+	if c.broken {
+		return driver.ErrBadConn
+	}
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *rowsConn) IsValid() bool {
+	if c.broken {
+		return false
+	}
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+func (c *rowsConn) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+// trackedRows ends its query's span when closed, with the first error
+// fetching rows
+type trackedRows struct {
+	driver.Rows
+	finish func(error)
+	err    error
+	once   sync.Once
+}
+
+func (r *trackedRows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	if err != nil && !errors.Is(err, io.EOF) && r.err == nil {
+		r.err = err
+	}
+	return err
+}
+
+func (r *trackedRows) Close() error {
+	err := r.Rows.Close()
+	r.once.Do(func() {
+		if r.err == nil {
+			r.err = err
+		}
+		r.finish(r.err)
+	})
+	return err
+}
+
+func (r *trackedRows) HasNextResultSet() bool {
+	if sets, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return sets.HasNextResultSet()
+	}
+	return false
+}
+
+func (r *trackedRows) NextResultSet() error {
+	if sets, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return sets.NextResultSet()
+	}
+	return io.EOF
+}
+
+func (r *trackedRows) ColumnTypeScanType(index int) reflect.Type {
+	if types, ok := r.Rows.(driver.RowsColumnTypeScanType); ok {
+		return types.ColumnTypeScanType(index)
+	}
+	return reflect.TypeOf(new(interface{})).Elem()
+}
+
+func (r *trackedRows) ColumnTypeDatabaseTypeName(index int) string {
+	if types, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return types.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+func (r *trackedRows) ColumnTypeLength(index int) (int64, bool) {
+	if types, ok := r.Rows.(driver.RowsColumnTypeLength); ok {
+		return types.ColumnTypeLength(index)
+	}
+	return 0, false
+}
+
+func (r *trackedRows) ColumnTypeNullable(index int) (bool, bool) {
+	if types, ok := r.Rows.(driver.RowsColumnTypeNullable); ok {
+		return types.ColumnTypeNullable(index)
+	}
+	return false, false
+}
+
+func (r *trackedRows) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	if types, ok := r.Rows.(driver.RowsColumnTypePrecisionScale); ok {
+		return types.ColumnTypePrecisionScale(index)
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// setDomain is the statement that sets the domain of a session
+const setDomain = "SELECT set_config('app.domain_id', $1, false)"
+
+type domainScopeKey struct{}
+
+// WithDomain returns a context whose statements only see rows of domainID.
+// No connection is reserved: each statement sets the domain on the pooled
+// connection that runs it, so a scoped context may be used for nested and
+// concurrent queries like any other.
+func (db *Database) WithDomain(ctx context.Context, domainID uuid.UUID) context.Context {
+	return context.WithValue(ctx, domainScopeKey{}, domainID)
+}
+
+// DomainFromContext returns the domain a context is scoped to
+func DomainFromContext(ctx context.Context) (uuid.UUID, bool) {
+	domainID, ok := ctx.Value(domainScopeKey{}).(uuid.UUID)
+	return domainID, ok
+}
+
+// scope sets the session's domain to the one of ctx, or clears it for
+// statements without a domain, so row level security applies to whoever
+// borrows the connection next. The setting is only sent when it changes. A
+// transaction keeps the domain it began in.
+func (c *rowsConn) scope(ctx context.Context) error {
+	if c.inTx {
+		return nil
+	}
+
+	var domain string
+	if domainID, ok := DomainFromContext(ctx); ok {
+		domain = domainID.String()
+	}
+	if domain == c.domain {
+		return nil
+	}
+
+	if err := c.exec(ctx, setDomain, []driver.NamedValue{{Ordinal: 1, Value: domain}}); err != nil {
+		// The session's domain is unknown now, so the pool must drop it
+		c.broken = true
+		return fmt.Errorf("failed to set domain: %w: %w", driver.ErrBadConn, err)
+	}
+	c.domain = domain
+	return nil
+}
+
+// exec runs a statement on the driver's connection
+func (c *rowsConn) exec(ctx context.Context, query string, args []driver.NamedValue) error {
+	if execer, ok := c.Conn.(driver.ExecerContext); ok {
+		if _, err := execer.ExecContext(ctx, query, args); err != driver.ErrSkip {
+			return err
+		}
+	}
+
+	stmt, err := c.prepare(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	_, err = execStmt(ctx, stmt, args)
+	return err
+}
+
+// scopedTx ends the transaction of a rowsConn, after which statements set
+// the domain again
+type scopedTx struct {
+	driver.Tx
+	conn *rowsConn
+}
+
+func (t *scopedTx) Commit() error {
+	t.conn.inTx = false
+	return t.Tx.Commit()
+}
+
+func (t *scopedTx) Rollback() error {
+	t.conn.inTx = false
+	return t.Tx.Rollback()
+}
+
+// scopedStmt sets the domain before each execution of a prepared statement
+type scopedStmt struct {
+	driver.Stmt
+	conn *rowsConn
+}
+
+func (s *scopedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if err := s.conn.scope(ctx); err != nil {
+		return nil, err
+	}
+	return execStmt(ctx, s.Stmt, args)
+}
+
+func (s *scopedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if err := s.conn.scope(ctx); err != nil {
+		return nil, err
+	}
+	if queryer, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		return queryer.QueryContext(ctx, args)
+	}
+	values, err := namedValues(args)
+	if err != nil {
+		return nil, err
+	}
+	return s.Stmt.Query(values)
+}
+
+func (s *scopedStmt) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+// execStmt executes stmt with or without a context, as the driver supports
+func execStmt(ctx context.Context, stmt driver.Stmt, args []driver.NamedValue) (driver.Result, error) {
+	if execer, ok := stmt.(driver.StmtExecContext); ok {
+		return execer.ExecContext(ctx, args)
+	}
+	values, err := namedValues(args)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.Exec(values)
+}
+
+// namedValues converts positional arguments for drivers without contexts
+func namedValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errors.New("database driver does not support named parameters")
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
@@ -0,0 +1,110 @@
+package database
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWithDomain tests that statements set the domain of a scoped context
+// on their connection only when it changes, that a transaction keeps the
+// domain it began in, and that unscoped statements clear it
+func TestWithDomain(t *testing.T) {
+	database, r := newRecordedDatabase(t)
+	database.db.SetMaxOpenConns(1)
+
+	domainID := uuid.New()
+	ctx := database.WithDomain(context.Background(), domainID)
+
+	scoped, ok := DomainFromContext(ctx)
+	require.True(t, ok)
+	assert.Equal(t, domainID, scoped)
+	_, ok = DomainFromContext(context.Background())
+	assert.False(t, ok)
+
+	queries := database.Querier()
+	require.NoError(t, queries.DeleteContact(ctx, uuid.New()))
+	require.NoError(t, database.WithTx(ctx, func(q db.Querier) error {
+		return q.DeleteEvent(context.Background(), uuid.New())
+	}))
+	require.NoError(t, queries.DeleteContact(ctx, uuid.New()))
+	require.NoError(t, queries.DeleteContact(context.Background(), uuid.New()))
+
+	in := " in " + domainID.String()
+	assert.Equal(t, []string{
+		setDomain,
+		"DeleteContact" + in,
+		"BEGIN", "DeleteEvent" + in, "COMMIT",
+		"DeleteContact" + in,
+		setDomain,
+		"DeleteContact",
+	}, r.entries())
+}
+
+// TestWithDomainNestedQueries tests that a scoped context can run queries
+// while the rows of another are still open
+func TestWithDomainNestedQueries(t *testing.T) {
+	database, r := newRecordedDatabase(t)
+	database.db.SetMaxOpenConns(2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	domainID := uuid.New()
+	ctx = database.WithDomain(ctx, domainID)
+
+	rows, err := database.Query(ctx, "SELECT 1")
+	require.NoError(t, err)
+	for rows.Next() {
+		require.NoError(t, database.Querier().DeleteContact(ctx, uuid.New()))
+		require.NoError(t, database.WithTx(ctx, func(q db.Querier) error {
+			return q.DeleteEvent(ctx, uuid.New())
+		}))
+	}
+	require.NoError(t, rows.Err())
+	require.NoError(t, rows.Close())
+
+	in := " in " + domainID.String()
+	assert.Equal(t, []string{
+		setDomain, "SELECT 1" + in,
+		setDomain, "DeleteContact" + in,
+		"BEGIN", "DeleteEvent" + in, "COMMIT",
+	}, r.entries())
+}
+
+// TestWithDomainConcurrent tests that concurrent statements of different
+// domains sharing a pool each run in their own domain
+func TestWithDomainConcurrent(t *testing.T) {
+	database, r := newRecordedDatabase(t)
+	database.db.SetMaxOpenConns(3)
+
+	domains := []uuid.UUID{uuid.New(), uuid.New()}
+	const calls = 50
+
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func(domainID uuid.UUID) {
+			defer wg.Done()
+			ctx := database.WithDomain(context.Background(), domainID)
+			assert.NoError(t, database.Querier().DeleteContact(ctx, uuid.New()))
+		}(domains[i%len(domains)])
+	}
+	wg.Wait()
+
+	counts := make(map[string]int)
+	for _, entry := range r.entries() {
+		if entry != setDomain {
+			counts[entry]++
+		}
+	}
+	assert.Equal(t, map[string]int{
+		"DeleteContact in " + domains[0].String(): calls / 2,
+		"DeleteContact in " + domains[1].String(): calls / 2,
+	}, counts)
+}
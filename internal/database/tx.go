@@ -0,0 +1,155 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	generated "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/lib/pq"
+)
+
+// Retry settings for transactions aborted by serialization failures or
+// deadlocks
+const (
+	txMaxAttempts = 5
+	txBaseBackoff = 10 * time.Millisecond
+	txMaxBackoff  = 500 * time.Millisecond
+)
+
+// Transactor runs a unit of work atomically. *Database starts a transaction
+// and *TxQuerier starts a savepoint inside its transaction, so code that
+// accepts a Transactor works whether or not it is already in a transaction.
+type Transactor interface {
+	WithTx(ctx context.Context, fn func(q generated.Querier) error) error
+}
+
+// TxQuerier is the generated querier bound to a transaction. It is passed to
+// WithTx callbacks; type assert to Transactor to nest a unit of work.
+type TxQuerier struct {
+	*generated.Queries
+	db    *Database
+	tx    *sql.Tx
+	depth int
+}
+
+// WithTx runs fn in a new transaction on the primary using the generated
+// querier. The transaction commits if fn returns nil and rolls back
+// otherwise. Serialization failures and deadlocks are retried with backoff,
+// so fn may run more than once and should not have side effects outside the
+// database.
+func (db *Database) WithTx(ctx context.Context, fn func(q generated.Querier) error) error {
+	return db.WithTxOptions(ctx, nil, fn)
+}
+
+// WithTxOptions is WithTx with explicit isolation level and read-only options
+func (db *Database) WithTxOptions(ctx context.Context, opts *sql.TxOptions, fn func(q generated.Querier) error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = db.runTx(ctx, opts, fn); err == nil || !isRetryable(err) || attempt == txMaxAttempts {
+			return err
+		}
+
+		delay := backoff(attempt)
+		if db.logger != nil {
+			db.logger.Warn("Retrying transaction", "attempt", attempt, "delay", delay, "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
+
+// runTx makes a single attempt at a transaction
+func (db *Database) runTx(ctx context.Context, opts *sql.TxOptions, fn func(q generated.Querier) error) (err error) {
+	tx, err := db.beginTx(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			db.rollback(tx.Rollback, "transaction")
+			panic(p)
+		}
+	}()
+
+	if err := fn(&TxQuerier{Queries: generated.New(db.instrument(tx, poolTx)), db: db, tx: tx}); err != nil {
+		db.rollback(tx.Rollback, "transaction")
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// WithTx runs fn inside a savepoint of the current transaction. If fn fails
+// only its own changes are rolled back and the error is returned; the outer
+// transaction decides whether to continue.
+func (q *TxQuerier) WithTx(ctx context.Context, fn func(q generated.Querier) error) (err error) {
+	name := fmt.Sprintf("sp_%d", q.depth+1)
+	if _, err := q.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("failed to create savepoint: %w", err)
+	}
+
+	rollback := func() error {
+		_, err := q.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			q.db.rollback(rollback, name)
+			panic(p)
+		}
+	}()
+
+	nested := &TxQuerier{Queries: q.Queries, db: q.db, tx: q.tx, depth: q.depth + 1}
+	if err := fn(nested); err != nil {
+		q.db.rollback(rollback, name)
+		return err
+	}
+
+	if _, err := q.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return nil
+}
+
+// Tx returns the underlying transaction for queries the generated code does
+// not cover
+func (q *TxQuerier) Tx() *sql.Tx {
+	return q.tx
+}
+
+// rollback runs a rollback and logs it if it fails, since the caller is
+// already returning another error or panicking
+func (db *Database) rollback(rollback func() error, scope string) {
+	if err := rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) && db.logger != nil {
+		db.logger.Error("Failed to roll back", "scope", scope, "error", err)
+	}
+}
+
+// isRetryable reports whether err aborted the transaction because of a
+// serialization failure or deadlock, in which case it can be run again
+func isRetryable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == "40001" || pqErr.Code == "40P01"
+}
+
+// backoff returns an exponential delay with jitter for the given attempt
+func backoff(attempt int) time.Duration {
+	delay := txBaseBackoff << (attempt - 1)
+	if delay > txMaxBackoff {
+		delay = txMaxBackoff
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
@@ -0,0 +1,233 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/config"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder is a minimal database/sql driver that records statements and
+// transaction boundaries, and can fail commits
+type recorder struct {
+	mutex      sync.Mutex
+	log        []string
+	commitErrs []error
+}
+
+func (r *recorder) record(entry string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.log = append(r.log, entry)
+}
+
+func (r *recorder) entries() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]string(nil), r.log...)
+}
+
+func (r *recorder) Connect(context.Context) (driver.Conn, error) { return &recorderConn{r: r}, nil }
+func (r *recorder) Driver() driver.Driver                        { return nil }
+
+// recorderConn tracks the domain set on its session, and records statements
+// run in a domain with the domain's ID
+type recorderConn struct {
+	r      *recorder
+	domain string
+}
+
+func (c *recorderConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not supported")
+}
+func (c *recorderConn) Close() error { return nil }
+
+func (c *recorderConn) Begin() (driver.Tx, error) {
+	c.r.record("BEGIN")
+	return &recorderTx{c.r}, nil
+}
+
+// ExecContext records sqlc queries by name and other statements verbatim
+func (c *recorderConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if query == setDomain {
+		c.domain = args[0].Value.(string)
+	}
+	c.record(query)
+	return driver.RowsAffected(1), nil
+}
+
+// QueryContext records a query like ExecContext and returns one row
+func (c *recorderConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.record(query)
+	return &recorderRows{left: 1}, nil
+}
+
+func (c *recorderConn) record(query string) {
+	if name, found := strings.CutPrefix(query, "-- name: "); found {
+		query, _, _ = strings.Cut(name, " ")
+	}
+	if c.domain != "" && query != setDomain {
+		query += " in " + c.domain
+	}
+	c.r.record(query)
+}
+
+// recorderRows returns left rows of a single column
+type recorderRows struct{ left int }
+
+func (r *recorderRows) Columns() []string { return []string{"n"} }
+func (r *recorderRows) Close() error      { return nil }
+
+func (r *recorderRows) Next(dest []driver.Value) error {
+	if r.left == 0 {
+		return io.EOF
+	}
+	r.left--
+	dest[0] = int64(1)
+	return nil
+}
+
+type recorderTx struct{ r *recorder }
+
+func (t *recorderTx) Commit() error {
+	t.r.record("COMMIT")
+	t.r.mutex.Lock()
+	defer t.r.mutex.Unlock()
+	if len(t.r.commitErrs) > 0 {
+		err := t.r.commitErrs[0]
+		t.r.commitErrs = t.r.commitErrs[1:]
+		return err
+	}
+	return nil
+}
+
+func (t *recorderTx) Rollback() error {
+	t.r.record("ROLLBACK")
+	return nil
+}
+
+func newRecordedDatabase(t *testing.T, commitErrs ...error) (*Database, *recorder) {
+	t.Helper()
+	r := &recorder{commitErrs: commitErrs}
+	pool := sql.OpenDB(rowsConnector{r})
+	t.Cleanup(func() { pool.Close() })
+	return &Database{config: &config.DatabaseConfig{}, db: pool}, r
+}
+
+// TestWithTxCommits tests that the querier runs on the transaction
+func TestWithTxCommits(t *testing.T) {
+	database, r := newRecordedDatabase(t)
+
+	err := database.WithTx(context.Background(), func(q db.Querier) error {
+		return q.DeleteContact(context.Background(), uuid.New())
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"BEGIN", "DeleteContact", "COMMIT"}, r.entries())
+}
+
+// TestWithTxRollsBack tests that an error from fn rolls back and is returned
+func TestWithTxRollsBack(t *testing.T) {
+	database, r := newRecordedDatabase(t)
+	failure := errors.New("insufficient funds")
+
+	err := database.WithTx(context.Background(), func(q db.Querier) error {
+		return failure
+	})
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, []string{"BEGIN", "ROLLBACK"}, r.entries())
+}
+
+// TestWithTxRetriesSerializationFailures tests that only serialization
+// failures and deadlocks are retried
+func TestWithTxRetriesSerializationFailures(t *testing.T) {
+	database, r := newRecordedDatabase(t, &pq.Error{Code: "40001"}, &pq.Error{Code: "40P01"})
+
+	attempts := 0
+	err := database.WithTx(context.Background(), func(q db.Querier) error {
+		attempts++
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+	assert.Len(t, r.entries(), 6)
+
+	database, _ = newRecordedDatabase(t, &pq.Error{Code: "23505"})
+	attempts = 0
+	err = database.WithTx(context.Background(), func(q db.Querier) error {
+		attempts++
+		return nil
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, attempts)
+}
+
+// TestWithTxGivesUp tests that retries stop after the maximum attempts
+func TestWithTxGivesUp(t *testing.T) {
+	errs := make([]error, txMaxAttempts+1)
+	for i := range errs {
+		errs[i] = &pq.Error{Code: "40001"}
+	}
+	database, _ := newRecordedDatabase(t, errs...)
+
+	attempts := 0
+	err := database.WithTx(context.Background(), func(q db.Querier) error {
+		attempts++
+		return nil
+	})
+	assert.True(t, isRetryable(err))
+	assert.Equal(t, txMaxAttempts, attempts)
+}
+
+// TestWithTxSavepoints tests that nested units of work use savepoints and
+// that a failed inner unit does not abort the outer one
+func TestWithTxSavepoints(t *testing.T) {
+	database, r := newRecordedDatabase(t)
+	ctx := context.Background()
+
+	err := database.WithTx(ctx, func(q db.Querier) error {
+		tx := q.(Transactor)
+
+		require.NoError(t, tx.WithTx(ctx, func(q db.Querier) error {
+			return q.DeleteContact(ctx, uuid.New())
+		}))
+
+		err := tx.WithTx(ctx, func(q db.Querier) error {
+			return q.(Transactor).WithTx(ctx, func(q db.Querier) error {
+				return errors.New("duplicate payment")
+			})
+		})
+		assert.Error(t, err)
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"BEGIN",
+		"SAVEPOINT sp_1", "DeleteContact", "RELEASE SAVEPOINT sp_1",
+		"SAVEPOINT sp_1", "SAVEPOINT sp_2", "ROLLBACK TO SAVEPOINT sp_2", "ROLLBACK TO SAVEPOINT sp_1",
+		"COMMIT",
+	}, r.entries())
+}
+
+// TestWithTxPanics tests that a panic rolls back and is propagated
+func TestWithTxPanics(t *testing.T) {
+	database, r := newRecordedDatabase(t)
+
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = database.WithTx(context.Background(), func(q db.Querier) error {
+			panic("boom")
+		})
+	})
+	assert.Equal(t, []string{"BEGIN", "ROLLBACK"}, r.entries())
+}
@@ -0,0 +1,361 @@
+// Package dedupe finds duplicate contacts using weighted rule groups. Each
+// rule compares one field and adds its weight to a pair's score when the
+// values match; pairs reaching the group's threshold are duplicates.
+package dedupe
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/database"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/jxlxx/civicrm/internal/logger"
+	"github.com/lib/pq"
+)
+
+// Rule group usages. Unsupervised groups are used to match contacts on
+// create and import, Supervised groups for duplicate searches reviewed by a
+// person, and General groups only when chosen explicitly.
+const (
+	UsedUnsupervised = "Unsupervised"
+	UsedSupervised   = "Supervised"
+	UsedGeneral      = "General"
+)
+
+// maxRules bounds the rules in a group so matched rules fit in a bitmask
+const maxRules = 64
+
+// Unique constraints a new rule group can violate: one Unsupervised and one
+// Supervised group per contact type, and unique names
+const (
+	defaultGroupConstraint = "idx_dedupe_rule_groups_default"
+	nameConstraint         = "dedupe_rule_groups_name_key"
+)
+
+var (
+	// ErrNotFound is returned when a rule group does not exist
+	ErrNotFound = errors.New("dedupe rule group not found")
+
+	// ErrInvalidRuleGroup is returned when a rule group or one of its rules
+	// cannot be used
+	ErrInvalidRuleGroup = errors.New("invalid dedupe rule group")
+)
+
+// RuleGroup is a rule group with its rules
+type RuleGroup struct {
+	db.DedupeRuleGroup
+	Rules []db.DedupeRule `json:"rules"`
+}
+
+// Candidate is a contact with its location records, either loaded from the
+// database or about to be created
+type Candidate struct {
+	Contact   db.Contact
+	Emails    []db.Email
+	Phones    []db.Phone
+	Addresses []db.Address
+}
+
+// Service manages rule groups and finds duplicate contacts
+type Service struct {
+	queries db.Querier
+	tx      database.Transactor
+	lookup  idLookup
+	logger  *logger.Logger
+}
+
+// idLookup runs a query returning contact IDs. Candidate lookups select
+// columns chosen by the rules, so they cannot be generated by sqlc.
+type idLookup func(ctx context.Context, query string, args ...interface{}) ([]uuid.UUID, error)
+
+// New creates a dedupe service. Reads go to replicas when configured.
+func New(database *database.Database, logger *logger.Logger) *Service {
+	return &Service{
+		queries: database.ReadQuerier(),
+		tx:      database,
+		lookup: func(ctx context.Context, query string, args ...interface{}) ([]uuid.UUID, error) {
+			rows, err := database.ReadQuery(ctx, query, args...)
+			if err != nil {
+				return nil, err
+			}
+			defer rows.Close()
+
+			var ids []uuid.UUID
+			for rows.Next() {
+				var id uuid.UUID
+				if err := rows.Scan(&id); err != nil {
+					return nil, err
+				}
+				ids = append(ids, id)
+			}
+			return ids, rows.Err()
+		},
+		logger: logger,
+	}
+}
+
+// RuleGroup returns a rule group by ID
+func (s *Service) RuleGroup(ctx context.Context, id uuid.UUID) (*RuleGroup, error) {
+	return s.loadRuleGroup(ctx, func(q db.Querier) (db.DedupeRuleGroup, error) {
+		return q.GetDedupeRuleGroup(ctx, id)
+	})
+}
+
+// RuleGroupByName returns a rule group by name
+func (s *Service) RuleGroupByName(ctx context.Context, name string) (*RuleGroup, error) {
+	return s.loadRuleGroup(ctx, func(q db.Querier) (db.DedupeRuleGroup, error) {
+		return q.GetDedupeRuleGroupByName(ctx, name)
+	})
+}
+
+// DefaultRuleGroup returns the rule group a contact type uses for a purpose,
+// such as UsedUnsupervised for matching on create
+func (s *Service) DefaultRuleGroup(ctx context.Context, contactType, used string) (*RuleGroup, error) {
+	return s.loadRuleGroup(ctx, func(q db.Querier) (db.DedupeRuleGroup, error) {
+		return q.GetDedupeRuleGroupByUsage(ctx, db.GetDedupeRuleGroupByUsageParams{ContactType: contactType, Used: used})
+	})
+}
+
+// RuleGroups returns every rule group with its rules
+func (s *Service) RuleGroups(ctx context.Context) ([]*RuleGroup, error) {
+	groups, err := s.queries.ListDedupeRuleGroups(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dedupe rule groups: %w", err)
+	}
+
+	result := make([]*RuleGroup, 0, len(groups))
+	for _, group := range groups {
+		rules, err := s.queries.ListDedupeRules(ctx, group.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list dedupe rules: %w", err)
+		}
+		result = append(result, &RuleGroup{DedupeRuleGroup: group, Rules: rules})
+	}
+	return result, nil
+}
+
+// CreateRuleGroup validates and stores a rule group with its rules
+func (s *Service) CreateRuleGroup(ctx context.Context, params db.CreateDedupeRuleGroupParams, rules []db.CreateDedupeRuleParams) (*RuleGroup, error) {
+	if err := validateRuleGroup(params, rules); err != nil {
+		return nil, err
+	}
+
+	var group *RuleGroup
+	err := s.tx.WithTx(ctx, func(q db.Querier) error {
+		created, err := q.CreateDedupeRuleGroup(ctx, params)
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) {
+			switch pqErr.Constraint {
+			case defaultGroupConstraint:
+				return fmt.Errorf("%w: the %s rule group for %s contacts already exists", ErrInvalidRuleGroup, params.Used, params.ContactType)
+			case nameConstraint:
+				return fmt.Errorf("%w: name %q is already used", ErrInvalidRuleGroup, params.Name)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create dedupe rule group: %w", err)
+		}
+
+		group = &RuleGroup{DedupeRuleGroup: created}
+		for _, rule := range rules {
+			rule.RuleGroupID = created.ID
+			createdRule, err := q.CreateDedupeRule(ctx, rule)
+			if err != nil {
+				return fmt.Errorf("failed to create dedupe rule: %w", err)
+			}
+			group.Rules = append(group.Rules, createdRule)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// loadRuleGroup loads a rule group and its rules
+func (s *Service) loadRuleGroup(ctx context.Context, load func(q db.Querier) (db.DedupeRuleGroup, error)) (*RuleGroup, error) {
+	group, err := load(s.queries)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dedupe rule group: %w", err)
+	}
+
+	rules, err := s.queries.ListDedupeRules(ctx, group.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dedupe rules: %w", err)
+	}
+	return &RuleGroup{DedupeRuleGroup: group, Rules: rules}, nil
+}
+
+// validateRuleGroup checks a rule group before it is stored
+func validateRuleGroup(params db.CreateDedupeRuleGroupParams, rules []db.CreateDedupeRuleParams) error {
+	switch {
+	case params.Name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidRuleGroup)
+	case params.Threshold <= 0:
+		return fmt.Errorf("%w: threshold must be positive", ErrInvalidRuleGroup)
+	case len(rules) == 0:
+		return fmt.Errorf("%w: at least one rule is required", ErrInvalidRuleGroup)
+	case len(rules) > maxRules:
+		return fmt.Errorf("%w: at most %d rules are allowed", ErrInvalidRuleGroup, maxRules)
+	}
+
+	switch params.ContactType {
+	case "Individual", "Organization", "Household":
+	default:
+		return fmt.Errorf("%w: unknown contact type %q", ErrInvalidRuleGroup, params.ContactType)
+	}
+
+	switch params.Used {
+	case UsedUnsupervised, UsedSupervised, UsedGeneral:
+	default:
+		return fmt.Errorf("%w: unknown usage %q", ErrInvalidRuleGroup, params.Used)
+	}
+
+	var total int32
+	for _, rule := range rules {
+		if !isRuleField(rule.RuleTable, rule.RuleField) {
+			return fmt.Errorf("%w: %s.%s cannot be matched on", ErrInvalidRuleGroup, rule.RuleTable, rule.RuleField)
+		}
+		if rule.RuleWeight <= 0 {
+			return fmt.Errorf("%w: weight of %s.%s must be positive", ErrInvalidRuleGroup, rule.RuleTable, rule.RuleField)
+		}
+		if rule.RuleLength.Valid && rule.RuleLength.Int32 <= 0 {
+			return fmt.Errorf("%w: length of %s.%s must be positive", ErrInvalidRuleGroup, rule.RuleTable, rule.RuleField)
+		}
+		total += rule.RuleWeight
+	}
+
+	if total < params.Threshold {
+		return fmt.Errorf("%w: rule weights add up to %d, below the threshold of %d", ErrInvalidRuleGroup, total, params.Threshold)
+	}
+	return nil
+}
+
+// Matchable fields of each table. The values are normalized before they are
+// compared, see normalize.
+func contactFields(c db.Contact) map[string]string {
+	return map[string]string{
+		"first_name":        c.FirstName.String,
+		"last_name":         c.LastName.String,
+		"nick_name":         c.NickName.String,
+		"organization_name": c.OrganizationName.String,
+		"household_name":    c.HouseholdName.String,
+	}
+}
+
+func emailFields(e db.Email) map[string]string {
+	return map[string]string{"email": e.Email}
+}
+
+func phoneFields(p db.Phone) map[string]string {
+	return map[string]string{"phone": p.Phone}
+}
+
+func addressFields(a db.Address) map[string]string {
+	return map[string]string{
+		"street_address": a.StreetAddress.String,
+		"city":           a.City.String,
+		"postal_code":    a.PostalCode.String,
+	}
+}
+
+// isRuleField reports whether rules can match on table.field
+func isRuleField(table, field string) bool {
+	var fields map[string]string
+	switch table {
+	case "contacts":
+		fields = contactFields(db.Contact{})
+	case "emails":
+		fields = emailFields(db.Email{})
+	case "phones":
+		fields = phoneFields(db.Phone{})
+	case "addresses":
+		fields = addressFields(db.Address{})
+	}
+	_, ok := fields[field]
+	return ok
+}
+
+// values returns the normalized values of every rule for a candidate, in
+// rule order. Location tables can hold several values per contact.
+func (c *Candidate) values(rules []db.DedupeRule) [][]string {
+	values := make([][]string, len(rules))
+	for i, rule := range rules {
+		var raw []string
+		switch rule.RuleTable {
+		case "contacts":
+			raw = append(raw, contactFields(c.Contact)[rule.RuleField])
+		case "emails":
+			for _, email := range c.Emails {
+				raw = append(raw, emailFields(email)[rule.RuleField])
+			}
+		case "phones":
+			for _, phone := range c.Phones {
+				raw = append(raw, phoneFields(phone)[rule.RuleField])
+			}
+		case "addresses":
+			for _, address := range c.Addresses {
+				raw = append(raw, addressFields(address)[rule.RuleField])
+			}
+		}
+
+		for _, value := range raw {
+			if value = normalize(rule, value); value != "" {
+				values[i] = append(values[i], value)
+			}
+		}
+	}
+	return values
+}
+
+var (
+	spacePattern    = regexp.MustCompile(`\s+`)
+	nonDigitPattern = regexp.MustCompile(`\D`)
+)
+
+// normalize lowercases and trims a value, collapses inner whitespace, keeps
+// only the digits of phone numbers and truncates to the rule length
+func normalize(rule db.DedupeRule, value string) string {
+	if rule.RuleField == "phone" {
+		value = nonDigitPattern.ReplaceAllString(value, "")
+	} else {
+		value = spacePattern.ReplaceAllString(strings.ToLower(strings.TrimSpace(value)), " ")
+	}
+
+	if rule.RuleLength.Valid && utf8.RuneCountInString(value) > int(rule.RuleLength.Int32) {
+		value = string([]rune(value)[:rule.RuleLength.Int32])
+	}
+	return value
+}
+
+// score adds up the weights of the rules on which a and b share a value
+func score(rules []db.DedupeRule, a, b [][]string) int32 {
+	var total int32
+	for i, rule := range rules {
+		if shareValue(a[i], b[i]) {
+			total += rule.RuleWeight
+		}
+	}
+	return total
+}
+
+func shareValue(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
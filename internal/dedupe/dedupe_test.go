@@ -0,0 +1,283 @@
+package dedupe
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/database/dbtest"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeQuerier serves contacts and emails from slices
+type fakeQuerier struct {
+	db.Querier
+	contacts []db.Contact
+	emails   []db.Email
+	batches  int
+}
+
+func (q *fakeQuerier) ListContactsForDedupe(ctx context.Context, arg db.ListContactsForDedupeParams) ([]db.Contact, error) {
+	q.batches++
+	var result []db.Contact
+	for _, contact := range q.contacts {
+		if contact.ContactType == arg.ContactType && bytes.Compare(contact.ID[:], arg.AfterID[:]) > 0 && len(result) < int(arg.BatchSize) {
+			result = append(result, contact)
+		}
+	}
+	return result, nil
+}
+
+func (q *fakeQuerier) ListContactsByIDs(ctx context.Context, ids []uuid.UUID) ([]db.Contact, error) {
+	var result []db.Contact
+	for _, contact := range q.contacts {
+		for _, id := range ids {
+			if contact.ID == id {
+				result = append(result, contact)
+			}
+		}
+	}
+	return result, nil
+}
+
+func (q *fakeQuerier) ListEmailsForContacts(ctx context.Context, ids []uuid.UUID) ([]db.Email, error) {
+	var result []db.Email
+	for _, email := range q.emails {
+		for _, id := range ids {
+			if email.ContactID == id {
+				result = append(result, email)
+			}
+		}
+	}
+	return result, nil
+}
+
+// newContacts returns individuals sorted by ID, as the database lists them
+func newContacts(names ...[2]string) []db.Contact {
+	contacts := make([]db.Contact, len(names))
+	for i, name := range names {
+		contacts[i] = db.Contact{
+			ID:          uuid.New(),
+			ContactType: "Individual",
+			FirstName:   sql.NullString{String: name[0], Valid: true},
+			LastName:    sql.NullString{String: name[1], Valid: true},
+		}
+	}
+	sort.Slice(contacts, func(i, j int) bool {
+		return bytes.Compare(contacts[i].ID[:], contacts[j].ID[:]) < 0
+	})
+	return contacts
+}
+
+func rule(table, field string, length, weight int32) db.DedupeRule {
+	return db.DedupeRule{
+		ID:         uuid.New(),
+		RuleTable:  table,
+		RuleField:  field,
+		RuleLength: sql.NullInt32{Int32: length, Valid: length > 0},
+		RuleWeight: weight,
+	}
+}
+
+func nameAndEmail() *RuleGroup {
+	return &RuleGroup{
+		DedupeRuleGroup: db.DedupeRuleGroup{Name: "IndividualSupervised", ContactType: "Individual", Threshold: 12},
+		Rules: []db.DedupeRule{
+			rule("contacts", "first_name", 1, 5),
+			rule("contacts", "last_name", 0, 7),
+			rule("emails", "email", 0, 10),
+		},
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	contacts := newContacts(
+		[2]string{"Ada", "Lovelace"},
+		[2]string{"Augusta", " lovelace "},
+		[2]string{"Grace", "Hopper"},
+		[2]string{"Gracie", "Hopper"},
+		[2]string{"Alan", "Turing"},
+	)
+	byName := make(map[string]uuid.UUID)
+	for _, contact := range contacts {
+		byName[contact.FirstName.String] = contact.ID
+	}
+
+	queries := &fakeQuerier{
+		contacts: contacts,
+		emails: []db.Email{
+			{ContactID: byName["Grace"], Email: "grace@example.org"},
+			{ContactID: byName["Gracie"], Email: "Grace@Example.org"},
+			// Email alone is below the threshold
+			{ContactID: byName["Alan"], Email: "grace@example.org"},
+		},
+	}
+	service := &Service{queries: queries}
+
+	pairs, err := service.FindDuplicates(context.Background(), nameAndEmail(), FindOptions{BatchSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 3, queries.batches)
+
+	assert.Equal(t, []Pair{
+		pair(byName["Grace"], byName["Gracie"], 22),
+		pair(byName["Ada"], byName["Augusta"], 12),
+	}, pairs)
+
+	limited, err := service.FindDuplicates(context.Background(), nameAndEmail(), FindOptions{Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, pairs[:1], limited)
+}
+
+func pair(a, b uuid.UUID, score int32) Pair {
+	ordered := orderedPair(a, b)
+	return Pair{ContactID1: ordered[0], ContactID2: ordered[1], Score: score}
+}
+
+// TestFindDuplicatesSkipsLargeBlocks tests that a value shared by too many
+// contacts does not pair them, while other rules still do
+func TestFindDuplicatesSkipsLargeBlocks(t *testing.T) {
+	contacts := newContacts(
+		[2]string{"Ann", "Smith"},
+		[2]string{"Bob", "Smith"},
+		[2]string{"Cat", "Smith"},
+		[2]string{"Dan", "Smith"},
+	)
+	emails := []db.Email{
+		{ContactID: contacts[0].ID, Email: "ann@example.org"},
+		{ContactID: contacts[1].ID, Email: "ann@example.org"},
+	}
+	group := &RuleGroup{
+		DedupeRuleGroup: db.DedupeRuleGroup{ContactType: "Individual", Threshold: 7},
+		Rules:           []db.DedupeRule{rule("contacts", "last_name", 0, 7), rule("emails", "email", 0, 10)},
+	}
+
+	pairs, err := (&Service{queries: &fakeQuerier{contacts: contacts, emails: emails}}).FindDuplicates(context.Background(), group, FindOptions{MaxBlockSize: 3})
+	require.NoError(t, err)
+	require.Len(t, pairs, 1)
+	assert.Equal(t, int32(17), pairs[0].Score)
+}
+
+func TestMatch(t *testing.T) {
+	contacts := newContacts(
+		[2]string{"Ada", "Lovelace"},
+		[2]string{"Ada", "Byron"},
+	)
+	queries := &fakeQuerier{contacts: contacts}
+
+	var lookups []string
+	service := &Service{
+		queries: queries,
+		lookup: func(ctx context.Context, query string, args ...interface{}) ([]uuid.UUID, error) {
+			lookups = append(lookups, args[1].(string))
+			assert.Equal(t, "Individual", args[0])
+			if strings.Contains(query, "LIKE $4") {
+				assert.Equal(t, args[1].(string)+"%", args[3])
+			} else {
+				assert.Len(t, args, 3)
+			}
+			if strings.Contains(query, "t.first_name") {
+				return []uuid.UUID{contacts[0].ID, contacts[1].ID}, nil
+			}
+			return nil, nil
+		},
+	}
+
+	group := nameAndEmail()
+	matches, err := service.Match(context.Background(), group, &Candidate{
+		Contact: db.Contact{
+			ContactType: "Individual",
+			FirstName:   sql.NullString{String: "ADA", Valid: true},
+			LastName:    sql.NullString{String: "Lovelace", Valid: true},
+		},
+		Emails: []db.Email{{Email: " ada@example.org "}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "lovelace", "ada@example.org"}, lookups)
+
+	// newContacts sorts by ID, so find Lovelace by name
+	lovelace := contacts[0]
+	if lovelace.LastName.String != "Lovelace" {
+		lovelace = contacts[1]
+	}
+	assert.Equal(t, []Match{{ContactID: lovelace.ID, Score: 12}}, matches)
+}
+
+func TestLookupQuery(t *testing.T) {
+	query, err := lookupQuery(rule("contacts", "first_name", 3, 5))
+	require.NoError(t, err)
+	assert.Contains(t, query, `regexp_replace(lower(btrim(t.first_name)), '\s+', ' ', 'g') LIKE $4`)
+	assert.Contains(t, query, `left(regexp_replace(lower(btrim(t.first_name)), '\s+', ' ', 'g'), 3) = $2`)
+
+	query, err = lookupQuery(rule("phones", "phone", 0, 5))
+	require.NoError(t, err)
+	assert.Contains(t, query, `FROM phones t JOIN contacts c`)
+	assert.Contains(t, query, `regexp_replace(t.phone, '\D', '', 'g') = $2`)
+
+	_, err = lookupQuery(rule("contacts", "id; DROP TABLE contacts", 0, 5))
+	assert.ErrorIs(t, err, ErrInvalidRuleGroup)
+}
+
+func TestLikePrefix(t *testing.T) {
+	assert.Equal(t, "ada%", likePrefix("ada"))
+	assert.Equal(t, `100\% o\_k\\%`, likePrefix(`100% o_k\`))
+}
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, "mary ann", normalize(rule("contacts", "first_name", 0, 1), "  Mary   Ann "))
+	assert.Equal(t, "mar", normalize(rule("contacts", "first_name", 3, 1), "Mary"))
+	assert.Equal(t, "zoë", normalize(rule("contacts", "first_name", 3, 1), "Zoëy"))
+	assert.Equal(t, "15551234567", normalize(rule("phones", "phone", 0, 1), "+1 (555) 123-4567"))
+}
+
+func TestValidateRuleGroup(t *testing.T) {
+	params := db.CreateDedupeRuleGroupParams{Name: "Custom", ContactType: "Individual", Used: UsedGeneral, Threshold: 10}
+	rules := []db.CreateDedupeRuleParams{{RuleTable: "emails", RuleField: "email", RuleWeight: 10}}
+	assert.NoError(t, validateRuleGroup(params, rules))
+
+	unknown := []db.CreateDedupeRuleParams{{RuleTable: "contacts", RuleField: "password", RuleWeight: 10}}
+	assert.ErrorIs(t, validateRuleGroup(params, unknown), ErrInvalidRuleGroup)
+
+	params.Threshold = 11
+	assert.ErrorContains(t, validateRuleGroup(params, rules), "below the threshold")
+
+	params.Threshold, params.Used = 10, "Sometimes"
+	assert.ErrorIs(t, validateRuleGroup(params, rules), ErrInvalidRuleGroup)
+}
+
+// conflictQuerier fails to create rule groups with a unique violation
+type conflictQuerier struct {
+	db.Querier
+	constraint string
+}
+
+func (q *conflictQuerier) CreateDedupeRuleGroup(ctx context.Context, arg db.CreateDedupeRuleGroupParams) (db.DedupeRuleGroup, error) {
+	return db.DedupeRuleGroup{}, &pq.Error{Code: "23505", Constraint: q.constraint}
+}
+
+// TestCreateRuleGroupConflicts tests that a second default group for a
+// contact type and a reused name are invalid rule groups
+func TestCreateRuleGroupConflicts(t *testing.T) {
+	params := db.CreateDedupeRuleGroupParams{Name: "IndividualUnsupervised", ContactType: "Individual", Used: UsedUnsupervised, Threshold: 10}
+	rules := []db.CreateDedupeRuleParams{{RuleTable: "emails", RuleField: "email", RuleWeight: 10}}
+
+	for constraint, message := range map[string]string{
+		"idx_dedupe_rule_groups_default": "the Unsupervised rule group for Individual contacts already exists",
+		"dedupe_rule_groups_name_key":    `name "IndividualUnsupervised" is already used`,
+	} {
+		service := &Service{tx: dbtest.Tx{Q: &conflictQuerier{constraint: constraint}}}
+		_, err := service.CreateRuleGroup(context.Background(), params, rules)
+		assert.ErrorIs(t, err, ErrInvalidRuleGroup)
+		assert.ErrorContains(t, err, message)
+	}
+
+	service := &Service{tx: dbtest.Tx{Q: &conflictQuerier{constraint: "other"}}}
+	_, err := service.CreateRuleGroup(context.Background(), params, rules)
+	assert.NotErrorIs(t, err, ErrInvalidRuleGroup)
+}
@@ -0,0 +1,201 @@
+package dedupe
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/google/uuid"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+)
+
+// Defaults for duplicate searches
+const (
+	DefaultBatchSize    = 1000
+	DefaultMaxBlockSize = 500
+)
+
+// FindOptions controls a duplicate search
+type FindOptions struct {
+	// BatchSize is the number of contacts loaded per query
+	BatchSize int
+
+	// MaxBlockSize skips blocking keys shared by more contacts than this, such
+	// as a common first name, so they do not produce every possible pair.
+	// Pairs sharing such a key are still found through their other rules.
+	MaxBlockSize int
+
+	// Limit caps the number of pairs returned; zero returns every pair
+	Limit int
+}
+
+// Pair is two contacts that are likely duplicates
+type Pair struct {
+	ContactID1 uuid.UUID `json:"contact_id_1"`
+	ContactID2 uuid.UUID `json:"contact_id_2"`
+	Score      int32     `json:"score"`
+}
+
+// FindDuplicates scans every contact of the group's contact type in batches
+// and returns the pairs scoring at least the group's threshold, highest
+// score first. Contacts sharing the value of any rule form a block, and only
+// contacts in the same block are compared.
+func (s *Service) FindDuplicates(ctx context.Context, group *RuleGroup, opts FindOptions) ([]Pair, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.MaxBlockSize <= 0 {
+		opts.MaxBlockSize = DefaultMaxBlockSize
+	}
+	if len(group.Rules) == 0 || len(group.Rules) > maxRules {
+		return nil, fmt.Errorf("%w: %s has %d rules", ErrInvalidRuleGroup, group.Name, len(group.Rules))
+	}
+
+	values := make(map[uuid.UUID][][]string)
+	blocks := make(map[string][]uuid.UUID)
+
+	after := uuid.Nil
+	for {
+		contacts, err := s.queries.ListContactsForDedupe(ctx, db.ListContactsForDedupeParams{
+			ContactType: group.ContactType,
+			AfterID:     after,
+			BatchSize:   int32(opts.BatchSize),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list contacts: %w", err)
+		}
+		if len(contacts) == 0 {
+			break
+		}
+		after = contacts[len(contacts)-1].ID
+
+		candidates, err := s.loadCandidates(ctx, group.Rules, contacts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, candidate := range candidates {
+			id := candidate.Contact.ID
+			values[id] = candidate.values(group.Rules)
+			for _, key := range blockingKeys(values[id]) {
+				blocks[key] = append(blocks[key], id)
+			}
+		}
+
+		if len(contacts) < opts.BatchSize {
+			break
+		}
+	}
+
+	pairs := make(map[[2]uuid.UUID]struct{})
+	skipped := 0
+	for _, ids := range blocks {
+		if len(ids) > opts.MaxBlockSize {
+			skipped++
+			continue
+		}
+		for i := 0; i < len(ids); i++ {
+			for j := i + 1; j < len(ids); j++ {
+				pairs[orderedPair(ids[i], ids[j])] = struct{}{}
+			}
+		}
+	}
+	if skipped > 0 && s.logger != nil {
+		s.logger.Debug("Skipped large dedupe blocks", "rule_group", group.Name, "blocks", skipped, "max_block_size", opts.MaxBlockSize)
+	}
+
+	var result []Pair
+	for pair := range pairs {
+		if total := score(group.Rules, values[pair[0]], values[pair[1]]); total >= group.Threshold {
+			result = append(result, Pair{ContactID1: pair[0], ContactID2: pair[1], Score: total})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Score != result[j].Score {
+			return result[i].Score > result[j].Score
+		}
+		if c := bytes.Compare(result[i].ContactID1[:], result[j].ContactID1[:]); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(result[i].ContactID2[:], result[j].ContactID2[:]) < 0
+	})
+
+	if opts.Limit > 0 && len(result) > opts.Limit {
+		result = result[:opts.Limit]
+	}
+	return result, nil
+}
+
+// blockingKeys returns one key per rule value, so contacts sharing a value
+// of the same rule share a key
+func blockingKeys(values [][]string) []string {
+	var keys []string
+	for i, ruleValues := range values {
+		prefix := strconv.Itoa(i) + "\x00"
+		for _, value := range ruleValues {
+			keys = append(keys, prefix+value)
+		}
+	}
+	return keys
+}
+
+// orderedPair returns the two IDs in a stable order so each pair is counted
+// once
+func orderedPair(a, b uuid.UUID) [2]uuid.UUID {
+	if bytes.Compare(a[:], b[:]) > 0 {
+		a, b = b, a
+	}
+	return [2]uuid.UUID{a, b}
+}
+
+// loadCandidates loads the location records the rules need for contacts
+func (s *Service) loadCandidates(ctx context.Context, rules []db.DedupeRule, contacts []db.Contact) ([]*Candidate, error) {
+	candidates := make([]*Candidate, len(contacts))
+	byID := make(map[uuid.UUID]*Candidate, len(contacts))
+	ids := make([]uuid.UUID, len(contacts))
+	for i, contact := range contacts {
+		candidates[i] = &Candidate{Contact: contact}
+		byID[contact.ID] = candidates[i]
+		ids[i] = contact.ID
+	}
+
+	tables := make(map[string]bool)
+	for _, rule := range rules {
+		tables[rule.RuleTable] = true
+	}
+
+	if tables["emails"] {
+		emails, err := s.queries.ListEmailsForContacts(ctx, ids)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list emails: %w", err)
+		}
+		for _, email := range emails {
+			byID[email.ContactID].Emails = append(byID[email.ContactID].Emails, email)
+		}
+	}
+
+	if tables["phones"] {
+		phones, err := s.queries.ListPhonesForContacts(ctx, ids)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list phones: %w", err)
+		}
+		for _, phone := range phones {
+			byID[phone.ContactID].Phones = append(byID[phone.ContactID].Phones, phone)
+		}
+	}
+
+	if tables["addresses"] {
+		addresses, err := s.queries.ListAddressesForContacts(ctx, ids)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list addresses: %w", err)
+		}
+		for _, address := range addresses {
+			byID[address.ContactID].Addresses = append(byID[address.ContactID].Addresses, address)
+		}
+	}
+
+	return candidates, nil
+}
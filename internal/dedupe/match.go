@@ -0,0 +1,118 @@
+package dedupe
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+)
+
+// maxMatchLookups bounds the contacts looked up per rule value when matching
+const maxMatchLookups = 100
+
+// Match is an existing contact that a candidate duplicates
+type Match struct {
+	ContactID uuid.UUID `json:"contact_id"`
+	Score     int32     `json:"score"`
+}
+
+// Match returns the existing contacts that candidate duplicates under group,
+// highest score first. It is used when contacts are created or imported, and
+// scores pairs the same way FindDuplicates does.
+func (s *Service) Match(ctx context.Context, group *RuleGroup, candidate *Candidate) ([]Match, error) {
+	values := candidate.values(group.Rules)
+
+	// Every weight is positive, so a duplicate shares at least one rule value
+	seen := make(map[uuid.UUID]bool)
+	var ids []uuid.UUID
+	for i, rule := range group.Rules {
+		if len(values[i]) == 0 {
+			continue
+		}
+
+		query, err := lookupQuery(rule)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, value := range values[i] {
+			args := []interface{}{group.ContactType, value, maxMatchLookups}
+			if rule.RuleLength.Valid {
+				args = append(args, likePrefix(value))
+			}
+			found, err := s.lookup(ctx, query, args...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to look up %s.%s: %w", rule.RuleTable, rule.RuleField, err)
+			}
+			for _, id := range found {
+				if !seen[id] && id != candidate.Contact.ID {
+					seen[id] = true
+					ids = append(ids, id)
+				}
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	contacts, err := s.queries.ListContactsByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list contacts: %w", err)
+	}
+	existing, err := s.loadCandidates(ctx, group.Rules, contacts)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []Match
+	for _, other := range existing {
+		if total := score(group.Rules, values, other.values(group.Rules)); total >= group.Threshold {
+			matches = append(matches, Match{ContactID: other.Contact.ID, Score: total})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ContactID.String() < matches[j].ContactID.String()
+	})
+	return matches, nil
+}
+
+// lookupQuery returns the query finding contacts of a type whose rule field
+// normalizes to a value, mirroring normalize in SQL. The column is checked
+// against the matchable fields before it is interpolated. Migration 055
+// indexes each normalized field; rules on a prefix also compare the field
+// with LIKE and the prefix pattern in $4, which that index can serve.
+func lookupQuery(rule db.DedupeRule) (string, error) {
+	if !isRuleField(rule.RuleTable, rule.RuleField) {
+		return "", fmt.Errorf("%w: %s.%s cannot be matched on", ErrInvalidRuleGroup, rule.RuleTable, rule.RuleField)
+	}
+
+	column := "t." + rule.RuleField
+	expr := fmt.Sprintf(`regexp_replace(lower(btrim(%s)), '\s+', ' ', 'g')`, column)
+	if rule.RuleField == "phone" {
+		expr = fmt.Sprintf(`regexp_replace(%s, '\D', '', 'g')`, column)
+	}
+	condition := expr + " = $2"
+	if rule.RuleLength.Valid {
+		condition = fmt.Sprintf("%s LIKE $4 AND left(%s, %d) = $2", expr, expr, rule.RuleLength.Int32)
+	}
+
+	if rule.RuleTable == "contacts" {
+		return fmt.Sprintf("SELECT t.id FROM contacts t WHERE t.contact_type = $1 AND NOT t.is_deleted AND %s LIMIT $3", condition), nil
+	}
+	return fmt.Sprintf("SELECT DISTINCT t.contact_id FROM %s t JOIN contacts c ON c.id = t.contact_id WHERE c.contact_type = $1 AND NOT c.is_deleted AND %s LIMIT $3", rule.RuleTable, condition), nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// likePrefix returns a LIKE pattern matching values that start with prefix
+func likePrefix(prefix string) string {
+	return likeEscaper.Replace(prefix) + "%"
+}
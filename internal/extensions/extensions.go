@@ -2,13 +2,21 @@ package extensions
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/jxlxx/civicrm/internal/config"
 	"github.com/jxlxx/civicrm/internal/logger"
 )
 
+// reloadDrainTimeout bounds how long a reload waits for calls to the old
+// extension to finish
+const reloadDrainTimeout = 30 * time.Second
+
 // Extension represents a CiviCRM extension
 type Extension interface {
 	Name() string
@@ -25,6 +33,7 @@ type Hook struct {
 	Name     string
 	Priority int
 	Handler  func(ctx context.Context, data interface{}) (interface{}, error)
+	owner    string
 }
 
 // APIService represents an API service provided by an extension
@@ -42,7 +51,10 @@ type Manager struct {
 	extensions map[string]Extension
 	hooks      map[string][]Hook
 	apis       map[string]APIService
+	dirs       map[string]string
 	mutex      sync.RWMutex
+	calls      sync.RWMutex
+	watcher    *watcher
 	ctx        context.Context
 	cancel     context.CancelFunc
 }
@@ -57,6 +69,7 @@ func New(config *config.ExtensionsConfig, logger *logger.Logger) (*Manager, erro
 		extensions: make(map[string]Extension),
 		hooks:      make(map[string][]Hook),
 		apis:       make(map[string]APIService),
+		dirs:       make(map[string]string),
 		ctx:        ctx,
 		cancel:     cancel,
 	}
@@ -83,11 +96,29 @@ func (m *Manager) Start() error {
 		m.logger.Info("Extension started", "extension", name)
 	}
 
+	// Watch the extensions directory for changes in development mode
+	if m.config.Development && m.watcher == nil {
+		w, err := newWatcher(m)
+		if err != nil {
+			m.logger.Error("Failed to watch extensions directory", "path", m.config.Path, "error", err)
+			return nil
+		}
+		m.watcher = w
+		go w.run(m.ctx)
+		m.logger.Info("Watching extensions for changes", "path", m.config.Path)
+	}
+
 	return nil
 }
 
 // Stop stops all extensions
 func (m *Manager) Stop() error {
+	if m.watcher != nil {
+		if err := m.watcher.close(); err != nil {
+			m.logger.Error("Failed to close extensions watcher", "error", err)
+		}
+	}
+
 	m.mutex.RLock()
 	defer m.mutex.RUnlock()
 
@@ -103,11 +134,16 @@ func (m *Manager) Stop() error {
 	return nil
 }
 
-// RegisterExtension registers a new extension
+// RegisterExtension registers a new extension. An extension whose name or
+// API names are already registered is rejected.
 func (m *Manager) RegisterExtension(extension Extension) error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
+	return m.register(extension)
+}
 
+// register registers an extension with the mutex held
+func (m *Manager) register(extension Extension) error {
 	name := extension.Name()
 
 	// Check if extension is disabled
@@ -117,6 +153,16 @@ func (m *Manager) RegisterExtension(extension Extension) error {
 		}
 	}
 
+	if _, exists := m.extensions[name]; exists {
+		return fmt.Errorf("extension %s is already registered", name)
+	}
+	apis := extension.RegisterAPIs()
+	for _, api := range apis {
+		if _, exists := m.apis[api.Name()]; exists {
+			return fmt.Errorf("extension %s: API %s is already registered", name, api.Name())
+		}
+	}
+
 	// Initialize extension
 	if err := extension.Initialize(m.ctx); err != nil {
 		return fmt.Errorf("failed to initialize extension %s: %w", name, err)
@@ -124,11 +170,12 @@ func (m *Manager) RegisterExtension(extension Extension) error {
 
 	// Register hooks
 	for _, hook := range extension.RegisterHooks() {
+		hook.owner = name
 		m.hooks[hook.Name] = append(m.hooks[hook.Name], hook)
 	}
 
 	// Register APIs
-	for _, api := range extension.RegisterAPIs() {
+	for _, api := range apis {
 		m.apis[api.Name()] = api
 	}
 
@@ -138,32 +185,40 @@ func (m *Manager) RegisterExtension(extension Extension) error {
 	return nil
 }
 
-// UnregisterExtension unregisters an extension
+// UnregisterExtension stops an extension, waiting for its calls in flight,
+// and unregisters it
 func (m *Manager) UnregisterExtension(name string) error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
+	return m.unregister(m.ctx, name)
+}
 
+// unregister unregisters an extension with the mutex held. Stopping it may
+// wait until ctx is done for its calls in flight.
+func (m *Manager) unregister(ctx context.Context, name string) error {
 	extension, exists := m.extensions[name]
 	if !exists {
 		return fmt.Errorf("extension %s not found", name)
 	}
 
 	// Stop extension
-	if err := extension.Stop(m.ctx); err != nil {
+	if err := extension.Stop(ctx); err != nil {
 		m.logger.Error("Failed to stop extension during unregistration", "extension", name, "error", err)
 	}
 
-	// Remove hooks
-	for _, hook := range extension.RegisterHooks() {
-		if hooks, exists := m.hooks[hook.Name]; exists {
-			for i, h := range hooks {
-				// Compare hook names instead of function pointers
-				if h.Name == hook.Name {
-					m.hooks[hook.Name] = append(hooks[:i], hooks[i+1:]...)
-					break
-				}
+	// Remove hooks registered by this extension
+	for hookName, hooks := range m.hooks {
+		remaining := hooks[:0]
+		for _, h := range hooks {
+			if h.owner != name {
+				remaining = append(remaining, h)
 			}
 		}
+		if len(remaining) == 0 {
+			delete(m.hooks, hookName)
+		} else {
+			m.hooks[hookName] = remaining
+		}
 	}
 
 	// Remove APIs
@@ -200,8 +255,13 @@ func (m *Manager) ListExtensions() []string {
 
 // ExecuteHook executes a hook with the given data
 func (m *Manager) ExecuteHook(ctx context.Context, hookName string, data interface{}) (interface{}, error) {
+	// Hold the call lock so a reload waits for in-flight hooks to finish
+	m.calls.RLock()
+	defer m.calls.RUnlock()
+
 	m.mutex.RLock()
-	hooks, exists := m.hooks[hookName]
+	hooks := append([]Hook(nil), m.hooks[hookName]...)
+	exists := len(hooks) > 0
 	m.mutex.RUnlock()
 
 	if !exists {
@@ -243,10 +303,113 @@ func (m *Manager) ListAPIServices() []string {
 	return names
 }
 
-// loadExtensions loads extensions from the configured path
+// loadExtensions loads out-of-process extensions from the configured path
 func (m *Manager) loadExtensions() error {
-	// In a real implementation, this would scan the extensions directory
-	// and dynamically load extension modules
-	// For now, this is a placeholder
+	entries, err := os.ReadDir(m.config.Path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(m.config.Path, entry.Name())
+		if err := m.loadDir(dir); err != nil && !errors.Is(err, os.ErrNotExist) {
+			m.logger.Error("Failed to load extension", "path", dir, "error", err)
+		}
+	}
+
 	return nil
 }
+
+// loadDir registers the extension described by the manifest in dir
+func (m *Manager) loadDir(dir string) error {
+	manifest, err := LoadManifest(dir)
+	if err != nil {
+		return err
+	}
+	return m.registerDir(dir, NewProcessExtension(manifest))
+}
+
+// registerDir registers an extension loaded from dir
+func (m *Manager) registerDir(dir string, extension Extension) error {
+	if err := m.RegisterExtension(extension); err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	m.dirs[dir] = extension.Name()
+	m.mutex.Unlock()
+
+	return nil
+}
+
+// reloadDir replaces the extension loaded from dir with the current contents
+// of its manifest. The manifest is loaded first, so a manifest that is
+// invalid mid-edit leaves the running extension in place. The old extension
+// is swapped out in one step: hook calls wait for the reload, and lookups of
+// extensions and APIs wait while the old extension's calls in flight drain,
+// for up to reloadDrainTimeout.
+func (m *Manager) reloadDir(dir string) error {
+	manifest, err := LoadManifest(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("kept the loaded extension: %w", err)
+	}
+
+	m.calls.Lock()
+	defer m.calls.Unlock()
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	name, loaded := m.dirs[dir]
+	old := m.extensions[name]
+	if loaded {
+		ctx, cancel := context.WithTimeout(m.ctx, reloadDrainTimeout)
+		err := m.unregister(ctx, name)
+		cancel()
+		if err != nil {
+			return err
+		}
+		delete(m.dirs, dir)
+	}
+
+	if manifest == nil {
+		if loaded {
+			m.logger.Info("Extension removed", "extension", name, "path", dir)
+		}
+		return nil
+	}
+
+	extension := NewProcessExtension(manifest)
+	if err := m.register(extension); err != nil {
+		if loaded {
+			m.restore(dir, old)
+		}
+		return err
+	}
+	m.dirs[dir] = extension.Name()
+
+	if err := extension.Start(m.ctx); err != nil {
+		return fmt.Errorf("failed to start extension %s: %w", extension.Name(), err)
+	}
+
+	m.logger.Info("Extension reloaded", "extension", extension.Name(), "version", extension.Version(), "path", dir)
+	return nil
+}
+
+// restore registers and starts again an extension whose replacement failed
+// to register. The mutex must be held.
+func (m *Manager) restore(dir string, extension Extension) {
+	if err := m.register(extension); err != nil {
+		m.logger.Error("Failed to restore extension", "extension", extension.Name(), "error", err)
+		return
+	}
+	m.dirs[dir] = extension.Name()
+	if err := extension.Start(m.ctx); err != nil {
+		m.logger.Error("Failed to restart extension", "extension", extension.Name(), "error", err)
+	}
+}
@@ -0,0 +1,238 @@
+package extensions
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jxlxx/civicrm/internal/config"
+	"github.com/jxlxx/civicrm/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeExtension writes a shell-script extension that answers every call with reply
+func writeExtension(t *testing.T, dir, name, version, reply string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+
+	manifest := `{"name": "` + name + `", "version": "` + version + `", "command": "run.sh",
+		"hooks": [{"name": "contact.pre_create", "priority": 1}],
+		"apis": [{"name": "` + name + `", "version": "` + version + `", "methods": ["ping"]}]}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ManifestFile), []byte(manifest), 0o644))
+
+	script := "#!/bin/sh\ncat > /dev/null\necho '{\"data\": \"" + reply + "\"}'\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "run.sh"), []byte(script), 0o755))
+}
+
+func newTestManager(t *testing.T, path string) *Manager {
+	t.Helper()
+	manager, err := New(&config.ExtensionsConfig{Path: path, AutoLoad: true}, logger.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(func() { manager.Stop() })
+	return manager
+}
+
+func TestLoadManifest(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "greeter")
+	writeExtension(t, dir, "greeter", "1.0.0", "hello")
+
+	manifest, err := LoadManifest(dir)
+	require.NoError(t, err)
+	assert.Equal(t, "greeter", manifest.Name)
+	assert.Equal(t, "1.0.0", manifest.Version)
+	assert.Len(t, manifest.Hooks, 1)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ManifestFile), []byte(`{"name": "greeter"}`), 0o644))
+	_, err = LoadManifest(dir)
+	assert.Error(t, err)
+}
+
+func TestProcessExtensionHooksAndAPIs(t *testing.T) {
+	root := t.TempDir()
+	writeExtension(t, filepath.Join(root, "greeter"), "greeter", "1.0.0", "hello")
+
+	manager := newTestManager(t, root)
+	assert.Equal(t, []string{"greeter"}, manager.ListExtensions())
+
+	result, err := manager.ExecuteHook(context.Background(), "contact.pre_create", map[string]interface{}{"id": 1})
+	require.NoError(t, err)
+	assert.Equal(t, "hello", result)
+
+	api, ok := manager.GetAPIService("greeter")
+	require.True(t, ok)
+	result, err = api.Execute(context.Background(), "ping", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", result)
+}
+
+func TestReloadDir(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "greeter")
+	writeExtension(t, dir, "greeter", "1.0.0", "hello")
+
+	manager := newTestManager(t, root)
+
+	// A changed manifest and command replace the loaded extension
+	writeExtension(t, dir, "greeter", "1.1.0", "bonjour")
+	require.NoError(t, manager.reloadDir(dir))
+
+	extension, ok := manager.GetExtension("greeter")
+	require.True(t, ok)
+	assert.Equal(t, "1.1.0", extension.Version())
+
+	result, err := manager.ExecuteHook(context.Background(), "contact.pre_create", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "bonjour", result)
+
+	manager.mutex.RLock()
+	assert.Len(t, manager.hooks["contact.pre_create"], 1)
+	manager.mutex.RUnlock()
+
+	// A manifest that is invalid mid-edit keeps the running extension
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ManifestFile), []byte(`{"name": "gree`), 0o644))
+	assert.Error(t, manager.reloadDir(dir))
+	extension, ok = manager.GetExtension("greeter")
+	require.True(t, ok)
+	assert.Equal(t, "1.1.0", extension.Version())
+	result, err = manager.ExecuteHook(context.Background(), "contact.pre_create", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "bonjour", result)
+
+	// Removing the directory unregisters the extension
+	require.NoError(t, os.RemoveAll(dir))
+	require.NoError(t, manager.reloadDir(dir))
+	assert.Empty(t, manager.ListExtensions())
+	_, ok = manager.GetAPIService("greeter")
+	assert.False(t, ok)
+}
+
+// TestWatcherReloads tests that file changes picked up by the watcher reload
+// an extension in development mode
+func TestWatcherReloads(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "greeter")
+	writeExtension(t, dir, "greeter", "1.0.0", "hello")
+
+	manager, err := New(&config.ExtensionsConfig{Path: root, AutoLoad: true, Development: true}, logger.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(func() { manager.Stop() })
+	require.NoError(t, manager.Start())
+	require.NotNil(t, manager.watcher)
+
+	version := func() string {
+		extension, ok := manager.GetExtension("greeter")
+		if !ok {
+			return ""
+		}
+		return extension.Version()
+	}
+
+	writeExtension(t, dir, "greeter", "1.1.0", "bonjour")
+	require.Eventually(t, func() bool { return version() == "1.1.0" }, 5*time.Second, 50*time.Millisecond)
+
+	// A broken save is ignored until the manifest is valid again
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ManifestFile), []byte(`{`), 0o644))
+	time.Sleep(2 * reloadDelay)
+	assert.Equal(t, "1.1.0", version())
+
+	writeExtension(t, dir, "greeter", "1.2.0", "hola")
+	require.Eventually(t, func() bool { return version() == "1.2.0" }, 5*time.Second, 50*time.Millisecond)
+	result, err := manager.ExecuteHook(context.Background(), "contact.pre_create", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "hola", result)
+
+	// A new extension directory is watched and loaded
+	writeExtension(t, filepath.Join(root, "farewell"), "farewell", "1.0.0", "bye")
+	require.Eventually(t, func() bool {
+		_, ok := manager.GetExtension("farewell")
+		return ok
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestRegisterExtensionRejectsDuplicates(t *testing.T) {
+	root := t.TempDir()
+	writeExtension(t, filepath.Join(root, "greeter"), "greeter", "1.0.0", "hello")
+	manager := newTestManager(t, root)
+
+	// The same name in another directory does not replace the loaded extension
+	dir := filepath.Join(t.TempDir(), "greeter")
+	writeExtension(t, dir, "greeter", "2.0.0", "bonjour")
+	manifest, err := LoadManifest(dir)
+	require.NoError(t, err)
+	assert.Error(t, manager.RegisterExtension(NewProcessExtension(manifest)))
+
+	extension, ok := manager.GetExtension("greeter")
+	require.True(t, ok)
+	assert.Equal(t, "1.0.0", extension.Version())
+
+	// Neither does an API of the same name
+	manifest.Name = "impostor"
+	assert.Error(t, manager.RegisterExtension(NewProcessExtension(manifest)))
+	assert.Equal(t, []string{"greeter"}, manager.ListExtensions())
+
+	api, ok := manager.GetAPIService("greeter")
+	require.True(t, ok)
+	result, err := api.Execute(context.Background(), "ping", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", result)
+}
+
+// TestReloadDrainsCalls tests that a reload waits for calls to the old
+// extension to finish before replacing it
+func TestReloadDrainsCalls(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "slow")
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	writeManifest := func(version string) {
+		manifest := `{"name": "slow", "version": "` + version + `", "command": "run.sh",
+			"apis": [{"name": "slow", "version": "` + version + `", "methods": ["wait"]}]}`
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ManifestFile), []byte(manifest), 0o644))
+	}
+	writeManifest("1.0.0")
+	// The command answers once the test creates the release file
+	script := "#!/bin/sh\ncat > /dev/null\ntouch started\nwhile [ ! -f release ]; do sleep 0.01; done\necho '{\"data\": \"done\"}'\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "run.sh"), []byte(script), 0o755))
+
+	manager := newTestManager(t, root)
+	api, ok := manager.GetAPIService("slow")
+	require.True(t, ok)
+
+	type response struct {
+		result interface{}
+		err    error
+	}
+	called := make(chan response, 1)
+	go func() {
+		result, err := api.Execute(context.Background(), "wait", nil)
+		called <- response{result, err}
+	}()
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(filepath.Join(dir, "started"))
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	writeManifest("1.1.0")
+	reloaded := make(chan error, 1)
+	go func() { reloaded <- manager.reloadDir(dir) }()
+
+	select {
+	case err := <-reloaded:
+		t.Fatalf("reload finished with a call in flight: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "release"), nil, 0o644))
+	call := <-called
+	require.NoError(t, call.err, "the call in flight completes")
+	assert.Equal(t, "done", call.result)
+	require.NoError(t, <-reloaded)
+
+	extension, ok := manager.GetExtension("slow")
+	require.True(t, ok)
+	assert.Equal(t, "1.1.0", extension.Version())
+	_, err := api.Execute(context.Background(), "wait", nil)
+	assert.Error(t, err, "the old extension takes no new calls")
+}
@@ -0,0 +1,246 @@
+package extensions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+)
+
+// ManifestFile is the name of the manifest expected in each extension directory
+const ManifestFile = "extension.json"
+
+// Manifest describes an out-of-process extension on disk
+type Manifest struct {
+	Name        string            `json:"name"`
+	Version     string            `json:"version"`
+	Description string            `json:"description"`
+	Command     string            `json:"command"`
+	Args        []string          `json:"args"`
+	Hooks       []ManifestHook    `json:"hooks"`
+	APIs        []ManifestAPI     `json:"apis"`
+	Env         map[string]string `json:"env"`
+	dir         string
+}
+
+// ManifestHook declares a hook point handled by an out-of-process extension
+type ManifestHook struct {
+	Name     string `json:"name"`
+	Priority int    `json:"priority"`
+}
+
+// ManifestAPI declares an API service provided by an out-of-process extension
+type ManifestAPI struct {
+	Name    string   `json:"name"`
+	Version string   `json:"version"`
+	Methods []string `json:"methods"`
+}
+
+// LoadManifest reads and validates the manifest in the given extension directory
+func LoadManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	if manifest.Name == "" {
+		return nil, fmt.Errorf("manifest in %s has no name", dir)
+	}
+	if manifest.Command == "" {
+		return nil, fmt.Errorf("manifest for extension %s has no command", manifest.Name)
+	}
+
+	manifest.dir = dir
+	return &manifest, nil
+}
+
+// processRequest is the JSON document written to an extension's stdin
+type processRequest struct {
+	Type   string                 `json:"type"`
+	Name   string                 `json:"name"`
+	Method string                 `json:"method,omitempty"`
+	Data   interface{}            `json:"data,omitempty"`
+	Params map[string]interface{} `json:"params,omitempty"`
+}
+
+// processResponse is the JSON document read from an extension's stdout
+type processResponse struct {
+	Data  interface{} `json:"data"`
+	Error string      `json:"error"`
+}
+
+// ProcessExtension is an extension that runs as a separate executable.
+// Each hook or API call starts the command, writes a JSON request to its
+// stdin and reads a JSON response from its stdout.
+type ProcessExtension struct {
+	manifest *Manifest
+
+	mutex   sync.Mutex
+	calls   sync.WaitGroup
+	stopped bool
+}
+
+// NewProcessExtension creates an extension backed by the manifest's command
+func NewProcessExtension(manifest *Manifest) *ProcessExtension {
+	return &ProcessExtension{manifest: manifest}
+}
+
+// Name returns the extension name
+func (e *ProcessExtension) Name() string {
+	return e.manifest.Name
+}
+
+// Version returns the extension version
+func (e *ProcessExtension) Version() string {
+	return e.manifest.Version
+}
+
+// Dir returns the directory the extension was loaded from
+func (e *ProcessExtension) Dir() string {
+	return e.manifest.dir
+}
+
+// Initialize checks that the extension command is present
+func (e *ProcessExtension) Initialize(ctx context.Context) error {
+	if _, err := exec.LookPath(e.commandPath()); err != nil {
+		return fmt.Errorf("extension command not found: %w", err)
+	}
+	return nil
+}
+
+// RegisterHooks returns the hooks declared in the manifest
+func (e *ProcessExtension) RegisterHooks() []Hook {
+	hooks := make([]Hook, 0, len(e.manifest.Hooks))
+	for _, h := range e.manifest.Hooks {
+		name := h.Name
+		hooks = append(hooks, Hook{
+			Name:     name,
+			Priority: h.Priority,
+			Handler: func(ctx context.Context, data interface{}) (interface{}, error) {
+				return e.call(ctx, processRequest{Type: "hook", Name: name, Data: data})
+			},
+		})
+	}
+	return hooks
+}
+
+// RegisterAPIs returns the API services declared in the manifest
+func (e *ProcessExtension) RegisterAPIs() []APIService {
+	apis := make([]APIService, 0, len(e.manifest.APIs))
+	for _, api := range e.manifest.APIs {
+		apis = append(apis, &processAPIService{extension: e, api: api})
+	}
+	return apis
+}
+
+// Start accepts calls again after Stop; the extension process is started
+// per call
+func (e *ProcessExtension) Start(ctx context.Context) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.stopped = false
+	return nil
+}
+
+// Stop rejects new calls and waits until the calls in flight have finished
+// or ctx is done
+func (e *ProcessExtension) Stop(ctx context.Context) error {
+	e.mutex.Lock()
+	e.stopped = true
+	e.mutex.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		e.calls.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("calls to extension %s still running: %w", e.manifest.Name, ctx.Err())
+	}
+}
+
+// commandPath resolves the command relative to the extension directory
+func (e *ProcessExtension) commandPath() string {
+	if filepath.IsAbs(e.manifest.Command) {
+		return e.manifest.Command
+	}
+	return filepath.Join(e.manifest.dir, e.manifest.Command)
+}
+
+// call runs the extension command with a single request
+func (e *ProcessExtension) call(ctx context.Context, req processRequest) (interface{}, error) {
+	e.mutex.Lock()
+	if e.stopped {
+		e.mutex.Unlock()
+		return nil, fmt.Errorf("extension %s is stopped", e.manifest.Name)
+	}
+	e.calls.Add(1)
+	e.mutex.Unlock()
+	defer e.calls.Done()
+
+	input, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, e.commandPath(), e.manifest.Args...)
+	cmd.Dir = e.manifest.dir
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Env = os.Environ()
+	for k, v := range e.manifest.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("extension %s failed: %w: %s", e.manifest.Name, err, stderr.String())
+	}
+
+	var resp processResponse
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return nil, fmt.Errorf("extension %s returned invalid response: %w", e.manifest.Name, err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("extension %s: %s", e.manifest.Name, resp.Error)
+	}
+
+	return resp.Data, nil
+}
+
+// processAPIService exposes a manifest API through the APIService interface
+type processAPIService struct {
+	extension *ProcessExtension
+	api       ManifestAPI
+}
+
+func (s *processAPIService) Name() string {
+	return s.api.Name
+}
+
+func (s *processAPIService) Version() string {
+	return s.api.Version
+}
+
+func (s *processAPIService) Methods() []string {
+	return s.api.Methods
+}
+
+func (s *processAPIService) Execute(ctx context.Context, method string, params map[string]interface{}) (interface{}, error) {
+	return s.extension.call(ctx, processRequest{Type: "api", Name: s.api.Name, Method: method, Params: params})
+}
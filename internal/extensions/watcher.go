@@ -0,0 +1,133 @@
+package extensions
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// reloadDelay collapses bursts of file events (editors, builds) into one reload
+const reloadDelay = 250 * time.Millisecond
+
+// watcher reloads extensions when files in the extensions directory change
+type watcher struct {
+	manager *Manager
+	fs      *fsnotify.Watcher
+	root    string
+	timers  map[string]*time.Timer
+	mutex   sync.Mutex
+}
+
+// newWatcher watches the extensions directory and each extension in it
+func newWatcher(manager *Manager) (*watcher, error) {
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	root := filepath.Clean(manager.config.Path)
+	if err := fsWatcher.Add(root); err != nil {
+		fsWatcher.Close()
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		fsWatcher.Close()
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			if err := fsWatcher.Add(filepath.Join(root, entry.Name())); err != nil {
+				manager.logger.Warn("Failed to watch extension directory", "path", entry.Name(), "error", err)
+			}
+		}
+	}
+
+	return &watcher{
+		manager: manager,
+		fs:      fsWatcher,
+		root:    root,
+		timers:  make(map[string]*time.Timer),
+	}, nil
+}
+
+// run processes file events until the context is cancelled or the watcher is closed
+func (w *watcher) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-w.fs.Events:
+			if !ok {
+				return
+			}
+			w.handle(event)
+		case err, ok := <-w.fs.Errors:
+			if !ok {
+				return
+			}
+			w.manager.logger.Error("Extensions watcher error", "error", err)
+		}
+	}
+}
+
+// handle maps a file event to the extension directory it belongs to
+func (w *watcher) handle(event fsnotify.Event) {
+	path := filepath.Clean(event.Name)
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return
+	}
+
+	dir := filepath.Join(w.root, strings.Split(rel, string(filepath.Separator))[0])
+
+	// Start watching newly created extension directories
+	if event.Has(fsnotify.Create) && path == dir {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			if err := w.fs.Add(dir); err != nil {
+				w.manager.logger.Warn("Failed to watch extension directory", "path", dir, "error", err)
+			}
+		}
+	}
+
+	w.schedule(dir)
+}
+
+// schedule reloads dir once events for it have settled
+func (w *watcher) schedule(dir string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if timer, exists := w.timers[dir]; exists {
+		timer.Reset(reloadDelay)
+		return
+	}
+
+	w.timers[dir] = time.AfterFunc(reloadDelay, func() {
+		w.mutex.Lock()
+		delete(w.timers, dir)
+		w.mutex.Unlock()
+
+		if err := w.manager.reloadDir(dir); err != nil {
+			w.manager.logger.Error("Failed to reload extension", "path", dir, "error", err)
+		}
+	})
+}
+
+// close stops watching and cancels pending reloads
+func (w *watcher) close() error {
+	w.mutex.Lock()
+	for dir, timer := range w.timers {
+		timer.Stop()
+		delete(w.timers, dir)
+	}
+	w.mutex.Unlock()
+
+	return w.fs.Close()
+}
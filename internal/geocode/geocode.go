@@ -0,0 +1,254 @@
+// Package geocode looks up the coordinates of addresses. Geocoders are
+// pluggable: postal code centroids work offline, and a Nominatim-compatible
+// service finds street addresses. The geocode_addresses job fills in
+// addresses.geo_code_1 and geo_code_2 for new and changed addresses.
+package geocode
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/config"
+	"github.com/jxlxx/civicrm/internal/database"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/jxlxx/civicrm/internal/logger"
+)
+
+// JobGeocode is the scheduled job that geocodes new and changed addresses
+const JobGeocode = "geocode_addresses"
+
+var (
+	// ErrNotFound is returned when a geocoder cannot locate an address
+	ErrNotFound = errors.New("address not found")
+
+	// ErrDisabled is returned when no geocoder is configured
+	ErrDisabled = errors.New("geocoding is disabled")
+)
+
+// Address is what a geocoder looks up. Country is an ISO 3166-1 alpha-2
+// code.
+type Address struct {
+	Street     string
+	City       string
+	State      string
+	PostalCode string
+	Country    string
+}
+
+// empty reports whether there is nothing to look up
+func (a Address) empty() bool {
+	return a.Street == "" && a.City == "" && a.PostalCode == ""
+}
+
+// Point is a latitude and longitude in degrees
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// Geocoder finds the coordinates of an address. It returns ErrNotFound when
+// the address cannot be located, and other errors when the lookup failed
+// and may be retried.
+type Geocoder interface {
+	Geocode(ctx context.Context, address Address) (Point, error)
+}
+
+// Chain tries geocoders in order until one locates the address
+type Chain []Geocoder
+
+// Geocode returns the point of the first geocoder to locate the address
+func (c Chain) Geocode(ctx context.Context, address Address) (Point, error) {
+	if len(c) == 0 {
+		return Point{}, ErrDisabled
+	}
+	for _, geocoder := range c {
+		point, err := geocoder.Geocode(ctx, address)
+		if !errors.Is(err, ErrNotFound) {
+			return point, err
+		}
+	}
+	return Point{}, ErrNotFound
+}
+
+// NewGeocoder creates the chain of configured geocoders
+func NewGeocoder(config *config.GeocodingConfig) (Chain, error) {
+	chain := make(Chain, 0, len(config.Providers))
+	for _, provider := range config.Providers {
+		switch provider {
+		case "postcode":
+			codes, err := loadPostalCodes(config.PostalCodesFile)
+			if err != nil {
+				return nil, err
+			}
+			chain = append(chain, codes)
+		case "nominatim":
+			chain = append(chain, NewNominatim(config))
+		default:
+			return nil, fmt.Errorf("unknown geocoding provider %q", provider)
+		}
+	}
+	return chain, nil
+}
+
+// loadPostalCodes reads a GeoNames postal code file, or the bundled sample
+// when path is empty. A zip file, as downloaded from GeoNames, is read from
+// the list inside it.
+func loadPostalCodes(path string) (*PostalCodes, error) {
+	if path == "" {
+		return BundledPostalCodes()
+	}
+	if strings.EqualFold(filepath.Ext(path), ".zip") {
+		return loadZippedPostalCodes(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open postal codes file: %w", err)
+	}
+	defer f.Close()
+	return LoadPostalCodes(f)
+}
+
+// loadZippedPostalCodes reads the first text file of a zip other than its
+// readme, such as allCountries.txt of allCountries.zip
+func loadZippedPostalCodes(path string) (*PostalCodes, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open postal codes file: %w", err)
+	}
+	defer archive.Close()
+
+	for _, file := range archive.File {
+		name := filepath.Base(file.Name)
+		if !strings.EqualFold(filepath.Ext(name), ".txt") || strings.EqualFold(name, "readme.txt") {
+			continue
+		}
+		f, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s in postal codes file: %w", file.Name, err)
+		}
+		defer f.Close()
+		return LoadPostalCodes(f)
+	}
+	return nil, fmt.Errorf("postal codes file %s has no postal code list", path)
+}
+
+// Service geocodes stored addresses
+type Service struct {
+	queries  db.Querier
+	tx       database.Transactor
+	geocoder Geocoder
+	config   *config.GeocodingConfig
+	logger   *logger.Logger
+	now      func() time.Time
+}
+
+// New creates a geocoding service with the configured geocoders
+func New(database *database.Database, config *config.GeocodingConfig, logger *logger.Logger) (*Service, error) {
+	geocoder, err := NewGeocoder(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Service{
+		queries:  database.ReadQuerier(),
+		tx:       database,
+		geocoder: geocoder,
+		config:   config,
+		logger:   logger,
+		now:      time.Now,
+	}, nil
+}
+
+// Geocode looks up an address with the configured geocoders
+func (s *Service) Geocode(ctx context.Context, address Address) (Point, error) {
+	return s.geocoder.Geocode(ctx, address)
+}
+
+// GeocodePending looks up a batch of a domain's addresses that were added
+// or changed since they were last looked up. Addresses that cannot be
+// located are recorded as looked up without coordinates. A failed lookup
+// ends the batch, leaving the rest for the next run.
+func (s *Service) GeocodePending(ctx context.Context, domainID uuid.UUID) (located, notFound int, err error) {
+	addresses, err := s.queries.ListAddressesToGeocode(ctx, db.ListAddressesToGeocodeParams{
+		DomainID:  domainID,
+		BatchSize: int32(s.config.BatchSize),
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to list addresses to geocode: %w", err)
+	}
+
+	for _, row := range addresses {
+		address := Address{
+			Street:     row.StreetAddress.String,
+			City:       row.City.String,
+			State:      row.StateProvince.String,
+			PostalCode: row.PostalCode.String,
+			Country:    row.CountryCode.String,
+		}
+
+		var point Point
+		found := false
+		if !address.empty() {
+			point, err = s.geocoder.Geocode(ctx, address)
+			switch {
+			case err == nil:
+				found = true
+			case errors.Is(err, ErrNotFound):
+				s.logger.Debug("Address not found by geocoder", "address_id", row.ID)
+			default:
+				return located, notFound, fmt.Errorf("failed to geocode address %s: %w", row.ID, err)
+			}
+		}
+
+		update := db.SetAddressGeoCodeParams{
+			ID:         row.ID,
+			UpdatedAt:  row.UpdatedAt,
+			GeocodedAt: sql.NullTime{Time: s.now(), Valid: true},
+		}
+		if found {
+			update.GeoCode1 = sql.NullString{String: formatDegrees(point.Lat), Valid: true}
+			update.GeoCode2 = sql.NullString{String: formatDegrees(point.Lon), Valid: true}
+		}
+		err = s.tx.WithTx(ctx, func(q db.Querier) error {
+			if _, err := q.SetAddressGeoCode(ctx, update); err != nil {
+				return fmt.Errorf("failed to set address geo code: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return located, notFound, err
+		}
+
+		if found {
+			located++
+		} else {
+			notFound++
+		}
+	}
+	return located, notFound, nil
+}
+
+// GeocodeJob is the handler of the geocode_addresses job
+func (s *Service) GeocodeJob(ctx context.Context, job db.Job) (string, error) {
+	if chain, ok := s.geocoder.(Chain); ok && len(chain) == 0 {
+		return "geocoding is disabled", nil
+	}
+	located, notFound, err := s.GeocodePending(ctx, job.DomainID)
+	if err != nil {
+		return "", fmt.Errorf("located %d addresses, %d not found: %w", located, notFound, err)
+	}
+	return fmt.Sprintf("located %d addresses, %d not found", located, notFound), nil
+}
+
+// formatDegrees formats a coordinate for the DECIMAL geo_code columns
+func formatDegrees(degrees float64) string {
+	return strconv.FormatFloat(degrees, 'f', 8, 64)
+}
@@ -0,0 +1,137 @@
+package geocode
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/config"
+	"github.com/jxlxx/civicrm/internal/database/dbtest"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/jxlxx/civicrm/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeQuerier lists addresses to geocode and records the geo codes set
+type fakeQuerier struct {
+	db.Querier
+	addresses []db.ListAddressesToGeocodeRow
+	listed    db.ListAddressesToGeocodeParams
+	set       []db.SetAddressGeoCodeParams
+}
+
+func (q *fakeQuerier) ListAddressesToGeocode(ctx context.Context, arg db.ListAddressesToGeocodeParams) ([]db.ListAddressesToGeocodeRow, error) {
+	q.listed = arg
+	return q.addresses, nil
+}
+
+func (q *fakeQuerier) SetAddressGeoCode(ctx context.Context, arg db.SetAddressGeoCodeParams) (int64, error) {
+	q.set = append(q.set, arg)
+	return 1, nil
+}
+
+// geocoderFunc adapts a function to Geocoder
+type geocoderFunc func(ctx context.Context, address Address) (Point, error)
+
+func (f geocoderFunc) Geocode(ctx context.Context, address Address) (Point, error) {
+	return f(ctx, address)
+}
+
+var now = time.Date(2024, 5, 15, 12, 0, 0, 0, time.UTC)
+
+func newService(q *fakeQuerier, geocoder Geocoder) *Service {
+	return &Service{
+		queries:  q,
+		tx:       dbtest.Tx{Q: q},
+		geocoder: geocoder,
+		config:   &config.GeocodingConfig{BatchSize: 50},
+		logger:   logger.NewNop(),
+		now:      func() time.Time { return now },
+	}
+}
+
+func TestGeocodePending(t *testing.T) {
+	updated := dbtest.Time(now.Add(-time.Hour))
+	located := db.ListAddressesToGeocodeRow{ID: uuid.New(), UpdatedAt: updated, City: dbtest.Text("Toronto"), PostalCode: dbtest.Text("M5V 3L9"), StateProvince: dbtest.Text("Ontario"), CountryCode: dbtest.Text("CA")}
+	unknown := db.ListAddressesToGeocodeRow{ID: uuid.New(), UpdatedAt: updated, City: dbtest.Text("Atlantis")}
+	blank := db.ListAddressesToGeocodeRow{ID: uuid.New(), UpdatedAt: updated, CountryCode: dbtest.Text("CA")}
+	q := &fakeQuerier{addresses: []db.ListAddressesToGeocodeRow{located, unknown, blank}}
+
+	var looked []Address
+	service := newService(q, geocoderFunc(func(ctx context.Context, address Address) (Point, error) {
+		looked = append(looked, address)
+		if address.City == "Toronto" {
+			return Point{Lat: 43.6426, Lon: -79.3871}, nil
+		}
+		return Point{}, ErrNotFound
+	}))
+	domainID := uuid.New()
+
+	found, notFound, err := service.GeocodePending(context.Background(), domainID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, found)
+	assert.Equal(t, 2, notFound)
+	assert.Equal(t, db.ListAddressesToGeocodeParams{DomainID: domainID, BatchSize: 50}, q.listed)
+
+	assert.Equal(t, []Address{
+		{City: "Toronto", State: "Ontario", PostalCode: "M5V 3L9", Country: "CA"},
+		{City: "Atlantis"},
+	}, looked, "addresses with nothing to look up are skipped")
+
+	require.Len(t, q.set, 3)
+	assert.Equal(t, db.SetAddressGeoCodeParams{
+		ID:         located.ID,
+		UpdatedAt:  updated,
+		GeocodedAt: dbtest.Time(now),
+		GeoCode1:   dbtest.Text("43.64260000"),
+		GeoCode2:   dbtest.Text("-79.38710000"),
+	}, q.set[0])
+	for _, set := range q.set[1:] {
+		assert.False(t, set.GeoCode1.Valid)
+		assert.True(t, set.GeocodedAt.Valid, "addresses not found are not looked up again")
+	}
+}
+
+func TestGeocodePendingStopsOnFailure(t *testing.T) {
+	q := &fakeQuerier{addresses: []db.ListAddressesToGeocodeRow{
+		{ID: uuid.New(), City: dbtest.Text("London")},
+		{ID: uuid.New(), City: dbtest.Text("Paris")},
+	}}
+	calls := 0
+	service := newService(q, geocoderFunc(func(ctx context.Context, address Address) (Point, error) {
+		calls++
+		return Point{}, errors.New("service unavailable")
+	}))
+
+	_, err := service.GeocodeJob(context.Background(), db.Job{DomainID: uuid.New()})
+	assert.ErrorContains(t, err, "service unavailable")
+	assert.Equal(t, 1, calls)
+	assert.Empty(t, q.set, "the address is left for the next run")
+}
+
+func TestGeocodeJobDisabled(t *testing.T) {
+	q := &fakeQuerier{addresses: []db.ListAddressesToGeocodeRow{{ID: uuid.New(), City: dbtest.Text("London")}}}
+	service := newService(q, Chain{})
+
+	message, err := service.GeocodeJob(context.Background(), db.Job{})
+	require.NoError(t, err)
+	assert.Equal(t, "geocoding is disabled", message)
+	assert.Empty(t, q.set)
+}
+
+func TestChain(t *testing.T) {
+	notFound := geocoderFunc(func(ctx context.Context, address Address) (Point, error) { return Point{}, ErrNotFound })
+	found := geocoderFunc(func(ctx context.Context, address Address) (Point, error) { return Point{Lat: 1, Lon: 2}, nil })
+
+	point, err := Chain{notFound, found}.Geocode(context.Background(), Address{City: "London"})
+	require.NoError(t, err)
+	assert.Equal(t, Point{Lat: 1, Lon: 2}, point)
+
+	_, err = Chain{notFound}.Geocode(context.Background(), Address{City: "London"})
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = Chain{}.Geocode(context.Background(), Address{City: "London"})
+	assert.ErrorIs(t, err, ErrDisabled)
+}
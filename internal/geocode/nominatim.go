@@ -0,0 +1,125 @@
+package geocode
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jxlxx/civicrm/internal/config"
+)
+
+// Nominatim locates addresses with the search API of a Nominatim-compatible
+// service. Requests are spaced by the configured interval, as the public
+// OpenStreetMap service allows one per second.
+type Nominatim struct {
+	baseURL   string
+	userAgent string
+	email     string
+	interval  time.Duration
+	client    *http.Client
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// NewNominatim creates a Nominatim geocoder
+func NewNominatim(config *config.GeocodingConfig) *Nominatim {
+	return &Nominatim{
+		baseURL:   strings.TrimRight(config.NominatimURL, "/"),
+		userAgent: config.UserAgent,
+		email:     config.Email,
+		interval:  config.RequestInterval,
+		client:    &http.Client{Timeout: config.Timeout},
+	}
+}
+
+// nominatimPlace is one result of a search. Coordinates are strings in the
+// JSON.
+type nominatimPlace struct {
+	Lat string `json:"lat"`
+	Lon string `json:"lon"`
+}
+
+// Geocode searches for the address as a structured query and returns the
+// best match
+func (n *Nominatim) Geocode(ctx context.Context, address Address) (Point, error) {
+	if address.empty() {
+		return Point{}, ErrNotFound
+	}
+
+	query := url.Values{}
+	query.Set("format", "jsonv2")
+	query.Set("limit", "1")
+	for name, value := range map[string]string{
+		"street":       address.Street,
+		"city":         address.City,
+		"state":        address.State,
+		"postalcode":   address.PostalCode,
+		"countrycodes": strings.ToLower(address.Country),
+		"email":        n.email,
+	} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, n.baseURL+"/search?"+query.Encode(), nil)
+	if err != nil {
+		return Point{}, fmt.Errorf("failed to build geocoding request: %w", err)
+	}
+	req.Header.Set("User-Agent", n.userAgent)
+	req.Header.Set("Accept", "application/json")
+
+	if err := n.wait(ctx); err != nil {
+		return Point{}, err
+	}
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return Point{}, fmt.Errorf("failed to call geocoding service: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Point{}, fmt.Errorf("geocoding service returned %s", resp.Status)
+	}
+
+	var places []nominatimPlace
+	if err := json.NewDecoder(resp.Body).Decode(&places); err != nil {
+		return Point{}, fmt.Errorf("failed to decode geocoding response: %w", err)
+	}
+	if len(places) == 0 {
+		return Point{}, ErrNotFound
+	}
+	lat, err := strconv.ParseFloat(places[0].Lat, 64)
+	if err != nil {
+		return Point{}, fmt.Errorf("geocoding service returned latitude %q", places[0].Lat)
+	}
+	lon, err := strconv.ParseFloat(places[0].Lon, 64)
+	if err != nil {
+		return Point{}, fmt.Errorf("geocoding service returned longitude %q", places[0].Lon)
+	}
+	return Point{Lat: lat, Lon: lon}, nil
+}
+
+// wait blocks until the request interval has passed since the last request
+func (n *Nominatim) wait(ctx context.Context) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if delay := n.interval - time.Since(n.last); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	n.last = time.Now()
+	return nil
+}
@@ -0,0 +1,94 @@
+package geocode
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/jxlxx/civicrm/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newStub returns a Nominatim geocoder calling a local stub, and the
+// queries the stub received
+func newStub(t *testing.T, handler func(w http.ResponseWriter, query url.Values)) (*Nominatim, *[]url.Values) {
+	var queries []url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/search", r.URL.Path)
+		assert.Equal(t, "civicrm-test", r.Header.Get("User-Agent"))
+		queries = append(queries, r.URL.Query())
+		handler(w, r.URL.Query())
+	}))
+	t.Cleanup(server.Close)
+
+	return NewNominatim(&config.GeocodingConfig{
+		NominatimURL: server.URL + "/",
+		UserAgent:    "civicrm-test",
+		Email:        "admin@example.org",
+		Timeout:      time.Second,
+	}), &queries
+}
+
+func TestNominatim(t *testing.T) {
+	nominatim, queries := newStub(t, func(w http.ResponseWriter, query url.Values) {
+		if query.Get("city") == "Atlantis" {
+			w.Write([]byte(`[]`))
+			return
+		}
+		w.Write([]byte(`[{"place_id": 1, "lat": "51.5010091", "lon": "-0.1415972", "display_name": "Buckingham Palace"}]`))
+	})
+
+	point, err := nominatim.Geocode(context.Background(), Address{
+		Street: "Buckingham Palace", City: "London", PostalCode: "SW1A 1AA", Country: "GB",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Point{Lat: 51.5010091, Lon: -0.1415972}, point)
+
+	require.Len(t, *queries, 1)
+	query := (*queries)[0]
+	assert.Equal(t, "jsonv2", query.Get("format"))
+	assert.Equal(t, "Buckingham Palace", query.Get("street"))
+	assert.Equal(t, "SW1A 1AA", query.Get("postalcode"))
+	assert.Equal(t, "gb", query.Get("countrycodes"))
+	assert.Equal(t, "admin@example.org", query.Get("email"))
+	assert.False(t, query.Has("state"), "empty fields are left out")
+
+	_, err = nominatim.Geocode(context.Background(), Address{City: "Atlantis"})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestNominatimErrors(t *testing.T) {
+	nominatim, _ := newStub(t, func(w http.ResponseWriter, query url.Values) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	_, err := nominatim.Geocode(context.Background(), Address{City: "London"})
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrNotFound, "failed lookups are retried")
+
+	_, err = nominatim.Geocode(context.Background(), Address{Country: "GB"})
+	assert.ErrorIs(t, err, ErrNotFound, "an address without street, city or postal code is not looked up")
+}
+
+func TestNominatimSpacesRequests(t *testing.T) {
+	nominatim, _ := newStub(t, func(w http.ResponseWriter, query url.Values) {
+		w.Write([]byte(`[{"lat": "1", "lon": "2"}]`))
+	})
+	nominatim.interval = 50 * time.Millisecond
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := nominatim.Geocode(context.Background(), Address{City: "London"})
+		require.NoError(t, err)
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := nominatim.Geocode(ctx, Address{City: "London"})
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,166 @@
+package geocode
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	_ "embed"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// bundledPostalCodes is a sample of 26 city centre postal codes in the
+// GeoNames format, enough to try proximity searches without a download.
+// Production servers should set geocoding.postal_codes_file to a GeoNames
+// file covering their countries.
+//
+//go:embed data/postal_codes.txt
+var bundledPostalCodes []byte
+
+// PostalCodes locates addresses at the centroid of their postal code,
+// without calling out of the server
+type PostalCodes struct {
+	// points holds centroids by country and normalized postal code
+	points map[string]map[string]Point
+}
+
+// BundledPostalCodes returns the sample centroids bundled with the server
+func BundledPostalCodes() (*PostalCodes, error) {
+	return LoadPostalCodes(bytes.NewReader(bundledPostalCodes))
+}
+
+// LoadPostalCodes reads centroids in the tab-separated format of the
+// GeoNames postal code files: country code, postal code, place name, three
+// pairs of admin names and codes, latitude, longitude and accuracy. A code
+// listed for several places is located at their average.
+func LoadPostalCodes(r io.Reader) (*PostalCodes, error) {
+	type sum struct {
+		lat, lon float64
+		n        int
+	}
+	sums := make(map[string]map[string]*sum)
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		fields := strings.Split(text, "\t")
+		if len(fields) < 11 {
+			return nil, fmt.Errorf("postal codes line %d: expected 11 or more columns, got %d", line, len(fields))
+		}
+		lat, err := strconv.ParseFloat(fields[9], 64)
+		if err != nil {
+			return nil, fmt.Errorf("postal codes line %d: bad latitude %q", line, fields[9])
+		}
+		lon, err := strconv.ParseFloat(fields[10], 64)
+		if err != nil {
+			return nil, fmt.Errorf("postal codes line %d: bad longitude %q", line, fields[10])
+		}
+
+		country, code := strings.ToUpper(fields[0]), normalizePostalCode(fields[1])
+		if sums[country] == nil {
+			sums[country] = make(map[string]*sum)
+		}
+		s := sums[country][code]
+		if s == nil {
+			s = &sum{}
+			sums[country][code] = s
+		}
+		s.lat, s.lon, s.n = s.lat+lat, s.lon+lon, s.n+1
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read postal codes: %w", err)
+	}
+
+	codes := &PostalCodes{points: make(map[string]map[string]Point, len(sums))}
+	for country, byCode := range sums {
+		codes.points[country] = make(map[string]Point, len(byCode))
+		for code, s := range byCode {
+			codes.points[country][code] = Point{Lat: s.lat / float64(s.n), Lon: s.lon / float64(s.n)}
+		}
+	}
+	return codes, nil
+}
+
+// Geocode returns the centroid of the address's postal code. Without a
+// country, the code must be known in only one country.
+func (p *PostalCodes) Geocode(ctx context.Context, address Address) (Point, error) {
+	if address.PostalCode == "" {
+		return Point{}, ErrNotFound
+	}
+
+	countries := []string{strings.ToUpper(address.Country)}
+	if address.Country == "" {
+		countries = countries[:0]
+		for country := range p.points {
+			countries = append(countries, country)
+		}
+	}
+
+	var found []Point
+	for _, country := range countries {
+		if point, ok := p.lookup(country, address.PostalCode); ok {
+			found = append(found, point)
+		}
+	}
+	if len(found) != 1 {
+		return Point{}, ErrNotFound
+	}
+	return found[0], nil
+}
+
+// lookup finds a postal code, falling back to the area it belongs to when
+// only areas are listed, as in the GeoNames files for Canada and the UK
+func (p *PostalCodes) lookup(country, postalCode string) (Point, bool) {
+	byCode := p.points[country]
+	if byCode == nil {
+		return Point{}, false
+	}
+	for _, code := range postalCodeAreas(country, postalCode) {
+		if point, ok := byCode[code]; ok {
+			return point, true
+		}
+	}
+	return Point{}, false
+}
+
+// postalCodeAreas returns a postal code and the codes of the areas that
+// contain it, most specific first
+func postalCodeAreas(country, postalCode string) []string {
+	code := normalizePostalCode(postalCode)
+	areas := []string{code}
+	if first, _, found := strings.Cut(strings.TrimSpace(postalCode), " "); found {
+		areas = append(areas, normalizePostalCode(first))
+	}
+	if first, _, found := strings.Cut(code, "-"); found {
+		areas = append(areas, first)
+	}
+
+	switch country {
+	case "CA":
+		// The forward sortation area, such as M5V of M5V 3L9
+		if len(code) > 3 {
+			areas = append(areas, code[:3])
+		}
+	case "GB":
+		// The outward code, such as SW1A of SW1A 1AA
+		if len(code) > 4 {
+			areas = append(areas, code[:len(code)-3])
+		}
+	case "US":
+		// The ZIP code of a ZIP+4 code
+		if len(code) > 5 {
+			areas = append(areas, code[:5])
+		}
+	}
+	return areas
+}
+
+// normalizePostalCode upper-cases a postal code and removes its spaces
+func normalizePostalCode(code string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(code), ""))
+}
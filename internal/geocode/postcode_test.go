@@ -0,0 +1,121 @@
+package geocode
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jxlxx/civicrm/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostalCodes(t *testing.T) {
+	codes, err := LoadPostalCodes(strings.NewReader(strings.Join([]string{
+		"US\t10001\tNew York\tNew York\tNY\t\t\t\t\t40.7506\t-73.9972\t4",
+		"CA\tM5V\tToronto\tOntario\tON\t\t\t\t\t43.6426\t-79.3871\t4",
+		"GB\tSW1A\tLondon\tEngland\tENG\t\t\t\t\t51.5010\t-0.1416\t4",
+		"FR\t75001\tParis 01\tIle-de-France\t11\t\t\t\t\t48.8600\t2.3400\t5",
+		"FR\t75001\tParis 01 Louvre\tIle-de-France\t11\t\t\t\t\t48.8700\t2.3300\t5",
+		"DE\t10001\tNowhere\t\t\t\t\t\t\t50\t10\t1",
+	}, "\n")))
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		address Address
+		want    Point
+	}{
+		{Address{PostalCode: "10001", Country: "US"}, Point{40.7506, -73.9972}},
+		{Address{PostalCode: "10001-2062", Country: "us"}, Point{40.7506, -73.9972}},
+		{Address{PostalCode: "m5v 3l9", Country: "CA"}, Point{43.6426, -79.3871}},
+		{Address{PostalCode: "M5V3L9", Country: "CA"}, Point{43.6426, -79.3871}},
+		{Address{PostalCode: "SW1A 1AA", Country: "GB"}, Point{51.5010, -0.1416}},
+		{Address{PostalCode: "SW1A1AA", Country: "GB"}, Point{51.5010, -0.1416}},
+		{Address{PostalCode: "75001", Country: "FR"}, Point{48.865, 2.335}},
+		{Address{PostalCode: "SW1A 1AA"}, Point{51.5010, -0.1416}},
+	} {
+		point, err := codes.Geocode(context.Background(), tc.address)
+		require.NoError(t, err, tc.address.PostalCode)
+		assert.InDelta(t, tc.want.Lat, point.Lat, 1e-9, tc.address.PostalCode)
+		assert.InDelta(t, tc.want.Lon, point.Lon, 1e-9, tc.address.PostalCode)
+	}
+
+	for _, address := range []Address{
+		{PostalCode: "10001"},
+		{PostalCode: "99999", Country: "US"},
+		{PostalCode: "M5V 3L9", Country: "US"},
+		{City: "Toronto", Country: "CA"},
+	} {
+		_, err := codes.Geocode(context.Background(), address)
+		assert.ErrorIs(t, err, ErrNotFound, address)
+	}
+}
+
+func TestLoadPostalCodesRejectsBadLines(t *testing.T) {
+	_, err := LoadPostalCodes(strings.NewReader("US\t10001\tNew York"))
+	assert.Error(t, err)
+	_, err = LoadPostalCodes(strings.NewReader("US\t10001\tNew York\t\t\t\t\t\t\tnorth\t-73.9972\t4"))
+	assert.Error(t, err)
+}
+
+func TestBundledPostalCodes(t *testing.T) {
+	codes, err := BundledPostalCodes()
+	require.NoError(t, err)
+	point, err := codes.Geocode(context.Background(), Address{PostalCode: "75001", Country: "FR"})
+	require.NoError(t, err)
+	assert.InDelta(t, 48.86, point.Lat, 0.01)
+}
+
+// geonamesSample is a GeoNames postal code list with two countries
+const geonamesSample = "NL\t1012\tAmsterdam\tNoord-Holland\tNH\t\t\t\t\t52.3740\t4.8897\t6\n" +
+	"DE\t10115\tBerlin\tBerlin\tBE\t\t\t\t\t52.5323\t13.3846\t4\n"
+
+func TestLoadPostalCodesFile(t *testing.T) {
+	dir := t.TempDir()
+	text := filepath.Join(dir, "allCountries.txt")
+	require.NoError(t, os.WriteFile(text, []byte(geonamesSample), 0o600))
+
+	zipped := filepath.Join(dir, "allCountries.zip")
+	f, err := os.Create(zipped)
+	require.NoError(t, err)
+	archive := zip.NewWriter(f)
+	for name, body := range map[string]string{"readme.txt": "GeoNames postal codes", "allCountries.txt": geonamesSample} {
+		w, err := archive.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(body))
+		require.NoError(t, err)
+	}
+	require.NoError(t, archive.Close())
+	require.NoError(t, f.Close())
+
+	for _, path := range []string{text, zipped} {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			chain, err := NewGeocoder(&config.GeocodingConfig{Providers: []string{"postcode"}, PostalCodesFile: path})
+			require.NoError(t, err)
+
+			point, err := chain.Geocode(context.Background(), Address{PostalCode: "1012 AB", Country: "NL"})
+			require.NoError(t, err)
+			assert.InDelta(t, 52.374, point.Lat, 1e-9)
+			point, err = chain.Geocode(context.Background(), Address{PostalCode: "10115", Country: "DE"})
+			require.NoError(t, err)
+			assert.InDelta(t, 13.3846, point.Lon, 1e-9)
+
+			_, err = chain.Geocode(context.Background(), Address{PostalCode: "75001", Country: "FR"})
+			assert.ErrorIs(t, err, ErrNotFound, "the file replaces the bundled sample")
+		})
+	}
+
+	_, err = NewGeocoder(&config.GeocodingConfig{Providers: []string{"postcode"}, PostalCodesFile: filepath.Join(dir, "missing.txt")})
+	assert.Error(t, err)
+
+	empty := filepath.Join(dir, "empty.zip")
+	f, err = os.Create(empty)
+	require.NoError(t, err)
+	require.NoError(t, zip.NewWriter(f).Close())
+	require.NoError(t, f.Close())
+	_, err = NewGeocoder(&config.GeocodingConfig{Providers: []string{"postcode"}, PostalCodesFile: empty})
+	assert.Error(t, err)
+}
@@ -0,0 +1,398 @@
+// Package groups manages contact groups and saved searches. A group with a
+// saved search is a smart group: its members are the contacts the search
+// finds, cached in group_contact_cache until the cache TTL passes. Groups
+// nest, and the members of a group include those of the groups nested under
+// it. Mailing lists, campaigns, surveys and ACLs read members of every kind
+// of group through the group_members view.
+package groups
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/cache"
+	"github.com/jxlxx/civicrm/internal/config"
+	"github.com/jxlxx/civicrm/internal/database"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/jxlxx/civicrm/internal/geocode"
+	"github.com/jxlxx/civicrm/internal/logger"
+	"github.com/jxlxx/civicrm/internal/mail"
+)
+
+// JobRefresh is the scheduled job that rebuilds expired smart group caches
+const JobRefresh = "refresh_smart_groups"
+
+var (
+	// ErrNotFound is returned when a group or saved search does not exist
+	ErrNotFound = errors.New("not found")
+
+	// ErrInvalidSearch is returned when a saved search's query cannot be
+	// run
+	ErrInvalidSearch = errors.New("invalid saved search")
+
+	// ErrNotSmartGroup is returned when refreshing a group without a saved
+	// search
+	ErrNotSmartGroup = errors.New("group is not a smart group")
+)
+
+// Service manages groups and saved searches
+type Service struct {
+	queries  db.Querier
+	tx       database.Transactor
+	lookup   idLookup
+	cache    cache.Invalidator
+	geocoder geocode.Geocoder
+	mailer   mail.Sender
+	config   *config.GroupsConfig
+	logger   *logger.Logger
+	now      func() time.Time
+}
+
+// idLookup runs a query returning contact IDs. Saved search queries are
+// built from the search, so they cannot be generated by sqlc.
+type idLookup func(ctx context.Context, query string, args ...interface{}) ([]uuid.UUID, error)
+
+// New creates a group service. Reads go to replicas when configured;
+// saved searches run on the primary so a refresh sees the latest contacts.
+// Membership changes invalidate cached permission checks. The geocoder
+// locates the postal codes of NEAR searches, and the mailer sends the
+// tokens that confirm pending subscriptions.
+func New(database *database.Database, config *config.GroupsConfig, cache cache.Invalidator, geocoder geocode.Geocoder, mailer mail.Sender, logger *logger.Logger) *Service {
+	return &Service{
+		queries: database.ReadQuerier(),
+		tx:      database,
+		lookup: func(ctx context.Context, query string, args ...interface{}) ([]uuid.UUID, error) {
+			rows, err := database.Query(ctx, query, args...)
+			if err != nil {
+				return nil, err
+			}
+			defer rows.Close()
+
+			var ids []uuid.UUID
+			for rows.Next() {
+				var id uuid.UUID
+				if err := rows.Scan(&id); err != nil {
+					return nil, err
+				}
+				ids = append(ids, id)
+			}
+			return ids, rows.Err()
+		},
+		cache:    cache,
+		geocoder: geocoder,
+		mailer:   mailer,
+		config:   config,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// SavedSearches returns every saved search
+func (s *Service) SavedSearches(ctx context.Context) ([]db.SavedSearch, error) {
+	searches, err := s.queries.ListSavedSearches(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list saved searches: %w", err)
+	}
+	return searches, nil
+}
+
+// SavedSearch returns a saved search by ID
+func (s *Service) SavedSearch(ctx context.Context, id uuid.UUID) (*db.SavedSearch, error) {
+	search, err := s.queries.GetSavedSearch(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("saved search %s %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved search: %w", err)
+	}
+	return &search, nil
+}
+
+// CreateSavedSearch checks and stores a saved search
+func (s *Service) CreateSavedSearch(ctx context.Context, params db.CreateSavedSearchParams) (*db.SavedSearch, error) {
+	if params.ApiEntity == "" {
+		params.ApiEntity = EntityContact
+	}
+	raw, err := s.normalizeParams(ctx, params.ApiEntity, params.ApiParams)
+	if err != nil {
+		return nil, err
+	}
+	params.ApiParams = raw
+
+	var created db.SavedSearch
+	err = s.tx.WithTx(ctx, func(q db.Querier) error {
+		var err error
+		if created, err = q.CreateSavedSearch(ctx, params); err != nil {
+			return fmt.Errorf("failed to create saved search: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// UpdateSavedSearch changes a saved search and expires the cache of the
+// smart groups using it
+func (s *Service) UpdateSavedSearch(ctx context.Context, params db.UpdateSavedSearchParams) (*db.SavedSearch, error) {
+	raw, err := s.normalizeParams(ctx, params.ApiEntity, params.ApiParams)
+	if err != nil {
+		return nil, err
+	}
+	params.ApiParams = raw
+
+	var updated db.SavedSearch
+	err = s.tx.WithTx(ctx, func(q db.Querier) error {
+		var err error
+		updated, err = q.UpdateSavedSearch(ctx, params)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("saved search %s %w", params.ID, ErrNotFound)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update saved search: %w", err)
+		}
+		if err := q.ExpireSavedSearchGroups(ctx, uuid.NullUUID{UUID: params.ID, Valid: true}); err != nil {
+			return fmt.Errorf("failed to expire smart groups: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// DeleteSavedSearch deletes a saved search. Groups using it become static
+// groups keeping their cached members until the cache is cleared.
+func (s *Service) DeleteSavedSearch(ctx context.Context, id uuid.UUID) error {
+	return s.tx.WithTx(ctx, func(q db.Querier) error {
+		n, err := q.DeleteSavedSearch(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete saved search: %w", err)
+		}
+		if n == 0 {
+			return fmt.Errorf("saved search %s %w", id, ErrNotFound)
+		}
+		return nil
+	})
+}
+
+// Groups returns the active groups
+func (s *Service) Groups(ctx context.Context) ([]db.Group, error) {
+	groups, err := s.queries.ListGroups(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list groups: %w", err)
+	}
+	return groups, nil
+}
+
+// Group returns a group by ID
+func (s *Service) Group(ctx context.Context, id uuid.UUID) (*db.Group, error) {
+	group, err := s.queries.GetGroup(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("group %s %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group: %w", err)
+	}
+	return &group, nil
+}
+
+// CreateGroup creates a group. A group with a saved search is a smart group
+// and its cache is built before it is returned; if that fails the group is
+// still created and its cache is built when it is next read.
+func (s *Service) CreateGroup(ctx context.Context, params db.CreateGroupParams) (*db.Group, error) {
+	var created db.Group
+	err := s.tx.WithTx(ctx, func(q db.Querier) error {
+		if params.SavedSearchID.Valid {
+			if _, err := q.GetSavedSearch(ctx, params.SavedSearchID.UUID); errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("saved search %s %w", params.SavedSearchID.UUID, ErrNotFound)
+			} else if err != nil {
+				return fmt.Errorf("failed to get saved search: %w", err)
+			}
+		}
+
+		var err error
+		if created, err = q.CreateGroup(ctx, params); err != nil {
+			return fmt.Errorf("failed to create group: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if created.SavedSearchID.Valid {
+		if _, err := s.refresh(ctx, &created); err != nil {
+			s.logger.Warn("Failed to build smart group cache", "group_id", created.ID, "error", err)
+		}
+	}
+	return &created, nil
+}
+
+// Members returns the members of a group, including the members of groups
+// nested under it. Expired caches of the group and of smart groups nested
+// under it are rebuilt first; if that fails the old cache is used.
+func (s *Service) Members(ctx context.Context, groupID uuid.UUID) ([]db.ListGroupMembersRow, error) {
+	group, err := s.Group(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	descendants, err := s.queries.ListGroupDescendants(ctx, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nested groups: %w", err)
+	}
+
+	for _, g := range append([]db.Group{*group}, descendants...) {
+		if g.SavedSearchID.Valid && s.stale(&g) {
+			if _, err := s.refresh(ctx, &g); err != nil {
+				s.logger.Warn("Failed to refresh smart group, using cached members", "group_id", g.ID, "error", err)
+			}
+		}
+	}
+
+	members, err := s.queries.ListGroupMembers(ctx, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list group members: %w", err)
+	}
+	return members, nil
+}
+
+// Refresh rebuilds a smart group's cache now, whatever its age, and returns
+// the number of contacts the saved search found
+func (s *Service) Refresh(ctx context.Context, groupID uuid.UUID) (int64, error) {
+	group, err := s.Group(ctx, groupID)
+	if err != nil {
+		return 0, err
+	}
+	if !group.SavedSearchID.Valid {
+		return 0, fmt.Errorf("%w: %s", ErrNotSmartGroup, group.Name)
+	}
+	return s.refresh(ctx, group)
+}
+
+// RefreshStale rebuilds the expired caches of a domain's smart groups. A
+// group that fails is logged and skipped.
+func (s *Service) RefreshStale(ctx context.Context, domainID uuid.UUID) (refreshed, failed int, err error) {
+	groups, err := s.queries.ListStaleSmartGroups(ctx, domainID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to list stale smart groups: %w", err)
+	}
+	for i := range groups {
+		if _, err := s.refresh(ctx, &groups[i]); err != nil {
+			s.logger.Error("Failed to refresh smart group", "group_id", groups[i].ID, "error", err)
+			failed++
+			continue
+		}
+		refreshed++
+	}
+	return refreshed, failed, nil
+}
+
+// RefreshJob runs RefreshStale for the job's domain
+func (s *Service) RefreshJob(ctx context.Context, job db.Job) (string, error) {
+	refreshed, failed, err := s.RefreshStale(ctx, job.DomainID)
+	if err != nil {
+		return "", err
+	}
+	if failed > 0 {
+		return "", fmt.Errorf("refreshed %d smart groups, %d failed", refreshed, failed)
+	}
+	return fmt.Sprintf("refreshed %d smart groups", refreshed), nil
+}
+
+// stale reports whether a smart group's cache has expired or was never
+// built
+func (s *Service) stale(group *db.Group) bool {
+	return !group.RefreshDate.Valid || !group.RefreshDate.Time.After(s.now())
+}
+
+// refresh runs a smart group's saved search and replaces its cache with the
+// contacts found. The group row is locked while the cache is replaced so
+// concurrent refreshes do not interleave.
+func (s *Service) refresh(ctx context.Context, group *db.Group) (int64, error) {
+	search, err := s.SavedSearch(ctx, group.SavedSearchID.UUID)
+	if err != nil {
+		return 0, err
+	}
+	query, args, err := memberQuery(*search)
+	if err != nil {
+		return 0, err
+	}
+	ids, err := s.lookup(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to run saved search: %w", err)
+	}
+
+	now := s.now()
+	var added int64
+	err = s.tx.WithTx(ctx, func(q db.Querier) error {
+		if _, err := q.GetGroupForUpdate(ctx, group.ID); errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("group %s %w", group.ID, ErrNotFound)
+		} else if err != nil {
+			return fmt.Errorf("failed to lock group: %w", err)
+		}
+		if err := q.DeleteGroupContactCache(ctx, group.ID); err != nil {
+			return fmt.Errorf("failed to clear group cache: %w", err)
+		}
+		if added, err = q.InsertGroupContactCache(ctx, db.InsertGroupContactCacheParams{GroupID: group.ID, ContactIds: ids}); err != nil {
+			return fmt.Errorf("failed to fill group cache: %w", err)
+		}
+		if err := q.SetGroupCacheDates(ctx, db.SetGroupCacheDatesParams{
+			ID:          group.ID,
+			CacheDate:   sql.NullTime{Time: now, Valid: true},
+			RefreshDate: sql.NullTime{Time: now.Add(s.config.CacheTTL), Valid: true},
+		}); err != nil {
+			return fmt.Errorf("failed to set group cache dates: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	s.invalidateACL(ctx)
+
+	group.CacheDate = sql.NullTime{Time: now, Valid: true}
+	group.RefreshDate = sql.NullTime{Time: now.Add(s.config.CacheTTL), Valid: true}
+	s.logger.Debug("Refreshed smart group", "group_id", group.ID, "contacts", added)
+	return added, nil
+}
+
+// invalidateACL drops cached permission checks after group membership
+// changes. The change is already committed, so a failure is logged rather
+// than returned; cached checks expire on their own.
+func (s *Service) invalidateACL(ctx context.Context) {
+	if err := s.cache.InvalidateTag(ctx, cache.TagACL); err != nil {
+		s.logger.Warn("Failed to invalidate cached permissions", "error", err)
+	}
+}
+
+// normalizeParams checks a saved search's query and returns it re-encoded,
+// so stored params always decode as SearchParams. Postal codes of NEAR
+// clauses are located once here.
+func (s *Service) normalizeParams(ctx context.Context, entity string, raw json.RawMessage) (json.RawMessage, error) {
+	params, err := decodeSearch(entity, raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := params.locate(ctx, s.geocoder); err != nil {
+		return nil, err
+	}
+	if _, _, err := params.compile(uuid.Nil); err != nil {
+		return nil, err
+	}
+	if params.Where == nil {
+		params.Where = []interface{}{}
+	}
+	normalized, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode saved search: %w", err)
+	}
+	return normalized, nil
+}
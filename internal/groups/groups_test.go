@@ -0,0 +1,288 @@
+package groups
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/cache"
+	"github.com/jxlxx/civicrm/internal/cache/cachetest"
+	"github.com/jxlxx/civicrm/internal/config"
+	"github.com/jxlxx/civicrm/internal/database/dbtest"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/jxlxx/civicrm/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeQuerier serves groups, saved searches, contacts and their emails from
+// maps and keeps each group's cached members, the nesting of groups and the
+// subscription history
+type fakeQuerier struct {
+	db.Querier
+	groups   map[uuid.UUID]db.Group
+	searches map[uuid.UUID]db.SavedSearch
+	contacts map[uuid.UUID]bool
+	emails   map[uuid.UUID]string
+	cache    map[uuid.UUID][]uuid.UUID
+	nesting  []db.ListGroupNestingRow
+	expired  []uuid.UUID
+	members  map[[2]uuid.UUID]db.GroupContact
+	history  []db.SubscriptionHistory
+}
+
+func (q *fakeQuerier) ListGroups(ctx context.Context) ([]db.Group, error) {
+	var groups []db.Group
+	for _, group := range q.groups {
+		if !group.IsActive.Valid || group.IsActive.Bool {
+			groups = append(groups, group)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return groups, nil
+}
+
+func (q *fakeQuerier) ListGroupNesting(ctx context.Context) ([]db.ListGroupNestingRow, error) {
+	return q.nesting, nil
+}
+
+func (q *fakeQuerier) CreateGroupNesting(ctx context.Context, arg db.CreateGroupNestingParams) error {
+	q.nesting = append(q.nesting, db.ListGroupNestingRow(arg))
+	return nil
+}
+
+func (q *fakeQuerier) DeleteGroupNesting(ctx context.Context, arg db.DeleteGroupNestingParams) (int64, error) {
+	for i, edge := range q.nesting {
+		if edge == db.ListGroupNestingRow(arg) {
+			q.nesting = append(q.nesting[:i], q.nesting[i+1:]...)
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (q *fakeQuerier) ListGroupDescendants(ctx context.Context, id uuid.UUID) ([]db.Group, error) {
+	var descendants []db.Group
+	seen := map[uuid.UUID]bool{}
+	queue := []uuid.UUID{id}
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+		for _, edge := range q.nesting {
+			if edge.ParentGroupID == parent && !seen[edge.ChildGroupID] {
+				seen[edge.ChildGroupID] = true
+				descendants = append(descendants, q.groups[edge.ChildGroupID])
+				queue = append(queue, edge.ChildGroupID)
+			}
+		}
+	}
+	return descendants, nil
+}
+
+func (q *fakeQuerier) GetGroup(ctx context.Context, id uuid.UUID) (db.Group, error) {
+	group, ok := q.groups[id]
+	if !ok {
+		return db.Group{}, sql.ErrNoRows
+	}
+	return group, nil
+}
+
+func (q *fakeQuerier) GetGroupForUpdate(ctx context.Context, id uuid.UUID) (db.Group, error) {
+	return q.GetGroup(ctx, id)
+}
+
+func (q *fakeQuerier) GetSavedSearch(ctx context.Context, id uuid.UUID) (db.SavedSearch, error) {
+	search, ok := q.searches[id]
+	if !ok {
+		return db.SavedSearch{}, sql.ErrNoRows
+	}
+	return search, nil
+}
+
+func (q *fakeQuerier) UpdateSavedSearch(ctx context.Context, arg db.UpdateSavedSearchParams) (db.SavedSearch, error) {
+	search, ok := q.searches[arg.ID]
+	if !ok {
+		return db.SavedSearch{}, sql.ErrNoRows
+	}
+	search.ApiEntity, search.ApiParams = arg.ApiEntity, arg.ApiParams
+	q.searches[arg.ID] = search
+	return search, nil
+}
+
+func (q *fakeQuerier) ExpireSavedSearchGroups(ctx context.Context, id uuid.NullUUID) error {
+	q.expired = append(q.expired, id.UUID)
+	return nil
+}
+
+func (q *fakeQuerier) ListStaleSmartGroups(ctx context.Context, domainID uuid.UUID) ([]db.Group, error) {
+	var stale []db.Group
+	for _, group := range q.groups {
+		if group.SavedSearchID.Valid && q.searches[group.SavedSearchID.UUID].DomainID == domainID && !group.RefreshDate.Valid {
+			stale = append(stale, group)
+		}
+	}
+	return stale, nil
+}
+
+func (q *fakeQuerier) DeleteGroupContactCache(ctx context.Context, groupID uuid.UUID) error {
+	delete(q.cache, groupID)
+	return nil
+}
+
+func (q *fakeQuerier) InsertGroupContactCache(ctx context.Context, arg db.InsertGroupContactCacheParams) (int64, error) {
+	q.cache[arg.GroupID] = append(q.cache[arg.GroupID], arg.ContactIds...)
+	return int64(len(arg.ContactIds)), nil
+}
+
+func (q *fakeQuerier) SetGroupCacheDates(ctx context.Context, arg db.SetGroupCacheDatesParams) error {
+	group := q.groups[arg.ID]
+	group.CacheDate, group.RefreshDate = arg.CacheDate, arg.RefreshDate
+	q.groups[arg.ID] = group
+	return nil
+}
+
+func (q *fakeQuerier) ListGroupMembers(ctx context.Context, groupID uuid.UUID) ([]db.ListGroupMembersRow, error) {
+	var members []db.ListGroupMembersRow
+	for _, id := range q.cache[groupID] {
+		members = append(members, db.ListGroupMembersRow{ID: id})
+	}
+	return members, nil
+}
+
+var now = time.Date(2024, 5, 15, 12, 0, 0, 0, time.UTC)
+
+// newFixture returns a service with one smart group whose search finds the
+// returned contacts, and records the queries it runs
+func newFixture() (*Service, *fakeQuerier, db.Group, *[]string, []uuid.UUID) {
+	domainID := uuid.New()
+	search := db.SavedSearch{ID: uuid.New(), DomainID: domainID, Name: "students", ApiEntity: EntityContact,
+		ApiParams: json.RawMessage(`{"where": [["contact_sub_type", "CONTAINS", "Student"]]}`)}
+	group := db.Group{ID: uuid.New(), Name: "students", SavedSearchID: dbtest.UUID(search.ID)}
+	found := []uuid.UUID{uuid.New(), uuid.New()}
+
+	q := &fakeQuerier{
+		groups:   map[uuid.UUID]db.Group{group.ID: group},
+		searches: map[uuid.UUID]db.SavedSearch{search.ID: search},
+		contacts: map[uuid.UUID]bool{found[0]: true, found[1]: true},
+		emails:   map[uuid.UUID]string{found[0]: "ada@example.org", found[1]: "grace@example.org"},
+		cache:    make(map[uuid.UUID][]uuid.UUID),
+		members:  make(map[[2]uuid.UUID]db.GroupContact),
+	}
+	var queries []string
+	service := &Service{
+		queries: q,
+		tx:      dbtest.Tx{Q: q},
+		lookup: func(ctx context.Context, query string, args ...interface{}) ([]uuid.UUID, error) {
+			queries = append(queries, query)
+			if args[0] != domainID {
+				return nil, errors.New("search not scoped to its domain")
+			}
+			return found, nil
+		},
+		cache:  &cachetest.Invalidator{},
+		mailer: &fakeMailer{},
+		config: &config.GroupsConfig{CacheTTL: 5 * time.Minute, ConfirmSecret: "secret", ConfirmTTL: 24 * time.Hour,
+			ConfirmURL: "https://example.org/subscribe/confirm"},
+		logger: logger.NewNop(),
+		now:    func() time.Time { return now },
+	}
+	return service, q, group, &queries, found
+}
+
+func TestRefresh(t *testing.T) {
+	service, q, group, queries, found := newFixture()
+	q.cache[group.ID] = []uuid.UUID{uuid.New()}
+
+	n, err := service.Refresh(context.Background(), group.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	assert.Equal(t, found, q.cache[group.ID], "the old cache is replaced")
+	assert.Len(t, *queries, 1)
+
+	assert.Equal(t, []string{cache.TagACL}, service.cache.(*cachetest.Invalidator).Tags(), "membership changed")
+
+	refreshed := q.groups[group.ID]
+	assert.Equal(t, now, refreshed.CacheDate.Time)
+	assert.Equal(t, now.Add(5*time.Minute), refreshed.RefreshDate.Time)
+
+	static := db.Group{ID: uuid.New(), Name: "volunteers"}
+	q.groups[static.ID] = static
+	_, err = service.Refresh(context.Background(), static.ID)
+	assert.ErrorIs(t, err, ErrNotSmartGroup)
+
+	_, err = service.Refresh(context.Background(), uuid.New())
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestMembersRefreshesExpiredCache(t *testing.T) {
+	service, _, group, queries, found := newFixture()
+
+	members, err := service.Members(context.Background(), group.ID)
+	require.NoError(t, err)
+	require.Len(t, members, 2)
+	assert.Equal(t, found[0], members[0].ID)
+	assert.Len(t, *queries, 1)
+
+	// Within the TTL the cache is used
+	_, err = service.Members(context.Background(), group.ID)
+	require.NoError(t, err)
+	assert.Len(t, *queries, 1)
+
+	now := now.Add(6 * time.Minute)
+	service.now = func() time.Time { return now }
+	_, err = service.Members(context.Background(), group.ID)
+	require.NoError(t, err)
+	assert.Len(t, *queries, 2)
+}
+
+func TestMembersRefreshesNestedSmartGroups(t *testing.T) {
+	service, q, smart, queries, _ := newFixture()
+	parent := db.Group{ID: uuid.New(), Name: "school"}
+	q.groups[parent.ID] = parent
+	require.NoError(t, service.AddChild(context.Background(), parent.ID, smart.ID))
+
+	_, err := service.Members(context.Background(), parent.ID)
+	require.NoError(t, err)
+	assert.Len(t, *queries, 1, "the nested smart group is refreshed")
+	assert.True(t, q.groups[smart.ID].RefreshDate.Valid)
+}
+
+func TestUpdateSavedSearchExpiresGroups(t *testing.T) {
+	service, q, group, _, _ := newFixture()
+
+	_, err := service.UpdateSavedSearch(context.Background(), db.UpdateSavedSearchParams{
+		ID:        group.SavedSearchID.UUID,
+		ApiEntity: EntityContact,
+		ApiParams: json.RawMessage(`{"where": [["last_name", "=", "Smith"]], "limit": 10}`),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{group.SavedSearchID.UUID}, q.expired)
+	assert.JSONEq(t, `{"where": [["last_name", "=", "Smith"]], "limit": 10}`, string(q.searches[group.SavedSearchID.UUID].ApiParams))
+
+	_, err = service.UpdateSavedSearch(context.Background(), db.UpdateSavedSearchParams{
+		ID:        group.SavedSearchID.UUID,
+		ApiEntity: EntityContact,
+		ApiParams: json.RawMessage(`{"where": [["password", "=", "x"]]}`),
+	})
+	assert.ErrorIs(t, err, ErrInvalidSearch)
+	assert.Len(t, q.expired, 1)
+}
+
+func TestRefreshJob(t *testing.T) {
+	service, q, group, _, found := newFixture()
+	domainID := q.searches[group.SavedSearchID.UUID].DomainID
+
+	message, err := service.RefreshJob(context.Background(), db.Job{DomainID: uuid.New()})
+	require.NoError(t, err)
+	assert.Equal(t, "refreshed 0 smart groups", message)
+
+	message, err = service.RefreshJob(context.Background(), db.Job{DomainID: domainID})
+	require.NoError(t, err)
+	assert.Equal(t, "refreshed 1 smart groups", message)
+	assert.Equal(t, found, q.cache[group.ID])
+}
@@ -0,0 +1,93 @@
+package groups
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/cache"
+	"github.com/jxlxx/civicrm/internal/cache/cachetest"
+	"github.com/jxlxx/civicrm/internal/database/dbtest"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newHierarchy adds groups a > b > c to the fixture and returns them
+func newHierarchy(t *testing.T) (*Service, *fakeQuerier, db.Group, db.Group, db.Group) {
+	service, q, _, _, _ := newFixture()
+	a := db.Group{ID: uuid.New(), Name: "a"}
+	b := db.Group{ID: uuid.New(), Name: "b"}
+	c := db.Group{ID: uuid.New(), Name: "c"}
+	for _, group := range []db.Group{a, b, c} {
+		q.groups[group.ID] = group
+	}
+	require.NoError(t, service.AddChild(context.Background(), a.ID, b.ID))
+	require.NoError(t, service.AddChild(context.Background(), b.ID, c.ID))
+	return service, q, a, b, c
+}
+
+func TestAddChildRejectsCycles(t *testing.T) {
+	service, q, a, b, c := newHierarchy(t)
+
+	assert.ErrorIs(t, service.AddChild(context.Background(), c.ID, a.ID), ErrCycle)
+	assert.ErrorIs(t, service.AddChild(context.Background(), b.ID, a.ID), ErrCycle)
+	assert.ErrorIs(t, service.AddChild(context.Background(), a.ID, a.ID), ErrCycle)
+	assert.ErrorIs(t, service.AddChild(context.Background(), a.ID, uuid.New()), ErrNotFound)
+	assert.Len(t, q.nesting, 2)
+
+	// A group may have several parents
+	assert.NoError(t, service.AddChild(context.Background(), a.ID, c.ID))
+}
+
+func TestRemoveChild(t *testing.T) {
+	service, q, a, b, c := newHierarchy(t)
+	invalidated := service.cache.(*cachetest.Invalidator)
+	assert.Equal(t, []string{cache.TagACL, cache.TagACL}, invalidated.Tags(), "each nesting changes membership")
+
+	require.NoError(t, service.RemoveChild(context.Background(), b.ID, c.ID))
+	assert.Len(t, q.nesting, 1)
+	assert.Equal(t, []string{cache.TagACL}, invalidated.Tags())
+	assert.ErrorIs(t, service.RemoveChild(context.Background(), b.ID, c.ID), ErrNotFound)
+	assert.Empty(t, invalidated.Tags())
+
+	// Un-nesting allows the reverse nesting
+	assert.NoError(t, service.AddChild(context.Background(), c.ID, a.ID))
+}
+
+func TestTree(t *testing.T) {
+	service, q, a, b, c := newHierarchy(t)
+	require.NoError(t, service.AddChild(context.Background(), a.ID, c.ID))
+
+	trees, err := service.Tree(context.Background(), uuid.NullUUID{})
+	require.NoError(t, err)
+
+	// a > (b > c, c), and the smart group "students" on its own
+	require.Len(t, trees, 2)
+	root := trees[0]
+	assert.Equal(t, "a", root.Name)
+	require.Len(t, root.Children, 2)
+	assert.Equal(t, "b", root.Children[0].Name)
+	assert.Equal(t, "c", root.Children[0].Children[0].Name)
+	assert.Equal(t, "c", root.Children[1].Name)
+	assert.Equal(t, "students", trees[1].Name)
+	assert.True(t, trees[1].IsSmart)
+
+	trees, err = service.Tree(context.Background(), dbtest.UUID(b.ID))
+	require.NoError(t, err)
+	require.Len(t, trees, 1)
+	assert.Equal(t, "b", trees[0].Name)
+	assert.Len(t, trees[0].Children, 1)
+
+	// Children of inactive groups are roots
+	inactive := q.groups[b.ID]
+	inactive.IsActive.Valid = true
+	q.groups[b.ID] = inactive
+	trees, err = service.Tree(context.Background(), uuid.NullUUID{})
+	require.NoError(t, err)
+	assert.Len(t, trees, 2)
+	assert.Len(t, trees[0].Children, 1, "a keeps only its direct child c")
+
+	_, err = service.Tree(context.Background(), dbtest.UUID(b.ID))
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,526 @@
+package groups
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/jxlxx/civicrm/internal/geocode"
+	"github.com/lib/pq"
+)
+
+// EntityContact is the only entity a smart group's saved search can query
+const EntityContact = "Contact"
+
+// SearchParams is the APIv4 query a saved search stores in api_params, such
+// as {"where": [["contact_type", "=", "Individual"], ["OR", [...]]]}. Only
+// where decides a smart group's members; the other keys are kept for
+// display.
+type SearchParams struct {
+	Select  []string          `json:"select,omitempty"`
+	Where   []interface{}     `json:"where"`
+	OrderBy map[string]string `json:"orderBy,omitempty"`
+	Limit   int               `json:"limit,omitempty"`
+}
+
+// Kinds of search fields, deciding the operators and values they accept
+const (
+	kindText = iota
+	kindUUID
+	kindTime
+	kindArray
+	kindGroup
+	kindLocation
+)
+
+// searchField is a field a where clause can filter on
+type searchField struct {
+	column string
+	kind   int
+	join   string
+}
+
+// searchJoins are the tables fields outside contacts come from
+var searchJoins = map[string]string{
+	"email_primary":   "LEFT JOIN emails email_primary ON email_primary.contact_id = c.id AND email_primary.is_primary",
+	"phone_primary":   "LEFT JOIN phones phone_primary ON phone_primary.contact_id = c.id AND phone_primary.is_primary",
+	"address_primary": "LEFT JOIN addresses address_primary ON address_primary.contact_id = c.id AND address_primary.is_primary",
+}
+
+// searchFields are the fields of Contact a where clause can use. Columns
+// are only ever taken from this map, never from the search.
+var searchFields = map[string]searchField{
+	"id":                                {"c.id", kindUUID, ""},
+	"contact_type":                      {"c.contact_type", kindText, ""},
+	"contact_sub_type":                  {"c.contact_sub_type", kindArray, ""},
+	"prefix":                            {"c.prefix", kindText, ""},
+	"first_name":                        {"c.first_name", kindText, ""},
+	"last_name":                         {"c.last_name", kindText, ""},
+	"suffix":                            {"c.suffix", kindText, ""},
+	"nick_name":                         {"c.nick_name", kindText, ""},
+	"display_name":                      {"c.display_name", kindText, ""},
+	"sort_name":                         {"c.sort_name", kindText, ""},
+	"organization_name":                 {"c.organization_name", kindText, ""},
+	"household_name":                    {"c.household_name", kindText, ""},
+	"employer_id":                       {"c.employer_id", kindUUID, ""},
+	"created_at":                        {"c.created_at", kindTime, ""},
+	"updated_at":                        {"c.updated_at", kindTime, ""},
+	"groups":                            {"c.id", kindGroup, ""},
+	"address_primary":                   {"address_primary", kindLocation, "address_primary"},
+	"email_primary.email":               {"email_primary.email", kindText, "email_primary"},
+	"phone_primary.phone":               {"phone_primary.phone", kindText, "phone_primary"},
+	"address_primary.street_address":    {"address_primary.street_address", kindText, "address_primary"},
+	"address_primary.city":              {"address_primary.city", kindText, "address_primary"},
+	"address_primary.postal_code":       {"address_primary.postal_code", kindText, "address_primary"},
+	"address_primary.state_province_id": {"address_primary.state_province_id", kindUUID, "address_primary"},
+	"address_primary.country_id":        {"address_primary.country_id", kindUUID, "address_primary"},
+}
+
+// sqlTypes are the types values of each kind are cast to
+var sqlTypes = map[int]string{
+	kindText:  "text",
+	kindUUID:  "uuid",
+	kindTime:  "timestamptz",
+	kindArray: "text",
+	kindGroup: "uuid",
+}
+
+// timeLayouts are the accepted formats of date and time values
+var timeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"}
+
+// distanceUnits are the units a NEAR distance may be given in, in km
+var distanceUnits = map[string]float64{"km": 1, "mi": 1.609344}
+
+// earthRadius is the mean radius of the earth in km
+const earthRadius = 6371.0088
+
+// ParseSearch decodes and checks a saved search's query
+func ParseSearch(entity string, raw json.RawMessage) (*SearchParams, error) {
+	params, err := decodeSearch(entity, raw)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err := params.compile(uuid.Nil); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+// decodeSearch decodes a saved search's query without checking its clauses
+func decodeSearch(entity string, raw json.RawMessage) (*SearchParams, error) {
+	if entity != EntityContact {
+		return nil, fmt.Errorf("%w: api_entity must be %s, not %q", ErrInvalidSearch, EntityContact, entity)
+	}
+	params := &SearchParams{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, params); err != nil {
+			return nil, fmt.Errorf("%w: api_params must be an APIv4 query: %v", ErrInvalidSearch, err)
+		}
+	}
+	return params, nil
+}
+
+// locate fills in the coordinates of NEAR clauses given a postal code, such
+// as ["address_primary", "NEAR", {"distance": 10, "postal_code": "M5V 3L9",
+// "country": "CA"}], so a smart group does not geocode on every refresh
+func (p *SearchParams) locate(ctx context.Context, geocoder geocode.Geocoder) error {
+	var walk func(items []interface{}) error
+	walk = func(items []interface{}) error {
+		for _, item := range items {
+			clause, ok := item.([]interface{})
+			if !ok || len(clause) < 2 {
+				continue
+			}
+			name, _ := clause[0].(string)
+			if logic := strings.ToUpper(name); logic == "AND" || logic == "OR" || logic == "NOT" {
+				if children, ok := clause[1].([]interface{}); ok {
+					if err := walk(children); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			op, _ := clause[1].(string)
+			if strings.ToUpper(strings.TrimSpace(op)) != "NEAR" || len(clause) < 3 {
+				continue
+			}
+			near, ok := clause[2].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			postalCode, _ := near["postal_code"].(string)
+			if postalCode == "" || near["lat"] != nil || near["lon"] != nil {
+				continue
+			}
+
+			country, _ := near["country"].(string)
+			if geocoder == nil {
+				return fmt.Errorf("%w: postal code %q cannot be located: %v", ErrInvalidSearch, postalCode, geocode.ErrDisabled)
+			}
+			point, err := geocoder.Geocode(ctx, geocode.Address{PostalCode: postalCode, Country: country})
+			if errors.Is(err, geocode.ErrNotFound) || errors.Is(err, geocode.ErrDisabled) {
+				return fmt.Errorf("%w: postal code %q cannot be located: %v", ErrInvalidSearch, postalCode, err)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to locate postal code %q: %w", postalCode, err)
+			}
+			near["lat"], near["lon"] = point.Lat, point.Lon
+		}
+		return nil
+	}
+	return walk(p.Where)
+}
+
+// memberQuery returns the query finding the IDs of the contacts of a domain
+// that a saved search matches
+func memberQuery(search db.SavedSearch) (string, []interface{}, error) {
+	params, err := ParseSearch(search.ApiEntity, search.ApiParams)
+	if err != nil {
+		return "", nil, err
+	}
+	return params.compile(search.DomainID)
+}
+
+// compile turns the where clauses into SQL. Values are passed as
+// arguments cast to the field's type; field names only select columns from
+// searchFields.
+func (p *SearchParams) compile(domainID uuid.UUID) (string, []interface{}, error) {
+	c := &compiler{args: []interface{}{domainID}, joins: make(map[string]bool)}
+	where, err := c.clauses(p.Where, " AND ")
+	if err != nil {
+		return "", nil, err
+	}
+
+	var b strings.Builder
+	b.WriteString("SELECT DISTINCT c.id FROM contacts c")
+	for _, name := range []string{"email_primary", "phone_primary", "address_primary"} {
+		if c.joins[name] {
+			b.WriteString(" " + searchJoins[name])
+		}
+	}
+	b.WriteString(" WHERE c.domain_id = $1 AND NOT c.is_deleted")
+	if where != "" {
+		b.WriteString(" AND " + where)
+	}
+	return b.String(), c.args, nil
+}
+
+// compiler collects the arguments and joins of a query being built
+type compiler struct {
+	args  []interface{}
+	joins map[string]bool
+}
+
+// arg adds an argument and returns its placeholder cast to type
+func (c *compiler) arg(value interface{}, sqlType string) string {
+	c.args = append(c.args, value)
+	return fmt.Sprintf("$%d::%s", len(c.args), sqlType)
+}
+
+// clauses compiles a list of clauses joined by op
+func (c *compiler) clauses(items []interface{}, op string) (string, error) {
+	parts := make([]string, 0, len(items))
+	for _, item := range items {
+		clause, ok := item.([]interface{})
+		if !ok || len(clause) == 0 {
+			return "", fmt.Errorf("%w: where clause %v must be a list", ErrInvalidSearch, item)
+		}
+		part, err := c.clause(clause)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return "", nil
+	}
+	return "(" + strings.Join(parts, op) + ")", nil
+}
+
+// clause compiles one condition such as ["first_name", "=", "Ann"], or a
+// group such as ["OR", [...]]
+func (c *compiler) clause(clause []interface{}) (string, error) {
+	name, _ := clause[0].(string)
+	if logic := strings.ToUpper(name); logic == "AND" || logic == "OR" || logic == "NOT" {
+		children, ok := clause[len(clause)-1].([]interface{})
+		if len(clause) != 2 || !ok {
+			return "", fmt.Errorf("%w: %s must be followed by a list of clauses", ErrInvalidSearch, logic)
+		}
+		op := " " + logic + " "
+		if logic == "NOT" {
+			op = " AND "
+		}
+		sql, err := c.clauses(children, op)
+		if err != nil {
+			return "", err
+		}
+		if sql == "" {
+			return "TRUE", nil
+		}
+		if logic == "NOT" {
+			return "NOT " + sql, nil
+		}
+		return sql, nil
+	}
+
+	field, ok := searchFields[name]
+	if !ok {
+		return "", fmt.Errorf("%w: unknown field %q", ErrInvalidSearch, name)
+	}
+	if len(clause) < 2 {
+		return "", fmt.Errorf("%w: %s has no operator", ErrInvalidSearch, name)
+	}
+	op, _ := clause[1].(string)
+	op = strings.ToUpper(strings.TrimSpace(op))
+	var value interface{}
+	if len(clause) > 2 {
+		value = clause[2]
+	}
+	if field.join != "" {
+		c.joins[field.join] = true
+	}
+
+	sql, err := c.condition(field, op, value)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s %s: %v", ErrInvalidSearch, name, op, err)
+	}
+	return sql, nil
+}
+
+// condition compiles one operator on a field
+func (c *compiler) condition(field searchField, op string, value interface{}) (string, error) {
+	col, sqlType := field.column, sqlTypes[field.kind]
+
+	switch field.kind {
+	case kindLocation:
+		if op != "NEAR" {
+			return "", fmt.Errorf("operator is not supported for addresses; use NEAR")
+		}
+		return c.near(col, value)
+
+	case kindGroup:
+		switch op {
+		case "=", "IN", "!=", "<>", "NOT IN":
+			ids, err := c.values(kindUUID, value, op == "=" || op == "!=" || op == "<>")
+			if err != nil {
+				return "", err
+			}
+			in := fmt.Sprintf("%s IN (SELECT gm.contact_id FROM group_members gm WHERE gm.group_id = ANY(%s[]))", col, c.arg(pq.Array(ids), sqlType))
+			if op == "=" || op == "IN" {
+				return in, nil
+			}
+			return "NOT " + in, nil
+		}
+		return "", fmt.Errorf("operator is not supported for groups")
+
+	case kindArray:
+		switch op {
+		case "CONTAINS", "NOT CONTAINS":
+			values, err := c.values(kindText, value, false)
+			if err != nil {
+				return "", err
+			}
+			overlap := fmt.Sprintf("%s && %s[]", col, c.arg(pq.Array(values), sqlType))
+			if op == "CONTAINS" {
+				return overlap, nil
+			}
+			return fmt.Sprintf("NOT COALESCE(%s, FALSE)", overlap), nil
+		case "IS EMPTY":
+			return fmt.Sprintf("COALESCE(cardinality(%s), 0) = 0", col), nil
+		case "IS NOT EMPTY":
+			return fmt.Sprintf("cardinality(%s) > 0", col), nil
+		}
+		return "", fmt.Errorf("operator is not supported for %s", col)
+	}
+
+	switch op {
+	case "IS NULL":
+		return col + " IS NULL", nil
+	case "IS NOT NULL":
+		return col + " IS NOT NULL", nil
+	case "IS EMPTY":
+		if field.kind == kindText {
+			return fmt.Sprintf("COALESCE(%s, '') = ''", col), nil
+		}
+		return col + " IS NULL", nil
+	case "IS NOT EMPTY":
+		if field.kind == kindText {
+			return fmt.Sprintf("COALESCE(%s, '') <> ''", col), nil
+		}
+		return col + " IS NOT NULL", nil
+	case "IN", "NOT IN":
+		values, err := c.values(field.kind, value, false)
+		if err != nil {
+			return "", err
+		}
+		in := fmt.Sprintf("%s = ANY(%s[])", col, c.arg(pq.Array(values), sqlType))
+		if op == "IN" {
+			return in, nil
+		}
+		return fmt.Sprintf("NOT COALESCE(%s, FALSE)", in), nil
+	case "=", "!=", "<>":
+		v, err := c.value(field.kind, value)
+		if err != nil {
+			return "", err
+		}
+		if op == "=" {
+			return fmt.Sprintf("%s = %s", col, c.arg(v, sqlType)), nil
+		}
+		return fmt.Sprintf("%s IS DISTINCT FROM %s", col, c.arg(v, sqlType)), nil
+	}
+
+	if field.kind == kindUUID {
+		return "", fmt.Errorf("operator is not supported for IDs")
+	}
+
+	switch op {
+	case ">", ">=", "<", "<=":
+		v, err := c.value(field.kind, value)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s %s %s", col, op, c.arg(v, sqlType)), nil
+	case "BETWEEN", "NOT BETWEEN":
+		values, err := c.values(field.kind, value, false)
+		if err != nil {
+			return "", err
+		}
+		if len(values) != 2 {
+			return "", fmt.Errorf("value must be a list of two values")
+		}
+		between := fmt.Sprintf("%s BETWEEN %s AND %s", col, c.arg(values[0], sqlType), c.arg(values[1], sqlType))
+		if op == "BETWEEN" {
+			return between, nil
+		}
+		return fmt.Sprintf("NOT COALESCE(%s, FALSE)", between), nil
+	}
+
+	if field.kind != kindText {
+		return "", fmt.Errorf("operator is not supported for dates")
+	}
+
+	switch op {
+	case "LIKE", "NOT LIKE", "CONTAINS", "NOT CONTAINS":
+		v, err := c.value(kindText, value)
+		if err != nil {
+			return "", err
+		}
+		if op == "CONTAINS" || op == "NOT CONTAINS" {
+			v = "%" + escapeLike(v) + "%"
+		}
+		like := fmt.Sprintf("%s ILIKE %s", col, c.arg(v, sqlType))
+		if op == "LIKE" || op == "CONTAINS" {
+			return like, nil
+		}
+		return fmt.Sprintf("NOT COALESCE(%s, FALSE)", like), nil
+	}
+	return "", fmt.Errorf("unknown operator")
+}
+
+// near compiles a NEAR condition on an address table, whose value is an
+// object with the distance, its unit (km or mi) and a lat and lon. The
+// latitude range narrows the addresses before their great-circle distance
+// is computed.
+func (c *compiler) near(table string, value interface{}) (string, error) {
+	near, ok := value.(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf(`value must be an object such as {"distance": 10, "unit": "km", "lat": 51.5, "lon": -0.12}`)
+	}
+	distance, ok := near["distance"].(float64)
+	if !ok || distance <= 0 {
+		return "", fmt.Errorf("distance must be a positive number")
+	}
+	unit, _ := near["unit"].(string)
+	if unit == "" {
+		unit = "km"
+	}
+	factor, ok := distanceUnits[unit]
+	if !ok {
+		return "", fmt.Errorf("unit must be km or mi, not %q", unit)
+	}
+	lat, latOK := near["lat"].(float64)
+	lon, lonOK := near["lon"].(float64)
+	if !latOK || !lonOK {
+		if postalCode, _ := near["postal_code"].(string); postalCode != "" {
+			return "", fmt.Errorf("postal code %q has not been located", postalCode)
+		}
+		return "", fmt.Errorf("lat and lon or postal_code are required")
+	}
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return "", fmt.Errorf("lat must be within ±90 and lon within ±180")
+	}
+
+	km := distance * factor
+	latArg, lonArg := c.arg(lat, "float8"), c.arg(lon, "float8")
+	spread := km / earthRadius * 180 / math.Pi
+	return fmt.Sprintf("(%[1]s.geo_code_1 BETWEEN %[2]s AND %[3]s"+
+		" AND %[6]g * 2 * asin(least(1, sqrt(power(sin(radians(%[1]s.geo_code_1 - %[4]s) / 2), 2)"+
+		" + cos(radians(%[4]s)) * cos(radians(%[1]s.geo_code_1)) * power(sin(radians(%[1]s.geo_code_2 - %[5]s) / 2), 2)))) <= %[7]s)",
+		table, c.arg(lat-spread, "float8"), c.arg(lat+spread, "float8"), latArg, lonArg, earthRadius, c.arg(km, "float8")), nil
+}
+
+// values returns a list value as strings of a kind. A single value is
+// accepted as a list of one when single is set or the value is not a list.
+func (c *compiler) values(kind int, value interface{}, single bool) ([]string, error) {
+	items, ok := value.([]interface{})
+	if !ok || single {
+		items = []interface{}{value}
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("value must not be empty")
+	}
+	result := make([]string, len(items))
+	for i, item := range items {
+		v, err := c.value(kind, item)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = v
+	}
+	return result, nil
+}
+
+// value returns a single value as a string of a kind, checking IDs and
+// dates so bad input is reported before the query runs
+func (c *compiler) value(kind int, value interface{}) (string, error) {
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		s = strconv.FormatBool(v)
+	default:
+		return "", fmt.Errorf("value %v must be a string or number", value)
+	}
+
+	switch kind {
+	case kindUUID:
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return "", fmt.Errorf("value %q is not an ID", s)
+		}
+		return id.String(), nil
+	case kindTime:
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t.Format(time.RFC3339), nil
+			}
+		}
+		return "", fmt.Errorf("value %q is not a date such as 2024-01-31", s)
+	}
+	return s, nil
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
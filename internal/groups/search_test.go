@@ -0,0 +1,143 @@
+package groups
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/geocode"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func compileJSON(t *testing.T, where string) (string, []interface{}, error) {
+	t.Helper()
+	params, err := ParseSearch(EntityContact, json.RawMessage(`{"where": `+where+`}`))
+	if err != nil {
+		return "", nil, err
+	}
+	return params.compile(uuid.Nil)
+}
+
+func TestCompileSearch(t *testing.T) {
+	query, args, err := compileJSON(t, `[]`)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT DISTINCT c.id FROM contacts c WHERE c.domain_id = $1 AND NOT c.is_deleted", query)
+	assert.Equal(t, []interface{}{uuid.Nil}, args)
+
+	query, args, err = compileJSON(t, `[
+		["contact_type", "=", "Individual"],
+		["OR", [["address_primary.city", "LIKE", "Spring%"], ["email_primary.email", "CONTAINS", "50%"]]],
+		["NOT", [["contact_sub_type", "CONTAINS", ["Student", "Staff"]]]],
+		["created_at", ">=", "2024-01-31"]
+	]`)
+	require.NoError(t, err)
+	assert.Equal(t, "SELECT DISTINCT c.id FROM contacts c"+
+		" LEFT JOIN emails email_primary ON email_primary.contact_id = c.id AND email_primary.is_primary"+
+		" LEFT JOIN addresses address_primary ON address_primary.contact_id = c.id AND address_primary.is_primary"+
+		" WHERE c.domain_id = $1 AND NOT c.is_deleted AND (c.contact_type = $2::text"+
+		" AND (address_primary.city ILIKE $3::text OR email_primary.email ILIKE $4::text)"+
+		" AND NOT (c.contact_sub_type && $5::text[])"+
+		" AND c.created_at >= $6::timestamptz)", query)
+	assert.Equal(t, []interface{}{uuid.Nil, "Individual", "Spring%", `%50\%%`, pq.Array([]string{"Student", "Staff"}), "2024-01-31T00:00:00Z"}, args)
+}
+
+func TestCompileSearchGroups(t *testing.T) {
+	group := uuid.New()
+	query, args, err := compileJSON(t, `[["groups", "NOT IN", ["`+group.String()+`"]], ["employer_id", "IS NULL"]]`)
+	require.NoError(t, err)
+	assert.Contains(t, query, "(NOT c.id IN (SELECT gm.contact_id FROM group_members gm WHERE gm.group_id = ANY($2::uuid[])) AND c.employer_id IS NULL)")
+	assert.Equal(t, pq.Array([]string{group.String()}), args[1])
+}
+
+func TestCompileSearchNear(t *testing.T) {
+	query, args, err := compileJSON(t, `[["address_primary", "NEAR", {"distance": 10, "unit": "mi", "lat": 51.5, "lon": -0.12}]]`)
+	require.NoError(t, err)
+	assert.Contains(t, query, " LEFT JOIN addresses address_primary ON ")
+	assert.Contains(t, query, "(address_primary.geo_code_1 BETWEEN $4::float8 AND $5::float8 AND ")
+	assert.Contains(t, query, "cos(radians($2::float8)) * cos(radians(address_primary.geo_code_1))")
+	assert.Contains(t, query, "sin(radians(address_primary.geo_code_2 - $3::float8) / 2)")
+	assert.Contains(t, query, "<= $6::float8)")
+	require.Len(t, args, 6)
+	assert.Equal(t, []interface{}{51.5, -0.12}, args[1:3])
+	assert.InDelta(t, 51.5-0.1447, args[3], 0.001, "10 miles is about 0.145 degrees of latitude")
+	assert.InDelta(t, 51.5+0.1447, args[4], 0.001)
+	assert.InDelta(t, 16.093, args[5], 0.001, "distances are compared in km")
+}
+
+// fakeGeocoder locates one postal code
+type fakeGeocoder struct {
+	calls int
+}
+
+func (g *fakeGeocoder) Geocode(ctx context.Context, address geocode.Address) (geocode.Point, error) {
+	g.calls++
+	if address.PostalCode != "M5V 3L9" || address.Country != "CA" {
+		return geocode.Point{}, geocode.ErrNotFound
+	}
+	return geocode.Point{Lat: 43.6426, Lon: -79.3871}, nil
+}
+
+func TestNormalizeParamsLocatesPostalCodes(t *testing.T) {
+	service, _, _, _, _ := newFixture()
+	geocoder := &fakeGeocoder{}
+	service.geocoder = geocoder
+
+	raw, err := service.normalizeParams(context.Background(), EntityContact, json.RawMessage(`{"where": [
+		["NOT", [["address_primary", "NEAR", {"distance": 5, "postal_code": "M5V 3L9", "country": "CA"}]]]
+	]}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"where": [
+		["NOT", [["address_primary", "NEAR", {"distance": 5, "postal_code": "M5V 3L9", "country": "CA", "lat": 43.6426, "lon": -79.3871}]]]
+	]}`, string(raw))
+
+	// Located clauses are not located again
+	_, err = service.normalizeParams(context.Background(), EntityContact, raw)
+	require.NoError(t, err)
+	assert.Equal(t, 1, geocoder.calls)
+
+	_, err = service.normalizeParams(context.Background(), EntityContact, json.RawMessage(`{"where": [
+		["address_primary", "NEAR", {"distance": 5, "postal_code": "00000", "country": "CA"}]
+	]}`))
+	assert.ErrorIs(t, err, ErrInvalidSearch)
+
+	service.geocoder = nil
+	_, err = service.normalizeParams(context.Background(), EntityContact, json.RawMessage(`{"where": [
+		["address_primary", "NEAR", {"distance": 5, "postal_code": "M5V 3L9", "country": "CA"}]
+	]}`))
+	assert.ErrorIs(t, err, ErrInvalidSearch)
+}
+
+func TestCompileSearchRejects(t *testing.T) {
+	for name, where := range map[string]string{
+		"unknown field":       `[["password", "=", "x"]]`,
+		"injected field":      `[["first_name = '' OR 1=1 --", "=", "x"]]`,
+		"unknown operator":    `[["first_name", "REGEXP", "x"]]`,
+		"bad id":              `[["employer_id", "=", "not-an-id"]]`,
+		"ordered id":          `[["employer_id", ">", "` + uuid.New().String() + `"]]`,
+		"bad date":            `[["created_at", ">", "yesterday"]]`,
+		"like on date":        `[["created_at", "LIKE", "2024%"]]`,
+		"between one value":   `[["last_name", "BETWEEN", ["A"]]]`,
+		"empty list":          `[["contact_type", "IN", []]]`,
+		"clause not a list":   `["first_name"]`,
+		"logic without list":  `[["OR", "first_name"]]`,
+		"object value":        `[["first_name", "=", {"a": 1}]]`,
+		"group with operator": `[["groups", "LIKE", "x"]]`,
+		"address with equals": `[["address_primary", "=", "x"]]`,
+		"near no location":    `[["address_primary", "NEAR", {"distance": 5}]]`,
+		"near postal code":    `[["address_primary", "NEAR", {"distance": 5, "postal_code": "M5V 3L9"}]]`,
+		"near no distance":    `[["address_primary", "NEAR", {"lat": 1, "lon": 2}]]`,
+		"near bad unit":       `[["address_primary", "NEAR", {"distance": 5, "unit": "ft", "lat": 1, "lon": 2}]]`,
+		"near bad latitude":   `[["address_primary", "NEAR", {"distance": 5, "lat": 91, "lon": 2}]]`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := compileJSON(t, where)
+			assert.ErrorIs(t, err, ErrInvalidSearch)
+		})
+	}
+
+	_, err := ParseSearch("Activity", json.RawMessage(`{}`))
+	assert.ErrorIs(t, err, ErrInvalidSearch)
+}
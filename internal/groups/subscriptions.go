@@ -0,0 +1,282 @@
+package groups
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/jxlxx/civicrm/internal/mail"
+)
+
+// Statuses of a contact in a group
+const (
+	StatusAdded   = "Added"
+	StatusRemoved = "Removed"
+	StatusPending = "Pending"
+)
+
+// Methods by which a status change was made
+const (
+	MethodAdmin = "Admin"
+	MethodEmail = "Email"
+	MethodWeb   = "Web"
+	MethodAPI   = "API"
+)
+
+var (
+	// ErrInvalidChange is returned for a status change with an unknown
+	// status or method
+	ErrInvalidChange = errors.New("invalid subscription change")
+
+	// ErrInvalidToken is returned when a confirmation token is forged,
+	// expired or no longer confirms a pending subscription
+	ErrInvalidToken = errors.New("invalid confirmation token")
+)
+
+var (
+	statuses = map[string]bool{StatusAdded: true, StatusRemoved: true, StatusPending: true}
+	methods  = map[string]bool{MethodAdmin: true, MethodEmail: true, MethodWeb: true, MethodAPI: true}
+)
+
+// Change is a change to a contact's status in a group. ActorID is the user
+// making it, or empty when the contact made it themselves.
+type Change struct {
+	GroupID   uuid.UUID
+	ContactID uuid.UUID
+	Status    string
+	Method    string
+	ActorID   uuid.NullUUID
+	Tracking  string
+}
+
+// SetStatus changes a contact's status in a group and records the change in
+// the subscription history. A Pending contact is emailed the token they
+// confirm with, which is never returned. Setting the status the contact
+// already has records nothing; for Pending, the token is sent again.
+func (s *Service) SetStatus(ctx context.Context, change Change) (*db.GroupContact, error) {
+	if !statuses[change.Status] {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidChange, change.Status)
+	}
+	if !methods[change.Method] {
+		return nil, fmt.Errorf("%w: unknown method %q", ErrInvalidChange, change.Method)
+	}
+
+	var subscription *db.GroupContact
+	err := s.tx.WithTx(ctx, func(q db.Querier) error {
+		if _, err := q.GetGroup(ctx, change.GroupID); errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("group %s %w", change.GroupID, ErrNotFound)
+		} else if err != nil {
+			return fmt.Errorf("failed to get group: %w", err)
+		}
+		if _, err := q.GetContact(ctx, change.ContactID); errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("contact %s %w", change.ContactID, ErrNotFound)
+		} else if err != nil {
+			return fmt.Errorf("failed to get contact: %w", err)
+		}
+
+		var err error
+		subscription, err = s.setStatus(ctx, q, change)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.invalidateACL(ctx)
+	return subscription, nil
+}
+
+// setStatus applies a checked change within a transaction
+func (s *Service) setStatus(ctx context.Context, q db.Querier, change Change) (*db.GroupContact, error) {
+	current, err := q.GetGroupContactForUpdate(ctx, db.GetGroupContactForUpdateParams{GroupID: change.GroupID, ContactID: change.ContactID})
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get group contact: %w", err)
+	}
+
+	if err == nil && current.Status.String == change.Status {
+		if change.Status == StatusPending {
+			latest, err := q.GetLatestSubscriptionHistory(ctx, db.GetLatestSubscriptionHistoryParams{GroupID: change.GroupID, ContactID: change.ContactID})
+			if err != nil {
+				return nil, fmt.Errorf("failed to get subscription history: %w", err)
+			}
+			if err := s.sendConfirmation(ctx, q, latest); err != nil {
+				return nil, err
+			}
+		}
+		return &current, nil
+	}
+
+	updated, err := q.UpsertGroupContact(ctx, db.UpsertGroupContactParams{
+		GroupID:   change.GroupID,
+		ContactID: change.ContactID,
+		Status:    sql.NullString{String: change.Status, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set group contact status: %w", err)
+	}
+	history, err := q.CreateSubscriptionHistory(ctx, db.CreateSubscriptionHistoryParams{
+		GroupID:   change.GroupID,
+		ContactID: change.ContactID,
+		Status:    change.Status,
+		Method:    change.Method,
+		Date:      s.now(),
+		ActorID:   change.ActorID,
+		Tracking:  sql.NullString{String: change.Tracking, Valid: change.Tracking != ""},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record subscription history: %w", err)
+	}
+
+	if change.Status == StatusPending {
+		if err := s.sendConfirmation(ctx, q, history); err != nil {
+			return nil, err
+		}
+	}
+	return &updated, nil
+}
+
+// sendConfirmation emails a contact the token that confirms the pending
+// subscription made by history. It is sent within the change's transaction,
+// so the change is undone when the email cannot be sent.
+func (s *Service) sendConfirmation(ctx context.Context, q db.Querier, history db.SubscriptionHistory) error {
+	group, err := q.GetGroup(ctx, history.GroupID)
+	if err != nil {
+		return fmt.Errorf("failed to get group: %w", err)
+	}
+	emails, err := q.ListPrimaryEmails(ctx, []uuid.UUID{history.ContactID})
+	if err != nil {
+		return fmt.Errorf("failed to get contact email: %w", err)
+	}
+	if len(emails) == 0 || emails[0].Email == "" || emails[0].OnHold.Bool {
+		return fmt.Errorf("%w: contact %s has no email to confirm with", ErrInvalidChange, history.ContactID)
+	}
+
+	title := group.Title.String
+	if title == "" {
+		title = group.Name
+	}
+	token := s.confirmToken(history.ID)
+	body := fmt.Sprintf("Please confirm your subscription to %s with this code:\n\n%s\n", title, token)
+	if s.config.ConfirmURL != "" {
+		link, err := url.Parse(s.config.ConfirmURL)
+		if err != nil {
+			return fmt.Errorf("invalid confirm URL: %w", err)
+		}
+		query := link.Query()
+		query.Set("token", token)
+		link.RawQuery = query.Encode()
+		body = fmt.Sprintf("Please confirm your subscription to %s by opening this link:\n\n%s\n", title, link)
+	}
+
+	err = s.mailer.Send(ctx, mail.Message{
+		To:      emails[0].Email,
+		Subject: "Confirm your subscription to " + strings.Join(strings.Fields(title), " "),
+		Body:    body + "\nIf you did not ask to subscribe, ignore this email.\n",
+	})
+	if errors.Is(err, mail.ErrDisabled) {
+		return fmt.Errorf("%w: confirmation emails cannot be sent: %v", ErrInvalidChange, err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to send confirmation email: %w", err)
+	}
+	return nil
+}
+
+// Confirm adds the contact of a pending subscription to its group. The
+// token is only valid for the change that made the subscription pending,
+// until the confirm TTL has passed since then.
+func (s *Service) Confirm(ctx context.Context, token string) (*db.GroupContact, error) {
+	historyID, err := s.parseConfirmToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	var subscription *db.GroupContact
+	err = s.tx.WithTx(ctx, func(q db.Querier) error {
+		pending, err := q.GetSubscriptionHistory(ctx, historyID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidToken
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get subscription history: %w", err)
+		}
+		if s.now().After(pending.Date.Add(s.config.ConfirmTTL)) {
+			return fmt.Errorf("%w: the token has expired", ErrInvalidToken)
+		}
+
+		current, err := q.GetGroupContactForUpdate(ctx, db.GetGroupContactForUpdateParams{GroupID: pending.GroupID, ContactID: pending.ContactID})
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to get group contact: %w", err)
+		}
+		latest, err := q.GetLatestSubscriptionHistory(ctx, db.GetLatestSubscriptionHistoryParams{GroupID: pending.GroupID, ContactID: pending.ContactID})
+		if err != nil {
+			return fmt.Errorf("failed to get subscription history: %w", err)
+		}
+		if latest.ID != pending.ID || current.Status.String != StatusPending {
+			return fmt.Errorf("%w: the subscription has changed since the token was issued", ErrInvalidToken)
+		}
+
+		subscription, err = s.setStatus(ctx, q, Change{
+			GroupID:   pending.GroupID,
+			ContactID: pending.ContactID,
+			Status:    StatusAdded,
+			Method:    MethodEmail,
+			Tracking:  "Confirmed subscription",
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.invalidateACL(ctx)
+	return subscription, nil
+}
+
+// History returns the subscription history of a contact, a group or both,
+// oldest first
+func (s *Service) History(ctx context.Context, contactID, groupID uuid.NullUUID) ([]db.SubscriptionHistory, error) {
+	history, err := s.queries.ListSubscriptionHistory(ctx, db.ListSubscriptionHistoryParams{ContactID: contactID, GroupID: groupID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscription history: %w", err)
+	}
+	return history, nil
+}
+
+// confirmToken signs the ID of the history row that made a subscription
+// pending
+func (s *Service) confirmToken(historyID uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString(historyID[:]) + "." + base64.RawURLEncoding.EncodeToString(s.sign(historyID[:]))
+}
+
+// parseConfirmToken returns the history ID a token was issued for
+func (s *Service) parseConfirmToken(token string) (uuid.UUID, error) {
+	encodedID, encodedMAC, ok := strings.Cut(token, ".")
+	if !ok {
+		return uuid.Nil, ErrInvalidToken
+	}
+	id, err := base64.RawURLEncoding.DecodeString(encodedID)
+	if err != nil || len(id) != len(uuid.UUID{}) {
+		return uuid.Nil, ErrInvalidToken
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(encodedMAC)
+	if err != nil || !hmac.Equal(mac, s.sign(id)) {
+		return uuid.Nil, ErrInvalidToken
+	}
+	return uuid.UUID(id), nil
+}
+
+// sign returns the HMAC of a confirmation token's payload. The secret may be
+// shared with JWTs, so the payload is prefixed to keep the two apart.
+func (s *Service) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(s.config.ConfirmSecret))
+	mac.Write([]byte("group-subscription-confirm:"))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
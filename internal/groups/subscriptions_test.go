@@ -0,0 +1,219 @@
+package groups
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/cache"
+	"github.com/jxlxx/civicrm/internal/cache/cachetest"
+	"github.com/jxlxx/civicrm/internal/database/dbtest"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/jxlxx/civicrm/internal/mail"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (q *fakeQuerier) GetContact(ctx context.Context, id uuid.UUID) (db.Contact, error) {
+	if !q.contacts[id] {
+		return db.Contact{}, sql.ErrNoRows
+	}
+	return db.Contact{ID: id}, nil
+}
+
+func (q *fakeQuerier) GetGroupContactForUpdate(ctx context.Context, arg db.GetGroupContactForUpdateParams) (db.GroupContact, error) {
+	member, ok := q.members[[2]uuid.UUID{arg.GroupID, arg.ContactID}]
+	if !ok {
+		return db.GroupContact{}, sql.ErrNoRows
+	}
+	return member, nil
+}
+
+func (q *fakeQuerier) UpsertGroupContact(ctx context.Context, arg db.UpsertGroupContactParams) (db.GroupContact, error) {
+	key := [2]uuid.UUID{arg.GroupID, arg.ContactID}
+	member, ok := q.members[key]
+	if !ok {
+		member = db.GroupContact{ID: uuid.New(), GroupID: arg.GroupID, ContactID: arg.ContactID}
+	}
+	member.Status = arg.Status
+	q.members[key] = member
+	return member, nil
+}
+
+func (q *fakeQuerier) CreateSubscriptionHistory(ctx context.Context, arg db.CreateSubscriptionHistoryParams) (db.SubscriptionHistory, error) {
+	history := db.SubscriptionHistory{
+		ID: uuid.New(), GroupID: arg.GroupID, ContactID: arg.ContactID, Status: arg.Status,
+		Method: arg.Method, Date: arg.Date, ActorID: arg.ActorID, Tracking: arg.Tracking,
+	}
+	q.history = append(q.history, history)
+	return history, nil
+}
+
+func (q *fakeQuerier) GetSubscriptionHistory(ctx context.Context, id uuid.UUID) (db.SubscriptionHistory, error) {
+	for _, history := range q.history {
+		if history.ID == id {
+			return history, nil
+		}
+	}
+	return db.SubscriptionHistory{}, sql.ErrNoRows
+}
+
+func (q *fakeQuerier) GetLatestSubscriptionHistory(ctx context.Context, arg db.GetLatestSubscriptionHistoryParams) (db.SubscriptionHistory, error) {
+	for i := len(q.history) - 1; i >= 0; i-- {
+		if q.history[i].GroupID == arg.GroupID && q.history[i].ContactID == arg.ContactID {
+			return q.history[i], nil
+		}
+	}
+	return db.SubscriptionHistory{}, sql.ErrNoRows
+}
+
+func (q *fakeQuerier) ListPrimaryEmails(ctx context.Context, contactIDs []uuid.UUID) ([]db.Email, error) {
+	var emails []db.Email
+	for _, id := range contactIDs {
+		if email, ok := q.emails[id]; ok {
+			emails = append(emails, db.Email{ContactID: id, Email: email})
+		}
+	}
+	return emails, nil
+}
+
+// fakeMailer records the messages sent
+type fakeMailer struct {
+	sent []mail.Message
+	err  error
+}
+
+func (m *fakeMailer) Send(ctx context.Context, message mail.Message) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, message)
+	return nil
+}
+
+// sentToken returns the confirmation token in the link of the last message
+// sent
+func sentToken(t *testing.T, service *Service) string {
+	sent := service.mailer.(*fakeMailer).sent
+	require.NotEmpty(t, sent)
+	for _, field := range strings.Fields(sent[len(sent)-1].Body) {
+		if strings.HasPrefix(field, service.config.ConfirmURL) {
+			link, err := url.Parse(field)
+			require.NoError(t, err)
+			return link.Query().Get("token")
+		}
+	}
+	t.Fatal("no confirmation link sent")
+	return ""
+}
+
+func TestSetStatusRecordsHistory(t *testing.T) {
+	service, q, group, _, contacts := newFixture()
+	actor := dbtest.UUID(uuid.New())
+
+	added, err := service.SetStatus(context.Background(), Change{
+		GroupID: group.ID, ContactID: contacts[0], Status: StatusAdded, Method: MethodAdmin, ActorID: actor,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, StatusAdded, added.Status.String)
+	assert.Empty(t, service.mailer.(*fakeMailer).sent)
+	assert.Equal(t, []string{cache.TagACL}, service.cache.(*cachetest.Invalidator).Tags())
+
+	// Setting the same status again is not a change
+	_, err = service.SetStatus(context.Background(), Change{GroupID: group.ID, ContactID: contacts[0], Status: StatusAdded, Method: MethodAPI})
+	require.NoError(t, err)
+
+	_, err = service.SetStatus(context.Background(), Change{
+		GroupID: group.ID, ContactID: contacts[0], Status: StatusRemoved, Method: MethodWeb, Tracking: "unsubscribe form",
+	})
+	require.NoError(t, err)
+
+	require.Len(t, q.history, 2)
+	assert.Equal(t, db.SubscriptionHistory{
+		ID: q.history[0].ID, GroupID: group.ID, ContactID: contacts[0],
+		Status: StatusAdded, Method: MethodAdmin, Date: now, ActorID: actor,
+	}, q.history[0])
+	assert.Equal(t, StatusRemoved, q.history[1].Status)
+	assert.Equal(t, MethodWeb, q.history[1].Method)
+	assert.False(t, q.history[1].ActorID.Valid)
+	assert.Equal(t, "unsubscribe form", q.history[1].Tracking.String)
+	assert.Equal(t, StatusRemoved, q.members[[2]uuid.UUID{group.ID, contacts[0]}].Status.String)
+}
+
+func TestSetStatusRejects(t *testing.T) {
+	service, _, group, _, contacts := newFixture()
+
+	_, err := service.SetStatus(context.Background(), Change{GroupID: group.ID, ContactID: contacts[0], Status: "Deleted", Method: MethodAdmin})
+	assert.ErrorIs(t, err, ErrInvalidChange)
+	_, err = service.SetStatus(context.Background(), Change{GroupID: group.ID, ContactID: contacts[0], Status: StatusAdded, Method: "Fax"})
+	assert.ErrorIs(t, err, ErrInvalidChange)
+	_, err = service.SetStatus(context.Background(), Change{GroupID: uuid.New(), ContactID: contacts[0], Status: StatusAdded, Method: MethodAdmin})
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = service.SetStatus(context.Background(), Change{GroupID: group.ID, ContactID: uuid.New(), Status: StatusAdded, Method: MethodAdmin})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestConfirm(t *testing.T) {
+	service, q, group, _, contacts := newFixture()
+	pending := Change{GroupID: group.ID, ContactID: contacts[0], Status: StatusPending, Method: MethodWeb}
+
+	subscription, err := service.SetStatus(context.Background(), pending)
+	require.NoError(t, err)
+	assert.Equal(t, StatusPending, subscription.Status.String)
+	mailer := service.mailer.(*fakeMailer)
+	require.Len(t, mailer.sent, 1)
+	assert.Equal(t, "ada@example.org", mailer.sent[0].To)
+	assert.Equal(t, "Confirm your subscription to students", mailer.sent[0].Subject)
+	token := sentToken(t, service)
+	require.NotEmpty(t, token)
+
+	// Asking again sends the same token
+	_, err = service.SetStatus(context.Background(), pending)
+	require.NoError(t, err)
+	require.Len(t, mailer.sent, 2)
+	assert.Equal(t, token, sentToken(t, service))
+
+	_, err = service.Confirm(context.Background(), token[:len(token)-2]+"xx")
+	assert.ErrorIs(t, err, ErrInvalidToken, "a forged token is rejected")
+
+	confirmed, err := service.Confirm(context.Background(), token)
+	require.NoError(t, err)
+	assert.Equal(t, StatusAdded, confirmed.Status.String)
+	require.Len(t, q.history, 2)
+	assert.Equal(t, StatusAdded, q.history[1].Status)
+	assert.Equal(t, MethodEmail, q.history[1].Method)
+
+	_, err = service.Confirm(context.Background(), token)
+	assert.ErrorIs(t, err, ErrInvalidToken, "a token confirms once")
+}
+
+func TestConfirmExpired(t *testing.T) {
+	service, _, group, _, contacts := newFixture()
+
+	_, err := service.SetStatus(context.Background(), Change{GroupID: group.ID, ContactID: contacts[1], Status: StatusPending, Method: MethodWeb})
+	require.NoError(t, err)
+
+	later := now.Add(25 * time.Hour)
+	service.now = func() time.Time { return later }
+	_, err = service.Confirm(context.Background(), sentToken(t, service))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestSetStatusPendingNeedsEmail(t *testing.T) {
+	service, q, group, _, contacts := newFixture()
+	pending := Change{GroupID: group.ID, ContactID: contacts[0], Status: StatusPending, Method: MethodWeb}
+
+	service.mailer.(*fakeMailer).err = mail.ErrDisabled
+	_, err := service.SetStatus(context.Background(), pending)
+	assert.ErrorIs(t, err, ErrInvalidChange, "pending needs email to be configured")
+
+	service.mailer.(*fakeMailer).err = nil
+	delete(q.emails, contacts[0])
+	_, err = service.SetStatus(context.Background(), pending)
+	assert.ErrorIs(t, err, ErrInvalidChange, "pending needs the contact to have an email")
+	assert.Empty(t, service.mailer.(*fakeMailer).sent)
+}
@@ -0,0 +1,209 @@
+// Package jobs runs the scheduled jobs in the jobs table. Each job has a
+// cron schedule and is run by the handler registered under its name; runs
+// are recorded in job_logs.
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/config"
+	"github.com/jxlxx/civicrm/internal/database"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/jxlxx/civicrm/internal/logger"
+)
+
+// Job log statuses
+const (
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+// Handler runs a job. The message it returns is stored in the job's log.
+type Handler func(ctx context.Context, job db.Job) (string, error)
+
+// Scope returns a context whose queries only see rows of domainID
+type Scope func(ctx context.Context, domainID uuid.UUID) context.Context
+
+// Scheduler runs due jobs every interval. Jobs are claimed in a transaction
+// that skips rows locked by other instances, so each run happens once. Each
+// handler runs scoped to its job's domain.
+type Scheduler struct {
+	queries  db.Querier
+	tx       database.Transactor
+	scope    Scope
+	config   *config.JobsConfig
+	logger   *logger.Logger
+	now      func() time.Time
+	mu       sync.RWMutex
+	handlers map[string]Handler
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// New creates a scheduler
+func New(database *database.Database, config *config.JobsConfig, logger *logger.Logger) *Scheduler {
+	return &Scheduler{
+		queries:  database.Querier(),
+		tx:       database,
+		scope:    database.WithDomain,
+		config:   config,
+		logger:   logger,
+		now:      time.Now,
+		handlers: make(map[string]Handler),
+	}
+}
+
+// Register sets the handler for the jobs named name
+func (s *Scheduler) Register(name string, handler Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[name] = handler
+}
+
+// Start runs due jobs every interval until Stop is called. It does nothing
+// when jobs are disabled.
+func (s *Scheduler) Start() {
+	if !s.config.Enabled || s.stop != nil {
+		return
+	}
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(s.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				if err := s.RunDue(context.Background()); err != nil {
+					s.logger.Error("Failed to run scheduled jobs", "error", err)
+				}
+			}
+		}
+	}()
+
+	s.logger.Info("Job scheduler started", "interval", s.config.Interval)
+}
+
+// Stop stops the scheduler and waits for running jobs to finish
+func (s *Scheduler) Stop() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+	s.stop = nil
+}
+
+// RunDue runs the jobs that are due in every domain. Domains missing a job
+// get a copy of the default domain's first. A job that has never been
+// scheduled is scheduled for its next time instead of running at once.
+func (s *Scheduler) RunDue(ctx context.Context) error {
+	s.mu.RLock()
+	names := make([]string, 0, len(s.handlers))
+	for name := range s.handlers {
+		names = append(names, name)
+	}
+	s.mu.RUnlock()
+	if len(names) == 0 {
+		return nil
+	}
+
+	now := s.now()
+	var due []db.Job
+	err := s.tx.WithTx(ctx, func(q db.Querier) error {
+		// The transaction is retried on serialization failures
+		due = due[:0]
+		seeded, err := q.SeedDomainJobs(ctx, names)
+		if err != nil {
+			return fmt.Errorf("failed to seed domain jobs: %w", err)
+		}
+		if seeded > 0 {
+			s.logger.Info("Scheduled jobs for new domains", "jobs", seeded)
+		}
+
+		jobs, err := q.ClaimDueJobs(ctx, names)
+		if err != nil {
+			return fmt.Errorf("failed to claim due jobs: %w", err)
+		}
+
+		for _, job := range jobs {
+			schedule, err := ParseSchedule(job.Schedule.String)
+			if err != nil {
+				s.logger.Error("Invalid job schedule", "job", job.Name, "job_id", job.ID, "error", err)
+				continue
+			}
+			next, err := schedule.Next(now)
+			if err != nil {
+				s.logger.Error("Invalid job schedule", "job", job.Name, "job_id", job.ID, "error", err)
+				continue
+			}
+
+			lastRun := job.LastRun
+			if job.NextRun.Valid {
+				lastRun = sql.NullTime{Time: now, Valid: true}
+				due = append(due, job)
+			}
+			if _, err := q.UpdateJobLastRun(ctx, db.UpdateJobLastRunParams{
+				ID:       job.ID,
+				DomainID: job.DomainID,
+				LastRun:  lastRun,
+				NextRun:  sql.NullTime{Time: next, Valid: true},
+			}); err != nil {
+				return fmt.Errorf("failed to schedule job %s: %w", job.Name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, job := range due {
+		s.run(ctx, job)
+	}
+	return nil
+}
+
+// run runs one job and records the run in its log
+func (s *Scheduler) run(ctx context.Context, job db.Job) {
+	s.mu.RLock()
+	handler := s.handlers[job.Name]
+	s.mu.RUnlock()
+
+	entry, err := s.queries.CreateJobLog(ctx, job.ID)
+	if err != nil {
+		s.logger.Error("Failed to create job log", "job", job.Name, "job_id", job.ID, "error", err)
+		return
+	}
+
+	start := time.Now()
+	message, err := handler(s.scope(ctx, job.DomainID), job)
+	status := StatusCompleted
+	if err != nil {
+		status, message = StatusFailed, err.Error()
+		s.logger.Error("Scheduled job failed", "job", job.Name, "job_id", job.ID, "error", err)
+	} else {
+		s.logger.Info("Scheduled job completed", "job", job.Name, "job_id", job.ID, "message", message)
+	}
+
+	if err := s.queries.FinishJobLog(ctx, db.FinishJobLogParams{
+		ID:              entry.ID,
+		Status:          status,
+		Message:         sql.NullString{String: message, Valid: message != ""},
+		ExecutionTimeMs: sql.NullInt32{Int32: int32(time.Since(start).Milliseconds()), Valid: true},
+	}); err != nil {
+		s.logger.Error("Failed to finish job log", "job", job.Name, "job_id", job.ID, "error", err)
+	}
+}
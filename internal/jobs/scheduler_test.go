@@ -0,0 +1,106 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/config"
+	"github.com/jxlxx/civicrm/internal/database/dbtest"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/jxlxx/civicrm/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeQuerier serves jobs from a slice and records scheduling and logs
+type fakeQuerier struct {
+	db.Querier
+	jobs      []db.Job
+	claimed   []string
+	seeded    []string
+	scheduled map[uuid.UUID]db.UpdateJobLastRunParams
+	logs      []db.FinishJobLogParams
+}
+
+func (q *fakeQuerier) SeedDomainJobs(ctx context.Context, names []string) (int64, error) {
+	q.seeded = names
+	return int64(len(names)), nil
+}
+
+func (q *fakeQuerier) ClaimDueJobs(ctx context.Context, names []string) ([]db.Job, error) {
+	q.claimed = names
+	return q.jobs, nil
+}
+
+func (q *fakeQuerier) UpdateJobLastRun(ctx context.Context, arg db.UpdateJobLastRunParams) (db.Job, error) {
+	q.scheduled[arg.ID] = arg
+	return db.Job{}, nil
+}
+
+func (q *fakeQuerier) CreateJobLog(ctx context.Context, jobID uuid.UUID) (db.JobLog, error) {
+	return db.JobLog{ID: uuid.New(), JobID: jobID}, nil
+}
+
+func (q *fakeQuerier) FinishJobLog(ctx context.Context, arg db.FinishJobLogParams) error {
+	q.logs = append(q.logs, arg)
+	return nil
+}
+
+type scopeKey struct{}
+
+func TestRunDue(t *testing.T) {
+	now := time.Date(2024, 5, 15, 10, 30, 0, 0, time.UTC)
+	domainID := uuid.New()
+	due := db.Job{ID: uuid.New(), DomainID: domainID, Name: "expire", Schedule: dbtest.Text("0 0 * * *"), NextRun: dbtest.Time(now.Add(-time.Minute))}
+	failing := db.Job{ID: uuid.New(), Name: "fail", Schedule: dbtest.Text("@hourly"), NextRun: dbtest.Time(now)}
+	unscheduled := db.Job{ID: uuid.New(), Name: "expire", Schedule: dbtest.Text("0 0 * * *")}
+	invalid := db.Job{ID: uuid.New(), Name: "expire", Schedule: dbtest.Text("every day")}
+
+	q := &fakeQuerier{jobs: []db.Job{due, failing, unscheduled, invalid}, scheduled: make(map[uuid.UUID]db.UpdateJobLastRunParams)}
+	var scoped []uuid.UUID
+	scheduler := &Scheduler{
+		queries: q,
+		tx:      dbtest.Tx{Q: q},
+		scope: func(ctx context.Context, domainID uuid.UUID) context.Context {
+			scoped = append(scoped, domainID)
+			return context.WithValue(ctx, scopeKey{}, domainID)
+		},
+		config:   &config.JobsConfig{Enabled: true, Interval: time.Minute},
+		logger:   logger.NewNop(),
+		now:      func() time.Time { return now },
+		handlers: make(map[string]Handler),
+	}
+
+	var ran []uuid.UUID
+	scheduler.Register("expire", func(ctx context.Context, job db.Job) (string, error) {
+		assert.Equal(t, job.DomainID, ctx.Value(scopeKey{}))
+		ran = append(ran, job.ID)
+		return "expired 2", nil
+	})
+	scheduler.Register("fail", func(ctx context.Context, job db.Job) (string, error) {
+		return "", errors.New("boom")
+	})
+
+	require.NoError(t, scheduler.RunDue(context.Background()))
+	assert.ElementsMatch(t, []string{"expire", "fail"}, q.seeded, "domains get the handled jobs they lack")
+	assert.ElementsMatch(t, []string{"expire", "fail"}, q.claimed)
+
+	// Only due jobs run; a job without a next run is only scheduled
+	assert.Equal(t, []uuid.UUID{due.ID}, ran)
+	assert.Equal(t, []uuid.UUID{domainID, failing.DomainID}, scoped)
+	assert.Equal(t, time.Date(2024, 5, 16, 0, 0, 0, 0, time.UTC), q.scheduled[due.ID].NextRun.Time)
+	assert.Equal(t, now, q.scheduled[due.ID].LastRun.Time)
+	assert.Equal(t, time.Date(2024, 5, 15, 11, 0, 0, 0, time.UTC), q.scheduled[failing.ID].NextRun.Time)
+	assert.Equal(t, time.Date(2024, 5, 16, 0, 0, 0, 0, time.UTC), q.scheduled[unscheduled.ID].NextRun.Time)
+	assert.False(t, q.scheduled[unscheduled.ID].LastRun.Valid)
+	assert.NotContains(t, q.scheduled, invalid.ID)
+
+	require.Len(t, q.logs, 2)
+	assert.Equal(t, StatusCompleted, q.logs[0].Status)
+	assert.Equal(t, "expired 2", q.logs[0].Message.String)
+	assert.Equal(t, StatusFailed, q.logs[1].Status)
+	assert.Equal(t, "boom", q.logs[1].Message.String)
+}
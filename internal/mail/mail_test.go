@@ -0,0 +1,100 @@
+package mail
+
+import (
+	"context"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jxlxx/civicrm/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDisabled(t *testing.T) {
+	sender := New(&config.MailConfig{Port: 587})
+	assert.ErrorIs(t, sender.Send(context.Background(), Message{To: "ada@example.org"}), ErrDisabled)
+}
+
+func TestFormat(t *testing.T) {
+	from := &mail.Address{Name: "Members", Address: "members@example.org"}
+	to := &mail.Address{Address: "ada@example.org"}
+
+	body, err := format(from, to, Message{Subject: "Confirm your subscription", Body: "Hello\nworld"})
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "From: \"Members\" <members@example.org>\r\n")
+	assert.Contains(t, string(body), "To: <ada@example.org>\r\n")
+	assert.Contains(t, string(body), "Subject: Confirm your subscription\r\n")
+	assert.True(t, strings.HasSuffix(string(body), "\r\n\r\nHello\r\nworld"))
+
+	_, err = format(from, to, Message{Subject: "Hi\r\nBcc: eve@example.org"})
+	assert.Error(t, err)
+}
+
+func TestSMTPSend(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	received := make(chan []string, 1)
+	go serveSMTP(t, listener, received)
+
+	host, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+	portNumber, err := strconv.Atoi(port)
+	require.NoError(t, err)
+
+	sender := New(&config.MailConfig{Host: host, Port: portNumber, From: "members@example.org"})
+	err = sender.Send(context.Background(), Message{To: "ada@example.org", Subject: "Confirm", Body: "Open the link"})
+	require.NoError(t, err)
+
+	commands := <-received
+	assert.Contains(t, commands, "MAIL FROM:<members@example.org>")
+	assert.Contains(t, commands, "RCPT TO:<ada@example.org>")
+	assert.Contains(t, commands, "Open the link")
+
+	err = sender.Send(context.Background(), Message{To: "not an address"})
+	assert.Error(t, err)
+}
+
+// serveSMTP accepts one session and sends back the lines it received
+func serveSMTP(t *testing.T, listener net.Listener, received chan<- []string) {
+	conn, err := listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	text := textproto.NewConn(conn)
+	var lines []string
+	_ = text.PrintfLine("220 localhost ready")
+	data := false
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			break
+		}
+		lines = append(lines, line)
+		switch {
+		case data && line == ".":
+			data = false
+			_ = text.PrintfLine("250 queued")
+		case data:
+		case strings.HasPrefix(line, "EHLO"), strings.HasPrefix(line, "HELO"):
+			_ = text.PrintfLine("250 localhost")
+		case line == "DATA":
+			data = true
+			_ = text.PrintfLine("354 go ahead")
+		case line == "QUIT":
+			_ = text.PrintfLine("221 bye")
+			received <- lines
+			return
+		default:
+			_ = text.PrintfLine("250 ok")
+		}
+	}
+	received <- lines
+}
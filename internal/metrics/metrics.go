@@ -0,0 +1,209 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Metric types understood by Prometheus
+const (
+	CounterType = "counter"
+	GaugeType   = "gauge"
+)
+
+// Sample is a single metric value with its labels
+type Sample struct {
+	Name   string
+	Help   string
+	Type   string
+	Labels map[string]string
+	Value  float64
+}
+
+// Collector produces samples when metrics are scraped
+type Collector interface {
+	Collect() []Sample
+}
+
+// CollectorFunc adapts a function to the Collector interface
+type CollectorFunc func() []Sample
+
+// Collect calls f
+func (f CollectorFunc) Collect() []Sample {
+	return f()
+}
+
+// Registry holds the collectors exposed on the metrics endpoint
+type Registry struct {
+	collectors []Collector
+	mutex      sync.RWMutex
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a collector to the registry
+func (r *Registry) Register(collector Collector) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.collectors = append(r.collectors, collector)
+}
+
+// Gather collects samples from every registered collector
+func (r *Registry) Gather() []Sample {
+	r.mutex.RLock()
+	collectors := append([]Collector(nil), r.collectors...)
+	r.mutex.RUnlock()
+
+	var samples []Sample
+	for _, collector := range collectors {
+		samples = append(samples, collector.Collect()...)
+	}
+	return samples
+}
+
+// Handler serves the registry in the Prometheus text exposition format
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		Write(w, r.Gather())
+	})
+}
+
+// Write renders samples in the Prometheus text exposition format, grouping
+// samples of the same metric under one HELP and TYPE header
+func Write(w io.Writer, samples []Sample) {
+	sort.SliceStable(samples, func(i, j int) bool {
+		return samples[i].Name < samples[j].Name
+	})
+
+	var current string
+	for _, sample := range samples {
+		if sample.Name != current {
+			current = sample.Name
+			if sample.Help != "" {
+				fmt.Fprintf(w, "# HELP %s %s\n", sample.Name, sample.Help)
+			}
+			if sample.Type != "" {
+				fmt.Fprintf(w, "# TYPE %s %s\n", sample.Name, sample.Type)
+			}
+		}
+		fmt.Fprintf(w, "%s%s %s\n", sample.Name, formatLabels(sample.Labels), strconv.FormatFloat(sample.Value, 'g', -1, 64))
+	}
+}
+
+// formatLabels renders labels sorted by name
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s=%q", name, labels[name]))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// CounterVec is a set of counters partitioned by label values
+type CounterVec struct {
+	name     string
+	help     string
+	labels   []string
+	counters map[string]*counter
+	mutex    sync.RWMutex
+}
+
+type counter struct {
+	values []string
+	value  atomic.Uint64
+}
+
+// NewCounterVec creates a counter partitioned by the given label names
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{
+		name:     name,
+		help:     help,
+		labels:   labels,
+		counters: make(map[string]*counter),
+	}
+}
+
+// Inc increments the counter for the given label values
+func (c *CounterVec) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds n to the counter for the given label values
+func (c *CounterVec) Add(n uint64, values ...string) {
+	key := strings.Join(values, "\xff")
+
+	c.mutex.RLock()
+	ctr, exists := c.counters[key]
+	c.mutex.RUnlock()
+
+	if !exists {
+		c.mutex.Lock()
+		if ctr, exists = c.counters[key]; !exists {
+			ctr = &counter{values: append([]string(nil), values...)}
+			c.counters[key] = ctr
+		}
+		c.mutex.Unlock()
+	}
+
+	ctr.value.Add(n)
+}
+
+// Value returns the current count for the given label values
+func (c *CounterVec) Value(values ...string) uint64 {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if ctr, exists := c.counters[strings.Join(values, "\xff")]; exists {
+		return ctr.value.Load()
+	}
+	return 0
+}
+
+// Each calls fn with the label values and count of every counter
+func (c *CounterVec) Each(fn func(values []string, count uint64)) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	for _, ctr := range c.counters {
+		fn(ctr.values, ctr.value.Load())
+	}
+}
+
+// Collect implements Collector
+func (c *CounterVec) Collect() []Sample {
+	var samples []Sample
+	c.Each(func(values []string, count uint64) {
+		labels := make(map[string]string, len(c.labels))
+		for i, name := range c.labels {
+			if i < len(values) {
+				labels[name] = values[i]
+			}
+		}
+		samples = append(samples, Sample{
+			Name:   c.name,
+			Help:   c.help,
+			Type:   CounterType,
+			Labels: labels,
+			Value:  float64(count),
+		})
+	})
+	return samples
+}
@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounterVec(t *testing.T) {
+	counter := NewCounterVec("requests_total", "Requests.", "method")
+	counter.Inc("GET")
+	counter.Add(2, "GET")
+	counter.Inc("POST")
+
+	assert.Equal(t, uint64(3), counter.Value("GET"))
+	assert.Equal(t, uint64(1), counter.Value("POST"))
+	assert.Zero(t, counter.Value("PUT"))
+}
+
+func TestRegistryHandler(t *testing.T) {
+	counter := NewCounterVec("requests_total", "Requests.", "method")
+	counter.Inc("GET")
+
+	registry := NewRegistry()
+	registry.Register(counter)
+	registry.Register(CollectorFunc(func() []Sample {
+		return []Sample{{Name: "entries", Type: GaugeType, Value: 42}}
+	}))
+
+	recorder := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	body := recorder.Body.String()
+	assert.Equal(t, strings.Join([]string{
+		"# TYPE entries gauge",
+		"entries 42",
+		"# HELP requests_total Requests.",
+		"# TYPE requests_total counter",
+		`requests_total{method="GET"} 1`,
+		"",
+	}, "\n"), body)
+}
@@ -0,0 +1,437 @@
+package migrate
+
+import (
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Lint rules. A statement can opt out of a rule with a comment such as
+// "-- lint:ignore create-index" inside or just above it.
+const (
+	RuleAddColumnDefault = "add-column-default"
+	RuleCreateIndex      = "create-index"
+	RuleAlterType        = "alter-type"
+	RuleDropColumn       = "drop-column"
+)
+
+// DefaultLargeTables are the tables that grow with the number of contacts and
+// cannot be rewritten or locked for long on a live database
+var DefaultLargeTables = []string{
+	"contacts",
+	"contributions",
+	"activities",
+	"activity_contacts",
+	"addresses",
+	"emails",
+	"phones",
+	"participants",
+	"event_registrations",
+	"memberships",
+	"group_contacts",
+	"entity_tags",
+	"line_items",
+}
+
+// Finding is an operation in a migration that may block writes while it runs
+type Finding struct {
+	Migration string
+	Line      int
+	Rule      string
+	Message   string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s", f.Migration, f.Line, f.Rule, f.Message)
+}
+
+// Linter checks migrations for schema changes that take long locks on
+// existing tables
+type Linter struct {
+	// LargeTables are checked for column defaults that rewrite the table
+	LargeTables []string
+
+	// Queries holds the sqlc query files checked for dropped columns
+	Queries fs.FS
+}
+
+// NewLinter creates a linter that checks dropped columns against queries
+func NewLinter(queries fs.FS) *Linter {
+	return &Linter{
+		LargeTables: DefaultLargeTables,
+		Queries:     queries,
+	}
+}
+
+var (
+	createTablePattern = regexp.MustCompile(`(?i)^create (?:(?:global |local )?(?:temp|temporary|unlogged) )?table (?:if not exists )?([\w."]+)`)
+	createTypePattern  = regexp.MustCompile(`(?i)^create type ([\w."]+)`)
+	createIndexPattern = regexp.MustCompile(`(?i)^create (?:unique )?index (concurrently )?(?:if not exists )?(?:[\w"]+ )?on (?:only )?([\w."]+)`)
+	alterTablePattern  = regexp.MustCompile(`(?i)^alter table (?:if exists )?(?:only )?([\w."]+) (.*)$`)
+	alterTypePattern   = regexp.MustCompile(`(?i)^alter type ([\w."]+)`)
+
+	addColumnPattern  = regexp.MustCompile(`(?i)^add (?:column )?(?:if not exists )?([\w"]+)`)
+	columnTypePattern = regexp.MustCompile(`(?i)^alter (?:column )?([\w"]+) (?:set data )?type\b`)
+	dropColumnPattern = regexp.MustCompile(`(?i)^drop (?:column )?(?:if exists )?([\w"]+)`)
+	defaultPattern    = regexp.MustCompile(`(?i)\bdefault\b`)
+	ignorePattern     = regexp.MustCompile(`lint:ignore\s+([\w\-, ]+)`)
+	queryNamePattern  = regexp.MustCompile(`(?m)^-- name: (\w+)`)
+	notColumnKeywords = map[string]bool{"constraint": true, "primary": true, "unique": true, "foreign": true, "check": true, "exclude": true}
+
+	// tableRefPattern matches a table a query reads or writes, and
+	// aliasPattern the alias that may follow it
+	tableRefPattern = regexp.MustCompile(`(?i)\b(?:from|join|update|into)\s+(?:only\s+)?([\w."]+)(\()?`)
+	aliasPattern    = regexp.MustCompile(`(?i)^\s+(?:as\s+)?([\w"]+)`)
+	sqlKeywords     = map[string]bool{
+		"where": true, "join": true, "left": true, "right": true, "inner": true, "outer": true, "full": true,
+		"cross": true, "natural": true, "lateral": true, "on": true, "using": true, "set": true, "values": true,
+		"select": true, "returning": true, "order": true, "group": true, "limit": true, "offset": true,
+		"for": true, "union": true, "except": true, "intersect": true, "having": true, "window": true,
+		"default": true, "as": true,
+	}
+)
+
+// Lint checks migrations that are about to be applied, in order. Tables and
+// types created by one of them are new and empty, so later changes to them in
+// the same run are not reported.
+func (l *Linter) Lint(migrations []*Migration) ([]Finding, error) {
+	large := make(map[string]bool, len(l.LargeTables))
+	for _, table := range l.LargeTables {
+		large[table] = true
+	}
+
+	queries, err := l.loadQueries()
+	if err != nil {
+		return nil, err
+	}
+
+	created := make(map[string]bool)
+	var findings []Finding
+
+	for _, migration := range migrations {
+		for _, stmt := range splitStatements(migration.Up) {
+			report := func(rule, format string, args ...interface{}) {
+				if stmt.ignores(rule) {
+					return
+				}
+				findings = append(findings, Finding{
+					Migration: migration.Name,
+					Line:      stmt.line,
+					Rule:      rule,
+					Message:   fmt.Sprintf(format, args...),
+				})
+			}
+
+			if match := createTablePattern.FindStringSubmatch(stmt.sql); match != nil {
+				created[objectName(match[1])] = true
+				continue
+			}
+			if match := createTypePattern.FindStringSubmatch(stmt.sql); match != nil {
+				created[objectName(match[1])] = true
+				continue
+			}
+
+			if match := createIndexPattern.FindStringSubmatch(stmt.sql); match != nil {
+				table := objectName(match[2])
+				switch {
+				case match[1] != "" && !migration.DisableTx:
+					report(RuleCreateIndex, "CREATE INDEX CONCURRENTLY on %s cannot run in a transaction; add %q to the migration", table, disableTxMarker)
+				case match[1] == "" && !created[table]:
+					report(RuleCreateIndex, "CREATE INDEX on %s blocks writes until the index is built; use CREATE INDEX CONCURRENTLY", table)
+				}
+				continue
+			}
+
+			if match := alterTypePattern.FindStringSubmatch(stmt.sql); match != nil {
+				if name := objectName(match[1]); !created[name] {
+					report(RuleAlterType, "ALTER TYPE %s locks every table that uses the type", name)
+				}
+				continue
+			}
+
+			match := alterTablePattern.FindStringSubmatch(stmt.sql)
+			if match == nil {
+				continue
+			}
+			table := objectName(match[1])
+			if created[table] {
+				continue
+			}
+
+			for _, action := range splitActions(match[2]) {
+				if add := addColumnPattern.FindStringSubmatch(action); add != nil && !notColumnKeywords[strings.ToLower(add[1])] {
+					if large[table] && defaultPattern.MatchString(action) {
+						report(RuleAddColumnDefault, "adding %s.%s with a default can rewrite the table; add the column, backfill in batches, then set the default", table, objectName(add[1]))
+					}
+					continue
+				}
+
+				if alter := columnTypePattern.FindStringSubmatch(action); alter != nil {
+					report(RuleAlterType, "changing the type of %s.%s rewrites the table under an exclusive lock", table, objectName(alter[1]))
+					continue
+				}
+
+				if drop := dropColumnPattern.FindStringSubmatch(action); drop != nil && !notColumnKeywords[strings.ToLower(drop[1])] {
+					column := objectName(drop[1])
+					if users := queries.using(table, column); len(users) > 0 {
+						report(RuleDropColumn, "%s.%s is still used by %s", table, column, strings.Join(users, ", "))
+					}
+				}
+			}
+		}
+	}
+
+	return findings, nil
+}
+
+// statement is one SQL statement. sql has comments removed and whitespace
+// collapsed for matching; text is the statement as written.
+type statement struct {
+	sql      string
+	text     string
+	line     int
+	comments string
+}
+
+// ignores reports whether the statement's comments disable rule
+func (s statement) ignores(rule string) bool {
+	for _, match := range ignorePattern.FindAllStringSubmatch(s.comments, -1) {
+		for _, name := range strings.FieldsFunc(match[1], func(r rune) bool { return r == ',' || r == ' ' }) {
+			if name == rule {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// splitStatements splits SQL on semicolons outside quotes, dollar-quoted
+// bodies and comments. Whitespace in each statement is collapsed to single
+// spaces and the line it starts on is kept for reporting.
+func splitStatements(sql string) []statement {
+	var (
+		statements []statement
+		current    strings.Builder
+		comments   strings.Builder
+		line       = 1
+		start      = 0
+		begin      = 0
+		i          = 0
+	)
+
+	flush := func() {
+		text := strings.Join(strings.Fields(current.String()), " ")
+		if text != "" {
+			statements = append(statements, statement{
+				sql:      text,
+				text:     strings.TrimSpace(sql[begin:i]),
+				line:     start,
+				comments: comments.String(),
+			})
+		}
+		current.Reset()
+		comments.Reset()
+		start = 0
+	}
+
+	write := func(s string) {
+		if start == 0 && strings.TrimSpace(s) != "" {
+			start = line
+			begin = i
+		}
+		current.WriteString(s)
+		line += strings.Count(s, "\n")
+	}
+
+	for i < len(sql) {
+		rest := sql[i:]
+		switch {
+		case strings.HasPrefix(rest, "--"):
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			comments.WriteString(rest[:end] + "\n")
+			i += end
+
+		case strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest, "*/")
+			if end < 0 {
+				end = len(rest) - 2
+			}
+			comments.WriteString(rest[:end+2] + "\n")
+			line += strings.Count(rest[:end+2], "\n")
+			current.WriteByte(' ')
+			i += end + 2
+
+		case rest[0] == '\'':
+			end := 1
+			for end < len(rest) {
+				if rest[end] == '\'' {
+					if end+1 < len(rest) && rest[end+1] == '\'' {
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			end = min(end+1, len(rest))
+			write(rest[:end])
+			i += end
+
+		case rest[0] == '$':
+			if tag := dollarTag(rest); tag != "" {
+				end := strings.Index(rest[len(tag):], tag)
+				if end < 0 {
+					end = len(rest) - 2*len(tag)
+				}
+				write(rest[:end+2*len(tag)])
+				i += end + 2*len(tag)
+				continue
+			}
+			write(rest[:1])
+			i++
+
+		case rest[0] == ';':
+			flush()
+			i++
+
+		default:
+			write(rest[:1])
+			i++
+		}
+	}
+	flush()
+
+	return statements
+}
+
+var dollarTagPattern = regexp.MustCompile(`^\$[A-Za-z_]*\$`)
+
+// dollarTag returns the opening tag of a dollar-quoted string, if s starts
+// with one
+func dollarTag(s string) string {
+	return dollarTagPattern.FindString(s)
+}
+
+// splitActions splits the actions of an ALTER TABLE on commas outside
+// parentheses
+func splitActions(actions string) []string {
+	var (
+		parts []string
+		depth int
+		start int
+	)
+	for i, r := range actions {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(actions[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, strings.TrimSpace(actions[start:]))
+}
+
+// objectName normalizes an identifier by dropping quotes, the public schema
+// and case
+func objectName(name string) string {
+	name = strings.ToLower(strings.ReplaceAll(name, `"`, ""))
+	return strings.TrimPrefix(name, "public.")
+}
+
+// queryIndex holds the text of each named sqlc query
+type queryIndex map[string]string
+
+// loadQueries reads every query file, keyed by "Name (file.sql)"
+func (l *Linter) loadQueries() (queryIndex, error) {
+	index := make(queryIndex)
+	if l.Queries == nil {
+		return index, nil
+	}
+
+	files, err := fs.Glob(l.Queries, "*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list queries: %w", err)
+	}
+
+	for _, file := range files {
+		data, err := fs.ReadFile(l.Queries, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read queries %s: %w", file, err)
+		}
+
+		text := string(data)
+		matches := queryNamePattern.FindAllStringSubmatchIndex(text, -1)
+		for i, match := range matches {
+			end := len(text)
+			if i+1 < len(matches) {
+				end = matches[i+1][0]
+			}
+			name := fmt.Sprintf("%s (%s)", text[match[2]:match[3]], file)
+			index[name] = strings.ToLower(text[match[1]:end])
+		}
+	}
+	return index, nil
+}
+
+// using returns the queries that reference column of table. A reference
+// qualified by the table or one of its aliases always counts. An
+// unqualified one only counts in queries that use no other table, since it
+// could belong to any of them.
+func (q queryIndex) using(table, column string) []string {
+	qualified := regexp.MustCompile(`([\w"]+)\.` + regexp.QuoteMeta(column) + `\b`)
+	unqualified := regexp.MustCompile(`(?:^|[^.\w"])` + regexp.QuoteMeta(column) + `\b`)
+
+	var names []string
+	for name, text := range q {
+		refs := tableReferences(text)
+		tables := make(map[string]bool)
+		for _, referenced := range refs {
+			tables[referenced] = true
+		}
+		if !tables[table] {
+			continue
+		}
+
+		used := len(tables) == 1 && unqualified.MatchString(text)
+		for _, match := range qualified.FindAllStringSubmatch(text, -1) {
+			if refs[objectName(match[1])] == table {
+				used = true
+				break
+			}
+		}
+		if used {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// tableReferences maps the names a query uses for its tables, the table
+// names themselves and their aliases, to the tables
+func tableReferences(text string) map[string]string {
+	refs := make(map[string]string)
+	for _, match := range tableRefPattern.FindAllStringSubmatchIndex(text, -1) {
+		table := objectName(text[match[2]:match[3]])
+		if match[4] >= 0 || sqlKeywords[table] {
+			// A function such as unnest(...), or ON CONFLICT ... DO UPDATE SET
+			continue
+		}
+		refs[table] = table
+
+		if alias := aliasPattern.FindStringSubmatch(text[match[1]:]); alias != nil && !sqlKeywords[objectName(alias[1])] {
+			refs[objectName(alias[1])] = table
+		}
+	}
+	return refs
+}
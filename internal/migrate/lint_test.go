@@ -0,0 +1,139 @@
+package migrate
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/jxlxx/civicrm/internal/database/queries"
+	"github.com/jxlxx/civicrm/migrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	linter := NewLinter(fstest.MapFS{
+		"contacts.sql": {Data: []byte("-- name: GetContactByNickname :one\nSELECT id FROM contacts WHERE nickname = $1;\n\n-- name: ListContacts :many\nSELECT id, email FROM contacts;\n")},
+	})
+
+	findings, err := linter.Lint([]*Migration{{
+		Name: "036_example.sql",
+		Up: `-- Safe: new table
+CREATE TABLE notes (id UUID PRIMARY KEY, body TEXT DEFAULT '');
+CREATE INDEX idx_notes_body ON notes(body);
+ALTER TABLE notes ADD COLUMN pinned BOOLEAN DEFAULT false;
+
+ALTER TABLE contacts ADD COLUMN score INT DEFAULT 0, ADD CONSTRAINT score_positive CHECK (score >= 0);
+ALTER TABLE countries ADD COLUMN flag TEXT DEFAULT 'none';
+CREATE INDEX idx_contacts_score
+    ON contacts(score);
+ALTER TABLE contacts ALTER COLUMN score TYPE BIGINT;
+ALTER TYPE contact_kind ADD VALUE 'household';
+ALTER TABLE contacts DROP COLUMN nickname, DROP CONSTRAINT contacts_email_key;
+ALTER TABLE contacts DROP COLUMN legacy_id;
+
+CREATE FUNCTION touch() RETURNS trigger AS $$
+BEGIN
+    ALTER TABLE contacts ALTER COLUMN score TYPE INT;
+END;
+$$ LANGUAGE plpgsql;
+
+-- lint:ignore create-index
+CREATE INDEX idx_contacts_reviewed ON contacts(score);`,
+	}})
+	require.NoError(t, err)
+
+	var got []string
+	for _, finding := range findings {
+		got = append(got, finding.Rule)
+		assert.Equal(t, "036_example.sql", finding.Migration)
+	}
+	assert.Equal(t, []string{RuleAddColumnDefault, RuleCreateIndex, RuleAlterType, RuleAlterType, RuleDropColumn}, got)
+
+	assert.Equal(t, 6, findings[0].Line)
+	assert.Equal(t, 8, findings[1].Line)
+	assert.Contains(t, findings[4].Message, "GetContactByNickname (contacts.sql)")
+	assert.NotContains(t, findings[4].Message, "ListContacts")
+}
+
+func TestSplitStatements(t *testing.T) {
+	statements := splitStatements(`-- Indexes
+CREATE INDEX CONCURRENTLY idx_a ON a(id);
+
+DO $$
+BEGIN
+    -- keep; this
+    PERFORM 1;
+END $$;`)
+	require.Len(t, statements, 2)
+
+	assert.Equal(t, "CREATE INDEX CONCURRENTLY idx_a ON a(id)", statements[0].text)
+	assert.Equal(t, 2, statements[0].line)
+	assert.Equal(t, "DO $$\nBEGIN\n    -- keep; this\n    PERFORM 1;\nEND $$", statements[1].text)
+	assert.Equal(t, 4, statements[1].line)
+}
+
+func TestLintDropColumnReferences(t *testing.T) {
+	linter := NewLinter(fstest.MapFS{
+		"contacts.sql": {Data: []byte(`-- name: ListContactEmails :many
+SELECT c.id, e.email FROM contacts c JOIN emails e ON e.contact_id = c.id;
+
+-- name: SearchContacts :many
+SELECT c.id FROM contacts AS c WHERE c.nickname ILIKE $1;
+
+-- name: ListContactsWithEmails :many
+SELECT contacts.id, nickname FROM contacts JOIN emails ON emails.contact_id = contacts.id;
+
+-- name: UpdateNickname :exec
+UPDATE contacts SET nickname = $2 WHERE id = $1;
+`)},
+	})
+
+	findings, err := linter.Lint([]*Migration{{
+		Name: "036_example.sql",
+		Up:   "ALTER TABLE contacts DROP COLUMN email; ALTER TABLE contacts DROP COLUMN nickname;",
+	}})
+	require.NoError(t, err)
+
+	// e.email belongs to emails, and an unqualified column in a join could
+	// belong to either table
+	require.Len(t, findings, 1)
+	assert.Equal(t, "contacts.nickname is still used by SearchContacts (contacts.sql), UpdateNickname (contacts.sql)", findings[0].Message)
+}
+
+func TestLintConcurrentIndexNeedsDisableTx(t *testing.T) {
+	migration := &Migration{Name: "036_example.sql", Up: "CREATE INDEX CONCURRENTLY idx_contacts_score ON contacts(score);"}
+
+	findings, err := NewLinter(nil).Lint([]*Migration{migration})
+	require.NoError(t, err)
+	require.Len(t, findings, 1)
+	assert.Contains(t, findings[0].Message, disableTxMarker)
+
+	migration.DisableTx = true
+	findings, err = NewLinter(nil).Lint([]*Migration{migration})
+	require.NoError(t, err)
+	assert.Empty(t, findings)
+}
+
+// TestLintEmbeddedMigrations tests that a fresh database can be migrated
+// without findings, since every table is created in the same run
+func TestLintEmbeddedMigrations(t *testing.T) {
+	loaded, err := Load(migrations.FS)
+	require.NoError(t, err)
+
+	findings, err := NewLinter(queries.FS).Lint(loaded)
+	require.NoError(t, err)
+	assert.Empty(t, findings)
+}
+
+// TestLintDomainMigrations tests that the migrations since domains were
+// added apply to an existing database without findings
+func TestLintDomainMigrations(t *testing.T) {
+	loaded, err := Load(migrations.FS)
+	require.NoError(t, err)
+	require.Greater(t, len(loaded), 34)
+	require.Equal(t, "035_domain_isolation.sql", loaded[34].Name)
+
+	findings, err := NewLinter(queries.FS).Lint(loaded[34:])
+	require.NoError(t, err)
+	assert.Empty(t, findings)
+}
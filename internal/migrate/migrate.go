@@ -0,0 +1,303 @@
+// Package migrate applies the embedded SQL migrations. It reads the same
+// files, version table and advisory lock as tern, so a database can be moved
+// between tern and the server binary in either direction.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jxlxx/civicrm/internal/logger"
+)
+
+const (
+	// VersionTable is the table tern records the current version in
+	VersionTable = "public.schema_version"
+
+	// lockID is the advisory lock key tern takes while migrating, so replicas
+	// running the server and operators running tern never race each other
+	lockID = 9628173550095224
+
+	splitMarker     = "---- create above / drop below ----"
+	disableTxMarker = "---- tern: disable-tx ----"
+)
+
+// ErrIrreversible is returned when migrating down through a migration that
+// has no drop section
+var ErrIrreversible = errors.New("migration cannot be reverted")
+
+var filePattern = regexp.MustCompile(`^(\d+)_.+\.sql$`)
+
+// Migration is a single numbered migration file
+type Migration struct {
+	Version   int32
+	Name      string
+	Up        string
+	Down      string
+	DisableTx bool
+}
+
+// Load reads migrations from the root of fsys. Files must be named
+// NNN_description.sql and numbered from 1 without gaps; other files are
+// ignored.
+func Load(fsys fs.FS) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	var migrations []*Migration
+	for _, entry := range entries {
+		match := filePattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		migrations = append(migrations, parse(int32(version), entry.Name(), string(data)))
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	for i, migration := range migrations {
+		if migration.Version != int32(i+1) {
+			if i > 0 && migration.Version == migrations[i-1].Version {
+				return nil, fmt.Errorf("duplicate migration version %d", migration.Version)
+			}
+			return nil, fmt.Errorf("missing migration version %d", i+1)
+		}
+	}
+
+	return migrations, nil
+}
+
+// parse splits a migration file on the tern markers
+func parse(version int32, name, sql string) *Migration {
+	migration := &Migration{Version: version, Name: name}
+
+	if strings.Contains(sql, disableTxMarker) {
+		migration.DisableTx = true
+		sql = strings.Replace(sql, disableTxMarker, "", 1)
+	}
+
+	up, down, _ := strings.Cut(sql, splitMarker)
+	migration.Up = strings.TrimSpace(up)
+	migration.Down = strings.TrimSpace(down)
+	return migration
+}
+
+// Status describes the state of the database relative to the migrations
+type Status struct {
+	Current int32
+	Latest  int32
+	Pending []*Migration
+}
+
+// Migrator applies migrations to a PostgreSQL database
+type Migrator struct {
+	db         *sql.DB
+	migrations []*Migration
+	logger     *logger.Logger
+}
+
+// New creates a migrator for the given migrations
+func New(db *sql.DB, migrations []*Migration, logger *logger.Logger) *Migrator {
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		logger:     logger,
+	}
+}
+
+// Latest returns the highest migration version
+func (m *Migrator) Latest() int32 {
+	return int32(len(m.migrations))
+}
+
+// Status returns the current version and the migrations not yet applied
+func (m *Migrator) Status(ctx context.Context) (*Status, error) {
+	status := &Status{Latest: m.Latest()}
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		current, err := currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		status.Current = current
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if status.Current >= 0 && status.Current < status.Latest {
+		status.Pending = m.migrations[status.Current:]
+	}
+	return status, nil
+}
+
+// Up applies every pending migration
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.To(ctx, m.Latest())
+}
+
+// Down reverts the most recently applied migration
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		current, err := currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if current == 0 {
+			return errors.New("no migrations to revert")
+		}
+		return m.migrateTo(ctx, conn, current, current-1)
+	})
+}
+
+// To migrates up or down to the target version. Version 0 reverts every
+// migration.
+func (m *Migrator) To(ctx context.Context, target int32) error {
+	if target < 0 || target > m.Latest() {
+		return fmt.Errorf("target version %d is outside 0..%d", target, m.Latest())
+	}
+
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		current, err := currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		return m.migrateTo(ctx, conn, current, target)
+	})
+}
+
+// migrateTo steps one migration at a time from current to target, recording
+// the version after each step
+func (m *Migrator) migrateTo(ctx context.Context, conn *sql.Conn, current, target int32) error {
+	if current > m.Latest() {
+		return fmt.Errorf("database version %d is newer than the latest migration %d", current, m.Latest())
+	}
+
+	for current != target {
+		var (
+			migration *Migration
+			sql       string
+			next      int32
+		)
+
+		if current < target {
+			migration = m.migrations[current]
+			sql, next = migration.Up, current+1
+		} else {
+			migration = m.migrations[current-1]
+			if migration.Down == "" {
+				return fmt.Errorf("%w: %s", ErrIrreversible, migration.Name)
+			}
+			sql, next = migration.Down, current-1
+		}
+
+		m.logger.Info("Applying migration", "name", migration.Name, "from", current, "to", next)
+		if err := apply(ctx, conn, sql, next, migration.DisableTx); err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", migration.Name, err)
+		}
+		current = next
+	}
+
+	return nil
+}
+
+// withLock runs fn on a dedicated connection holding the tern advisory lock,
+// creating the version table first if needed
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "select pg_advisory_lock($1)", lockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		// Use a fresh context so the lock is released even if ctx was cancelled
+		if _, err := conn.ExecContext(context.Background(), "select pg_advisory_unlock($1)", lockID); err != nil {
+			m.logger.Error("Failed to release migration lock", "error", err)
+		}
+	}()
+
+	if err := ensureVersionTable(ctx, conn); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+// ensureVersionTable creates the version table the same way tern does
+func ensureVersionTable(ctx context.Context, conn *sql.Conn) error {
+	if _, err := conn.ExecContext(ctx, "create table if not exists "+VersionTable+"(version int4 not null)"); err != nil {
+		return fmt.Errorf("failed to create version table: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, "insert into "+VersionTable+"(version) select 0 where 0=(select count(*) from "+VersionTable+")"); err != nil {
+		return fmt.Errorf("failed to initialize version table: %w", err)
+	}
+	return nil
+}
+
+// currentVersion reads the applied version from the version table
+func currentVersion(ctx context.Context, conn *sql.Conn) (int32, error) {
+	var version int32
+	if err := conn.QueryRowContext(ctx, "select version from "+VersionTable).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// apply runs a migration and records the new version, in one transaction
+// unless the migration disables it. Without a transaction each statement is
+// sent on its own, since PostgreSQL runs a multi-statement query in an
+// implicit transaction and rejects CREATE INDEX CONCURRENTLY inside one.
+func apply(ctx context.Context, conn *sql.Conn, sql string, version int32, disableTx bool) error {
+	setVersion := "update " + VersionTable + " set version=$1"
+
+	if disableTx {
+		for _, stmt := range splitStatements(sql) {
+			if _, err := conn.ExecContext(ctx, stmt.text); err != nil {
+				return fmt.Errorf("line %d: %w", stmt.line, err)
+			}
+		}
+		_, err := conn.ExecContext(ctx, setVersion, version)
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, sql); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, setVersion, version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,96 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/jxlxx/civicrm/internal/logger"
+	"github.com/jxlxx/civicrm/migrations"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"002_second.sql": {Data: []byte("CREATE TABLE b ();\n---- create above / drop below ----\nDROP TABLE b;\n")},
+		"001_first.sql":  {Data: []byte("---- tern: disable-tx ----\nCREATE INDEX CONCURRENTLY a_idx ON a (id);\n")},
+		"README.md":      {Data: []byte("not a migration")},
+	}
+
+	loaded, err := Load(fsys)
+	require.NoError(t, err)
+	require.Len(t, loaded, 2)
+
+	assert.Equal(t, int32(1), loaded[0].Version)
+	assert.True(t, loaded[0].DisableTx)
+	assert.Equal(t, "CREATE INDEX CONCURRENTLY a_idx ON a (id);", loaded[0].Up)
+	assert.Empty(t, loaded[0].Down)
+
+	assert.Equal(t, "002_second.sql", loaded[1].Name)
+	assert.Equal(t, "CREATE TABLE b ();", loaded[1].Up)
+	assert.Equal(t, "DROP TABLE b;", loaded[1].Down)
+}
+
+func TestLoadRejectsGapsAndDuplicates(t *testing.T) {
+	_, err := Load(fstest.MapFS{
+		"001_first.sql": {Data: []byte("SELECT 1;")},
+		"003_third.sql": {Data: []byte("SELECT 3;")},
+	})
+	assert.ErrorContains(t, err, "missing migration version 2")
+
+	_, err = Load(fstest.MapFS{
+		"001_first.sql": {Data: []byte("SELECT 1;")},
+		"1_again.sql":   {Data: []byte("SELECT 1;")},
+	})
+	assert.ErrorContains(t, err, "duplicate migration version 1")
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	loaded, err := Load(migrations.FS)
+	require.NoError(t, err)
+	require.NotEmpty(t, loaded)
+
+	for _, migration := range loaded {
+		assert.NotEmpty(t, migration.Up, migration.Name)
+	}
+}
+
+// TestMigratorIntegration runs against TEST_DATABASE_URL and leaves the
+// database fully migrated
+func TestMigratorIntegration(t *testing.T) {
+	dbURL := os.Getenv("TEST_DATABASE_URL")
+	if dbURL == "" {
+		t.Skip("TEST_DATABASE_URL not set - skipping integration tests")
+	}
+
+	database, err := sql.Open("postgres", dbURL)
+	require.NoError(t, err)
+	defer database.Close()
+
+	loaded, err := Load(migrations.FS)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	migrator := New(database, loaded, logger.NewNop())
+
+	require.NoError(t, migrator.Up(ctx))
+	status, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, migrator.Latest(), status.Current)
+	assert.Empty(t, status.Pending)
+
+	require.NoError(t, migrator.Down(ctx))
+	status, err = migrator.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, migrator.Latest()-1, status.Current)
+	require.Len(t, status.Pending, 1)
+
+	require.NoError(t, migrator.Up(ctx))
+	status, err = migrator.Status(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, migrator.Latest(), status.Current)
+}
@@ -0,0 +1,294 @@
+// Package relationships manages relationships between contacts. A
+// relationship links contact A to contact B under a type that names it from
+// both sides, such as "Employee of" and "Has Employee". Triggers from
+// migration 042 feed active relationships with permissions into the contact
+// ACL cache and keep each individual's current employer.
+package relationships
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/cache"
+	"github.com/jxlxx/civicrm/internal/database"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/jxlxx/civicrm/internal/logger"
+)
+
+// JobUpdateStatus is the scheduled job that activates and expires
+// relationships by their dates
+const JobUpdateStatus = "update_relationship_status"
+
+// Relationship directions, from the side of the contact they are listed for
+const (
+	DirectionAB = "a_b"
+	DirectionBA = "b_a"
+)
+
+var (
+	// ErrNotFound is returned when a relationship, its type or one of its
+	// contacts does not exist
+	ErrNotFound = errors.New("relationship not found")
+
+	// ErrInvalidRelationship is returned when a relationship does not fit its
+	// type or its dates are inconsistent
+	ErrInvalidRelationship = errors.New("invalid relationship")
+)
+
+// Relationship is the input for creating or updating a relationship.
+// IsActive defaults to true; a relationship outside its dates is inactive
+// regardless.
+type Relationship struct {
+	ContactIDA         uuid.UUID
+	ContactIDB         uuid.UUID
+	RelationshipTypeID uuid.UUID
+	StartDate          sql.NullTime
+	EndDate            sql.NullTime
+	IsActive           *bool
+	IsPermissionAB     bool
+	IsPermissionBA     bool
+}
+
+// Service manages relationships
+type Service struct {
+	queries db.Querier
+	tx      database.Transactor
+	cache   cache.Invalidator
+	logger  *logger.Logger
+	now     func() time.Time
+}
+
+// New creates a relationship service. Reads go to replicas when configured.
+// Relationships grant permissions, so changes invalidate cached permission
+// checks.
+func New(database *database.Database, cache cache.Invalidator, logger *logger.Logger) *Service {
+	return &Service{
+		queries: database.ReadQuerier(),
+		tx:      database,
+		cache:   cache,
+		logger:  logger,
+		now:     time.Now,
+	}
+}
+
+// Types returns the active relationship types
+func (s *Service) Types(ctx context.Context) ([]db.RelationshipType, error) {
+	types, err := s.queries.ListRelationshipTypes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list relationship types: %w", err)
+	}
+	return types, nil
+}
+
+// Get returns a relationship by ID
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (*db.Relationship, error) {
+	relationship, err := s.queries.GetRelationship(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get relationship: %w", err)
+	}
+	return &relationship, nil
+}
+
+// ListForContact returns a contact's relationships named from its side, so
+// an employee sees "Employee of" and the employer "Has Employee"
+func (s *Service) ListForContact(ctx context.Context, contactID uuid.UUID, includeInactive bool) ([]db.ListContactRelationshipsRow, error) {
+	rows, err := s.queries.ListContactRelationships(ctx, db.ListContactRelationshipsParams{
+		ContactID:       contactID,
+		IncludeInactive: includeInactive,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list relationships: %w", err)
+	}
+	return rows, nil
+}
+
+// Create creates a relationship after checking it against its type
+func (s *Service) Create(ctx context.Context, input Relationship) (*db.Relationship, error) {
+	var created db.Relationship
+	err := s.tx.WithTx(ctx, func(q db.Querier) error {
+		if err := s.validate(ctx, q, input); err != nil {
+			return err
+		}
+
+		var err error
+		created, err = q.CreateRelationship(ctx, db.CreateRelationshipParams{
+			ContactIDA:         input.ContactIDA,
+			ContactIDB:         input.ContactIDB,
+			RelationshipTypeID: input.RelationshipTypeID,
+			StartDate:          input.StartDate,
+			EndDate:            input.EndDate,
+			IsActive:           sql.NullBool{Bool: s.isActive(input), Valid: true},
+			IsPermissionAB:     sql.NullBool{Bool: input.IsPermissionAB, Valid: true},
+			IsPermissionBA:     sql.NullBool{Bool: input.IsPermissionBA, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create relationship: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.invalidateACL(ctx)
+	return &created, nil
+}
+
+// Update changes a relationship's type, dates, status and permissions. Its
+// contacts cannot change.
+func (s *Service) Update(ctx context.Context, id uuid.UUID, input Relationship) (*db.Relationship, error) {
+	var updated db.Relationship
+	err := s.tx.WithTx(ctx, func(q db.Querier) error {
+		current, err := q.GetRelationship(ctx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get relationship: %w", err)
+		}
+
+		input.ContactIDA, input.ContactIDB = current.ContactIDA, current.ContactIDB
+		if err := s.validate(ctx, q, input); err != nil {
+			return err
+		}
+
+		updated, err = q.UpdateRelationship(ctx, db.UpdateRelationshipParams{
+			ID:                 id,
+			RelationshipTypeID: input.RelationshipTypeID,
+			StartDate:          input.StartDate,
+			EndDate:            input.EndDate,
+			IsActive:           sql.NullBool{Bool: s.isActive(input), Valid: true},
+			IsPermissionAB:     sql.NullBool{Bool: input.IsPermissionAB, Valid: true},
+			IsPermissionBA:     sql.NullBool{Bool: input.IsPermissionBA, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update relationship: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.invalidateACL(ctx)
+	return &updated, nil
+}
+
+// Delete deletes a relationship
+func (s *Service) Delete(ctx context.Context, id uuid.UUID) error {
+	err := s.tx.WithTx(ctx, func(q db.Querier) error {
+		n, err := q.DeleteRelationship(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete relationship: %w", err)
+		}
+		if n == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.invalidateACL(ctx)
+	return nil
+}
+
+// UpdateStatus activates the relationships of a domain that reached their
+// start date and deactivates those past their end date
+func (s *Service) UpdateStatus(ctx context.Context, domainID uuid.UUID) (activated, deactivated int64, err error) {
+	err = s.tx.WithTx(ctx, func(q db.Querier) error {
+		if activated, err = q.ActivateStartedRelationships(ctx, domainID); err != nil {
+			return fmt.Errorf("failed to activate relationships: %w", err)
+		}
+		if deactivated, err = q.DeactivateEndedRelationships(ctx, domainID); err != nil {
+			return fmt.Errorf("failed to deactivate relationships: %w", err)
+		}
+		return nil
+	})
+	if err == nil && activated+deactivated > 0 {
+		s.invalidateACL(ctx)
+	}
+	return activated, deactivated, err
+}
+
+// UpdateStatusJob runs UpdateStatus for the job's domain
+func (s *Service) UpdateStatusJob(ctx context.Context, job db.Job) (string, error) {
+	activated, deactivated, err := s.UpdateStatus(ctx, job.DomainID)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("activated %d, deactivated %d relationships", activated, deactivated), nil
+}
+
+// invalidateACL drops cached permission checks after relationships change.
+// The change is already committed, so a failure is logged rather than
+// returned; cached checks expire on their own.
+func (s *Service) invalidateACL(ctx context.Context) {
+	if err := s.cache.InvalidateTag(ctx, cache.TagACL); err != nil {
+		s.logger.Warn("Failed to invalidate cached permissions", "error", err)
+	}
+}
+
+// validate checks a relationship against its type and contacts
+func (s *Service) validate(ctx context.Context, q db.Querier, input Relationship) error {
+	if input.ContactIDA == input.ContactIDB {
+		return fmt.Errorf("%w: a contact cannot be related to itself", ErrInvalidRelationship)
+	}
+	if input.StartDate.Valid && input.EndDate.Valid && input.EndDate.Time.Before(input.StartDate.Time) {
+		return fmt.Errorf("%w: end_date is before start_date", ErrInvalidRelationship)
+	}
+
+	relType, err := q.GetRelationshipType(ctx, input.RelationshipTypeID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: unknown relationship type", ErrInvalidRelationship)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get relationship type: %w", err)
+	}
+	if relType.IsActive.Valid && !relType.IsActive.Bool {
+		return fmt.Errorf("%w: relationship type %q is disabled", ErrInvalidRelationship, relType.NameAB)
+	}
+
+	for _, side := range []struct {
+		name     string
+		id       uuid.UUID
+		required sql.NullString
+	}{
+		{"contact_id_a", input.ContactIDA, relType.ContactTypeA},
+		{"contact_id_b", input.ContactIDB, relType.ContactTypeB},
+	} {
+		contact, err := q.GetContact(ctx, side.id)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && contact.IsDeleted) {
+			return fmt.Errorf("%w: %s does not exist", ErrNotFound, side.name)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get contact: %w", err)
+		}
+		if side.required.Valid && side.required.String != "" && contact.ContactType != side.required.String {
+			return fmt.Errorf("%w: %s must be a %s for %q, not a %s", ErrInvalidRelationship, side.name, side.required.String, relType.NameAB, contact.ContactType)
+		}
+	}
+	return nil
+}
+
+// isActive reports whether a relationship is active: as requested, and only
+// between its start and end dates
+func (s *Service) isActive(input Relationship) bool {
+	if input.IsActive != nil && !*input.IsActive {
+		return false
+	}
+	y, m, d := s.now().Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	if input.StartDate.Valid && input.StartDate.Time.After(today) {
+		return false
+	}
+	if input.EndDate.Valid && input.EndDate.Time.Before(today) {
+		return false
+	}
+	return true
+}
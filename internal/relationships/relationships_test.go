@@ -0,0 +1,169 @@
+package relationships
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/cache"
+	"github.com/jxlxx/civicrm/internal/cache/cachetest"
+	"github.com/jxlxx/civicrm/internal/database/dbtest"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/jxlxx/civicrm/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeQuerier serves contacts and relationship types from maps and records
+// created relationships
+type fakeQuerier struct {
+	db.Querier
+	contacts map[uuid.UUID]db.Contact
+	types    map[uuid.UUID]db.RelationshipType
+	created  []db.CreateRelationshipParams
+	started  int64
+	ended    int64
+}
+
+func (q *fakeQuerier) GetContact(ctx context.Context, id uuid.UUID) (db.Contact, error) {
+	contact, ok := q.contacts[id]
+	if !ok {
+		return db.Contact{}, sql.ErrNoRows
+	}
+	return contact, nil
+}
+
+func (q *fakeQuerier) GetRelationshipType(ctx context.Context, id uuid.UUID) (db.RelationshipType, error) {
+	relType, ok := q.types[id]
+	if !ok {
+		return db.RelationshipType{}, sql.ErrNoRows
+	}
+	return relType, nil
+}
+
+func (q *fakeQuerier) CreateRelationship(ctx context.Context, arg db.CreateRelationshipParams) (db.Relationship, error) {
+	q.created = append(q.created, arg)
+	return db.Relationship{ID: uuid.New(), ContactIDA: arg.ContactIDA, ContactIDB: arg.ContactIDB, IsActive: arg.IsActive}, nil
+}
+
+func (q *fakeQuerier) ActivateStartedRelationships(ctx context.Context, domainID uuid.UUID) (int64, error) {
+	return q.started, nil
+}
+
+func (q *fakeQuerier) DeactivateEndedRelationships(ctx context.Context, domainID uuid.UUID) (int64, error) {
+	return q.ended, nil
+}
+
+func date(year int, month time.Month, day int) sql.NullTime {
+	return dbtest.Time(time.Date(year, month, day, 0, 0, 0, 0, time.UTC))
+}
+
+func newFixture() (*Service, *fakeQuerier, db.Contact, db.Contact, db.RelationshipType) {
+	person := db.Contact{ID: uuid.New(), ContactType: "Individual"}
+	org := db.Contact{ID: uuid.New(), ContactType: "Organization"}
+	employer := db.RelationshipType{ID: uuid.New(), NameAB: "Employee of", NameBA: "Has Employee", ContactTypeA: dbtest.Text("Individual"), ContactTypeB: dbtest.Text("Organization")}
+
+	q := &fakeQuerier{
+		contacts: map[uuid.UUID]db.Contact{person.ID: person, org.ID: org},
+		types:    map[uuid.UUID]db.RelationshipType{employer.ID: employer},
+	}
+	service := &Service{
+		queries: q,
+		tx:      dbtest.Tx{Q: q},
+		cache:   &cachetest.Invalidator{},
+		logger:  logger.NewNop(),
+		now:     func() time.Time { return time.Date(2024, 5, 15, 12, 0, 0, 0, time.UTC) },
+	}
+	return service, q, person, org, employer
+}
+
+func TestCreateChecksContactTypes(t *testing.T) {
+	service, q, person, org, employer := newFixture()
+	ctx := context.Background()
+
+	created, err := service.Create(ctx, Relationship{ContactIDA: person.ID, ContactIDB: org.ID, RelationshipTypeID: employer.ID, IsPermissionAB: true})
+	require.NoError(t, err)
+	assert.True(t, created.IsActive.Bool)
+	require.Len(t, q.created, 1)
+	assert.True(t, q.created[0].IsPermissionAB.Bool)
+
+	// The organization cannot be the employee
+	_, err = service.Create(ctx, Relationship{ContactIDA: org.ID, ContactIDB: person.ID, RelationshipTypeID: employer.ID})
+	assert.ErrorIs(t, err, ErrInvalidRelationship)
+
+	_, err = service.Create(ctx, Relationship{ContactIDA: person.ID, ContactIDB: person.ID, RelationshipTypeID: employer.ID})
+	assert.ErrorIs(t, err, ErrInvalidRelationship)
+
+	_, err = service.Create(ctx, Relationship{ContactIDA: person.ID, ContactIDB: org.ID, RelationshipTypeID: uuid.New()})
+	assert.ErrorIs(t, err, ErrInvalidRelationship)
+
+	_, err = service.Create(ctx, Relationship{ContactIDA: person.ID, ContactIDB: uuid.New(), RelationshipTypeID: employer.ID})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.Len(t, q.created, 1)
+}
+
+func TestCreateSetsActiveFromDates(t *testing.T) {
+	inactive := false
+	tests := []struct {
+		name  string
+		input Relationship
+		want  bool
+	}{
+		{"no dates", Relationship{}, true},
+		{"started", Relationship{StartDate: date(2024, 1, 1)}, true},
+		{"starts today", Relationship{StartDate: date(2024, 5, 15)}, true},
+		{"not started", Relationship{StartDate: date(2024, 6, 1)}, false},
+		{"ends today", Relationship{EndDate: date(2024, 5, 15)}, true},
+		{"ended", Relationship{StartDate: date(2023, 1, 1), EndDate: date(2024, 5, 14)}, false},
+		{"disabled", Relationship{IsActive: &inactive}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _, person, org, employer := newFixture()
+			input := tt.input
+			input.ContactIDA, input.ContactIDB, input.RelationshipTypeID = person.ID, org.ID, employer.ID
+
+			created, err := service.Create(context.Background(), input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, created.IsActive.Bool)
+		})
+	}
+}
+
+func TestCreateRejectsEndBeforeStart(t *testing.T) {
+	service, _, person, org, employer := newFixture()
+	_, err := service.Create(context.Background(), Relationship{
+		ContactIDA:         person.ID,
+		ContactIDB:         org.ID,
+		RelationshipTypeID: employer.ID,
+		StartDate:          date(2024, 5, 1),
+		EndDate:            date(2024, 4, 1),
+	})
+	assert.ErrorIs(t, err, ErrInvalidRelationship)
+}
+
+func TestChangesInvalidateCachedPermissions(t *testing.T) {
+	service, q, person, org, employer := newFixture()
+	ctx := context.Background()
+	invalidated := service.cache.(*cachetest.Invalidator)
+
+	_, err := service.Create(ctx, Relationship{ContactIDA: person.ID, ContactIDB: org.ID, RelationshipTypeID: employer.ID, IsPermissionAB: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{cache.TagACL}, invalidated.Tags())
+
+	_, err = service.Create(ctx, Relationship{ContactIDA: org.ID, ContactIDB: person.ID, RelationshipTypeID: employer.ID})
+	require.Error(t, err)
+	assert.Empty(t, invalidated.Tags(), "nothing changed")
+
+	_, _, err = service.UpdateStatus(ctx, uuid.New())
+	require.NoError(t, err)
+	assert.Empty(t, invalidated.Tags(), "no relationship started or ended")
+
+	q.ended = 1
+	_, _, err = service.UpdateStatus(ctx, uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, []string{cache.TagACL}, invalidated.Tags())
+}
@@ -44,6 +44,7 @@ type User struct {
 	Username string            `json:"username"`
 	Email    string            `json:"email"`
 	Roles    []string          `json:"roles"`
+	DomainID string            `json:"domain_id,omitempty"`
 	Metadata map[string]string `json:"metadata"`
 	Created  time.Time         `json:"created"`
 	Updated  time.Time         `json:"updated"`
@@ -55,6 +56,7 @@ type Claims struct {
 	Username string   `json:"username"`
 	Email    string   `json:"email"`
 	Roles    []string `json:"roles"`
+	DomainID string   `json:"domain_id,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -90,6 +92,7 @@ func (m *Manager) GenerateToken(user *User) (string, error) {
 		Username: user.Username,
 		Email:    user.Email,
 		Roles:    user.Roles,
+		DomainID: user.DomainID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(m.config.JWTExpiration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -122,6 +125,7 @@ func (m *Manager) ValidateToken(tokenString string) (*User, error) {
 			Username: claims.Username,
 			Email:    claims.Email,
 			Roles:    claims.Roles,
+			DomainID: claims.DomainID,
 		}
 		return user, nil
 	}
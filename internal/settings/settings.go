@@ -0,0 +1,79 @@
+package settings
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/cache"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+)
+
+// DefaultTTL is how long setting values stay cached when not invalidated
+const DefaultTTL = 15 * time.Minute
+
+// Service provides cached access to per-domain settings
+type Service struct {
+	queries db.Querier
+	cache   *cache.Manager
+	ttl     time.Duration
+}
+
+// New creates a new settings service
+func New(queries db.Querier, cache *cache.Manager) *Service {
+	return &Service{
+		queries: queries,
+		cache:   cache,
+		ttl:     DefaultTTL,
+	}
+}
+
+// cacheKey returns the cache key for a setting
+func cacheKey(domainID uuid.UUID, name string) string {
+	return fmt.Sprintf("setting:%s:%s", domainID, name)
+}
+
+// Get returns the value of a setting. A setting that does not exist is
+// returned as an invalid NullString rather than an error, and is cached too.
+func (s *Service) Get(ctx context.Context, domainID uuid.UUID, name string) (sql.NullString, error) {
+	return cache.GetOrLoad(ctx, s.cache, cacheKey(domainID, name), s.ttl, func(ctx context.Context) (sql.NullString, error) {
+		value, err := s.queries.GetSettingValue(ctx, db.GetSettingValueParams{DomainID: domainID, Name: name})
+		if errors.Is(err, sql.ErrNoRows) {
+			return sql.NullString{}, nil
+		}
+		return value, err
+	}, cache.TagSettings, cache.EntityTag("domain", domainID))
+}
+
+// GetString returns the value of a setting, or fallback when it is not set
+func (s *Service) GetString(ctx context.Context, domainID uuid.UUID, name, fallback string) (string, error) {
+	value, err := s.Get(ctx, domainID, name)
+	if err != nil {
+		return "", err
+	}
+	if !value.Valid || value.String == "" {
+		return fallback, nil
+	}
+	return value.String, nil
+}
+
+// Set updates the value of an existing setting and drops the cached copy
+func (s *Service) Set(ctx context.Context, domainID uuid.UUID, name, value string) error {
+	if _, err := s.queries.UpdateSettingValue(ctx, db.UpdateSettingValueParams{
+		DomainID: domainID,
+		Name:     name,
+		Value:    sql.NullString{String: value, Valid: true},
+	}); err != nil {
+		return fmt.Errorf("failed to update setting %s: %w", name, err)
+	}
+
+	return s.cache.Delete(ctx, cacheKey(domainID, name))
+}
+
+// Invalidate drops every cached setting for a domain
+func (s *Service) Invalidate(ctx context.Context, domainID uuid.UUID) error {
+	return s.cache.InvalidateTag(ctx, cache.EntityTag("domain", domainID))
+}
@@ -0,0 +1,70 @@
+package settings
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jxlxx/civicrm/internal/cache"
+	"github.com/jxlxx/civicrm/internal/config"
+	db "github.com/jxlxx/civicrm/internal/database/generated"
+	"github.com/jxlxx/civicrm/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeQuerier serves settings from a map and counts lookups
+type fakeQuerier struct {
+	db.Querier
+	values  map[string]string
+	lookups int
+}
+
+func (q *fakeQuerier) GetSettingValue(ctx context.Context, arg db.GetSettingValueParams) (sql.NullString, error) {
+	q.lookups++
+	value, ok := q.values[arg.Name]
+	if !ok {
+		return sql.NullString{}, sql.ErrNoRows
+	}
+	return sql.NullString{String: value, Valid: true}, nil
+}
+
+func (q *fakeQuerier) UpdateSettingValue(ctx context.Context, arg db.UpdateSettingValueParams) (db.Setting, error) {
+	q.values[arg.Name] = arg.Value.String
+	return db.Setting{DomainID: arg.DomainID, Name: arg.Name, Value: arg.Value}, nil
+}
+
+func TestServiceCachesLookups(t *testing.T) {
+	ctx := context.Background()
+	manager, err := cache.New(&config.CacheConfig{Driver: "memory", TTL: time.Minute, MaxEntries: 100}, logger.NewNop())
+	require.NoError(t, err)
+	defer manager.Close()
+
+	queries := &fakeQuerier{values: map[string]string{"default_currency": "USD"}}
+	service := New(queries, manager)
+	domainID := uuid.New()
+
+	for i := 0; i < 3; i++ {
+		value, err := service.GetString(ctx, domainID, "default_currency", "EUR")
+		require.NoError(t, err)
+		assert.Equal(t, "USD", value)
+	}
+	assert.Equal(t, 1, queries.lookups)
+
+	// Missing settings fall back and are cached as missing
+	for i := 0; i < 2; i++ {
+		value, err := service.GetString(ctx, domainID, "date_format", "%Y-%m-%d")
+		require.NoError(t, err)
+		assert.Equal(t, "%Y-%m-%d", value)
+	}
+	assert.Equal(t, 2, queries.lookups)
+
+	// Updating a setting drops the cached value
+	require.NoError(t, service.Set(ctx, domainID, "default_currency", "CAD"))
+	value, err := service.GetString(ctx, domainID, "default_currency", "EUR")
+	require.NoError(t, err)
+	assert.Equal(t, "CAD", value)
+	assert.Equal(t, 3, queries.lookups)
+}
@@ -0,0 +1,68 @@
+// Package tracing installs the OpenTelemetry tracer provider that the
+// instrumented packages, such as database, emit their spans through. Spans
+// are written to the application log; sampling keeps the volume in check.
+package tracing
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jxlxx/civicrm/internal/config"
+	"github.com/jxlxx/civicrm/internal/logger"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// serviceName identifies the application's spans
+const serviceName = "civicrm"
+
+// Setup installs a global tracer provider sampling the configured ratio of
+// traces, and returns the function that flushes and stops it. With tracing
+// disabled nothing is installed and spans are dropped.
+func Setup(config *config.TracingConfig, logger *logger.Logger) func(context.Context) error {
+	if !config.Enabled {
+		return func(context.Context) error { return nil }
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(&logExporter{logger: logger}),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	logger.Info("Tracing enabled", "sample_ratio", config.SampleRatio)
+	return provider.Shutdown
+}
+
+// logExporter writes finished spans to the application log
+type logExporter struct {
+	logger *logger.Logger
+}
+
+func (e *logExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	for _, span := range spans {
+		attrs := []any{
+			"span", span.Name(),
+			"trace_id", span.SpanContext().TraceID().String(),
+			"span_id", span.SpanContext().SpanID().String(),
+			"duration", span.EndTime().Sub(span.StartTime()),
+		}
+		if span.Parent().IsValid() {
+			attrs = append(attrs, "parent_id", span.Parent().SpanID().String())
+		}
+		if status := span.Status(); status.Description != "" {
+			attrs = append(attrs, "error", status.Description)
+		}
+		for _, attr := range span.Attributes() {
+			attrs = append(attrs, string(attr.Key), attr.Value.Emit())
+		}
+		e.logger.Log(ctx, slog.LevelInfo, "Span", attrs...)
+	}
+	return nil
+}
+
+func (e *logExporter) Shutdown(context.Context) error {
+	return nil
+}
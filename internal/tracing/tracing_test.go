@@ -0,0 +1,35 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/jxlxx/civicrm/internal/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// TestLogExporter tests that finished spans are logged with their
+// attributes
+func TestLogExporter(t *testing.T) {
+	var buf bytes.Buffer
+	exporter := &logExporter{logger: &logger.Logger{Logger: slog.New(slog.NewTextHandler(&buf, nil))}}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "Request")
+	_, span := provider.Tracer("test").Start(ctx, "GetContact")
+	span.SetAttributes(attribute.String("db.pool", "primary"))
+	span.End()
+	parent.End()
+
+	require.NoError(t, provider.ForceFlush(context.Background()))
+	assert.Contains(t, buf.String(), "span=GetContact")
+	assert.Contains(t, buf.String(), "db.pool=primary")
+	assert.Contains(t, buf.String(), "parent_id="+parent.SpanContext().SpanID().String())
+	assert.Contains(t, buf.String(), "span=Request")
+}
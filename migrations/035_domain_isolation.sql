@@ -0,0 +1,59 @@
+-- Domain Isolation Migration
+-- Scopes contacts, contributions, events and activities to a domain so one deployment can
+-- serve several organizations. The new columns are filled with the default
+-- domain here; migration 053 indexes them and makes them NOT NULL without
+-- long locks, and migration 054 turns on row level security.
+
+-- Domain of the current session, or NULL when the session is not scoped
+CREATE OR REPLACE FUNCTION current_domain_id()
+RETURNS UUID AS $$
+    SELECT NULLIF(current_setting('app.domain_id', true), '')::uuid;
+$$ LANGUAGE sql STABLE;
+
+-- Domain for new rows: the session's domain, otherwise the default domain
+CREATE OR REPLACE FUNCTION default_domain_id()
+RETURNS UUID AS $$
+    SELECT COALESCE(current_domain_id(), (SELECT id FROM domains WHERE name = 'default'));
+$$ LANGUAGE sql STABLE;
+
+-- Contacts. The check keeps new rows from leaving the domain empty until the
+-- column is made NOT NULL.
+ALTER TABLE contacts ADD COLUMN domain_id UUID REFERENCES domains(id);
+ALTER TABLE contacts ALTER COLUMN domain_id SET DEFAULT default_domain_id();
+UPDATE contacts SET domain_id = (SELECT id FROM domains WHERE name = 'default');
+ALTER TABLE contacts ADD CONSTRAINT contacts_domain_id_not_null CHECK (domain_id IS NOT NULL) NOT VALID;
+
+-- The same person may be a contact in several domains
+ALTER TABLE contacts DROP CONSTRAINT contacts_email_key;
+ALTER TABLE contacts ADD CONSTRAINT contacts_domain_id_email_key UNIQUE (domain_id, email);
+
+-- Contributions
+ALTER TABLE contributions ADD COLUMN domain_id UUID REFERENCES domains(id);
+ALTER TABLE contributions ALTER COLUMN domain_id SET DEFAULT default_domain_id();
+UPDATE contributions SET domain_id = (SELECT id FROM domains WHERE name = 'default');
+ALTER TABLE contributions ADD CONSTRAINT contributions_domain_id_not_null CHECK (domain_id IS NOT NULL) NOT VALID;
+
+-- Events
+ALTER TABLE events ADD COLUMN domain_id UUID REFERENCES domains(id);
+ALTER TABLE events ALTER COLUMN domain_id SET DEFAULT default_domain_id();
+UPDATE events SET domain_id = (SELECT id FROM domains WHERE name = 'default');
+ALTER TABLE events ADD CONSTRAINT events_domain_id_not_null CHECK (domain_id IS NOT NULL) NOT VALID;
+
+-- Activities
+ALTER TABLE activities ADD COLUMN domain_id UUID REFERENCES domains(id);
+ALTER TABLE activities ALTER COLUMN domain_id SET DEFAULT default_domain_id();
+UPDATE activities SET domain_id = (SELECT id FROM domains WHERE name = 'default');
+ALTER TABLE activities ADD CONSTRAINT activities_domain_id_not_null CHECK (domain_id IS NOT NULL) NOT VALID;
+
+---- create above / drop below ----
+
+ALTER TABLE activities DROP COLUMN IF EXISTS domain_id;
+ALTER TABLE events DROP COLUMN IF EXISTS domain_id;
+ALTER TABLE contributions DROP COLUMN IF EXISTS domain_id;
+
+ALTER TABLE contacts DROP CONSTRAINT IF EXISTS contacts_domain_id_email_key;
+ALTER TABLE contacts ADD CONSTRAINT contacts_email_key UNIQUE (email);
+ALTER TABLE contacts DROP COLUMN IF EXISTS domain_id;
+
+DROP FUNCTION IF EXISTS default_domain_id();
+DROP FUNCTION IF EXISTS current_domain_id();
@@ -0,0 +1,78 @@
+-- Dedupe Rules Migration
+-- Rule groups decide when two contacts are duplicates. Each rule compares one
+-- field, optionally only its first rule_length characters, and adds its
+-- weight to the pair's score when the values match. A pair whose score
+-- reaches the group's threshold is a duplicate.
+
+CREATE TABLE dedupe_rule_groups (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL UNIQUE,
+    title TEXT NOT NULL,
+    contact_type TEXT NOT NULL CHECK (contact_type IN ('Individual', 'Organization', 'Household')),
+    used TEXT NOT NULL CHECK (used IN ('Unsupervised', 'Supervised', 'General')),
+    threshold INTEGER NOT NULL CHECK (threshold > 0),
+    is_reserved BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- At most one Unsupervised and one Supervised group per contact type
+CREATE UNIQUE INDEX idx_dedupe_rule_groups_default ON dedupe_rule_groups(contact_type, used)
+    WHERE used IN ('Unsupervised', 'Supervised');
+
+CREATE TABLE dedupe_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    rule_group_id UUID NOT NULL REFERENCES dedupe_rule_groups(id) ON DELETE CASCADE,
+    rule_table TEXT NOT NULL CHECK (rule_table IN ('contacts', 'emails', 'phones', 'addresses')),
+    rule_field TEXT NOT NULL,
+    rule_length INTEGER CHECK (rule_length > 0),
+    rule_weight INTEGER NOT NULL CHECK (rule_weight > 0),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (rule_group_id, rule_table, rule_field)
+);
+
+CREATE INDEX idx_dedupe_rules_rule_group_id ON dedupe_rules(rule_group_id);
+
+CREATE TRIGGER update_dedupe_rule_groups_updated_at BEFORE UPDATE ON dedupe_rule_groups
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_dedupe_rules_updated_at BEFORE UPDATE ON dedupe_rules
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Default rule groups, matching CiviCRM's reserved groups
+INSERT INTO dedupe_rule_groups (name, title, contact_type, used, threshold, is_reserved) VALUES
+    ('IndividualUnsupervised', 'Email (reserved)', 'Individual', 'Unsupervised', 10, true),
+    ('IndividualSupervised', 'Name and Email (reserved)', 'Individual', 'Supervised', 20, true),
+    ('IndividualGeneral', 'Name and Postal Code', 'Individual', 'General', 15, false),
+    ('OrganizationUnsupervised', 'Name or Email', 'Organization', 'Unsupervised', 10, true),
+    ('OrganizationSupervised', 'Name and Email', 'Organization', 'Supervised', 20, true),
+    ('HouseholdUnsupervised', 'Email', 'Household', 'Unsupervised', 10, true),
+    ('HouseholdSupervised', 'Name and Email', 'Household', 'Supervised', 20, true);
+
+INSERT INTO dedupe_rules (rule_group_id, rule_table, rule_field, rule_length, rule_weight)
+SELECT g.id, r.rule_table, r.rule_field, r.rule_length, r.rule_weight
+FROM (VALUES
+    ('IndividualUnsupervised', 'contacts', 'email', NULL::INTEGER, 10),
+    ('IndividualSupervised', 'contacts', 'first_name', NULL, 5),
+    ('IndividualSupervised', 'contacts', 'last_name', NULL, 7),
+    ('IndividualSupervised', 'contacts', 'email', NULL, 10),
+    ('IndividualGeneral', 'contacts', 'first_name', 1, 5),
+    ('IndividualGeneral', 'contacts', 'last_name', NULL, 5),
+    ('IndividualGeneral', 'contacts', 'postal_code', NULL, 5),
+    ('OrganizationUnsupervised', 'contacts', 'organization_name', NULL, 10),
+    ('OrganizationUnsupervised', 'contacts', 'email', NULL, 10),
+    ('OrganizationSupervised', 'contacts', 'organization_name', NULL, 10),
+    ('OrganizationSupervised', 'contacts', 'email', NULL, 10),
+    ('HouseholdUnsupervised', 'contacts', 'email', NULL, 10),
+    ('HouseholdSupervised', 'contacts', 'last_name', NULL, 10),
+    ('HouseholdSupervised', 'contacts', 'email', NULL, 10)
+) AS r(group_name, rule_table, rule_field, rule_length, rule_weight)
+JOIN dedupe_rule_groups g ON g.name = r.group_name;
+
+---- create above / drop below ----
+
+DROP TRIGGER IF EXISTS update_dedupe_rules_updated_at ON dedupe_rules;
+DROP TRIGGER IF EXISTS update_dedupe_rule_groups_updated_at ON dedupe_rule_groups;
+DROP TABLE IF EXISTS dedupe_rules;
+DROP TABLE IF EXISTS dedupe_rule_groups;
@@ -0,0 +1,32 @@
+-- Contact Merge Migration
+-- Merging moves a duplicate's related records to the contact that is kept.
+-- The duplicate is soft deleted and points at the kept contact through
+-- merged_to_id, so links to it can be redirected.
+
+-- A constant default is stored in the catalog on PostgreSQL 11 and later, so
+-- this does not rewrite contacts.
+-- lint:ignore add-column-default
+ALTER TABLE contacts ADD COLUMN is_deleted BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE contacts ADD COLUMN merged_to_id UUID REFERENCES contacts(id);
+
+-- One row per merge. other_snapshot keeps the deleted contact's fields as
+-- they were before the merge, and moved counts the rows moved per table.
+CREATE TABLE contact_merges (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    main_contact_id UUID NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+    other_contact_id UUID NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+    field_choices JSONB NOT NULL DEFAULT '{}',
+    moved JSONB NOT NULL DEFAULT '{}',
+    other_snapshot JSONB NOT NULL,
+    merged_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_contact_merges_main ON contact_merges(main_contact_id);
+CREATE INDEX idx_contact_merges_other ON contact_merges(other_contact_id);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS contact_merges;
+ALTER TABLE contacts DROP COLUMN IF EXISTS merged_to_id;
+ALTER TABLE contacts DROP COLUMN IF EXISTS is_deleted;
@@ -0,0 +1,233 @@
+-- Contact Locations Migration
+-- Makes the emails, phones and addresses tables the only place contact
+-- locations are stored. The flat columns on contacts are copied into primary
+-- records and dropped. Every contact with records of a kind has exactly one
+-- primary record of that kind, kept so by triggers.
+
+ALTER TABLE addresses ADD COLUMN supplemental_address_1 TEXT;
+
+-- Flat values as they were, including states and countries that do not
+-- match a known one. Drop it once the migrated addresses have been reviewed.
+CREATE TABLE contact_location_backup AS
+SELECT id AS contact_id, email, phone, address_line_1, address_line_2,
+       city, state_province, postal_code, country
+FROM contacts
+WHERE email IS NOT NULL OR phone IS NOT NULL OR address_line_1 IS NOT NULL
+   OR address_line_2 IS NOT NULL OR city IS NOT NULL OR state_province IS NOT NULL
+   OR postal_code IS NOT NULL OR country IS NOT NULL;
+
+-- Existing records stop being primary where a contact has several
+UPDATE emails SET is_primary = FALSE
+WHERE is_primary AND id NOT IN (
+    SELECT DISTINCT ON (contact_id) id FROM emails WHERE is_primary ORDER BY contact_id, created_at, id
+);
+UPDATE phones SET is_primary = FALSE
+WHERE is_primary AND id NOT IN (
+    SELECT DISTINCT ON (contact_id) id FROM phones WHERE is_primary ORDER BY contact_id, created_at, id
+);
+UPDATE addresses SET is_primary = FALSE
+WHERE is_primary AND id NOT IN (
+    SELECT DISTINCT ON (contact_id) id FROM addresses WHERE is_primary ORDER BY contact_id, created_at, id
+);
+
+-- Flat values become primary records unless the contact already has the
+-- same value in the location table
+INSERT INTO emails (contact_id, location_type_id, is_primary, email)
+SELECT c.id, (SELECT id FROM location_types WHERE name = 'Home'),
+       NOT EXISTS (SELECT 1 FROM emails e WHERE e.contact_id = c.id AND e.is_primary),
+       btrim(c.email)
+FROM contacts c
+WHERE NULLIF(btrim(c.email), '') IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM emails e WHERE e.contact_id = c.id AND lower(e.email) = lower(btrim(c.email)));
+
+INSERT INTO phones (contact_id, location_type_id, phone_type, is_primary, phone)
+SELECT c.id, (SELECT id FROM location_types WHERE name = 'Home'), 'Phone',
+       NOT EXISTS (SELECT 1 FROM phones p WHERE p.contact_id = c.id AND p.is_primary),
+       btrim(c.phone)
+FROM contacts c
+WHERE NULLIF(btrim(c.phone), '') IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM phones p WHERE p.contact_id = c.id AND p.phone = btrim(c.phone));
+
+INSERT INTO addresses (
+    contact_id, location_type_id, is_primary, street_address, supplemental_address_1,
+    city, state_province_id, postal_code, country_id
+)
+SELECT c.id, (SELECT id FROM location_types WHERE name = 'Home'),
+       NOT EXISTS (SELECT 1 FROM addresses a WHERE a.contact_id = c.id AND a.is_primary),
+       c.address_line_1, c.address_line_2, c.city, sp.id, c.postal_code, co.id
+FROM contacts c
+LEFT JOIN countries co
+    ON lower(co.name) = lower(btrim(c.country)) OR lower(co.iso_code) = lower(btrim(c.country))
+LEFT JOIN LATERAL (
+    SELECT s.id FROM state_provinces s
+    WHERE (lower(s.name) = lower(btrim(c.state_province)) OR lower(s.abbreviation) = lower(btrim(c.state_province)))
+      AND (co.id IS NULL OR s.country_id = co.id)
+    ORDER BY s.country_id = co.id DESC NULLS LAST
+    LIMIT 1
+) sp ON TRUE
+WHERE COALESCE(c.address_line_1, c.address_line_2, c.city, c.state_province, c.postal_code, c.country) IS NOT NULL;
+
+-- Contacts whose records were all non-primary get their oldest one
+UPDATE emails SET is_primary = TRUE
+WHERE id IN (
+    SELECT DISTINCT ON (contact_id) id FROM emails e
+    WHERE NOT EXISTS (SELECT 1 FROM emails p WHERE p.contact_id = e.contact_id AND p.is_primary)
+    ORDER BY contact_id, created_at, id
+);
+UPDATE phones SET is_primary = TRUE
+WHERE id IN (
+    SELECT DISTINCT ON (contact_id) id FROM phones ph
+    WHERE NOT EXISTS (SELECT 1 FROM phones p WHERE p.contact_id = ph.contact_id AND p.is_primary)
+    ORDER BY contact_id, created_at, id
+);
+UPDATE addresses SET is_primary = TRUE
+WHERE id IN (
+    SELECT DISTINCT ON (contact_id) id FROM addresses a
+    WHERE NOT EXISTS (SELECT 1 FROM addresses p WHERE p.contact_id = a.contact_id AND p.is_primary)
+    ORDER BY contact_id, created_at, id
+);
+
+-- Making a record primary demotes the contact's other primary record. A
+-- contact's only primary record stays primary until another one is made
+-- primary, and the first record of a kind is always primary. Statements run
+-- by these triggers are not checked again.
+CREATE OR REPLACE FUNCTION set_primary_location()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF pg_trigger_depth() > 1 THEN
+        RETURN NEW;
+    END IF;
+
+    IF NEW.is_primary THEN
+        EXECUTE format('UPDATE %I SET is_primary = FALSE WHERE contact_id = $1 AND id <> $2 AND is_primary', TG_TABLE_NAME)
+            USING NEW.contact_id, NEW.id;
+    ELSE
+        EXECUTE format('SELECT NOT EXISTS (SELECT 1 FROM %I WHERE contact_id = $1 AND id <> $2 AND is_primary)', TG_TABLE_NAME)
+            INTO NEW.is_primary
+            USING NEW.contact_id, NEW.id;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Deleting a primary record, or moving it to another contact, promotes the
+-- contact's oldest remaining record
+CREATE OR REPLACE FUNCTION promote_primary_location()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF OLD.is_primary AND (TG_OP = 'DELETE' OR NEW.contact_id <> OLD.contact_id) THEN
+        EXECUTE format(
+            'UPDATE %1$I SET is_primary = TRUE WHERE id = (
+                SELECT id FROM %1$I WHERE contact_id = $1
+                  AND NOT EXISTS (SELECT 1 FROM %1$I WHERE contact_id = $1 AND is_primary)
+                ORDER BY created_at, id LIMIT 1)', TG_TABLE_NAME)
+            USING OLD.contact_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER set_primary_email BEFORE INSERT OR UPDATE OF is_primary, contact_id ON emails
+    FOR EACH ROW EXECUTE FUNCTION set_primary_location();
+CREATE TRIGGER promote_primary_email AFTER DELETE OR UPDATE OF contact_id ON emails
+    FOR EACH ROW EXECUTE FUNCTION promote_primary_location();
+
+CREATE TRIGGER set_primary_phone BEFORE INSERT OR UPDATE OF is_primary, contact_id ON phones
+    FOR EACH ROW EXECUTE FUNCTION set_primary_location();
+CREATE TRIGGER promote_primary_phone AFTER DELETE OR UPDATE OF contact_id ON phones
+    FOR EACH ROW EXECUTE FUNCTION promote_primary_location();
+
+CREATE TRIGGER set_primary_address BEFORE INSERT OR UPDATE OF is_primary, contact_id ON addresses
+    FOR EACH ROW EXECUTE FUNCTION set_primary_location();
+CREATE TRIGGER promote_primary_address AFTER DELETE OR UPDATE OF contact_id ON addresses
+    FOR EACH ROW EXECUTE FUNCTION promote_primary_location();
+
+-- Dedupe rules on the flat columns compare the location tables instead
+UPDATE dedupe_rules r
+SET rule_table = m.rule_table, rule_field = m.rule_field
+FROM (VALUES
+    ('email', 'emails', 'email'),
+    ('phone', 'phones', 'phone'),
+    ('address_line_1', 'addresses', 'street_address'),
+    ('city', 'addresses', 'city'),
+    ('postal_code', 'addresses', 'postal_code')
+) AS m(old_field, rule_table, rule_field)
+WHERE r.rule_table = 'contacts' AND r.rule_field = m.old_field
+  AND NOT EXISTS (
+      SELECT 1 FROM dedupe_rules d
+      WHERE d.rule_group_id = r.rule_group_id AND d.rule_table = m.rule_table AND d.rule_field = m.rule_field
+  );
+DELETE FROM dedupe_rules
+WHERE rule_table = 'contacts' AND rule_field IN ('email', 'phone', 'address_line_1', 'city', 'postal_code');
+
+ALTER TABLE contacts
+    DROP CONSTRAINT IF EXISTS contacts_domain_id_email_key,
+    DROP COLUMN email,
+    DROP COLUMN phone,
+    DROP COLUMN address_line_1,
+    DROP COLUMN address_line_2,
+    DROP COLUMN city,
+    DROP COLUMN state_province,
+    DROP COLUMN postal_code,
+    DROP COLUMN country;
+
+---- create above / drop below ----
+
+ALTER TABLE contacts
+    ADD COLUMN email TEXT,
+    ADD COLUMN phone TEXT,
+    ADD COLUMN address_line_1 TEXT,
+    ADD COLUMN address_line_2 TEXT,
+    ADD COLUMN city TEXT,
+    ADD COLUMN state_province TEXT,
+    ADD COLUMN postal_code TEXT,
+    ADD COLUMN country TEXT;
+
+UPDATE contacts c SET email = e.email
+FROM emails e WHERE e.contact_id = c.id AND e.is_primary;
+UPDATE contacts c SET phone = p.phone
+FROM phones p WHERE p.contact_id = c.id AND p.is_primary;
+UPDATE contacts c
+SET address_line_1 = a.street_address,
+    address_line_2 = a.supplemental_address_1,
+    city = a.city,
+    state_province = sp.name,
+    postal_code = a.postal_code,
+    country = co.name
+FROM addresses a
+LEFT JOIN state_provinces sp ON sp.id = a.state_province_id
+LEFT JOIN countries co ON co.id = a.country_id
+WHERE a.contact_id = c.id AND a.is_primary;
+
+-- Contacts sharing an email keep it on the oldest one only
+UPDATE contacts c SET email = NULL
+WHERE EXISTS (
+    SELECT 1 FROM contacts o
+    WHERE o.domain_id = c.domain_id AND lower(o.email) = lower(c.email)
+      AND (o.created_at, o.id) < (c.created_at, c.id)
+);
+ALTER TABLE contacts ADD CONSTRAINT contacts_domain_id_email_key UNIQUE (domain_id, email);
+
+UPDATE dedupe_rules r
+SET rule_table = 'contacts', rule_field = m.old_field
+FROM (VALUES
+    ('email', 'emails', 'email'),
+    ('phone', 'phones', 'phone')
+) AS m(old_field, rule_table, rule_field)
+WHERE r.rule_table = m.rule_table AND r.rule_field = m.rule_field
+  AND NOT EXISTS (
+      SELECT 1 FROM dedupe_rules d
+      WHERE d.rule_group_id = r.rule_group_id AND d.rule_table = 'contacts' AND d.rule_field = m.old_field
+  );
+
+DROP TRIGGER IF EXISTS promote_primary_address ON addresses;
+DROP TRIGGER IF EXISTS set_primary_address ON addresses;
+DROP TRIGGER IF EXISTS promote_primary_phone ON phones;
+DROP TRIGGER IF EXISTS set_primary_phone ON phones;
+DROP TRIGGER IF EXISTS promote_primary_email ON emails;
+DROP TRIGGER IF EXISTS set_primary_email ON emails;
+DROP FUNCTION IF EXISTS promote_primary_location();
+DROP FUNCTION IF EXISTS set_primary_location();
+
+DROP TABLE IF EXISTS contact_location_backup;
+ALTER TABLE addresses DROP COLUMN IF EXISTS supplemental_address_1;
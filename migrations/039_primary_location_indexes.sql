@@ -0,0 +1,15 @@
+---- tern: disable-tx ----
+-- Primary Location Indexes Migration
+-- Backs the primary record triggers with a constraint: at most one primary
+-- email, phone and address per contact. Built concurrently, so this runs
+-- outside a transaction.
+
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS idx_emails_primary ON emails(contact_id) WHERE is_primary;
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS idx_phones_primary ON phones(contact_id) WHERE is_primary;
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS idx_addresses_primary ON addresses(contact_id) WHERE is_primary;
+
+---- create above / drop below ----
+
+DROP INDEX CONCURRENTLY IF EXISTS idx_addresses_primary;
+DROP INDEX CONCURRENTLY IF EXISTS idx_phones_primary;
+DROP INDEX CONCURRENTLY IF EXISTS idx_emails_primary;
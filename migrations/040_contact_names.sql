@@ -0,0 +1,92 @@
+-- Contact Names Migration
+-- Adds the name parts CiviCRM contacts carry and the computed display_name
+-- and sort_name that lists, exports and tokens show. The application
+-- recomputes both on every contact write from the formats in the
+-- display_name_format and sort_name_format settings.
+
+ALTER TABLE contacts ADD COLUMN prefix TEXT;
+ALTER TABLE contacts ADD COLUMN suffix TEXT;
+ALTER TABLE contacts ADD COLUMN nick_name TEXT;
+ALTER TABLE contacts ADD COLUMN household_name TEXT;
+ALTER TABLE contacts ADD COLUMN display_name TEXT;
+ALTER TABLE contacts ADD COLUMN sort_name TEXT;
+ALTER TABLE contacts ADD COLUMN contact_sub_type TEXT[];
+
+-- Households used organization_name before household_name existed
+UPDATE contacts SET household_name = organization_name
+WHERE contact_type = 'Household' AND household_name IS NULL;
+
+-- Households are matched by their household name
+UPDATE dedupe_rules SET rule_field = 'household_name'
+WHERE rule_table = 'contacts' AND rule_field = 'last_name'
+  AND rule_group_id IN (SELECT id FROM dedupe_rule_groups WHERE contact_type = 'Household');
+
+-- Matches the default formats below
+UPDATE contacts SET
+    display_name = CASE contact_type
+        WHEN 'Individual' THEN NULLIF(concat_ws(' ', NULLIF(first_name, ''), NULLIF(last_name, '')), '')
+        WHEN 'Organization' THEN organization_name
+        ELSE household_name
+    END,
+    sort_name = CASE contact_type
+        WHEN 'Individual' THEN NULLIF(concat_ws(', ', NULLIF(last_name, ''), NULLIF(first_name, '')), '')
+        WHEN 'Organization' THEN organization_name
+        ELSE household_name
+    END;
+
+-- Contact subtypes, such as Student or Foundation. A contact can have several
+-- subtypes of its own contact type; contacts.contact_sub_type holds their
+-- names.
+CREATE TABLE contact_sub_types (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL UNIQUE,
+    label TEXT NOT NULL,
+    parent TEXT NOT NULL CHECK (parent IN ('Individual', 'Organization', 'Household')),
+    description TEXT,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+INSERT INTO contact_sub_types (name, label, parent, description) VALUES
+    ('Student', 'Student', 'Individual', 'Student'),
+    ('Parent', 'Parent', 'Individual', 'Parent or guardian'),
+    ('Staff', 'Staff', 'Individual', 'Member of staff'),
+    ('Team', 'Team', 'Organization', 'Team'),
+    ('Sponsor', 'Sponsor', 'Organization', 'Sponsoring organization'),
+    ('Foundation', 'Foundation', 'Organization', 'Grant making foundation');
+
+-- A custom group that extends a contact type can be limited to some of its
+-- subtypes. NULL applies the group to every contact of the type.
+ALTER TABLE custom_groups ADD COLUMN extends_entity_column_value TEXT[];
+
+-- Name formats. Tokens in braces are replaced by the contact's fields;
+-- separators next to empty fields are dropped.
+INSERT INTO settings (domain_id, name, value, description, is_system, is_public)
+SELECT id, 'display_name_format', '{prefix} {first_name} {last_name} {suffix}',
+       'Format of the display name of individuals', TRUE, FALSE
+FROM domains
+ON CONFLICT (domain_id, name) DO NOTHING;
+
+INSERT INTO settings (domain_id, name, value, description, is_system, is_public)
+SELECT id, 'sort_name_format', '{last_name}, {first_name}',
+       'Format of the sort name of individuals', TRUE, FALSE
+FROM domains
+ON CONFLICT (domain_id, name) DO NOTHING;
+
+---- create above / drop below ----
+
+UPDATE dedupe_rules SET rule_field = 'last_name'
+WHERE rule_table = 'contacts' AND rule_field = 'household_name'
+  AND rule_group_id IN (SELECT id FROM dedupe_rule_groups WHERE contact_type = 'Household');
+
+DELETE FROM settings WHERE name IN ('display_name_format', 'sort_name_format');
+ALTER TABLE custom_groups DROP COLUMN IF EXISTS extends_entity_column_value;
+DROP TABLE IF EXISTS contact_sub_types;
+ALTER TABLE contacts DROP COLUMN IF EXISTS contact_sub_type;
+ALTER TABLE contacts DROP COLUMN IF EXISTS sort_name;
+ALTER TABLE contacts DROP COLUMN IF EXISTS display_name;
+ALTER TABLE contacts DROP COLUMN IF EXISTS household_name;
+ALTER TABLE contacts DROP COLUMN IF EXISTS nick_name;
+ALTER TABLE contacts DROP COLUMN IF EXISTS suffix;
+ALTER TABLE contacts DROP COLUMN IF EXISTS prefix;
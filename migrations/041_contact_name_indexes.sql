@@ -0,0 +1,12 @@
+---- tern: disable-tx ----
+-- Contact Name Indexes Migration
+-- Indexes the computed sort name for ordered contact lists, and subtypes for
+-- subtype filters. Built concurrently, so this runs outside a transaction.
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_contacts_sort_name ON contacts(sort_name);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_contacts_contact_sub_type ON contacts USING GIN (contact_sub_type);
+
+---- create above / drop below ----
+
+DROP INDEX CONCURRENTLY IF EXISTS idx_contacts_contact_sub_type;
+DROP INDEX CONCURRENTLY IF EXISTS idx_contacts_sort_name;
@@ -0,0 +1,110 @@
+-- Relationship Permissions Migration
+-- Active relationships with is_permission_a_b or is_permission_b_a let one
+-- contact view and edit the other. Triggers keep the granted rows in
+-- acl_contact_cache and each individual's current employer in step with the
+-- relationships, including changes made by merges and the scheduled status
+-- job.
+
+-- The employer type is looked up by name, so it cannot be renamed
+UPDATE relationship_types SET is_reserved = TRUE WHERE name_a_b = 'Employee of';
+
+ALTER TABLE contacts ADD COLUMN employer_id UUID REFERENCES contacts(id) ON DELETE SET NULL;
+
+-- Cache rows granted by a relationship go away with it. Indexed by 043.
+ALTER TABLE acl_contact_cache ADD COLUMN relationship_id UUID REFERENCES relationships(id) ON DELETE CASCADE;
+
+-- Rebuilds the cache rows a relationship grants
+CREATE OR REPLACE FUNCTION sync_relationship_acl(rel relationships)
+RETURNS VOID AS $$
+BEGIN
+    DELETE FROM acl_contact_cache WHERE relationship_id = rel.id;
+    IF NOT COALESCE(rel.is_active, FALSE) THEN
+        RETURN;
+    END IF;
+
+    INSERT INTO acl_contact_cache (user_id, contact_id, operation, domain_id, relationship_id)
+    SELECT grant_to.user_id, grant_to.contact_id, op.operation, c.domain_id, rel.id
+    FROM (VALUES
+        (rel.contact_id_a, rel.contact_id_b, COALESCE(rel.is_permission_a_b, FALSE)),
+        (rel.contact_id_b, rel.contact_id_a, COALESCE(rel.is_permission_b_a, FALSE))
+    ) AS grant_to(user_id, contact_id, permitted)
+    CROSS JOIN (VALUES ('View'), ('Edit')) AS op(operation)
+    JOIN contacts c ON c.id = grant_to.contact_id
+    WHERE grant_to.permitted;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Sets an individual's employer to the organization of their most recent
+-- active employer relationship
+CREATE OR REPLACE FUNCTION sync_current_employer(individual_id UUID)
+RETURNS VOID AS $$
+DECLARE
+    employer UUID;
+BEGIN
+    SELECT r.contact_id_b INTO employer FROM relationships r
+    JOIN relationship_types t ON t.id = r.relationship_type_id
+    WHERE t.name_a_b = 'Employee of'
+      AND r.contact_id_a = individual_id
+      AND r.is_active
+    ORDER BY r.start_date DESC NULLS LAST, r.created_at DESC
+    LIMIT 1;
+
+    UPDATE contacts SET employer_id = employer
+    WHERE id = individual_id AND employer_id IS DISTINCT FROM employer;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION is_employer_type(type_id UUID)
+RETURNS BOOLEAN AS $$
+    SELECT EXISTS (SELECT 1 FROM relationship_types WHERE id = type_id AND name_a_b = 'Employee of');
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION sync_relationship()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP <> 'DELETE' THEN
+        PERFORM sync_relationship_acl(NEW);
+        IF is_employer_type(NEW.relationship_type_id) THEN
+            PERFORM sync_current_employer(NEW.contact_id_a);
+        END IF;
+    END IF;
+    IF TG_OP = 'DELETE' OR OLD.contact_id_a <> NEW.contact_id_a
+       OR OLD.relationship_type_id <> NEW.relationship_type_id THEN
+        IF is_employer_type(OLD.relationship_type_id) THEN
+            PERFORM sync_current_employer(OLD.contact_id_a);
+        END IF;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER sync_relationship AFTER INSERT OR UPDATE OR DELETE ON relationships
+    FOR EACH ROW EXECUTE FUNCTION sync_relationship();
+
+-- Existing relationships
+SELECT sync_relationship_acl(r) FROM relationships r
+WHERE COALESCE(r.is_permission_a_b, FALSE) OR COALESCE(r.is_permission_b_a, FALSE);
+SELECT sync_current_employer(r.contact_id_a) FROM relationships r
+JOIN relationship_types t ON t.id = r.relationship_type_id
+WHERE t.name_a_b = 'Employee of'
+GROUP BY r.contact_id_a;
+
+-- Activates relationships that reached their start date and deactivates
+-- those past their end date, daily at midnight
+INSERT INTO jobs (domain_id, name, description, job_type, parameters, schedule, is_active)
+SELECT id, 'update_relationship_status', 'Activate and expire relationships by their start and end dates', 'relationship', '{}', '0 0 * * *', TRUE
+FROM domains
+WHERE NOT EXISTS (SELECT 1 FROM jobs j WHERE j.domain_id = domains.id AND j.name = 'update_relationship_status');
+
+---- create above / drop below ----
+
+DELETE FROM jobs WHERE name = 'update_relationship_status';
+DROP TRIGGER IF EXISTS sync_relationship ON relationships;
+DROP FUNCTION IF EXISTS sync_relationship();
+DROP FUNCTION IF EXISTS is_employer_type(UUID);
+DROP FUNCTION IF EXISTS sync_current_employer(UUID);
+DROP FUNCTION IF EXISTS sync_relationship_acl(relationships);
+DELETE FROM acl_contact_cache WHERE relationship_id IS NOT NULL;
+ALTER TABLE acl_contact_cache DROP COLUMN IF EXISTS relationship_id;
+ALTER TABLE contacts DROP COLUMN IF EXISTS employer_id;
+UPDATE relationship_types SET is_reserved = FALSE WHERE name_a_b = 'Employee of';
@@ -0,0 +1,13 @@
+---- tern: disable-tx ----
+-- Relationship Permission Indexes Migration
+-- Indexes the ACL cache rows granted by relationships, which the sync
+-- trigger replaces on every relationship write, and the lookup of a user's
+-- permitted contacts. Built concurrently, so this runs outside a transaction.
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_acl_contact_cache_relationship_id ON acl_contact_cache(relationship_id);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_acl_contact_cache_user_id ON acl_contact_cache(user_id, operation);
+
+---- create above / drop below ----
+
+DROP INDEX CONCURRENTLY IF EXISTS idx_acl_contact_cache_user_id;
+DROP INDEX CONCURRENTLY IF EXISTS idx_acl_contact_cache_relationship_id;
@@ -0,0 +1,79 @@
+-- Smart Groups Migration
+-- A saved search stores an APIv4 query. A group with a saved search is a
+-- smart group: its members are the contacts the search returns, cached in
+-- group_contact_cache and refreshed once the cache is older than the
+-- configured TTL. Contacts added to or removed from a smart group by hand
+-- are kept in group_contacts and override the search.
+
+CREATE TABLE saved_searches (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    domain_id UUID NOT NULL DEFAULT default_domain_id() REFERENCES domains(id),
+    name TEXT NOT NULL,
+    label TEXT,
+    description TEXT,
+    api_entity TEXT NOT NULL DEFAULT 'Contact',
+    api_params JSONB NOT NULL DEFAULT '{}',
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (domain_id, name)
+);
+
+CREATE TRIGGER update_saved_searches_updated_at BEFORE UPDATE ON saved_searches
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+ALTER TABLE saved_searches ENABLE ROW LEVEL SECURITY;
+ALTER TABLE saved_searches FORCE ROW LEVEL SECURITY;
+CREATE POLICY saved_searches_domain_isolation ON saved_searches
+    USING (current_domain_id() IS NULL OR domain_id = current_domain_id())
+    WITH CHECK (current_domain_id() IS NULL OR domain_id = current_domain_id());
+
+-- saved_search_id was added before saved searches existed, so any value it
+-- holds points nowhere
+UPDATE groups SET saved_search_id = NULL WHERE saved_search_id IS NOT NULL;
+ALTER TABLE groups ADD CONSTRAINT groups_saved_search_id_fkey
+    FOREIGN KEY (saved_search_id) REFERENCES saved_searches(id) ON DELETE SET NULL;
+
+CREATE TABLE group_contact_cache (
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    contact_id UUID NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, contact_id)
+);
+
+CREATE INDEX idx_group_contact_cache_contact_id ON group_contact_cache(contact_id);
+
+-- Members of every group: contacts added by hand, and contacts found by a
+-- smart group's search unless removed from it by hand. Mailing lists,
+-- campaigns and ACLs resolve groups through this view.
+CREATE VIEW group_members AS
+SELECT gc.group_id, gc.contact_id
+FROM group_contacts gc
+WHERE gc.status = 'Added'
+UNION
+SELECT cache.group_id, cache.contact_id
+FROM group_contact_cache cache
+WHERE NOT EXISTS (
+    SELECT 1 FROM group_contacts gc
+    WHERE gc.group_id = cache.group_id
+      AND gc.contact_id = cache.contact_id
+      AND gc.status = 'Removed'
+);
+
+-- A mailing list backed by a group mails the group's members as well as its
+-- own subscribers
+ALTER TABLE mailing_lists ADD COLUMN group_id UUID REFERENCES groups(id) ON DELETE SET NULL;
+
+-- Refreshes smart groups whose cache has expired, every 15 minutes
+INSERT INTO jobs (domain_id, name, description, job_type, parameters, schedule, is_active)
+SELECT id, 'refresh_smart_groups', 'Rebuild the membership cache of smart groups past their TTL', 'group', '{}', '*/15 * * * *', TRUE
+FROM domains
+WHERE NOT EXISTS (SELECT 1 FROM jobs j WHERE j.domain_id = domains.id AND j.name = 'refresh_smart_groups');
+
+---- create above / drop below ----
+
+DELETE FROM jobs WHERE name = 'refresh_smart_groups';
+ALTER TABLE mailing_lists DROP COLUMN IF EXISTS group_id;
+DROP VIEW IF EXISTS group_members;
+DROP TABLE IF EXISTS group_contact_cache;
+ALTER TABLE groups DROP CONSTRAINT IF EXISTS groups_saved_search_id_fkey;
+DROP TABLE IF EXISTS saved_searches;
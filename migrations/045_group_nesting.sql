@@ -0,0 +1,152 @@
+-- Group Nesting Migration
+-- Groups nest through group_nesting: members of a child group are members
+-- of its parents, at any depth. group_hierarchy holds the transitive closure
+-- of group_nesting and is rebuilt after every change to it; a change that
+-- would make a group its own ancestor is rejected. group_members now
+-- includes the members of descendant groups, so mailing lists, campaigns,
+-- surveys and ACLs see nested members without changes of their own.
+
+-- Nesting used to be kept as serialized IDs in groups.parents and
+-- groups.children. Those become a read-only copy of group_nesting.
+INSERT INTO group_nesting (parent_group_id, child_group_id)
+SELECT DISTINCT parent.id, child.id
+FROM (
+    SELECT (regexp_matches(g.parents, '[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}', 'gi'))[1]::uuid AS parent_id, g.id AS child_id
+    FROM groups g WHERE g.parents IS NOT NULL
+    UNION
+    SELECT g.id, (regexp_matches(g.children, '[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}', 'gi'))[1]::uuid
+    FROM groups g WHERE g.children IS NOT NULL
+) legacy
+JOIN groups parent ON parent.id = legacy.parent_id
+JOIN groups child ON child.id = legacy.child_id
+WHERE parent.id <> child.id
+  AND NOT EXISTS (
+      SELECT 1 FROM group_nesting n
+      WHERE n.parent_group_id = parent.id AND n.child_group_id = child.id
+  );
+
+-- A group is nested under another at most once
+DELETE FROM group_nesting n
+USING group_nesting earlier
+WHERE earlier.parent_group_id = n.parent_group_id
+  AND earlier.child_group_id = n.child_group_id
+  AND earlier.id < n.id;
+ALTER TABLE group_nesting ADD CONSTRAINT group_nesting_parent_child_key UNIQUE (parent_group_id, child_group_id);
+ALTER TABLE group_nesting ADD CONSTRAINT group_nesting_not_self CHECK (parent_group_id <> child_group_id);
+
+-- Every ancestor of every group. A group is not its own ancestor.
+CREATE TABLE group_hierarchy (
+    ancestor_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    descendant_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    PRIMARY KEY (ancestor_id, descendant_id)
+);
+
+CREATE INDEX idx_group_hierarchy_descendant_id ON group_hierarchy(descendant_id);
+
+-- Rebuilds group_hierarchy and the legacy parents and children columns.
+-- Nesting changes are rare, so they are serialized with an advisory lock;
+-- each statement here sees nesting committed by the previous holder, so two
+-- concurrent changes cannot form a cycle between them.
+CREATE OR REPLACE FUNCTION refresh_group_hierarchy()
+RETURNS VOID AS $$
+BEGIN
+    PERFORM pg_advisory_xact_lock(hashtext('group_hierarchy'));
+
+    DELETE FROM group_hierarchy;
+    INSERT INTO group_hierarchy (ancestor_id, descendant_id)
+    WITH RECURSIVE closure(ancestor_id, descendant_id) AS (
+        SELECT parent_group_id, child_group_id FROM group_nesting
+        UNION
+        SELECT c.ancestor_id, n.child_group_id
+        FROM closure c
+        JOIN group_nesting n ON n.parent_group_id = c.descendant_id
+    )
+    SELECT ancestor_id, descendant_id FROM closure;
+
+    IF EXISTS (SELECT 1 FROM group_hierarchy WHERE ancestor_id = descendant_id) THEN
+        RAISE EXCEPTION 'group nesting would create a cycle'
+            USING ERRCODE = 'check_violation', CONSTRAINT = 'group_nesting_no_cycle';
+    END IF;
+
+    UPDATE groups g
+    SET parents = nesting.parents, children = nesting.children
+    FROM (
+        SELECT g2.id,
+               (SELECT string_agg(n.parent_group_id::text, ',' ORDER BY n.parent_group_id) FROM group_nesting n WHERE n.child_group_id = g2.id) AS parents,
+               (SELECT string_agg(n.child_group_id::text, ',' ORDER BY n.child_group_id) FROM group_nesting n WHERE n.parent_group_id = g2.id) AS children
+        FROM groups g2
+    ) nesting
+    WHERE nesting.id = g.id
+      AND (g.parents IS DISTINCT FROM nesting.parents OR g.children IS DISTINCT FROM nesting.children);
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION rebuild_group_hierarchy()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM refresh_group_hierarchy();
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER rebuild_group_hierarchy AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON group_nesting
+    FOR EACH STATEMENT EXECUTE FUNCTION rebuild_group_hierarchy();
+
+-- Existing nesting
+SELECT refresh_group_hierarchy();
+
+-- Members added to a group or found by its saved search, as group_members
+-- was before nesting
+CREATE VIEW group_direct_members AS
+SELECT gc.group_id, gc.contact_id
+FROM group_contacts gc
+WHERE gc.status = 'Added'
+UNION
+SELECT cache.group_id, cache.contact_id
+FROM group_contact_cache cache
+WHERE NOT EXISTS (
+    SELECT 1 FROM group_contacts gc
+    WHERE gc.group_id = cache.group_id
+      AND gc.contact_id = cache.contact_id
+      AND gc.status = 'Removed'
+);
+
+-- Members of a group and of every group nested under it. A contact removed
+-- from a group by hand is not a member through its child groups either.
+CREATE OR REPLACE VIEW group_members AS
+SELECT dm.group_id, dm.contact_id
+FROM group_direct_members dm
+UNION
+SELECT h.ancestor_id AS group_id, dm.contact_id
+FROM group_hierarchy h
+JOIN group_direct_members dm ON dm.group_id = h.descendant_id
+WHERE NOT EXISTS (
+    SELECT 1 FROM group_contacts gc
+    WHERE gc.group_id = h.ancestor_id
+      AND gc.contact_id = dm.contact_id
+      AND gc.status = 'Removed'
+);
+
+---- create above / drop below ----
+
+DROP VIEW IF EXISTS group_members;
+DROP VIEW IF EXISTS group_direct_members;
+CREATE VIEW group_members AS
+SELECT gc.group_id, gc.contact_id
+FROM group_contacts gc
+WHERE gc.status = 'Added'
+UNION
+SELECT cache.group_id, cache.contact_id
+FROM group_contact_cache cache
+WHERE NOT EXISTS (
+    SELECT 1 FROM group_contacts gc
+    WHERE gc.group_id = cache.group_id
+      AND gc.contact_id = cache.contact_id
+      AND gc.status = 'Removed'
+);
+DROP TRIGGER IF EXISTS rebuild_group_hierarchy ON group_nesting;
+DROP FUNCTION IF EXISTS rebuild_group_hierarchy();
+DROP FUNCTION IF EXISTS refresh_group_hierarchy();
+DROP TABLE IF EXISTS group_hierarchy;
+ALTER TABLE group_nesting DROP CONSTRAINT IF EXISTS group_nesting_not_self;
+ALTER TABLE group_nesting DROP CONSTRAINT IF EXISTS group_nesting_parent_child_key;
@@ -0,0 +1,42 @@
+-- Subscription History Migration
+-- Every change to a contact's status in a group is recorded in
+-- subscription_history with when, how and by whom it was made, so we can
+-- show when someone joined or left a group. A contact added as Pending
+-- becomes Added once they confirm with the signed token sent to them.
+
+-- A contact has one status per group
+DELETE FROM group_contacts gc
+USING group_contacts later
+WHERE later.group_id = gc.group_id
+  AND later.contact_id = gc.contact_id
+  AND (COALESCE(later.updated_at, '-infinity'), later.id) > (COALESCE(gc.updated_at, '-infinity'), gc.id);
+ALTER TABLE group_contacts ADD CONSTRAINT group_contacts_group_contact_key UNIQUE (group_id, contact_id);
+ALTER TABLE group_contacts ADD CONSTRAINT group_contacts_status_check CHECK (status IN ('Added', 'Removed', 'Pending'));
+
+CREATE TABLE subscription_history (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    contact_id UUID NOT NULL REFERENCES contacts(id) ON DELETE CASCADE,
+    status TEXT NOT NULL CHECK (status IN ('Added', 'Removed', 'Pending')),
+    method TEXT NOT NULL CHECK (method IN ('Admin', 'Email', 'Web', 'API')),
+    date TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL, -- NULL when the contact made the change
+    tracking TEXT, -- Free text such as the form or mailing the change came from
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_subscription_history_contact_id ON subscription_history(contact_id, date);
+CREATE INDEX idx_subscription_history_group_id ON subscription_history(group_id, date);
+
+-- Memberships from before the history was kept. Their date is the last
+-- change we know of and their method is unknown, so they are marked as such.
+INSERT INTO subscription_history (group_id, contact_id, status, method, date, tracking)
+SELECT gc.group_id, gc.contact_id, COALESCE(gc.status, 'Added'), 'Admin',
+       COALESCE(gc.updated_at, gc.created_at, NOW()), 'Recorded by migration 046'
+FROM group_contacts gc;
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS subscription_history;
+ALTER TABLE group_contacts DROP CONSTRAINT IF EXISTS group_contacts_status_check;
+ALTER TABLE group_contacts DROP CONSTRAINT IF EXISTS group_contacts_group_contact_key;
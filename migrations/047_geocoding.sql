@@ -0,0 +1,58 @@
+-- Geocoding Migration
+-- Addresses get their latitude (geo_code_1) and longitude (geo_code_2) from
+-- the geocode_addresses job. geocoded_at records when an address was last
+-- looked up, found or not; it is cleared whenever the address changes, so
+-- the job picks it up again. Coordinates entered by hand are kept with
+-- manual_geo_code.
+
+ALTER TABLE addresses ADD COLUMN geocoded_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE addresses ADD COLUMN manual_geo_code BOOLEAN;
+
+-- Coordinates already present were entered by hand
+UPDATE addresses SET geocoded_at = NOW(), manual_geo_code = TRUE
+WHERE geo_code_1 IS NOT NULL AND geo_code_2 IS NOT NULL;
+
+-- Clears the coordinates of an address that moved, unless they were given
+-- in the same statement or by hand. Coordinates given on insert count as
+-- looked up.
+CREATE OR REPLACE FUNCTION reset_address_geo_code()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        IF NEW.geo_code_1 IS NOT NULL AND NEW.geo_code_2 IS NOT NULL AND NEW.geocoded_at IS NULL THEN
+            NEW.geocoded_at := NOW();
+        END IF;
+        RETURN NEW;
+    END IF;
+
+    IF (NEW.street_address, NEW.supplemental_address_1, NEW.city, NEW.state_province_id, NEW.postal_code, NEW.country_id)
+           IS DISTINCT FROM
+       (OLD.street_address, OLD.supplemental_address_1, OLD.city, OLD.state_province_id, OLD.postal_code, OLD.country_id)
+       AND NOT COALESCE(NEW.manual_geo_code, FALSE)
+       AND NEW.geo_code_1 IS NOT DISTINCT FROM OLD.geo_code_1
+       AND NEW.geo_code_2 IS NOT DISTINCT FROM OLD.geo_code_2 THEN
+        NEW.geo_code_1 := NULL;
+        NEW.geo_code_2 := NULL;
+        NEW.geocoded_at := NULL;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER reset_address_geo_code BEFORE INSERT OR UPDATE ON addresses
+    FOR EACH ROW EXECUTE FUNCTION reset_address_geo_code();
+
+-- Geocodes up to batch_size new or changed addresses of the domain every
+-- 10 minutes
+INSERT INTO jobs (domain_id, name, description, job_type, parameters, schedule, is_active)
+SELECT id, 'geocode_addresses', 'Look up the coordinates of new and changed addresses', 'address', '{}', '*/10 * * * *', TRUE
+FROM domains
+WHERE NOT EXISTS (SELECT 1 FROM jobs j WHERE j.domain_id = domains.id AND j.name = 'geocode_addresses');
+
+---- create above / drop below ----
+
+DELETE FROM jobs WHERE name = 'geocode_addresses';
+DROP TRIGGER IF EXISTS reset_address_geo_code ON addresses;
+DROP FUNCTION IF EXISTS reset_address_geo_code();
+ALTER TABLE addresses DROP COLUMN IF EXISTS manual_geo_code;
+ALTER TABLE addresses DROP COLUMN IF EXISTS geocoded_at;
@@ -0,0 +1,10 @@
+---- tern: disable-tx ----
+-- Address Geocode Index Migration
+-- Indexes the addresses the geocode_addresses job has yet to look up, oldest
+-- change first. Built concurrently, so this runs outside a transaction.
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_addresses_geocode_pending ON addresses(updated_at, id) WHERE geocoded_at IS NULL;
+
+---- create above / drop below ----
+
+DROP INDEX CONCURRENTLY IF EXISTS idx_addresses_geocode_pending;
@@ -0,0 +1,64 @@
+-- Address Standardization Migration
+-- street_number, street_name and street_unit are split from street_address
+-- when an address is created, and by the parse_street_addresses job for
+-- addresses written some other way. They are cleared whenever
+-- street_address changes, so the job picks the address up again. A state or
+-- province must belong to the address's country.
+
+-- Clears the parts of a street address that changed, unless they were given
+-- in the same statement
+CREATE OR REPLACE FUNCTION reset_address_street_parts()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.street_address IS DISTINCT FROM OLD.street_address
+       AND (NEW.street_number, NEW.street_name, NEW.street_unit)
+           IS NOT DISTINCT FROM (OLD.street_number, OLD.street_name, OLD.street_unit) THEN
+        NEW.street_number := NULL;
+        NEW.street_name := NULL;
+        NEW.street_unit := NULL;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER reset_address_street_parts BEFORE UPDATE OF street_address ON addresses
+    FOR EACH ROW EXECUTE FUNCTION reset_address_street_parts();
+
+-- Fills in the country of an address from its state or province, and
+-- rejects a state or province of another country
+CREATE OR REPLACE FUNCTION check_address_state_province()
+RETURNS TRIGGER AS $$
+DECLARE
+    state_country UUID;
+BEGIN
+    IF NEW.state_province_id IS NULL THEN
+        RETURN NEW;
+    END IF;
+
+    SELECT country_id INTO state_country FROM state_provinces WHERE id = NEW.state_province_id;
+    IF NEW.country_id IS NULL THEN
+        NEW.country_id := state_country;
+    ELSIF state_country IS DISTINCT FROM NEW.country_id THEN
+        RAISE EXCEPTION 'state or province % does not belong to country %', NEW.state_province_id, NEW.country_id
+            USING ERRCODE = 'check_violation', CONSTRAINT = 'addresses_state_province_country';
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER check_address_state_province BEFORE INSERT OR UPDATE OF state_province_id, country_id ON addresses
+    FOR EACH ROW EXECUTE FUNCTION check_address_state_province();
+
+-- Splits the street addresses of the domain's contacts nightly
+INSERT INTO jobs (domain_id, name, description, job_type, parameters, schedule, is_active)
+SELECT id, 'parse_street_addresses', 'Split street addresses into number, name and unit', 'address', '{}', '30 2 * * *', TRUE
+FROM domains
+WHERE NOT EXISTS (SELECT 1 FROM jobs j WHERE j.domain_id = domains.id AND j.name = 'parse_street_addresses');
+
+---- create above / drop below ----
+
+DELETE FROM jobs WHERE name = 'parse_street_addresses';
+DROP TRIGGER IF EXISTS check_address_state_province ON addresses;
+DROP FUNCTION IF EXISTS check_address_state_province();
+DROP TRIGGER IF EXISTS reset_address_street_parts ON addresses;
+DROP FUNCTION IF EXISTS reset_address_street_parts();
@@ -0,0 +1,120 @@
+-- Shared Addresses Migration
+-- A contact can use another contact's address, such as their household's or
+-- employer's: the copy's master_id points to the address it is shared from.
+-- Copies hold the master's fields and change only with it; an update to the
+-- master is written through to every copy. Removing master_id makes a copy
+-- independent, with the fields it had. Deleting the master does the same.
+
+ALTER TABLE addresses ADD COLUMN master_id UUID REFERENCES addresses(id) ON DELETE SET NULL;
+
+-- Individuals sharing a household's address are its members
+INSERT INTO relationship_types (name_a_b, name_b_a, description, contact_type_a, contact_type_b, is_reserved)
+SELECT 'Household Member of', 'Household Member is', 'Household membership', 'Individual', 'Household', TRUE
+WHERE NOT EXISTS (SELECT 1 FROM relationship_types WHERE name_a_b = 'Household Member of');
+
+-- Copies the master's fields into a shared address. Only addresses that
+-- are not shared themselves can be masters, and a copy's fields can only
+-- change through the master, which writes them from its trigger.
+CREATE OR REPLACE FUNCTION inherit_master_address()
+RETURNS TRIGGER AS $$
+DECLARE
+    master addresses%ROWTYPE;
+BEGIN
+    IF NEW.master_id IS NULL THEN
+        RETURN NEW;
+    END IF;
+    IF NEW.master_id = NEW.id THEN
+        RAISE EXCEPTION 'address % cannot be shared from itself', NEW.id
+            USING ERRCODE = 'check_violation', CONSTRAINT = 'addresses_master_not_shared';
+    END IF;
+
+    IF TG_OP = 'UPDATE' AND NEW.master_id = OLD.master_id AND pg_trigger_depth() = 1
+       AND (NEW.street_address, NEW.street_number, NEW.street_name, NEW.street_unit, NEW.supplemental_address_1,
+            NEW.city, NEW.state_province_id, NEW.postal_code, NEW.country_id,
+            NEW.geo_code_1, NEW.geo_code_2, NEW.geocoded_at, NEW.manual_geo_code)
+           IS DISTINCT FROM
+           (OLD.street_address, OLD.street_number, OLD.street_name, OLD.street_unit, OLD.supplemental_address_1,
+            OLD.city, OLD.state_province_id, OLD.postal_code, OLD.country_id,
+            OLD.geo_code_1, OLD.geo_code_2, OLD.geocoded_at, OLD.manual_geo_code) THEN
+        RAISE EXCEPTION 'address % is shared from address % and changes only with it', NEW.id, NEW.master_id
+            USING ERRCODE = 'check_violation', CONSTRAINT = 'addresses_shared_read_only';
+    END IF;
+
+    SELECT * INTO master FROM addresses WHERE id = NEW.master_id;
+    IF NOT FOUND THEN
+        -- The foreign key reports it
+        RETURN NEW;
+    END IF;
+    IF master.master_id IS NOT NULL THEN
+        RAISE EXCEPTION 'address % is itself shared from address %', master.id, master.master_id
+            USING ERRCODE = 'check_violation', CONSTRAINT = 'addresses_master_not_shared';
+    END IF;
+    IF TG_OP = 'UPDATE' AND OLD.master_id IS NULL AND EXISTS (SELECT 1 FROM addresses WHERE master_id = NEW.id) THEN
+        RAISE EXCEPTION 'address % is shared with other addresses', NEW.id
+            USING ERRCODE = 'check_violation', CONSTRAINT = 'addresses_master_not_shared';
+    END IF;
+
+    NEW.street_address := master.street_address;
+    NEW.street_number := master.street_number;
+    NEW.street_name := master.street_name;
+    NEW.street_unit := master.street_unit;
+    NEW.supplemental_address_1 := master.supplemental_address_1;
+    NEW.city := master.city;
+    NEW.state_province_id := master.state_province_id;
+    NEW.postal_code := master.postal_code;
+    NEW.country_id := master.country_id;
+    NEW.geo_code_1 := master.geo_code_1;
+    NEW.geo_code_2 := master.geo_code_2;
+    NEW.geocoded_at := master.geocoded_at;
+    NEW.manual_geo_code := master.manual_geo_code;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER inherit_master_address BEFORE INSERT OR UPDATE ON addresses
+    FOR EACH ROW EXECUTE FUNCTION inherit_master_address();
+
+-- Writes a master's changed fields through to its copies
+CREATE OR REPLACE FUNCTION sync_shared_addresses()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF (NEW.street_address, NEW.street_number, NEW.street_name, NEW.street_unit, NEW.supplemental_address_1,
+        NEW.city, NEW.state_province_id, NEW.postal_code, NEW.country_id,
+        NEW.geo_code_1, NEW.geo_code_2, NEW.geocoded_at, NEW.manual_geo_code)
+       IS DISTINCT FROM
+       (OLD.street_address, OLD.street_number, OLD.street_name, OLD.street_unit, OLD.supplemental_address_1,
+        OLD.city, OLD.state_province_id, OLD.postal_code, OLD.country_id,
+        OLD.geo_code_1, OLD.geo_code_2, OLD.geocoded_at, OLD.manual_geo_code) THEN
+        UPDATE addresses
+        SET street_address = NEW.street_address,
+            street_number = NEW.street_number,
+            street_name = NEW.street_name,
+            street_unit = NEW.street_unit,
+            supplemental_address_1 = NEW.supplemental_address_1,
+            city = NEW.city,
+            state_province_id = NEW.state_province_id,
+            postal_code = NEW.postal_code,
+            country_id = NEW.country_id,
+            geo_code_1 = NEW.geo_code_1,
+            geo_code_2 = NEW.geo_code_2,
+            geocoded_at = NEW.geocoded_at,
+            manual_geo_code = NEW.manual_geo_code,
+            updated_at = NOW()
+        WHERE master_id = NEW.id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER sync_shared_addresses AFTER UPDATE ON addresses
+    FOR EACH ROW EXECUTE FUNCTION sync_shared_addresses();
+
+---- create above / drop below ----
+
+DROP TRIGGER IF EXISTS sync_shared_addresses ON addresses;
+DROP FUNCTION IF EXISTS sync_shared_addresses();
+DROP TRIGGER IF EXISTS inherit_master_address ON addresses;
+DROP FUNCTION IF EXISTS inherit_master_address();
+DELETE FROM relationships WHERE relationship_type_id IN (SELECT id FROM relationship_types WHERE name_a_b = 'Household Member of');
+DELETE FROM relationship_types WHERE name_a_b = 'Household Member of';
+ALTER TABLE addresses DROP COLUMN IF EXISTS master_id;
@@ -0,0 +1,11 @@
+---- tern: disable-tx ----
+-- Address Master Index Migration
+-- Indexes shared addresses by the address they are shared from, for the
+-- write-through from migration 050. Built concurrently, so this runs
+-- outside a transaction.
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_addresses_master_id ON addresses(master_id) WHERE master_id IS NOT NULL;
+
+---- create above / drop below ----
+
+DROP INDEX CONCURRENTLY IF EXISTS idx_addresses_master_id;
@@ -0,0 +1,27 @@
+-- Personal Data Requests Migration
+-- Records every export and anonymization of a contact's personal data, for
+-- subject access and erasure requests. The log outlives the contact, so
+-- contact_id is not a foreign key. anonymized_at marks contacts whose
+-- personal data was scrubbed.
+
+ALTER TABLE contacts ADD COLUMN anonymized_at TIMESTAMP WITH TIME ZONE;
+
+CREATE TABLE personal_data_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    domain_id UUID NOT NULL REFERENCES domains(id) ON DELETE CASCADE,
+    contact_id UUID NOT NULL,
+    request_type TEXT NOT NULL CHECK (request_type IN ('Export', 'Anonymize')),
+    format TEXT CHECK (format IN ('json', 'zip')),
+    records JSONB NOT NULL DEFAULT '{}',
+    reason TEXT,
+    actor_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_personal_data_requests_contact ON personal_data_requests(contact_id, created_at);
+CREATE INDEX idx_personal_data_requests_domain ON personal_data_requests(domain_id, created_at);
+
+---- create above / drop below ----
+
+DROP TABLE IF EXISTS personal_data_requests;
+ALTER TABLE contacts DROP COLUMN IF EXISTS anonymized_at;
@@ -0,0 +1,44 @@
+---- tern: disable-tx ----
+-- Domain Indexes Migration
+-- Makes the domain columns from migration 035 NOT NULL and indexes them.
+-- Validating the check constraints scans each table without blocking
+-- writes, and SET NOT NULL then uses the valid constraint instead of a
+-- second scan under an exclusive lock. Indexes are built concurrently, so
+-- this runs outside a transaction.
+
+ALTER TABLE contacts VALIDATE CONSTRAINT contacts_domain_id_not_null;
+ALTER TABLE contacts ALTER COLUMN domain_id SET NOT NULL;
+ALTER TABLE contacts DROP CONSTRAINT contacts_domain_id_not_null;
+
+ALTER TABLE contributions VALIDATE CONSTRAINT contributions_domain_id_not_null;
+ALTER TABLE contributions ALTER COLUMN domain_id SET NOT NULL;
+ALTER TABLE contributions DROP CONSTRAINT contributions_domain_id_not_null;
+
+ALTER TABLE events VALIDATE CONSTRAINT events_domain_id_not_null;
+ALTER TABLE events ALTER COLUMN domain_id SET NOT NULL;
+ALTER TABLE events DROP CONSTRAINT events_domain_id_not_null;
+
+ALTER TABLE activities VALIDATE CONSTRAINT activities_domain_id_not_null;
+ALTER TABLE activities ALTER COLUMN domain_id SET NOT NULL;
+ALTER TABLE activities DROP CONSTRAINT activities_domain_id_not_null;
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_contacts_domain_id ON contacts(domain_id);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_contributions_domain_id ON contributions(domain_id);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_events_domain_id ON events(domain_id);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_activities_domain_id ON activities(domain_id);
+
+---- create above / drop below ----
+
+DROP INDEX CONCURRENTLY IF EXISTS idx_activities_domain_id;
+DROP INDEX CONCURRENTLY IF EXISTS idx_events_domain_id;
+DROP INDEX CONCURRENTLY IF EXISTS idx_contributions_domain_id;
+DROP INDEX CONCURRENTLY IF EXISTS idx_contacts_domain_id;
+
+ALTER TABLE activities ALTER COLUMN domain_id DROP NOT NULL;
+ALTER TABLE activities ADD CONSTRAINT activities_domain_id_not_null CHECK (domain_id IS NOT NULL) NOT VALID;
+ALTER TABLE events ALTER COLUMN domain_id DROP NOT NULL;
+ALTER TABLE events ADD CONSTRAINT events_domain_id_not_null CHECK (domain_id IS NOT NULL) NOT VALID;
+ALTER TABLE contributions ALTER COLUMN domain_id DROP NOT NULL;
+ALTER TABLE contributions ADD CONSTRAINT contributions_domain_id_not_null CHECK (domain_id IS NOT NULL) NOT VALID;
+ALTER TABLE contacts ALTER COLUMN domain_id DROP NOT NULL;
+ALTER TABLE contacts ADD CONSTRAINT contacts_domain_id_not_null CHECK (domain_id IS NOT NULL) NOT VALID;
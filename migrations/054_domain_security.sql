@@ -0,0 +1,127 @@
+-- Domain Security Migration
+-- Row level security limits each session to the domain in the app.domain_id
+-- setting. Sessions without a domain see no rows, so a query that was never
+-- scoped fails closed. Maintenance sessions and migrations that must change
+-- rows across domains switch to the civicrm_system role with
+-- SET LOCAL ROLE civicrm_system, which sees every domain.
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'civicrm_system') THEN
+        CREATE ROLE civicrm_system NOLOGIN;
+    END IF;
+END $$;
+
+GRANT civicrm_system TO CURRENT_USER;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO civicrm_system;
+GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO civicrm_system;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO civicrm_system;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT USAGE, SELECT ON SEQUENCES TO civicrm_system;
+
+-- Whether a row of the given domain is visible to the session. The role is checked
+-- by name rather than membership, since the application's own role is a
+-- member of civicrm_system and would otherwise inherit the bypass.
+CREATE OR REPLACE FUNCTION domain_visible(row_domain_id UUID)
+RETURNS BOOLEAN AS $$
+    SELECT row_domain_id = current_domain_id() OR current_user = 'civicrm_system';
+$$ LANGUAGE sql STABLE;
+
+-- Tables with a domain, forced so the table owner is restricted too
+ALTER TABLE contacts ENABLE ROW LEVEL SECURITY;
+ALTER TABLE contacts FORCE ROW LEVEL SECURITY;
+CREATE POLICY contacts_domain_isolation ON contacts
+    USING (domain_visible(domain_id))
+    WITH CHECK (domain_visible(domain_id));
+
+ALTER TABLE contributions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE contributions FORCE ROW LEVEL SECURITY;
+CREATE POLICY contributions_domain_isolation ON contributions
+    USING (domain_visible(domain_id))
+    WITH CHECK (domain_visible(domain_id));
+
+ALTER TABLE events ENABLE ROW LEVEL SECURITY;
+ALTER TABLE events FORCE ROW LEVEL SECURITY;
+CREATE POLICY events_domain_isolation ON events
+    USING (domain_visible(domain_id))
+    WITH CHECK (domain_visible(domain_id));
+
+ALTER TABLE activities ENABLE ROW LEVEL SECURITY;
+ALTER TABLE activities FORCE ROW LEVEL SECURITY;
+CREATE POLICY activities_domain_isolation ON activities
+    USING (domain_visible(domain_id))
+    WITH CHECK (domain_visible(domain_id));
+
+-- Saved searches were created with a policy that let unscoped sessions
+-- through
+DROP POLICY IF EXISTS saved_searches_domain_isolation ON saved_searches;
+CREATE POLICY saved_searches_domain_isolation ON saved_searches
+    USING (domain_visible(domain_id))
+    WITH CHECK (domain_visible(domain_id));
+
+-- Tables that belong to a contact follow the contact. The subquery is
+-- itself limited by the contacts policy.
+ALTER TABLE emails ENABLE ROW LEVEL SECURITY;
+ALTER TABLE emails FORCE ROW LEVEL SECURITY;
+CREATE POLICY emails_domain_isolation ON emails
+    USING (EXISTS (SELECT 1 FROM contacts c WHERE c.id = emails.contact_id))
+    WITH CHECK (EXISTS (SELECT 1 FROM contacts c WHERE c.id = emails.contact_id));
+
+ALTER TABLE phones ENABLE ROW LEVEL SECURITY;
+ALTER TABLE phones FORCE ROW LEVEL SECURITY;
+CREATE POLICY phones_domain_isolation ON phones
+    USING (EXISTS (SELECT 1 FROM contacts c WHERE c.id = phones.contact_id))
+    WITH CHECK (EXISTS (SELECT 1 FROM contacts c WHERE c.id = phones.contact_id));
+
+ALTER TABLE addresses ENABLE ROW LEVEL SECURITY;
+ALTER TABLE addresses FORCE ROW LEVEL SECURITY;
+CREATE POLICY addresses_domain_isolation ON addresses
+    USING (EXISTS (SELECT 1 FROM contacts c WHERE c.id = addresses.contact_id))
+    WITH CHECK (EXISTS (SELECT 1 FROM contacts c WHERE c.id = addresses.contact_id));
+
+ALTER TABLE activity_contacts ENABLE ROW LEVEL SECURITY;
+ALTER TABLE activity_contacts FORCE ROW LEVEL SECURITY;
+CREATE POLICY activity_contacts_domain_isolation ON activity_contacts
+    USING (EXISTS (SELECT 1 FROM contacts c WHERE c.id = activity_contacts.contact_id))
+    WITH CHECK (EXISTS (SELECT 1 FROM contacts c WHERE c.id = activity_contacts.contact_id));
+
+---- create above / drop below ----
+
+DROP POLICY IF EXISTS activity_contacts_domain_isolation ON activity_contacts;
+DROP POLICY IF EXISTS addresses_domain_isolation ON addresses;
+DROP POLICY IF EXISTS phones_domain_isolation ON phones;
+DROP POLICY IF EXISTS emails_domain_isolation ON emails;
+ALTER TABLE activity_contacts NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE activity_contacts DISABLE ROW LEVEL SECURITY;
+ALTER TABLE addresses NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE addresses DISABLE ROW LEVEL SECURITY;
+ALTER TABLE phones NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE phones DISABLE ROW LEVEL SECURITY;
+ALTER TABLE emails NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE emails DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS saved_searches_domain_isolation ON saved_searches;
+CREATE POLICY saved_searches_domain_isolation ON saved_searches
+    USING (current_domain_id() IS NULL OR domain_id = current_domain_id())
+    WITH CHECK (current_domain_id() IS NULL OR domain_id = current_domain_id());
+
+DROP POLICY IF EXISTS activities_domain_isolation ON activities;
+DROP POLICY IF EXISTS events_domain_isolation ON events;
+DROP POLICY IF EXISTS contributions_domain_isolation ON contributions;
+DROP POLICY IF EXISTS contacts_domain_isolation ON contacts;
+ALTER TABLE activities NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE activities DISABLE ROW LEVEL SECURITY;
+ALTER TABLE events NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE events DISABLE ROW LEVEL SECURITY;
+ALTER TABLE contributions NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE contributions DISABLE ROW LEVEL SECURITY;
+ALTER TABLE contacts NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE contacts DISABLE ROW LEVEL SECURITY;
+
+DROP FUNCTION IF EXISTS domain_visible(UUID);
+
+-- The role is shared by every database in the cluster, so it is kept
+
+ALTER DEFAULT PRIVILEGES IN SCHEMA public REVOKE USAGE, SELECT ON SEQUENCES FROM civicrm_system;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public REVOKE SELECT, INSERT, UPDATE, DELETE ON TABLES FROM civicrm_system;
+REVOKE USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public FROM civicrm_system;
+REVOKE SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public FROM civicrm_system;
@@ -0,0 +1,41 @@
+---- tern: disable-tx ----
+-- Dedupe Lookup Indexes Migration
+-- Indexes the normalized fields that dedupe rules match new contacts on,
+-- using the same expressions as the lookups in internal/dedupe. The pattern
+-- operator class serves both exact matches and the prefix matches of rules
+-- with a length. Built concurrently, so this runs outside a transaction.
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_contacts_first_name_normalized
+    ON contacts(contact_type, (regexp_replace(lower(btrim(first_name)), '\s+', ' ', 'g')) text_pattern_ops) WHERE NOT is_deleted;
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_contacts_last_name_normalized
+    ON contacts(contact_type, (regexp_replace(lower(btrim(last_name)), '\s+', ' ', 'g')) text_pattern_ops) WHERE NOT is_deleted;
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_contacts_nick_name_normalized
+    ON contacts(contact_type, (regexp_replace(lower(btrim(nick_name)), '\s+', ' ', 'g')) text_pattern_ops) WHERE NOT is_deleted;
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_contacts_organization_name_normalized
+    ON contacts(contact_type, (regexp_replace(lower(btrim(organization_name)), '\s+', ' ', 'g')) text_pattern_ops) WHERE NOT is_deleted;
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_contacts_household_name_normalized
+    ON contacts(contact_type, (regexp_replace(lower(btrim(household_name)), '\s+', ' ', 'g')) text_pattern_ops) WHERE NOT is_deleted;
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_emails_email_normalized
+    ON emails((regexp_replace(lower(btrim(email)), '\s+', ' ', 'g')) text_pattern_ops);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_phones_phone_normalized
+    ON phones((regexp_replace(phone, '\D', '', 'g')) text_pattern_ops);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_addresses_street_address_normalized
+    ON addresses((regexp_replace(lower(btrim(street_address)), '\s+', ' ', 'g')) text_pattern_ops);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_addresses_city_normalized
+    ON addresses((regexp_replace(lower(btrim(city)), '\s+', ' ', 'g')) text_pattern_ops);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_addresses_postal_code_normalized
+    ON addresses((regexp_replace(lower(btrim(postal_code)), '\s+', ' ', 'g')) text_pattern_ops);
+
+---- create above / drop below ----
+
+DROP INDEX CONCURRENTLY IF EXISTS idx_addresses_postal_code_normalized;
+DROP INDEX CONCURRENTLY IF EXISTS idx_addresses_city_normalized;
+DROP INDEX CONCURRENTLY IF EXISTS idx_addresses_street_address_normalized;
+DROP INDEX CONCURRENTLY IF EXISTS idx_phones_phone_normalized;
+DROP INDEX CONCURRENTLY IF EXISTS idx_emails_email_normalized;
+DROP INDEX CONCURRENTLY IF EXISTS idx_contacts_household_name_normalized;
+DROP INDEX CONCURRENTLY IF EXISTS idx_contacts_organization_name_normalized;
+DROP INDEX CONCURRENTLY IF EXISTS idx_contacts_nick_name_normalized;
+DROP INDEX CONCURRENTLY IF EXISTS idx_contacts_last_name_normalized;
+DROP INDEX CONCURRENTLY IF EXISTS idx_contacts_first_name_normalized;
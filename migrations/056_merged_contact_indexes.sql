@@ -0,0 +1,15 @@
+---- tern: disable-tx ----
+-- Merged Contact Indexes Migration
+-- Indexes the merge redirects that personal data exports and erasures
+-- follow to a contact's duplicates, and the backed up location values they
+-- cover. Built concurrently, so this runs outside a transaction.
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_contacts_merged_to_id
+    ON contacts(merged_to_id) WHERE merged_to_id IS NOT NULL;
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_contact_location_backup_contact_id
+    ON contact_location_backup(contact_id);
+
+---- create above / drop below ----
+
+DROP INDEX CONCURRENTLY IF EXISTS idx_contact_location_backup_contact_id;
+DROP INDEX CONCURRENTLY IF EXISTS idx_contacts_merged_to_id;
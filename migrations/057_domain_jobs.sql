@@ -0,0 +1,17 @@
+---- tern: disable-tx ----
+-- Domain Jobs Migration
+-- A domain has at most one job of each name. The scheduler copies the jobs
+-- of the default domain to every other domain that lacks them, so new
+-- domains are maintained like the default one. The index is built
+-- concurrently, so this runs outside a transaction.
+
+DELETE FROM jobs j
+USING jobs older
+WHERE older.domain_id = j.domain_id AND older.name = j.name
+AND (older.created_at, older.id) < (j.created_at, j.id);
+
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS idx_jobs_domain_name ON jobs(domain_id, name);
+
+---- create above / drop below ----
+
+DROP INDEX CONCURRENTLY IF EXISTS idx_jobs_domain_name;
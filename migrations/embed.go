@@ -0,0 +1,10 @@
+// Package migrations embeds the SQL migration files so the server binary can
+// apply them without the tern tool.
+package migrations
+
+import "embed"
+
+// FS holds every migration file in this directory
+//
+//go:embed *.sql
+var FS embed.FS